@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/luiz504/week-tech-go-server/internal/contract"
+)
+
+// contract runs the websocket protocol conformance suite against a server,
+// printing each rule with its outcome.
+func main() {
+	urlFlag := flag.String("url", "http://localhost:8080", "base URL of the server under test")
+	apiKeyFlag := flag.String("api-key", "", "API key sent as X-API-Key")
+	timeoutFlag := flag.Duration("timeout", 5*time.Second, "timeout for each request and expected frame")
+	docsFlag := flag.Bool("docs", false, "print the protocol rules without running them")
+	flag.Parse()
+
+	if *docsFlag {
+		for _, check := range contract.Checks {
+			fmt.Printf("%s\n    %s\n", check.Name, check.Doc)
+		}
+		return
+	}
+
+	_ = godotenv.Load()
+	results := contract.Run(context.Background(), contract.Config{
+		BaseURL:    *urlFlag,
+		APIKey:     *apiKeyFlag,
+		AdminToken: os.Getenv("WS_ADMIN_TOKEN"),
+		Timeout:    *timeoutFlag,
+	})
+
+	failed := 0
+	for _, result := range results {
+		switch {
+		case errors.Is(result.Err, contract.ErrSkipped):
+			fmt.Printf("SKIP %s\n", result.Check.Name)
+		case result.Err != nil:
+			failed++
+			fmt.Printf("FAIL %s\n    %s\n    %v\n", result.Check.Name, result.Check.Doc, result.Err)
+		default:
+			fmt.Printf("PASS %s\n", result.Check.Name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
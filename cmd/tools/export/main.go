@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/luiz504/week-tech-go-server/internal/config"
+	"github.com/luiz504/week-tech-go-server/internal/export"
+	"github.com/luiz504/week-tech-go-server/internal/redact"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+)
+
+func main() {
+	roomFlag := flag.String("room", "", "id of the room to export")
+	formatFlag := flag.String("format", export.FormatHTML, "export format: html or pdf")
+	outFlag := flag.String("out", "", "output file (defaults to room-<code>.<format>)")
+	flag.Parse()
+
+	roomID, err := uuid.Parse(*roomFlag)
+	if err != nil {
+		log.Fatalf("Invalid room id 💥: %v", err)
+	}
+	render := export.RenderHTML
+	switch *formatFlag {
+	case export.FormatHTML:
+	case export.FormatPDF:
+		render = export.RenderPDF
+	default:
+		log.Fatalf("Unsupported export format 💥: %s", *formatFlag)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Fatalf("Error loading .env file 💥: %v", err)
+	}
+	ctx := context.Background()
+
+	db, err := config.LoadDatabase()
+	if err != nil {
+		log.Fatalf("Invalid configuration 💥:\n%v", err)
+	}
+
+	poll, err := pgxpool.New(ctx, db.ConnString())
+	if err != nil {
+		log.Fatalf("Error connecting to database 💥: %v", err)
+	}
+	defer poll.Close()
+
+	st, err := store.EncryptedFromEnv(store.NewPostgres(poll))
+	if err != nil {
+		log.Fatalf("Error enabling message encryption 💥: %v", err)
+	}
+
+	transcript, err := export.Load(ctx, st, roomID)
+	if err != nil {
+		log.Fatalf("Error loading room 💥: %v", err)
+	}
+
+	redactor, err := redact.FromEnv()
+	if err != nil {
+		log.Fatalf("Invalid redaction settings 💥: %v", err)
+	}
+	transcript = transcript.Redact(redactor)
+
+	out := *outFlag
+	if out == "" {
+		out = fmt.Sprintf("room-%s.%s", transcript.Room.Code, *formatFlag)
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		log.Fatalf("Error creating output file 💥: %v", err)
+	}
+	defer file.Close()
+
+	if err := render(file, transcript); err != nil {
+		log.Fatalf("Error rendering export 💥: %v", err)
+	}
+
+	fmt.Printf("Room exported to %s 🫐\n", out)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/luiz504/week-tech-go-server/internal/api"
+)
+
+// genclient writes TypeScript declarations for the API payloads and
+// websocket events, plus a thin fetch/WebSocket client over them, so the
+// frontend is regenerated instead of hand-synced on every server change.
+func main() {
+	outFlag := flag.String("out", "", "file to write, stdout when empty")
+	flag.Parse()
+
+	source, err := generate()
+	if err != nil {
+		log.Fatalf("Error generating client 💥: %v", err)
+	}
+
+	if *outFlag == "" {
+		fmt.Print(source)
+		return
+	}
+	if err := os.WriteFile(*outFlag, []byte(source), 0o644); err != nil {
+		log.Fatalf("Error writing %s 💥: %v", *outFlag, err)
+	}
+}
+
+func generate() (string, error) {
+	g := newGenerator()
+
+	events := api.EventTypes()
+	kinds := make([]string, 0, len(events))
+	for kind := range events {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var union []string
+	for _, kind := range kinds {
+		union = append(union, fmt.Sprintf("  | ServerEventOf<%q, %s>", kind, g.typeOf(reflect.TypeOf(events[kind]))))
+	}
+
+	payloads := api.PayloadTypes()
+	names := make(map[string]string, len(payloads))
+	for name, value := range payloads {
+		names[name] = g.typeOf(reflect.TypeOf(value))
+	}
+	for _, required := range []string{api.SchemaCommand, api.SchemaRoom, api.SchemaRoomMessage, api.SchemaPublicMessage} {
+		if names[required] == "" {
+			return "", fmt.Errorf("payload %s is not registered", required)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by cmd/tools/genclient. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "export const SCHEMA_VERSION = %d;\n\n", api.SchemaVersion)
+	b.WriteString(g.declarations())
+	fmt.Fprintf(&b, "export type EventKind =\n%s;\n\n", "  | "+strings.Join(quoteAll(kinds), "\n  | "))
+	b.WriteString("export interface ServerEventOf<K extends EventKind, V> {\n  event_id?: number;\n  channel?: string;\n  kind: K;\n  value: V;\n}\n\n")
+	fmt.Fprintf(&b, "export type ServerEvent =\n%s;\n\n", strings.Join(union, "\n"))
+	b.WriteString(strings.NewReplacer(
+		"$Command", names[api.SchemaCommand],
+		"$RoomMessage", names[api.SchemaRoomMessage],
+		"$PublicMessage", names[api.SchemaPublicMessage],
+		"$Room", names[api.SchemaRoom],
+	).Replace(clientSource))
+
+	return b.String(), nil
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+
+	return quoted
+}
+
+const clientSource = `export interface ClientOptions {
+  apiKey?: string;
+  sessionToken?: string;
+  ownerToken?: string;
+  fetch?: typeof fetch;
+}
+
+export class ApiError extends Error {
+  constructor(
+    public readonly status: number,
+    message: string,
+  ) {
+    super(message);
+  }
+}
+
+export class WsrsClient {
+  constructor(
+    private readonly baseUrl: string,
+    private readonly options: ClientOptions = {},
+  ) {}
+
+  private headers(): Record<string, string> {
+    const headers: Record<string, string> = { "Content-Type": "application/json" };
+    if (this.options.apiKey) headers["X-API-Key"] = this.options.apiKey;
+    if (this.options.sessionToken) headers["X-Session-Token"] = this.options.sessionToken;
+    if (this.options.ownerToken) headers["Authorization"] = "Bearer " + this.options.ownerToken;
+    return headers;
+  }
+
+  async request<T>(method: string, path: string, body?: unknown): Promise<T> {
+    const doFetch = this.options.fetch ?? fetch;
+    const response = await doFetch(this.baseUrl.replace(/\/$/, "") + path, {
+      method,
+      headers: this.headers(),
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (!response.ok) {
+      throw new ApiError(response.status, (await response.text()).trim());
+    }
+    if (response.status === 204) {
+      return undefined as T;
+    }
+    return (await response.json()) as T;
+  }
+
+  getRoom(roomId: string): Promise<{ room: $Room }> {
+    return this.request("GET", "/api/rooms/" + roomId);
+  }
+
+  getRoomMessages(roomId: string): Promise<{ room_id: string; last_event_id: number; messages: $RoomMessage[] }> {
+    return this.request("GET", "/api/rooms/" + roomId + "/messages");
+  }
+
+  getPublicRoom(code: string): Promise<{ questions: $PublicMessage[] }> {
+    return this.request("GET", "/public/rooms/" + code);
+  }
+
+  createRoomMessage(
+    roomId: string,
+    body: { message: string; fields?: Record<string, string>; author_name?: string },
+  ): Promise<{ id: string }> {
+    return this.request("POST", "/api/rooms/" + roomId + "/messages", body);
+  }
+
+  /**
+   * Opens the room socket. Browsers can't set headers on the upgrade, so the
+   * owner token, when configured, is sent with host channel subscriptions.
+   */
+  subscribe(roomId: string, onEvent: (event: ServerEvent) => void): RoomSocket {
+    const url = this.baseUrl.replace(/^http/, "ws").replace(/\/$/, "") + "/subscribe/" + roomId;
+    return new RoomSocket(new WebSocket(url), onEvent, this.options.ownerToken);
+  }
+}
+
+export class RoomSocket {
+  constructor(
+    public readonly socket: WebSocket,
+    onEvent: (event: ServerEvent) => void,
+    private readonly ownerToken?: string,
+  ) {
+    socket.addEventListener("message", (message) => {
+      onEvent(JSON.parse(String(message.data)) as ServerEvent);
+    });
+  }
+
+  send(command: $Command): void {
+    if (command.type === "subscribe" && this.ownerToken && !command.token) {
+      command = { ...command, token: this.ownerToken };
+    }
+    this.socket.send(JSON.stringify(command));
+  }
+
+  close(): void {
+    this.socket.close();
+  }
+}
+`
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// generator turns Go types into TypeScript declarations, following their
+// json tags the same way internal/schema does.
+type generator struct {
+	// interfaces holds the declaration of every named struct met so far.
+	interfaces map[string]string
+	types      map[string]reflect.Type
+}
+
+func newGenerator() *generator {
+	return &generator{interfaces: make(map[string]string), types: make(map[string]reflect.Type)}
+}
+
+// interfaceName exports unexported Go names, as TypeScript has no such notion.
+func interfaceName(t reflect.Type) string {
+	name := t.Name()
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (g *generator) typeOf(t reflect.Type) string {
+	switch t {
+	case timeType, uuidType:
+		return "string"
+	case rawMessageType:
+		return "unknown"
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeOf(t.Elem()) + " | null"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		//? nil slices marshal to null
+		return "Array<" + g.typeOf(t.Elem()) + "> | null"
+	case reflect.Map:
+		return "Record<string, " + g.typeOf(t.Elem()) + "> | null"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return "{ " + strings.Join(g.fields(t), " ") + " }"
+		}
+		name := interfaceName(t)
+		if seen, ok := g.types[name]; ok && seen != t {
+			panic(fmt.Sprintf("%s and %s both generate interface %s", seen, t, name))
+		}
+		g.types[name] = t
+		if _, ok := g.interfaces[name]; !ok {
+			//* placeholder first, so self-referencing types terminate
+			g.interfaces[name] = ""
+			g.interfaces[name] = "export interface " + name + " {\n  " + strings.Join(g.fields(t), "\n  ") + "\n}\n"
+		}
+		return name
+	default:
+		return "unknown"
+	}
+}
+
+func (g *generator) fields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, g.fields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		optional := ""
+		if strings.Contains(opts, "omitempty") {
+			optional = "?"
+		}
+		fields = append(fields, fmt.Sprintf("%s%s: %s;", name, optional, g.typeOf(f.Type)))
+	}
+
+	return fields
+}
+
+// declarations returns the interfaces in name order, so output is stable.
+func (g *generator) declarations() string {
+	names := make([]string, 0, len(g.interfaces))
+	for name := range g.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(g.interfaces[name])
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/luiz504/week-tech-go-server/internal/api"
+)
+
+// rebalance compares per-room connection counts across wsrs instances and
+// asks the overloaded ones to shed subscribers back to the load balancer.
+func main() {
+	instancesFlag := flag.String("instances", "", "comma separated base URLs of the wsrs instances")
+	toleranceFlag := flag.Float64("tolerance", 0.1, "fraction above the even share an instance may hold before shedding")
+	minFlag := flag.Int("min", 20, "ignore rooms with fewer connections than this across all instances")
+	dryRunFlag := flag.Bool("dry-run", false, "print the plan without shedding")
+	flag.Parse()
+
+	instances := strings.Split(*instancesFlag, ",")
+	if *instancesFlag == "" || len(instances) < 2 {
+		log.Fatal("At least two instances are required 💥")
+	}
+
+	_ = godotenv.Load()
+	token := os.Getenv("WS_ADMIN_TOKEN")
+	if token == "" {
+		log.Fatal("WS_ADMIN_TOKEN is not set 💥")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	//* room id -> instance -> subscribers
+	counts := make(map[string]map[string]int)
+	for _, instance := range instances {
+		var conns api.InstanceConnections
+		if err := call(client, token, http.MethodGet, instance+"/admin/connections", nil, &conns); err != nil {
+			log.Fatalf("Error reading connections from %s 💥: %v", instance, err)
+		}
+		for _, room := range conns.Rooms {
+			if counts[room.RoomID] == nil {
+				counts[room.RoomID] = make(map[string]int)
+			}
+			counts[room.RoomID][instance] = room.Subscribers
+		}
+	}
+
+	for roomID, perInstance := range counts {
+		total := 0
+		for _, count := range perInstance {
+			total += count
+		}
+		if total < *minFlag {
+			continue
+		}
+
+		share := float64(total) / float64(len(instances))
+		for _, instance := range instances {
+			excess := perInstance[instance] - int(share)
+			if float64(perInstance[instance]) <= share*(1+*toleranceFlag) || excess <= 0 {
+				continue
+			}
+
+			fmt.Printf("room %s: %s holds %d of %d, shedding %d\n", roomID, instance, perInstance[instance], total, excess)
+			if *dryRunFlag {
+				continue
+			}
+
+			body := map[string]int{"count": excess}
+			if err := call(client, token, http.MethodPost, fmt.Sprintf("%s/admin/rooms/%s/shed", instance, roomID), body, nil); err != nil {
+				log.Printf("Error shedding room %s on %s 💥: %v", roomID, instance, err)
+			}
+		}
+	}
+}
+
+func call(client *http.Client, token, method, url string, body, out any) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luiz504/week-tech-go-server/internal/api"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+)
+
+// simulate replays a scripted scenario against a running server, for demos
+// and for latency experiments that can be repeated step for step. With
+// -subscribers it also listens on the room and reports how long events took
+// from the request to each socket.
+func main() {
+	urlFlag := flag.String("url", "http://localhost:8080", "base URL of the server")
+	apiKeyFlag := flag.String("api-key", "", "API key sent as X-API-Key")
+	ownerTokenFlag := flag.String("owner-token", "", "owner token of the scenario's room_id, needed to answer in an existing room")
+	speedFlag := flag.Float64("speed", 1, "time scale, 2 plays the scenario twice as fast")
+	subscribersFlag := flag.Int("subscribers", 1, "websocket subscribers measuring delivery latency, 0 disables them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: simulate [flags] scenario.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *speedFlag <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	scenario, err := loadScenario(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading scenario 💥: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sim := &simulator{
+		baseURL:    strings.TrimRight(*urlFlag, "/"),
+		apiKey:     *apiKeyFlag,
+		ownerToken: *ownerTokenFlag,
+		http:       &http.Client{Timeout: 10 * time.Second},
+		ids:        map[string]string{},
+		sent:       map[string]time.Time{},
+	}
+	if err := sim.run(ctx, scenario, *speedFlag, *subscribersFlag); err != nil {
+		log.Fatalf("Error running scenario 💥: %v", err)
+	}
+	sim.report(os.Stdout)
+}
+
+type arrival struct {
+	key string
+	at  time.Time
+}
+
+type simulator struct {
+	baseURL    string
+	apiKey     string
+	ownerToken string
+	http       *http.Client
+
+	mu       sync.Mutex
+	ids      map[string]string    // step name -> message id
+	sent     map[string]time.Time // event key -> when its request was sent
+	arrivals []arrival
+	failures int
+}
+
+func (s *simulator) run(ctx context.Context, scenario Scenario, speed float64, subscribers int) error {
+	roomID := scenario.RoomID
+	if roomID == "" {
+		var room struct {
+			ID         string `json:"id"`
+			OwnerToken string `json:"owner_token"`
+		}
+		if err := s.do(ctx, http.MethodPost, "/api/rooms", map[string]any{"theme": scenario.Theme}, &room, http.StatusCreated); err != nil {
+			return fmt.Errorf("create room: %w", err)
+		}
+		roomID, s.ownerToken = room.ID, room.OwnerToken
+		log.Printf("Created room %s", roomID)
+	}
+
+	var listeners sync.WaitGroup
+	listenCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
+	for range subscribers {
+		conn, err := s.subscribe(ctx, roomID)
+		if err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			s.listen(listenCtx, conn)
+		}()
+	}
+
+	start := time.Now()
+	scale := func(d duration) time.Duration { return time.Duration(float64(d) / speed) }
+	var steps sync.WaitGroup
+	for i, step := range scenario.Steps {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(scale(step.At)))):
+		}
+
+		//* Posts run inline so later steps can resolve their names, the rest overlap
+		if step.Action == actionPost {
+			s.post(ctx, roomID, i, step)
+			continue
+		}
+		steps.Add(1)
+		go func() {
+			defer steps.Done()
+			switch step.Action {
+			case actionReact:
+				s.react(ctx, roomID, i, step, scale(step.Over))
+			case actionAnswer:
+				s.answer(ctx, roomID, i, step)
+			}
+		}()
+	}
+	steps.Wait()
+
+	//* Give the last events a moment to reach the subscribers
+	time.Sleep(time.Second)
+	stopListening()
+	listeners.Wait()
+
+	return nil
+}
+
+func (s *simulator) fail(i int, step Step, err error) {
+	s.mu.Lock()
+	s.failures++
+	s.mu.Unlock()
+	log.Printf("Step %d (%s) failed: %v", i, step.Action, err)
+}
+
+func (s *simulator) expect(key string, sentAt time.Time) {
+	s.mu.Lock()
+	s.sent[key] = sentAt
+	s.mu.Unlock()
+}
+
+func (s *simulator) messageID(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ids[name]
+}
+
+func (s *simulator) post(ctx context.Context, roomID string, i int, step Step) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	sentAt := time.Now()
+	body := map[string]any{"message": step.Message, "author_name": step.AuthorName}
+	if err := s.do(ctx, http.MethodPost, "/api/rooms/"+roomID+"/messages", body, &resp, http.StatusCreated); err != nil {
+		s.fail(i, step, err)
+		return
+	}
+
+	s.expect(api.MessageKindMessageCreated+":"+resp.ID, sentAt)
+	if step.Name != "" {
+		s.mu.Lock()
+		s.ids[step.Name] = resp.ID
+		s.mu.Unlock()
+	}
+}
+
+func (s *simulator) react(ctx context.Context, roomID string, i int, step Step, over time.Duration) {
+	id := s.messageID(step.Target)
+	if id == "" {
+		s.fail(i, step, fmt.Errorf("target %q was never posted", step.Target))
+		return
+	}
+	kind := step.Kind
+	if kind == "" {
+		kind = api.ReactionKindLike
+	}
+
+	gap := over / time.Duration(step.Count)
+	for n := range step.Count {
+		if n > 0 && gap > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gap):
+			}
+		}
+
+		//* Each session reacts once to a message, so every reaction is a fresh audience member
+		var sess struct {
+			Token string `json:"token"`
+		}
+		if err := s.do(ctx, http.MethodPost, "/api/sessions", nil, &sess, http.StatusCreated); err != nil {
+			s.fail(i, step, err)
+			continue
+		}
+
+		var resp struct {
+			Count int64 `json:"count"`
+		}
+		sentAt := time.Now()
+		if err := s.doAs(ctx, sess.Token, http.MethodPatch, "/api/rooms/"+roomID+"/messages/"+id+"/react", map[string]any{"kind": kind}, &resp, http.StatusOK); err != nil {
+			s.fail(i, step, err)
+			continue
+		}
+		s.expect(fmt.Sprintf("%s:%s:%s:%d", api.MessageKindMessageReactionIncreased, id, kind, resp.Count), sentAt)
+	}
+}
+
+func (s *simulator) answer(ctx context.Context, roomID string, i int, step Step) {
+	id := s.messageID(step.Target)
+	if id == "" {
+		s.fail(i, step, fmt.Errorf("target %q was never posted", step.Target))
+		return
+	}
+
+	//* Answers must name the version they apply to, reactions don't change it
+	var current struct {
+		Message struct {
+			Version int32 `json:"version"`
+		} `json:"message"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/api/rooms/"+roomID+"/messages/"+id, nil, &current, http.StatusOK); err != nil {
+		s.fail(i, step, err)
+		return
+	}
+
+	sentAt := time.Now()
+	body := map[string]any{"answer": step.Answer, "version": current.Message.Version}
+	if err := s.do(ctx, http.MethodPatch, "/api/rooms/"+roomID+"/messages/"+id+"/answer", body, nil, http.StatusNoContent); err != nil {
+		s.fail(i, step, err)
+		return
+	}
+	s.expect(api.MessageKindMessageAnswered+":"+id, sentAt)
+}
+
+// do sends a JSON request, with the owner token when the scenario has one,
+// and decodes the response into out.
+func (s *simulator) do(ctx context.Context, method, path string, body, out any, want int) error {
+	return s.doAs(ctx, "", method, path, body, out, want)
+}
+
+// doAs is do on behalf of an audience session when sessionToken is set.
+func (s *simulator) doAs(ctx context.Context, sessionToken, method, path string, body, out any, want int) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("X-API-Key", s.apiKey)
+	}
+	if s.ownerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.ownerToken)
+	}
+	if sessionToken != "" {
+		req.Header.Set(session.HeaderName, sessionToken)
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *simulator) subscribe(ctx context.Context, roomID string) (*websocket.Conn, error) {
+	u, err := url.Parse(s.baseURL + "/subscribe/" + roomID)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = "ws"
+	if strings.HasPrefix(s.baseURL, "https") {
+		u.Scheme = "wss"
+	}
+
+	header := http.Header{}
+	if s.apiKey != "" {
+		header.Set("X-API-Key", s.apiKey)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+
+	return conn, err
+}
+
+// listen records when each room event reaches this socket, keyed the same way
+// the requests that caused them are.
+func (s *simulator) listen(ctx context.Context, conn *websocket.Conn) {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var msg struct {
+			Kind  string          `json:"kind"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		at := time.Now()
+
+		var key string
+		switch msg.Kind {
+		case api.MessageKindMessageCreated, api.MessageKindMessageAnswered:
+			var v struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(msg.Value, &v) != nil {
+				continue
+			}
+			key = msg.Kind + ":" + v.ID
+		case api.MessageKindMessageReactionIncreased:
+			var v api.MessageMessageReactionUpdated
+			if json.Unmarshal(msg.Value, &v) != nil {
+				continue
+			}
+			key = fmt.Sprintf("%s:%s:%s:%d", msg.Kind, v.ID, v.Kind, v.Count)
+		default:
+			continue
+		}
+
+		s.mu.Lock()
+		s.arrivals = append(s.arrivals, arrival{key: key, at: at})
+		s.mu.Unlock()
+	}
+}
+
+// report prints delivery latency per event kind, from sending the request to
+// the event reaching a subscriber.
+func (s *simulator) report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latencies := map[string][]time.Duration{}
+	for _, a := range s.arrivals {
+		sentAt, ok := s.sent[a.key]
+		if !ok {
+			continue
+		}
+		kind, _, _ := strings.Cut(a.key, ":")
+		latencies[kind] = append(latencies[kind], a.at.Sub(sentAt))
+	}
+
+	fmt.Fprintf(w, "%d requests, %d failed\n", len(s.sent)+s.failures, s.failures)
+	kinds := make([]string, 0, len(latencies))
+	for kind := range latencies {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	for _, kind := range kinds {
+		values := latencies[kind]
+		slices.Sort(values)
+		pct := func(p float64) time.Duration { return values[int(p*float64(len(values)-1))] }
+		fmt.Fprintf(w, "%-28s n=%-5d p50=%-10v p95=%-10v max=%v\n", kind, len(values), pct(0.5), pct(0.95), values[len(values)-1])
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	actionPost   = "post"
+	actionReact  = "react"
+	actionAnswer = "answer"
+)
+
+// duration reads "1.5s" style strings from the scenario file.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+
+	return nil
+}
+
+// Step is one scripted action, run At after the scenario starts.
+type Step struct {
+	At     duration `json:"at"`
+	Action string   `json:"action"`
+
+	//* post: Name labels the message so later steps can target it
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	AuthorName string `json:"author_name"`
+
+	//* react and answer
+	Target string `json:"target"`
+
+	//* react: Count reactions spread evenly over Over, zero sends them at once
+	Kind  string   `json:"kind"`
+	Count int      `json:"count"`
+	Over  duration `json:"over"`
+
+	//* answer
+	Answer string `json:"answer"`
+}
+
+// Scenario is a room and a timeline of steps against it. A fresh room with
+// Theme is created unless RoomID points at an existing one, in which case
+// answers need the owner token through -owner-token.
+type Scenario struct {
+	Theme  string `json:"theme"`
+	RoomID string `json:"room_id"`
+	Steps  []Step `json:"steps"`
+}
+
+func loadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Scenario{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s.Theme == "" && s.RoomID == "" {
+		return Scenario{}, fmt.Errorf("%s: needs a theme or a room_id", path)
+	}
+
+	names := map[string]bool{}
+	sort.SliceStable(s.Steps, func(i, j int) bool { return s.Steps[i].At < s.Steps[j].At })
+	for i, step := range s.Steps {
+		switch step.Action {
+		case actionPost:
+			if step.Message == "" {
+				return Scenario{}, fmt.Errorf("step %d: post needs a message", i)
+			}
+			if step.Name != "" {
+				names[step.Name] = true
+			}
+		case actionReact, actionAnswer:
+			//* Steps are sorted, so a target must be posted at or before its use
+			if !names[step.Target] {
+				return Scenario{}, fmt.Errorf("step %d: %s targets %q, which no earlier post names", i, step.Action, step.Target)
+			}
+			if step.Action == actionReact && step.Count < 1 {
+				s.Steps[i].Count = 1
+			}
+		default:
+			return Scenario{}, fmt.Errorf("step %d: unknown action %q", i, step.Action)
+		}
+	}
+
+	return s, nil
+}
@@ -3,33 +3,61 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/cloudflare/tableflip"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/luiz504/week-tech-go-server/internal/api"
-	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/config"
+	"github.com/luiz504/week-tech-go-server/internal/logging"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/mock"
+	"github.com/luiz504/week-tech-go-server/internal/store"
 )
 
 func main() {
+	//* `wsrs` and `wsrs serve` are the same, the subcommand exists for its flags
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	mockMode := fs.Bool("mock", false, "serve seeded in-memory rooms with generated events, no database needed")
+	mockSeed := fs.Uint64("mock-seed", 1, "seed for the mock data and event generator")
+	mockTick := fs.Duration("mock-tick", 2*time.Second, "how often the mock generator emits an event, 0 disables it")
+	mockPort := fs.Int("mock-port", config.DefaultPort, "port the mock server listens on")
+	_ = fs.Parse(args)
+
+	if *mockMode {
+		serveMock(*mockSeed, *mockTick, *mockPort)
+		return
+	}
+
 	if err := godotenv.Load(); err != nil {
 		log.Fatalf("Error loading .env file 💥: %v", err)
 	}
+	logging.Setup()
 	ctx := context.Background()
 
-	poll, err := pgxpool.New(ctx, fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s",
-		os.Getenv("WS_DATABASE_HOST"),
-		os.Getenv("WS_DATABASE_PORT"),
-		os.Getenv("WS_DATABASE_USER"),
-		os.Getenv("WS_DATABASE_PASSWORD"),
-		os.Getenv("WS_DATABASE_NAME"),
-	))
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration 💥:\n%v", err)
+	}
 
+	shutdownMetrics, err := metrics.StartOTLP(ctx)
+	if err != nil {
+		log.Fatalf("Error starting OTLP metrics exporter 💥: %v", err)
+	}
+
+	poll, err := pgxpool.New(ctx, cfg.Database.ConnString())
 	if err != nil {
 		log.Fatalf("Error connecting to database 💥: %v", err)
 	}
@@ -40,23 +68,139 @@ func main() {
 		log.Fatalf("Error pinging database 💥: %v", err)
 	}
 
-	handler := api.NewHandler(pg.New(poll))
+	if err := metrics.RegisterPool(poll); err != nil {
+		log.Fatalf("Error registering pool metrics 💥: %v", err)
+	}
+
+	primary := store.NewPostgres(poll)
+	if cfg.Replica != nil {
+		replica, err := pgxpool.New(ctx, cfg.Replica.ConnString())
+		if err != nil {
+			log.Fatalf("Error connecting to replica database 💥: %v", err)
+		}
+		defer replica.Close()
+		primary.WithReplica(replica, cfg.ReplicaMaxWait)
+	}
+
+	st, err := store.EncryptedFromEnv(primary)
+	if err != nil {
+		log.Fatalf("Error enabling message encryption 💥: %v", err)
+	}
+
+	handler := api.NewHandler(st, cfg)
+
+	address := fmt.Sprintf(":%d", cfg.Port)
+
+	//* SIGHUP execs a new binary that inherits the listener, this process exits once it's ready
+	upg, err := tableflip.New(tableflip.Options{PIDFile: os.Getenv("WS_PID_FILE")})
+	if err != nil {
+		log.Fatalf("Error preparing upgrades 💥: %v", err)
+	}
+	defer upg.Stop()
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+		for range sig {
+			log.Println("Upgrading server...")
+			if err := upg.Upgrade(); err != nil {
+				log.Printf("Error upgrading server 💥: %v", err)
+			}
+		}
+	}()
+
+	ln, err := upg.Listen("tcp", address)
+	if err != nil {
+		log.Fatalf("Error listening on %s 💥: %v", address, err)
+	}
 
-	port := "8080"
-	address := fmt.Sprintf(":%s", port)
+	srv := &http.Server{Handler: handler}
 
 	go func() {
-		log.Printf("Server is starting on http:localhost:%s", port)
-		if err := http.ListenAndServe(address, handler); err != nil {
+		log.Printf("Server is starting on http:localhost:%d", cfg.Port)
+		if err := srv.Serve(ln); err != nil {
 			if !errors.Is(err, http.ErrServerClosed) {
 				log.Fatalf("Error starting server 💥: %v", err)
 			}
 		}
 	}()
 
+	if err := upg.Ready(); err != nil {
+		log.Fatalf("Error signalling readiness 💥: %v", err)
+	}
+
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-quit:
+	case <-upg.Exit():
+	}
 
 	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancel()
+
+	//* Once the listeners close, flush the bus and advise subscribers to reconnect,
+	//* reaching the new process on upgrades. The event streams only return then,
+	//* so srv.Shutdown waiting for them must not come first
+	drained := make(chan error, 1)
+	srv.RegisterOnShutdown(func() {
+		drained <- handler.Shutdown(shutdownCtx)
+	})
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server 💥: %v", err)
+	}
+	if err := <-drained; err != nil {
+		log.Printf("Error flushing pending events 💥: %v", err)
+	}
+	if err := shutdownMetrics(shutdownCtx); err != nil {
+		log.Printf("Error flushing metrics 💥: %v", err)
+	}
+}
+
+// serveMock runs the server over a seeded in-memory store for frontend
+// development. It skips .env, Postgres and upgrades, so it starts anywhere.
+func serveMock(seed uint64, tick time.Duration, port int) {
+	logging.Setup()
+
+	handler, err := mock.New(seed)
+	if err != nil {
+		log.Fatalf("Error seeding mock server 💥: %v", err)
+	}
+	stop := make(chan struct{})
+	if tick > 0 {
+		go handler.Run(tick, stop)
+	}
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler}
+
+	go func() {
+		log.Printf("Mock server is starting on http:localhost:%d", port)
+		if err := srv.ListenAndServe(); err != nil {
+			if !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Error starting mock server 💥: %v", err)
+			}
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down mock server...")
+	close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drained := make(chan error, 1)
+	srv.RegisterOnShutdown(func() {
+		drained <- handler.Shutdown(ctx)
+	})
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down mock server 💥: %v", err)
+	}
+	if err := <-drained; err != nil {
+		log.Printf("Error flushing pending events 💥: %v", err)
+	}
 }
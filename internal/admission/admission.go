@@ -0,0 +1,182 @@
+// Package admission decides which requests get served when the service runs
+// hot. Routes belong to a class with its own timeout and concurrency cap,
+// and once the database pool is saturated only writes are admitted, so
+// posting questions keeps working while listings back off.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ClassRead      = "read"
+	ClassWrite     = "write"
+	ClassSubscribe = "subscribe"
+)
+
+const defaultSaturation = 0.9
+
+// ClassLimits are the limits of one class. A zero Timeout or MaxInFlight
+// means none.
+type ClassLimits struct {
+	Timeout     time.Duration
+	MaxInFlight int64
+	// Sheddable classes are turned away while the pool is saturated.
+	Sheddable bool
+}
+
+var defaultLimits = map[string]ClassLimits{
+	ClassRead:  {Timeout: 5 * time.Second, Sheddable: true},
+	ClassWrite: {Timeout: 10 * time.Second},
+	//? The subscribe handler lives as long as the socket, so it gets no timeout
+	ClassSubscribe: {Sheddable: true},
+}
+
+type class struct {
+	ClassLimits
+	inFlight atomic.Int64
+}
+
+type Controller struct {
+	classes map[string]*class
+	// utilization reports how busy the database pool is, from 0 to 1.
+	utilization func() float64
+	saturation  float64
+	retryAfter  string
+}
+
+func New(limits map[string]ClassLimits, utilization func() float64, saturation float64) *Controller {
+	c := &Controller{
+		classes:     make(map[string]*class, len(limits)),
+		utilization: utilization,
+		saturation:  saturation,
+		retryAfter:  "1",
+	}
+	for name, l := range limits {
+		c.classes[name] = &class{ClassLimits: l}
+	}
+
+	return c
+}
+
+// FromEnv reads WS_<CLASS>_TIMEOUT and WS_<CLASS>_MAX_INFLIGHT for the read,
+// write and subscribe classes, and WS_POOL_SATURATION, the pool utilization
+// from which sheddable classes are refused (0.9 by default).
+func FromEnv(utilization func() float64) *Controller {
+	limits := make(map[string]ClassLimits, len(defaultLimits))
+	for name, l := range defaultLimits {
+		prefix := "WS_" + strings.ToUpper(name)
+		if name != ClassSubscribe {
+			l.Timeout = durationFromEnv(prefix+"_TIMEOUT", l.Timeout)
+		}
+		l.MaxInFlight = intFromEnv(prefix+"_MAX_INFLIGHT", l.MaxInFlight)
+		limits[name] = l
+	}
+
+	saturation := defaultSaturation
+	if raw := os.Getenv("WS_POOL_SATURATION"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value > 1 {
+			slog.Warn("invalid WS_POOL_SATURATION, using default", "value", raw, "default", defaultSaturation)
+		} else {
+			saturation = value
+		}
+	}
+
+	return New(limits, utilization, saturation)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		slog.Warn("invalid "+key+", using default", "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func intFromEnv(key string, fallback int64) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		slog.Warn("invalid "+key+", using default", "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func (c *Controller) saturated() bool {
+	return c.utilization != nil && c.utilization() >= c.saturation
+}
+
+// Middleware admits the requests of a class, answering 503 with Retry-After
+// to those over its cap or shed for a saturated pool.
+func (c *Controller) Middleware(name string) func(http.Handler) http.Handler {
+	cl, ok := c.classes[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown admission class %q", name))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.serve(cl, next, w, r)
+		})
+	}
+}
+
+// ByMethod classes GET and HEAD requests as reads and everything else as writes.
+func (c *Controller) ByMethod(next http.Handler) http.Handler {
+	read, write := c.classes[ClassRead], c.classes[ClassWrite]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cl := write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			cl = read
+		}
+		c.serve(cl, next, w, r)
+	})
+}
+
+func (c *Controller) serve(cl *class, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if cl.Sheddable && c.saturated() {
+		c.reject(w)
+		return
+	}
+
+	if n := cl.inFlight.Add(1); cl.MaxInFlight > 0 && n > cl.MaxInFlight {
+		cl.inFlight.Add(-1)
+		c.reject(w)
+		return
+	}
+	defer cl.inFlight.Add(-1)
+
+	if cl.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), cl.Timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func (c *Controller) reject(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", c.retryAfter)
+	http.Error(w, "server is busy", http.StatusServiceUnavailable)
+}
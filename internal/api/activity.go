@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// activityBuckets maps the accepted bucket sizes to date_trunc units.
+var activityBuckets = map[string]string{
+	"1m": "minute",
+	"1h": "hour",
+	"1d": "day",
+}
+
+// handleGetRoomActivity returns message and reaction counts per time bucket,
+// for activity sparklines. Empty buckets are left out.
+func (h apiHandler) handleGetRoomActivity(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "1m"
+	}
+	unit, ok := activityBuckets[bucket]
+	if !ok {
+		http.Error(w, "invalid bucket, expected 1m, 1h or 1d", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.q.GetRoomActivity(r.Context(), pg.GetRoomActivityParams{Unit: unit, RoomID: roomID})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get room activity", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type activityBucket struct {
+		Start     time.Time `json:"start"`
+		Messages  int64     `json:"messages"`
+		Reactions int64     `json:"reactions"`
+	}
+	type response struct {
+		RoomID  string           `json:"room_id"`
+		Bucket  string           `json:"bucket"`
+		Buckets []activityBucket `json:"buckets"`
+	}
+
+	res := response{RoomID: roomID.String(), Bucket: bucket, Buckets: make([]activityBucket, 0, len(rows))}
+	for _, row := range rows {
+		res.Buckets = append(res.Buckets, activityBucket{Start: row.BucketStart.UTC(), Messages: row.Messages, Reactions: row.Reactions})
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/logging"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// adminTokenHashFromEnv hashes WS_ADMIN_TOKEN, empty when unset.
+func adminTokenHashFromEnv() string {
+	token := os.Getenv("WS_ADMIN_TOKEN")
+	if token == "" {
+		return ""
+	}
+
+	return utils.HashToken(token)
+}
+
+// requireAdmin accepts the WS_ADMIN_TOKEN bearer token or one issued through
+// the bootstrap flow.
+func (h apiHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := utils.ParseBearerToken(r)
+		if token == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !utils.MatchTokenHash(token, h.adminTokenHash) {
+			_, err := h.q.GetAdminCredentialByHash(r.Context(), utils.HashToken(token))
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				helpers.LogErrorAndRespond(w, "failed to get admin credential", err, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h apiHandler) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.writeLogLevel(w)
+}
+
+func (h apiHandler) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	type _body struct {
+		Level string `json:"level"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := logging.Level.UnmarshalText([]byte(body.Level)); err != nil {
+		http.Error(w, "invalid log level", http.StatusBadRequest)
+		return
+	}
+
+	h.writeLogLevel(w)
+}
+
+func (h apiHandler) writeLogLevel(w http.ResponseWriter) {
+	type response struct {
+		Level string `json:"level"`
+	}
+
+	data, err := json.Marshal(response{Level: logging.Level.Level().String()})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -4,72 +4,354 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/admission"
+	"github.com/luiz504/week-tech-go-server/internal/api/docs"
+	"github.com/luiz504/week-tech-go-server/internal/chaos"
+	"github.com/luiz504/week-tech-go-server/internal/chatbridge"
+	"github.com/luiz504/week-tech-go-server/internal/clientip"
+	"github.com/luiz504/week-tech-go-server/internal/config"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/export"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/guard"
 	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/jobs"
+	"github.com/luiz504/week-tech-go-server/internal/language"
+	"github.com/luiz504/week-tech-go-server/internal/logging"
 	"github.com/luiz504/week-tech-go-server/internal/mappers"
+	"github.com/luiz504/week-tech-go-server/internal/metering"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/policy"
+	"github.com/luiz504/week-tech-go-server/internal/privacy"
+	"github.com/luiz504/week-tech-go-server/internal/quota"
+	"github.com/luiz504/week-tech-go-server/internal/redact"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/signedurl"
+	"github.com/luiz504/week-tech-go-server/internal/store"
 	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+	"github.com/luiz504/week-tech-go-server/internal/toxicity"
+	"github.com/luiz504/week-tech-go-server/internal/translate"
+	"github.com/luiz504/week-tech-go-server/internal/tts"
 	"github.com/luiz504/week-tech-go-server/internal/utils"
+	"github.com/luiz504/week-tech-go-server/internal/validation"
 )
 
 type apiHandler struct {
-	q           *pg.Queries
-	r           *chi.Mux
-	upgrader    websocket.Upgrader
-	subscribers map[string]map[*websocket.Conn]context.CancelFunc
-	mu          *sync.Mutex
+	q            store.Store
+	r            *chi.Mux
+	upgrader     websocket.Upgrader
+	subscribers  map[string]map[clientConn]*subscriber
+	waiting      map[string][]clientConn
+	mu           *sync.Mutex
+	applause     *applauseMeter
+	bus          *eventBus
+	writeQueue   int
+	leaderboards *leaderboards
+	usage        *metering.Meter
+	quotas       quota.Limits
+	stripe       stripeConfig
+	keyring      *crypto.Keyring
+	// redactor masks personal data in exports and mirrored chat messages.
+	redactor     *redact.Redactor
+	chaos        *chaos.Injector
+	tts          *tts.Queue
+	translator   translate.Provider
+	translations *translate.Queue
+	scorer       *toxicity.Scorer
+	jobs         *jobs.Runner
+	leader       *jobs.Elector
+	chatBridges  *chatBridges
+	youtube      *chatbridge.YouTube
+	twitch       *chatbridge.Twitch
+	matrix       *chatbridge.Matrix
+	irc          *chatbridge.IRC
+	// orgConnections counts the live sockets of each organization's rooms.
+	orgConnections map[uuid.UUID]int
+	roomPolicy     *policy.Engine
+	sessions       *session.Signer
+	links          *signedurl.Signer
+	editWindow     time.Duration
+	eventLog       eventLogRetention
+	failover       *failover
+	webhooks       webhookConfig
+	outbox         *eventOutbox
+	docs           *docs.Spec
+	// adminTokenHash is the WS_ADMIN_TOKEN break-glass credential for /admin,
+	// empty when unset. Bootstrapped credentials live in the database.
+	adminTokenHash string
+	bootstrap      *bootstrapState
+	// replayLimit is how many missed events a reconnecting subscriber gets
+	// replayed at most, beyond which it's told to resync.
+	replayLimit int
+}
+
+// Handler serves the API and owns the background work behind it.
+type Handler interface {
+	http.Handler
+	Shutdown(ctx context.Context) error
 }
 
 func (h apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.r.ServeHTTP(w, r)
 }
 
-func NewHandler(q *pg.Queries) http.Handler {
+func NewHandler(s store.Store, cfg config.Config) Handler {
 	a := apiHandler{
-		q:           q,
-		upgrader:    websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}, // TODO: allow only production
-		subscribers: make(map[string]map[*websocket.Conn]context.CancelFunc),
-		mu:          &sync.Mutex{},
+		q:            s,
+		upgrader:     websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}, // TODO: allow only production
+		subscribers:  make(map[string]map[clientConn]*subscriber),
+		waiting:      make(map[string][]clientConn),
+		mu:           &sync.Mutex{},
+		applause:     newApplauseMeter(),
+		bus:          newEventBus(),
+		writeQueue:   positiveIntFromEnv("WS_WRITE_QUEUE_SIZE", defaultWriteQueueSize),
+		leaderboards: newLeaderboards(),
+		usage:        metering.New(),
+		quotas:       quota.LimitsFromEnv(),
+		stripe:       stripeFromEnv(),
+		keyring:      keyringFromEnv(),
+		redactor:     cfg.Redactor,
+		chaos:        chaos.FromEnv(),
+		translations: translate.NewQueue(),
+		chatBridges:  newChatBridges(),
+		twitch:       chatbridge.NewTwitch(),
+		irc:          chatbridge.IRCFromEnv(),
+
+		orgConnections: make(map[uuid.UUID]int),
+		roomPolicy:     policy.FromEnv(),
+		sessions:       session.SignerFromEnv(),
+		links:          signedurl.SignerFromEnv(),
+		editWindow:     editWindowFromEnv(),
+		eventLog:       eventLogRetentionFromEnv(),
+		replayLimit:    positiveIntFromEnv("WS_REPLAY_MAX_EVENTS", defaultReplayMaxEvents),
+		failover:       failoverFromEnv(),
+		webhooks:       webhookConfigFromEnv(),
+		outbox:         eventOutboxFromEnv(),
+		docs:           newSpec(),
+
+		adminTokenHash: adminTokenHashFromEnv(),
+	}
+	//? A Config not from Load, as in tests and mock mode, still redacts personal data
+	if a.redactor == nil {
+		a.redactor = redact.New(true)
+	}
+	a.bootstrap = newBootstrap(a.q)
+	a.tts = tts.FromEnv(a.ttsAPIKey)
+	a.translator = translate.FromEnv(a.translateAPIKey)
+	a.scorer = toxicity.FromEnv(a.toxicityAPIKey)
+	a.youtube = chatbridge.YouTubeFromEnv(a.youtubeAPIKey)
+	a.matrix = chatbridge.MatrixFromEnv(a.matrixAccessToken)
+	a.jobs = jobs.FromEnv(a.q)
+	a.leader = jobs.ElectorFromEnv(a.q, leaderLockName)
+	a.jobs.ScheduleAsLeader(a.leader)
+	a.jobs.Register(jobKindEventReport, a.runEventReportJob)
+	a.jobs.Register(jobKindPruneRoomEvents, a.runPruneRoomEventsJob)
+	if a.failover.standby.Load() {
+		a.jobs.Pause()
 	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID, middleware.Recoverer, middleware.Logger)
+	r.Use(
+		middleware.RequestID,
+		metrics.Middleware,
+		//* Resolve the client before anonymizing it
+		clientip.Middleware(clientIPFromEnv()),
+		privacy.Middleware(privacyFromEnv()),
+		middleware.Recoverer,
+		middleware.Logger,
+	)
+	r.Use(a.rejectWritesOnStandby)
+	guards := guard.LimitsFromEnv()
+	r.Use(guard.MaxBytes(guards.MaxBodyBytes), guard.InFlight(guards.MaxInFlight, guards.RetryAfterSeconds))
+	r.Use(chaos.Middleware(a.chaos))
 	r.Use(
 		cors.Handler(
 			cors.Options{
-				AllowedOrigins:   []string{"http://*", "https://*"}, // TODO: allow only production
-				AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-				AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+				AllowedOrigins: cfg.CORSOrigins,
+				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+				AllowedHeaders: []string{
+					"Accept", "Authorization", "Content-Type", "X-CSRF-Token",
+					session.HeaderName, tenant.HeaderName,
+					tenant.HeaderKeyID, tenant.HeaderTimestamp, tenant.HeaderNonce, tenant.HeaderSignature,
+				},
 				ExposedHeaders:   []string{"Link"},
 				AllowCredentials: false,
 				MaxAge:           300,
 			},
 		),
 	)
+	r.Use(session.Middleware(a.sessions))
+	r.Use(
+		tenant.SignedMiddleware(a.resolveSigningKey, signatureToleranceFromEnv()),
+		tenant.Middleware(a.resolveAPIKey),
+		metering.Middleware(a.usage),
+	)
+
+	admit := admission.FromEnv(a.q.Utilization)
+
+	//* Sockets and event streams share the per-IP budget of long-lived connections
+	perIPSockets := guard.PerIPSockets(guards.MaxSocketsPerIP)
+	r.With(
+		perIPSockets,
+		admit.Middleware(admission.ClassSubscribe),
+		a.enforceConnectionQuota,
+	).Get("/subscribe/{room_id}", a.handleSubscribeToRoom)
+	r.With(
+		perIPSockets,
+		admit.Middleware(admission.ClassSubscribe),
+		a.enforceConnectionQuota,
+	).Get("/subscribe/{room_id}/sse", a.handleSubscribeToRoomSSE)
+
+	r.Handle("/metrics", metrics.Handler())
+
+	r.Post("/webhooks/stripe", a.handleStripeWebhook)
+
+	r.Post("/admin/bootstrap", a.handleBootstrap)
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(a.requireAdmin)
+
+		r.Get("/log-level", a.handleGetLogLevel)
+		r.Put("/log-level", a.handleSetLogLevel)
+
+		r.Get("/failover", a.handleGetFailover)
+		r.Post("/failover/promote", a.handlePromote)
+		r.Post("/failover/demote", a.handleDemote)
+
+		r.Post("/webhooks/redrive", a.handleRedriveWebhooks)
+		r.Get("/dead-letters", a.handleGetDeadLetters)
+		r.Post("/dead-letters/{dead_letter_id}/retry", a.handleRetryDeadLetter)
+		r.Delete("/dead-letters/{dead_letter_id}", a.handleDiscardDeadLetter)
+
+		r.Get("/connections", a.handleGetConnections)
+		r.Post("/rooms/{room_id}/shed", a.handleShedRoomSubscribers)
+
+		r.Post("/organizations", a.handleCreateOrganization)
+		r.Post("/organizations/{organization_id}/api-keys", a.handleCreateAPIKey)
+		r.Post("/organizations/{organization_id}/api-keys/{api_key_id}/signing-secret", a.handleCreateSigningSecret)
 
-	r.Get("/subscribe/{room_id}", a.handleSubscribeToRoom)
+		r.Get("/secrets", a.handleGetSecrets)
+		r.Post("/secrets/rotate", a.handleRotateSecrets)
+		r.Put("/secrets/{name}", a.handlePutSecret)
+		r.Delete("/secrets/{name}", a.handleDeleteSecret)
+	})
+
+	r.With(admit.ByMethod).Get("/public/rooms/{code}", a.handleGetPublicRoom)
+	r.With(admit.ByMethod).Get("/overlay/rooms/{code}/top.json", a.handleGetOverlayTop)
+	r.With(perIPSockets, admit.Middleware(admission.ClassSubscribe)).Get("/overlay/rooms/{code}/top/stream", a.handleStreamOverlayTop)
+	r.With(signedurl.Middleware(a.links), admit.ByMethod).Get("/downloads/rooms/{room_id}/export", a.handleDownloadExport)
+	r.With(signedurl.Middleware(a.links), admit.ByMethod).Get("/downloads/jobs/{job_id}", a.handleDownloadJob)
 
 	r.Route("/api", func(r chi.Router) {
+		r.Use(admit.ByMethod)
+
+		r.Post("/sessions", a.handleCreateSession)
+		r.Get("/schemas", a.handleGetSchemas)
+		r.Get("/schemas/{kind}", a.handleGetSchema)
+		r.Method(http.MethodGet, "/openapi.json", a.docs.Handler())
+		r.Method(http.MethodGet, "/docs", a.docs.UIHandler(openAPIPath))
+		r.Get("/usage", a.handleGetUsage)
+		r.Get("/dashboard", a.handleGetDashboard)
+
+		r.Route("/events", func(r chi.Router) {
+			r.Post("/", a.handleCreateEvent)
+			r.Get("/", a.handleGetEvents)
+
+			r.Get("/{event_id}", a.handleGetEvent)
+			r.Delete("/{event_id}", a.handleDeleteEvent)
+			r.Get("/{event_id}/stats", a.handleGetEventStats)
+			r.Get("/{event_id}/report", a.handleCreateEventReport)
+			r.Get("/{event_id}/report/{job_id}", a.handleGetEventReport)
+			r.Post("/{event_id}/rooms/import", a.handleImportEventRooms)
+			r.Get("/{event_id}/room-defaults", a.handleGetEventRoomDefaults)
+			r.Put("/{event_id}/room-defaults", a.handlePutEventRoomDefaults)
+			r.Post("/{event_id}/room-defaults/apply", a.handleApplyEventRoomDefaults)
+
+			r.Post("/{event_id}/tracks", a.handleCreateTrack)
+			r.Delete("/{event_id}/tracks/{track_id}", a.handleDeleteTrack)
+			r.Put("/{event_id}/tracks/{track_id}/rooms/{room_id}", a.handlePutTrackRoom)
+			r.Delete("/{event_id}/tracks/{track_id}/rooms/{room_id}", a.handleDeleteTrackRoom)
+		})
+
 		r.Route("/rooms", func(r chi.Router) {
-			r.Post("/", a.handleCreateRoom)
+			r.With(a.enforceRoomQuota).Post("/", a.handleCreateRoom)
 			r.Get("/", a.handleGetRooms)
 
+			r.Get("/{room_id}", a.handleGetRoom)
+			r.Get("/{room_id}/reactions/summary", a.handleGetRoomReactionsSummary)
+			r.Get("/{room_id}/stats", a.handleGetRoomStats)
+			r.Get("/{room_id}/activity", a.handleGetRoomActivity)
+			r.Get("/{room_id}/related", a.handleGetRelatedRooms)
+			r.With(a.requireRoomHost("export it")).Get("/{room_id}/export", a.handleExportRoom)
+			r.With(a.requireRoomHost("export the event log")).Get("/{room_id}/events/export", a.handleExportRoomEvents)
+			r.With(a.requireRoomHost("export it")).Post("/{room_id}/export/links", a.handleCreateExportLink)
+			r.With(a.requireRoomHost("archive or restore this room")).Post("/{room_id}/archive", a.handleArchiveRoom)
+			r.With(a.requireRoomHost("archive or restore this room")).Post("/{room_id}/unarchive", a.handleUnarchiveRoom)
+			r.With(a.requireRoomHost("close or reopen this room")).Patch("/{room_id}/close", a.handleCloseRoom)
+			r.With(a.requireRoomHost("close or reopen this room")).Patch("/{room_id}/reopen", a.handleReopenRoom)
+			r.With(a.requireRoomHost("transfer this room")).Post("/{room_id}/transfer", a.handleCreateRoomTransfer)
+			r.Post("/{room_id}/transfer/accept", a.handleAcceptRoomTransfer)
+
+			r.With(a.requireRoomHost("manage the overlay")).Post("/{room_id}/overlay/token", a.handleCreateOverlayToken)
+			r.With(a.requireRoomHost("manage captions")).Post("/{room_id}/captions/token", a.handleCreateCaptionToken)
+			r.With(a.requireRoomHost("manage captions")).Delete("/{room_id}/captions/token", a.handleDeleteCaptionToken)
+			r.Post("/{room_id}/captions", a.handleIngestCaption)
+			r.Get("/{room_id}/captions", a.handleGetRoomCaptions)
+			r.With(a.requireRoomHost("import webinar questions")).Post("/{room_id}/webinar-questions/token", a.handleCreateWebinarToken)
+			r.With(a.requireRoomHost("import webinar questions")).Delete("/{room_id}/webinar-questions/token", a.handleDeleteWebinarToken)
+			r.Post("/{room_id}/webinar-questions", a.handleIngestWebinarQuestions)
+			r.With(a.enforceMessageQuota).Post("/{room_id}/ingest", a.handleIngest)
+			r.With(a.requireRoomHost("manage the ingest webhook")).Get("/{room_id}/ingest/hook", a.handleGetIngestHook)
+			r.With(a.requireRoomHost("manage the ingest webhook")).Put("/{room_id}/ingest/hook", a.handlePutIngestHook)
+			r.With(a.requireRoomHost("manage the ingest webhook")).Delete("/{room_id}/ingest/hook", a.handleDeleteIngestHook)
+			r.With(a.requireRoomHost("manage the webhook")).Get("/{room_id}/webhook", a.handleGetRoomWebhook)
+			r.With(a.requireRoomHost("manage the webhook")).Put("/{room_id}/webhook", a.handlePutRoomWebhook)
+			r.With(a.requireRoomHost("manage the webhook")).Delete("/{room_id}/webhook", a.handleDeleteRoomWebhook)
+			r.With(a.requireRoomHost("manage the chat bridge")).Get("/{room_id}/chat-bridge", a.handleGetChatBridge)
+			r.With(a.requireRoomHost("manage the chat bridge")).Put("/{room_id}/chat-bridge", a.handlePutChatBridge)
+			r.With(a.requireRoomHost("manage the chat bridge")).Delete("/{room_id}/chat-bridge", a.handleDeleteChatBridge)
+			r.With(a.requireRoomHost("manage the overlay")).Delete("/{room_id}/overlay/token", a.handleDeleteOverlayToken)
+
+			r.With(a.requireRoomHost("review its messages")).Get("/{room_id}/moderation/queue", a.handleGetModerationQueue)
+
+			r.With(a.requireRoomHost("manage saved views")).Get("/{room_id}/views", a.handleGetSavedViews)
+			r.With(a.requireRoomHost("manage saved views")).Put("/{room_id}/views/{name}", a.handlePutSavedView)
+			r.With(a.requireRoomHost("manage saved views")).Delete("/{room_id}/views/{name}", a.handleDeleteSavedView)
+
 			r.Route("/{room_id}/messages", func(r chi.Router) {
-				r.Post("/", a.handleCreateRoomMessage)
+				r.With(a.enforceMessageQuota).Post("/", a.handleCreateRoomMessage)
 				r.Get("/", a.handleGetRoomMessages)
+				r.Get("/mine", a.handleGetMyRoomMessages)
+				r.With(a.requireRoomHost("search messages")).Get("/search", a.handleSearchRoomMessages)
+				r.With(a.requireRoomHost("moderate messages")).Post("/bulk", a.handleBulkModerateMessages)
 
 				r.Route("/{message_id}", func(r chi.Router) {
 					r.Get("/", a.handleGetRoomMessage)
+					r.Put("/", a.handleEditMessage)
+					r.Delete("/", a.handleDeleteMessage)
 					r.Patch("/react", a.handleReactToMessage)
 					r.Delete("/react", a.handleRemoveReactionFromMessage)
-					r.Patch("/answer", a.handleMarkMessageAsAnswered)
+					r.With(a.requireRoomHost("answer its questions")).Patch("/answer", a.handleMarkMessageAsAnswered)
+					r.Post("/report", a.handleReportMessage)
+					r.With(a.requireRoomHost("review its messages")).Post("/review", a.handleReviewMessage)
+					r.Get("/translate", a.handleTranslateMessage)
 				})
 
 			})
@@ -77,10 +359,36 @@ func NewHandler(q *pg.Queries) http.Handler {
 	})
 
 	a.r = r
+	if err := describeAPI(a.docs, r); err != nil {
+		slog.Error("failed to describe the api", "error", err)
+	}
+
+	go a.runEventBus()
+	go a.runApplauseMeter()
+	go a.runLeaderboards()
+	go a.runUsageMeter()
+	go a.tts.Run(a.bus.ctx, a.publishAnswerAudio)
+	go a.translations.Run(a.bus.ctx, a.broadcastTranslations)
+	go a.jobs.Run(a.bus.ctx)
+	go a.leader.Run(a.bus.ctx)
+	if a.eventLog.enabled() {
+		go a.jobs.Schedule(a.bus.ctx, jobKindPruneRoomEvents, a.eventLog.Interval)
+	}
+	go a.runPeakSampler()
+	go a.runConnectionReaper()
+	go a.runChatBridges()
+	go a.runStandbyFollower()
+	go a.runWebhooks()
+	go a.runDeadLetterMetrics()
 
 	return a
 }
 
+const (
+	maxAnswerLength      = 1000
+	maxDescriptionLength = 1000
+)
+
 // * WS Controllers
 func (h apiHandler) handleSubscribeToRoom(w http.ResponseWriter, r *http.Request) {
 
@@ -90,9 +398,9 @@ func (h apiHandler) handleSubscribeToRoom(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = h.q.GetRoom(r.Context(), roomId)
+	room, err := h.q.GetRoom(r.Context(), roomId)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "room not found", http.StatusNotFound)
 			return
 		}
@@ -100,102 +408,311 @@ func (h apiHandler) handleSubscribeToRoom(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	c, err := h.upgrader.Upgrade(w, r, nil)
+	after, replay, err := replayAfter(r)
+	if err != nil {
+		http.Error(w, "invalid last_event_id", http.StatusBadRequest)
+		return
+	}
+
+	//* Echo the request ID so clients can quote it when reporting issues
+	requestID := middleware.GetReqID(r.Context())
+	ws, err := h.upgrader.Upgrade(w, r, http.Header{middleware.RequestIDHeader: {requestID}})
 	if err != nil {
 		msg := "failed to upgrade connection"
 		helpers.LogErrorAndRespond(w, msg, err, msg, http.StatusBadRequest)
 		return
 	}
 
-	defer c.Close()
+	defer ws.Close()
+	c := wsConn{ws}
 
 	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := newRoomSubscriber(r, room, requestID, cancel)
+	sub.pongDeadline = time.Now().Add(pongWait)
+	_ = ws.SetReadDeadline(sub.pongDeadline)
+	ws.SetPongHandler(func(string) error {
+		deadline := time.Now().Add(pongWait)
+		h.mu.Lock()
+		sub.pongDeadline = deadline
+		h.mu.Unlock()
+		return ws.SetReadDeadline(deadline)
+	})
 
-	h.mu.Lock()
-	if _, ok := h.subscribers[roomId.String()]; !ok {
-		h.subscribers[roomId.String()] = make(map[*websocket.Conn]context.CancelFunc)
-	}
-	h.subscribers[roomId.String()][c] = cancel
+	written := h.startWriter(ctx, c, sub)
 
+	h.mu.Lock()
+	h.joinLocked(roomId.String(), c, sub)
+	//? A waiting subscriber gets no events until admitted, nor the missed ones
+	replay = replay && !sub.waiting
+	sub.replaying = replay
 	h.mu.Unlock()
+	if replay {
+		h.replay(ctx, sub, roomId, after)
+	}
 
-	slog.Info("new subscriber connected", "room_id", roomId.String(), "client_ip", r.RemoteAddr)
-	<-ctx.Done()
+	go h.readCommands(c, roomId.String(), sub)
+
+	sub.log.Info(logging.MsgSubscriberConnected, "client_ip", r.RemoteAddr)
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+			//* Control frames may be written alongside the writer's frames
+			h.mu.Lock()
+			closed := sub.closed
+			h.mu.Unlock()
+			if !closed && c.ping() != nil {
+				h.mu.Lock()
+				sub.closed = true
+				h.mu.Unlock()
+				done = true
+			}
+		}
+	}
 	//? Will be called when the client closes the connection
 	h.mu.Lock()
-
-	delete(h.subscribers[roomId.String()], c)
-
+	h.leaveLocked(roomId.String(), c)
+	h.accountConnectionLocked(sub, time.Now())
 	h.mu.Unlock()
+	//? Closing the socket fails a write stuck on a client that stopped reading
+	cancel()
+	_ = ws.Close()
+	<-written
+	sub.log.Info(logging.MsgSubscriberDisconnected, "client_ip", r.RemoteAddr)
 }
 
 const (
 	MessageKindMessageCreated           = "message_created"
 	MessageKindMessageAnswered          = "message_answered"
+	MessageKindMessageUnanswered        = "message_unanswered"
 	MessageKindMessageReactionIncreased = "message_reaction_increased"
 	MessageKindMessageReactionDecreased = "message_reaction_decreased"
 )
 
 type MessageMessageCreated struct {
-	ID      string `json:"id"`
-	Message string `json:"message"`
+	ID         string            `json:"id"`
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields"`
+	AuthorName string            `json:"author_name,omitempty"`
 }
 type MessageMessageAnswered struct {
-	ID     string `json:"id"`
-	RoomID string `json:"room_id"`
+	ID         string `json:"id"`
+	RoomID     string `json:"room_id"`
+	AnswerText string `json:"answer_text,omitempty"`
+	// AudioURL arrives in a second message_answered once the answer was
+	// read out by the TTS provider, when one is configured.
+	AudioURL string `json:"audio_url,omitempty"`
+	// VideoTimestamp is the second of the stream at VideoURL the answer was
+	// given, VideoLink jumping right to it.
+	VideoURL       string `json:"video_url,omitempty"`
+	VideoTimestamp *int32 `json:"video_timestamp,omitempty"`
+	VideoLink      string `json:"video_link,omitempty"`
+	// Version is the message's after the change, absent from the second
+	// message_answered carrying the audio.
+	Version int32 `json:"version,omitempty"`
 }
 
 type MessageMessageReactionUpdated struct {
 	ID     string `json:"id"`
 	RoomID string `json:"room_id"`
+	Kind   string `json:"kind"`
 	Count  int64  `json:"count"`
 }
 type Message struct {
-	Kind   string `json:"kind"`
-	Value  any    `json:"value"`
-	RoomID string `json:"-"`
+	EventID int64  `json:"event_id,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Kind    string `json:"kind"`
+	Value   any    `json:"value"`
+	RoomID  string `json:"-"`
+}
+
+// notifyClients sequences msg in its room, logs it and broadcasts it. It
+// reports false when msg couldn't be sequenced and so wasn't sent. Only the
+// event bus calls it, so events are sequenced in order without h.mu held
+// over the round trips.
+func (h apiHandler) notifyClients(ctx context.Context, msg Message) bool {
+	//* The sequence is bumped even without subscribers so REST snapshots stay in sync
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		slog.Error("failed to parse room id for event", "room_id", msg.RoomID, "error", err)
+		return false
+	}
+	eventID, err := h.q.IncrementRoomEventSeq(ctx, roomID)
+	if err != nil {
+		slog.Error("failed to increment room event sequence", "room_id", msg.RoomID, "error", err)
+		return false
+	}
+	msg.EventID = eventID
+	h.recordEvent(ctx, roomID, msg)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcastLocked(msg)
+	h.leaderboards.touch(msg.RoomID)
+
+	return true
 }
 
-func (h apiHandler) notifyClients(msg Message) {
+// notifyClientsEphemeral broadcasts a transient event outside the room event sequence.
+func (h apiHandler) notifyClientsEphemeral(msg Message) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	h.broadcastLocked(msg)
+}
+
+// broadcastLocked must be called with h.mu held.
+func (h apiHandler) broadcastLocked(msg Message) {
+	if msg.Channel == "" {
+		msg.Channel = ChannelQuestions
+	}
+
 	subscribers, ok := h.subscribers[msg.RoomID]
 	if !ok || len(subscribers) == 0 {
 		return
 	}
 
-	for conn, cancel := range subscribers {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("failed to send message to client", "error", err)
-			cancel()
-			//* this call will trigger the handleSubscribeToRoom cleanup
+	start := time.Now()
+	frame := &outboundFrame{msg: msg}
+	queued := 0
+	for _, sub := range subscribers {
+		if sub.waiting {
+			continue
 		}
+		//? Held back in order for replay to merge with the logged events
+		if sub.replaying {
+			sub.backlog = append(sub.backlog, frame)
+			continue
+		}
+		if !sub.channels[msg.Channel] {
+			continue
+		}
+		h.queueLocked(sub, frame)
+		queued++
 	}
+	metrics.FanoutSeconds.Observe(time.Since(start).Seconds())
+	metrics.FanoutSize.Observe(float64(queued))
 }
 
 // * HTTP Controllers
+
+type createRoomRequest struct {
+	Theme          string       `json:"theme"`
+	Description    string       `json:"description"`
+	Fields         forms.Schema `json:"fields"`
+	PostingMode    string       `json:"posting_mode"`
+	MaxSubscribers int32        `json:"max_subscribers"`
+	TranslateTo    []string     `json:"translate_to"`
+	TrackID        string       `json:"track_id"`
+}
+
+type createRoomResponse struct {
+	ID         string `json:"id"`
+	Code       string `json:"code"`
+	OwnerToken string `json:"owner_token"`
+}
+
 func (h apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
-	type _body struct {
-		Theme string `json:"theme"`
-	}
-	var body _body
+	var body createRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
 
-	roomId, err := h.q.InsertRoom(r.Context(), body.Theme)
+	//* Rooms scheduled in a track start from the defaults of its event
+	var trackID uuid.NullUUID
+	if body.TrackID != "" {
+		key, ok := tenant.FromContext(r.Context())
+		if !ok {
+			http.Error(w, "an api key is required", http.StatusUnauthorized)
+			return
+		}
+		id, err := uuid.Parse(body.TrackID)
+		if err != nil {
+			helpers.RespondValidationErrors(w, []forms.FieldError{{Field: "track_id", Message: "must be a uuid"}})
+			return
+		}
+		raw, err := h.q.GetOrganizationTrackRoomDefaults(r.Context(), pg.GetOrganizationTrackRoomDefaultsParams{
+			ID:             id,
+			OrganizationID: key.OrganizationID,
+		})
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, "track not found", http.StatusNotFound)
+				return
+			}
+			helpers.LogErrorAndRespond(w, "failed to get track", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		parseRoomDefaults(raw).inherit(&body.PostingMode, &body.MaxSubscribers, &body.TranslateTo)
+		trackID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	//? Policy violations carry the rule that was broken, they're answered on their own
+	input := policy.RoomInput{Theme: body.Theme}
+	if violations := h.roomPolicy.Apply(&input); len(violations) > 0 {
+		helpers.RespondValidationErrors(w, violations)
+		return
+	}
+	body.Theme = input.Theme
+
+	if body.PostingMode == "" {
+		body.PostingMode = forms.PostingModeOptional
+	}
+	translateTo, ok := parseTranslateTargets(body.TranslateTo)
+
+	var v validation.Validator
+	v.Text("theme", &body.Theme, 1, policy.DefaultMaxThemeLength)
+	v.Text("description", &body.Description, 0, maxDescriptionLength)
+	v.Check(forms.IsPostingMode(body.PostingMode), "posting_mode", "must be optional, named or anonymous")
+	v.Check(body.MaxSubscribers >= 0, "max_subscribers", "must not be negative")
+	v.Check(ok, "translate_to", fmt.Sprintf("must be at most %d valid language tags", translate.MaxTargets))
+	v.Add(body.Fields.Check()...)
+	if v.Respond(w) {
+		return
+	}
+	if body.Fields == nil {
+		body.Fields = forms.Schema{}
+	}
+	formSchema, err := json.Marshal(body.Fields)
 	if err != nil {
-		helpers.LogErrorAndRespond(w, "failed to insert room", err, "something went wrong", http.StatusInternalServerError)
+		helpers.LogErrorAndRespond(w, "failed to marshal form schema", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	ownerToken, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate owner token", err, "something went wrong", http.StatusInternalServerError)
 		return
 	}
 
-	type response struct {
-		ID string `json:"id"`
+	//* Rooms created with an API key belong to its organization
+	var organizationID uuid.NullUUID
+	if key, ok := tenant.FromContext(r.Context()); ok {
+		organizationID = uuid.NullUUID{UUID: key.OrganizationID, Valid: true}
+	}
+
+	room, err := h.q.InsertRoom(r.Context(), pg.InsertRoomParams{
+		Theme:          body.Theme,
+		OwnerTokenHash: utils.HashToken(ownerToken),
+		FormSchema:     formSchema,
+		PostingMode:    body.PostingMode,
+		MaxSubscribers: body.MaxSubscribers,
+		OrganizationID: organizationID,
+		TranslateTo:    translateTo,
+		Description:    body.Description,
+		TrackID:        trackID,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert room", err, "something went wrong", http.StatusInternalServerError)
+		return
 	}
 
-	data, err := json.Marshal(response{ID: roomId.String()})
+	data, err := json.Marshal(createRoomResponse{ID: room.ID.String(), Code: room.Code, OwnerToken: ownerToken})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -210,12 +727,86 @@ func (h apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type getRoomsResponse struct {
+	Rooms      []pg.GetRoomsRow `json:"rooms"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// handleGetRooms lists rooms newest first, a page at a time when ?limit or
+// ?cursor is given.
 func (h apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
-	// Todo:  Add Pagination
+	filter, ok := roomStatusFilter(r)
+	if !ok {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	p, ok := parsePage(r.URL.Query())
+	if !ok {
+		http.Error(w, "invalid limit or cursor", http.StatusBadRequest)
+		return
+	}
+
+	var rooms []pg.GetRoomsRow
+	var nextCursor string
+	if p.limit == 0 {
+		var err error
+		rooms, err = h.q.GetRooms(r.Context(), filter)
+		if err != nil {
+			http.Error(w, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		rows, err := h.q.GetRoomsPage(r.Context(), pg.GetRoomsPageParams{
+			IncludeArchived: filter.IncludeArchived,
+			Status:          filter.Status,
+			CursorCreatedAt: p.createdAt,
+			CursorID:        p.id,
+			PageSize:        p.limit,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to get rooms page", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		//* Both queries select the same columns, a page row is a listing row
+		rooms = make([]pg.GetRoomsRow, 0, len(rows))
+		for _, row := range rows {
+			rooms = append(rooms, pg.GetRoomsRow(row))
+		}
+		if len(rows) > 0 {
+			last := rows[len(rows)-1]
+			nextCursor = p.nextCursor(len(rows), last.CreatedAt, last.ID)
+		}
+	}
+
+	data, err := json.Marshal(getRoomsResponse{Rooms: rooms, NextCursor: nextCursor})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+type getRoomResponse struct {
+	Room       mappers.Room `json:"room"`
+	SavedViews []SavedView  `json:"saved_views,omitempty"`
+}
+
+func (h apiHandler) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	roomId, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
 
-	rooms, err := h.q.GetRooms(r.Context())
+	room, err := h.q.GetRoom(r.Context(), roomId)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "room not found", http.StatusNotFound)
 			return
 		}
@@ -223,11 +814,18 @@ func (h apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type response struct {
-		Rooms []pg.Room `json:"rooms"`
+	//* Hosts get their saved views along, so moderation picks up where it was left
+	var views []SavedView
+	if isRoomHost(r, room) {
+		saved, err := h.q.GetSavedViews(r.Context(), room.ID)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to get saved views", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		views = mapSavedViews(saved)
 	}
 
-	data, err := json.Marshal(response{Rooms: rooms})
+	data, err := json.Marshal(getRoomResponse{Room: mappers.MapRoom(room), SavedViews: views})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -241,6 +839,19 @@ func (h apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type createRoomMessageRequest struct {
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields"`
+	AuthorName string            `json:"author_name"`
+}
+
+type createRoomMessageResponse struct {
+	ID string `json:"id"`
+	// ConsistencyToken is passed to the next GET messages to be sure to see
+	// the message there, see HeaderConsistencyToken.
+	ConsistencyToken string `json:"consistency_token,omitempty"`
+}
+
 func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Request) {
 	roomId, err := utils.ParseUUIDParam(r, "room_id")
 	if err != nil {
@@ -248,36 +859,122 @@ func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	_, err = h.q.GetRoom(r.Context(), roomId)
+	room, err := h.q.GetRoom(r.Context(), roomId)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "room not found", http.StatusNotFound)
 			return
 		}
 		http.Error(w, "something went wrong", http.StatusInternalServerError)
 		return
 	}
-
-	type _body struct {
-		Message string `json:"message"`
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
 	}
-	var body _body
+	if rejectClosedRoom(w, room) {
+		return
+	}
+
+	var body createRoomMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
 
-	messageID, err := h.q.InsertMessage(r.Context(), pg.InsertMessageParams{RoomID: roomId, Message: body.Message})
+	schema, err := forms.ParseSchema(room.FormSchema)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to parse room form schema", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	var v validation.Validator
+	v.Text("message", &body.Message, 1, forms.DefaultMaxLength)
+	fields, fieldErrs := schema.Validate(body.Fields)
+	v.Add(fieldErrs...)
+	authorName, authorErrs := forms.ValidateAuthorName(room.PostingMode, body.AuthorName)
+	v.Add(authorErrs...)
+	if v.Respond(w) {
+		return
+	}
+	rawFields, err := json.Marshal(fields)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal message fields", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	var sessionID uuid.NullUUID
+	if id, ok := session.FromContext(r.Context()); ok {
+		sessionID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	//* Scored before the insert, so a message over the threshold is held before anyone sees it
+	var toxicityScore pgtype.Float4
+	var hiddenAt pgtype.Timestamptz
+	score, hold, scored := h.scorer.Check(r.Context(), body.Message)
+	if scored {
+		toxicityScore = pgtype.Float4{Float32: float32(score), Valid: true}
+	}
+	if hold {
+		hiddenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	//* The event is written along with the message, broadcast by the outbox once committed
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	messageID, err := tx.InsertMessage(r.Context(), pg.InsertMessageParams{
+		RoomID:     roomId,
+		Message:    body.Message,
+		Fields:     rawFields,
+		AuthorName: authorName,
+		SessionID:  sessionID,
+		Language:   language.Detect(body.Message),
+		Toxicity:   toxicityScore,
+		HiddenAt:   hiddenAt,
+	})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to insert message", err, "something went wrong", http.StatusInternalServerError)
 		return
 	}
 
-	type response struct {
-		ID string `json:"id"`
+	event := Message{
+		Kind:   MessageKindMessageCreated,
+		RoomID: roomId.String(),
+		Value: MessageMessageCreated{
+			ID:         messageID.String(),
+			Message:    body.Message,
+			Fields:     fields,
+			AuthorName: authorName,
+		}}
+	if hold {
+		event = Message{
+			Kind:    MessageKindMessageHeld,
+			Channel: ChannelBackstage,
+			RoomID:  roomId.String(),
+			Value: MessageMessageHeld{
+				ID:         messageID.String(),
+				RoomID:     roomId.String(),
+				Message:    body.Message,
+				AuthorName: authorName,
+				Toxicity:   score,
+			}}
 	}
+	if err := h.enqueueEvent(r.Context(), tx, event); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to enqueue event", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	h.outbox.notify()
+	token := h.writeConsistencyToken(w, r)
 
-	data, err := json.Marshal(response{ID: messageID.String()})
+	data, err := json.Marshal(createRoomMessageResponse{ID: messageID.String(), ConsistencyToken: token})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -291,24 +988,44 @@ func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	go h.notifyClients(Message{
-		Kind:   MessageKindMessageCreated,
-		RoomID: roomId.String(),
-		Value: MessageMessageCreated{
-			ID:      messageID.String(),
-			Message: body.Message,
-		}})
+	if !hold {
+		h.queueTranslations(room, messageID, body.Message)
+	}
+}
+
+type getRoomMessagesResponse struct {
+	RoomID      string                `json:"room_id"`
+	LastEventID int64                 `json:"last_event_id"`
+	Messages    []mappers.RoomMessage `json:"messages"`
+	NextCursor  string                `json:"next_cursor,omitempty"`
 }
 
+// handleGetRoomMessages lists the room's messages, everything at once or a
+// page at a time with ?limit and ?cursor. ?sort=recent pages newest first,
+// ?sort=reactions most reacted first; ?answered and the other filters of
+// parseMessageFilter narrow the list.
 func (h apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	roomId, err := utils.ParseUUIDParam(r, "room_id")
 	if err != nil {
 		http.Error(w, "invalid room id", http.StatusBadRequest)
 		return
 	}
-	messages, err := h.q.GetRoomMessages(r.Context(), roomId)
+
+	//* A replica may serve the listing, once it caught up with the client's last post
+	q, err := h.q.Reader(r.Context(), consistencyToken(r))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrInvalidToken) {
+			http.Error(w, "invalid consistency token", http.StatusBadRequest)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get reader", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	//? Read the sequence before the snapshot: resuming from it may repeat events, never skip them
+	lastEventID, err := q.GetRoomEventSeq(r.Context(), roomId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "room not found", http.StatusNotFound)
 			return
 		}
@@ -316,12 +1033,79 @@ func (h apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	type response struct {
-		RoomID   string                `json:"room_id"`
-		Messages []mappers.RoomMessage `json:"messages"`
+	filter, ok := parseMessageFilter(r.URL.Query())
+	if !ok {
+		http.Error(w, "invalid filter", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Has("view") {
+		if filter, ok = h.savedViewFilter(w, r, roomId); !ok {
+			return
+		}
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort != "" && sort != MessageSortRecent && sort != MessageSortReactions {
+		http.Error(w, "sort must be recent or reactions", http.StatusBadRequest)
+		return
+	}
+	p, ok := parsePage(r.URL.Query())
+	if !ok || (p.createdAt.Valid && p.reactionCount.Valid != (sort == MessageSortReactions)) {
+		http.Error(w, "invalid limit or cursor", http.StatusBadRequest)
+		return
+	}
+	//* Sorted listings are always paged, an unpaginated one comes in no particular order
+	if sort != "" && p.limit == 0 {
+		p.limit = maxPageSize
+	}
+	var answered pgtype.Bool
+	if filter.Answered != nil {
+		answered = pgtype.Bool{Bool: *filter.Answered, Valid: true}
+	}
+
+	var messages []pg.Message
+	var nextCursor string
+	switch {
+	case p.limit == 0:
+		messages, err = q.GetRoomMessages(r.Context(), roomId)
+	case sort == MessageSortReactions:
+		messages, err = q.GetRoomTopMessagesPage(r.Context(), pg.GetRoomTopMessagesPageParams{
+			RoomID:              roomId,
+			CursorReactionCount: p.reactionCount,
+			CursorCreatedAt:     p.createdAt.Time,
+			CursorID:            p.id,
+			Answered:            answered,
+			PageSize:            p.limit,
+		})
+		if err == nil && len(messages) > 0 {
+			last := messages[len(messages)-1]
+			nextCursor = p.nextReactionsCursor(len(messages), last.ReactionCount, last.CreatedAt, last.ID)
+		}
+	default:
+		messages, err = q.GetRoomMessagesPage(r.Context(), pg.GetRoomMessagesPageParams{
+			RoomID:          roomId,
+			CursorCreatedAt: p.createdAt,
+			CursorID:        p.id,
+			Answered:        answered,
+			PageSize:        p.limit,
+		})
+		//? The cursor follows the page as read, filters may leave it shorter than the limit
+		if err == nil && len(messages) > 0 {
+			last := messages[len(messages)-1]
+			nextCursor = p.nextCursor(len(messages), last.CreatedAt, last.ID)
+		}
+	}
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
 	}
 
-	data, err := json.Marshal(response{RoomID: roomId.String(), Messages: mappers.MapMessageToRoomMessage(messages)})
+	data, err := json.Marshal(getRoomMessagesResponse{
+		RoomID:      roomId.String(),
+		LastEventID: lastEventID,
+		Messages:    mappers.MapMessageToRoomMessage(filter.Apply(messages)),
+		NextCursor:  nextCursor,
+	})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -335,6 +1119,61 @@ func (h apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request
 	}
 }
 
+type getMyRoomMessagesResponse struct {
+	RoomID   string                `json:"room_id"`
+	Messages []mappers.RoomMessage `json:"messages"`
+}
+
+func (h apiHandler) handleGetMyRoomMessages(w http.ResponseWriter, r *http.Request) {
+	roomId, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, ok := session.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "session required", http.StatusUnauthorized)
+		return
+	}
+
+	_, err = h.q.GetRoom(r.Context(), roomId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.q.GetRoomSessionMessages(r.Context(), pg.GetRoomSessionMessagesParams{
+		RoomID:    roomId,
+		SessionID: uuid.NullUUID{UUID: sessionID, Valid: true},
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get session messages", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(getMyRoomMessagesResponse{RoomID: roomId.String(), Messages: mappers.MapMessageToRoomMessage(messages)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+type getRoomMessageResponse struct {
+	Message mappers.RoomMessage `json:"message"`
+}
+
 func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request) {
 	roomID, err := utils.ParseUUIDParam(r, "room_id")
 	if err != nil {
@@ -346,9 +1185,9 @@ func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid message id", http.StatusBadRequest)
 		return
 	}
-	message, err := h.q.GetMessage(r.Context(), messageId)
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "message not found", http.StatusNotFound)
 			return
 		}
@@ -356,22 +1195,14 @@ func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if message.RoomID.String() != roomID.String() {
-		http.Error(w, "message not found", http.StatusNotFound)
-		return
-	}
-
-	type response struct {
-		Message pg.Message `json:"message"`
-	}
-
-	data, err := json.Marshal(response{Message: message})
+	data, err := json.Marshal(getRoomMessageResponse{Message: mappers.MapMessage(message)})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", messageETag(message.Version))
 	_, err = w.Write(data)
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
@@ -379,6 +1210,10 @@ func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+type reactionCountResponse struct {
+	Count int64 `json:"count"`
+}
+
 func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request) {
 	roomID, err := utils.ParseUUIDParam(r, "room_id")
 	if err != nil {
@@ -390,9 +1225,9 @@ func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid message id", http.StatusBadRequest)
 		return
 	}
-	message, err := h.q.GetMessage(r.Context(), messageId)
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "message not found", http.StatusNotFound)
 			return
 		}
@@ -400,22 +1235,44 @@ func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if message.RoomID.String() != roomID.String() {
-		http.Error(w, "message not found", http.StatusNotFound)
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
+	}
+	if rejectClosedRoom(w, room) {
 		return
 	}
 
-	count, err := h.q.ReactToMessage(r.Context(), messageId)
+	kind, err := parseReactionKind(r)
 	if err != nil {
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		http.Error(w, "invalid reaction kind", http.StatusBadRequest)
 		return
 	}
 
-	type response struct {
-		Count int64 `json:"count"`
+	sessionID, ok := session.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "session required", http.StatusUnauthorized)
+		return
 	}
 
-	data, err := json.Marshal(response{Count: count})
+	reaction, err := h.q.ReactToMessage(r.Context(), pg.ReactToMessageParams{Kind: kind, SessionID: sessionID, ID: messageId})
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	//* One reaction per session and message, whatever its kind
+	if reaction.Reacted == 0 {
+		http.Error(w, "message already reacted to", http.StatusConflict)
+		return
+	}
+	count := reaction.ReactionCount
+
+	data, err := json.Marshal(reactionCountResponse{Count: count})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -429,13 +1286,14 @@ func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	go h.notifyClients(
+	h.publish(
 		Message{
 			RoomID: roomID.String(),
 			Kind:   MessageKindMessageReactionIncreased,
 			Value: MessageMessageReactionUpdated{
 				ID:     message.ID.String(),
 				RoomID: message.RoomID.String(),
+				Kind:   kind,
 				Count:  count,
 			},
 		},
@@ -452,9 +1310,9 @@ func (h apiHandler) handleRemoveReactionFromMessage(w http.ResponseWriter, r *ht
 		http.Error(w, "invalid message id", http.StatusBadRequest)
 		return
 	}
-	message, err := h.q.GetMessage(r.Context(), messageId)
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "message not found", http.StatusNotFound)
 			return
 		}
@@ -462,27 +1320,30 @@ func (h apiHandler) handleRemoveReactionFromMessage(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if message.RoomID.String() != roomID.String() {
-		http.Error(w, "message not found", http.StatusNotFound)
+	kind, err := parseReactionKind(r)
+	if err != nil {
+		http.Error(w, "invalid reaction kind", http.StatusBadRequest)
 		return
 	}
 
-	if message.ReactionCount == 0 {
-		w.WriteHeader(http.StatusNoContent)
+	sessionID, ok := session.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "session required", http.StatusUnauthorized)
 		return
 	}
 
-	count, err := h.q.RemoveReactionFromMessage(r.Context(), messageId)
+	reaction, err := h.q.RemoveReactionFromMessage(r.Context(), pg.RemoveReactionFromMessageParams{ID: messageId, SessionID: sessionID, Kind: kind})
 	if err != nil {
 		http.Error(w, "something went wrong", http.StatusInternalServerError)
 		return
 	}
-
-	type response struct {
-		Count int64 `json:"count"`
+	if reaction.Removed == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
+	count := reaction.ReactionCount
 
-	data, err := json.Marshal(response{Count: count})
+	data, err := json.Marshal(reactionCountResponse{Count: count})
 	if err != nil {
 		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
 		return
@@ -496,33 +1357,46 @@ func (h apiHandler) handleRemoveReactionFromMessage(w http.ResponseWriter, r *ht
 		return
 	}
 
-	go h.notifyClients(
+	h.publish(
 		Message{
 			RoomID: roomID.String(),
 			Kind:   MessageKindMessageReactionDecreased,
 			Value: MessageMessageReactionUpdated{
 				ID:     message.ID.String(),
 				RoomID: message.RoomID.String(),
+				Kind:   kind,
 				Count:  count,
 			},
 		},
 	)
 }
 
+const maxVideoURLLength = 2048
+
+func isVideoURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && len(raw) <= maxVideoURLLength && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+type markMessageAnsweredRequest struct {
+	Answered       *bool  `json:"answered"`
+	Answer         string `json:"answer"`
+	VideoURL       string `json:"video_url"`
+	VideoTimestamp *int32 `json:"video_timestamp"`
+	// * The version answered, when not sent as If-Match
+	Version *int32 `json:"version"`
+}
+
 func (h apiHandler) handleMarkMessageAsAnswered(w http.ResponseWriter, r *http.Request) {
-	roomID, err := utils.ParseUUIDParam(r, "room_id")
-	if err != nil {
-		http.Error(w, "invalid room id", http.StatusBadRequest)
-		return
-	}
+	roomID := hostedRoom(r).ID
 	messageId, err := utils.ParseUUIDParam(r, "message_id")
 	if err != nil {
 		http.Error(w, "invalid message id", http.StatusBadRequest)
 		return
 	}
-	message, err := h.q.GetMessage(r.Context(), messageId)
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "message not found", http.StatusNotFound)
 			return
 		}
@@ -530,31 +1404,96 @@ func (h apiHandler) handleMarkMessageAsAnswered(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if message.RoomID.String() != roomID.String() {
-		http.Error(w, "message not found", http.StatusNotFound)
+	var body markMessageAnsweredRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	version, ok := expectedVersion(w, r, body.Version)
+	if !ok {
 		return
 	}
-	if message.Answered {
+	if version != message.Version {
+		respondVersionConflict(w)
+		return
+	}
+	//* Leaving answered out marks the message answered
+	answered := body.Answered == nil || *body.Answered
+	body.Answer = strings.TrimSpace(body.Answer)
+	body.VideoURL = strings.TrimSpace(body.VideoURL)
+	var errs []forms.FieldError
+	if utf8.RuneCountInString(body.Answer) > maxAnswerLength {
+		errs = append(errs, forms.FieldError{Field: "answer", Message: "must be at most 1000 characters"})
+	}
+	if body.VideoURL != "" && !isVideoURL(body.VideoURL) {
+		errs = append(errs, forms.FieldError{Field: "video_url", Message: "must be an http or https url of at most 2048 characters"})
+	}
+	if body.VideoTimestamp != nil && *body.VideoTimestamp < 0 {
+		errs = append(errs, forms.FieldError{Field: "video_timestamp", Message: "must not be negative"})
+	}
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+	if !answered {
+		body.VideoURL, body.VideoTimestamp = "", nil
+	}
+	var videoOffset pgtype.Int4
+	if body.VideoTimestamp != nil {
+		videoOffset = pgtype.Int4{Int32: *body.VideoTimestamp, Valid: true}
+	}
+
+	if message.Answered == answered && (!answered || message.AnswerText == body.Answer &&
+		message.AnswerVideoUrl == body.VideoURL && message.AnswerVideoOffset == videoOffset) {
+		w.Header().Set("ETag", messageETag(message.Version))
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	err = h.q.MarkMessageAsAnswered(r.Context(), messageId)
+	kind := MessageKindMessageAnswered
+	if answered {
+		version, err = h.q.MarkMessageAsAnswered(r.Context(), pg.MarkMessageAsAnsweredParams{
+			ID:                messageId,
+			AnswerText:        body.Answer,
+			AnswerVideoUrl:    body.VideoURL,
+			AnswerVideoOffset: videoOffset,
+			Version:           pgtype.Int4{Int32: version, Valid: true},
+		})
+	} else {
+		kind = MessageKindMessageUnanswered
+		version, err = h.q.MarkMessageAsUnanswered(r.Context(), pg.MarkMessageAsUnansweredParams{
+			ID:      messageId,
+			Version: pgtype.Int4{Int32: version, Valid: true},
+		})
+	}
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondVersionConflict(w)
+			return
+		}
 		http.Error(w, "something went wrong", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", messageETag(version))
 	w.WriteHeader(http.StatusNoContent)
 
-	go h.notifyClients(
+	h.publish(
 		Message{
 			RoomID: roomID.String(),
-			Kind:   MessageKindMessageAnswered,
+			Kind:   kind,
 			Value: MessageMessageAnswered{
-				ID:     message.ID.String(),
-				RoomID: message.RoomID.String(),
+				ID:             message.ID.String(),
+				RoomID:         message.RoomID.String(),
+				AnswerText:     body.Answer,
+				VideoURL:       body.VideoURL,
+				VideoTimestamp: body.VideoTimestamp,
+				VideoLink:      export.VideoLink(body.VideoURL, videoOffset),
+				Version:        version,
 			},
 		},
 	)
+	if answered && body.Answer != "" {
+		h.tts.Enqueue(tts.Job{RoomID: roomID, MessageID: message.ID, Text: body.Answer})
+	}
 }
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/config"
+	"github.com/luiz504/week-tech-go-server/internal/contract"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store/memory"
+)
+
+func TestContract(t *testing.T) {
+	handler := NewHandler(memory.New(), config.Config{CORSOrigins: config.DefaultCORSOrigins})
+	server := httptest.NewServer(handler)
+	defer func() {
+		server.Close()
+		_ = handler.Shutdown(context.Background())
+	}()
+
+	for _, result := range contract.Run(context.Background(), contract.Config{BaseURL: server.URL, Timeout: 5 * time.Second}) {
+		t.Run(result.Check.Name, func(t *testing.T) {
+			if errors.Is(result.Err, contract.ErrSkipped) {
+				t.Skip(result.Check.Doc)
+			}
+			if result.Err != nil {
+				t.Fatalf("%s\n%v", result.Check.Doc, result.Err)
+			}
+		})
+	}
+}
+
+// TestOpenAPI catches operations documented for routes that were renamed or
+// removed, which would otherwise silently vanish from the spec.
+func TestOpenAPI(t *testing.T) {
+	handler := NewHandler(memory.New(), config.Config{CORSOrigins: config.DefaultCORSOrigins})
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d", openAPIPath, rec.Code)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for key := range operations {
+		method, path, _ := strings.Cut(key, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("%s is documented but not routed", key)
+		}
+	}
+}
+
+// TestAnswerRequiresRoomHost covers both directions of PATCH /answer: only
+// the owner token marks a question answered or takes that back.
+func TestAnswerRequiresRoomHost(t *testing.T) {
+	handler := NewHandler(memory.New(), config.Config{CORSOrigins: config.DefaultCORSOrigins})
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var room struct {
+		ID         string `json:"id"`
+		OwnerToken string `json:"owner_token"`
+	}
+	rec := serve(http.MethodPost, "/api/rooms", "", `{"theme":"answers"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create room: status %d", rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &room); err != nil {
+		t.Fatal(err)
+	}
+	var message struct {
+		ID string `json:"id"`
+	}
+	rec = serve(http.MethodPost, "/api/rooms/"+room.ID+"/messages", "", `{"message":"who hosts?"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create message: status %d", rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &message); err != nil {
+		t.Fatal(err)
+	}
+	answer := "/api/rooms/" + room.ID + "/messages/" + message.ID + "/answer"
+
+	steps := []struct {
+		token string
+		body  string
+		want  int
+	}{
+		{"", `{"answered":true,"version":1}`, http.StatusForbidden},
+		{room.OwnerToken, `{"answered":true,"version":1}`, http.StatusNoContent},
+		{"", `{"answered":false,"version":2}`, http.StatusForbidden},
+		{room.OwnerToken, `{"answered":false,"version":2}`, http.StatusNoContent},
+	}
+	for _, step := range steps {
+		if rec := serve(http.MethodPatch, answer, step.token, step.body); rec.Code != step.want {
+			t.Fatalf("PATCH %s %s with token %t: status %d, want %d", answer, step.body, step.token != "", rec.Code, step.want)
+		}
+	}
+}
+
+// TestArchivedRoomRejectsWrites covers the conflict an archived room answers
+// new messages and reactions with, until it's restored.
+func TestArchivedRoomRejectsWrites(t *testing.T) {
+	handler := NewHandler(memory.New(), config.Config{CORSOrigins: config.DefaultCORSOrigins})
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	serve := func(method, path string, header http.Header, body string, out any) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if out != nil {
+			_ = json.Unmarshal(rec.Body.Bytes(), out)
+		}
+		return rec.Code
+	}
+
+	var room struct {
+		ID         string `json:"id"`
+		OwnerToken string `json:"owner_token"`
+	}
+	if code := serve(http.MethodPost, "/api/rooms", nil, `{"theme":"archives"}`, &room); code != http.StatusCreated {
+		t.Fatalf("create room: status %d", code)
+	}
+	var message struct {
+		ID string `json:"id"`
+	}
+	if code := serve(http.MethodPost, "/api/rooms/"+room.ID+"/messages", nil, `{"message":"still open?"}`, &message); code != http.StatusCreated {
+		t.Fatalf("create message: status %d", code)
+	}
+	var sess struct {
+		Token string `json:"token"`
+	}
+	if code := serve(http.MethodPost, "/api/sessions", nil, "", &sess); code != http.StatusCreated {
+		t.Fatalf("create session: status %d", code)
+	}
+	owner := http.Header{"Authorization": {"Bearer " + room.OwnerToken}}
+	audience := http.Header{session.HeaderName: {sess.Token}}
+
+	steps := []struct {
+		method string
+		path   string
+		header http.Header
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "/api/rooms/" + room.ID + "/archive", owner, "", http.StatusNoContent},
+		{http.MethodPost, "/api/rooms/" + room.ID + "/messages", nil, `{"message":"too late"}`, http.StatusConflict},
+		{http.MethodPatch, "/api/rooms/" + room.ID + "/messages/" + message.ID + "/react", audience, `{"kind":"like"}`, http.StatusConflict},
+		{http.MethodPost, "/api/rooms/" + room.ID + "/unarchive", owner, "", http.StatusNoContent},
+		{http.MethodPost, "/api/rooms/" + room.ID + "/messages", nil, `{"message":"back again"}`, http.StatusCreated},
+		{http.MethodPatch, "/api/rooms/" + room.ID + "/messages/" + message.ID + "/react", audience, `{"kind":"like"}`, http.StatusOK},
+	}
+	for _, step := range steps {
+		if code := serve(step.method, step.path, step.header, step.body, nil); code != step.want {
+			t.Fatalf("%s %s: status %d, want %d", step.method, step.path, code, step.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	MessageKindRoomApplause = "room_applause"
+)
+
+type MessageRoomApplause struct {
+	RoomID string `json:"room_id"`
+	Level  int    `json:"level"`
+	Claps  int    `json:"claps"`
+}
+
+const (
+	applauseTick = time.Second
+	// Caps how much a single connection can move the meter per tick.
+	maxClapsPerTick = 5
+	// Weight kept from the previous level, so the meter rises and falls smoothly.
+	applauseDecay = 0.6
+)
+
+type roomApplause struct {
+	claps map[*subscriber]int
+	level float64
+}
+
+// applauseMeter aggregates room-level claps into a 0-100 level relative to
+// the audience size. Nothing is persisted.
+type applauseMeter struct {
+	mu    sync.Mutex
+	rooms map[string]*roomApplause
+}
+
+func newApplauseMeter() *applauseMeter {
+	return &applauseMeter{rooms: make(map[string]*roomApplause)}
+}
+
+func (m *applauseMeter) clap(roomID string, sub *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room, ok := m.rooms[roomID]
+	if !ok {
+		room = &roomApplause{claps: make(map[*subscriber]int)}
+		m.rooms[roomID] = room
+	}
+	if room.claps[sub] < maxClapsPerTick {
+		room.claps[sub]++
+	}
+}
+
+func (h apiHandler) runApplauseMeter() {
+	ticker := time.NewTicker(applauseTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, msg := range h.tickApplause() {
+				h.notifyClientsEphemeral(msg)
+			}
+		}
+	}
+}
+
+// tickApplause folds the claps of the last tick into each room level and
+// returns the updates to broadcast. Rooms that went quiet are dropped.
+func (h apiHandler) tickApplause() []Message {
+	h.mu.Lock()
+	audience := make(map[string]int, len(h.subscribers))
+	for roomID := range h.subscribers {
+		audience[roomID] = h.admittedLocked(roomID)
+	}
+	h.mu.Unlock()
+
+	m := h.applause
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updates []Message
+	for roomID, room := range m.rooms {
+		claps := 0
+		for _, n := range room.claps {
+			claps += n
+		}
+
+		instant := 0.0
+		if listeners := audience[roomID]; listeners > 0 {
+			instant = math.Min(1, float64(claps)/float64(listeners)) * 100
+		}
+		room.level = room.level*applauseDecay + instant*(1-applauseDecay)
+		room.claps = make(map[*subscriber]int)
+
+		if claps == 0 && room.level < 1 {
+			delete(m.rooms, roomID)
+			room.level = 0
+		}
+
+		updates = append(updates, Message{
+			Kind:   MessageKindRoomApplause,
+			RoomID: roomID,
+			Value: MessageRoomApplause{
+				RoomID: roomID,
+				Level:  int(math.Round(room.level)),
+				Claps:  claps,
+			},
+		})
+	}
+
+	return updates
+}
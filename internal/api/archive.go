@@ -0,0 +1,157 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// * A room's status in listings: archived takes precedence over closed, an
+// * archived room takes nothing new whether it was closed or not
+const (
+	RoomStatusActive   = "active"
+	RoomStatusClosed   = "closed"
+	RoomStatusArchived = "archived"
+)
+
+const (
+	MessageKindRoomArchived   = "room_archived"
+	MessageKindRoomUnarchived = "room_unarchived"
+	MessageKindRoomClosed     = "room_closed"
+	MessageKindRoomReopened   = "room_reopened"
+)
+
+type MessageRoomArchived struct {
+	RoomID string `json:"room_id"`
+}
+
+type MessageRoomClosed struct {
+	RoomID string `json:"room_id"`
+}
+
+// rejectClosedRoom responds 409, like an archived room does, and returns
+// true when room takes no new messages or reactions. Closing is apart from
+// archival: closed rooms stay readable and keep their subscribers.
+func rejectClosedRoom(w http.ResponseWriter, room pg.Room) bool {
+	if !room.ClosedAt.Valid {
+		return false
+	}
+
+	http.Error(w, "room is closed", http.StatusConflict)
+	return true
+}
+
+// roomStatus derives the status of a room from when it was archived and
+// closed, as GetRooms does.
+func roomStatus(archivedAt, closedAt pgtype.Timestamptz) string {
+	switch {
+	case archivedAt.Valid:
+		return RoomStatusArchived
+	case closedAt.Valid:
+		return RoomStatusClosed
+	default:
+		return RoomStatusActive
+	}
+}
+
+// roomStatusFilter maps the listing query to a GetRooms filter, where an
+// empty status matches every room. Archived rooms are hidden unless asked for.
+func roomStatusFilter(r *http.Request) (pg.GetRoomsParams, bool) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case RoomStatusActive, RoomStatusClosed:
+		return pg.GetRoomsParams{Status: status}, true
+	case RoomStatusArchived:
+		return pg.GetRoomsParams{IncludeArchived: true, Status: status}, true
+	case "":
+		return pg.GetRoomsParams{IncludeArchived: r.URL.Query().Get("include_archived") == "true"}, true
+	default:
+		return pg.GetRoomsParams{}, false
+	}
+}
+
+func (h apiHandler) handleArchiveRoom(w http.ResponseWriter, r *http.Request) {
+	h.setRoomArchived(w, r, true)
+}
+
+func (h apiHandler) handleUnarchiveRoom(w http.ResponseWriter, r *http.Request) {
+	h.setRoomArchived(w, r, false)
+}
+
+func (h apiHandler) setRoomArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	room := hostedRoom(r)
+
+	if room.ArchivedAt.Valid == archived {
+		if archived {
+			http.Error(w, "room is already archived", http.StatusConflict)
+		} else {
+			http.Error(w, "room is not archived", http.StatusConflict)
+		}
+		return
+	}
+
+	var err error
+	kind := MessageKindRoomArchived
+	if archived {
+		err = h.q.ArchiveRoom(r.Context(), room.ID)
+	} else {
+		kind = MessageKindRoomUnarchived
+		err = h.q.UnarchiveRoom(r.Context(), room.ID)
+	}
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to update room archive state", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	h.publish(Message{
+		Kind:   kind,
+		RoomID: room.ID.String(),
+		Value:  MessageRoomArchived{RoomID: room.ID.String()},
+	})
+}
+
+func (h apiHandler) handleCloseRoom(w http.ResponseWriter, r *http.Request) {
+	h.setRoomClosed(w, r, true)
+}
+
+func (h apiHandler) handleReopenRoom(w http.ResponseWriter, r *http.Request) {
+	h.setRoomClosed(w, r, false)
+}
+
+func (h apiHandler) setRoomClosed(w http.ResponseWriter, r *http.Request, closed bool) {
+	room := hostedRoom(r)
+
+	var updated int64
+	var err error
+	kind := MessageKindRoomClosed
+	if closed {
+		updated, err = h.q.CloseRoom(r.Context(), room.ID)
+	} else {
+		kind = MessageKindRoomReopened
+		updated, err = h.q.ReopenRoom(r.Context(), room.ID)
+	}
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to update room closed state", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		if closed {
+			http.Error(w, "room is already closed", http.StatusConflict)
+		} else {
+			http.Error(w, "room is not closed", http.StatusConflict)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	h.publish(Message{
+		Kind:   kind,
+		RoomID: room.ID.String(),
+		Value:  MessageRoomClosed{RoomID: room.ID.String()},
+	})
+}
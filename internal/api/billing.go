@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/billing"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const maxWebhookBodyBytes = 64 << 10
+
+type stripeConfig struct {
+	webhookSecret string
+	priceToPlan   map[string]string
+}
+
+// stripeFromEnv reads WS_STRIPE_WEBHOOK_SECRET, falling back to the stored
+// secret when unset, and the WS_STRIPE_PRICE_PLANS mapping.
+func stripeFromEnv() stripeConfig {
+	return stripeConfig{
+		webhookSecret: os.Getenv("WS_STRIPE_WEBHOOK_SECRET"),
+		priceToPlan:   billing.PriceToPlanFromEnv(),
+	}
+}
+
+// organizationSettings loads the billing settings of an organization.
+func (h apiHandler) organizationSettings(ctx context.Context, organizationID uuid.UUID) (billing.Settings, error) {
+	org, err := h.q.GetOrganization(ctx, organizationID)
+	if err != nil {
+		return billing.Settings{}, err
+	}
+
+	return billing.ParseSettings(org.Settings)
+}
+
+// featureEnabled reports whether the plan of the room's organization grants
+// the feature. Rooms outside an organization have every feature.
+func (h apiHandler) featureEnabled(ctx context.Context, room pg.Room, feature string) (bool, error) {
+	if !room.OrganizationID.Valid {
+		return true, nil
+	}
+	settings, err := h.organizationSettings(ctx, room.OrganizationID.UUID)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.Enabled(feature), nil
+}
+
+// handleStripeWebhook keeps organization plans in sync with their Stripe
+// subscriptions. Events that can't be matched to an organization are
+// acknowledged so Stripe doesn't keep retrying them.
+func (h apiHandler) handleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	secret, err := h.stripeWebhookSecret(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get stripe webhook secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if secret == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err = billing.VerifySignature(payload, r.Header.Get(billing.StripeSignatureHeader), secret, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var event billing.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case billing.EventSubscriptionCreated, billing.EventSubscriptionUpdated, billing.EventSubscriptionDeleted:
+	default:
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var sub billing.Subscription
+	if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+		http.Error(w, "invalid subscription", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.subscriptionOrganization(r.Context(), sub)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			slog.Warn("stripe subscription without a matching organization", "event_id", event.ID, "customer", sub.Customer)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get organization", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	status := sub.Status
+	if event.Type == billing.EventSubscriptionDeleted {
+		status = "canceled"
+	}
+	settings, err := json.Marshal(billing.SettingsFor(status, sub.Plan(h.stripe.priceToPlan)))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal settings", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.q.UpdateOrganizationBilling(r.Context(), pg.UpdateOrganizationBillingParams{
+		ID:               org.ID,
+		StripeCustomerID: pgtype.Text{String: sub.Customer, Valid: sub.Customer != ""},
+		Settings:         settings,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to update organization billing", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("organization plan updated", "organization_id", org.ID.String(), "event_id", event.ID, "status", status)
+	w.WriteHeader(http.StatusOK)
+}
+
+// subscriptionOrganization finds the organization by the organization_id
+// metadata set at checkout, falling back to a customer seen before.
+func (h apiHandler) subscriptionOrganization(ctx context.Context, sub billing.Subscription) (pg.Organization, error) {
+	if raw, ok := sub.Metadata["organization_id"]; ok {
+		if id, err := uuid.Parse(raw); err == nil {
+			return h.q.GetOrganization(ctx, id)
+		}
+	}
+
+	return h.q.GetOrganizationByStripeCustomer(ctx, pgtype.Text{String: sub.Customer, Valid: true})
+}
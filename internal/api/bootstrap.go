@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// bootstrapState holds the one-time token that unlocks POST /admin/bootstrap
+// while no admin credential exists. tokenHash is cleared once it was used.
+type bootstrapState struct {
+	mu        sync.Mutex
+	tokenHash string
+}
+
+// newBootstrap arms the bootstrap flow on a fresh database. WS_BOOTSTRAP_TOKEN
+// lets provisioning tools choose the token, otherwise one is generated and
+// printed so the operator can pick it up from the first startup output.
+func newBootstrap(q pg.Querier) *bootstrapState {
+	state := &bootstrapState{}
+
+	count, err := q.CountAdminCredentials(context.Background())
+	if err != nil {
+		slog.Error("failed to count admin credentials, bootstrap disabled", "error", err)
+		return state
+	}
+	if count > 0 {
+		return state
+	}
+
+	token := os.Getenv("WS_BOOTSTRAP_TOKEN")
+	if token == "" {
+		token, err = utils.GenerateToken()
+		if err != nil {
+			slog.Error("failed to generate bootstrap token, bootstrap disabled", "error", err)
+			return state
+		}
+		//? Printed rather than logged so it doesn't end up in shipped log files
+		fmt.Fprintf(os.Stderr, "no admin credential configured, bootstrap with: POST /admin/bootstrap (Authorization: Bearer %s)\n", token)
+	}
+	state.tokenHash = utils.HashToken(token)
+
+	return state
+}
+
+func (b *bootstrapState) match(token string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return utils.MatchTokenHash(token, b.tokenHash)
+}
+
+func (b *bootstrapState) disarm() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokenHash = ""
+}
+
+// handleBootstrap creates the first admin credential and, when asked, the
+// first organization with an API key. It only succeeds once per database, so
+// reruns from provisioning get a 409 instead of duplicate configuration.
+func (h apiHandler) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if !h.bootstrap.match(utils.ParseBearerToken(r)) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	type _body struct {
+		Name         string `json:"name"`
+		Organization *struct {
+			Name       string `json:"name"`
+			APIKeyName string `json:"api_key_name"`
+		} `json:"organization"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if body.Organization != nil {
+		body.Organization.Name = strings.TrimSpace(body.Organization.Name)
+		if body.Organization.Name == "" {
+			http.Error(w, "organization name is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	adminToken, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate admin token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	//* Serializes concurrent bootstraps, across instances too
+	if err := tx.AcquireBootstrapLock(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to acquire bootstrap lock", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	count, err := tx.CountAdminCredentials(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to count admin credentials", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		h.bootstrap.disarm()
+		http.Error(w, "already bootstrapped", http.StatusConflict)
+		return
+	}
+
+	credential, err := tx.InsertAdminCredential(r.Context(), pg.InsertAdminCredentialParams{
+		Name:      body.Name,
+		TokenHash: utils.HashToken(adminToken),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert admin credential", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type organization struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		APIKeyID string `json:"api_key_id"`
+		APIKey   string `json:"api_key"`
+	}
+	type response struct {
+		ID           string        `json:"id"`
+		Name         string        `json:"name"`
+		Token        string        `json:"token"`
+		Organization *organization `json:"organization,omitempty"`
+	}
+
+	resp := response{ID: credential.ID.String(), Name: credential.Name, Token: adminToken}
+
+	if body.Organization != nil {
+		org, err := tx.InsertOrganization(r.Context(), body.Organization.Name)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to insert organization", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		rawKey, err := utils.GenerateToken()
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to generate api key", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		key, err := tx.InsertAPIKey(r.Context(), pg.InsertAPIKeyParams{
+			OrganizationID: org.ID,
+			Name:           strings.TrimSpace(body.Organization.APIKeyName),
+			KeyHash:        utils.HashToken(rawKey),
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to insert api key", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		resp.Organization = &organization{ID: org.ID.String(), Name: org.Name, APIKeyID: key.ID.String(), APIKey: rawKey}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	h.bootstrap.disarm()
+	slog.Info("bootstrap completed", "admin_credential_id", credential.ID)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/billing"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	BulkActionAnswer = "answer"
+	BulkActionDelete = "delete"
+	BulkActionPin    = "pin"
+	BulkActionTag    = "tag"
+)
+
+const (
+	BulkResultOK        = "ok"
+	BulkResultNotFound  = "not_found"
+	BulkResultUnchanged = "unchanged"
+	BulkResultConflict  = "conflict"
+)
+
+const (
+	maxBulkMessages = 500
+	maxTagLength    = 32
+)
+
+const (
+	MessageKindMessagesBulkUpdated = "messages_bulk_updated"
+)
+
+type MessageMessagesBulkUpdated struct {
+	RoomID string   `json:"room_id"`
+	Action string   `json:"action"`
+	Tag    string   `json:"tag,omitempty"`
+	IDs    []string `json:"ids"`
+}
+
+type bulkResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type bulkModerateRequest struct {
+	IDs    []uuid.UUID `json:"ids"`
+	Action string      `json:"action"`
+	Tag    string      `json:"tag"`
+	// * The version of each message, required to answer or pin them
+	Versions map[uuid.UUID]int32 `json:"versions"`
+}
+
+type bulkModerateResponse struct {
+	Action  string       `json:"action"`
+	Results []bulkResult `json:"results"`
+}
+
+func (h apiHandler) handleBulkModerateMessages(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	enabled, err := h.featureEnabled(r.Context(), room, billing.FeatureModeration)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to check plan features", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		http.Error(w, "bulk moderation is not included in this organization's plan", http.StatusPaymentRequired)
+		return
+	}
+
+	var body bulkModerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	var errs []forms.FieldError
+	if len(body.IDs) == 0 || len(body.IDs) > maxBulkMessages {
+		errs = append(errs, forms.FieldError{Field: "ids", Message: "must have between 1 and 500 ids"})
+	}
+	switch body.Action {
+	case BulkActionAnswer, BulkActionPin:
+		for _, id := range body.IDs {
+			if _, ok := body.Versions[id]; !ok {
+				errs = append(errs, forms.FieldError{Field: "versions", Message: "must have the version of every id to answer or pin"})
+				break
+			}
+		}
+	case BulkActionDelete:
+	case BulkActionTag:
+		body.Tag = strings.ToLower(strings.TrimSpace(body.Tag))
+		if body.Tag == "" || utf8.RuneCountInString(body.Tag) > maxTagLength {
+			errs = append(errs, forms.FieldError{Field: "tag", Message: "must be between 1 and 32 characters"})
+		}
+	default:
+		errs = append(errs, forms.FieldError{Field: "action", Message: "must be answer, delete, pin or tag"})
+	}
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	results := make([]bulkResult, 0, len(body.IDs))
+	updated := []string{}
+	seen := make(map[uuid.UUID]bool, len(body.IDs))
+	for _, id := range body.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		version, ok := body.Versions[id]
+		status, err := applyBulkAction(r.Context(), tx, room.ID, id, body.Action, body.Tag, pgtype.Int4{Int32: version, Valid: ok})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to apply bulk action", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		results = append(results, bulkResult{ID: id.String(), Status: status})
+		if status == BulkResultOK {
+			updated = append(updated, id.String())
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(bulkModerateResponse{Action: body.Action, Results: results})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if len(updated) == 0 {
+		return
+	}
+
+	h.publish(Message{
+		Kind:   MessageKindMessagesBulkUpdated,
+		RoomID: room.ID.String(),
+		Value: MessageMessagesBulkUpdated{
+			RoomID: room.ID.String(),
+			Action: body.Action,
+			Tag:    body.Tag,
+			IDs:    updated,
+		},
+	})
+}
+
+// applyBulkAction runs one action inside the bulk transaction. Missing or
+// foreign messages, and those changed since version, are reported per item
+// instead of failing the batch.
+func applyBulkAction(ctx context.Context, q pg.Querier, roomID uuid.UUID, id uuid.UUID, action string, tag string, version pgtype.Int4) (string, error) {
+	message, err := q.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: roomID, ID: id})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return BulkResultNotFound, nil
+		}
+		return "", err
+	}
+
+	switch action {
+	case BulkActionAnswer:
+		if message.Answered {
+			return BulkResultUnchanged, nil
+		}
+		_, err = q.MarkMessageAsAnswered(ctx, pg.MarkMessageAsAnsweredParams{ID: id, Version: version})
+	case BulkActionDelete:
+		err = q.SoftDeleteMessage(ctx, id)
+	case BulkActionPin:
+		if message.Pinned {
+			return BulkResultUnchanged, nil
+		}
+		_, err = q.PinMessage(ctx, pg.PinMessageParams{ID: id, Version: version})
+	case BulkActionTag:
+		for _, existing := range message.Tags {
+			if existing == tag {
+				return BulkResultUnchanged, nil
+			}
+		}
+		err = q.AddMessageTag(ctx, pg.AddMessageTagParams{Tag: tag, ID: id})
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return BulkResultConflict, nil
+		}
+		return "", err
+	}
+
+	return BulkResultOK, nil
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	MessageKindWaitingRoom         = "waiting_room"
+	MessageKindWaitingRoomAdmitted = "waiting_room_admitted"
+)
+
+type MessageWaitingRoom struct {
+	RoomID   string `json:"room_id"`
+	Position int    `json:"position"`
+	Capacity int    `json:"capacity"`
+}
+
+type MessageWaitingRoomAdmitted struct {
+	RoomID string `json:"room_id"`
+}
+
+// writeLocked queues msg for sub. Must be called with h.mu held.
+func (h apiHandler) writeLocked(sub *subscriber, msg Message) {
+	h.queueLocked(sub, &outboundFrame{msg: msg})
+}
+
+// admittedLocked counts the subscribers of a room that are not waiting.
+func (h apiHandler) admittedLocked(roomID string) int {
+	return len(h.subscribers[roomID]) - len(h.waiting[roomID])
+}
+
+// joinLocked registers the connection, queueing it in the waiting room when
+// the room is at capacity. Must be called with h.mu held.
+func (h apiHandler) joinLocked(roomID string, c clientConn, sub *subscriber) {
+	if _, ok := h.subscribers[roomID]; !ok {
+		h.subscribers[roomID] = make(map[clientConn]*subscriber)
+	}
+
+	if sub.capacity > 0 && h.admittedLocked(roomID) >= sub.capacity {
+		sub.waiting = true
+		h.waiting[roomID] = append(h.waiting[roomID], c)
+	}
+	h.subscribers[roomID][c] = sub
+	metrics.RoomSubscribers.WithLabelValues(roomID).Set(float64(len(h.subscribers[roomID])))
+	if sub.organizationID.Valid {
+		h.orgConnections[sub.organizationID.UUID]++
+	}
+
+	if sub.waiting {
+		h.writeLocked(sub, Message{
+			Kind: MessageKindWaitingRoom,
+			Value: MessageWaitingRoom{
+				RoomID:   roomID,
+				Position: len(h.waiting[roomID]),
+				Capacity: sub.capacity,
+			},
+		})
+	}
+}
+
+// leaveLocked removes the connection and promotes waiting connections into
+// the freed slots. Must be called with h.mu held.
+func (h apiHandler) leaveLocked(roomID string, c clientConn) {
+	sub, ok := h.subscribers[roomID][c]
+	if !ok {
+		return
+	}
+	delete(h.subscribers[roomID], c)
+	if sub.organizationID.Valid {
+		if h.orgConnections[sub.organizationID.UUID]--; h.orgConnections[sub.organizationID.UUID] <= 0 {
+			delete(h.orgConnections, sub.organizationID.UUID)
+		}
+	}
+
+	queue := h.waiting[roomID]
+	moved := false
+	if sub.waiting {
+		for i, waiting := range queue {
+			if waiting == c {
+				queue = append(queue[:i], queue[i+1:]...)
+				moved = true
+				break
+			}
+		}
+	}
+
+	for len(queue) > 0 && len(h.subscribers[roomID])-len(queue) < sub.capacity {
+		next := queue[0]
+		queue = queue[1:]
+		moved = true
+
+		promoted := h.subscribers[roomID][next]
+		promoted.waiting = false
+		h.writeLocked(promoted, Message{
+			Kind:  MessageKindWaitingRoomAdmitted,
+			Value: MessageWaitingRoomAdmitted{RoomID: roomID},
+		})
+	}
+
+	if len(queue) == 0 {
+		delete(h.waiting, roomID)
+	} else {
+		h.waiting[roomID] = queue
+	}
+
+	//* Everyone behind the moved entries advanced in the queue
+	if moved {
+		for i, waiting := range queue {
+			h.writeLocked(h.subscribers[roomID][waiting], Message{
+				Kind: MessageKindWaitingRoom,
+				Value: MessageWaitingRoom{
+					RoomID:   roomID,
+					Position: i + 1,
+					Capacity: sub.capacity,
+				},
+			})
+		}
+	}
+
+	if len(h.subscribers[roomID]) == 0 {
+		delete(h.subscribers, roomID)
+		metrics.RoomSubscribers.DeleteLabelValues(roomID)
+	} else {
+		metrics.RoomSubscribers.WithLabelValues(roomID).Set(float64(len(h.subscribers[roomID])))
+	}
+}
+
+const peakSampleInterval = 30 * time.Second
+
+// runPeakSampler records the peak audience of each room, sampled rather than
+// written on every join so a room filling up doesn't hammer the database.
+// Peaks are per instance: with the audience spread across replicas the
+// recorded peak is the largest share one of them saw.
+func (h apiHandler) runPeakSampler() {
+	ticker := time.NewTicker(peakSampleInterval)
+	defer ticker.Stop()
+
+	recorded := make(map[string]int)
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			//? The primary records the peaks, a standby's replica is read-only
+			if !h.failover.standby.Load() {
+				h.samplePeaks(recorded)
+			}
+		}
+	}
+}
+
+// samplePeaks persists the audiences that grew past what was recorded,
+// forgetting rooms nobody watches anymore.
+func (h apiHandler) samplePeaks(recorded map[string]int) {
+	grown := make(map[string]int)
+	h.mu.Lock()
+	for roomID := range recorded {
+		if _, ok := h.subscribers[roomID]; !ok {
+			delete(recorded, roomID)
+		}
+	}
+	for roomID := range h.subscribers {
+		if admitted := h.admittedLocked(roomID); admitted > recorded[roomID] {
+			grown[roomID] = admitted
+		}
+	}
+	h.mu.Unlock()
+
+	for roomID, admitted := range grown {
+		id, err := uuid.Parse(roomID)
+		if err != nil {
+			continue
+		}
+		if err := h.q.RecordRoomPeak(h.bus.ctx, pg.RecordRoomPeakParams{RoomID: id, PeakSubscribers: int32(admitted)}); err != nil {
+			slog.Error("failed to record room peak", "room_id", roomID, "error", err)
+			continue
+		}
+		recorded[roomID] = admitted
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/translate"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const ChannelCaptions = "captions"
+
+const MessageKindCaption = "caption"
+
+const (
+	maxCaptionLength     = 2000
+	defaultCaptionsLimit = 100
+	maxCaptionsLimit     = 500
+)
+
+// MessageCaption is a segment of the live captions. Interim segments are
+// the captioner's running guess, replaced by the next one until a final
+// segment settles the text; only final ones have an ID and are stored.
+type MessageCaption struct {
+	ID       string `json:"id,omitempty"`
+	RoomID   string `json:"room_id"`
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+	StartMs  *int64 `json:"start_ms,omitempty"`
+	EndMs    *int64 `json:"end_ms,omitempty"`
+	Final    bool   `json:"final"`
+}
+
+func mapCaption(caption pg.Caption) MessageCaption {
+	var startMs, endMs *int64
+	if caption.StartMs.Valid {
+		startMs = &caption.StartMs.Int64
+	}
+	if caption.EndMs.Valid {
+		endMs = &caption.EndMs.Int64
+	}
+
+	return MessageCaption{
+		ID:       caption.ID.String(),
+		RoomID:   caption.RoomID.String(),
+		Text:     caption.Text,
+		Language: caption.Language,
+		StartMs:  startMs,
+		EndMs:    endMs,
+		Final:    true,
+	}
+}
+
+// handleCreateCaptionToken issues the token a captioning service pushes the
+// room's captions with, replacing any previous one.
+func (h apiHandler) handleCreateCaptionToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate caption token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	err = h.q.UpsertRoomCaptionToken(r.Context(), pg.UpsertRoomCaptionTokenParams{RoomID: room.ID, TokenHash: utils.HashToken(token)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save caption token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Token     string `json:"token"`
+		IngestURL string `json:"ingest_url"`
+	}
+
+	data, err := json.Marshal(response{Token: token, IngestURL: "/api/rooms/" + room.ID.String() + "/captions"})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteCaptionToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomCaptionToken(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete caption token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "captions are not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleIngestCaption takes a segment from the captioning service holding the
+// room's caption token. Final segments are stored and join the room event
+// sequence, interim ones are only relayed to whoever is watching.
+func (h apiHandler) handleIngestCaption(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	tokenHash, err := h.q.GetRoomCaptionTokenHash(r.Context(), room.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		helpers.LogErrorAndRespond(w, "failed to get caption token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !utils.MatchTokenHash(utils.ParseBearerToken(r), tokenHash) {
+		http.Error(w, "invalid caption token", http.StatusUnauthorized)
+		return
+	}
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
+	}
+
+	type _body struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+		StartMs  *int64 `json:"start_ms"`
+		EndMs    *int64 `json:"end_ms"`
+		Final    bool   `json:"final"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	var errs []forms.FieldError
+	body.Text = strings.TrimSpace(body.Text)
+	if body.Text == "" || utf8.RuneCountInString(body.Text) > maxCaptionLength {
+		errs = append(errs, forms.FieldError{Field: "text", Message: "must be between 1 and 2000 characters"})
+	}
+	if body.Language != "" {
+		language, err := translate.ParseLanguage(body.Language)
+		if err != nil {
+			errs = append(errs, forms.FieldError{Field: "language", Message: "must be a valid language tag"})
+		}
+		body.Language = language
+	}
+	if body.StartMs != nil && *body.StartMs < 0 {
+		errs = append(errs, forms.FieldError{Field: "start_ms", Message: "must not be negative"})
+	}
+	if body.EndMs != nil && body.StartMs != nil && *body.EndMs < *body.StartMs {
+		errs = append(errs, forms.FieldError{Field: "end_ms", Message: "must not be before start_ms"})
+	}
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	caption := MessageCaption{
+		RoomID:   room.ID.String(),
+		Text:     body.Text,
+		Language: body.Language,
+		StartMs:  body.StartMs,
+		EndMs:    body.EndMs,
+	}
+	status := http.StatusAccepted
+	if body.Final {
+		params := pg.InsertCaptionParams{RoomID: room.ID, Text: body.Text, Language: body.Language}
+		if body.StartMs != nil {
+			params.StartMs = pgtype.Int8{Int64: *body.StartMs, Valid: true}
+		}
+		if body.EndMs != nil {
+			params.EndMs = pgtype.Int8{Int64: *body.EndMs, Valid: true}
+		}
+		inserted, err := h.q.InsertCaption(r.Context(), params)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to insert caption", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		caption = mapCaption(inserted)
+		status = http.StatusCreated
+
+		h.publish(Message{Kind: MessageKindCaption, Channel: ChannelCaptions, RoomID: caption.RoomID, Value: caption})
+	} else {
+		//* Interim segments are superseded within a second, not worth a sequence number
+		h.notifyClientsEphemeral(Message{Kind: MessageKindCaption, Channel: ChannelCaptions, RoomID: caption.RoomID, Value: caption})
+	}
+
+	data, err := json.Marshal(caption)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetRoomCaptions lists the final captions of a room in order, after
+// ?since (RFC 3339) when given, for late joiners and transcripts.
+func (h apiHandler) handleGetRoomCaptions(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		if since, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			http.Error(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultCaptionsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxCaptionsLimit {
+			http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.q.GetRoom(r.Context(), roomID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	captions, err := h.q.GetRoomCaptions(r.Context(), pg.GetRoomCaptionsParams{RoomID: roomID, Since: since, MaxCaptions: int32(limit)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get room captions", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		RoomID   string           `json:"room_id"`
+		Captions []MessageCaption `json:"captions"`
+	}
+
+	res := response{RoomID: roomID.String(), Captions: make([]MessageCaption, 0, len(captions))}
+	for _, caption := range captions {
+		res.Captions = append(res.Captions, mapCaption(caption))
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
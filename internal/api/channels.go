@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	ChannelQuestions  = "questions"
+	ChannelModeration = "moderation"
+	ChannelBackstage  = "backstage"
+)
+
+var roomChannels = map[string]bool{
+	ChannelQuestions:   true,
+	ChannelModeration:  true,
+	ChannelBackstage:   true,
+	ChannelLeaderboard: true,
+	ChannelCaptions:    true,
+}
+
+// * Channels only the room owner can join
+var hostChannels = map[string]bool{
+	ChannelModeration: true,
+	ChannelBackstage:  true,
+}
+
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandApplause    = "applause"
+)
+
+const (
+	MessageKindChannelSubscribed   = "channel_subscribed"
+	MessageKindChannelUnsubscribed = "channel_unsubscribed"
+	MessageKindCommandRejected     = "command_rejected"
+)
+
+type MessageChannelUpdated struct {
+	Channel string `json:"channel"`
+}
+
+type MessageCommandRejected struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type clientCommand struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+	Token   string `json:"token,omitempty"`
+	// * heartbeat
+	TS    int64 `json:"ts,omitempty"`
+	AckTS int64 `json:"ack_ts,omitempty"`
+}
+
+type subscriber struct {
+	cancel         context.CancelFunc
+	channels       map[string]bool
+	ownerTokenHash string
+	host           bool
+	// requestID is the upgrade request's ID, carried for the socket's lifetime.
+	requestID string
+	log       *slog.Logger
+	// capacity is the room limit seen at connect time, 0 meaning unlimited.
+	capacity int
+	waiting  bool
+	// closed is set once a write failed and the connection is being torn down.
+	closed bool
+	// out is the write queue drained by the connection's writer.
+	out chan *outboundFrame
+	// replaying is set while the missed events are read from the log, the
+	// live ones broadcast meanwhile wait in backlog.
+	replaying bool
+	backlog   []*outboundFrame
+	// rtt is the last round trip measured from heartbeats, 0 until one completes.
+	rtt time.Duration
+	// pongDeadline is when a WebSocket that hasn't answered a ping gets
+	// reaped. Zero for transports without pings.
+	pongDeadline time.Time
+	// organizationID is billed for the connection time, meteredAt is where
+	// the last accounting stopped.
+	organizationID uuid.NullUUID
+	meteredAt      time.Time
+}
+
+// clientConn is the transport a subscriber is reached through, a WebSocket or
+// an event stream. Frames are only written by the subscriber's writer, see
+// startWriter.
+type clientConn interface {
+	send(msg Message) error
+	// close ends the connection, with code and text where the transport can
+	// carry them.
+	close(code int, text string) error
+}
+
+type wsConn struct {
+	*websocket.Conn
+}
+
+func (c wsConn) send(msg Message) error {
+	return c.WriteJSON(msg)
+}
+
+func (c wsConn) close(code int, text string) error {
+	return c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(closeFrameTimeout))
+}
+
+func (c wsConn) ping() error {
+	return c.WriteControl(websocket.PingMessage, nil, time.Now().Add(closeFrameTimeout))
+}
+
+func newSubscriber(cancel context.CancelFunc, roomID, requestID, ownerTokenHash string) *subscriber {
+	return &subscriber{
+		cancel:         cancel,
+		requestID:      requestID,
+		log:            slog.With("room_id", roomID, "request_id", requestID),
+		channels:       map[string]bool{ChannelQuestions: true},
+		ownerTokenHash: ownerTokenHash,
+	}
+}
+
+// newRoomSubscriber sets up the subscription of r to room, whatever its
+// transport. The owner token makes it a host right away.
+func newRoomSubscriber(r *http.Request, room pg.Room, requestID string, cancel context.CancelFunc) *subscriber {
+	sub := newSubscriber(cancel, room.ID.String(), requestID, room.OwnerTokenHash)
+	sub.host = utils.MatchTokenHash(utils.ParseBearerToken(r), room.OwnerTokenHash)
+	sub.capacity = int(room.MaxSubscribers)
+	sub.organizationID = room.OrganizationID
+	sub.meteredAt = time.Now()
+
+	return sub
+}
+
+// sendTo writes a reply to a single connection, outside the room event sequence.
+func (h apiHandler) sendTo(sub *subscriber, msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeLocked(sub, msg)
+}
+
+// readCommands consumes client frames until the connection is closed,
+// cancelling the subscription once reading fails.
+func (h apiHandler) readCommands(c wsConn, roomID string, sub *subscriber) {
+	defer sub.cancel()
+
+	for {
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				sub.log.Warn("failed to read from client", "error", err)
+			}
+			return
+		}
+
+		var cmd clientCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			h.sendTo(sub, Message{
+				Kind:  MessageKindCommandRejected,
+				Value: MessageCommandRejected{Reason: "invalid json"},
+			})
+			continue
+		}
+
+		h.handleCommand(roomID, sub, cmd)
+	}
+}
+
+func (h apiHandler) handleCommand(roomID string, sub *subscriber, cmd clientCommand) {
+	switch cmd.Type {
+	case CommandHeartbeat:
+		h.handleHeartbeat(sub, cmd)
+	case CommandApplause:
+		h.mu.Lock()
+		waiting := sub.waiting
+		h.mu.Unlock()
+		if !waiting {
+			h.applause.clap(roomID, sub)
+		}
+	case CommandSubscribe, CommandUnsubscribe:
+		if !roomChannels[cmd.Channel] {
+			h.sendTo(sub, Message{
+				Kind:  MessageKindCommandRejected,
+				Value: MessageCommandRejected{Type: cmd.Type, Reason: "unknown channel"},
+			})
+			return
+		}
+
+		if cmd.Type == CommandSubscribe && hostChannels[cmd.Channel] {
+			//? Browsers can't set headers on upgrade, so the token may come with the command
+			h.mu.Lock()
+			host, ownerTokenHash := sub.host, sub.ownerTokenHash
+			h.mu.Unlock()
+			if !host && !utils.MatchTokenHash(cmd.Token, ownerTokenHash) {
+				h.sendTo(sub, Message{
+					Kind:  MessageKindCommandRejected,
+					Value: MessageCommandRejected{Type: cmd.Type, Reason: "unauthorized"},
+				})
+				return
+			}
+		}
+
+		kind := MessageKindChannelSubscribed
+		h.mu.Lock()
+		if cmd.Type == CommandSubscribe {
+			sub.channels[cmd.Channel] = true
+			sub.host = sub.host || hostChannels[cmd.Channel]
+		} else {
+			delete(sub.channels, cmd.Channel)
+			kind = MessageKindChannelUnsubscribed
+		}
+		h.mu.Unlock()
+
+		h.sendTo(sub, Message{Kind: kind, Value: MessageChannelUpdated{Channel: cmd.Channel}})
+		if cmd.Type == CommandSubscribe && cmd.Channel == ChannelLeaderboard {
+			h.sendLeaderboardSnapshot(roomID, sub)
+		}
+	default:
+		h.sendTo(sub, Message{
+			Kind:  MessageKindCommandRejected,
+			Value: MessageCommandRejected{Type: cmd.Type, Reason: "unknown command"},
+		})
+	}
+}
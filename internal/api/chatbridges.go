@@ -0,0 +1,568 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/chatbridge"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/language"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	chatBridgeSyncInterval = time.Minute
+	// chatBridgeOutbox is how many room events wait to be posted back to a
+	// chat, later ones being dropped while it's full.
+	chatBridgeOutbox = 64
+)
+
+var (
+	twitchChannelPattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,25}$`)
+	youtubeVideoPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	// matrixRoomPattern is a room id like "!abc:matrix.org" or an alias like
+	// "#week-tech:matrix.org".
+	matrixRoomPattern = regexp.MustCompile(`^[!#][^:\s]{1,200}:[A-Za-z0-9.-]{1,200}(:[0-9]{1,5})?$`)
+	ircChannelPattern = regexp.MustCompile(`^[^\s,:\x07#][^\s,:\x07]{0,49}$`)
+)
+
+// ChatBridge is the chat a room mirrors questions from.
+type ChatBridge struct {
+	RoomID        string    `json:"room_id"`
+	Platform      string    `json:"platform"`
+	Channel       string    `json:"channel"`
+	Prefix        string    `json:"prefix"`
+	RatePerMinute int32     `json:"rate_per_minute"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func mapChatBridge(bridge pg.RoomChatBridge) ChatBridge {
+	return ChatBridge{
+		RoomID:        bridge.RoomID.String(),
+		Platform:      bridge.Platform,
+		Channel:       bridge.Channel,
+		Prefix:        bridge.Prefix,
+		RatePerMinute: bridge.RatePerMinute,
+		UpdatedAt:     bridge.UpdatedAt,
+	}
+}
+
+// chatBridges tracks the bridges running on this instance. Every instance
+// runs every bridge, the chat message ids recorded when posting keep a
+// question from being mirrored once per instance.
+type chatBridges struct {
+	mu      sync.Mutex
+	running map[uuid.UUID]runningChatBridge
+	wake    chan struct{}
+}
+
+type runningChatBridge struct {
+	updatedAt time.Time
+	cancel    context.CancelFunc
+	// outbox carries the room's events to post back, nil when the platform
+	// is only read.
+	outbox chan Message
+}
+
+func newChatBridges() *chatBridges {
+	return &chatBridges{running: make(map[uuid.UUID]runningChatBridge), wake: make(chan struct{}, 1)}
+}
+
+// kick has the bridges synced now, for changes made on this instance. Other
+// instances pick them up on their next sync.
+func (b *chatBridges) kick() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// mirror queues an event to be posted back to the chat of its room's bridge.
+// Only the instance that published the event sees it, so it's posted once.
+func (b *chatBridges) mirror(msg Message) {
+	if msg.Channel != "" || (msg.Kind != MessageKindMessageCreated && msg.Kind != MessageKindMessageAnswered) {
+		return
+	}
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	running, ok := b.running[roomID]
+	if !ok || running.outbox == nil {
+		return
+	}
+	select {
+	case running.outbox <- msg:
+	default:
+		slog.Warn("chat bridge outbox full, dropping event", "room_id", msg.RoomID, "kind", msg.Kind)
+	}
+}
+
+// youtubeAPIKey loads the YouTube key from the secret store, for when it
+// isn't set in the environment.
+func (h apiHandler) youtubeAPIKey(ctx context.Context) (string, error) {
+	key, err := h.secret(ctx, uuid.NullUUID{}, SecretYouTubeAPIKey)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return key, err
+}
+
+// matrixAccessToken loads the Matrix token from the secret store, for when
+// it isn't set in the environment.
+func (h apiHandler) matrixAccessToken(ctx context.Context) (string, error) {
+	token, err := h.secret(ctx, uuid.NullUUID{}, SecretMatrixAccessToken)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return token, err
+}
+
+func (h apiHandler) chatSource(platform string) chatbridge.Source {
+	switch platform {
+	case chatbridge.PlatformYouTube:
+		return h.youtube
+	case chatbridge.PlatformTwitch:
+		return h.twitch
+	case chatbridge.PlatformMatrix:
+		return h.matrix
+	case chatbridge.PlatformIRC:
+		return h.irc
+	}
+
+	return nil
+}
+
+// runChatBridges keeps a bridge running for every room configured with one,
+// restarting those whose configuration changed.
+func (h apiHandler) runChatBridges() {
+	ticker := time.NewTicker(chatBridgeSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		h.syncChatBridges()
+
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-h.chatBridges.wake:
+		}
+	}
+}
+
+func (h apiHandler) syncChatBridges() {
+	//* A standby posts nothing, the primary's bridges do the mirroring
+	if h.failover.standby.Load() {
+		h.chatBridges.mu.Lock()
+		defer h.chatBridges.mu.Unlock()
+		for roomID, running := range h.chatBridges.running {
+			running.cancel()
+			delete(h.chatBridges.running, roomID)
+		}
+		return
+	}
+
+	bridges, err := h.q.GetActiveChatBridges(h.bus.ctx)
+	if err != nil {
+		if h.bus.ctx.Err() == nil {
+			slog.Error("failed to get chat bridges", "error", err)
+		}
+		return
+	}
+
+	h.chatBridges.mu.Lock()
+	defer h.chatBridges.mu.Unlock()
+
+	wanted := make(map[uuid.UUID]bool, len(bridges))
+	for _, bridge := range bridges {
+		wanted[bridge.RoomID] = true
+		running, ok := h.chatBridges.running[bridge.RoomID]
+		if ok && running.updatedAt.Equal(bridge.UpdatedAt) {
+			continue
+		}
+		if ok {
+			running.cancel()
+		}
+
+		source := h.chatSource(bridge.Platform)
+		if source == nil {
+			continue
+		}
+		ctx, cancel := context.WithCancel(h.bus.ctx)
+		running = runningChatBridge{updatedAt: bridge.UpdatedAt, cancel: cancel}
+		if sink, ok := source.(chatbridge.Sink); ok {
+			running.outbox = make(chan Message, chatBridgeOutbox)
+			go h.mirrorChatBridge(ctx, bridge, sink, running.outbox)
+		}
+		h.chatBridges.running[bridge.RoomID] = running
+
+		cfg := chatbridge.Config{
+			Platform:      bridge.Platform,
+			Channel:       bridge.Channel,
+			Prefix:        bridge.Prefix,
+			RatePerMinute: int(bridge.RatePerMinute),
+		}
+		go chatbridge.Run(ctx, source, cfg, func(ctx context.Context, msg chatbridge.ChatMessage, question string) error {
+			return h.postChatQuestion(ctx, bridge, msg, question)
+		})
+		slog.Info("chat bridge started", "room_id", bridge.RoomID, "platform", bridge.Platform, "channel", bridge.Channel)
+	}
+
+	//* Removed, or the room was archived or deleted
+	for roomID, running := range h.chatBridges.running {
+		if !wanted[roomID] {
+			running.cancel()
+			delete(h.chatBridges.running, roomID)
+		}
+	}
+}
+
+// postChatQuestion mirrors a question from the chat into the room, as if it
+// was asked there: scored, announced and translated like any other.
+func (h apiHandler) postChatQuestion(ctx context.Context, bridge pg.RoomChatBridge, msg chatbridge.ChatMessage, question string) error {
+	//? Claimed before posting: the instance that records the chat message is the one that mirrors it
+	marked, err := h.q.MarkChatBridgeMessage(ctx, pg.MarkChatBridgeMessageParams{RoomID: bridge.RoomID, ExternalID: bridge.Platform + ":" + msg.ID})
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		return nil
+	}
+
+	room, err := h.q.GetRoom(ctx, bridge.RoomID)
+	if err != nil {
+		return err
+	}
+	if room.ArchivedAt.Valid || room.ClosedAt.Valid {
+		return nil
+	}
+
+	inserted, err := h.insertExternalQuestion(ctx, h.q, room, msg.Author, question, nil)
+	if err != nil {
+		return err
+	}
+	h.announceExternalQuestion(room, inserted)
+
+	return nil
+}
+
+// mirrorChatBridge posts the room's new questions and answers to the chat
+// until ctx is done. A post that fails is dropped, the chat only following
+// along.
+func (h apiHandler) mirrorChatBridge(ctx context.Context, bridge pg.RoomChatBridge, sink chatbridge.Sink, outbox <-chan Message) {
+	for {
+		var msg Message
+		select {
+		case <-ctx.Done():
+			return
+		case msg = <-outbox:
+		}
+
+		text, ok := h.chatBridgeText(ctx, msg)
+		if !ok {
+			continue
+		}
+		if err := sink.Send(ctx, bridge.Channel, h.redactor.String(text)); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to mirror to chat bridge", "room_id", bridge.RoomID, "platform", bridge.Platform, "channel", bridge.Channel, "error", err)
+		}
+	}
+}
+
+// chatBridgeText is how an event reads in the chat, false for those left out.
+func (h apiHandler) chatBridgeText(ctx context.Context, msg Message) (string, bool) {
+	switch value := msg.Value.(type) {
+	case MessageMessageCreated:
+		if value.AuthorName == "" {
+			return "Q: " + value.Message, true
+		}
+		return "Q: " + value.Message + " (" + value.AuthorName + ")", true
+
+	case MessageMessageAnswered:
+		//* The second message_answered only adds the answer's audio
+		if value.AudioURL != "" {
+			return "", false
+		}
+		roomID, err := uuid.Parse(value.RoomID)
+		if err != nil {
+			return "", false
+		}
+		id, err := uuid.Parse(value.ID)
+		if err != nil {
+			return "", false
+		}
+		message, err := h.q.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: roomID, ID: id})
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) && ctx.Err() == nil {
+				slog.Warn("failed to get answered message for chat bridge", "room_id", value.RoomID, "message_id", value.ID, "error", err)
+			}
+			return "", false
+		}
+		if message.HiddenAt.Valid || message.DeletedAt.Valid {
+			return "", false
+		}
+		if value.AnswerText == "" {
+			return "Answered: " + message.Message, true
+		}
+		return "Answered: " + message.Message + " — " + value.AnswerText, true
+	}
+
+	return "", false
+}
+
+// externalQuestion is a question asked on another platform, stored in a room.
+type externalQuestion struct {
+	id         uuid.UUID
+	question   string
+	authorName string
+	fields     map[string]string
+	held       bool
+	toxicity   float64
+}
+
+// insertExternalQuestion scores and stores a question asked on another
+// platform like one posted in the room, with q so it can be part of a
+// transaction. fields must have been validated against the room's form. The
+// caller announces it once stored for good.
+func (h apiHandler) insertExternalQuestion(ctx context.Context, q pg.Querier, room pg.Room, author, question string, fields map[string]string) (externalQuestion, error) {
+	//* A name the room wouldn't take is dropped, the question still counts
+	authorName, errs := forms.ValidateAuthorName(room.PostingMode, author)
+	if len(errs) > 0 {
+		authorName = ""
+	}
+
+	var toxicityScore pgtype.Float4
+	var hiddenAt pgtype.Timestamptz
+	score, hold, scored := h.scorer.Check(ctx, question)
+	if scored {
+		toxicityScore = pgtype.Float4{Float32: float32(score), Valid: true}
+	}
+	if hold {
+		hiddenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	rawFields, err := json.Marshal(fields)
+	if err != nil {
+		return externalQuestion{}, err
+	}
+
+	messageID, err := q.InsertMessage(ctx, pg.InsertMessageParams{
+		RoomID:     room.ID,
+		Message:    question,
+		Fields:     rawFields,
+		AuthorName: authorName,
+		Language:   language.Detect(question),
+		Toxicity:   toxicityScore,
+		HiddenAt:   hiddenAt,
+	})
+	if err != nil {
+		return externalQuestion{}, err
+	}
+
+	return externalQuestion{id: messageID, question: question, authorName: authorName, fields: fields, held: hold, toxicity: score}, nil
+}
+
+// announceExternalQuestion publishes a question stored by
+// insertExternalQuestion, to the host alone when it's held.
+func (h apiHandler) announceExternalQuestion(room pg.Room, question externalQuestion) {
+	if question.held {
+		h.publish(Message{
+			Kind:    MessageKindMessageHeld,
+			Channel: ChannelBackstage,
+			RoomID:  room.ID.String(),
+			Value: MessageMessageHeld{
+				ID:         question.id.String(),
+				RoomID:     room.ID.String(),
+				Message:    question.question,
+				AuthorName: question.authorName,
+				Toxicity:   question.toxicity,
+			}})
+		return
+	}
+
+	h.publish(Message{
+		Kind:   MessageKindMessageCreated,
+		RoomID: room.ID.String(),
+		Value: MessageMessageCreated{
+			ID:         question.id.String(),
+			Message:    question.question,
+			Fields:     question.fields,
+			AuthorName: question.authorName,
+		}})
+	h.queueTranslations(room, question.id, question.question)
+}
+
+func (h apiHandler) handleGetChatBridge(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	bridge, err := h.q.GetRoomChatBridge(r.Context(), room.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "chat bridge is not enabled", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get chat bridge", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(mapChatBridge(bridge))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handlePutChatBridge sets the chat the room mirrors questions from: a
+// Twitch channel name, or the id of a YouTube live video.
+func (h apiHandler) handlePutChatBridge(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
+	}
+
+	type _body struct {
+		Platform      string `json:"platform"`
+		Channel       string `json:"channel"`
+		Prefix        string `json:"prefix"`
+		RatePerMinute *int   `json:"rate_per_minute"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	var errs []forms.FieldError
+	body.Channel = strings.TrimSpace(body.Channel)
+	switch body.Platform {
+	case chatbridge.PlatformTwitch:
+		body.Channel = strings.TrimPrefix(body.Channel, "#")
+		if !twitchChannelPattern.MatchString(body.Channel) {
+			errs = append(errs, forms.FieldError{Field: "channel", Message: "must be a twitch channel name"})
+		}
+	case chatbridge.PlatformYouTube:
+		if !youtubeVideoPattern.MatchString(body.Channel) {
+			errs = append(errs, forms.FieldError{Field: "channel", Message: "must be the id of the live video"})
+		}
+		configured, err := h.youtube.Configured(r.Context())
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to load youtube api key", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !configured {
+			errs = append(errs, forms.FieldError{Field: "platform", Message: "youtube is not configured on this server"})
+		}
+	case chatbridge.PlatformMatrix:
+		if !matrixRoomPattern.MatchString(body.Channel) {
+			errs = append(errs, forms.FieldError{Field: "channel", Message: "must be a matrix room id or alias"})
+		}
+		configured, err := h.matrix.Configured(r.Context())
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to load matrix access token", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !configured {
+			errs = append(errs, forms.FieldError{Field: "platform", Message: "matrix is not configured on this server"})
+		}
+	case chatbridge.PlatformIRC:
+		body.Channel = strings.TrimPrefix(body.Channel, "#")
+		if !ircChannelPattern.MatchString(body.Channel) {
+			errs = append(errs, forms.FieldError{Field: "channel", Message: "must be an irc channel name"})
+		}
+		if !h.irc.Configured() {
+			errs = append(errs, forms.FieldError{Field: "platform", Message: "irc is not configured on this server"})
+		}
+	default:
+		errs = append(errs, forms.FieldError{Field: "platform", Message: "must be youtube, twitch, matrix or irc"})
+	}
+	body.Prefix = strings.TrimSpace(body.Prefix)
+	if body.Prefix == "" {
+		body.Prefix = chatbridge.DefaultPrefix
+	}
+	if len(body.Prefix) > chatbridge.MaxPrefixLength || strings.ContainsFunc(body.Prefix, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		errs = append(errs, forms.FieldError{Field: "prefix", Message: "must be a single word of up to 32 characters"})
+	}
+	rate := chatbridge.DefaultRatePerMinute
+	if body.RatePerMinute != nil {
+		rate = *body.RatePerMinute
+	}
+	if rate < 1 || rate > chatbridge.MaxRatePerMinute {
+		errs = append(errs, forms.FieldError{Field: "rate_per_minute", Message: "must be between 1 and 120"})
+	}
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	bridge, err := h.q.UpsertRoomChatBridge(r.Context(), pg.UpsertRoomChatBridgeParams{
+		RoomID:        room.ID,
+		Platform:      body.Platform,
+		Channel:       body.Channel,
+		Prefix:        body.Prefix,
+		RatePerMinute: int32(rate),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save chat bridge", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	h.chatBridges.kick()
+
+	data, err := json.Marshal(mapChatBridge(bridge))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteChatBridge(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomChatBridge(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete chat bridge", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "chat bridge is not enabled", http.StatusNotFound)
+		return
+	}
+	h.chatBridges.kick()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// HeaderConsistencyToken carries the consistency token of a write, for the
+// reads that follow to see it even when served by a replica.
+const HeaderConsistencyToken = "X-Consistency-Token"
+
+// consistencyToken is the token a read must be consistent with, from the
+// header or the consistency_token query parameter.
+func consistencyToken(r *http.Request) string {
+	if token := r.Header.Get(HeaderConsistencyToken); token != "" {
+		return token
+	}
+
+	return r.URL.Query().Get("consistency_token")
+}
+
+// writeConsistencyToken sets the token of the writes committed so far on the
+// response, when reads may miss them. It's best effort: without it the client
+// may only read its write a little later.
+func (h apiHandler) writeConsistencyToken(w http.ResponseWriter, r *http.Request) string {
+	token, err := h.q.Position(r.Context())
+	if err != nil {
+		slog.Error("failed to get consistency token", "error", err)
+		return ""
+	}
+	if token != "" {
+		w.Header().Set(HeaderConsistencyToken, token)
+	}
+
+	return token
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+)
+
+const (
+	defaultDashboardWindow = time.Hour
+	maxDashboardWindow     = 7 * 24 * time.Hour
+)
+
+// handleGetDashboard returns every room of the API key's organization with
+// what the host dashboard shows for it, so the screen loads in one request.
+// Recent activity counts what happened within ?window, an hour by default.
+func (h apiHandler) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	key, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "an api key is required", http.StatusUnauthorized)
+		return
+	}
+
+	window := defaultDashboardWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		var err error
+		window, err = time.ParseDuration(raw)
+		if err != nil || window < time.Minute || window > maxDashboardWindow {
+			http.Error(w, "invalid window", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rows, err := h.q.GetOrganizationDashboardRooms(r.Context(), pg.GetOrganizationDashboardRoomsParams{
+		Since:          time.Now().Add(-window),
+		OrganizationID: uuid.NullUUID{UUID: key.OrganizationID, Valid: true},
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get dashboard rooms", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type recentActivity struct {
+		Messages  int64 `json:"messages"`
+		Reactions int64 `json:"reactions"`
+	}
+	type dashboardRoom struct {
+		ID              string         `json:"id"`
+		Code            string         `json:"code"`
+		Theme           string         `json:"theme"`
+		Status          string         `json:"status"`
+		CreatedAt       time.Time      `json:"created_at"`
+		UnansweredCount int64          `json:"unanswered_count"`
+		LastActivity    *time.Time     `json:"last_activity"`
+		Viewers         int            `json:"viewers"`
+		RecentActivity  recentActivity `json:"recent_activity"`
+	}
+	type response struct {
+		Window string          `json:"window"`
+		Rooms  []dashboardRoom `json:"rooms"`
+	}
+
+	res := response{Window: window.String(), Rooms: make([]dashboardRoom, 0, len(rows))}
+
+	//* Viewers are read in one pass so the lock is held once, not per room
+	h.mu.Lock()
+	for _, row := range rows {
+		status := roomStatus(row.ArchivedAt, row.ClosedAt)
+		room := dashboardRoom{
+			ID:              row.ID.String(),
+			Code:            row.Code,
+			Theme:           row.Theme,
+			Status:          status,
+			CreatedAt:       row.CreatedAt,
+			UnansweredCount: row.UnansweredCount,
+			Viewers:         h.admittedLocked(row.ID.String()),
+			RecentActivity:  recentActivity{Messages: row.RecentMessages, Reactions: row.RecentReactions},
+		}
+		if row.LastActivity.Valid {
+			room.LastActivity = &row.LastActivity.Time
+		}
+		res.Rooms = append(res.Rooms, room)
+	}
+	h.mu.Unlock()
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// Dead letters are written by the queries marking work failed for good,
+// named after where the work comes from.
+const (
+	DeadLetterSourceJob     = "job"
+	DeadLetterSourceWebhook = "webhook"
+
+	defaultDeadLettersLimit  = 50
+	maxDeadLettersLimit      = 500
+	deadLetterSampleInterval = 30 * time.Second
+)
+
+var deadLetterSources = []string{DeadLetterSourceJob, DeadLetterSourceWebhook}
+
+// DeadLetter is async work that ran out of attempts: a job, such as an
+// event report export, or a webhook delivery. Ref is the job id, or
+// "<webhook_id>:<event_id>" for deliveries.
+type DeadLetter struct {
+	ID             string    `json:"id"`
+	Source         string    `json:"source"`
+	Ref            string    `json:"ref"`
+	Kind           string    `json:"kind"`
+	OrganizationID *string   `json:"organization_id"`
+	RoomID         *string   `json:"room_id"`
+	Error          string    `json:"error"`
+	Attempts       int32     `json:"attempts"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func mapDeadLetter(d pg.DeadLetter) DeadLetter {
+	dl := DeadLetter{
+		ID:        d.ID.String(),
+		Source:    d.Source,
+		Ref:       d.Ref,
+		Kind:      d.Kind,
+		Error:     d.Error,
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt,
+	}
+	if d.OrganizationID.Valid {
+		id := d.OrganizationID.UUID.String()
+		dl.OrganizationID = &id
+	}
+	if d.RoomID.Valid {
+		id := d.RoomID.UUID.String()
+		dl.RoomID = &id
+	}
+
+	return dl
+}
+
+type getDeadLettersResponse struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+}
+
+// handleGetDeadLetters lists dead letters newest first, of every source or
+// the ?source one.
+func (h apiHandler) handleGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var source pgtype.Text
+	if raw := r.URL.Query().Get("source"); raw != "" {
+		if raw != DeadLetterSourceJob && raw != DeadLetterSourceWebhook {
+			http.Error(w, "source must be one of "+strings.Join(deadLetterSources, ", "), http.StatusBadRequest)
+			return
+		}
+		source = pgtype.Text{String: raw, Valid: true}
+	}
+	limit := defaultDeadLettersLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxDeadLettersLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxDeadLettersLimit), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	letters, err := h.q.GetDeadLetters(r.Context(), pg.GetDeadLettersParams{Source: source, MaxDeadLetters: int32(limit)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get dead letters", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	res := getDeadLettersResponse{DeadLetters: make([]DeadLetter, 0, len(letters))}
+	for _, d := range letters {
+		res.DeadLetters = append(res.DeadLetters, mapDeadLetter(d))
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleRetryDeadLetter queues the work again from its first attempt and
+// drops the dead letter. When the work is gone or was already retried, the
+// dead letter is dropped all the same and 410 tells there was nothing to do.
+func (h apiHandler) handleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUIDParam(r, "dead_letter_id")
+	if err != nil {
+		http.Error(w, "invalid dead letter id", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	letter, err := tx.GetDeadLetter(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "dead letter not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get dead letter", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	requeued, err := requeueDeadLetter(r.Context(), tx, letter)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to retry dead letter", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.DeleteDeadLetter(r.Context(), letter.ID); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete dead letter", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if !requeued {
+		http.Error(w, "the work is no longer failed, the dead letter was discarded", http.StatusGone)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requeueDeadLetter queues the failed work letter points at, reporting
+// false when it isn't failed anymore.
+func requeueDeadLetter(ctx context.Context, q store.Tx, letter pg.DeadLetter) (bool, error) {
+	var (
+		requeued int64
+		err      error
+	)
+	switch letter.Source {
+	case DeadLetterSourceJob:
+		jobID, parseErr := uuid.Parse(letter.Ref)
+		if parseErr != nil {
+			return false, fmt.Errorf("invalid job ref %q: %w", letter.Ref, parseErr)
+		}
+		requeued, err = q.RetryJob(ctx, jobID)
+	case DeadLetterSourceWebhook:
+		webhookID, eventID, parseErr := parseWebhookDeliveryKey(letter.Ref)
+		if parseErr != nil {
+			return false, parseErr
+		}
+		requeued, err = q.RedriveWebhookDelivery(ctx, pg.RedriveWebhookDeliveryParams{WebhookID: webhookID, EventID: eventID})
+	default:
+		return false, fmt.Errorf("unknown dead letter source %q", letter.Source)
+	}
+
+	return requeued > 0, err
+}
+
+// handleDiscardDeadLetter drops a dead letter, leaving the work failed.
+func (h apiHandler) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUIDParam(r, "dead_letter_id")
+	if err != nil {
+		http.Error(w, "invalid dead letter id", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.q.DeleteDeadLetter(r.Context(), id)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete dead letter", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runDeadLetterMetrics keeps the dead letter gauges current. They're read
+// from the database, every instance reports the same values.
+func (h apiHandler) runDeadLetterMetrics() {
+	ticker := time.NewTicker(deadLetterSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			h.sampleDeadLetters(h.bus.ctx)
+		}
+	}
+}
+
+func (h apiHandler) sampleDeadLetters(ctx context.Context) {
+	stats, err := h.q.GetDeadLetterStats(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to get dead letter stats", "error", err)
+		}
+		return
+	}
+
+	//* Sources without dead letters report zero rather than their last value
+	for _, source := range deadLetterSources {
+		metrics.DeadLetters.WithLabelValues(source).Set(0)
+		metrics.DeadLetterOldestAge.WithLabelValues(source).Set(0)
+	}
+	for _, s := range stats {
+		metrics.DeadLetters.WithLabelValues(s.Source).Set(float64(s.Count))
+		metrics.DeadLetterOldestAge.WithLabelValues(s.Source).Set(time.Since(s.Oldest).Seconds())
+	}
+}
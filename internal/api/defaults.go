@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/translate"
+)
+
+// RoomDefaults are the settings an event hands down to the rooms scheduled in
+// it. Unset fields leave rooms with their own value, translate_to being unset
+// when null and turning translations off when empty.
+type RoomDefaults struct {
+	PostingMode    string   `json:"posting_mode,omitempty"`
+	MaxSubscribers *int32   `json:"max_subscribers,omitempty"`
+	TranslateTo    []string `json:"translate_to"`
+}
+
+func parseRoomDefaults(raw []byte) RoomDefaults {
+	var defaults RoomDefaults
+	_ = json.Unmarshal(raw, &defaults)
+
+	return defaults
+}
+
+// normalize validates the defaults like room creation would, canonicalizing
+// the language tags.
+func (d *RoomDefaults) normalize() []forms.FieldError {
+	var errs []forms.FieldError
+	if d.PostingMode != "" && !forms.IsPostingMode(d.PostingMode) {
+		errs = append(errs, forms.FieldError{Field: "posting_mode", Message: "must be optional, named or anonymous"})
+	}
+	if d.MaxSubscribers != nil && *d.MaxSubscribers < 0 {
+		errs = append(errs, forms.FieldError{Field: "max_subscribers", Message: "must not be negative"})
+	}
+	if d.TranslateTo != nil {
+		translateTo, ok := parseTranslateTargets(d.TranslateTo)
+		if !ok {
+			errs = append(errs, forms.FieldError{
+				Field:   "translate_to",
+				Message: fmt.Sprintf("must be at most %d valid language tags", translate.MaxTargets),
+			})
+		}
+		d.TranslateTo = translateTo
+	}
+
+	return errs
+}
+
+// inherit fills in the settings a new room was created without.
+func (d RoomDefaults) inherit(postingMode *string, maxSubscribers *int32, translateTo *[]string) {
+	if *postingMode == "" {
+		*postingMode = d.PostingMode
+	}
+	if *maxSubscribers == 0 && d.MaxSubscribers != nil {
+		*maxSubscribers = *d.MaxSubscribers
+	}
+	if *translateTo == nil {
+		*translateTo = d.TranslateTo
+	}
+}
+
+func (h apiHandler) handleGetEventRoomDefaults(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(parseRoomDefaults(event.RoomDefaults))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handlePutEventRoomDefaults replaces the defaults of the event. Rooms
+// already scheduled keep their settings until the defaults are applied.
+func (h apiHandler) handlePutEventRoomDefaults(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	var defaults RoomDefaults
+	if err := json.NewDecoder(r.Body).Decode(&defaults); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if errs := defaults.normalize(); len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	raw, err := json.Marshal(defaults)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal room defaults", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err := h.q.SetEventRoomDefaults(r.Context(), pg.SetEventRoomDefaultsParams{RoomDefaults: raw, ID: event.ID}); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to set event room defaults", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(raw)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleApplyEventRoomDefaults pushes the event defaults to every room
+// scheduled in its tracks, overwriting what each room had set.
+func (h apiHandler) handleApplyEventRoomDefaults(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	defaults := parseRoomDefaults(event.RoomDefaults)
+	params := pg.ApplyEventRoomDefaultsParams{TranslateTo: defaults.TranslateTo, EventID: event.ID}
+	if defaults.PostingMode != "" {
+		params.PostingMode = pgtype.Text{String: defaults.PostingMode, Valid: true}
+	}
+	if defaults.MaxSubscribers != nil {
+		params.MaxSubscribers = pgtype.Int4{Int32: *defaults.MaxSubscribers, Valid: true}
+	}
+
+	updated, err := h.q.ApplyEventRoomDefaults(r.Context(), params)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to apply event room defaults", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Updated int64 `json:"updated"`
+	}
+
+	data, err := json.Marshal(response{Updated: updated})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
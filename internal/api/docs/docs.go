@@ -0,0 +1,274 @@
+// Package docs builds an OpenAPI 3.1 document from the routes a router
+// serves and the Go types its handlers decode and marshal, and serves it
+// along with a Swagger UI to browse it.
+package docs
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/schema"
+)
+
+const OpenAPIVersion = "3.1.0"
+
+// Operation describes what a route takes and returns. Request and Response
+// are zero values of the types the handler decodes and marshals, or a
+// schema.Object when there's no Go type to derive one from.
+type Operation struct {
+	Summary string
+	Tags    []string
+	// Request is the JSON body, nil when the route takes none.
+	Request any
+	// Response is the JSON body of a successful response, nil when there is
+	// none or it isn't JSON.
+	Response any
+	// Status is the successful status code, 200 when zero.
+	Status int
+	// Stream is each message sent after the response on websockets and
+	// event streams.
+	Stream any
+}
+
+// Spec collects the operations of an API. It's filled once while the router
+// is built and safe to serve from afterwards.
+type Spec struct {
+	title   string
+	version string
+
+	mu         sync.RWMutex
+	paths      map[string]map[string]Operation
+	components schema.Object
+}
+
+func New(title, version string) *Spec {
+	return &Spec{
+		title:      title,
+		version:    version,
+		paths:      make(map[string]map[string]Operation),
+		components: schema.Object{},
+	}
+}
+
+// Add documents method on path, a chi route pattern.
+func (s *Spec) Add(method, path string, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path = openAPIPath(path)
+	if s.paths[path] == nil {
+		s.paths[path] = make(map[string]Operation)
+	}
+	s.paths[path][strings.ToLower(method)] = op
+}
+
+// Component registers a named schema, for Ref to point at.
+func (s *Spec) Component(name string, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.components[name] = schemaOf(v)
+}
+
+// Ref points at a schema registered with Component.
+func Ref(name string) schema.Object {
+	return schema.Object{"$ref": "#/components/schemas/" + name}
+}
+
+// Document renders the OpenAPI document.
+func (s *Spec) Document() schema.Object {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths := schema.Object{}
+	for path, methods := range s.paths {
+		item := schema.Object{}
+		for method, op := range methods {
+			item[method] = operation(path, op)
+		}
+		paths[path] = item
+	}
+
+	return schema.Object{
+		"openapi": OpenAPIVersion,
+		"info": schema.Object{
+			"title":   s.title,
+			"version": s.version,
+		},
+		"jsonSchemaDialect": schema.Draft,
+		"paths":             paths,
+		"components":        schema.Object{"schemas": s.components},
+	}
+}
+
+func operation(path string, op Operation) schema.Object {
+	o := schema.Object{}
+	if op.Summary != "" {
+		o["summary"] = op.Summary
+	}
+	if len(op.Tags) > 0 {
+		o["tags"] = op.Tags
+	}
+	if params := pathParameters(path); len(params) > 0 {
+		o["parameters"] = params
+	}
+	if op.Request != nil {
+		o["requestBody"] = schema.Object{
+			"required": true,
+			"content":  schema.Object{"application/json": schema.Object{"schema": schemaOf(op.Request)}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := schema.Object{"description": http.StatusText(status)}
+	if op.Response != nil {
+		response["content"] = schema.Object{"application/json": schema.Object{"schema": schemaOf(op.Response)}}
+	}
+	if op.Stream != nil {
+		response["x-stream-message"] = schemaOf(op.Stream)
+	}
+	o["responses"] = schema.Object{strconv.Itoa(status): response}
+
+	return o
+}
+
+// pathParameters declares the {name} segments of path.
+func pathParameters(path string) []schema.Object {
+	var params []schema.Object
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		params = append(params, schema.Object{
+			"name":     strings.TrimSuffix(name, "}"),
+			"in":       "path",
+			"required": true,
+			"schema":   schema.Object{"type": "string"},
+		})
+	}
+
+	return params
+}
+
+// openAPIPath turns a chi pattern into an OpenAPI path: regexps are dropped
+// from parameters and so is the trailing slash of subrouter roots.
+func openAPIPath(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			name, _, _ := strings.Cut(strings.TrimSuffix(segment[1:], "}"), ":")
+			segments[i] = "{" + name + "}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return path
+}
+
+func schemaOf(v any) schema.Object {
+	if s, ok := v.(schema.Object); ok {
+		return s
+	}
+
+	return schema.For(v)
+}
+
+// Handler serves the document as JSON, rendered once.
+func (s *Spec) Handler() http.Handler {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			data, err = json.Marshal(s.Document())
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to marshal openapi document", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write(data)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// * Swagger UI is loaded from its CDN build, nothing is vendored
+var uiPage = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+</script>
+</body>
+</html>
+`))
+
+// UIHandler serves a Swagger UI page browsing the document at specURL.
+func (s *Spec) UIHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := uiPage.Execute(w, struct{ Title, SpecURL string }{s.title, specURL})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to render swagger ui", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// AddRoutes documents every route of routes under one of prefixes, taking
+// the operation from ops by "METHOD /path" when there is one. Routes without
+// are listed with their parameters only, tagged by their first segment.
+func (s *Spec) AddRoutes(routes chi.Routes, ops map[string]Operation, prefixes ...string) error {
+	return chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(route, prefix) }) {
+			return nil
+		}
+
+		path := openAPIPath(route)
+		op, ok := ops[method+" "+path]
+		if !ok {
+			op = Operation{Tags: defaultTags(path)}
+		}
+		s.Add(method, path, op)
+
+		return nil
+	})
+}
+
+func defaultTags(path string) []string {
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" && segment != "api" && !strings.HasPrefix(segment, "{") {
+			return []string{segment}
+		}
+	}
+
+	return nil
+}
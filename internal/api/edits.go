@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/language"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+	"github.com/luiz504/week-tech-go-server/internal/validation"
+)
+
+const (
+	MessageKindMessageEdited = "message_edited"
+)
+
+type MessageMessageEdited struct {
+	ID       string    `json:"id"`
+	RoomID   string    `json:"room_id"`
+	Message  string    `json:"message"`
+	EditedAt time.Time `json:"edited_at"`
+	Version  int32     `json:"version"`
+}
+
+const defaultEditWindow = 5 * time.Minute
+
+// editWindowFromEnv reads WS_MESSAGE_EDIT_WINDOW as a Go duration, e.g. "5m".
+func editWindowFromEnv() time.Duration {
+	raw := os.Getenv("WS_MESSAGE_EDIT_WINDOW")
+	if raw == "" {
+		return defaultEditWindow
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil || window < 0 {
+		slog.Warn("invalid WS_MESSAGE_EDIT_WINDOW, using default", "value", raw, "default", defaultEditWindow)
+		return defaultEditWindow
+	}
+
+	return window
+}
+
+type editMessageRequest struct {
+	Message string `json:"message"`
+	// * The version edited, when not sent as If-Match
+	Version *int32 `json:"version"`
+}
+
+type editMessageResponse struct {
+	ID       string    `json:"id"`
+	Message  string    `json:"message"`
+	EditedAt time.Time `json:"edited_at"`
+	Version  int32     `json:"version"`
+}
+
+// handleEditMessage lets the author of a question reword it within the edit
+// window, for as long as it isn't answered. The previous text is kept in
+// message_edits. The edit is made against a version of the message, see
+// expectedVersion.
+func (h apiHandler) handleEditMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+	messageId, err := utils.ParseUUIDParam(r, "message_id")
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, ok := session.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "session required", http.StatusUnauthorized)
+		return
+	}
+
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !message.SessionID.Valid || message.SessionID.UUID != sessionID {
+		http.Error(w, "only the author can edit this message", http.StatusForbidden)
+		return
+	}
+	if message.Answered {
+		http.Error(w, "answered messages can't be edited", http.StatusConflict)
+		return
+	}
+	if time.Since(message.CreatedAt) > h.editWindow {
+		http.Error(w, "edit window has expired", http.StatusForbidden)
+		return
+	}
+
+	var body editMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	version, ok := expectedVersion(w, r, body.Version)
+	if !ok {
+		return
+	}
+	if version != message.Version {
+		respondVersionConflict(w)
+		return
+	}
+	var v validation.Validator
+	v.Text("message", &body.Message, 1, forms.DefaultMaxLength)
+	if v.Respond(w) {
+		return
+	}
+
+	if body.Message == message.Message {
+		w.Header().Set("ETag", messageETag(message.Version))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	edited, err := h.q.EditMessage(r.Context(), pg.EditMessageParams{
+		ID:       messageId,
+		Version:  version,
+		Message:  body.Message,
+		Language: language.Detect(body.Message),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondVersionConflict(w)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to edit message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(editMessageResponse{
+		ID:       messageId.String(),
+		Message:  edited.Message,
+		EditedAt: edited.EditedAt.Time,
+		Version:  edited.Version,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", messageETag(edited.Version))
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	h.publish(Message{
+		Kind:   MessageKindMessageEdited,
+		RoomID: roomID.String(),
+		Value: MessageMessageEdited{
+			ID:       messageId.String(),
+			RoomID:   roomID.String(),
+			Message:  edited.Message,
+			EditedAt: edited.EditedAt.Time,
+			Version:  edited.Version,
+		},
+	})
+
+	if h.translator != nil {
+		room, err := h.q.GetRoom(r.Context(), roomID)
+		if err != nil {
+			slog.Error("failed to get room for translations", "room_id", roomID, "error", err)
+			return
+		}
+		h.queueTranslations(room, messageId, edited.Message)
+	}
+}
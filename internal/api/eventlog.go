@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/jobs"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	// eventExportPage is how many events are read, written and flushed at a
+	// time, so an export holds one page in memory whatever the log's size.
+	eventExportPage = 500
+	// eventExportChunkTimeout is how long a client gets to take each page.
+	// The deadline moves along with the export, a slow reader is only cut
+	// off once it stalls.
+	eventExportChunkTimeout = 30 * time.Second
+)
+
+const (
+	jobKindPruneRoomEvents = "prune_room_events"
+	// leaderLockName is the lock the instances campaign for to schedule the
+	// periodic jobs, pruning among them.
+	leaderLockName = "wsrs_scheduler"
+
+	defaultEventLogPruneInterval = time.Hour
+)
+
+// eventLogRetention bounds the room event log, pruned by a periodic job. A
+// zero rule is off, and with both off the log is kept forever.
+type eventLogRetention struct {
+	// MaxEvents is how many of the latest events each room keeps.
+	MaxEvents int64
+	// MaxAge is how long an event is kept.
+	MaxAge   time.Duration
+	Interval time.Duration
+}
+
+func (r eventLogRetention) enabled() bool {
+	return r.MaxEvents > 0 || r.MaxAge > 0
+}
+
+// eventLogRetentionFromEnv reads WS_EVENT_LOG_MAX_EVENTS,
+// WS_EVENT_LOG_MAX_AGE and WS_EVENT_LOG_PRUNE_INTERVAL (1h by default).
+func eventLogRetentionFromEnv() eventLogRetention {
+	return eventLogRetention{
+		MaxEvents: int64(positiveIntFromEnv("WS_EVENT_LOG_MAX_EVENTS", 0)),
+		MaxAge:    positiveDurationFromEnv("WS_EVENT_LOG_MAX_AGE", 0),
+		Interval:  positiveDurationFromEnv("WS_EVENT_LOG_PRUNE_INTERVAL", defaultEventLogPruneInterval),
+	}
+}
+
+// runPruneRoomEventsJob deletes the events past either retention rule.
+func (h apiHandler) runPruneRoomEventsJob(ctx context.Context, payload []byte) (jobs.Output, error) {
+	if h.eventLog.MaxAge > 0 {
+		pruned, err := h.q.DeleteRoomEventsBefore(ctx, time.Now().Add(-h.eventLog.MaxAge))
+		if err != nil {
+			return jobs.Output{}, fmt.Errorf("prune events by age: %w", err)
+		}
+		metrics.EventLogPruned.WithLabelValues("age").Add(float64(pruned))
+	}
+	if h.eventLog.MaxEvents > 0 {
+		pruned, err := h.q.DeleteRoomEventsBeyond(ctx, h.eventLog.MaxEvents)
+		if err != nil {
+			return jobs.Output{}, fmt.Errorf("prune events by count: %w", err)
+		}
+		metrics.EventLogPruned.WithLabelValues("count").Add(float64(pruned))
+	}
+	metrics.EventLogPrunedAt.SetToCurrentTime()
+
+	return jobs.Output{}, nil
+}
+
+// recordEvent appends a sequenced event to the room's log. It's best effort:
+// subscribers still get the event when it can't be stored.
+func (h apiHandler) recordEvent(ctx context.Context, roomID uuid.UUID, msg Message) {
+	value, err := json.Marshal(msg.Value)
+	if err != nil {
+		slog.Error("failed to marshal event for the log", "room_id", msg.RoomID, "kind", msg.Kind, "error", err)
+		return
+	}
+	channel := msg.Channel
+	if channel == "" {
+		channel = ChannelQuestions
+	}
+
+	err = h.q.InsertRoomEvent(ctx, pg.InsertRoomEventParams{
+		RoomID:  roomID,
+		EventID: msg.EventID,
+		Channel: channel,
+		Kind:    msg.Kind,
+		Value:   value,
+	})
+	if err != nil {
+		slog.Error("failed to record room event", "room_id", msg.RoomID, "event_id", msg.EventID, "error", err)
+	}
+}
+
+// RoomEvent is a line of the event log export.
+type RoomEvent struct {
+	EventID   int64           `json:"event_id"`
+	Channel   string          `json:"channel"`
+	Kind      string          `json:"kind"`
+	Value     json.RawMessage `json:"value"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// handleExportRoomEvents streams the room's event log as NDJSON, one event
+// per line in sequence order, host channels included. ?after_event_id
+// resumes an export that was cut short after the last line received.
+func (h apiHandler) handleExportRoomEvents(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	var after int64
+	if raw := r.URL.Query().Get("after_event_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid after_event_id", http.StatusBadRequest)
+			return
+		}
+		after = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="room-`+room.ID.String()+`-events.ndjson"`)
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+
+	written := false
+	for {
+		events, err := h.q.GetRoomEvents(r.Context(), pg.GetRoomEventsParams{
+			RoomID:       room.ID,
+			AfterEventID: after,
+			MaxEvents:    eventExportPage,
+		})
+		if err != nil {
+			if !written {
+				helpers.LogErrorAndRespond(w, "failed to get room events", err, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+			//? Headers are out once a page has been written, the cut short body is all the client sees
+			slog.Error("failed to get room events", "room_id", room.ID, "after_event_id", after, "error", err)
+			return
+		}
+		written = true
+
+		//* Errors are ignored where the writer doesn't support deadlines
+		_ = rc.SetWriteDeadline(time.Now().Add(eventExportChunkTimeout))
+		for _, e := range events {
+			value, err := h.redactor.JSON(e.Value)
+			if err != nil {
+				slog.Error("failed to redact room event", "room_id", room.ID, "event_id", e.EventID, "error", err)
+				return
+			}
+			err = enc.Encode(RoomEvent{
+				EventID:   e.EventID,
+				Channel:   e.Channel,
+				Kind:      e.Kind,
+				Value:     value,
+				CreatedAt: e.CreatedAt,
+			})
+			if err != nil {
+				return
+			}
+			after = e.EventID
+		}
+		if out.Flush() != nil || rc.Flush() != nil {
+			return
+		}
+
+		if len(events) < eventExportPage {
+			return
+		}
+	}
+}
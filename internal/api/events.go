@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	eventBusBuffer = 1024
+	// drainPollInterval is how often shutdown checks whether the subscriber
+	// handlers are done cleaning up.
+	drainPollInterval = 50 * time.Millisecond
+)
+
+// eventBus serializes room broadcasts on a single goroutine bound to the
+// server lifetime, instead of one detached goroutine per request, so events
+// go out in publish order and shutdown can drain what is still queued.
+type eventBus struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan Message
+	done   chan struct{}
+
+	//? Held for reading while publishing so close never races a send
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newEventBus() *eventBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &eventBus{
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan Message, eventBusBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+// publish queues a sequenced broadcast. It blocks when the buffer is full,
+// pushing back on the request that produced the event.
+func (h apiHandler) publish(msg Message) {
+	b := h.bus
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		slog.Warn("dropping event published after shutdown", "room_id", msg.RoomID, "kind", msg.Kind)
+		return
+	}
+	b.events <- msg
+}
+
+// runEventBus is the one goroutine sequencing room events, the published ones
+// and the outbox's alike. Outbox events committed before an event was
+// published go out first, so a reaction never precedes the message_created
+// of its message.
+func (h apiHandler) runEventBus() {
+	defer close(h.bus.done)
+
+	ticker := time.NewTicker(h.outbox.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-h.bus.events:
+			if !ok {
+				//* Closed by Shutdown, which advises subscribers to reconnect after
+				h.drainOutbox(h.bus.ctx)
+				return
+			}
+			select {
+			case <-h.outbox.wake:
+				h.drainOutbox(h.bus.ctx)
+			default:
+			}
+			h.notifyClients(h.bus.ctx, msg)
+			h.chatBridges.mirror(msg)
+		case <-h.outbox.wake:
+			h.drainOutbox(h.bus.ctx)
+		case <-ticker.C:
+			h.drainOutbox(h.bus.ctx)
+		}
+	}
+}
+
+// Shutdown stops accepting events and delivers the queued ones, then advises
+// every subscriber to reconnect, waits for their handlers to unregister and
+// persists pending usage. When ctx expires first, in-flight work is cancelled
+// and the rest is discarded.
+func (h apiHandler) Shutdown(ctx context.Context) error {
+	b := h.bus
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.events)
+	}
+	b.mu.Unlock()
+
+	var err error
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		slog.Warn("event bus shutdown timed out", "pending", len(b.events))
+	}
+	b.cancel()
+
+	h.mu.Lock()
+	h.adviseReconnectLocked(ReconnectReasonDeploy)
+	h.mu.Unlock()
+
+	//? Their cleanup still meters usage and touches the pool, which closes after this returns
+	if drainErr := h.waitForSubscribers(ctx); err == nil {
+		err = drainErr
+	}
+
+	//* The bus context is gone by now, the final flush runs on the caller's deadline
+	h.flushUsage(ctx)
+
+	return err
+}
+
+// waitForSubscribers blocks until every subscriber handler has unregistered
+// or ctx is done.
+func (h apiHandler) waitForSubscribers(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		h.mu.Lock()
+		remaining := 0
+		for _, subscribers := range h.subscribers {
+			remaining += len(subscribers)
+		}
+		h.mu.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Warn("subscriber drain timed out", "remaining", remaining)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/billing"
+	"github.com/luiz504/week-tech-go-server/internal/export"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	defaultExportLinkTTL = 15 * time.Minute
+	maxExportLinkTTL     = 7 * 24 * time.Hour
+)
+
+func parseExportFormat(r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatHTML
+	}
+
+	return format, format == export.FormatHTML || format == export.FormatPDF
+}
+
+func (h apiHandler) handleExportRoom(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseExportFormat(r)
+	if !ok {
+		http.Error(w, "unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	transcript, ok := h.loadExport(w, r, hostedRoom(r).ID)
+	if !ok {
+		return
+	}
+
+	h.writeExport(w, transcript, format)
+}
+
+// handleCreateExportLink issues a time-limited download link for the room
+// export, which the host can share with people who don't hold the owner token.
+func (h apiHandler) handleCreateExportLink(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	type _body struct {
+		Format     string `json:"format"`
+		TTLSeconds int    `json:"ttl_seconds"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if body.Format == "" {
+		body.Format = export.FormatHTML
+	}
+	if body.Format != export.FormatHTML && body.Format != export.FormatPDF {
+		http.Error(w, "unsupported export format", http.StatusBadRequest)
+		return
+	}
+	ttl := defaultExportLinkTTL
+	if body.TTLSeconds != 0 {
+		ttl = time.Duration(body.TTLSeconds) * time.Second
+		if ttl <= 0 || ttl > maxExportLinkTTL {
+			http.Error(w, fmt.Sprintf("ttl_seconds must be between 1 and %d", int(maxExportLinkTTL.Seconds())), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !h.exportsEnabled(w, r, room) {
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	link := h.links.Sign("/downloads/rooms/"+room.ID.String()+"/export", url.Values{"format": {body.Format}}, expiresAt)
+
+	type response struct {
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	data, err := json.Marshal(response{URL: link, ExpiresAt: expiresAt.UTC().Truncate(time.Second)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleDownloadExport serves an export through a signed link, the signature
+// standing in for the owner token.
+func (h apiHandler) handleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	format, ok := parseExportFormat(r)
+	if !ok {
+		http.Error(w, "unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	transcript, ok := h.loadExport(w, r, roomID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	h.writeExport(w, transcript, format)
+}
+
+// loadExport loads the room transcript and checks the plan includes exports.
+// It responds and returns false otherwise.
+func (h apiHandler) loadExport(w http.ResponseWriter, r *http.Request, roomID uuid.UUID) (export.Transcript, bool) {
+	transcript, err := export.Load(r.Context(), h.q, roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return export.Transcript{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to load room transcript", err, "something went wrong", http.StatusInternalServerError)
+		return export.Transcript{}, false
+	}
+	if !h.exportsEnabled(w, r, transcript.Room) {
+		return export.Transcript{}, false
+	}
+
+	return transcript.Redact(h.redactor), true
+}
+
+// exportsEnabled checks the plan of room includes exports. It responds and
+// returns false otherwise.
+func (h apiHandler) exportsEnabled(w http.ResponseWriter, r *http.Request, room pg.Room) bool {
+	enabled, err := h.featureEnabled(r.Context(), room, billing.FeatureExports)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to check plan features", err, "something went wrong", http.StatusInternalServerError)
+		return false
+	}
+	if !enabled {
+		http.Error(w, "exports are not included in this organization's plan", http.StatusPaymentRequired)
+		return false
+	}
+
+	return true
+}
+
+func (h apiHandler) writeExport(w http.ResponseWriter, transcript export.Transcript, format string) {
+	//? Render fully before writing so a render error can still become a 500
+	var buf bytes.Buffer
+	var err error
+	contentType := "text/html; charset=utf-8"
+	if format == export.FormatPDF {
+		contentType = "application/pdf"
+		err = export.RenderPDF(&buf, transcript)
+	} else {
+		err = export.RenderHTML(&buf, transcript)
+	}
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to render room export", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%s.%s"`, transcript.Room.Code, format))
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
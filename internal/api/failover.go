@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	RolePrimary = "primary"
+	RoleStandby = "standby"
+)
+
+const (
+	defaultStandbyPollInterval = time.Second
+	// standbyFollowPage is how many events a room catches up on per poll.
+	standbyFollowPage = 500
+)
+
+// failover tracks whether this deployment serves as the primary or follows it
+// as a standby in another region. The database is replicated and promoted by
+// the operator; the standby reads its replica, relays the primary's events
+// to its own subscribers from the room event log and refuses writes until it
+// is promoted.
+type failover struct {
+	standby atomic.Bool
+	// peerURL is the other deployment, where subscribers are sent on handoff.
+	peerURL      string
+	pollInterval time.Duration
+}
+
+// failoverFromEnv reads WS_ROLE (primary by default), WS_FAILOVER_PEER_URL
+// and WS_STANDBY_POLL_INTERVAL (1s by default).
+func failoverFromEnv() *failover {
+	f := &failover{
+		peerURL:      os.Getenv("WS_FAILOVER_PEER_URL"),
+		pollInterval: positiveDurationFromEnv("WS_STANDBY_POLL_INTERVAL", defaultStandbyPollInterval),
+	}
+
+	switch role := os.Getenv("WS_ROLE"); role {
+	case "", RolePrimary:
+	case RoleStandby:
+		f.standby.Store(true)
+	default:
+		slog.Warn("invalid WS_ROLE, starting as primary", "value", role)
+	}
+
+	return f
+}
+
+func (f *failover) role() string {
+	if f.standby.Load() {
+		return RoleStandby
+	}
+	return RolePrimary
+}
+
+// rejectWritesOnStandby answers every request that could write with a 503
+// while on standby, so clients and webhook senders retry against the
+// primary. Admin routes stay open for promotion.
+func (h apiHandler) rejectWritesOnStandby(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.failover.standby.Load() || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "this instance is a standby, writes go to the primary", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// runStandbyFollower relays the events the primary logs to the subscribers
+// of this instance while on standby. Each room is followed from the event it
+// was at when its first subscriber showed up.
+func (h apiHandler) runStandbyFollower() {
+	ticker := time.NewTicker(h.failover.pollInterval)
+	defer ticker.Stop()
+
+	followed := make(map[string]int64)
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !h.failover.standby.Load() {
+			clear(followed)
+			continue
+		}
+		h.followRooms(followed)
+	}
+}
+
+func (h apiHandler) followRooms(followed map[string]int64) {
+	h.mu.Lock()
+	watched := make(map[string]bool, len(h.subscribers))
+	for roomID := range h.subscribers {
+		watched[roomID] = true
+	}
+	h.mu.Unlock()
+
+	for roomID := range followed {
+		if !watched[roomID] {
+			delete(followed, roomID)
+		}
+	}
+
+	ctx := h.bus.ctx
+	for roomID := range watched {
+		id, err := uuid.Parse(roomID)
+		if err != nil {
+			continue
+		}
+
+		after, ok := followed[roomID]
+		if !ok {
+			seq, err := h.q.GetRoomEventSeq(ctx, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("failed to get room event sequence to follow", "room_id", roomID, "error", err)
+				}
+				continue
+			}
+			followed[roomID] = seq
+			continue
+		}
+
+		events, err := h.q.GetRoomEvents(ctx, pg.GetRoomEventsParams{RoomID: id, AfterEventID: after, MaxEvents: standbyFollowPage})
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to follow room events", "room_id", roomID, "after_event_id", after, "error", err)
+			}
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		h.mu.Lock()
+		for _, e := range events {
+			h.broadcastLocked(Message{
+				EventID: e.EventID,
+				Channel: e.Channel,
+				Kind:    e.Kind,
+				Value:   json.RawMessage(e.Value),
+				RoomID:  roomID,
+			})
+		}
+		h.mu.Unlock()
+		h.leaderboards.touch(roomID)
+		followed[roomID] = events[len(events)-1].EventID
+	}
+}
+
+type failoverResponse struct {
+	Role    string `json:"role"`
+	PeerURL string `json:"peer_url,omitempty"`
+}
+
+func (h apiHandler) handleGetFailover(w http.ResponseWriter, r *http.Request) {
+	h.writeFailover(w)
+}
+
+// handlePromote makes a standby the primary. The replica database must have
+// been promoted first, writes go through as soon as this returns.
+func (h apiHandler) handlePromote(w http.ResponseWriter, r *http.Request) {
+	if !h.failover.standby.CompareAndSwap(true, false) {
+		http.Error(w, "this instance is already the primary", http.StatusConflict)
+		return
+	}
+
+	h.jobs.Resume()
+	h.chatBridges.kick()
+	slog.Warn("promoted to primary")
+
+	h.writeFailover(w)
+}
+
+// handleDemote hands the primary role over: writes are refused from now on,
+// background work stops and every subscriber is advised to reconnect to the
+// peer, or to redirect_url when given.
+func (h apiHandler) handleDemote(w http.ResponseWriter, r *http.Request) {
+	type _body struct {
+		RedirectURL string `json:"redirect_url"`
+	}
+	var body _body
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	redirect := body.RedirectURL
+	if redirect == "" {
+		redirect = h.failover.peerURL
+	}
+	if u, err := url.Parse(redirect); err != nil || (redirect != "" && u.Host == "") {
+		http.Error(w, "invalid redirect_url", http.StatusBadRequest)
+		return
+	}
+
+	if !h.failover.standby.CompareAndSwap(false, true) {
+		http.Error(w, "this instance is already a standby", http.StatusConflict)
+		return
+	}
+
+	h.jobs.Pause()
+	h.chatBridges.kick()
+
+	h.mu.Lock()
+	h.adviseFailoverLocked(redirect)
+	h.mu.Unlock()
+	slog.Warn("demoted to standby", "redirect_url", redirect)
+
+	h.writeFailover(w)
+}
+
+// adviseFailoverLocked sends every subscriber to redirect. Must be called with
+// h.mu held.
+func (h apiHandler) adviseFailoverLocked(redirect string) {
+	total := 0
+	for _, subscribers := range h.subscribers {
+		total += len(subscribers)
+	}
+	lower, upper := reconnectWindow(total)
+
+	for roomID, subscribers := range h.subscribers {
+		for _, sub := range subscribers {
+			advice := newReconnectAdvice(ReconnectReasonFailover, lower, upper)
+			advice.URL = redirect
+			h.adviseLocked(roomID, sub, advice)
+		}
+	}
+}
+
+func (h apiHandler) writeFailover(w http.ResponseWriter) {
+	data, err := json.Marshal(failoverResponse{Role: h.failover.role(), PeerURL: h.failover.peerURL})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+)
+
+// defaultWriteQueueSize is how many frames a connection may have pending,
+// enough for a full replay of missed events.
+const defaultWriteQueueSize = 1024
+
+func positiveIntFromEnv(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		slog.Warn("invalid "+key+", using default", "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func positiveDurationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		slog.Warn("invalid "+key+", using default", "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
+}
+
+// outboundFrame is a frame queued for a connection. A broadcast queues the
+// same one to every recipient.
+type outboundFrame struct {
+	msg Message
+	// keepAlive is an idle event stream's comment, sent instead of msg.
+	keepAlive bool
+	// close, when set, is sent instead of msg and ends the connection once
+	// the frames queued before it went out.
+	close *closeFrame
+}
+
+type closeFrame struct {
+	code int
+	text string
+}
+
+// startWriter gives sub its write queue, sized by WS_WRITE_QUEUE_SIZE, and
+// the goroutine draining it into c: the only one writing frames to c, so a
+// slow client holds up its own queue rather than the room's broadcasts. The
+// returned channel is closed once the goroutine is done writing.
+func (h apiHandler) startWriter(ctx context.Context, c clientConn, sub *subscriber) <-chan struct{} {
+	sub.out = make(chan *outboundFrame, h.writeQueue)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.writeFrames(ctx, c, sub)
+	}()
+
+	return done
+}
+
+func (h apiHandler) writeFrames(ctx context.Context, c clientConn, sub *subscriber) {
+	for {
+		var f *outboundFrame
+		select {
+		case <-ctx.Done():
+			return
+		case f = <-sub.out:
+		}
+
+		if f.close != nil {
+			_ = c.close(f.close.code, f.close.text)
+			sub.cancel()
+			return
+		}
+		if f.keepAlive {
+			if k, ok := c.(interface{ keepAlive() error }); ok && k.keepAlive() != nil {
+				sub.cancel()
+				return
+			}
+			continue
+		}
+
+		start := time.Now()
+		err := c.send(f.msg)
+		metrics.ObserveWrite(start, err, sub.requestID)
+		if err != nil {
+			sub.log.Error("failed to send message to client", "error", err)
+			h.mu.Lock()
+			sub.closed = true
+			h.mu.Unlock()
+			//* this call will trigger the handleSubscribeToRoom cleanup
+			sub.cancel()
+			return
+		}
+	}
+}
+
+// queueLocked hands f to the connection's writer. A connection whose queue
+// is full can't keep up with the room: it's disconnected rather than left
+// to miss events, and catches up by reconnecting with its last_event_id.
+// Must be called with h.mu held.
+func (h apiHandler) queueLocked(sub *subscriber, f *outboundFrame) {
+	//? The connection stays registered until its handler cleans up, skip it meanwhile
+	if sub.closed {
+		metrics.FramesDropped.WithLabelValues(metrics.DropClosing).Inc()
+		return
+	}
+	if f.close == nil && !f.keepAlive && h.chaos.DropFrame() {
+		metrics.FramesDropped.WithLabelValues(metrics.DropInjected).Inc()
+		return
+	}
+
+	select {
+	case sub.out <- f:
+	default:
+		sub.log.Warn("disconnecting client whose write queue is full", "queue_size", cap(sub.out))
+		metrics.FramesDropped.WithLabelValues(metrics.DropOverflow).Inc()
+		metrics.WriteQueueOverflows.Inc()
+		sub.closed = true
+		sub.cancel()
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const CommandHeartbeat = "heartbeat"
+
+const MessageKindHeartbeatAck = "heartbeat_ack"
+
+// Echoed server timestamps older than this are ignored rather than counted as latency.
+const maxHeartbeatAge = time.Minute
+
+// MessageHeartbeatAck answers a heartbeat. Clients echo ServerTS back as
+// ack_ts on their next heartbeat, letting the server measure the round trip
+// on its own clock without trusting the client's.
+type MessageHeartbeatAck struct {
+	TS       int64    `json:"ts,omitempty"`
+	ServerTS int64    `json:"server_ts"`
+	RTTMs    *float64 `json:"rtt_ms,omitempty"`
+}
+
+func (h apiHandler) handleHeartbeat(sub *subscriber, cmd clientCommand) {
+	now := time.Now()
+	ack := MessageHeartbeatAck{TS: cmd.TS, ServerTS: now.UnixMilli()}
+
+	if cmd.AckTS > 0 {
+		rtt := now.Sub(time.UnixMilli(cmd.AckTS))
+		if rtt >= 0 && rtt <= maxHeartbeatAge {
+			metrics.ClientRTTSeconds.Observe(rtt.Seconds())
+
+			h.mu.Lock()
+			sub.rtt = rtt
+			h.mu.Unlock()
+			rttMs := float64(rtt.Microseconds()) / 1000
+			ack.RTTMs = &rttMs
+		}
+	}
+
+	h.sendTo(sub, Message{Kind: MessageKindHeartbeatAck, Value: ack})
+}
+
+type LatencyStats struct {
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P90Ms   float64 `json:"p90_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+}
+
+// latencyStatsLocked aggregates the last measured RTT of each connection in
+// the room. Must be called with h.mu held.
+func (h apiHandler) latencyStatsLocked(roomID string) LatencyStats {
+	var samples []time.Duration
+	for _, sub := range h.subscribers[roomID] {
+		if sub.rtt > 0 {
+			samples = append(samples, sub.rtt)
+		}
+	}
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	//* Nearest-rank percentile
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(samples)))) - 1
+		return float64(samples[max(rank, 0)].Microseconds()) / 1000
+	}
+
+	return LatencyStats{
+		Samples: len(samples),
+		P50Ms:   percentile(0.50),
+		P90Ms:   percentile(0.90),
+		P99Ms:   percentile(0.99),
+	}
+}
+
+func (h apiHandler) handleGetRoomStats(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.q.GetRoomStats(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room stats", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := h.q.GetRoomLanguageCounts(r.Context(), roomID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get language counts", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type languageCount struct {
+		//? Empty for messages too short or ambiguous to tell
+		Language string `json:"language"`
+		Count    int64  `json:"count"`
+	}
+	type response struct {
+		RoomID          string          `json:"room_id"`
+		Subscribers     int             `json:"subscribers"`
+		Waiting         int             `json:"waiting"`
+		Latency         LatencyStats    `json:"latency"`
+		Languages       []languageCount `json:"languages"`
+		MessageCount    int64           `json:"message_count"`
+		UnansweredCount int64           `json:"unanswered_count"`
+		ReactionTotal   int64           `json:"reaction_total"`
+		LastActivity    *time.Time      `json:"last_activity"`
+	}
+
+	languages := make([]languageCount, 0, len(counts))
+	for _, count := range counts {
+		languages = append(languages, languageCount{Language: count.Language, Count: count.Count})
+	}
+
+	h.mu.Lock()
+	res := response{
+		RoomID:          roomID.String(),
+		Subscribers:     h.admittedLocked(roomID.String()),
+		Waiting:         len(h.waiting[roomID.String()]),
+		Latency:         h.latencyStatsLocked(roomID.String()),
+		Languages:       languages,
+		MessageCount:    stats.MessageCount,
+		UnansweredCount: stats.UnansweredCount,
+		ReactionTotal:   stats.ReactionTotal,
+	}
+	h.mu.Unlock()
+	if stats.LastActivity.Valid {
+		res.LastActivity = &stats.LastActivity.Time
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
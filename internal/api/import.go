@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/policy"
+	"github.com/luiz504/week-tech-go-server/internal/quota"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	maxImportRows  = 200
+	maxImportBytes = 1 << 20
+	// defaultTrackName takes the rows of a schedule that name no track.
+	defaultTrackName = "Main"
+)
+
+var errNoTitleColumn = errors.New("the csv needs a title column")
+
+// scheduleRow is one session of an imported schedule. StartTime is RFC 3339.
+type scheduleRow struct {
+	Title     string `json:"title"`
+	StartTime string `json:"start_time"`
+	Host      string `json:"host"`
+	Track     string `json:"track"`
+}
+
+// parseScheduleCSV reads a schedule whose header names its columns, title
+// being the only one required. Unknown columns are ignored.
+func parseScheduleCSV(r io.Reader) ([]scheduleRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errNoTitleColumn
+	}
+
+	var rows []scheduleRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		rows = append(rows, scheduleRow{
+			Title:     field("title"),
+			StartTime: field("start_time"),
+			Host:      field("host"),
+			Track:     field("track"),
+		})
+	}
+}
+
+// importRoom is a validated schedule row, ready to insert.
+type importRoom struct {
+	theme    string
+	startsAt pgtype.Timestamptz
+	hostName string
+	track    string
+}
+
+func (h apiHandler) validateScheduleRow(row scheduleRow) (importRoom, []forms.FieldError) {
+	var errs []forms.FieldError
+
+	input := policy.RoomInput{Theme: row.Title}
+	for _, v := range h.roomPolicy.Apply(&input) {
+		errs = append(errs, forms.FieldError{Field: "title", Message: v.Message})
+	}
+
+	var startsAt pgtype.Timestamptz
+	if raw := strings.TrimSpace(row.StartTime); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			errs = append(errs, forms.FieldError{Field: "start_time", Message: "must be an RFC 3339 timestamp"})
+		}
+		startsAt = pgtype.Timestamptz{Time: t, Valid: err == nil}
+	}
+
+	hostName := strings.TrimSpace(row.Host)
+	if utf8.RuneCountInString(hostName) > maxEventNameLength {
+		errs = append(errs, forms.FieldError{Field: "host", Message: "must be at most 255 characters"})
+	}
+
+	track := defaultTrackName
+	if strings.TrimSpace(row.Track) != "" {
+		var ok bool
+		if track, ok = parseEventName(row.Track); !ok {
+			errs = append(errs, forms.FieldError{Field: "track", Message: "must be at most 255 characters"})
+		}
+	}
+
+	return importRoom{
+		theme:    strings.TrimSpace(input.Theme),
+		startsAt: startsAt,
+		hostName: hostName,
+		track:    track,
+	}, errs
+}
+
+// handleImportEventRooms creates the rooms of a schedule, sent as CSV with
+// Content-Type text/csv or as JSON {"rooms": [...]}, with the event's room
+// defaults. Rooms land in the track their row names, created when the event
+// doesn't have it yet. Either every row is created or none is: any invalid
+// row fails the whole import with the errors of each row.
+func (h apiHandler) handleImportEventRooms(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var rows []scheduleRow
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		var err error
+		rows, err = parseScheduleCSV(body)
+		if err != nil {
+			if errors.Is(err, errNoTitleColumn) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "invalid csv", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var payload struct {
+			Rooms []scheduleRow `json:"rooms"`
+		}
+		if err := json.NewDecoder(body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		rows = payload.Rooms
+	}
+	if len(rows) == 0 {
+		http.Error(w, "the schedule has no rooms", http.StatusBadRequest)
+		return
+	}
+	if len(rows) > maxImportRows {
+		http.Error(w, fmt.Sprintf("a schedule can have at most %d rooms", maxImportRows), http.StatusBadRequest)
+		return
+	}
+
+	type rowResult struct {
+		Row        int                `json:"row"`
+		ID         string             `json:"id,omitempty"`
+		Code       string             `json:"code,omitempty"`
+		OwnerToken string             `json:"owner_token,omitempty"`
+		TrackID    string             `json:"track_id,omitempty"`
+		Errors     []forms.FieldError `json:"errors,omitempty"`
+	}
+
+	rooms := make([]importRoom, len(rows))
+	var invalid []rowResult
+	for i, row := range rows {
+		room, errs := h.validateScheduleRow(row)
+		if len(errs) > 0 {
+			invalid = append(invalid, rowResult{Row: i + 1, Errors: errs})
+		}
+		rooms[i] = room
+	}
+	if len(invalid) > 0 {
+		helpers.RespondValidationErrors(w, invalid)
+		return
+	}
+
+	//* The room quota must cover the whole schedule, not just its first room
+	organizationID := uuid.NullUUID{UUID: event.OrganizationID, Valid: true}
+	if limits := h.limitsFor(r.Context(), event.OrganizationID); limits.RoomsPerMonth > 0 {
+		month := quota.MonthStart(time.Now())
+		used, err := h.q.CountOrganizationRoomsSince(r.Context(), pg.CountOrganizationRoomsSinceParams{
+			OrganizationID: organizationID,
+			CreatedAt:      month,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to count organization rooms", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		usage := quota.Usage{
+			Resource: quota.ResourceRoomsPerMonth,
+			Limit:    limits.RoomsPerMonth,
+			Used:     used + int64(len(rooms)) - 1,
+			Reset:    month.AddDate(0, 1, 0),
+		}
+		if usage.Exceeded() {
+			quota.Deny(w, usage)
+			return
+		}
+		quota.WriteHeaders(w, usage)
+	}
+
+	postingMode, maxSubscribers, translateTo := "", int32(0), []string(nil)
+	parseRoomDefaults(event.RoomDefaults).inherit(&postingMode, &maxSubscribers, &translateTo)
+	if postingMode == "" {
+		postingMode = forms.PostingModeOptional
+	}
+	if translateTo == nil {
+		translateTo = []string{}
+	}
+
+	formSchema, err := json.Marshal(forms.Schema{})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal form schema", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	tracks, err := tx.GetEventTracks(r.Context(), event.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get event tracks", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	trackIDs := map[string]uuid.UUID{}
+	var position int32
+	for _, track := range tracks {
+		trackIDs[track.Name] = track.ID
+		position = max(position, track.Position+1)
+	}
+
+	results := make([]rowResult, 0, len(rooms))
+	for i, room := range rooms {
+		trackID, ok := trackIDs[room.track]
+		if !ok {
+			track, err := tx.InsertTrack(r.Context(), pg.InsertTrackParams{EventID: event.ID, Name: room.track, Position: position})
+			if err != nil {
+				helpers.LogErrorAndRespond(w, "failed to insert track", err, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+			trackID = track.ID
+			trackIDs[room.track] = trackID
+			position++
+		}
+
+		ownerToken, err := utils.GenerateToken()
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to generate owner token", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		inserted, err := tx.InsertRoom(r.Context(), pg.InsertRoomParams{
+			Theme:          room.theme,
+			OwnerTokenHash: utils.HashToken(ownerToken),
+			FormSchema:     formSchema,
+			PostingMode:    postingMode,
+			MaxSubscribers: maxSubscribers,
+			OrganizationID: organizationID,
+			TranslateTo:    translateTo,
+			TrackID:        uuid.NullUUID{UUID: trackID, Valid: true},
+			StartsAt:       room.startsAt,
+			HostName:       room.hostName,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to insert room", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		results = append(results, rowResult{
+			Row:        i + 1,
+			ID:         inserted.ID.String(),
+			Code:       inserted.Code,
+			OwnerToken: ownerToken,
+			TrackID:    trackID.String(),
+		})
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		EventID string      `json:"event_id"`
+		Rooms   []rowResult `json:"rooms"`
+	}
+
+	data, err := json.Marshal(response{EventID: event.ID.String(), Rooms: results})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
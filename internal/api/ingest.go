@@ -0,0 +1,349 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/ingest"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	// HeaderIngestSignature carries the hex or base64 HMAC-SHA256 of the body,
+	// prefixed with "sha256=". Typeform sends it as Typeform-Signature.
+	HeaderIngestSignature   = "X-Signature-256"
+	headerTypeformSignature = "Typeform-Signature"
+
+	maxIngestBytes = 64 << 10
+	// maxIngestQuestionLength is where longer questions are cut, the length
+	// of messages.message.
+	maxIngestQuestionLength = 255
+	maxIngestIDLength       = 200
+)
+
+// ingestSecretName is the stored secret deliveries to a room's webhook are
+// signed with.
+func ingestSecretName(roomID uuid.UUID) string {
+	return "room_ingest:" + roomID.String()
+}
+
+// IngestHook is how a room takes questions from a forms tool's webhook.
+type IngestHook struct {
+	RoomID    string         `json:"room_id"`
+	Mapping   ingest.Mapping `json:"mapping"`
+	IngestURL string         `json:"ingest_url"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	// Secret is only returned when it's issued, with the first mapping.
+	Secret string `json:"secret,omitempty"`
+}
+
+func mapIngestHook(hook pg.RoomIngestHook) (IngestHook, error) {
+	var mapping ingest.Mapping
+	if err := json.Unmarshal(hook.Mapping, &mapping); err != nil {
+		return IngestHook{}, err
+	}
+
+	return IngestHook{
+		RoomID:    hook.RoomID.String(),
+		Mapping:   mapping,
+		IngestURL: "/api/rooms/" + hook.RoomID.String() + "/ingest",
+		UpdatedAt: hook.UpdatedAt,
+	}, nil
+}
+
+// verifyIngestSignature checks the signature header against the HMAC of
+// body, taking it hex encoded or base64 encoded.
+func verifyIngestSignature(r *http.Request, secret string, body []byte) bool {
+	header := r.Header.Get(HeaderIngestSignature)
+	if header == "" {
+		header = r.Header.Get(headerTypeformSignature)
+	}
+	signature, ok := strings.CutPrefix(strings.TrimSpace(header), "sha256=")
+	if !ok {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	if got, err := hex.DecodeString(signature); err == nil && hmac.Equal(got, expected) {
+		return true
+	}
+	got, err := base64.StdEncoding.DecodeString(signature)
+
+	return err == nil && hmac.Equal(got, expected)
+}
+
+func (h apiHandler) handleGetIngestHook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	hook, err := h.q.GetRoomIngestHook(r.Context(), room.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "ingest webhook is not enabled", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get ingest webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	res, err := mapIngestHook(hook)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to parse ingest mapping", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handlePutIngestHook saves how the room's webhook maps payloads to
+// questions. The first mapping also issues the secret deliveries are signed
+// with; deleting the webhook and setting it up again rotates it.
+func (h apiHandler) handlePutIngestHook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+	if h.keyring == nil {
+		http.Error(w, "ingest webhooks need stored secrets, which are disabled on this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	type _body struct {
+		Mapping ingest.Mapping `json:"mapping"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if errs := body.Mapping.Check(); len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+	rawMapping, err := json.Marshal(body.Mapping)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal ingest mapping", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := h.issueSecret(r.Context(), room.OrganizationID, ingestSecretName(room.ID))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to issue ingest secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	hook, err := h.q.UpsertRoomIngestHook(r.Context(), pg.UpsertRoomIngestHookParams{RoomID: room.ID, Mapping: rawMapping})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save ingest webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	res, err := mapIngestHook(hook)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to parse ingest mapping", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	res.Secret = secret
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteIngestHook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomIngestHook(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete ingest webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	_, err = h.q.DeleteSecret(r.Context(), pg.DeleteSecretParams{OrganizationID: room.OrganizationID, Name: ingestSecretName(room.ID)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete ingest secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "ingest webhook is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleIngest posts the question a forms tool's webhook delivers, read off
+// its JSON payload with the room's mapping. Deliveries are signed with the
+// room's ingest secret; those repeating an external id already posted are
+// acknowledged without posting again, as tools retry until they see a 2xx.
+func (h apiHandler) handleIngest(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	hook, err := h.q.GetRoomIngestHook(r.Context(), room.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "ingest webhook is not enabled", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get ingest webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	secret, err := h.secret(r.Context(), room.OrganizationID, ingestSecretName(room.ID))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+			http.Error(w, "ingest webhook is not enabled", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to load ingest secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBytes))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !verifyIngestSignature(r, secret, raw) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
+	}
+	if rejectClosedRoom(w, room) {
+		return
+	}
+
+	var payload any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	var mapping ingest.Mapping
+	if err := json.Unmarshal(hook.Mapping, &mapping); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to parse ingest mapping", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	question, errs := mapping.Apply(payload)
+	if utf8.RuneCountInString(question.Message) > maxIngestQuestionLength {
+		question.Message = string([]rune(question.Message)[:maxIngestQuestionLength])
+	}
+	if utf8.RuneCountInString(question.ExternalID) > maxIngestIDLength {
+		errs = append(errs, forms.FieldError{Field: "external_id", Message: "must be at most 200 characters"})
+	}
+	schema, err := forms.ParseSchema(room.FormSchema)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to parse room form schema", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	fields, fieldErrs := schema.Validate(question.Fields)
+	errs = append(errs, fieldErrs...)
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	tx, err := h.q.Begin(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to begin transaction", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	inserted, err := h.insertExternalQuestion(r.Context(), tx, room, question.AuthorName, question.Message, fields)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	//? Recorded in the same transaction, a concurrent retry waits on it and then finds it
+	duplicate := false
+	if question.ExternalID != "" {
+		recorded, err := tx.InsertIngestDelivery(r.Context(), pg.InsertIngestDeliveryParams{
+			RoomID:     room.ID,
+			ExternalID: question.ExternalID,
+			MessageID:  inserted.id,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to record ingest delivery", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		duplicate = recorded == 0
+	}
+
+	type response struct {
+		RoomID    string `json:"room_id"`
+		MessageID string `json:"message_id,omitempty"`
+		Duplicate bool   `json:"duplicate"`
+	}
+	res := response{RoomID: room.ID.String(), Duplicate: duplicate}
+	status := http.StatusOK
+	if !duplicate {
+		if err := tx.Commit(r.Context()); err != nil {
+			helpers.LogErrorAndRespond(w, "failed to commit transaction", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		h.announceExternalQuestion(room, inserted)
+		res.MessageID = inserted.id.String()
+		status = http.StatusCreated
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
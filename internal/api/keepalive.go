@@ -0,0 +1,62 @@
+package api
+
+import (
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+)
+
+// * A socket is pinged every pingInterval and reaped once it went pongWait
+// * without answering, so it may miss one ping before it's given up on.
+const (
+	pingInterval = 25 * time.Second
+	pongWait     = 60 * time.Second
+)
+
+type reapedConn struct {
+	roomID string
+	conn   clientConn
+	sub    *subscriber
+}
+
+// runConnectionReaper removes the sockets that stopped answering pings.
+// Connections that vanish without a close frame would otherwise stay
+// subscribed until a write to them fails, which in a quiet room may be never.
+func (h apiHandler) runConnectionReaper() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.reapConnections(now)
+		}
+	}
+}
+
+// reapConnections cancels the sockets past their pong deadline and removes
+// them right away, freeing their slots without waiting on their handlers.
+func (h apiHandler) reapConnections(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var dead []reapedConn
+	for roomID, subscribers := range h.subscribers {
+		for conn, sub := range subscribers {
+			if !sub.pongDeadline.IsZero() && now.After(sub.pongDeadline) {
+				dead = append(dead, reapedConn{roomID: roomID, conn: conn, sub: sub})
+			}
+		}
+	}
+
+	for _, d := range dead {
+		d.sub.log.Warn("reaping connection that missed its pong deadline", "deadline", d.sub.pongDeadline)
+		metrics.ConnectionsReaped.Inc()
+		//? Closed before leaving, so the waiting room promotions don't write to it
+		d.sub.closed = true
+		d.sub.cancel()
+		h.leaveLocked(d.roomID, d.conn)
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const ChannelLeaderboard = "leaderboard"
+
+const (
+	MessageKindLeaderboardSnapshot = "leaderboard_snapshot"
+	MessageKindLeaderboardDiff     = "leaderboard_diff"
+)
+
+const (
+	leaderboardSize = 10
+	leaderboardTick = time.Second
+	// A full snapshot goes out every this many ticks so clients that missed
+	// a diff converge without resubscribing.
+	leaderboardKeyframeEvery = 30
+)
+
+type LeaderboardEntry struct {
+	ID       string `json:"id"`
+	Message  string `json:"message"`
+	Count    int64  `json:"count"`
+	Answered bool   `json:"answered"`
+}
+
+type MessageLeaderboardSnapshot struct {
+	RoomID  string             `json:"room_id"`
+	Version int64              `json:"version"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+type LeaderboardAdded struct {
+	Position int `json:"position"`
+	LeaderboardEntry
+}
+
+// LeaderboardChange updates an entry already on the board. Position is
+// always sent, the rest only when it changed.
+type LeaderboardChange struct {
+	ID         string `json:"id"`
+	Position   int    `json:"position"`
+	CountDelta int64  `json:"count_delta,omitempty"`
+	Answered   *bool  `json:"answered,omitempty"`
+}
+
+// MessageLeaderboardDiff applies on top of BaseVersion. Clients holding any
+// other version should drop it and wait for the next snapshot.
+type MessageLeaderboardDiff struct {
+	RoomID      string              `json:"room_id"`
+	Version     int64               `json:"version"`
+	BaseVersion int64               `json:"base_version"`
+	Added       []LeaderboardAdded  `json:"added,omitempty"`
+	Changed     []LeaderboardChange `json:"changed,omitempty"`
+	Removed     []string            `json:"removed,omitempty"`
+}
+
+type roomLeaderboard struct {
+	entries       []LeaderboardEntry
+	version       int64
+	dirty         bool
+	sinceKeyframe int
+}
+
+// leaderboards keeps the last board sent to each room with leaderboard
+// subscribers, so updates can be sent as diffs against it.
+type leaderboards struct {
+	mu    sync.Mutex
+	rooms map[string]*roomLeaderboard
+}
+
+func newLeaderboards() *leaderboards {
+	return &leaderboards{rooms: make(map[string]*roomLeaderboard)}
+}
+
+// touch marks the board stale after a room event. Rooms nobody watches are ignored.
+func (l *leaderboards) touch(roomID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if room, ok := l.rooms[roomID]; ok {
+		room.dirty = true
+	}
+}
+
+func (h apiHandler) loadLeaderboard(ctx context.Context, roomID string) ([]LeaderboardEntry, error) {
+	id, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := h.q.GetRoomTopMessages(ctx, pg.GetRoomTopMessagesParams{RoomID: id, Limit: leaderboardSize})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(messages))
+	for _, message := range messages {
+		entries = append(entries, LeaderboardEntry{
+			ID:       message.ID.String(),
+			Message:  message.Message,
+			Count:    message.ReactionCount,
+			Answered: message.Answered,
+		})
+	}
+
+	return entries, nil
+}
+
+// sendLeaderboardSnapshot gives a new leaderboard subscriber the current board.
+func (h apiHandler) sendLeaderboardSnapshot(roomID string, sub *subscriber) {
+	l := h.leaderboards
+	l.mu.Lock()
+	room, ok := l.rooms[roomID]
+	if !ok {
+		entries, err := h.loadLeaderboard(h.bus.ctx, roomID)
+		if err != nil {
+			l.mu.Unlock()
+			slog.Error("failed to load leaderboard", "room_id", roomID, "error", err)
+			return
+		}
+		room = &roomLeaderboard{entries: entries, version: 1}
+		l.rooms[roomID] = room
+	}
+	snapshot := MessageLeaderboardSnapshot{RoomID: roomID, Version: room.version, Entries: room.entries}
+	l.mu.Unlock()
+
+	h.sendTo(sub, Message{Kind: MessageKindLeaderboardSnapshot, Channel: ChannelLeaderboard, RoomID: roomID, Value: snapshot})
+}
+
+func (h apiHandler) runLeaderboards() {
+	ticker := time.NewTicker(leaderboardTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, msg := range h.tickLeaderboards() {
+				h.notifyClientsEphemeral(msg)
+			}
+		}
+	}
+}
+
+// tickLeaderboards refreshes stale boards and returns the diffs and
+// keyframes to broadcast. Boards nobody watches anymore are dropped.
+func (h apiHandler) tickLeaderboards() []Message {
+	h.mu.Lock()
+	watched := make(map[string]bool)
+	for roomID, subscribers := range h.subscribers {
+		for _, sub := range subscribers {
+			if !sub.waiting && sub.channels[ChannelLeaderboard] {
+				watched[roomID] = true
+				break
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	l := h.leaderboards
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var updates []Message
+	for roomID, room := range l.rooms {
+		if !watched[roomID] {
+			delete(l.rooms, roomID)
+			continue
+		}
+
+		room.sinceKeyframe++
+		keyframe := room.sinceKeyframe >= leaderboardKeyframeEvery
+		if !room.dirty && !keyframe {
+			continue
+		}
+
+		entries := room.entries
+		if room.dirty {
+			var err error
+			entries, err = h.loadLeaderboard(h.bus.ctx, roomID)
+			if err != nil {
+				slog.Error("failed to load leaderboard", "room_id", roomID, "error", err)
+				continue
+			}
+			room.dirty = false
+		}
+
+		diff := diffLeaderboard(room.entries, entries)
+		if !keyframe && len(diff.Added)+len(diff.Changed)+len(diff.Removed) == 0 {
+			continue
+		}
+
+		diff.RoomID = roomID
+		diff.BaseVersion = room.version
+		room.version++
+		diff.Version = room.version
+		room.entries = entries
+
+		msg := Message{Kind: MessageKindLeaderboardDiff, Channel: ChannelLeaderboard, RoomID: roomID, Value: diff}
+		if keyframe {
+			room.sinceKeyframe = 0
+			msg.Kind = MessageKindLeaderboardSnapshot
+			msg.Value = MessageLeaderboardSnapshot{RoomID: roomID, Version: room.version, Entries: entries}
+		}
+		updates = append(updates, msg)
+	}
+
+	return updates
+}
+
+func diffLeaderboard(prev, next []LeaderboardEntry) MessageLeaderboardDiff {
+	type placed struct {
+		position int
+		entry    LeaderboardEntry
+	}
+	before := make(map[string]placed, len(prev))
+	for i, entry := range prev {
+		before[entry.ID] = placed{position: i, entry: entry}
+	}
+
+	var diff MessageLeaderboardDiff
+	for i, entry := range next {
+		old, ok := before[entry.ID]
+		if !ok {
+			diff.Added = append(diff.Added, LeaderboardAdded{Position: i, LeaderboardEntry: entry})
+			continue
+		}
+		delete(before, entry.ID)
+
+		if old.position == i && old.entry.Count == entry.Count && old.entry.Answered == entry.Answered {
+			continue
+		}
+		change := LeaderboardChange{ID: entry.ID, Position: i, CountDelta: entry.Count - old.entry.Count}
+		if old.entry.Answered != entry.Answered {
+			answered := entry.Answered
+			change.Answered = &answered
+		}
+		diff.Changed = append(diff.Changed, change)
+	}
+	for _, entry := range prev {
+		if _, ok := before[entry.ID]; ok {
+			diff.Removed = append(diff.Removed, entry.ID)
+		}
+	}
+
+	return diff
+}
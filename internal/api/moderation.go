@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	MessageKindMessageReported = "message_reported"
+	MessageKindMessageDeleted  = "message_deleted"
+)
+
+type MessageMessageDeleted struct {
+	ID     string `json:"id"`
+	RoomID string `json:"room_id"`
+}
+
+type MessageMessageReported struct {
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	RoomID    string `json:"room_id"`
+	Reason    string `json:"reason"`
+}
+
+type reportMessageRequest struct {
+	Reason string `json:"reason"`
+}
+
+type reportMessageResponse struct {
+	ID string `json:"id"`
+}
+
+func (h apiHandler) handleReportMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+	messageId, err := utils.ParseUUIDParam(r, "message_id")
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+	_, err = h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	var body reportMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.q.InsertMessageReport(r.Context(), pg.InsertMessageReportParams{MessageID: messageId, Reason: body.Reason})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert message report", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(reportMessageResponse{ID: report.ID.String()})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	h.publish(Message{
+		Kind:    MessageKindMessageReported,
+		Channel: ChannelBackstage,
+		RoomID:  roomID.String(),
+		Value: MessageMessageReported{
+			ID:        report.ID.String(),
+			MessageID: messageId.String(),
+			RoomID:    roomID.String(),
+			Reason:    report.Reason,
+		},
+	})
+}
+
+// isRoomHost reports whether the request carries the room owner token.
+func isRoomHost(r *http.Request, room pg.Room) bool {
+	return utils.MatchTokenHash(utils.ParseBearerToken(r), room.OwnerTokenHash)
+}
+
+type hostedRoomKey struct{}
+
+// requireRoomHost guards the routes only the room owner may use: it loads the
+// room of the request and lets the caller through if they host it, action
+// completing the 403 message otherwise. Handlers get the room with hostedRoom.
+func (h apiHandler) requireRoomHost(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roomID, err := utils.ParseUUIDParam(r, "room_id")
+			if err != nil {
+				http.Error(w, "invalid room id", http.StatusBadRequest)
+				return
+			}
+
+			room, err := h.q.GetRoom(r.Context(), roomID)
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					http.Error(w, "room not found", http.StatusNotFound)
+					return
+				}
+				helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+			if !isRoomHost(r, room) {
+				http.Error(w, "only the room host can "+action, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), hostedRoomKey{}, room)))
+		})
+	}
+}
+
+// hostedRoom returns the room requireRoomHost loaded for the request.
+func hostedRoom(r *http.Request) pg.Room {
+	room, _ := r.Context().Value(hostedRoomKey{}).(pg.Room)
+
+	return room
+}
+
+func (h apiHandler) handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+	messageId, err := utils.ParseUUIDParam(r, "message_id")
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageId})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	//* Hosts can always delete, authors only while the question is unanswered
+	if !isRoomHost(r, room) {
+		sessionID, ok := session.FromContext(r.Context())
+		if !ok || !message.SessionID.Valid || message.SessionID.UUID != sessionID {
+			http.Error(w, "only the author or the room host can delete this message", http.StatusForbidden)
+			return
+		}
+		if message.Answered {
+			http.Error(w, "answered messages can't be retracted", http.StatusConflict)
+			return
+		}
+	}
+
+	err = h.q.SoftDeleteMessage(r.Context(), messageId)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	h.publish(Message{
+		Kind:   MessageKindMessageDeleted,
+		RoomID: roomID.String(),
+		Value: MessageMessageDeleted{
+			ID:     messageId.String(),
+			RoomID: roomID.String(),
+		},
+	})
+}
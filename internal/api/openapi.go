@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luiz504/week-tech-go-server/internal/api/docs"
+	"github.com/luiz504/week-tech-go-server/internal/schema"
+)
+
+const (
+	openAPIPath = "/api/openapi.json"
+
+	componentMessage = "WebSocketMessage"
+	componentCommand = "WebSocketCommand"
+)
+
+// operations describes the routes whose handlers decode or marshal a named
+// type. The other routes are still listed, with their parameters only.
+var operations = map[string]docs.Operation{
+	"POST /api/rooms": {
+		Summary:  "Create a room",
+		Tags:     []string{"rooms"},
+		Request:  createRoomRequest{},
+		Response: createRoomResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GET /api/rooms": {
+		Summary:  "List rooms, newest first",
+		Tags:     []string{"rooms"},
+		Response: getRoomsResponse{},
+	},
+	"GET /api/rooms/{room_id}": {
+		Summary:  "Get a room",
+		Tags:     []string{"rooms"},
+		Response: getRoomResponse{},
+	},
+	"POST /api/rooms/{room_id}/archive": {
+		Summary: "Archive a room",
+		Tags:    []string{"rooms"},
+		Status:  http.StatusNoContent,
+	},
+	"POST /api/rooms/{room_id}/unarchive": {
+		Summary: "Restore an archived room",
+		Tags:    []string{"rooms"},
+		Status:  http.StatusNoContent,
+	},
+	"PATCH /api/rooms/{room_id}/close": {
+		Summary: "Close a room to new messages and reactions",
+		Tags:    []string{"rooms"},
+		Status:  http.StatusNoContent,
+	},
+	"PATCH /api/rooms/{room_id}/reopen": {
+		Summary: "Reopen a closed room",
+		Tags:    []string{"rooms"},
+		Status:  http.StatusNoContent,
+	},
+	"GET /api/rooms/{room_id}/moderation/queue": {
+		Summary:  "List the messages held or reported in a room",
+		Tags:     []string{"moderation"},
+		Response: moderationQueueResponse{},
+	},
+	"POST /api/rooms/{room_id}/messages": {
+		Summary:  "Ask a question",
+		Tags:     []string{"messages"},
+		Request:  createRoomMessageRequest{},
+		Response: createRoomMessageResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GET /api/rooms/{room_id}/messages": {
+		Summary:  "List a room's messages",
+		Tags:     []string{"messages"},
+		Response: getRoomMessagesResponse{},
+	},
+	"GET /api/rooms/{room_id}/messages/mine": {
+		Summary:  "List the messages asked by the session",
+		Tags:     []string{"messages"},
+		Response: getMyRoomMessagesResponse{},
+	},
+	"GET /api/rooms/{room_id}/messages/search": {
+		Summary:  "Search a room's messages by text, best match first",
+		Tags:     []string{"messages"},
+		Response: searchRoomMessagesResponse{},
+	},
+	"POST /api/rooms/{room_id}/messages/bulk": {
+		Summary:  "Moderate messages in bulk",
+		Tags:     []string{"moderation"},
+		Request:  bulkModerateRequest{},
+		Response: bulkModerateResponse{},
+	},
+	"GET /api/rooms/{room_id}/messages/{message_id}": {
+		Summary:  "Get a message",
+		Tags:     []string{"messages"},
+		Response: getRoomMessageResponse{},
+	},
+	"PUT /api/rooms/{room_id}/messages/{message_id}": {
+		Summary:  "Edit a message",
+		Tags:     []string{"messages"},
+		Request:  editMessageRequest{},
+		Response: editMessageResponse{},
+	},
+	"DELETE /api/rooms/{room_id}/messages/{message_id}": {
+		Summary: "Delete a message",
+		Tags:    []string{"moderation"},
+		Status:  http.StatusNoContent,
+	},
+	"PATCH /api/rooms/{room_id}/messages/{message_id}/react": {
+		Summary:  "React to a message",
+		Tags:     []string{"messages"},
+		Response: reactionCountResponse{},
+	},
+	"DELETE /api/rooms/{room_id}/messages/{message_id}/react": {
+		Summary:  "Remove the session's reaction from a message",
+		Tags:     []string{"messages"},
+		Response: reactionCountResponse{},
+	},
+	"PATCH /api/rooms/{room_id}/messages/{message_id}/answer": {
+		Summary: "Mark a message as answered or not",
+		Tags:    []string{"messages"},
+		Request: markMessageAnsweredRequest{},
+		Status:  http.StatusNoContent,
+	},
+	"POST /api/rooms/{room_id}/messages/{message_id}/report": {
+		Summary:  "Report a message",
+		Tags:     []string{"moderation"},
+		Request:  reportMessageRequest{},
+		Response: reportMessageResponse{},
+		Status:   http.StatusCreated,
+	},
+	"POST /api/rooms/{room_id}/messages/{message_id}/review": {
+		Summary: "Approve or reject a held message",
+		Tags:    []string{"moderation"},
+		Request: reviewMessageRequest{},
+		Status:  http.StatusNoContent,
+	},
+	"GET /api/rooms/{room_id}/messages/{message_id}/translate": {
+		Summary:  "Translate a message",
+		Tags:     []string{"messages"},
+		Response: translateMessageResponse{},
+	},
+	"GET /api/rooms/{room_id}/webhook": {
+		Summary:  "Get where the room's events are delivered",
+		Tags:     []string{"webhooks"},
+		Response: RoomWebhook{},
+	},
+	"PUT /api/rooms/{room_id}/webhook": {
+		Summary:  "Deliver the room's events to a url",
+		Tags:     []string{"webhooks"},
+		Request:  putRoomWebhookRequest{},
+		Response: RoomWebhook{},
+	},
+	"DELETE /api/rooms/{room_id}/webhook": {
+		Summary: "Stop delivering the room's events",
+		Tags:    []string{"webhooks"},
+		Status:  http.StatusNoContent,
+	},
+	"GET /subscribe/{room_id}": {
+		Summary: "Subscribe to a room's events over a websocket",
+		Tags:    []string{"subscribe"},
+		Status:  http.StatusSwitchingProtocols,
+		Stream:  docs.Ref(componentMessage),
+	},
+	"GET /subscribe/{room_id}/sse": {
+		Summary: "Subscribe to a room's events as server-sent events",
+		Tags:    []string{"subscribe"},
+		Stream:  docs.Ref(componentMessage),
+	},
+}
+
+func newSpec() *docs.Spec {
+	return docs.New("week-tech rooms API", strconv.Itoa(SchemaVersion))
+}
+
+// describeAPI documents the rooms API served by r, along with the websocket
+// envelope of every event kind. Called once the routes are all registered.
+func describeAPI(spec *docs.Spec, r chi.Routes) error {
+	//* Each kind gets its own component so clients can switch on the discriminator
+	mapping := map[string]string{}
+	var oneOf []schema.Object
+	kinds := make([]string, 0, len(eventValues))
+	for kind := range eventValues {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		s := eventSchema(kind, eventValues[kind])
+		delete(s, "$schema")
+		delete(s, "$id")
+
+		name := "Event." + kind
+		spec.Component(name, s)
+		ref := docs.Ref(name)
+		mapping[kind] = ref["$ref"].(string)
+		oneOf = append(oneOf, ref)
+	}
+	spec.Component(componentMessage, schema.Object{
+		"oneOf":         oneOf,
+		"discriminator": schema.Object{"propertyName": "kind", "mapping": mapping},
+	})
+	spec.Component(componentCommand, clientCommand{})
+
+	return spec.AddRoutes(r, operations, "/api/rooms", "/subscribe/")
+}
@@ -0,0 +1,410 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/metering"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	usageFlushInterval        = 30 * time.Second
+	defaultSignatureTolerance = 5 * time.Minute
+)
+
+// signatureToleranceFromEnv reads WS_SIGNATURE_TOLERANCE, the clock skew
+// accepted on signed requests.
+func signatureToleranceFromEnv() time.Duration {
+	raw := os.Getenv("WS_SIGNATURE_TOLERANCE")
+	if raw == "" {
+		return defaultSignatureTolerance
+	}
+	tolerance, err := time.ParseDuration(raw)
+	if err != nil || tolerance <= 0 {
+		slog.Warn("invalid WS_SIGNATURE_TOLERANCE, using default", "value", raw, "default", defaultSignatureTolerance)
+		return defaultSignatureTolerance
+	}
+
+	return tolerance
+}
+
+func (h apiHandler) resolveAPIKey(ctx context.Context, rawKey string) (tenant.Key, error) {
+	key, err := h.q.GetActiveAPIKeyByHash(ctx, utils.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return tenant.Key{}, tenant.ErrUnknownKey
+		}
+		return tenant.Key{}, err
+	}
+
+	return tenant.Key{ID: key.ID, OrganizationID: key.OrganizationID}, nil
+}
+
+// signingSecretName is the stored secret an API key signs requests with.
+func signingSecretName(keyID uuid.UUID) string {
+	return "api_key_signing:" + keyID.String()
+}
+
+func (h apiHandler) resolveSigningKey(ctx context.Context, keyID uuid.UUID) (tenant.Key, []byte, error) {
+	key, err := h.q.GetActiveAPIKey(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return tenant.Key{}, nil, tenant.ErrUnknownKey
+		}
+		return tenant.Key{}, nil, err
+	}
+
+	orgID := uuid.NullUUID{UUID: key.OrganizationID, Valid: true}
+	secret, err := h.secret(ctx, orgID, signingSecretName(key.ID))
+	if err != nil {
+		//? A key without a signing secret, or without a keyring to open it, can't sign
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+			return tenant.Key{}, nil, tenant.ErrUnknownKey
+		}
+		return tenant.Key{}, nil, err
+	}
+
+	return tenant.Key{ID: key.ID, OrganizationID: key.OrganizationID}, []byte(secret), nil
+}
+
+func (h apiHandler) handleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	type _body struct {
+		Name string `json:"name"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.q.InsertOrganization(r.Context(), body.Name)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert organization", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	data, err := json.Marshal(response{ID: org.ID.String(), Name: org.Name})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleCreateAPIKey issues a key for the organization. The raw key is only
+// returned here, the database keeps its hash.
+func (h apiHandler) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	orgID, err := utils.ParseUUIDParam(r, "organization_id")
+	if err != nil {
+		http.Error(w, "invalid organization id", http.StatusBadRequest)
+		return
+	}
+
+	type _body struct {
+		Name string `json:"name"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	rawKey, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate api key", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	key, err := h.q.InsertAPIKey(r.Context(), pg.InsertAPIKeyParams{
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(body.Name),
+		KeyHash:        utils.HashToken(rawKey),
+	})
+	if err != nil {
+		//? The only foreign key is the organization
+		if errors.Is(err, store.ErrForeignKey) {
+			http.Error(w, "organization not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to insert api key", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+
+	data, err := json.Marshal(response{ID: key.ID.String(), Key: rawKey})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleCreateSigningSecret issues the secret an API key signs requests
+// with, replacing any previous one. Like the key, it is only returned here.
+func (h apiHandler) handleCreateSigningSecret(w http.ResponseWriter, r *http.Request) {
+	if h.keyring == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	orgID, err := utils.ParseUUIDParam(r, "organization_id")
+	if err != nil {
+		http.Error(w, "invalid organization id", http.StatusBadRequest)
+		return
+	}
+	keyID, err := utils.ParseUUIDParam(r, "api_key_id")
+	if err != nil {
+		http.Error(w, "invalid api key id", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.q.GetActiveAPIKey(r.Context(), keyID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "api key not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get api key", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if key.OrganizationID != orgID {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate signing secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	scope := uuid.NullUUID{UUID: orgID, Valid: true}
+	name := signingSecretName(key.ID)
+	envelope, err := h.keyring.Seal([]byte(secret), secretAAD(scope, name))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to encrypt signing secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.q.UpsertSecret(r.Context(), pg.UpsertSecretParams{
+		OrganizationID: scope,
+		Name:           name,
+		KeyID:          envelope.KeyID,
+		WrappedKey:     envelope.WrappedKey,
+		Ciphertext:     envelope.Ciphertext,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to upsert signing secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		APIKeyID string `json:"api_key_id"`
+		Secret   string `json:"secret"`
+	}
+
+	data, err := json.Marshal(response{APIKeyID: key.ID.String(), Secret: secret})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetUsage reports the daily usage of the caller's organization.
+// from and to are dates, to being inclusive, defaulting to the current month.
+func (h apiHandler) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	key, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "an api key is required", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.q.GetOrganizationUsage(r.Context(), pg.GetOrganizationUsageParams{
+		OrganizationID: key.OrganizationID,
+		FromTime:       from,
+		ToTime:         to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get usage", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type usage struct {
+		Day      string  `json:"day"`
+		APIKeyID *string `json:"api_key_id"`
+		Metric   string  `json:"metric"`
+		Quantity int64   `json:"quantity"`
+	}
+	type response struct {
+		OrganizationID string           `json:"organization_id"`
+		From           string           `json:"from"`
+		To             string           `json:"to"`
+		Totals         map[string]int64 `json:"totals"`
+		Usage          []usage          `json:"usage"`
+	}
+
+	res := response{
+		OrganizationID: key.OrganizationID.String(),
+		From:           from.Format(time.DateOnly),
+		To:             to.Format(time.DateOnly),
+		Totals:         map[string]int64{},
+		Usage:          []usage{},
+	}
+	for _, row := range rows {
+		item := usage{Day: row.Day.UTC().Format(time.DateOnly), Metric: row.Metric, Quantity: row.Quantity}
+		if row.ApiKeyID.Valid {
+			id := row.ApiKeyID.UUID.String()
+			item.APIKeyID = &id
+		}
+		res.Usage = append(res.Usage, item)
+		res.Totals[row.Metric] += row.Quantity
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// accountConnectionLocked bills the time a socket has been connected since
+// it was last metered to the room's organization. Must be called with h.mu held.
+func (h apiHandler) accountConnectionLocked(sub *subscriber, now time.Time) {
+	if !sub.organizationID.Valid {
+		return
+	}
+
+	//* Whole seconds only, the remainder carries over to the next tick
+	seconds := int64(now.Sub(sub.meteredAt) / time.Second)
+	if seconds <= 0 {
+		return
+	}
+	sub.meteredAt = sub.meteredAt.Add(time.Duration(seconds) * time.Second)
+	h.usage.Add(sub.organizationID.UUID, uuid.NullUUID{}, metering.MetricWSSeconds, now, seconds)
+}
+
+func (h apiHandler) runUsageMeter() {
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			//? Kept pending on a standby until it's promoted
+			if !h.failover.standby.Load() {
+				h.flushUsage(h.bus.ctx)
+			}
+		}
+	}
+}
+
+// flushUsage meters live connections and persists everything pending.
+// Records that fail to persist are kept for the next flush.
+func (h apiHandler) flushUsage(ctx context.Context) {
+	now := time.Now()
+	h.mu.Lock()
+	for _, subscribers := range h.subscribers {
+		for _, sub := range subscribers {
+			h.accountConnectionLocked(sub, now)
+		}
+	}
+	h.mu.Unlock()
+
+	records := h.usage.Drain()
+	for i, record := range records {
+		err := h.q.AddUsage(ctx, pg.AddUsageParams{
+			OrganizationID: record.OrganizationID,
+			ApiKeyID:       record.APIKeyID,
+			Metric:         record.Metric,
+			PeriodStart:    record.PeriodStart,
+			Quantity:       record.Quantity,
+		})
+		if err != nil {
+			slog.Error("failed to persist usage", "pending", len(records)-i, "error", err)
+			h.usage.Restore(records[i:])
+			return
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxStaleAfter   = 30 * time.Second
+	// outboxBatch is how many events are broadcast per transaction.
+	outboxBatch = 100
+)
+
+// eventOutbox broadcasts the events written in the transaction of the change
+// they announce, so a crash between the commit and the broadcast delays them
+// rather than losing them. Each instance broadcasts its own events in the
+// order they were written, woken up right after the commit; those of an
+// instance that died, the previous run of this one included, are taken over
+// by any instance once stale.
+type eventOutbox struct {
+	instanceID   uuid.UUID
+	wake         chan struct{}
+	pollInterval time.Duration
+	staleAfter   time.Duration
+}
+
+// eventOutboxFromEnv reads WS_OUTBOX_POLL_INTERVAL (1s by default) and
+// WS_OUTBOX_STALE_AFTER (30s by default).
+func eventOutboxFromEnv() *eventOutbox {
+	return &eventOutbox{
+		instanceID:   uuid.New(),
+		wake:         make(chan struct{}, 1),
+		pollInterval: positiveDurationFromEnv("WS_OUTBOX_POLL_INTERVAL", defaultOutboxPollInterval),
+		staleAfter:   positiveDurationFromEnv("WS_OUTBOX_STALE_AFTER", defaultOutboxStaleAfter),
+	}
+}
+
+// notify wakes the dispatcher up once a transaction that wrote events has
+// committed.
+func (o *eventOutbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueEvent writes msg to the outbox in tx. It's broadcast once tx commits
+// and the outbox is notified.
+func (h apiHandler) enqueueEvent(ctx context.Context, tx store.Tx, msg Message) error {
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		return fmt.Errorf("invalid room id %q: %w", msg.RoomID, err)
+	}
+	value, err := json.Marshal(msg.Value)
+	if err != nil {
+		return err
+	}
+	channel := msg.Channel
+	if channel == "" {
+		channel = ChannelQuestions
+	}
+
+	return tx.InsertOutboxEvent(ctx, pg.InsertOutboxEventParams{
+		InstanceID: h.outbox.instanceID,
+		RoomID:     roomID,
+		Channel:    channel,
+		Kind:       msg.Kind,
+		Value:      value,
+	})
+}
+
+// drainOutbox broadcasts the outbox batch by batch, until it's empty or a
+// batch falls short. Only runEventBus calls it, so outbox events are
+// sequenced in line with the published ones.
+func (h apiHandler) drainOutbox(ctx context.Context) {
+	//? A standby reads a replica, its writes all go to the primary
+	if h.failover.standby.Load() {
+		return
+	}
+	for h.dispatchOutbox(ctx) == outboxBatch {
+	}
+}
+
+// dispatchOutbox broadcasts a batch of events and deletes the ones it did
+// in the transaction that locked them, returning how many. An instance dying
+// halfway leaves the batch to be broadcast again: subscribers get each event
+// at least once.
+func (h apiHandler) dispatchOutbox(ctx context.Context) int {
+	tx, err := h.q.Begin(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to begin outbox transaction", "error", err)
+		}
+		return 0
+	}
+	defer tx.Rollback(ctx)
+
+	events, err := tx.ClaimOutboxEvents(ctx, pg.ClaimOutboxEventsParams{
+		InstanceID:  h.outbox.instanceID,
+		StaleBefore: time.Now().Add(-h.outbox.staleAfter),
+		MaxEvents:   outboxBatch,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to claim outbox events", "error", err)
+		}
+		return 0
+	}
+	if len(events) == 0 {
+		return 0
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		msg, err := outboxMessage(e)
+		if err != nil {
+			slog.Error("dropping undecodable outbox event", "room_id", e.RoomID, "kind", e.Kind, "error", err)
+			ids = append(ids, e.ID)
+			continue
+		}
+		//? The rest stay queued behind the one that failed, to go out in order on the next poll
+		if !h.notifyClients(ctx, msg) {
+			break
+		}
+		ids = append(ids, e.ID)
+		h.chatBridges.mirror(msg)
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+
+	if err := tx.DeleteOutboxEvents(ctx, ids); err != nil {
+		slog.Error("failed to delete outbox events", "error", err)
+		return 0
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("failed to commit outbox transaction", "error", err)
+		return 0
+	}
+
+	return len(ids)
+}
+
+// outboxMessage decodes an event back into the value type of its kind, which
+// chat bridges switch on.
+func outboxMessage(e pg.EventOutbox) (Message, error) {
+	msg := Message{Channel: e.Channel, Kind: e.Kind, RoomID: e.RoomID.String()}
+	//* Events of the questions channel go out without one, as published
+	if msg.Channel == ChannelQuestions {
+		msg.Channel = ""
+	}
+
+	typ, ok := eventValues[e.Kind]
+	if !ok {
+		msg.Value = json.RawMessage(e.Value)
+		return msg, nil
+	}
+	value := reflect.New(reflect.TypeOf(typ))
+	if err := json.Unmarshal(e.Value, value.Interface()); err != nil {
+		return Message{}, err
+	}
+	msg.Value = value.Elem().Interface()
+
+	return msg, nil
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/mappers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	defaultOverlayLimit   = 5
+	defaultOverlayRefresh = 5 * time.Second
+	minOverlayRefresh     = time.Second
+	maxOverlayRefresh     = time.Minute
+	overlayKeepAlive      = 15 * time.Second
+)
+
+// overlayFields are the message fields an overlay can ask for with ?fields.
+var overlayFields = map[string]func(mappers.RoomMessage) any{
+	"id":             func(m mappers.RoomMessage) any { return m.ID },
+	"message":        func(m mappers.RoomMessage) any { return m.Message },
+	"author_name":    func(m mappers.RoomMessage) any { return m.AuthorName },
+	"reaction_count": func(m mappers.RoomMessage) any { return m.ReactionCount },
+	"answered":       func(m mappers.RoomMessage) any { return m.Answered },
+	"answer_text":    func(m mappers.RoomMessage) any { return m.AnswerText },
+	"tags":           func(m mappers.RoomMessage) any { return m.Tags },
+}
+
+var defaultOverlayFields = []string{"id", "message", "author_name", "reaction_count"}
+
+type overlayOptions struct {
+	limit   int
+	fields  []string
+	refresh time.Duration
+	filter  MessageFilter
+}
+
+// parseOverlayOptions reads ?limit, ?fields, ?refresh and the message filters.
+// Browser sources can't set headers, so everything rides on the URL.
+func parseOverlayOptions(query url.Values) (overlayOptions, error) {
+	opts := overlayOptions{limit: defaultOverlayLimit, fields: defaultOverlayFields, refresh: defaultOverlayRefresh}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > publicTopMessagesLimit {
+			return overlayOptions{}, fmt.Errorf("limit must be between 1 and %d", publicTopMessagesLimit)
+		}
+		opts.limit = limit
+	}
+	if raw := query.Get("fields"); raw != "" {
+		opts.fields = strings.Split(raw, ",")
+		for _, field := range opts.fields {
+			if _, ok := overlayFields[field]; !ok {
+				return overlayOptions{}, fmt.Errorf("unknown field %q", field)
+			}
+		}
+	}
+	if raw := query.Get("refresh"); raw != "" {
+		refresh, err := time.ParseDuration(raw)
+		if err != nil || refresh < minOverlayRefresh || refresh > maxOverlayRefresh {
+			return overlayOptions{}, fmt.Errorf("refresh must be between %s and %s", minOverlayRefresh, maxOverlayRefresh)
+		}
+		opts.refresh = refresh
+	}
+
+	filter, ok := parseMessageFilter(query)
+	if !ok {
+		return overlayOptions{}, errors.New("invalid filter")
+	}
+	opts.filter = filter
+
+	return opts, nil
+}
+
+// loadOverlay resolves the room by code and checks the overlay token, taken
+// from ?token or a bearer header. It responds and returns false otherwise.
+func (h apiHandler) loadOverlay(w http.ResponseWriter, r *http.Request) (pg.Room, overlayOptions, bool) {
+	code := chi.URLParam(r, "code")
+	if code == "" || len(code) > 16 {
+		http.Error(w, "invalid room code", http.StatusBadRequest)
+		return pg.Room{}, overlayOptions{}, false
+	}
+
+	opts, err := parseOverlayOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return pg.Room{}, overlayOptions{}, false
+	}
+
+	room, err := h.q.GetRoomByCode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return pg.Room{}, overlayOptions{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return pg.Room{}, overlayOptions{}, false
+	}
+
+	tokenHash, err := h.q.GetRoomOverlayTokenHash(r.Context(), room.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		helpers.LogErrorAndRespond(w, "failed to get overlay token", err, "something went wrong", http.StatusInternalServerError)
+		return pg.Room{}, overlayOptions{}, false
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = utils.ParseBearerToken(r)
+	}
+	if !utils.MatchTokenHash(token, tokenHash) {
+		http.Error(w, "invalid overlay token", http.StatusUnauthorized)
+		return pg.Room{}, overlayOptions{}, false
+	}
+
+	return room, opts, true
+}
+
+// overlayPayload renders the top questions trimmed to the requested fields.
+func (h apiHandler) overlayPayload(ctx context.Context, room pg.Room, opts overlayOptions) ([]byte, error) {
+	//* Filters apply after ranking, so fetch the public window and cut it down
+	messages, err := h.q.GetRoomTopMessages(ctx, pg.GetRoomTopMessagesParams{RoomID: room.ID, Limit: publicTopMessagesLimit})
+	if err != nil {
+		return nil, err
+	}
+	messages = opts.filter.Apply(messages)
+	messages = messages[:min(len(messages), opts.limit)]
+
+	questions := make([]map[string]any, 0, len(messages))
+	for _, message := range mappers.MapMessageToRoomMessage(messages) {
+		question := make(map[string]any, len(opts.fields))
+		for _, field := range opts.fields {
+			question[field] = overlayFields[field](message)
+		}
+		questions = append(questions, question)
+	}
+
+	type overlayRoom struct {
+		Code  string `json:"code"`
+		Theme string `json:"theme"`
+	}
+	type response struct {
+		Room           overlayRoom      `json:"room"`
+		Questions      []map[string]any `json:"questions"`
+		RefreshSeconds float64          `json:"refresh_seconds"`
+	}
+
+	return json.Marshal(response{
+		Room:           overlayRoom{Code: room.Code, Theme: room.Theme},
+		Questions:      questions,
+		RefreshSeconds: opts.refresh.Seconds(),
+	})
+}
+
+// handleGetOverlayTop serves the top questions for browser sources that poll.
+func (h apiHandler) handleGetOverlayTop(w http.ResponseWriter, r *http.Request) {
+	room, opts, ok := h.loadOverlay(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.overlayPayload(r.Context(), room, opts)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to render overlay", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(opts.refresh.Seconds())))
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleStreamOverlayTop pushes the top questions as server-sent events,
+// re-reading them every refresh and sending an update only when they changed.
+func (h apiHandler) handleStreamOverlayTop(w http.ResponseWriter, r *http.Request) {
+	room, opts, ok := h.loadOverlay(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.overlayPayload(r.Context(), room, opts)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to render overlay", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	//* Keeps reverse proxies like nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+
+	send := func(format string, args ...any) bool {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !send("retry: %d\nevent: top\ndata: %s\n\n", opts.refresh.Milliseconds(), data) {
+		return
+	}
+
+	ticker := time.NewTicker(opts.refresh)
+	defer ticker.Stop()
+	last, lastSent := data, time.Now()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := h.overlayPayload(r.Context(), room, opts)
+		if err != nil {
+			if r.Context().Err() == nil {
+				slog.Error("failed to refresh overlay", "room_id", room.ID, "error", err)
+			}
+			return
+		}
+
+		switch {
+		case !bytes.Equal(data, last):
+			if !send("event: top\ndata: %s\n\n", data) {
+				return
+			}
+			last, lastSent = data, time.Now()
+		case time.Since(lastSent) >= overlayKeepAlive:
+			if !send(": keepalive\n\n") {
+				return
+			}
+			lastSent = time.Now()
+		}
+	}
+}
+
+// handleCreateOverlayToken issues the token for the room's overlay feed,
+// replacing any previous one. It's meant to sit in a streamer's browser source
+// URL, so it can read the top questions and nothing else.
+func (h apiHandler) handleCreateOverlayToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate overlay token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	err = h.q.UpsertRoomOverlayToken(r.Context(), pg.UpsertRoomOverlayTokenParams{RoomID: room.ID, TokenHash: utils.HashToken(token)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save overlay token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Token     string `json:"token"`
+		JSONURL   string `json:"json_url"`
+		StreamURL string `json:"stream_url"`
+	}
+
+	base := "/overlay/rooms/" + url.PathEscape(room.Code)
+	query := "?token=" + url.QueryEscape(token)
+	data, err := json.Marshal(response{
+		Token:     token,
+		JSONURL:   base + "/top.json" + query,
+		StreamURL: base + "/top/stream" + query,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteOverlayToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomOverlayToken(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete overlay token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "overlay is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const maxPageSize = 200
+
+// page is a keyset page request: the limit rows created before the cursor,
+// newest first. A zero limit asks for everything, unpaginated.
+type page struct {
+	limit     int32
+	createdAt pgtype.Timestamptz
+	id        uuid.UUID
+	// reactionCount leads the cursor of listings ordered by reactions.
+	reactionCount pgtype.Int8
+}
+
+// parsePage reads ?limit and ?cursor. A cursor without a limit pages with
+// the largest page size.
+func parsePage(query url.Values) (page, bool) {
+	var p page
+	if raw := query.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPageSize {
+			return page{}, false
+		}
+		p.limit = int32(v)
+	}
+
+	raw := query.Get("cursor")
+	if raw == "" {
+		return p, true
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return page{}, false
+	}
+	fields := strings.Split(string(decoded), "|")
+	if len(fields) == 3 {
+		count, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return page{}, false
+		}
+		p.reactionCount = pgtype.Int8{Int64: count, Valid: true}
+		fields = fields[1:]
+	}
+	if len(fields) != 2 {
+		return page{}, false
+	}
+	rawTime, rawID := fields[0], fields[1]
+	createdAt, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return page{}, false
+	}
+	if p.id, err = uuid.Parse(rawID); err != nil {
+		return page{}, false
+	}
+	p.createdAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+	if p.limit == 0 {
+		p.limit = maxPageSize
+	}
+
+	return p, true
+}
+
+// nextCursor points past the last row of a full page, and is empty once a
+// page comes back short, there being nothing after it.
+func (p page) nextCursor(rows int, createdAt time.Time, id uuid.UUID) string {
+	if p.limit == 0 || rows < int(p.limit) {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+// nextReactionsCursor is nextCursor for listings ordered by reactions.
+func (p page) nextReactionsCursor(rows int, reactionCount int64, createdAt time.Time, id uuid.UUID) string {
+	if p.limit == 0 || rows < int(p.limit) {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(reactionCount, 10) + "|" + createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/mappers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	publicTopMessagesLimit = 50
+	//* Shared caches may keep the snapshot for an hour and serve it stale while refreshing
+	publicCacheControl = "public, max-age=300, s-maxage=3600, stale-while-revalidate=86400"
+)
+
+func (h apiHandler) handleGetPublicRoom(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if code == "" || len(code) > 16 {
+		http.Error(w, "invalid room code", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoomByCode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.q.GetRoomTopMessages(r.Context(), pg.GetRoomTopMessagesParams{
+		RoomID: room.ID,
+		Limit:  publicTopMessagesLimit,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get top messages", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type publicRoom struct {
+		Code  string `json:"code"`
+		Theme string `json:"theme"`
+	}
+	type response struct {
+		Room      publicRoom              `json:"room"`
+		Questions []mappers.PublicMessage `json:"questions"`
+	}
+
+	data, err := json.Marshal(response{
+		Room:      publicRoom{Code: room.Code, Theme: room.Theme},
+		Questions: mappers.MapMessageToPublicMessage(messages),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", publicCacheControl)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
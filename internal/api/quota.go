@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/quota"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// limitsFor returns the quotas of an organization, from its plan when it has
+// one. Lookup failures fall back to the server defaults rather than blocking.
+func (h apiHandler) limitsFor(ctx context.Context, organizationID uuid.UUID) quota.Limits {
+	settings, err := h.organizationSettings(ctx, organizationID)
+	if err != nil {
+		slog.Warn("failed to load organization settings, using default quotas", "organization_id", organizationID.String(), "error", err)
+		return h.quotas
+	}
+
+	return settings.Limits(h.quotas)
+}
+
+// enforceRoomQuota caps the rooms an organization creates per calendar month.
+func (h apiHandler) enforceRoomQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := tenant.FromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limits := h.limitsFor(r.Context(), key.OrganizationID)
+		if limits.RoomsPerMonth == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		month := quota.MonthStart(time.Now())
+		used, err := h.q.CountOrganizationRoomsSince(r.Context(), pg.CountOrganizationRoomsSinceParams{
+			OrganizationID: uuid.NullUUID{UUID: key.OrganizationID, Valid: true},
+			CreatedAt:      month,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to count organization rooms", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		usage := quota.Usage{
+			Resource: quota.ResourceRoomsPerMonth,
+			Limit:    limits.RoomsPerMonth,
+			Used:     used,
+			Reset:    month.AddDate(0, 1, 0),
+		}
+		if usage.Exceeded() {
+			quota.Deny(w, usage)
+			return
+		}
+		quota.WriteHeaders(w, usage)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enforceMessageQuota caps the messages of rooms owned by an organization.
+// Deleted messages still count, so retracting doesn't free up room.
+func (h apiHandler) enforceMessageQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		room, ok := h.quotaRoom(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limits := h.limitsFor(r.Context(), room.OrganizationID.UUID)
+		if limits.MessagesPerRoom == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		used, err := h.q.CountRoomMessages(r.Context(), room.ID)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to count room messages", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		usage := quota.Usage{Resource: quota.ResourceMessagesPerRoom, Limit: limits.MessagesPerRoom, Used: used}
+		if usage.Exceeded() {
+			quota.Deny(w, usage)
+			return
+		}
+		quota.WriteHeaders(w, usage)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enforceConnectionQuota caps the live sockets of an organization's rooms on
+// this instance.
+func (h apiHandler) enforceConnectionQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		room, ok := h.quotaRoom(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limits := h.limitsFor(r.Context(), room.OrganizationID.UUID)
+		if limits.ConnectionsPerOrg == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.mu.Lock()
+		used := int64(h.orgConnections[room.OrganizationID.UUID])
+		h.mu.Unlock()
+
+		usage := quota.Usage{Resource: quota.ResourceConnectionsPerOrg, Limit: limits.ConnectionsPerOrg, Used: used}
+		if usage.Exceeded() {
+			quota.Deny(w, usage)
+			return
+		}
+		quota.WriteHeaders(w, usage)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// quotaRoom loads the routed room when it belongs to an organization. Any
+// lookup failure is left for the handler to report.
+func (h apiHandler) quotaRoom(r *http.Request) (pg.Room, bool) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		return pg.Room{}, false
+	}
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil || !room.OrganizationID.Valid {
+		return pg.Room{}, false
+	}
+
+	return room, true
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	ReactionKindLike     = "like"
+	ReactionKindHeart    = "heart"
+	ReactionKindLaugh    = "laugh"
+	ReactionKindClap     = "clap"
+	ReactionKindFire     = "fire"
+	ReactionKindThinking = "thinking"
+)
+
+var reactionKinds = map[string]bool{
+	ReactionKindLike:     true,
+	ReactionKindHeart:    true,
+	ReactionKindLaugh:    true,
+	ReactionKindClap:     true,
+	ReactionKindFire:     true,
+	ReactionKindThinking: true,
+}
+
+var errInvalidReactionKind = errors.New("invalid reaction kind")
+
+// parseReactionKind reads the optional {"kind": "..."} body, defaulting to a like.
+func parseReactionKind(r *http.Request) (string, error) {
+	type _body struct {
+		Kind string `json:"kind"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	if body.Kind == "" {
+		return ReactionKindLike, nil
+	}
+	if !reactionKinds[body.Kind] {
+		return "", errInvalidReactionKind
+	}
+
+	return body.Kind, nil
+}
+
+const (
+	defaultReactionBucket = time.Minute
+	maxReactionBucket     = 24 * time.Hour
+)
+
+func (h apiHandler) handleGetRoomReactionsSummary(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	bucket := defaultReactionBucket
+	if raw := r.URL.Query().Get("bucket"); raw != "" {
+		bucket, err = time.ParseDuration(raw)
+		if err != nil || bucket < time.Second || bucket > maxReactionBucket || bucket%time.Second != 0 {
+			http.Error(w, "invalid bucket", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	totals, err := h.q.GetRoomReactionTotals(r.Context(), roomID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get reaction totals", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	buckets, err := h.q.GetRoomReactionBuckets(r.Context(), pg.GetRoomReactionBucketsParams{
+		BucketSeconds: int32(bucket / time.Second),
+		RoomID:        roomID,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get reaction buckets", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type kindTotal struct {
+		Kind  string `json:"kind"`
+		Count int64  `json:"count"`
+	}
+	type timeBucket struct {
+		Start time.Time `json:"start"`
+		Kind  string    `json:"kind"`
+		Count int64     `json:"count"`
+	}
+	type messageBuckets struct {
+		MessageID string       `json:"message_id"`
+		Buckets   []timeBucket `json:"buckets"`
+	}
+	type response struct {
+		RoomID   string           `json:"room_id"`
+		Bucket   string           `json:"bucket"`
+		Totals   []kindTotal      `json:"totals"`
+		Messages []messageBuckets `json:"messages"`
+	}
+
+	res := response{
+		RoomID:   roomID.String(),
+		Bucket:   bucket.String(),
+		Totals:   []kindTotal{},
+		Messages: []messageBuckets{},
+	}
+	for _, total := range totals {
+		res.Totals = append(res.Totals, kindTotal{Kind: total.Kind, Count: total.Count})
+	}
+	//* Rows come ordered by message, so consecutive rows share a group
+	for _, row := range buckets {
+		last := len(res.Messages) - 1
+		if last < 0 || res.Messages[last].MessageID != row.MessageID.String() {
+			res.Messages = append(res.Messages, messageBuckets{MessageID: row.MessageID.String()})
+			last++
+		}
+		res.Messages[last].Buckets = append(res.Messages[last].Buckets, timeBucket{
+			Start: row.BucketStart,
+			Kind:  row.Kind,
+			Count: row.Count,
+		})
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+type RoomConnections struct {
+	RoomID      string `json:"room_id"`
+	Subscribers int    `json:"subscribers"`
+	Waiting     int    `json:"waiting"`
+}
+
+type InstanceConnections struct {
+	Total int               `json:"total"`
+	Rooms []RoomConnections `json:"rooms"`
+}
+
+// handleGetConnections reports this instance's connection counts so a
+// coordinator can compare instances.
+func (h apiHandler) handleGetConnections(w http.ResponseWriter, r *http.Request) {
+	res := InstanceConnections{Rooms: []RoomConnections{}}
+
+	h.mu.Lock()
+	for roomID, subscribers := range h.subscribers {
+		res.Total += len(subscribers)
+		res.Rooms = append(res.Rooms, RoomConnections{
+			RoomID:      roomID,
+			Subscribers: len(subscribers),
+			Waiting:     len(h.waiting[roomID]),
+		})
+	}
+	h.mu.Unlock()
+
+	sort.Slice(res.Rooms, func(i, j int) bool { return res.Rooms[i].Subscribers > res.Rooms[j].Subscribers })
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleShedRoomSubscribers advises up to count subscribers of a room to
+// reconnect, letting the load balancer place them on another instance.
+func (h apiHandler) handleShedRoomSubscribers(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	type _body struct {
+		Count int `json:"count"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if body.Count <= 0 {
+		http.Error(w, "count must be positive", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	shed := h.shedLocked(roomID.String(), body.Count)
+	h.mu.Unlock()
+
+	type response struct {
+		RoomID string `json:"room_id"`
+		Shed   int    `json:"shed"`
+	}
+
+	data, err := json.Marshal(response{RoomID: roomID.String(), Shed: shed})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// shedLocked picks up to count non-host subscribers, queued ones first since
+// they lose nothing by moving, and advises them to reconnect. Must be called
+// with h.mu held.
+func (h apiHandler) shedLocked(roomID string, count int) int {
+	candidates := make([]clientConn, 0, count)
+	candidates = append(candidates, h.waiting[roomID]...)
+	for conn, sub := range h.subscribers[roomID] {
+		if !sub.waiting {
+			candidates = append(candidates, conn)
+		}
+	}
+
+	shed := make([]clientConn, 0, count)
+	for _, conn := range candidates {
+		if len(shed) == count {
+			break
+		}
+		if sub := h.subscribers[roomID][conn]; sub.host || sub.closed {
+			continue
+		}
+		shed = append(shed, conn)
+	}
+
+	lower, upper := reconnectWindow(len(shed))
+	for _, conn := range shed {
+		h.adviseLocked(roomID, h.subscribers[roomID][conn], newReconnectAdvice(ReconnectReasonRebalance, lower, upper))
+	}
+
+	return len(shed)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const MessageKindReconnectAdvised = "reconnect_advised"
+
+const (
+	ReconnectReasonDeploy     = "deploy"
+	ReconnectReasonRebalance  = "rebalance"
+	ReconnectReasonRoomClosed = "room_closed"
+	ReconnectReasonFailover   = "failover"
+)
+
+const closeFrameTimeout = time.Second
+
+// * Suggested reconnect windows. The upper bound grows with the number of
+// * sockets advised at once so big rooms don't come back in a single burst.
+const (
+	reconnectBackoffMin      = 500 * time.Millisecond
+	reconnectBackoffMax      = 30 * time.Second
+	reconnectSpreadPerSocket = 2 * time.Millisecond
+)
+
+// MessageReconnectAdvised asks the client to drop the socket and reconnect.
+// Clients should wait RetryAfterMs before the first attempt and keep any
+// further retries inside [BackoffMinMs, BackoffMaxMs] with jitter. URL, when
+// set, is the deployment to reconnect to instead of this one.
+type MessageReconnectAdvised struct {
+	Reason       string `json:"reason"`
+	URL          string `json:"url,omitempty"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+	BackoffMinMs int64  `json:"backoff_min_ms"`
+	BackoffMaxMs int64  `json:"backoff_max_ms"`
+}
+
+// reconnectWindow returns the backoff bounds for advising count sockets at once.
+func reconnectWindow(count int) (time.Duration, time.Duration) {
+	upper := reconnectBackoffMin + time.Duration(count)*reconnectSpreadPerSocket
+	upper = max(upper, 2*reconnectBackoffMin)
+	upper = min(upper, reconnectBackoffMax)
+
+	return reconnectBackoffMin, upper
+}
+
+func newReconnectAdvice(reason string, lower, upper time.Duration) MessageReconnectAdvised {
+	retryAfter := lower + rand.N(upper-lower)
+
+	return MessageReconnectAdvised{
+		Reason:       reason,
+		RetryAfterMs: retryAfter.Milliseconds(),
+		BackoffMinMs: lower.Milliseconds(),
+		BackoffMaxMs: upper.Milliseconds(),
+	}
+}
+
+// adviseReconnectLocked tells every subscriber to reconnect, then closes
+// their sockets. Must be called with h.mu held.
+func (h apiHandler) adviseReconnectLocked(reason string) {
+	total := 0
+	for _, subscribers := range h.subscribers {
+		total += len(subscribers)
+	}
+	lower, upper := reconnectWindow(total)
+
+	for roomID, subscribers := range h.subscribers {
+		for _, sub := range subscribers {
+			h.adviseLocked(roomID, sub, newReconnectAdvice(reason, lower, upper))
+		}
+	}
+}
+
+// adviseLocked sends a reconnect advisory and closes the socket. Must be
+// called with h.mu held.
+func (h apiHandler) adviseLocked(roomID string, sub *subscriber, advice MessageReconnectAdvised) {
+	h.writeLocked(sub, Message{
+		Kind:   MessageKindReconnectAdvised,
+		RoomID: roomID,
+		Value:  advice,
+	})
+
+	code := websocket.CloseGoingAway
+	if advice.Reason == ReconnectReasonRoomClosed {
+		code = websocket.CloseNormalClosure
+	}
+	h.closeLocked(sub, code, advice.Reason)
+}
+
+// closeLocked queues a close frame after the frames already queued, the
+// writer tearing the subscription down once it's out. Must be called with
+// h.mu held.
+func (h apiHandler) closeLocked(sub *subscriber, code int, text string) {
+	h.queueLocked(sub, &outboundFrame{close: &closeFrame{code: code, text: text}})
+	//? Marked closed so the waiting room cleanup stops writing to it on the way out
+	sub.closed = true
+}
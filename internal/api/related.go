@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	defaultRelatedRoomsLimit = 5
+	maxRelatedRoomsLimit     = 20
+)
+
+// handleGetRelatedRooms suggests other open rooms of the same organization
+// whose theme and description read alike, by trigram similarity, so the
+// audience of one track can find its neighbours.
+func (h apiHandler) handleGetRelatedRooms(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultRelatedRoomsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxRelatedRoomsLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxRelatedRoomsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.q.GetRelatedRooms(r.Context(), pg.GetRelatedRoomsParams{
+		Source:         room.Theme + " " + room.Description,
+		ID:             room.ID,
+		OrganizationID: room.OrganizationID,
+		MaxRooms:       int32(limit),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get related rooms", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type relatedRoom struct {
+		ID          string  `json:"id"`
+		Code        string  `json:"code"`
+		Theme       string  `json:"theme"`
+		Description string  `json:"description"`
+		Score       float32 `json:"score"`
+	}
+	type response struct {
+		RoomID string        `json:"room_id"`
+		Rooms  []relatedRoom `json:"rooms"`
+	}
+
+	rooms := make([]relatedRoom, 0, len(rows))
+	for _, row := range rows {
+		rooms = append(rooms, relatedRoom{
+			ID:          row.ID.String(),
+			Code:        row.Code,
+			Theme:       row.Theme,
+			Description: row.Description,
+			Score:       row.Score,
+		})
+	}
+
+	data, err := json.Marshal(response{RoomID: room.ID.String(), Rooms: rooms})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
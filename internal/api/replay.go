@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// MessageKindResyncRequired tells a reconnecting client the events it missed
+// can't be replayed, so it gets GET /rooms/{room_id}/messages again and goes
+// on from its last_event_id.
+const MessageKindResyncRequired = "resync_required"
+
+const (
+	// ResyncReasonPruned is a gap in the log, pruned by its retention.
+	ResyncReasonPruned = "pruned"
+	// ResyncReasonTooFarBehind is more missed events than WS_REPLAY_MAX_EVENTS.
+	ResyncReasonTooFarBehind = "too_far_behind"
+	// ResyncReasonUnavailable is a log that couldn't be read.
+	ResyncReasonUnavailable = "unavailable"
+
+	defaultReplayMaxEvents = 1000
+)
+
+type MessageResyncRequired struct {
+	Reason string `json:"reason"`
+}
+
+// replayAfter reads the event id a reconnecting client last got, from
+// ?last_event_id or the Last-Event-ID header an EventSource sends on its own.
+// It reports false when there's neither.
+func replayAfter(r *http.Request) (int64, bool, error) {
+	raw := r.URL.Query().Get("last_event_id")
+	if raw == "" {
+		raw = r.Header.Get("Last-Event-ID")
+	}
+	if raw == "" {
+		return 0, false, nil
+	}
+
+	after, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	if after < 0 {
+		return 0, false, fmt.Errorf("negative event id %d", after)
+	}
+
+	return after, true, nil
+}
+
+// replay sends a subscriber that just joined the events of its channels
+// logged after after, before any live one. The log is read without h.mu:
+// the subscriber joined replaying, so broadcastLocked holds its live events
+// back meanwhile, and they're merged with the logged ones here.
+func (h apiHandler) replay(ctx context.Context, sub *subscriber, roomID uuid.UUID, after int64) {
+	seq, events, reason, err := h.replayEvents(ctx, sub, roomID, after)
+	if err != nil {
+		sub.log.Error("failed to read room events for replay", "after_event_id", after, "error", err)
+		reason = ResyncReasonUnavailable
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := sub.backlog
+	sub.backlog, sub.replaying = nil, false
+
+	//* An event sequenced before seq may only have been logged after the log
+	//* was read, it's then in the backlog
+	missed := make(map[int64]*outboundFrame, len(events)+len(backlog))
+	for _, e := range events {
+		if e.EventID > seq {
+			break
+		}
+		missed[e.EventID] = &outboundFrame{msg: Message{
+			EventID: e.EventID,
+			Channel: e.Channel,
+			Kind:    e.Kind,
+			Value:   json.RawMessage(e.Value),
+		}}
+	}
+	for _, f := range backlog {
+		if f.msg.EventID > after && f.msg.EventID <= seq {
+			missed[f.msg.EventID] = f
+		}
+	}
+	//* The log is best effort too, any event missing from it is a gap
+	if reason == "" && int64(len(missed)) != seq-after {
+		reason = ResyncReasonPruned
+	}
+
+	if reason != "" {
+		h.writeLocked(sub, Message{Kind: MessageKindResyncRequired, Value: MessageResyncRequired{Reason: reason}})
+	} else {
+		for id := after + 1; id <= seq; id++ {
+			if f := missed[id]; sub.channels[f.msg.Channel] {
+				h.queueLocked(sub, f)
+			}
+		}
+	}
+
+	for _, f := range backlog {
+		if (f.msg.EventID == 0 || f.msg.EventID > seq) && sub.channels[f.msg.Channel] {
+			h.queueLocked(sub, f)
+		}
+	}
+}
+
+// replayEvents reads the room's sequence and the events logged after after
+// up to it, or the reason to resync instead.
+func (h apiHandler) replayEvents(ctx context.Context, sub *subscriber, roomID uuid.UUID, after int64) (int64, []pg.RoomEvent, string, error) {
+	seq, err := h.q.GetRoomEventSeq(ctx, roomID)
+	if err != nil || after >= seq {
+		return after, nil, "", err
+	}
+
+	h.mu.Lock()
+	limit := int64(min(h.replayLimit, cap(sub.out)-len(sub.out)))
+	h.mu.Unlock()
+	//? The queue takes the whole replay at once, the writer has yet to drain it
+	if seq-after > limit {
+		return seq, nil, ResyncReasonTooFarBehind, nil
+	}
+
+	events, err := h.q.GetRoomEvents(ctx, pg.GetRoomEventsParams{
+		RoomID:       roomID,
+		AfterEventID: after,
+		MaxEvents:    int32(seq - after),
+	})
+	if err != nil {
+		return after, nil, "", err
+	}
+
+	return seq, events, "", nil
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/jobs"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	jobKindEventReport = "event_report"
+
+	reportFormatCSV  = "csv"
+	reportFormatJSON = "json"
+
+	// reportTopQuestions is how many of each room's questions the report lists.
+	reportTopQuestions = 3
+)
+
+type eventReportJob struct {
+	EventID        uuid.UUID `json:"event_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Format         string    `json:"format"`
+}
+
+type reportQuestion struct {
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	Reactions int64  `json:"reactions"`
+	Answered  bool   `json:"answered"`
+}
+
+type reportCounts struct {
+	PeakSubscribers int32   `json:"peak_subscribers"`
+	Participants    int64   `json:"participants"`
+	Messages        int64   `json:"messages"`
+	Answered        int64   `json:"answered"`
+	AnsweredRate    float64 `json:"answered_rate"`
+	Reactions       int64   `json:"reactions"`
+}
+
+type reportRoom struct {
+	ID       string     `json:"id"`
+	Code     string     `json:"code"`
+	Theme    string     `json:"theme"`
+	Track    string     `json:"track"`
+	StartsAt *time.Time `json:"starts_at"`
+	Host     string     `json:"host"`
+	reportCounts
+	TopQuestions []reportQuestion `json:"top_questions"`
+}
+
+// eventReport consolidates every room of an event. The total peak is the
+// largest peak of a single room, rooms running at different times.
+type eventReport struct {
+	EventID     string       `json:"event_id"`
+	Name        string       `json:"name"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Total       reportCounts `json:"total"`
+	Rooms       []reportRoom `json:"rooms"`
+}
+
+func answeredRate(answered, messages int64) float64 {
+	if messages == 0 {
+		return 0
+	}
+	return float64(answered) / float64(messages)
+}
+
+// loadEventReport gathers the report from the rooms scheduled in the event.
+func (h apiHandler) loadEventReport(ctx context.Context, event pg.Event) (eventReport, error) {
+	rooms, err := h.q.GetEventReportRooms(ctx, event.ID)
+	if err != nil {
+		return eventReport{}, fmt.Errorf("get event rooms: %w", err)
+	}
+	top, err := h.q.GetEventTopMessages(ctx, pg.GetEventTopMessagesParams{EventID: event.ID, PerRoom: reportTopQuestions})
+	if err != nil {
+		return eventReport{}, fmt.Errorf("get event top messages: %w", err)
+	}
+
+	questions := map[uuid.UUID][]reportQuestion{}
+	for _, message := range top {
+		questions[message.RoomID] = append(questions[message.RoomID], reportQuestion{
+			ID:        message.ID.String(),
+			Message:   h.redactor.String(message.Message),
+			Reactions: message.ReactionCount,
+			Answered:  message.Answered,
+		})
+	}
+
+	report := eventReport{
+		EventID:     event.ID.String(),
+		Name:        event.Name,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Rooms:       make([]reportRoom, 0, len(rooms)),
+	}
+	for _, room := range rooms {
+		var startsAt *time.Time
+		if room.StartsAt.Valid {
+			startsAt = &room.StartsAt.Time
+		}
+		topQuestions := questions[room.ID]
+		if topQuestions == nil {
+			topQuestions = []reportQuestion{}
+		}
+
+		report.Rooms = append(report.Rooms, reportRoom{
+			ID:       room.ID.String(),
+			Code:     room.Code,
+			Theme:    room.Theme,
+			Track:    room.TrackName,
+			StartsAt: startsAt,
+			Host:     room.HostName,
+			reportCounts: reportCounts{
+				PeakSubscribers: room.PeakSubscribers,
+				Participants:    room.ParticipantCount,
+				Messages:        room.MessageCount,
+				Answered:        room.AnsweredCount,
+				AnsweredRate:    answeredRate(room.AnsweredCount, room.MessageCount),
+				Reactions:       room.ReactionCount,
+			},
+			TopQuestions: topQuestions,
+		})
+
+		report.Total.PeakSubscribers = max(report.Total.PeakSubscribers, room.PeakSubscribers)
+		report.Total.Participants += room.ParticipantCount
+		report.Total.Messages += room.MessageCount
+		report.Total.Answered += room.AnsweredCount
+		report.Total.Reactions += room.ReactionCount
+	}
+	report.Total.AnsweredRate = answeredRate(report.Total.Answered, report.Total.Messages)
+
+	return report, nil
+}
+
+// renderReportCSV writes a row per room, the top questions joined in one
+// column so spreadsheets keep a room to a line.
+func renderReportCSV(report eventReport) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	_ = cw.Write([]string{
+		"track", "code", "title", "start_time", "host",
+		"peak_subscribers", "participants", "messages", "answered", "answered_rate", "reactions",
+		"top_questions",
+	})
+	for _, room := range report.Rooms {
+		startTime := ""
+		if room.StartsAt != nil {
+			startTime = room.StartsAt.UTC().Format(time.RFC3339)
+		}
+		questions := make([]string, 0, len(room.TopQuestions))
+		for _, question := range room.TopQuestions {
+			questions = append(questions, question.Message)
+		}
+
+		_ = cw.Write([]string{
+			room.Track, room.Code, room.Theme, startTime, room.Host,
+			strconv.Itoa(int(room.PeakSubscribers)),
+			strconv.FormatInt(room.Participants, 10),
+			strconv.FormatInt(room.Messages, 10),
+			strconv.FormatInt(room.Answered, 10),
+			strconv.FormatFloat(room.AnsweredRate, 'f', 3, 64),
+			strconv.FormatInt(room.Reactions, 10),
+			strings.Join(questions, " | "),
+		})
+	}
+	cw.Flush()
+
+	return buf.Bytes(), cw.Error()
+}
+
+// runEventReportJob is the jobs handler generating an event report.
+func (h apiHandler) runEventReportJob(ctx context.Context, payload []byte) (jobs.Output, error) {
+	var job eventReportJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Output{}, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	event, err := h.q.GetOrganizationEvent(ctx, pg.GetOrganizationEventParams{ID: job.EventID, OrganizationID: job.OrganizationID})
+	if err != nil {
+		return jobs.Output{}, fmt.Errorf("get event: %w", err)
+	}
+	report, err := h.loadEventReport(ctx, event)
+	if err != nil {
+		return jobs.Output{}, err
+	}
+
+	output := jobs.Output{Filename: fmt.Sprintf("event-%s-report.%s", event.ID, job.Format)}
+	if job.Format == reportFormatCSV {
+		output.ContentType = "text/csv; charset=utf-8"
+		output.Data, err = renderReportCSV(report)
+	} else {
+		output.ContentType = "application/json"
+		output.Data, err = json.Marshal(report)
+	}
+	if err != nil {
+		return jobs.Output{}, fmt.Errorf("render report: %w", err)
+	}
+
+	return output, nil
+}
+
+type reportJobResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	StatusURL   string     `json:"status_url"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// reportJobResponse describes the job, with a signed download link once done.
+func (h apiHandler) reportJobResponse(event pg.Event, job pg.Job) reportJobResponse {
+	res := reportJobResponse{
+		ID:        job.ID.String(),
+		Status:    job.Status,
+		CreatedAt: job.CreatedAt,
+		StatusURL: "/api/events/" + event.ID.String() + "/report/" + job.ID.String(),
+	}
+	if job.Status == jobs.StatusFailed {
+		res.Error = job.Error
+	}
+	if job.FinishedAt.Valid {
+		res.FinishedAt = &job.FinishedAt.Time
+	}
+	if job.Status == jobs.StatusDone {
+		expiresAt := time.Now().Add(defaultExportLinkTTL).UTC().Truncate(time.Second)
+		res.DownloadURL = h.links.Sign("/downloads/jobs/"+job.ID.String(), nil, expiresAt)
+		res.ExpiresAt = &expiresAt
+	}
+
+	return res
+}
+
+// handleCreateEventReport queues the generation of the event report, in
+// ?format=csv or json. The job is polled through the returned status_url,
+// which links to the download once ready.
+func (h apiHandler) handleCreateEventReport(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = reportFormatCSV
+	}
+	if format != reportFormatCSV && format != reportFormatJSON {
+		http.Error(w, "unsupported report format", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), jobKindEventReport, uuid.NullUUID{UUID: event.OrganizationID, Valid: true}, eventReportJob{
+		EventID:        event.ID,
+		OrganizationID: event.OrganizationID,
+		Format:         format,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to enqueue event report", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	res := h.reportJobResponse(event, job)
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", res.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleGetEventReport(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+	jobID, err := utils.ParseUUIDParam(r, "job_id")
+	if err != nil {
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	key, _ := tenant.FromContext(r.Context())
+	job, err := h.q.GetOrganizationJob(r.Context(), pg.GetOrganizationJobParams{
+		ID:             jobID,
+		OrganizationID: uuid.NullUUID{UUID: key.OrganizationID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "report not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get job", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	var payload eventReportJob
+	if job.Kind != jobKindEventReport || json.Unmarshal(job.Payload, &payload) != nil || payload.EventID != event.ID {
+		http.Error(w, "report not found", http.StatusNotFound)
+		return
+	}
+
+	data, err := json.Marshal(h.reportJobResponse(event, job))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleDownloadJob serves the output of a finished job through a signed
+// link, the signature standing in for the api key.
+func (h apiHandler) handleDownloadJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := utils.ParseUUIDParam(r, "job_id")
+	if err != nil {
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.q.GetJob(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get job", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if job.Status != jobs.StatusDone {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", job.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, job.Filename))
+	w.Header().Set("Cache-Control", "private, no-store")
+	_, err = w.Write(job.Output)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/mappers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const maxEventNameLength = 255
+
+// parseEventName trims a name for an event or a track, reporting false when
+// it ends up empty or too long.
+func parseEventName(raw string) (string, bool) {
+	name := strings.TrimSpace(raw)
+
+	return name, name != "" && utf8.RuneCountInString(name) <= maxEventNameLength
+}
+
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+// organizationEvent loads the event of the request, which must belong to the
+// API key's organization. It responds and returns false otherwise.
+func (h apiHandler) organizationEvent(w http.ResponseWriter, r *http.Request) (pg.Event, bool) {
+	key, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "an api key is required", http.StatusUnauthorized)
+		return pg.Event{}, false
+	}
+	eventID, err := utils.ParseUUIDParam(r, "event_id")
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return pg.Event{}, false
+	}
+
+	//? Another organization's event answers like a missing one, not to reveal it exists
+	event, err := h.q.GetOrganizationEvent(r.Context(), pg.GetOrganizationEventParams{ID: eventID, OrganizationID: key.OrganizationID})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "event not found", http.StatusNotFound)
+			return pg.Event{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to get event", err, "something went wrong", http.StatusInternalServerError)
+		return pg.Event{}, false
+	}
+
+	return event, true
+}
+
+// eventTrack loads the track of the request within event. It responds and
+// returns false when it isn't part of it.
+func (h apiHandler) eventTrack(w http.ResponseWriter, r *http.Request, event pg.Event) (pg.Track, bool) {
+	trackID, err := utils.ParseUUIDParam(r, "track_id")
+	if err != nil {
+		http.Error(w, "invalid track id", http.StatusBadRequest)
+		return pg.Track{}, false
+	}
+
+	track, err := h.q.GetEventTrack(r.Context(), pg.GetEventTrackParams{ID: trackID, EventID: event.ID})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "track not found", http.StatusNotFound)
+			return pg.Track{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to get track", err, "something went wrong", http.StatusInternalServerError)
+		return pg.Track{}, false
+	}
+
+	return track, true
+}
+
+func (h apiHandler) handleCreateEvent(w http.ResponseWriter, r *http.Request) {
+	key, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "an api key is required", http.StatusUnauthorized)
+		return
+	}
+
+	type _body struct {
+		Name     string     `json:"name"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	name, ok := parseEventName(body.Name)
+	if !ok {
+		helpers.RespondValidationErrors(w, []forms.FieldError{{Field: "name", Message: "must be between 1 and 255 characters"}})
+		return
+	}
+	if body.StartsAt != nil && body.EndsAt != nil && body.EndsAt.Before(*body.StartsAt) {
+		helpers.RespondValidationErrors(w, []forms.FieldError{{Field: "ends_at", Message: "must not be before starts_at"}})
+		return
+	}
+
+	event, err := h.q.InsertEvent(r.Context(), pg.InsertEventParams{
+		OrganizationID: key.OrganizationID,
+		Name:           name,
+		StartsAt:       timestamptz(body.StartsAt),
+		EndsAt:         timestamptz(body.EndsAt),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert event", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(mappers.MapEvent(event))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	key, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "an api key is required", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := h.q.GetOrganizationEvents(r.Context(), key.OrganizationID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get events", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Events []mappers.Event `json:"events"`
+	}
+
+	data, err := json.Marshal(response{Events: mappers.MapEvents(events)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// eventSchedule is an event with its tracks in order, each listing its rooms.
+type eventSchedule struct {
+	mappers.Event
+	RoomDefaults RoomDefaults    `json:"room_defaults"`
+	Tracks       []scheduleTrack `json:"tracks"`
+}
+
+type scheduleTrack struct {
+	mappers.Track
+	Rooms []scheduleRoom `json:"rooms"`
+}
+
+type scheduleRoom struct {
+	ID       string     `json:"id"`
+	Code     string     `json:"code"`
+	Theme    string     `json:"theme"`
+	Status   string     `json:"status"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	HostName string     `json:"host_name,omitempty"`
+}
+
+func (h apiHandler) handleGetEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	tracks, err := h.q.GetEventTracks(r.Context(), event.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get event tracks", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	rooms, err := h.q.GetEventRoomStats(r.Context(), event.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get event rooms", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	byTrack := map[uuid.UUID][]scheduleRoom{}
+	for _, room := range rooms {
+		status := roomStatus(room.ArchivedAt, room.ClosedAt)
+		var startsAt *time.Time
+		if room.StartsAt.Valid {
+			startsAt = &room.StartsAt.Time
+		}
+		byTrack[room.TrackID.UUID] = append(byTrack[room.TrackID.UUID], scheduleRoom{
+			ID:       room.ID.String(),
+			Code:     room.Code,
+			Theme:    room.Theme,
+			Status:   status,
+			StartsAt: startsAt,
+			HostName: room.HostName,
+		})
+	}
+
+	res := eventSchedule{
+		Event:        mappers.MapEvent(event),
+		RoomDefaults: parseRoomDefaults(event.RoomDefaults),
+		Tracks:       make([]scheduleTrack, 0, len(tracks)),
+	}
+	for _, track := range tracks {
+		trackRooms := byTrack[track.ID]
+		if trackRooms == nil {
+			trackRooms = []scheduleRoom{}
+		}
+		res.Tracks = append(res.Tracks, scheduleTrack{Track: mappers.MapTrack(track), Rooms: trackRooms})
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleDeleteEvent deletes the event and its tracks. Their rooms are kept,
+// only losing their place in the schedule.
+func (h apiHandler) handleDeleteEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.q.DeleteEvent(r.Context(), pg.DeleteEventParams{ID: event.ID, OrganizationID: event.OrganizationID}); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete event", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h apiHandler) handleCreateTrack(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	type _body struct {
+		Name     string `json:"name"`
+		Position int32  `json:"position"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	name, ok := parseEventName(body.Name)
+	if !ok {
+		helpers.RespondValidationErrors(w, []forms.FieldError{{Field: "name", Message: "must be between 1 and 255 characters"}})
+		return
+	}
+
+	track, err := h.q.InsertTrack(r.Context(), pg.InsertTrackParams{EventID: event.ID, Name: name, Position: body.Position})
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			http.Error(w, "the event already has a track with this name", http.StatusConflict)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to insert track", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(mappers.MapTrack(track))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleDeleteTrack deletes the track, its rooms leaving the schedule.
+func (h apiHandler) handleDeleteTrack(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+	track, ok := h.eventTrack(w, r, event)
+	if !ok {
+		return
+	}
+
+	if _, err := h.q.DeleteTrack(r.Context(), pg.DeleteTrackParams{ID: track.ID, EventID: event.ID}); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete track", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePutTrackRoom schedules a room of the organization in the track,
+// moving it out of any track it was in.
+func (h apiHandler) handlePutTrackRoom(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+	track, ok := h.eventTrack(w, r, event)
+	if !ok {
+		return
+	}
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.q.SetRoomTrack(r.Context(), pg.SetRoomTrackParams{
+		TrackID:        uuid.NullUUID{UUID: track.ID, Valid: true},
+		ID:             roomID,
+		OrganizationID: uuid.NullUUID{UUID: event.OrganizationID, Valid: true},
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to set room track", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		http.Error(w, "room not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h apiHandler) handleDeleteTrackRoom(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+	track, ok := h.eventTrack(w, r, event)
+	if !ok {
+		return
+	}
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err != nil || !room.TrackID.Valid || room.TrackID.UUID != track.ID {
+		http.Error(w, "room not found in this track", http.StatusNotFound)
+		return
+	}
+
+	_, err = h.q.SetRoomTrack(r.Context(), pg.SetRoomTrackParams{
+		ID:             roomID,
+		OrganizationID: uuid.NullUUID{UUID: event.OrganizationID, Valid: true},
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to set room track", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetEventStats rolls the counts of every scheduled room up per track
+// and for the whole event. Viewers are those connected right now.
+func (h apiHandler) handleGetEventStats(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.organizationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	tracks, err := h.q.GetEventTracks(r.Context(), event.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get event tracks", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	rooms, err := h.q.GetEventRoomStats(r.Context(), event.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get event rooms", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type rollup struct {
+		Rooms     int   `json:"rooms"`
+		Messages  int64 `json:"messages"`
+		Answered  int64 `json:"answered"`
+		Reactions int64 `json:"reactions"`
+		Viewers   int   `json:"viewers"`
+	}
+	type trackStats struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		rollup
+	}
+	type response struct {
+		EventID string       `json:"event_id"`
+		Total   rollup       `json:"total"`
+		Tracks  []trackStats `json:"tracks"`
+	}
+
+	byTrack := map[uuid.UUID]*rollup{}
+	for _, track := range tracks {
+		byTrack[track.ID] = &rollup{}
+	}
+	res := response{EventID: event.ID.String(), Tracks: make([]trackStats, 0, len(tracks))}
+
+	//* Viewers are read in one pass so the lock is held once, not per room
+	h.mu.Lock()
+	for _, room := range rooms {
+		viewers := h.admittedLocked(room.ID.String())
+		for _, stats := range []*rollup{byTrack[room.TrackID.UUID], &res.Total} {
+			if stats == nil {
+				continue
+			}
+			stats.Rooms++
+			stats.Messages += room.MessageCount
+			stats.Answered += room.AnsweredCount
+			stats.Reactions += room.ReactionCount
+			stats.Viewers += viewers
+		}
+	}
+	h.mu.Unlock()
+
+	for _, track := range tracks {
+		res.Tracks = append(res.Tracks, trackStats{ID: track.ID.String(), Name: track.Name, rollup: *byTrack[track.ID]})
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/mappers"
+	"github.com/luiz504/week-tech-go-server/internal/schema"
+)
+
+// SchemaVersion is bumped on breaking changes to the published schemas.
+// Additive changes keep it, the ETag telling deployments apart.
+const SchemaVersion = 2
+
+// * Schemas that aren't websocket events
+const (
+	SchemaCommand       = "command"
+	SchemaRoom          = "rest.room"
+	SchemaRoomMessage   = "rest.room_message"
+	SchemaPublicMessage = "rest.public_message"
+	SchemaSavedView     = "rest.saved_view"
+)
+
+// eventValues maps every websocket event kind to the type of its value.
+var eventValues = map[string]any{
+	MessageKindMessageCreated:           MessageMessageCreated{},
+	MessageKindMessageAnswered:          MessageMessageAnswered{},
+	MessageKindMessageUnanswered:        MessageMessageAnswered{},
+	MessageKindMessageReactionIncreased: MessageMessageReactionUpdated{},
+	MessageKindMessageReactionDecreased: MessageMessageReactionUpdated{},
+	MessageKindMessageEdited:            MessageMessageEdited{},
+	MessageKindMessageTranslated:        MessageMessageTranslated{},
+	MessageKindMessageDeleted:           MessageMessageDeleted{},
+	MessageKindMessageReported:          MessageMessageReported{},
+	MessageKindMessageHeld:              MessageMessageHeld{},
+	MessageKindMessagesBulkUpdated:      MessageMessagesBulkUpdated{},
+	MessageKindRoomApplause:             MessageRoomApplause{},
+	MessageKindCaption:                  MessageCaption{},
+	MessageKindRoomArchived:             MessageRoomArchived{},
+	MessageKindRoomUnarchived:           MessageRoomArchived{},
+	MessageKindRoomClosed:               MessageRoomClosed{},
+	MessageKindRoomReopened:             MessageRoomClosed{},
+	MessageKindLeaderboardSnapshot:      MessageLeaderboardSnapshot{},
+	MessageKindLeaderboardDiff:          MessageLeaderboardDiff{},
+	MessageKindChannelSubscribed:        MessageChannelUpdated{},
+	MessageKindChannelUnsubscribed:      MessageChannelUpdated{},
+	MessageKindCommandRejected:          MessageCommandRejected{},
+	MessageKindHeartbeatAck:             MessageHeartbeatAck{},
+	MessageKindWaitingRoom:              MessageWaitingRoom{},
+	MessageKindWaitingRoomAdmitted:      MessageWaitingRoomAdmitted{},
+	MessageKindReconnectAdvised:         MessageReconnectAdvised{},
+	MessageKindResyncRequired:           MessageResyncRequired{},
+}
+
+var restValues = map[string]any{
+	SchemaCommand:       clientCommand{},
+	SchemaRoom:          mappers.Room{},
+	SchemaRoomMessage:   mappers.RoomMessage{},
+	SchemaPublicMessage: mappers.PublicMessage{},
+	SchemaSavedView:     SavedView{},
+}
+
+// EventTypes returns a zero value of each event kind's value type, for code
+// generators that need the Go types behind the schemas.
+func EventTypes() map[string]any {
+	return maps.Clone(eventValues)
+}
+
+// PayloadTypes is EventTypes for the schemas that aren't websocket events.
+func PayloadTypes() map[string]any {
+	return maps.Clone(restValues)
+}
+
+func schemaID(kind string) string {
+	return "/api/schemas/" + kind + "?version=" + strconv.Itoa(SchemaVersion)
+}
+
+// eventSchema describes the Message envelope an event kind arrives in.
+func eventSchema(kind string, value any) schema.Object {
+	return schema.Object{
+		"$schema": schema.Draft,
+		"$id":     schemaID(kind),
+		"title":   kind,
+		"type":    "object",
+		"properties": schema.Object{
+			"event_id": schema.Object{"type": "integer"},
+			"channel":  schema.Object{"type": "string"},
+			"kind":     schema.Object{"const": kind},
+			"value":    schema.For(value),
+		},
+		"required":             []string{"kind", "value"},
+		"additionalProperties": false,
+	}
+}
+
+func lookupSchema(kind string) (schema.Object, bool) {
+	if value, ok := eventValues[kind]; ok {
+		return eventSchema(kind, value), true
+	}
+	if value, ok := restValues[kind]; ok {
+		s := schema.For(value)
+		s["$schema"] = schema.Draft
+		s["$id"] = schemaID(kind)
+		s["title"] = kind
+		return s, true
+	}
+
+	return nil, false
+}
+
+func (h apiHandler) handleGetSchemas(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Version int      `json:"version"`
+		Events  []string `json:"events"`
+		Other   []string `json:"other"`
+	}
+
+	resp := response{Version: SchemaVersion}
+	for kind := range eventValues {
+		resp.Events = append(resp.Events, kind)
+	}
+	for kind := range restValues {
+		resp.Other = append(resp.Other, kind)
+	}
+	sort.Strings(resp.Events)
+	sort.Strings(resp.Other)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetSchema serves the schema of one kind. Only the deployed version
+// is served, asking for another one is a 404.
+func (h apiHandler) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	if raw := r.URL.Query().Get("version"); raw != "" && raw != strconv.Itoa(SchemaVersion) {
+		http.Error(w, "schema version not found", http.StatusNotFound)
+		return
+	}
+
+	s, ok := lookupSchema(chi.URLParam(r, "kind"))
+	if !ok {
+		http.Error(w, "schema not found", http.StatusNotFound)
+		return
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("ETag", etag)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
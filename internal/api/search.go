@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQuery     = 200
+)
+
+type searchRoomMessagesResult struct {
+	ID            string    `json:"id"`
+	Message       string    `json:"message"`
+	AuthorName    string    `json:"author_name"`
+	ReactionCount int64     `json:"reaction_count"`
+	Answered      bool      `json:"answered"`
+	CreatedAt     time.Time `json:"created_at"`
+	Rank          float32   `json:"rank"`
+}
+
+type searchRoomMessagesResponse struct {
+	RoomID   string                     `json:"room_id"`
+	Query    string                     `json:"query"`
+	Messages []searchRoomMessagesResult `json:"messages"`
+}
+
+// handleSearchRoomMessages finds the room's messages matching ?q, best
+// match first, so hosts of large rooms can tell whether a question was
+// already asked. The query takes web search syntax: quoted phrases, "or"
+// and words negated with a leading "-".
+func (h apiHandler) handleSearchRoomMessages(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > maxSearchQuery {
+		http.Error(w, "q must be between 1 and "+strconv.Itoa(maxSearchQuery)+" characters", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxSearchLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	rows, err := h.q.SearchRoomMessages(r.Context(), pg.SearchRoomMessagesParams{
+		RoomID:      room.ID,
+		Query:       query,
+		MaxMessages: int32(limit),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrSearchUnavailable) {
+			http.Error(w, "message search is unavailable on this server", http.StatusNotImplemented)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to search messages", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	messages := make([]searchRoomMessagesResult, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, searchRoomMessagesResult{
+			ID:            row.ID.String(),
+			Message:       row.Message,
+			AuthorName:    row.AuthorName,
+			ReactionCount: row.ReactionCount,
+			Answered:      row.Answered,
+			CreatedAt:     row.CreatedAt,
+			Rank:          row.Rank,
+		})
+	}
+
+	data, err := json.Marshal(searchRoomMessagesResponse{RoomID: room.ID.String(), Query: query, Messages: messages})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	// SecretStripeWebhook is read when WS_STRIPE_WEBHOOK_SECRET is unset.
+	SecretStripeWebhook = "stripe_webhook_secret"
+	// SecretTTSAPIKey is read when WS_TTS_API_KEY is unset.
+	SecretTTSAPIKey = "tts_api_key"
+	// SecretTranslateAPIKey is read when WS_TRANSLATE_API_KEY is unset.
+	SecretTranslateAPIKey = "translate_api_key"
+	// SecretToxicityAPIKey is read when WS_TOXICITY_API_KEY is unset.
+	SecretToxicityAPIKey = "toxicity_api_key"
+	// SecretYouTubeAPIKey is read when WS_YOUTUBE_API_KEY is unset.
+	SecretYouTubeAPIKey = "youtube_api_key"
+	// SecretMatrixAccessToken is read when WS_MATRIX_ACCESS_TOKEN is unset.
+	SecretMatrixAccessToken = "matrix_access_token"
+)
+
+const maxSecretNameLength = 255
+
+// keyringFromEnv loads the master keys, refusing to start on a malformed
+// WS_MASTER_KEYS rather than silently disabling the stored secrets.
+func keyringFromEnv() *crypto.Keyring {
+	keyring, err := crypto.KeyringFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if keyring == nil {
+		slog.Warn("WS_MASTER_KEYS is not set, stored secrets are disabled")
+	}
+
+	return keyring
+}
+
+// secretAAD binds an envelope to its row, so ciphertexts can't be swapped
+// between secrets or organizations.
+func secretAAD(organizationID uuid.NullUUID, name string) []byte {
+	org := ""
+	if organizationID.Valid {
+		org = organizationID.UUID.String()
+	}
+
+	return []byte("secret:" + org + ":" + name)
+}
+
+// secret decrypts a stored secret. A missing one returns store.ErrNotFound.
+func (h apiHandler) secret(ctx context.Context, organizationID uuid.NullUUID, name string) (string, error) {
+	if h.keyring == nil {
+		return "", crypto.ErrNoKeyring
+	}
+
+	s, err := h.q.GetSecret(ctx, pg.GetSecretParams{OrganizationID: organizationID, Name: name})
+	if err != nil {
+		return "", err
+	}
+
+	value, err := h.keyring.Open(crypto.Envelope{
+		KeyID:      s.KeyID,
+		WrappedKey: s.WrappedKey,
+		Ciphertext: s.Ciphertext,
+	}, secretAAD(s.OrganizationID, s.Name))
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+// issueSecret generates and stores the secret name unless it exists,
+// returning it when it was just issued and empty otherwise: secrets are only
+// ever shown once.
+func (h apiHandler) issueSecret(ctx context.Context, scope uuid.NullUUID, name string) (string, error) {
+	_, err := h.secret(ctx, scope, name)
+	if err == nil || !errors.Is(err, store.ErrNotFound) {
+		return "", err
+	}
+
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	envelope, err := h.keyring.Seal([]byte(secret), secretAAD(scope, name))
+	if err != nil {
+		return "", fmt.Errorf("encrypt secret: %w", err)
+	}
+	err = h.q.UpsertSecret(ctx, pg.UpsertSecretParams{
+		OrganizationID: scope,
+		Name:           name,
+		KeyID:          envelope.KeyID,
+		WrappedKey:     envelope.WrappedKey,
+		Ciphertext:     envelope.Ciphertext,
+	})
+	if err != nil {
+		return "", fmt.Errorf("store secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// parseSecretOrganization reads the optional organization_id query parameter
+// scoping a secret, global secrets having none.
+func parseSecretOrganization(r *http.Request) (uuid.NullUUID, error) {
+	raw := r.URL.Query().Get("organization_id")
+	if raw == "" {
+		return uuid.NullUUID{}, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+func (h apiHandler) handlePutSecret(w http.ResponseWriter, r *http.Request) {
+	if h.keyring == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	name := strings.TrimSpace(chi.URLParam(r, "name"))
+	if name == "" || len(name) > maxSecretNameLength {
+		http.Error(w, "invalid secret name", http.StatusBadRequest)
+		return
+	}
+	organizationID, err := parseSecretOrganization(r)
+	if err != nil {
+		http.Error(w, "invalid organization id", http.StatusBadRequest)
+		return
+	}
+
+	type _body struct {
+		Value string `json:"value"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if body.Value == "" {
+		http.Error(w, "value is required", http.StatusBadRequest)
+		return
+	}
+
+	envelope, err := h.keyring.Seal([]byte(body.Value), secretAAD(organizationID, name))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to encrypt secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.q.UpsertSecret(r.Context(), pg.UpsertSecretParams{
+		OrganizationID: organizationID,
+		Name:           name,
+		KeyID:          envelope.KeyID,
+		WrappedKey:     envelope.WrappedKey,
+		Ciphertext:     envelope.Ciphertext,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrForeignKey) {
+			http.Error(w, "organization not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to upsert secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetSecrets lists the stored secrets without their values.
+func (h apiHandler) handleGetSecrets(w http.ResponseWriter, r *http.Request) {
+	secrets, err := h.q.GetSecrets(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get secrets", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type secret struct {
+		Name           string    `json:"name"`
+		OrganizationID *string   `json:"organization_id"`
+		KeyID          string    `json:"key_id"`
+		UpdatedAt      time.Time `json:"updated_at"`
+	}
+
+	response := make([]secret, len(secrets))
+	for i, s := range secrets {
+		response[i] = secret{Name: s.Name, KeyID: s.KeyID, UpdatedAt: s.UpdatedAt}
+		if s.OrganizationID.Valid {
+			id := s.OrganizationID.UUID.String()
+			response[i].OrganizationID = &id
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteSecret(w http.ResponseWriter, r *http.Request) {
+	organizationID, err := parseSecretOrganization(r)
+	if err != nil {
+		http.Error(w, "invalid organization id", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.q.DeleteSecret(r.Context(), pg.DeleteSecretParams{
+		OrganizationID: organizationID,
+		Name:           chi.URLParam(r, "name"),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "secret not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRotateSecrets rewraps every data key still sealed under a retired
+// master key with the active one. Once it reports nothing left to rotate the
+// retired key can be dropped from WS_MASTER_KEYS.
+func (h apiHandler) handleRotateSecrets(w http.ResponseWriter, r *http.Request) {
+	if h.keyring == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	secrets, err := h.q.GetSecrets(r.Context())
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get secrets", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	rotated, failed := 0, 0
+	for _, s := range secrets {
+		envelope, changed, err := h.keyring.Rewrap(crypto.Envelope{
+			KeyID:      s.KeyID,
+			WrappedKey: s.WrappedKey,
+			Ciphertext: s.Ciphertext,
+		}, secretAAD(s.OrganizationID, s.Name))
+		if err != nil {
+			//? Keep going, a key missing from the keyring only blocks its own secrets
+			slog.Error("failed to rewrap secret", "secret_id", s.ID, "key_id", s.KeyID, "error", err)
+			failed++
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		//* Conditional on the previous key, so a concurrent write isn't overwritten
+		_, err = h.q.RewrapSecret(r.Context(), pg.RewrapSecretParams{
+			KeyID:         envelope.KeyID,
+			WrappedKey:    envelope.WrappedKey,
+			ID:            s.ID,
+			PreviousKeyID: s.KeyID,
+		})
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to rewrap secret", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		rotated++
+	}
+
+	type response struct {
+		KeyID   string `json:"key_id"`
+		Rotated int    `json:"rotated"`
+		Failed  int    `json:"failed"`
+	}
+
+	data, err := json.Marshal(response{KeyID: h.keyring.ActiveKeyID(), Rotated: rotated, Failed: failed})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// stripeWebhookSecret prefers WS_STRIPE_WEBHOOK_SECRET and falls back to the
+// stored secret, returning "" when neither is configured.
+func (h apiHandler) stripeWebhookSecret(ctx context.Context) (string, error) {
+	if h.stripe.webhookSecret != "" {
+		return h.stripe.webhookSecret, nil
+	}
+
+	secret, err := h.secret(ctx, uuid.NullUUID{}, SecretStripeWebhook)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return secret, err
+}
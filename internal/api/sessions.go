@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/clientip"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/privacy"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const sessionCookieMaxAge = 365 * 24 * time.Hour
+
+func (h apiHandler) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	//* Sticky: a caller that already holds a valid session gets it back
+	if id, ok := session.FromContext(r.Context()); ok {
+		s, err := h.q.GetSession(r.Context(), id)
+		if err == nil {
+			h.writeSession(w, r, s, http.StatusOK)
+			return
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			helpers.LogErrorAndRespond(w, "failed to get session", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	s, err := h.q.InsertSession(r.Context(), pg.InsertSessionParams{UserAgent: userAgent, ClientIp: clientIP})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert session", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeSession(w, r, s, http.StatusCreated)
+}
+
+func (h apiHandler) writeSession(w http.ResponseWriter, r *http.Request, s pg.Session, code int) {
+	token := h.sessions.Sign(s.ID)
+
+	type response struct {
+		ID        string    `json:"id"`
+		Token     string    `json:"token"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	data, err := json.Marshal(response{ID: s.ID.String(), Token: token, CreatedAt: s.CreatedAt})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionCookieMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// privacyFromEnv refuses to start on an unknown WS_PRIVACY_MODE rather than
+// silently logging full addresses.
+func privacyFromEnv() *privacy.Anonymizer {
+	anonymizer, err := privacy.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	return anonymizer
+}
+
+// clientIPFromEnv refuses to start on a malformed WS_TRUSTED_PROXIES.
+func clientIPFromEnv() *clientip.Resolver {
+	resolver, err := clientip.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	return resolver
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/luiz504/week-tech-go-server/internal/logging"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+// sseKeepAlive is how often an idle event stream gets a comment, so proxies
+// don't take it for a dead connection.
+const sseKeepAlive = 15 * time.Second
+
+// sseConn streams messages as server-sent events, for clients behind proxies
+// that block WebSocket upgrades. Each message is one event, its data the same
+// JSON a socket receives and its id the room event id when sequenced.
+type sseConn struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (c *sseConn) send(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if msg.EventID > 0 {
+		_, err = fmt.Fprintf(c.w, "id: %d\ndata: %s\n\n", msg.EventID, data)
+	} else {
+		_, err = fmt.Fprintf(c.w, "data: %s\n\n", data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.rc.Flush()
+}
+
+// close has nothing to send, the stream ends when the handler returns.
+func (c *sseConn) close(int, string) error {
+	return nil
+}
+
+func (c *sseConn) keepAlive() error {
+	if _, err := fmt.Fprint(c.w, ": keepalive\n\n"); err != nil {
+		return err
+	}
+
+	return c.rc.Flush()
+}
+
+// handleSubscribeToRoomSSE streams the room's events like handleSubscribeToRoom
+// does over a WebSocket. The stream only goes one way, so the channels are
+// picked upfront with ?channels, host ones needing the owner token.
+func (h apiHandler) handleSubscribeToRoomSSE(w http.ResponseWriter, r *http.Request) {
+	roomId, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	requestID := middleware.GetReqID(r.Context())
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := newRoomSubscriber(r, room, requestID, cancel)
+
+	if raw := r.URL.Query().Get("channels"); raw != "" {
+		sub.channels = map[string]bool{}
+		for _, channel := range strings.Split(raw, ",") {
+			channel = strings.TrimSpace(channel)
+			if !roomChannels[channel] {
+				http.Error(w, "unknown channel "+channel, http.StatusBadRequest)
+				return
+			}
+			if hostChannels[channel] && !sub.host {
+				http.Error(w, "only the room host can subscribe to "+channel, http.StatusForbidden)
+				return
+			}
+			sub.channels[channel] = true
+		}
+	}
+
+	after, replay, err := replayAfter(r)
+	if err != nil {
+		http.Error(w, "invalid last_event_id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	//* Keeps reverse proxies like nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set(middleware.RequestIDHeader, requestID)
+	c := &sseConn{w: w, rc: http.NewResponseController(w)}
+	if err := c.rc.Flush(); err != nil {
+		return
+	}
+
+	written := h.startWriter(ctx, c, sub)
+
+	h.mu.Lock()
+	h.joinLocked(roomId.String(), c, sub)
+	//? A waiting subscriber gets no events until admitted, nor the missed ones
+	replay = replay && !sub.waiting
+	sub.replaying = replay
+	h.mu.Unlock()
+	if replay {
+		h.replay(ctx, sub, roomId, after)
+	}
+	if sub.channels[ChannelLeaderboard] {
+		h.sendLeaderboardSnapshot(roomId.String(), sub)
+	}
+
+	sub.log.Info(logging.MsgSubscriberConnected, "client_ip", r.RemoteAddr, "transport", "sse")
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+			h.mu.Lock()
+			h.queueLocked(sub, &outboundFrame{keepAlive: true})
+			h.mu.Unlock()
+		}
+	}
+
+	//? Unregistered before returning, the response can't be written once the handler is done
+	h.mu.Lock()
+	h.leaveLocked(roomId.String(), c)
+	h.accountConnectionLocked(sub, time.Now())
+	h.mu.Unlock()
+	//? A deadline in the past fails a write stuck on a client that stopped reading
+	cancel()
+	_ = c.rc.SetWriteDeadline(time.Now())
+	<-written
+	sub.log.Info(logging.MsgSubscriberDisconnected, "client_ip", r.RemoteAddr, "transport", "sse")
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const MessageKindMessageHeld = "message_held"
+
+const (
+	defaultModerationQueueLimit = 50
+	maxModerationQueueLimit     = 200
+)
+
+const (
+	ReviewActionApprove = "approve"
+	ReviewActionReject  = "reject"
+)
+
+// MessageMessageHeld tells the host a message scored above the toxicity
+// threshold and waits for review, the audience getting no message_created.
+type MessageMessageHeld struct {
+	ID         string  `json:"id"`
+	RoomID     string  `json:"room_id"`
+	Message    string  `json:"message"`
+	AuthorName string  `json:"author_name,omitempty"`
+	Toxicity   float64 `json:"toxicity"`
+}
+
+// toxicityAPIKey loads the provider key from the secret store, for when it
+// isn't set in the environment. No stored key means requests go unauthenticated.
+func (h apiHandler) toxicityAPIKey(ctx context.Context) (string, error) {
+	key, err := h.secret(ctx, uuid.NullUUID{}, SecretToxicityAPIKey)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return key, err
+}
+
+// handleGetModerationQueue lists the messages waiting on the host: those held
+// for toxicity and those reported since they were last reviewed, the most
+// toxic first.
+type moderationQueueItem struct {
+	ID          string     `json:"id"`
+	Message     string     `json:"message"`
+	AuthorName  string     `json:"author_name,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Toxicity    *float64   `json:"toxicity"`
+	HeldAt      *time.Time `json:"held_at,omitempty"`
+	ReportCount int64      `json:"report_count"`
+}
+
+type moderationQueueResponse struct {
+	RoomID   string                `json:"room_id"`
+	Messages []moderationQueueItem `json:"messages"`
+}
+
+func (h apiHandler) handleGetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	limit := defaultModerationQueueLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxModerationQueueLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxModerationQueueLimit), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	rows, err := h.q.GetRoomModerationQueue(r.Context(), pg.GetRoomModerationQueueParams{RoomID: room.ID, Limit: int32(limit)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get moderation queue", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]moderationQueueItem, 0, len(rows))
+	for _, row := range rows {
+		it := moderationQueueItem{
+			ID:          row.ID.String(),
+			Message:     row.Message,
+			AuthorName:  row.AuthorName,
+			CreatedAt:   row.CreatedAt,
+			ReportCount: row.ReportCount,
+		}
+		if row.Toxicity.Valid {
+			score := float64(row.Toxicity.Float32)
+			it.Toxicity = &score
+		}
+		if row.HiddenAt.Valid {
+			it.HeldAt = &row.HiddenAt.Time
+		}
+		items = append(items, it)
+	}
+
+	data, err := json.Marshal(moderationQueueResponse{RoomID: room.ID.String(), Messages: items})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleReviewMessage settles a queued message. Approving releases a held
+// message to the audience and clears its reports, rejecting deletes it.
+type reviewMessageRequest struct {
+	Action string `json:"action"`
+}
+
+func (h apiHandler) handleReviewMessage(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+	messageID, err := utils.ParseUUIDParam(r, "message_id")
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	var body reviewMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if body.Action != ReviewActionApprove && body.Action != ReviewActionReject {
+		http.Error(w, "action must be approve or reject", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: room.ID, ID: messageID})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if body.Action == ReviewActionReject {
+		if err := h.q.SoftDeleteMessage(r.Context(), messageID); err != nil {
+			helpers.LogErrorAndRespond(w, "failed to delete message", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		//* A held message never reached the audience, there's nothing to take back
+		if !message.HiddenAt.Valid {
+			h.publish(Message{
+				Kind:   MessageKindMessageDeleted,
+				RoomID: room.ID.String(),
+				Value: MessageMessageDeleted{
+					ID:     messageID.String(),
+					RoomID: room.ID.String(),
+				},
+			})
+		}
+		return
+	}
+
+	if err := h.q.ApproveMessage(r.Context(), messageID); err != nil {
+		helpers.LogErrorAndRespond(w, "failed to approve message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	if message.HiddenAt.Valid {
+		fields := map[string]string{}
+		_ = json.Unmarshal(message.Fields, &fields)
+		h.publish(Message{
+			Kind:   MessageKindMessageCreated,
+			RoomID: room.ID.String(),
+			Value: MessageMessageCreated{
+				ID:         messageID.String(),
+				Message:    message.Message,
+				Fields:     fields,
+				AuthorName: message.AuthorName,
+			}})
+		h.queueTranslations(room, messageID, message.Message)
+	}
+}
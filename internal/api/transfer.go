@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const roomTransferTTL = 24 * time.Hour
+
+// handleCreateRoomTransfer lets the host hand the room over. It returns a
+// confirmation token the new owner redeems at /transfer/accept, optionally
+// bound to the recipient's session.
+func (h apiHandler) handleCreateRoomTransfer(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	type _body struct {
+		SessionID *uuid.UUID `json:"session_id"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	var toSession uuid.NullUUID
+	if body.SessionID != nil {
+		if _, err := h.q.GetSession(r.Context(), *body.SessionID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, "session not found", http.StatusUnprocessableEntity)
+				return
+			}
+			helpers.LogErrorAndRespond(w, "failed to get session", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		toSession = uuid.NullUUID{UUID: *body.SessionID, Valid: true}
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate transfer token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	transfer, err := h.q.InsertRoomTransfer(r.Context(), pg.InsertRoomTransferParams{
+		RoomID:             room.ID,
+		TokenHash:          utils.HashToken(token),
+		FromOwnerTokenHash: room.OwnerTokenHash,
+		ToSessionID:        toSession,
+		ExpiresAt:          time.Now().Add(roomTransferTTL),
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to insert room transfer", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("room ownership transfer created",
+		"room_id", room.ID.String(),
+		"transfer_id", transfer.ID.String(),
+		"to_session_id", body.SessionID,
+		"request_id", middleware.GetReqID(r.Context()),
+	)
+
+	type response struct {
+		ID                string    `json:"id"`
+		ConfirmationToken string    `json:"confirmation_token"`
+		ExpiresAt         time.Time `json:"expires_at"`
+	}
+
+	data, err := json.Marshal(response{ID: transfer.ID.String(), ConfirmationToken: token, ExpiresAt: transfer.ExpiresAt})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAcceptRoomTransfer redeems a confirmation token for a fresh owner
+// token. The previous owner token stops working at the same moment.
+func (h apiHandler) handleAcceptRoomTransfer(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	type _body struct {
+		ConfirmationToken string `json:"confirmation_token"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.q.GetPendingRoomTransfer(r.Context(), pg.GetPendingRoomTransferParams{
+		RoomID:    roomID,
+		TokenHash: utils.HashToken(body.ConfirmationToken),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "transfer not found or expired", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if transfer.ToSessionID.Valid {
+		sessionID, ok := session.FromContext(r.Context())
+		if !ok || sessionID != transfer.ToSessionID.UUID {
+			http.Error(w, "this transfer is addressed to another session", http.StatusForbidden)
+			return
+		}
+	}
+
+	ownerToken, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate owner token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	ownerTokenHash := utils.HashToken(ownerToken)
+
+	accepted, err := h.q.AcceptRoomTransfer(r.Context(), pg.AcceptRoomTransferParams{
+		ID:             transfer.ID,
+		OwnerTokenHash: ownerTokenHash,
+	})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to accept room transfer", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	//? Nothing changed: accepted concurrently, expired, or the owner token moved on since
+	if accepted == 0 {
+		http.Error(w, "transfer is no longer valid", http.StatusConflict)
+		return
+	}
+
+	slog.Info("room ownership transfer accepted",
+		"room_id", roomID.String(),
+		"transfer_id", transfer.ID.String(),
+		"request_id", middleware.GetReqID(r.Context()),
+	)
+
+	h.mu.Lock()
+	h.revokeHostsLocked(roomID.String(), ownerTokenHash)
+	h.mu.Unlock()
+
+	type response struct {
+		OwnerToken string `json:"owner_token"`
+	}
+
+	data, err := json.Marshal(response{OwnerToken: ownerToken})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// revokeHostsLocked drops host rights from live connections authenticated
+// with the previous owner token. Must be called with h.mu held.
+func (h apiHandler) revokeHostsLocked(roomID string, ownerTokenHash string) {
+	for _, sub := range h.subscribers[roomID] {
+		sub.ownerTokenHash = ownerTokenHash
+		if !sub.host {
+			continue
+		}
+		sub.host = false
+
+		for channel := range hostChannels {
+			if !sub.channels[channel] {
+				continue
+			}
+			delete(sub.channels, channel)
+			h.writeLocked(sub, Message{
+				Kind:  MessageKindChannelUnsubscribed,
+				Value: MessageChannelUpdated{Channel: channel},
+			})
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/translate"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	MessageKindMessageTranslated = "message_translated"
+)
+
+type MessageMessageTranslated struct {
+	ID       string `json:"id"`
+	RoomID   string `json:"room_id"`
+	Language string `json:"language"`
+	Message  string `json:"message"`
+}
+
+// translateAPIKey loads the provider key from the secret store, for when it
+// isn't set in the environment.
+func (h apiHandler) translateAPIKey(ctx context.Context) (string, error) {
+	key, err := h.secret(ctx, uuid.NullUUID{}, SecretTranslateAPIKey)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return key, err
+}
+
+// translation returns the message in the target language, from the cache
+// when it was translated since it last changed.
+func (h apiHandler) translation(ctx context.Context, messageID uuid.UUID, text, target string) (string, error) {
+	sum := sha256.Sum256([]byte(text))
+	sourceHash := hex.EncodeToString(sum[:])
+
+	cached, err := h.q.GetMessageTranslation(ctx, pg.GetMessageTranslationParams{MessageID: messageID, Language: target})
+	if err == nil && cached.SourceHash == sourceHash {
+		return cached.Text, nil
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return "", err
+	}
+
+	translated, err := h.translator.Translate(ctx, text, target)
+	if err != nil {
+		return "", err
+	}
+
+	err = h.q.UpsertMessageTranslation(ctx, pg.UpsertMessageTranslationParams{
+		MessageID:  messageID,
+		Language:   target,
+		SourceHash: sourceHash,
+		Text:       translated,
+	})
+	if err != nil {
+		slog.Error("failed to cache translation", "message_id", messageID, "language", target, "error", err)
+	}
+
+	return translated, nil
+}
+
+// queueTranslations broadcasts the message in every language the room is
+// set to translate into, when it is.
+func (h apiHandler) queueTranslations(room pg.Room, messageID uuid.UUID, text string) {
+	if h.translator == nil || len(room.TranslateTo) == 0 {
+		return
+	}
+
+	h.translations.Enqueue(translate.Job{RoomID: room.ID, MessageID: messageID, Text: text, Targets: room.TranslateTo})
+}
+
+func (h apiHandler) broadcastTranslations(ctx context.Context, job translate.Job) {
+	for _, target := range job.Targets {
+		text, err := h.translation(ctx, job.MessageID, job.Text, target)
+		if err != nil {
+			slog.Error("failed to translate message", "message_id", job.MessageID, "language", target, "error", err)
+			continue
+		}
+
+		h.publish(Message{
+			Kind:   MessageKindMessageTranslated,
+			RoomID: job.RoomID.String(),
+			Value: MessageMessageTranslated{
+				ID:       job.MessageID.String(),
+				RoomID:   job.RoomID.String(),
+				Language: target,
+				Message:  text,
+			},
+		})
+	}
+}
+
+// parseTranslateTargets validates the languages a room broadcasts, returning
+// them canonical and without duplicates.
+func parseTranslateTargets(raw []string) ([]string, bool) {
+	targets := []string{}
+	seen := map[string]bool{}
+	for _, tag := range raw {
+		target, err := translate.ParseLanguage(tag)
+		if err != nil {
+			return nil, false
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, len(targets) <= translate.MaxTargets
+}
+
+type translateMessageResponse struct {
+	ID       string `json:"id"`
+	Language string `json:"language"`
+	Message  string `json:"message"`
+}
+
+func (h apiHandler) handleTranslateMessage(w http.ResponseWriter, r *http.Request) {
+	if h.translator == nil {
+		http.Error(w, "translation is not enabled", http.StatusNotFound)
+		return
+	}
+
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+	messageID, err := utils.ParseUUIDParam(r, "message_id")
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+	target, err := translate.ParseLanguage(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "invalid target language", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.q.GetRoomMessage(r.Context(), pg.GetRoomMessageParams{RoomID: roomID, ID: messageID})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get message", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	text := message.Message
+	if !translate.SameLanguage(message.Language, target) {
+		text, err = h.translation(r.Context(), message.ID, message.Message, target)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to translate message", err, "translation failed", http.StatusBadGateway)
+			return
+		}
+	}
+
+	data, err := json.Marshal(translateMessageResponse{ID: message.ID.String(), Language: target, Message: text})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/tts"
+)
+
+// ttsAPIKey loads the provider key from the secret store, for when it isn't
+// set in the environment. No stored key means requests go unauthenticated.
+func (h apiHandler) ttsAPIKey(ctx context.Context) (string, error) {
+	key, err := h.secret(ctx, uuid.NullUUID{}, SecretTTSAPIKey)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, crypto.ErrNoKeyring) {
+		return "", nil
+	}
+
+	return key, err
+}
+
+// publishAnswerAudio stores the audio of an answer and announces it with a
+// second message_answered. Answers edited or reverted while synthesizing are
+// left alone, their audio no longer matching.
+func (h apiHandler) publishAnswerAudio(ctx context.Context, job tts.Job, audioURL string) {
+	updated, err := h.q.SetMessageAnswerAudio(ctx, pg.SetMessageAnswerAudioParams{
+		AnswerAudioUrl: audioURL,
+		ID:             job.MessageID,
+		RoomID:         job.RoomID,
+		AnswerText:     job.Text,
+	})
+	if err != nil {
+		slog.Error("failed to store answer audio", "message_id", job.MessageID, "error", err)
+		return
+	}
+	if updated == 0 {
+		return
+	}
+
+	h.publish(Message{
+		RoomID: job.RoomID.String(),
+		Kind:   MessageKindMessageAnswered,
+		Value: MessageMessageAnswered{
+			ID:         job.MessageID.String(),
+			RoomID:     job.RoomID.String(),
+			AnswerText: job.Text,
+			AudioURL:   audioURL,
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Edits and answers are made against the version of the message the client
+// last saw, sent as If-Match with the ETag of the message or as the version
+// field of the body. One made against an older version is refused with 409,
+// so co-hosts don't silently overwrite each other.
+
+func messageETag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+// expectedVersion reads the version a change is made against, If-Match
+// taking precedence over the body. It answers 428 when there's neither,
+// reporting whether the handler goes on.
+func expectedVersion(w http.ResponseWriter, r *http.Request, body *int32) (int32, bool) {
+	if raw := r.Header.Get("If-Match"); raw != "" {
+		v, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(raw, "W/"), `"`), 10, 32)
+		if err != nil || v < 1 {
+			http.Error(w, "If-Match must be the ETag of the message", http.StatusBadRequest)
+			return 0, false
+		}
+		return int32(v), true
+	}
+	if body != nil {
+		return *body, true
+	}
+
+	http.Error(w, "If-Match or version is required", http.StatusPreconditionRequired)
+	return 0, false
+}
+
+func respondVersionConflict(w http.ResponseWriter) {
+	http.Error(w, "the message was changed meanwhile, get it again and retry", http.StatusConflict)
+}
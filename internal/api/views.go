@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const maxSavedViewNameLength = 100
+
+// Orders of ?sort on message listings.
+const (
+	MessageSortRecent    = "recent"
+	MessageSortReactions = "reactions"
+)
+
+// MessageFilter narrows a message listing. Zero fields don't filter.
+type MessageFilter struct {
+	Answered     *bool  `json:"answered,omitempty"`
+	Pinned       *bool  `json:"pinned,omitempty"`
+	MinReactions int64  `json:"min_reactions,omitempty"`
+	Tag          string `json:"tag,omitempty"`
+	// Language is an ISO 639-1 code, as detected when the message was posted.
+	Language string `json:"lang,omitempty"`
+}
+
+func (f MessageFilter) Match(message pg.Message) bool {
+	if f.Answered != nil && message.Answered != *f.Answered {
+		return false
+	}
+	if f.Pinned != nil && message.Pinned != *f.Pinned {
+		return false
+	}
+	if message.ReactionCount < f.MinReactions {
+		return false
+	}
+
+	if f.Language != "" && message.Language != f.Language {
+		return false
+	}
+
+	return f.Tag == "" || slices.Contains(message.Tags, f.Tag)
+}
+
+func (f MessageFilter) Apply(messages []pg.Message) []pg.Message {
+	if f == (MessageFilter{}) {
+		return messages
+	}
+
+	return slices.DeleteFunc(messages, func(message pg.Message) bool { return !f.Match(message) })
+}
+
+// parseMessageFilter reads ?answered, ?pinned, ?min_reactions, ?tag and ?lang.
+func parseMessageFilter(query url.Values) (MessageFilter, bool) {
+	var f MessageFilter
+	parseBool := func(key string) (*bool, bool) {
+		raw := query.Get(key)
+		if raw == "" {
+			return nil, true
+		}
+		v, err := strconv.ParseBool(raw)
+		return &v, err == nil
+	}
+
+	var ok bool
+	if f.Answered, ok = parseBool("answered"); !ok {
+		return MessageFilter{}, false
+	}
+	if f.Pinned, ok = parseBool("pinned"); !ok {
+		return MessageFilter{}, false
+	}
+	if raw := query.Get("min_reactions"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			return MessageFilter{}, false
+		}
+		f.MinReactions = v
+	}
+	f.Tag = query.Get("tag")
+	f.Language = strings.ToLower(query.Get("lang"))
+
+	return f, true
+}
+
+// SavedView is a named message filter a host keeps for their room.
+type SavedView struct {
+	Name      string        `json:"name"`
+	Filter    MessageFilter `json:"filter"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func mapSavedView(view pg.SavedView) SavedView {
+	var filter MessageFilter
+	_ = json.Unmarshal(view.Filter, &filter)
+
+	return SavedView{Name: view.Name, Filter: filter, UpdatedAt: view.UpdatedAt}
+}
+
+func mapSavedViews(views []pg.SavedView) []SavedView {
+	saved := make([]SavedView, 0, len(views))
+	for _, view := range views {
+		saved = append(saved, mapSavedView(view))
+	}
+
+	return saved
+}
+
+// savedViewFilter resolves ?view on a message listing. Views belong to the
+// host, so applying one takes the owner token like managing them does.
+func (h apiHandler) savedViewFilter(w http.ResponseWriter, r *http.Request, roomID uuid.UUID) (MessageFilter, bool) {
+	name := r.URL.Query().Get("view")
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return MessageFilter{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return MessageFilter{}, false
+	}
+	if !isRoomHost(r, room) {
+		http.Error(w, "only the room host can use saved views", http.StatusForbidden)
+		return MessageFilter{}, false
+	}
+
+	view, err := h.q.GetSavedView(r.Context(), pg.GetSavedViewParams{RoomID: roomID, Name: name})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "saved view not found", http.StatusNotFound)
+			return MessageFilter{}, false
+		}
+		helpers.LogErrorAndRespond(w, "failed to get saved view", err, "something went wrong", http.StatusInternalServerError)
+		return MessageFilter{}, false
+	}
+
+	return mapSavedView(view).Filter, true
+}
+
+func (h apiHandler) handleGetSavedViews(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	views, err := h.q.GetSavedViews(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to get saved views", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Views []SavedView `json:"views"`
+	}
+
+	data, err := json.Marshal(response{Views: mapSavedViews(views)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handlePutSavedView(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(chi.URLParam(r, "name"))
+	if name == "" || len(name) > maxSavedViewNameLength {
+		http.Error(w, "invalid view name", http.StatusBadRequest)
+		return
+	}
+
+	room := hostedRoom(r)
+
+	type _body struct {
+		Filter MessageFilter `json:"filter"`
+	}
+	var body _body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if body.Filter.MinReactions < 0 {
+		http.Error(w, "min_reactions must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := json.Marshal(body.Filter)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal filter", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := h.q.UpsertSavedView(r.Context(), pg.UpsertSavedViewParams{RoomID: room.ID, Name: name, Filter: filter})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save view", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		View SavedView `json:"view"`
+	}
+
+	data, err := json.Marshal(response{View: mapSavedView(view)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteSavedView(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteSavedView(r.Context(), pg.DeleteSavedViewParams{RoomID: room.ID, Name: chi.URLParam(r, "name")})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete saved view", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "saved view not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
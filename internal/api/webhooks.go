@@ -0,0 +1,446 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/metrics"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	// HeaderIdempotencyKey names the delivery, the same on every attempt, so
+	// receivers can drop the ones they've already handled.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	defaultWebhookPollInterval = 2 * time.Second
+	defaultWebhookTimeout      = 10 * time.Second
+	defaultWebhookMaxAttempts  = 8
+
+	// webhookSettleWindow is how old events must be to be queued. Instances
+	// number events as they publish, so a later event can commit before an
+	// earlier one; waiting lets the earlier one land before the cursor passes.
+	webhookSettleWindow = 5 * time.Second
+	webhookRetryBase    = 10 * time.Second
+	webhookRetryMax     = time.Hour
+	// maxWebhookErrorLength is how much of a failed response body is kept.
+	maxWebhookErrorLength = 512
+)
+
+// webhookSecretName is the stored secret a room's outgoing webhook
+// deliveries are signed with.
+func webhookSecretName(roomID uuid.UUID) string {
+	return "room_webhook:" + roomID.String()
+}
+
+// webhookDeliveryKey names a delivery, as its idempotency key and as the
+// ref of its dead letter.
+func webhookDeliveryKey(webhookID uuid.UUID, eventID int64) string {
+	return webhookID.String() + ":" + strconv.FormatInt(eventID, 10)
+}
+
+func parseWebhookDeliveryKey(key string) (uuid.UUID, int64, error) {
+	rawWebhookID, rawEventID, ok := strings.Cut(key, ":")
+	if !ok {
+		return uuid.UUID{}, 0, fmt.Errorf("invalid webhook delivery key %q", key)
+	}
+	webhookID, err := uuid.Parse(rawWebhookID)
+	if err != nil {
+		return uuid.UUID{}, 0, fmt.Errorf("invalid webhook delivery key %q: %w", key, err)
+	}
+	eventID, err := strconv.ParseInt(rawEventID, 10, 64)
+	if err != nil {
+		return uuid.UUID{}, 0, fmt.Errorf("invalid webhook delivery key %q: %w", key, err)
+	}
+
+	return webhookID, eventID, nil
+}
+
+type webhookConfig struct {
+	pollInterval time.Duration
+	maxAttempts  int
+	client       *http.Client
+}
+
+// webhookConfigFromEnv reads WS_WEBHOOK_POLL_INTERVAL (2s by default),
+// WS_WEBHOOK_TIMEOUT (10s), WS_WEBHOOK_MAX_ATTEMPTS (8) and
+// WS_WEBHOOK_ALLOW_PRIVATE, which lets hosts point webhooks at loopback and
+// private addresses, off by default.
+func webhookConfigFromEnv() webhookConfig {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WS_WEBHOOK_ALLOW_PRIVATE"))
+	dialer := &net.Dialer{Timeout: defaultWebhookTimeout}
+	if !allowPrivate {
+		dialer.Control = rejectPrivateAddress
+	}
+
+	return webhookConfig{
+		pollInterval: positiveDurationFromEnv("WS_WEBHOOK_POLL_INTERVAL", defaultWebhookPollInterval),
+		maxAttempts:  positiveIntFromEnv("WS_WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+		client: &http.Client{
+			Timeout:   positiveDurationFromEnv("WS_WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			//? Redirects would get around the address check and the signature is for the room's url only
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// rejectPrivateAddress refuses connections to the server's own networks, as
+// the address is checked once resolved.
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+
+	return nil
+}
+
+// RoomWebhook is where a room's events are posted as they're published.
+type RoomWebhook struct {
+	RoomID    string    `json:"room_id"`
+	URL       string    `json:"url"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Secret is only returned when it's issued, with the first url.
+	Secret string `json:"secret,omitempty"`
+}
+
+func mapRoomWebhook(hook pg.RoomWebhook) RoomWebhook {
+	return RoomWebhook{RoomID: hook.RoomID.String(), URL: hook.Url, UpdatedAt: hook.UpdatedAt}
+}
+
+// WebhookDelivery is the body posted for each event. IdempotencyKey is also
+// sent as the Idempotency-Key header and stays the same across attempts.
+type WebhookDelivery struct {
+	IdempotencyKey string          `json:"idempotency_key"`
+	RoomID         string          `json:"room_id"`
+	EventID        int64           `json:"event_id"`
+	Channel        string          `json:"channel"`
+	Kind           string          `json:"kind"`
+	Value          json.RawMessage `json:"value"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Attempt        int32           `json:"attempt"`
+}
+
+type putRoomWebhookRequest struct {
+	URL string `json:"url"`
+}
+
+type redriveWebhooksResponse struct {
+	Redriven int64 `json:"redriven"`
+}
+
+func (h apiHandler) handleGetRoomWebhook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	hook, err := h.q.GetRoomWebhook(r.Context(), room.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "webhook is not enabled", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRoomWebhook(w, mapRoomWebhook(hook))
+}
+
+// handlePutRoomWebhook points the room's webhook at a url. Events published
+// from then on are delivered; the first url also issues the secret they're
+// signed with, and deleting the webhook and setting it up again rotates it.
+func (h apiHandler) handlePutRoomWebhook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+	if h.keyring == nil {
+		http.Error(w, "webhooks need stored secrets, which are disabled on this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body putRoomWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	target, err := url.Parse(body.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		http.Error(w, "url must be an absolute http or https url", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := h.issueSecret(r.Context(), room.OrganizationID, webhookSecretName(room.ID))
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to issue webhook secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	hook, err := h.q.UpsertRoomWebhook(r.Context(), pg.UpsertRoomWebhookParams{RoomID: room.ID, Url: target.String()})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	res := mapRoomWebhook(hook)
+	res.Secret = secret
+
+	h.writeRoomWebhook(w, res)
+}
+
+// handleDeleteRoomWebhook stops deliveries, dropping the ones still queued.
+func (h apiHandler) handleDeleteRoomWebhook(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomWebhook(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete webhook", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	_, err = h.q.DeleteSecret(r.Context(), pg.DeleteSecretParams{OrganizationID: room.OrganizationID, Name: webhookSecretName(room.ID)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete webhook secret", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "webhook is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h apiHandler) writeRoomWebhook(w http.ResponseWriter, res RoomWebhook) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleRedriveWebhooks queues the deliveries that ran out of attempts
+// again, from their first attempt, for every room or the ?room_id one.
+// Their dead letters are dropped along.
+func (h apiHandler) handleRedriveWebhooks(w http.ResponseWriter, r *http.Request) {
+	var roomID uuid.NullUUID
+	if raw := r.URL.Query().Get("room_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "invalid room id", http.StatusBadRequest)
+			return
+		}
+		roomID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	redriven, err := h.q.RedriveWebhookDeliveries(r.Context(), roomID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to redrive webhook deliveries", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(redriveWebhooksResponse{Redriven: redriven})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// runWebhooks delivers the room event log to the rooms' webhooks. Progress
+// is kept in the database, one row per webhook and event, so deliveries pick
+// up where they stopped after a restart and any instance can make them: the
+// leader queues new events behind each webhook's cursor and every instance
+// claims what's due. A delivery is only marked done once the receiver
+// answers 2xx, so it's made at least once and receivers dedupe by key.
+func (h apiHandler) runWebhooks() {
+	ticker := time.NewTicker(h.webhooks.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.bus.ctx.Done():
+			return
+		case <-ticker.C:
+			//? The primary delivers, a standby's replica is read-only
+			if !h.failover.standby.Load() {
+				h.deliverWebhooks(h.bus.ctx)
+			}
+		}
+	}
+}
+
+func (h apiHandler) deliverWebhooks(ctx context.Context) {
+	if h.leader.Leader() {
+		if _, err := h.q.EnqueueWebhookDeliveries(ctx, time.Now().Add(-webhookSettleWindow)); err != nil && ctx.Err() == nil {
+			slog.Error("failed to queue webhook deliveries", "error", err)
+		}
+	}
+
+	for ctx.Err() == nil {
+		//* The lease outlasts the request, a crashed attempt is retried once it runs out
+		delivery, err := h.q.ClaimWebhookDelivery(ctx, time.Now().Add(2*h.webhooks.client.Timeout))
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) && ctx.Err() == nil {
+				slog.Error("failed to claim webhook delivery", "error", err)
+			}
+			return
+		}
+		h.deliverWebhook(ctx, delivery)
+	}
+}
+
+func (h apiHandler) deliverWebhook(ctx context.Context, delivery pg.ClaimWebhookDeliveryRow) {
+	err := h.postWebhook(ctx, delivery)
+	if err == nil {
+		err = h.q.CompleteWebhookDelivery(ctx, pg.CompleteWebhookDeliveryParams{WebhookID: delivery.WebhookID, EventID: delivery.EventID})
+		if err != nil {
+			//? It's retried once the lease runs out and the receiver drops the repeat
+			slog.Error("failed to mark webhook delivery done", "webhook_id", delivery.WebhookID, "event_id", delivery.EventID, "error", err)
+			return
+		}
+		metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+		return
+	}
+
+	maxAttempts := int32(h.webhooks.maxAttempts)
+	var gone errWebhookEventGone
+	if errors.As(err, &gone) {
+		maxAttempts = 0
+	}
+	result := "retried"
+	if delivery.Attempts >= maxAttempts {
+		result = "failed"
+	}
+	slog.Warn("webhook delivery failed", "webhook_id", delivery.WebhookID, "event_id", delivery.EventID, "attempt", delivery.Attempts, "result", result, "error", err)
+
+	err = h.q.FailWebhookDelivery(ctx, pg.FailWebhookDeliveryParams{
+		MaxAttempts: maxAttempts,
+		RetryAt:     time.Now().Add(webhookBackoff(delivery.Attempts)),
+		//? The response body is cut by bytes, Postgres refuses a split rune
+		Error:     strings.ToValidUTF8(err.Error(), ""),
+		WebhookID: delivery.WebhookID,
+		EventID:   delivery.EventID,
+	})
+	if err != nil {
+		slog.Error("failed to record webhook delivery failure", "webhook_id", delivery.WebhookID, "event_id", delivery.EventID, "error", err)
+		return
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// errWebhookEventGone is a delivery whose event was pruned from the log
+// before it was made, which no retry can fix.
+type errWebhookEventGone struct{}
+
+func (errWebhookEventGone) Error() string {
+	return "event is no longer in the log"
+}
+
+// postWebhook posts the delivery's event, signed like ingest deliveries are
+// checked: the hex HMAC-SHA256 of the body in X-Signature-256.
+func (h apiHandler) postWebhook(ctx context.Context, delivery pg.ClaimWebhookDeliveryRow) error {
+	events, err := h.q.GetRoomEvents(ctx, pg.GetRoomEventsParams{
+		RoomID:       delivery.RoomID,
+		AfterEventID: delivery.EventID - 1,
+		MaxEvents:    1,
+	})
+	if err != nil {
+		return fmt.Errorf("get event: %w", err)
+	}
+	if len(events) == 0 || events[0].EventID != delivery.EventID {
+		return errWebhookEventGone{}
+	}
+	event := events[0]
+
+	room, err := h.q.GetRoom(ctx, delivery.RoomID)
+	if err != nil {
+		return fmt.Errorf("get room: %w", err)
+	}
+	secret, err := h.secret(ctx, room.OrganizationID, webhookSecretName(room.ID))
+	if err != nil {
+		return fmt.Errorf("load webhook secret: %w", err)
+	}
+	value, err := h.redactor.JSON(event.Value)
+	if err != nil {
+		return fmt.Errorf("redact event: %w", err)
+	}
+
+	key := webhookDeliveryKey(delivery.WebhookID, delivery.EventID)
+	body, err := json.Marshal(WebhookDelivery{
+		IdempotencyKey: key,
+		RoomID:         room.ID.String(),
+		EventID:        event.EventID,
+		Channel:        event.Channel,
+		Kind:           event.Kind,
+		Value:          value,
+		CreatedAt:      event.CreatedAt,
+		Attempt:        delivery.Attempts,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal delivery: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderIdempotencyKey, key)
+	req.Header.Set(HeaderIngestSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	res, err := h.webhooks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(res.Body, maxWebhookErrorLength))
+		return fmt.Errorf("receiver answered %s: %s", res.Status, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
+
+// webhookBackoff doubles the wait after each failed attempt, up to an hour.
+func webhookBackoff(attempts int32) time.Duration {
+	wait := webhookRetryBase
+	for i := int32(1); i < attempts && wait < webhookRetryMax; i++ {
+		wait *= 2
+	}
+
+	return min(wait, webhookRetryMax)
+}
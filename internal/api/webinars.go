@@ -0,0 +1,385 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+	"github.com/luiz504/week-tech-go-server/internal/utils"
+)
+
+const (
+	WebinarSourceZoom = "zoom"
+	WebinarSourceMeet = "meet"
+)
+
+var webinarSources = map[string]bool{
+	WebinarSourceZoom: true,
+	WebinarSourceMeet: true,
+}
+
+const (
+	maxWebinarQuestions = 500
+	// maxWebinarQuestionLength is where longer questions are cut, the length
+	// of messages.message.
+	maxWebinarQuestionLength = 255
+	maxWebinarIDLength       = 200
+	// maxWebinarUpvotes bounds the reactions one delivery can add to a question.
+	maxWebinarUpvotes = 10000
+)
+
+var errNoQuestionColumn = errors.New("the csv needs a question column")
+
+// webinarQuestion is one item of a webinar's Q&A. ID is the platform's, when
+// the export has one.
+type webinarQuestion struct {
+	ID       string `json:"id"`
+	Question string `json:"question"`
+	Author   string `json:"author"`
+	Upvotes  int    `json:"upvotes"`
+}
+
+// parseWebinarCSV reads a Q&A export whose header names its columns, question
+// being the only one required. Unknown columns are ignored.
+func parseWebinarCSV(r io.Reader) ([]webinarQuestion, []forms.FieldError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["question"]; !ok {
+		return nil, nil, errNoQuestionColumn
+	}
+
+	var questions []webinarQuestion
+	var errs []forms.FieldError
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return questions, errs, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		var upvotes int
+		if raw := field("upvotes"); raw != "" {
+			if upvotes, err = strconv.Atoi(raw); err != nil {
+				errs = append(errs, forms.FieldError{
+					Field:   fmt.Sprintf("questions[%d].upvotes", len(questions)),
+					Message: "must be a whole number",
+				})
+			}
+		}
+		questions = append(questions, webinarQuestion{
+			ID:       field("id"),
+			Question: field("question"),
+			Author:   field("author"),
+			Upvotes:  upvotes,
+		})
+	}
+}
+
+// validateWebinarQuestion trims a question and cuts it to fit a message.
+func validateWebinarQuestion(i int, question *webinarQuestion) []forms.FieldError {
+	var errs []forms.FieldError
+	field := func(name string) string { return fmt.Sprintf("questions[%d].%s", i, name) }
+
+	question.ID = strings.TrimSpace(question.ID)
+	if utf8.RuneCountInString(question.ID) > maxWebinarIDLength {
+		errs = append(errs, forms.FieldError{Field: field("id"), Message: "must be at most 200 characters"})
+	}
+	question.Question = strings.TrimSpace(question.Question)
+	if question.Question == "" {
+		errs = append(errs, forms.FieldError{Field: field("question"), Message: "must not be empty"})
+	}
+	if utf8.RuneCountInString(question.Question) > maxWebinarQuestionLength {
+		question.Question = string([]rune(question.Question)[:maxWebinarQuestionLength])
+	}
+	question.Author = strings.TrimSpace(question.Author)
+	if question.Upvotes < 0 || question.Upvotes > maxWebinarUpvotes {
+		errs = append(errs, forms.FieldError{Field: field("upvotes"), Message: "must be between 0 and 10000"})
+	}
+
+	return errs
+}
+
+// externalID keys a question within its room. Exports without ids are keyed by
+// author and text, so importing the same file twice doesn't repeat them.
+func (q webinarQuestion) externalID(source string) string {
+	if q.ID != "" {
+		return source + ":" + q.ID
+	}
+	sum := sha256.Sum256([]byte(q.Author + "\x00" + q.Question))
+
+	return source + ":sha256:" + hex.EncodeToString(sum[:16])
+}
+
+// handleCreateWebinarToken issues the token a webinar platform, or the
+// organizer's script, pushes Q&A to the room with, replacing any previous one.
+func (h apiHandler) handleCreateWebinarToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to generate webinar token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	err = h.q.UpsertRoomWebinarToken(r.Context(), pg.UpsertRoomWebinarTokenParams{RoomID: room.ID, TokenHash: utils.HashToken(token)})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to save webinar token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Token     string `json:"token"`
+		IngestURL string `json:"ingest_url"`
+	}
+
+	data, err := json.Marshal(response{Token: token, IngestURL: "/api/rooms/" + room.ID.String() + "/webinar-questions"})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h apiHandler) handleDeleteWebinarToken(w http.ResponseWriter, r *http.Request) {
+	room := hostedRoom(r)
+
+	deleted, err := h.q.DeleteRoomWebinarToken(r.Context(), room.ID)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to delete webinar token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "webinar import is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webinarResult is what importing one question did.
+type webinarResult struct {
+	Row       int    `json:"row"`
+	MessageID string `json:"message_id"`
+	Created   bool   `json:"created"`
+	// Reactions is how many upvotes were new since the last delivery.
+	Reactions int32 `json:"reactions"`
+}
+
+// handleIngestWebinarQuestions takes the Q&A of a Zoom or Meet webinar
+// (?source=zoom or meet) from whoever holds the room's webinar token, as CSV
+// with Content-Type text/csv or as JSON {"questions": [...]}. New questions
+// are posted to the room; upvotes gained since the last delivery become likes,
+// so the same export can be sent again as the webinar goes on.
+func (h apiHandler) handleIngestWebinarQuestions(w http.ResponseWriter, r *http.Request) {
+	roomID, err := utils.ParseUUIDParam(r, "room_id")
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.q.GetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		helpers.LogErrorAndRespond(w, "failed to get room", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	tokenHash, err := h.q.GetRoomWebinarTokenHash(r.Context(), room.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		helpers.LogErrorAndRespond(w, "failed to get webinar token", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !utils.MatchTokenHash(utils.ParseBearerToken(r), tokenHash) {
+		http.Error(w, "invalid webinar token", http.StatusUnauthorized)
+		return
+	}
+	if room.ArchivedAt.Valid {
+		http.Error(w, "room is archived", http.StatusConflict)
+		return
+	}
+	if rejectClosedRoom(w, room) {
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if !webinarSources[source] {
+		http.Error(w, "source must be zoom or meet", http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var questions []webinarQuestion
+	var errs []forms.FieldError
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		questions, errs, err = parseWebinarCSV(body)
+		if err != nil {
+			if errors.Is(err, errNoQuestionColumn) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "invalid csv", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var payload struct {
+			Questions []webinarQuestion `json:"questions"`
+		}
+		if err := json.NewDecoder(body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		questions = payload.Questions
+	}
+	if len(questions) == 0 {
+		http.Error(w, "the export has no questions", http.StatusBadRequest)
+		return
+	}
+	if len(questions) > maxWebinarQuestions {
+		http.Error(w, fmt.Sprintf("an export can have at most %d questions", maxWebinarQuestions), http.StatusBadRequest)
+		return
+	}
+
+	for i := range questions {
+		errs = append(errs, validateWebinarQuestion(i, &questions[i])...)
+	}
+	if len(errs) > 0 {
+		helpers.RespondValidationErrors(w, errs)
+		return
+	}
+
+	results := make([]webinarResult, 0, len(questions))
+	for i, question := range questions {
+		result, err := h.importWebinarQuestion(r.Context(), room, question.externalID(source), question)
+		if err != nil {
+			helpers.LogErrorAndRespond(w, "failed to import webinar question", err, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+		result.Row = i + 1
+		results = append(results, result)
+	}
+
+	type response struct {
+		RoomID    string          `json:"room_id"`
+		Questions []webinarResult `json:"questions"`
+	}
+
+	data, err := json.Marshal(response{RoomID: room.ID.String(), Questions: results})
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to marshal response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		helpers.LogErrorAndRespond(w, "failed to write response", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+}
+
+// importWebinarQuestion posts a question the room hasn't seen and adds the
+// upvotes it gained as likes, in one transaction so a retried delivery
+// neither repeats the question nor counts its upvotes twice.
+func (h apiHandler) importWebinarQuestion(ctx context.Context, room pg.Room, externalID string, question webinarQuestion) (webinarResult, error) {
+	tx, err := h.q.Begin(ctx)
+	if err != nil {
+		return webinarResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var inserted *externalQuestion
+	imported, err := tx.GetWebinarQuestion(ctx, pg.GetWebinarQuestionParams{RoomID: room.ID, ExternalID: externalID})
+	if errors.Is(err, store.ErrNotFound) {
+		posted, err := h.insertExternalQuestion(ctx, tx, room, question.Author, question.Question, nil)
+		if err != nil {
+			return webinarResult{}, err
+		}
+		err = tx.InsertWebinarQuestion(ctx, pg.InsertWebinarQuestionParams{RoomID: room.ID, ExternalID: externalID, MessageID: posted.id})
+		if err != nil {
+			return webinarResult{}, err
+		}
+		inserted = &posted
+		imported = pg.WebinarQuestion{MessageID: posted.id}
+	} else if err != nil {
+		return webinarResult{}, err
+	}
+
+	//* Upvotes only add up: one withdrawn on the platform stays a like here
+	gained := int32(question.Upvotes) - imported.Upvotes
+	var count int64
+	if gained > 0 {
+		count, err = tx.AddMessageReactions(ctx, pg.AddMessageReactionsParams{Kind: ReactionKindLike, Count: gained, ID: imported.MessageID})
+		if err != nil {
+			return webinarResult{}, err
+		}
+		err = tx.SetWebinarQuestionUpvotes(ctx, pg.SetWebinarQuestionUpvotesParams{RoomID: room.ID, ExternalID: externalID, Upvotes: int32(question.Upvotes)})
+		if err != nil {
+			return webinarResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return webinarResult{}, err
+	}
+
+	if inserted != nil {
+		h.announceExternalQuestion(room, *inserted)
+	}
+	if gained > 0 && (inserted == nil || !inserted.held) {
+		h.publish(Message{
+			RoomID: room.ID.String(),
+			Kind:   MessageKindMessageReactionIncreased,
+			Value: MessageMessageReactionUpdated{
+				ID:     imported.MessageID.String(),
+				RoomID: room.ID.String(),
+				Kind:   ReactionKindLike,
+				Count:  count,
+			},
+		})
+	}
+
+	return webinarResult{MessageID: imported.MessageID.String(), Created: inserted != nil, Reactions: max(gained, 0)}, nil
+}
@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/luiz504/week-tech-go-server/internal/quota"
+)
+
+// * Feature flags gated by plan
+const (
+	FeatureModeration = "moderation"
+	FeatureExports    = "exports"
+)
+
+const (
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+type Quotas struct {
+	RoomsPerMonth     int64 `json:"rooms_per_month"`
+	MessagesPerRoom   int64 `json:"messages_per_room"`
+	ConnectionsPerOrg int64 `json:"connections_per_org"`
+}
+
+type Plan struct {
+	Quotas   Quotas          `json:"quotas"`
+	Features map[string]bool `json:"features"`
+}
+
+// Plans maps plan names to what they grant, 0 quotas meaning unlimited.
+var Plans = map[string]Plan{
+	PlanFree: {
+		Quotas:   Quotas{RoomsPerMonth: 5, MessagesPerRoom: 500, ConnectionsPerOrg: 100},
+		Features: map[string]bool{FeatureModeration: false, FeatureExports: false},
+	},
+	PlanPro: {
+		Quotas:   Quotas{RoomsPerMonth: 100, MessagesPerRoom: 10000, ConnectionsPerOrg: 2000},
+		Features: map[string]bool{FeatureModeration: true, FeatureExports: true},
+	},
+	PlanEnterprise: {
+		Quotas:   Quotas{},
+		Features: map[string]bool{FeatureModeration: true, FeatureExports: true},
+	},
+}
+
+// Settings is what organizations.settings stores. Organizations that never
+// went through billing have no plan and keep the server defaults.
+type Settings struct {
+	Plan               string          `json:"plan,omitempty"`
+	SubscriptionStatus string          `json:"subscription_status,omitempty"`
+	Quotas             Quotas          `json:"quotas"`
+	Features           map[string]bool `json:"features,omitempty"`
+}
+
+func ParseSettings(raw []byte) (Settings, error) {
+	var settings Settings
+	if len(raw) == 0 {
+		return settings, nil
+	}
+	err := json.Unmarshal(raw, &settings)
+
+	return settings, err
+}
+
+// Limits returns the quotas of the organization, falling back to the server
+// defaults when it has no plan.
+func (s Settings) Limits(defaults quota.Limits) quota.Limits {
+	if s.Plan == "" {
+		return defaults
+	}
+
+	return quota.Limits{
+		RoomsPerMonth:     s.Quotas.RoomsPerMonth,
+		MessagesPerRoom:   s.Quotas.MessagesPerRoom,
+		ConnectionsPerOrg: s.Quotas.ConnectionsPerOrg,
+	}
+}
+
+// Enabled reports whether the plan grants the feature. Everything is enabled without a plan.
+func (s Settings) Enabled(feature string) bool {
+	if s.Plan == "" {
+		return true
+	}
+
+	return s.Features[feature]
+}
+
+// SettingsFor maps a Stripe subscription status and plan to settings.
+// Past due subscriptions keep their plan while Stripe retries the payment,
+// anything that ended drops to the free plan.
+func SettingsFor(status string, plan string) Settings {
+	switch status {
+	case "active", "trialing", "past_due":
+	default:
+		plan = PlanFree
+	}
+	granted, ok := Plans[plan]
+	if !ok {
+		plan, granted = PlanFree, Plans[PlanFree]
+	}
+
+	return Settings{
+		Plan:               plan,
+		SubscriptionStatus: status,
+		Quotas:             granted.Quotas,
+		Features:           granted.Features,
+	}
+}
+
+// PriceToPlanFromEnv reads WS_STRIPE_PRICE_PLANS, a list of price_id:plan
+// pairs such as "price_123:pro,price_456:enterprise". Prices whose lookup
+// key is a plan name don't need an entry.
+func PriceToPlanFromEnv() map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("WS_STRIPE_PRICE_PLANS"), ",") {
+		price, plan, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && price != "" && plan != "" {
+			mapping[price] = plan
+		}
+	}
+
+	return mapping
+}
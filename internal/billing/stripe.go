@@ -0,0 +1,112 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const StripeSignatureHeader = "Stripe-Signature"
+
+// SignatureTolerance bounds how old a signed webhook may be, guarding against replays.
+const SignatureTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid stripe signature")
+	ErrStaleSignature   = errors.New("stripe signature timestamp outside tolerance")
+)
+
+// VerifySignature checks a Stripe-Signature header ("t=<unix>,v1=<hex>,...")
+// against the endpoint secret, as described in Stripe's webhook docs.
+func VerifySignature(payload []byte, header string, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrStaleSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// * Subscription events mapped to organization settings
+const (
+	EventSubscriptionCreated = "customer.subscription.created"
+	EventSubscriptionUpdated = "customer.subscription.updated"
+	EventSubscriptionDeleted = "customer.subscription.deleted"
+)
+
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Subscription holds the fields of a Stripe subscription object used here.
+type Subscription struct {
+	ID       string            `json:"id"`
+	Customer string            `json:"customer"`
+	Status   string            `json:"status"`
+	Metadata map[string]string `json:"metadata"`
+	Items    struct {
+		Data []struct {
+			Price struct {
+				ID        string `json:"id"`
+				LookupKey string `json:"lookup_key"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// Plan resolves the subscription's plan from its first price, by lookup key
+// first and then by the configured price mapping.
+func (s Subscription) Plan(priceToPlan map[string]string) string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	price := s.Items.Data[0].Price
+	if _, ok := Plans[price.LookupKey]; ok {
+		return price.LookupKey
+	}
+
+	return priceToPlan[price.ID]
+}
@@ -0,0 +1,120 @@
+// Package chaos injects faults for resilience testing: added latency, failed
+// requests and dropped websocket frames, each on a percentage of traffic. It
+// is off unless WS_CHAOS_ENABLED is set and must never run in production.
+package chaos
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Injector holds the fault rates, as percentages from 0 to 100. A nil
+// Injector injects nothing.
+type Injector struct {
+	Latency     time.Duration
+	LatencyRate float64
+	ErrorRate   float64
+	ErrorStatus int
+	DropRate    float64
+}
+
+// FromEnv returns nil unless WS_CHAOS_ENABLED is true. The faults are set
+// with WS_CHAOS_LATENCY and WS_CHAOS_LATENCY_PERCENT, WS_CHAOS_ERROR_PERCENT
+// and WS_CHAOS_ERROR_STATUS (503 by default), and WS_CHAOS_DROP_PERCENT
+// for websocket frames.
+func FromEnv() *Injector {
+	if enabled, _ := strconv.ParseBool(os.Getenv("WS_CHAOS_ENABLED")); !enabled {
+		return nil
+	}
+
+	i := &Injector{
+		LatencyRate: percentFromEnv("WS_CHAOS_LATENCY_PERCENT"),
+		ErrorRate:   percentFromEnv("WS_CHAOS_ERROR_PERCENT"),
+		ErrorStatus: http.StatusServiceUnavailable,
+		DropRate:    percentFromEnv("WS_CHAOS_DROP_PERCENT"),
+	}
+	if raw := os.Getenv("WS_CHAOS_LATENCY"); raw != "" {
+		latency, err := time.ParseDuration(raw)
+		if err != nil || latency < 0 {
+			slog.Warn("invalid WS_CHAOS_LATENCY, latency disabled", "value", raw)
+		} else {
+			i.Latency = latency
+		}
+	}
+	if raw := os.Getenv("WS_CHAOS_ERROR_STATUS"); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil || status < 400 || status > 599 {
+			slog.Warn("invalid WS_CHAOS_ERROR_STATUS, using default", "value", raw, "default", i.ErrorStatus)
+		} else {
+			i.ErrorStatus = status
+		}
+	}
+
+	slog.Warn("fault injection is enabled",
+		"latency", i.Latency,
+		"latency_percent", i.LatencyRate,
+		"error_percent", i.ErrorRate,
+		"drop_percent", i.DropRate,
+	)
+
+	return i
+}
+
+func percentFromEnv(key string) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0
+	}
+	percent, err := strconv.ParseFloat(raw, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		slog.Warn("invalid "+key+", fault disabled", "value", raw)
+		return 0
+	}
+
+	return percent
+}
+
+func hit(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// DropFrame reports whether the next websocket frame should be dropped.
+func (i *Injector) DropFrame() bool {
+	return i != nil && hit(i.DropRate)
+}
+
+// Middleware delays and fails requests at the configured rates. Metrics and
+// admin routes are spared so the experiment can still be watched and stopped.
+func Middleware(i *Injector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if i == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/metrics" || strings.HasPrefix(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if i.Latency > 0 && hit(i.LatencyRate) {
+				select {
+				case <-time.After(i.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if hit(i.ErrorRate) {
+				http.Error(w, "injected fault", i.ErrorStatus)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
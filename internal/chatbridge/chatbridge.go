@@ -0,0 +1,195 @@
+// Package chatbridge mirrors the questions asked in a livestream's chat, on
+// YouTube Live or Twitch, or in a community's Matrix room or IRC channel, into
+// a room. Only messages starting with the bridge's prefix, like "!ask", are
+// questions; repeats and bursts over the bridge's rate are dropped. Matrix and
+// IRC bridges mirror the room back too, posting its questions and answers.
+package chatbridge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PlatformYouTube = "youtube"
+	PlatformTwitch  = "twitch"
+	PlatformMatrix  = "matrix"
+	PlatformIRC     = "irc"
+
+	DefaultPrefix        = "!ask"
+	MaxPrefixLength      = 32
+	DefaultRatePerMinute = 20
+	MaxRatePerMinute     = 120
+	// MaxQuestionLength is where longer questions are cut, the length of
+	// messages.message. Twitch allows 500 characters, YouTube 200.
+	MaxQuestionLength = 255
+
+	// dedupWindow is how long a question asked again is taken as a repeat.
+	dedupWindow = 10 * time.Minute
+	minBackoff  = time.Second
+	maxBackoff  = time.Minute
+)
+
+var ErrNotConfigured = errors.New("chat platform is not configured")
+
+// ValidPlatform reports whether platform is one bridges can read.
+func ValidPlatform(platform string) bool {
+	switch platform {
+	case PlatformYouTube, PlatformTwitch, PlatformMatrix, PlatformIRC:
+		return true
+	}
+
+	return false
+}
+
+// ChatMessage is a message read from a channel's chat. ID is the platform's,
+// unique within the channel.
+type ChatMessage struct {
+	ID     string
+	Author string
+	Text   string
+}
+
+// Source reads the chat of a channel, handing each new message to emit until
+// ctx is done or the connection drops.
+type Source interface {
+	Read(ctx context.Context, channel string, emit func(ChatMessage)) error
+}
+
+// Sink posts to a channel's chat, for the platforms a room is mirrored to.
+type Sink interface {
+	Send(ctx context.Context, channel, text string) error
+}
+
+// Config is how a room bridges a channel's chat.
+type Config struct {
+	Platform      string
+	Channel       string
+	Prefix        string
+	RatePerMinute int
+}
+
+// Post mirrors a question into the room, msg being the chat message it was
+// asked in.
+type Post func(ctx context.Context, msg ChatMessage, question string) error
+
+// Run reads the channel through source until ctx is done, reconnecting with
+// backoff whenever the connection drops.
+func Run(ctx context.Context, source Source, cfg Config, post Post) {
+	filter := NewFilter(cfg.RatePerMinute)
+	emit := func(msg ChatMessage) {
+		question, ok := Question(msg.Text, cfg.Prefix)
+		if !ok || !filter.Allow(question) {
+			return
+		}
+		if err := post(ctx, msg, question); err != nil && ctx.Err() == nil {
+			slog.Error("failed to post chat question", "platform", cfg.Platform, "channel", cfg.Channel, "error", err)
+		}
+	}
+
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		connectedAt := time.Now()
+		err := source.Read(ctx, cfg.Channel, emit)
+		if ctx.Err() != nil {
+			return
+		}
+		//* A connection that held for a while starts the backoff over
+		if time.Since(connectedAt) > maxBackoff {
+			backoff = minBackoff
+		}
+		slog.Warn("chat bridge disconnected, reconnecting", "platform", cfg.Platform, "channel", cfg.Channel, "retry_in", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// Question returns the question in text when it starts with prefix, matched
+// case-insensitively and followed by a space.
+func Question(text, prefix string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if len(text) <= len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := text[len(prefix):]
+	//* "!asking" is not "!ask"
+	if r, _ := utf8.DecodeRuneInString(rest); !unicode.IsSpace(r) {
+		return "", false
+	}
+
+	question := strings.TrimSpace(rest)
+	if question == "" {
+		return "", false
+	}
+	if utf8.RuneCountInString(question) > MaxQuestionLength {
+		question = string([]rune(question)[:MaxQuestionLength])
+	}
+
+	return question, true
+}
+
+// Filter drops the questions a bridge shouldn't post: one already asked in
+// the last minutes, and any past the bridge's rate. It isn't safe for
+// concurrent use, each bridge reading its chat on one goroutine.
+type Filter struct {
+	ratePerMinute float64
+	tokens        float64
+	refilledAt    time.Time
+	seen          map[string]time.Time
+	now           func() time.Time
+}
+
+func NewFilter(ratePerMinute int) *Filter {
+	return &Filter{
+		ratePerMinute: float64(ratePerMinute),
+		tokens:        float64(ratePerMinute),
+		seen:          make(map[string]time.Time),
+		now:           time.Now,
+	}
+}
+
+// Allow reports whether question should be posted, counting it against the
+// rate when it is.
+func (f *Filter) Allow(question string) bool {
+	now := f.now()
+	for key, at := range f.seen {
+		if now.Sub(at) > dedupWindow {
+			delete(f.seen, key)
+		}
+	}
+
+	key := normalize(question)
+	if _, ok := f.seen[key]; ok {
+		return false
+	}
+
+	if !f.refilledAt.IsZero() {
+		f.tokens = min(f.ratePerMinute, f.tokens+now.Sub(f.refilledAt).Minutes()*f.ratePerMinute)
+	}
+	f.refilledAt = now
+	if f.tokens < 1 {
+		return false
+	}
+	f.tokens--
+	f.seen[key] = now
+
+	return true
+}
+
+// normalize folds the differences that don't make a question another one:
+// case, spacing and trailing punctuation.
+func normalize(question string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(question), " "))
+
+	return strings.TrimRightFunc(key, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) })
+}
@@ -0,0 +1,241 @@
+package chatbridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultIRCNick = "weektech"
+	// ircReadTimeout outlasts the PING servers send every few minutes.
+	ircReadTimeout = 6 * time.Minute
+	// maxIRCText keeps a PRIVMSG, with its prefix, under the 512 bytes of an
+	// IRC line.
+	maxIRCText = 400
+)
+
+var errIRCNotJoined = errors.New("irc channel is not joined")
+
+// IRC reads a channel's chat on an IRC network and posts the room's
+// messages back to it, over TLS. Each instance keeps its own connection, a
+// nick already taken getting an underscore appended.
+type IRC struct {
+	addr     string
+	nick     string
+	password string
+
+	mu    sync.Mutex
+	conns map[string]net.Conn
+}
+
+func NewIRC(addr, nick, password string) *IRC {
+	if nick == "" {
+		nick = defaultIRCNick
+	}
+
+	return &IRC{addr: addr, nick: nick, password: password, conns: make(map[string]net.Conn)}
+}
+
+// IRCFromEnv connects to WS_IRC_SERVER, a host:port speaking TLS, as
+// WS_IRC_NICK with WS_IRC_PASSWORD when the nick is registered.
+func IRCFromEnv() *IRC {
+	return NewIRC(os.Getenv("WS_IRC_SERVER"), os.Getenv("WS_IRC_NICK"), os.Getenv("WS_IRC_PASSWORD"))
+}
+
+// Configured reports whether there is a server to connect to.
+func (c *IRC) Configured() bool {
+	return c.addr != ""
+}
+
+func (c *IRC) Read(ctx context.Context, channel string, emit func(ChatMessage)) error {
+	if !c.Configured() {
+		return ErrNotConfigured
+	}
+	channel = "#" + strings.ToLower(strings.TrimPrefix(channel, "#"))
+
+	dialer := tls.Dialer{NetDialer: &net.Dialer{Timeout: dialTimeout}, Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	nick := c.nick
+	if c.password != "" {
+		if _, err := fmt.Fprintf(conn, "PASS %s\r\n", c.password); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(conn, "NICK %s\r\nUSER %s 0 * :Week Tech\r\n", nick, c.nick); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(ircReadTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		line := scanner.Text()
+
+		if payload, ok := strings.CutPrefix(line, "PING"); ok {
+			if _, err := fmt.Fprintf(conn, "PONG%s\r\n", payload); err != nil {
+				return err
+			}
+			continue
+		}
+
+		msg, ok := parseIRCLine(line)
+		if !ok {
+			continue
+		}
+		switch msg.command {
+		case "001":
+			//* Registered, the channel can be joined
+			if _, err := fmt.Fprintf(conn, "JOIN %s\r\n", channel); err != nil {
+				return err
+			}
+		case "433":
+			nick += "_"
+			if _, err := fmt.Fprintf(conn, "NICK %s\r\n", nick); err != nil {
+				return err
+			}
+		case "JOIN":
+			if msg.nick == nick && len(msg.params) > 0 && strings.EqualFold(msg.params[0], channel) {
+				c.join(channel, conn)
+				defer c.leave(channel, conn)
+			}
+		case "PRIVMSG":
+			if len(msg.params) < 2 || !strings.EqualFold(msg.params[0], channel) {
+				continue
+			}
+			text := msg.params[1]
+			emit(ChatMessage{ID: ircMessageID(msg.nick, text, time.Now()), Author: msg.nick, Text: text})
+		case "ERROR":
+			return fmt.Errorf("irc server closed the connection: %s", strings.Join(msg.params, " "))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// Send posts text to the channel, on the connection reading it. It fails
+// while the channel isn't joined.
+func (c *IRC) Send(ctx context.Context, channel, text string) error {
+	channel = "#" + strings.ToLower(strings.TrimPrefix(channel, "#"))
+
+	c.mu.Lock()
+	conn := c.conns[channel]
+	c.mu.Unlock()
+	if conn == nil {
+		return errIRCNotJoined
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxIRCText {
+		text = text[:maxIRCText]
+		//* Not cutting a character in half
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	_, err := fmt.Fprintf(conn, "PRIVMSG %s :%s\r\n", channel, text)
+
+	return err
+}
+
+func (c *IRC) join(channel string, conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conns[channel] = conn
+}
+
+func (c *IRC) leave(channel string, conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[channel] == conn {
+		delete(c.conns, channel)
+	}
+}
+
+// ircMessageID stands in for the id IRC messages lack, the same on every
+// instance reading the channel: the message, its author and the minute it
+// was read in.
+func ircMessageID(nick, text string, at time.Time) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(nick) + "\x00" + text))
+
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(sum[:16]), at.Unix()/60)
+}
+
+// ircLine is a line read from an IRC server, the trailing parameter last in
+// params.
+type ircLine struct {
+	tags    map[string]string
+	nick    string
+	command string
+	params  []string
+}
+
+// parseIRCLine reads a line like
+// "@id=…;display-name=Ana :ana!ana@host PRIVMSG #channel :text", the tags
+// and source being optional.
+func parseIRCLine(line string) (ircLine, bool) {
+	msg := ircLine{tags: map[string]string{}}
+	if raw, ok := strings.CutPrefix(line, "@"); ok {
+		var rest string
+		raw, rest, ok = strings.Cut(raw, " ")
+		if !ok {
+			return ircLine{}, false
+		}
+		for _, tag := range strings.Split(raw, ";") {
+			key, value, _ := strings.Cut(tag, "=")
+			msg.tags[key] = value
+		}
+		line = rest
+	}
+	if source, ok := strings.CutPrefix(line, ":"); ok {
+		var rest string
+		source, rest, ok = strings.Cut(source, " ")
+		if !ok {
+			return ircLine{}, false
+		}
+		msg.nick, _, _ = strings.Cut(source, "!")
+		line = rest
+	}
+
+	line, trailing, hasTrailing := strings.Cut(line, " :")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ircLine{}, false
+	}
+	msg.command = fields[0]
+	msg.params = fields[1:]
+	if hasTrailing {
+		msg.params = append(msg.params, trailing)
+	}
+
+	return msg, true
+}
@@ -0,0 +1,249 @@
+package chatbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// matrixSyncTimeout is how long the homeserver holds a sync open waiting for
+// new events.
+const matrixSyncTimeout = 30 * time.Second
+
+// Matrix reads a Matrix room's messages through the client-server API and
+// posts the room's messages back to it, as notices. Its channel is a room id
+// like "!abc:matrix.org" or an alias like "#week-tech:matrix.org", joined by
+// the bridge's account on connect.
+type Matrix struct {
+	homeserver  string
+	accessToken func(ctx context.Context) (string, error)
+	client      *http.Client
+
+	mu    sync.Mutex
+	rooms map[string]string
+	txn   atomic.Int64
+}
+
+func NewMatrix(homeserver string, accessToken func(ctx context.Context) (string, error)) *Matrix {
+	return &Matrix{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: matrixSyncTimeout + requestTimeout},
+		rooms:       make(map[string]string),
+	}
+}
+
+// MatrixFromEnv talks to WS_MATRIX_HOMESERVER, using WS_MATRIX_ACCESS_TOKEN
+// when set, the token from fallbackToken otherwise.
+func MatrixFromEnv(fallbackToken func(ctx context.Context) (string, error)) *Matrix {
+	accessToken := fallbackToken
+	if token := os.Getenv("WS_MATRIX_ACCESS_TOKEN"); token != "" {
+		accessToken = func(context.Context) (string, error) { return token, nil }
+	}
+
+	return NewMatrix(os.Getenv("WS_MATRIX_HOMESERVER"), accessToken)
+}
+
+// Configured reports whether there is a homeserver and a token to log in
+// with.
+func (m *Matrix) Configured(ctx context.Context) (bool, error) {
+	if m.homeserver == "" {
+		return false, nil
+	}
+	token, err := m.accessToken(ctx)
+
+	return token != "", err
+}
+
+// Read joins the room and follows its timeline. The messages already in the
+// room when it connects are skipped, as are the bridge's own.
+func (m *Matrix) Read(ctx context.Context, channel string, emit func(ChatMessage)) error {
+	token, err := m.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := m.do(ctx, token, http.MethodGet, "/account/whoami", nil, nil, &whoami); err != nil {
+		return err
+	}
+	roomID, err := m.join(ctx, token, channel)
+	if err != nil {
+		return err
+	}
+
+	filter, err := json.Marshal(map[string]any{
+		"presence":     map[string]any{"types": []string{}},
+		"account_data": map[string]any{"types": []string{}},
+		"room": map[string]any{
+			"rooms":    []string{roomID},
+			"state":    map[string]any{"types": []string{}},
+			"timeline": map[string]any{"types": []string{"m.room.message"}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var since string
+	for backlog := true; ; backlog = false {
+		query := url.Values{"filter": {string(filter)}, "timeout": {"0"}}
+		if !backlog {
+			query.Set("since", since)
+			query.Set("timeout", fmt.Sprint(matrixSyncTimeout.Milliseconds()))
+		}
+		var page struct {
+			NextBatch string `json:"next_batch"`
+			Rooms     struct {
+				Join map[string]struct {
+					Timeline struct {
+						Events []struct {
+							EventID string `json:"event_id"`
+							Sender  string `json:"sender"`
+							Type    string `json:"type"`
+							Content struct {
+								MsgType string `json:"msgtype"`
+								Body    string `json:"body"`
+							} `json:"content"`
+						} `json:"events"`
+					} `json:"timeline"`
+				} `json:"join"`
+			} `json:"rooms"`
+		}
+		if err := m.do(ctx, token, http.MethodGet, "/sync", query, nil, &page); err != nil {
+			return err
+		}
+
+		if !backlog {
+			for _, event := range page.Rooms.Join[roomID].Timeline.Events {
+				if event.Type != "m.room.message" || event.Content.MsgType != "m.text" || event.Sender == whoami.UserID {
+					continue
+				}
+				emit(ChatMessage{ID: event.EventID, Author: matrixLocalpart(event.Sender), Text: event.Content.Body})
+			}
+		}
+		since = page.NextBatch
+	}
+}
+
+// Send posts text to the room as a notice, the message type bots use so
+// other bots don't answer it.
+func (m *Matrix) Send(ctx context.Context, channel, text string) error {
+	token, err := m.token(ctx)
+	if err != nil {
+		return err
+	}
+	roomID, err := m.join(ctx, token, channel)
+	if err != nil {
+		return err
+	}
+
+	//? The transaction id makes the homeserver ignore a retried request
+	txnID := fmt.Sprintf("ws%d.%d", time.Now().UnixNano(), m.txn.Add(1))
+	path := "/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + url.PathEscape(txnID)
+
+	return m.do(ctx, token, http.MethodPut, path, nil, map[string]string{"msgtype": "m.notice", "body": text}, nil)
+}
+
+func (m *Matrix) token(ctx context.Context) (string, error) {
+	if m.homeserver == "" {
+		return "", ErrNotConfigured
+	}
+	token, err := m.accessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load access token: %w", err)
+	}
+	if token == "" {
+		return "", ErrNotConfigured
+	}
+
+	return token, nil
+}
+
+// join has the account join the room, an alias resolved to the room's id.
+// Joining a room already joined is a no-op, the id is remembered still to
+// spare a request per message sent.
+func (m *Matrix) join(ctx context.Context, token, channel string) (string, error) {
+	m.mu.Lock()
+	roomID, ok := m.rooms[channel]
+	m.mu.Unlock()
+	if ok {
+		return roomID, nil
+	}
+
+	var joined struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := m.do(ctx, token, http.MethodPost, "/join/"+url.PathEscape(channel), nil, struct{}{}, &joined); err != nil {
+		return "", fmt.Errorf("join %s: %w", channel, err)
+	}
+
+	m.mu.Lock()
+	m.rooms[channel] = joined.RoomID
+	m.mu.Unlock()
+
+	return joined.RoomID, nil
+}
+
+func (m *Matrix) do(ctx context.Context, token, method, path string, query url.Values, body, dst any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := m.homeserver + "/_matrix/client/v3" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	//* The token goes in the header, a query parameter would end up in access logs
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+		return fmt.Errorf("matrix %s %s: %d %s %s", method, path, res.StatusCode, e.ErrCode, e.Error)
+	}
+	if dst == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
+
+// matrixLocalpart is the name in a user id like "@ana:matrix.org".
+func matrixLocalpart(userID string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(userID, "@"), ":")
+
+	return name
+}
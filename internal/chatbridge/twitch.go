@@ -0,0 +1,96 @@
+package chatbridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	twitchAddr = "irc.chat.twitch.tv:6697"
+	// twitchReadTimeout outlasts the PING Twitch sends about every five minutes.
+	twitchReadTimeout = 6 * time.Minute
+	dialTimeout       = 10 * time.Second
+)
+
+// Twitch reads a channel's chat over Twitch's IRC interface, logged in
+// anonymously: reading needs no account.
+type Twitch struct {
+	addr string
+}
+
+func NewTwitch() *Twitch {
+	return &Twitch{addr: twitchAddr}
+}
+
+func (t *Twitch) Read(ctx context.Context, channel string, emit func(ChatMessage)) error {
+	dialer := tls.Dialer{NetDialer: &net.Dialer{Timeout: dialTimeout}, Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	//* justinfan logins are Twitch's anonymous, read-only ones; tags carry the message ids
+	_, err = fmt.Fprintf(conn, "CAP REQ :twitch.tv/tags\r\nPASS SCHMOOPIIE\r\nNICK justinfan%d\r\nJOIN #%s\r\n",
+		10000+rand.IntN(90000), strings.ToLower(strings.TrimPrefix(channel, "#")))
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(twitchReadTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		line := scanner.Text()
+
+		if payload, ok := strings.CutPrefix(line, "PING"); ok {
+			if _, err := fmt.Fprintf(conn, "PONG%s\r\n", payload); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.Contains(line, " RECONNECT") {
+			return errors.New("twitch asked to reconnect")
+		}
+		if msg, ok := parseTwitchMessage(line); ok {
+			emit(msg)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// parseTwitchMessage reads a tagged PRIVMSG line like
+// "@display-name=Ana;id=… :ana!ana@ana.tmi.twitch.tv PRIVMSG #channel :text".
+func parseTwitchMessage(line string) (ChatMessage, bool) {
+	msg, ok := parseIRCLine(line)
+	if !ok || msg.command != "PRIVMSG" || len(msg.params) < 2 || msg.tags["id"] == "" {
+		return ChatMessage{}, false
+	}
+
+	author := msg.tags["display-name"]
+	if author == "" {
+		author = msg.nick
+	}
+
+	return ChatMessage{ID: msg.tags["id"], Author: author, Text: msg.params[len(msg.params)-1]}, true
+}
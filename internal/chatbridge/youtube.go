@@ -0,0 +1,151 @@
+package chatbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	youtubeAPI = "https://www.googleapis.com/youtube/v3"
+	// minYouTubePoll keeps polling at a pace the API quota affords, whatever
+	// interval the API suggests.
+	minYouTubePoll = 5 * time.Second
+	requestTimeout = 15 * time.Second
+)
+
+var errChatEnded = errors.New("live chat has ended")
+
+// YouTube polls the live chat of a broadcast through the YouTube Data API.
+// Its channel is the ID of the live video, the chat of a YouTube channel not
+// being addressable on its own.
+type YouTube struct {
+	baseURL string
+	apiKey  func(ctx context.Context) (string, error)
+	client  *http.Client
+}
+
+func NewYouTube(apiKey func(ctx context.Context) (string, error)) *YouTube {
+	return &YouTube{baseURL: youtubeAPI, apiKey: apiKey, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// YouTubeFromEnv uses WS_YOUTUBE_API_KEY when set, the key from fallbackKey
+// otherwise.
+func YouTubeFromEnv(fallbackKey func(ctx context.Context) (string, error)) *YouTube {
+	apiKey := fallbackKey
+	if key := os.Getenv("WS_YOUTUBE_API_KEY"); key != "" {
+		apiKey = func(context.Context) (string, error) { return key, nil }
+	}
+
+	return NewYouTube(apiKey)
+}
+
+// Configured reports whether there is an API key to read chats with.
+func (y *YouTube) Configured(ctx context.Context) (bool, error) {
+	key, err := y.apiKey(ctx)
+
+	return key != "", err
+}
+
+// Read polls the chat of the live video. The messages already in the chat
+// when it connects are skipped, they were asked before the bridge listened.
+func (y *YouTube) Read(ctx context.Context, videoID string, emit func(ChatMessage)) error {
+	key, err := y.apiKey(ctx)
+	if err != nil {
+		return fmt.Errorf("load api key: %w", err)
+	}
+	if key == "" {
+		return ErrNotConfigured
+	}
+
+	var videos struct {
+		Items []struct {
+			LiveStreamingDetails struct {
+				ActiveLiveChatID string `json:"activeLiveChatId"`
+			} `json:"liveStreamingDetails"`
+		} `json:"items"`
+	}
+	err = y.get(ctx, "/videos", key, url.Values{"part": {"liveStreamingDetails"}, "id": {videoID}}, &videos)
+	if err != nil {
+		return err
+	}
+	if len(videos.Items) == 0 || videos.Items[0].LiveStreamingDetails.ActiveLiveChatID == "" {
+		return fmt.Errorf("video %s has no active live chat", videoID)
+	}
+	chatID := videos.Items[0].LiveStreamingDetails.ActiveLiveChatID
+
+	var pageToken string
+	for backlog := true; ; backlog = false {
+		query := url.Values{"liveChatId": {chatID}, "part": {"snippet,authorDetails"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var page struct {
+			NextPageToken         string `json:"nextPageToken"`
+			PollingIntervalMillis int    `json:"pollingIntervalMillis"`
+			OfflineAt             string `json:"offlineAt"`
+			Items                 []struct {
+				ID      string `json:"id"`
+				Snippet struct {
+					Type           string `json:"type"`
+					DisplayMessage string `json:"displayMessage"`
+				} `json:"snippet"`
+				AuthorDetails struct {
+					DisplayName string `json:"displayName"`
+				} `json:"authorDetails"`
+			} `json:"items"`
+		}
+		if err := y.get(ctx, "/liveChat/messages", key, query, &page); err != nil {
+			return err
+		}
+
+		if !backlog {
+			for _, item := range page.Items {
+				if item.Snippet.Type != "textMessageEvent" {
+					continue
+				}
+				emit(ChatMessage{ID: item.ID, Author: item.AuthorDetails.DisplayName, Text: item.Snippet.DisplayMessage})
+			}
+		}
+		if page.OfflineAt != "" {
+			return errChatEnded
+		}
+		pageToken = page.NextPageToken
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(max(time.Duration(page.PollingIntervalMillis)*time.Millisecond, minYouTubePoll)):
+		}
+	}
+}
+
+// get sends the key as a header rather than in the query, where it would show
+// up in the errors logged.
+func (y *YouTube) get(ctx context.Context, path, key string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, y.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Goog-Api-Key", key)
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("youtube returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,118 @@
+// Package clientip resolves the real client address of requests arriving
+// through reverse proxies, trusting forwarding headers only from known peers.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New parses trusted proxy CIDRs. Bare addresses are taken as single hosts.
+func New(cidrs []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, raw := range cidrs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "/") {
+			addr, err := netip.ParseAddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+// FromEnv reads WS_TRUSTED_PROXIES, a comma-separated list of CIDRs. With
+// none, forwarding headers are always ignored.
+func FromEnv() (*Resolver, error) {
+	return New(strings.Split(os.Getenv("WS_TRUSTED_PROXIES"), ","))
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the address of the client behind any trusted proxies.
+// X-Forwarded-For is walked from the right, the first hop that isn't a
+// trusted proxy being the client, since anything left of it could have been
+// written by the client itself.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	host := req.RemoteAddr
+	if h, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		host = h
+	}
+
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !r.isTrusted(peer) {
+		return host
+	}
+
+	if header := req.Header.Values(HeaderForwardedFor); len(header) > 0 {
+		hops := strings.Split(strings.Join(header, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				//? A malformed hop can't be trusted, nor anything before it
+				break
+			}
+			if !r.isTrusted(hop) {
+				return hop.String()
+			}
+			host = hop.String()
+		}
+		return host
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(req.Header.Get(HeaderRealIP))); err == nil {
+		return realIP.String()
+	}
+
+	return host
+}
+
+// Middleware replaces r.RemoteAddr with the resolved client address, so rate
+// limiting, logs and anything else keyed on it see the real client.
+func Middleware(r *Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(r.trusted) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			req.RemoteAddr = r.ClientIP(req)
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
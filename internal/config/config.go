@@ -0,0 +1,189 @@
+// Package config loads the settings the server can't start without: where the
+// database is, what to listen on, who may call it, what exports must redact
+// and how long to drain on shutdown. Optional integrations keep reading their own WS_* variables next
+// to the code that uses them.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/redact"
+)
+
+const (
+	DefaultPort            = 8080
+	DefaultShutdownTimeout = 10 * time.Second
+	DefaultReplicaMaxWait  = 500 * time.Millisecond
+)
+
+// DefaultCORSOrigins accepts every origin, for development.
+var DefaultCORSOrigins = []string{"http://*", "https://*"}
+
+type Database struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+}
+
+// ConnString is the libpq style connection string for pgxpool.
+func (d Database) ConnString() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", d.Host, d.Port, d.User, d.Password, d.Name)
+}
+
+type Config struct {
+	Database Database
+	// Replica is a streaming replica of Database some reads go to, nil
+	// when there's none.
+	Replica *Database
+	// ReplicaMaxWait bounds how long a read waits for the replica to catch
+	// up with the writes it must see, before going to the primary.
+	ReplicaMaxWait time.Duration
+	// Port is the TCP port the server listens on.
+	Port int
+	// CORSOrigins are the origins browsers may call the API from, wildcards
+	// allowed.
+	CORSOrigins []string
+	// ShutdownTimeout bounds how long a shutdown waits for requests, events
+	// and subscribers to drain before the pool is closed.
+	ShutdownTimeout time.Duration
+	// Redactor masks personal data in exports and mirrored chat messages.
+	Redactor *redact.Redactor
+}
+
+// Load reads WS_DATABASE_HOST, WS_DATABASE_PORT, WS_DATABASE_USER,
+// WS_DATABASE_PASSWORD and WS_DATABASE_NAME, WS_DATABASE_REPLICA_HOST and
+// WS_DATABASE_REPLICA_PORT (the primary's by default) for a replica to read,
+// WS_DATABASE_REPLICA_MAX_WAIT (500ms by default), WS_PORT (8080 by default),
+// WS_CORS_ORIGINS, a comma-separated list (any origin by default),
+// WS_SHUTDOWN_TIMEOUT (10s by default) and the redaction settings, see
+// redact.FromEnv. Every invalid or missing variable is reported at once.
+func Load() (Config, error) {
+	var errs []error
+
+	db, err := LoadDatabase()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var replica *Database
+	if host := os.Getenv("WS_DATABASE_REPLICA_HOST"); host != "" {
+		replicaPort, err := intFromEnv("WS_DATABASE_REPLICA_PORT", db.Port)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		replica = &Database{Host: host, Port: replicaPort, User: db.User, Password: db.Password, Name: db.Name}
+	}
+	replicaMaxWait, err := durationFromEnv("WS_DATABASE_REPLICA_MAX_WAIT", DefaultReplicaMaxWait)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	port, err := intFromEnv("WS_PORT", DefaultPort)
+	if err == nil && (port < 1 || port > 65535) {
+		err = fmt.Errorf("WS_PORT: %d is not a valid port", port)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	shutdownTimeout, err := durationFromEnv("WS_SHUTDOWN_TIMEOUT", DefaultShutdownTimeout)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	//* Malformed redaction settings must not let exports out unredacted
+	redactor, err := redact.FromEnv()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	origins := DefaultCORSOrigins
+	if raw := os.Getenv("WS_CORS_ORIGINS"); raw != "" {
+		origins = nil
+		for _, origin := range strings.Split(raw, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	return Config{
+		Database:        db,
+		Replica:         replica,
+		ReplicaMaxWait:  replicaMaxWait,
+		Port:            port,
+		CORSOrigins:     origins,
+		ShutdownTimeout: shutdownTimeout,
+		Redactor:        redactor,
+	}, nil
+}
+
+// LoadDatabase reads only the database settings, for tools that don't serve.
+// The password may be empty, for trust authentication.
+func LoadDatabase() (Database, error) {
+	var errs []error
+	required := func(key string) string {
+		value := os.Getenv(key)
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+		return value
+	}
+
+	db := Database{
+		Host:     required("WS_DATABASE_HOST"),
+		User:     required("WS_DATABASE_USER"),
+		Password: os.Getenv("WS_DATABASE_PASSWORD"),
+		Name:     required("WS_DATABASE_NAME"),
+	}
+	if required("WS_DATABASE_PORT") != "" {
+		port, err := intFromEnv("WS_DATABASE_PORT", 0)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		db.Port = port
+	}
+
+	if len(errs) > 0 {
+		return Database{}, errors.Join(errs...)
+	}
+
+	return db, nil
+}
+
+func intFromEnv(key string, fallback int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a number", key, raw)
+	}
+
+	return value, nil
+}
+
+func durationFromEnv(key string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s: %q is not a positive duration", key, raw)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,450 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Checks is the protocol, one rule per check.
+var Checks = []Check{
+	{
+		Name: "rejects_unknown_rooms",
+		Doc:  "Subscribing to a malformed room id fails the handshake with 400, to an unknown room with 404.",
+		Run:  checkRejectsUnknownRooms,
+	},
+	{
+		Name: "echoes_request_id",
+		Doc:  "The handshake response carries X-Request-Id, to be quoted when reporting issues.",
+		Run:  checkEchoesRequestID,
+	},
+	{
+		Name: "orders_events",
+		Doc:  "Room events arrive in the order they happened, with event_id increasing by exactly one.",
+		Run:  checkOrdersEvents,
+	},
+	{
+		Name: "orders_reactions",
+		Doc:  "A reaction sent right after posting a message arrives after that message's message_created.",
+		Run:  checkOrdersReactions,
+	},
+	{
+		Name: "resumes_from_snapshot",
+		Doc: "GET /api/rooms/{id}/messages returns last_event_id. A client resubscribing after it " +
+			"receives every later event, the first one being last_event_id + 1.",
+		Run: checkResumesFromSnapshot,
+	},
+	{
+		Name: "replays_missed_events",
+		Doc: "Subscribing with ?last_event_id=N first replays the events after N the socket missed, " +
+			"with their event_id, before the live ones.",
+		Run: checkReplaysMissedEvents,
+	},
+	{
+		Name: "answers_pings",
+		Doc:  "A websocket ping is answered with a pong carrying the same payload.",
+		Run:  checkAnswersPings,
+	},
+	{
+		Name: "acks_heartbeats",
+		Doc:  `A {"type":"heartbeat","ts":N} command is answered by heartbeat_ack echoing ts and carrying server_ts.`,
+		Run:  checkAcksHeartbeats,
+	},
+	{
+		Name: "rejects_bad_commands",
+		Doc: "Invalid frames, unknown commands and host channels without the owner token get " +
+			"command_rejected, and the socket stays open.",
+		Run: checkRejectsBadCommands,
+	},
+	{
+		Name: "closes_with_going_away",
+		Doc: "A subscriber shed for rebalancing gets reconnect_advised with reason rebalance, then a " +
+			"1001 close frame. Needs the admin token.",
+		Run: checkClosesWithGoingAway,
+	},
+}
+
+func expectHandshakeStatus(resp *http.Response, err error, want int) error {
+	if err == nil {
+		return fmt.Errorf("handshake succeeded, want status %d", want)
+	}
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp == nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("handshake got status %d, want %d", resp.StatusCode, want)
+	}
+
+	return nil
+}
+
+func checkRejectsUnknownRooms(ctx context.Context, c *Client) error {
+	conn, resp, err := c.dial(ctx, "not-a-uuid", nil)
+	if conn != nil {
+		conn.Close()
+	}
+	if err := expectHandshakeStatus(resp, err, http.StatusBadRequest); err != nil {
+		return err
+	}
+
+	conn, resp, err = c.dial(ctx, uuid.NewString(), nil)
+	if conn != nil {
+		conn.Close()
+	}
+
+	return expectHandshakeStatus(resp, err, http.StatusNotFound)
+}
+
+func checkEchoesRequestID(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, resp, err := c.dial(ctx, room.ID, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if resp.Header.Get("X-Request-Id") == "" {
+		return errors.New("handshake response has no X-Request-Id")
+	}
+
+	return nil
+}
+
+func checkOrdersEvents(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	const count = 5
+	posted := make([]string, count)
+	for i := range posted {
+		if posted[i], err = c.postMessage(ctx, room.ID, fmt.Sprintf("ordering %d", i)); err != nil {
+			return err
+		}
+	}
+
+	var last int64
+	for i, id := range posted {
+		e, err := c.next(conn, "message_created")
+		if err != nil {
+			return err
+		}
+		var value struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(e.Value, &value); err != nil {
+			return err
+		}
+		if value.ID != id {
+			return fmt.Errorf("event %d is message %s, want %s", i, value.ID, id)
+		}
+		if i > 0 && e.EventID != last+1 {
+			return fmt.Errorf("event_id %d follows %d", e.EventID, last)
+		}
+		last = e.EventID
+	}
+
+	return nil
+}
+
+func checkOrdersReactions(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	id, err := c.postMessage(ctx, room.ID, "react to me")
+	if err != nil {
+		return err
+	}
+	if err := c.react(ctx, room.ID, id); err != nil {
+		return err
+	}
+
+	//? Reading message_created skips an earlier reaction, which fails the next read
+	created, err := c.next(conn, "message_created")
+	if err != nil {
+		return err
+	}
+	reacted, err := c.next(conn, "message_reaction_increased")
+	if err != nil {
+		return fmt.Errorf("message_created came after its reaction: %w", err)
+	}
+	if reacted.EventID != created.EventID+1 {
+		return fmt.Errorf("reaction event_id %d follows message_created %d", reacted.EventID, created.EventID)
+	}
+
+	return nil
+}
+
+func checkResumesFromSnapshot(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+
+	//* Miss an event while disconnected, then catch up from the snapshot
+	if _, err := c.postMessage(ctx, room.ID, "while offline"); err != nil {
+		return err
+	}
+	snap, err := c.snapshot(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	if len(snap.Messages) != 1 {
+		return fmt.Errorf("snapshot has %d messages, want 1", len(snap.Messages))
+	}
+
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := c.postMessage(ctx, room.ID, "after resuming"); err != nil {
+		return err
+	}
+	e, err := c.next(conn, "message_created")
+	if err != nil {
+		return err
+	}
+	if e.EventID != snap.LastEventID+1 {
+		return fmt.Errorf("first event after the snapshot is %d, want %d", e.EventID, snap.LastEventID+1)
+	}
+
+	return nil
+}
+
+func checkReplaysMissedEvents(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	//* Another socket tells when the missed event was broadcast, and so logged
+	observer, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer observer.Close()
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.postMessage(ctx, room.ID, "before leaving"); err != nil {
+		conn.Close()
+		return err
+	}
+	seen, err := c.next(conn, "message_created")
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.postMessage(ctx, room.ID, "while offline"); err != nil {
+		return err
+	}
+	if _, err := c.next(observer, "message_created"); err != nil {
+		return err
+	}
+	missed, err := c.next(observer, "message_created")
+	if err != nil {
+		return err
+	}
+
+	conn, _, err = c.dial(ctx, room.ID, url.Values{"last_event_id": {strconv.FormatInt(seen.EventID, 10)}})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	e, err := c.next(conn, "message_created")
+	if err != nil {
+		return err
+	}
+	if e.EventID != missed.EventID || string(e.Value) != string(missed.Value) {
+		return fmt.Errorf("replayed event %d %s, want %d %s", e.EventID, e.Value, missed.EventID, missed.Value)
+	}
+
+	return nil
+}
+
+func checkAnswersPings(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	const payload = "contract"
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	//? Control frames are only processed while reading
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteControl(websocket.PingMessage, []byte(payload), time.Now().Add(c.cfg.Timeout)); err != nil {
+		return err
+	}
+
+	select {
+	case data := <-pong:
+		if data != payload {
+			return fmt.Errorf("pong payload %q, want %q", data, payload)
+		}
+		return nil
+	case <-time.After(c.cfg.Timeout):
+		return errors.New("no pong received")
+	}
+}
+
+func checkAcksHeartbeats(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]any{"type": "heartbeat", "ts": 42}); err != nil {
+		return err
+	}
+	e, err := c.next(conn, "heartbeat_ack")
+	if err != nil {
+		return err
+	}
+
+	var ack struct {
+		TS       int64 `json:"ts"`
+		ServerTS int64 `json:"server_ts"`
+	}
+	if err := json.Unmarshal(e.Value, &ack); err != nil {
+		return err
+	}
+	if ack.TS != 42 || ack.ServerTS <= 0 {
+		return fmt.Errorf("heartbeat_ack has ts %d and server_ts %d", ack.TS, ack.ServerTS)
+	}
+
+	return nil
+}
+
+func checkRejectsBadCommands(ctx context.Context, c *Client) error {
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cases := []struct {
+		frame  string
+		reason string
+	}{
+		{`not json`, "invalid json"},
+		{`{"type":"dance"}`, "unknown command"},
+		{`{"type":"subscribe","channel":"nowhere"}`, "unknown channel"},
+		{`{"type":"subscribe","channel":"moderation"}`, "unauthorized"},
+	}
+	for _, tc := range cases {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tc.frame)); err != nil {
+			return err
+		}
+		e, err := c.next(conn, "command_rejected")
+		if err != nil {
+			return err
+		}
+		var rejected struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(e.Value, &rejected); err != nil {
+			return err
+		}
+		if rejected.Reason != tc.reason {
+			return fmt.Errorf("%s was rejected with %q, want %q", tc.frame, rejected.Reason, tc.reason)
+		}
+	}
+
+	return nil
+}
+
+func checkClosesWithGoingAway(ctx context.Context, c *Client) error {
+	if c.cfg.AdminToken == "" {
+		return ErrSkipped
+	}
+
+	room, err := c.createRoom(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := c.subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = c.do(ctx, http.MethodPost, "/admin/rooms/"+room.ID+"/shed", c.cfg.AdminToken, map[string]any{"count": 1}, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+
+	e, err := c.next(conn, "reconnect_advised")
+	if err != nil {
+		return err
+	}
+	var advice struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(e.Value, &advice); err != nil {
+		return err
+	}
+	if advice.Reason != "rebalance" {
+		return fmt.Errorf("reconnect_advised reason %q, want rebalance", advice.Reason)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		return fmt.Errorf("got %v, want a %d close", err, websocket.CloseGoingAway)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event is a websocket frame as the server sends it.
+type Event struct {
+	EventID int64           `json:"event_id"`
+	Channel string          `json:"channel"`
+	Kind    string          `json:"kind"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Client talks to the server under test.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func newClient(cfg Config) *Client {
+	return &Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	if c.cfg.APIKey != "" {
+		header.Set("X-API-Key", c.cfg.APIKey)
+	}
+
+	return header
+}
+
+// do sends a JSON request and decodes the response into out, failing on any
+// status other than want.
+func (c *Client) do(ctx context.Context, method, path, bearer string, body, out any, want int) error {
+	header := c.header()
+	if bearer != "" {
+		header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	return c.request(ctx, method, path, header, body, out, want)
+}
+
+func (c *Client) request(ctx context.Context, method, path string, header http.Header, body, out any, want int) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.BaseURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: got status %d, want %d: %s", method, path, resp.StatusCode, want, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type room struct {
+	ID         string `json:"id"`
+	OwnerToken string `json:"owner_token"`
+}
+
+func (c *Client) createRoom(ctx context.Context) (room, error) {
+	var r room
+	err := c.do(ctx, http.MethodPost, "/api/rooms", "", map[string]any{"theme": "contract " + time.Now().Format(time.RFC3339Nano)}, &r, http.StatusCreated)
+
+	return r, err
+}
+
+func (c *Client) postMessage(ctx context.Context, roomID, message string) (string, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/api/rooms/"+roomID+"/messages", "", map[string]any{"message": message}, &resp, http.StatusCreated)
+
+	return resp.ID, err
+}
+
+// react likes a message as a new session, reactions being counted per
+// session.
+func (c *Client) react(ctx context.Context, roomID, messageID string) error {
+	var s struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/sessions", "", nil, &s, http.StatusCreated); err != nil {
+		return err
+	}
+
+	header := c.header()
+	header.Set("X-Session-Token", s.Token)
+	return c.request(ctx, http.MethodPatch, "/api/rooms/"+roomID+"/messages/"+messageID+"/react", header, nil, nil, http.StatusOK)
+}
+
+type snapshot struct {
+	LastEventID int64 `json:"last_event_id"`
+	Messages    []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+}
+
+func (c *Client) snapshot(ctx context.Context, roomID string) (snapshot, error) {
+	var s snapshot
+	err := c.do(ctx, http.MethodGet, "/api/rooms/"+roomID+"/messages", "", nil, &s, http.StatusOK)
+
+	return s, err
+}
+
+func (c *Client) dial(ctx context.Context, roomID string, query url.Values) (*websocket.Conn, *http.Response, error) {
+	u, err := url.Parse(strings.TrimRight(c.cfg.BaseURL, "/") + "/subscribe/" + roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.RawQuery = query.Encode()
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	dialer := websocket.Dialer{HandshakeTimeout: c.cfg.Timeout}
+
+	return dialer.DialContext(ctx, u.String(), c.header())
+}
+
+// subscribe dials the room and waits until the server has registered the
+// socket. The ack of a first heartbeat proves it, as commands are only read
+// once the subscriber joined.
+func (c *Client) subscribe(ctx context.Context, roomID string) (*websocket.Conn, error) {
+	conn, _, err := c.dial(ctx, roomID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.WriteJSON(map[string]any{"type": "heartbeat"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := c.next(conn, "heartbeat_ack"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// next reads frames until one of the given kind arrives, skipping the
+// ephemeral ones the server may interleave, like applause.
+func (c *Client) next(conn *websocket.Conn, kind string) (Event, error) {
+	deadline := time.Now().Add(c.cfg.Timeout)
+	for {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return Event{}, err
+		}
+		var e Event
+		if err := conn.ReadJSON(&e); err != nil {
+			return Event{}, fmt.Errorf("waiting for %s: %w", kind, err)
+		}
+		if e.Kind == kind {
+			return e, nil
+		}
+	}
+}
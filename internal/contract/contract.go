@@ -0,0 +1,54 @@
+// Package contract is a conformance suite for the websocket protocol. It
+// connects to a running server as a client would and checks the guarantees
+// SDKs rely on. Each check's Doc is the rule it enforces, so the list doubles
+// as protocol documentation.
+//
+// The suite runs with go test when WS_CONTRACT_BASE_URL is set, and through
+// cmd/tools/contract.
+package contract
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrSkipped is returned by checks whose prerequisites aren't configured.
+var ErrSkipped = errors.New("skipped")
+
+type Config struct {
+	// BaseURL is the HTTP address of the server, like http://localhost:8080.
+	BaseURL string
+	// APIKey is sent as X-API-Key when set.
+	APIKey string
+	// AdminToken enables the checks that need the admin API.
+	AdminToken string
+	Timeout    time.Duration
+}
+
+type Check struct {
+	Name string
+	Doc  string
+	Run  func(ctx context.Context, c *Client) error
+}
+
+// Result is the outcome of one check, Err being nil when it passed.
+type Result struct {
+	Check Check
+	Err   error
+}
+
+// Run executes every check in order against the configured server.
+func Run(ctx context.Context, cfg Config) []Result {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	client := newClient(cfg)
+
+	results := make([]Result, 0, len(Checks))
+	for _, check := range Checks {
+		results = append(results, Result{Check: check, Err: check.Run(ctx, client)})
+	}
+
+	return results
+}
@@ -0,0 +1,37 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestProtocol runs the suite against WS_CONTRACT_BASE_URL, with
+// WS_CONTRACT_API_KEY and WS_CONTRACT_ADMIN_TOKEN when set.
+func TestProtocol(t *testing.T) {
+	baseURL := os.Getenv("WS_CONTRACT_BASE_URL")
+	if baseURL == "" {
+		t.Skip("WS_CONTRACT_BASE_URL is not set")
+	}
+
+	client := newClient(Config{
+		BaseURL:    baseURL,
+		APIKey:     os.Getenv("WS_CONTRACT_API_KEY"),
+		AdminToken: os.Getenv("WS_CONTRACT_ADMIN_TOKEN"),
+		Timeout:    5 * time.Second,
+	})
+
+	for _, check := range Checks {
+		t.Run(check.Name, func(t *testing.T) {
+			err := check.Run(context.Background(), client)
+			if errors.Is(err, ErrSkipped) {
+				t.Skip(check.Doc)
+			}
+			if err != nil {
+				t.Fatalf("%s\n%v", check.Doc, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package crypto implements envelope encryption for secrets stored at rest.
+// Every value is sealed with its own data key, and only that data key is
+// encrypted with a master key, so rotating a master key rewraps a few bytes
+// per secret instead of re-encrypting the values.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const dataKeySize = 32
+
+var (
+	ErrNoKeyring  = errors.New("no master key configured")
+	ErrUnknownKey = errors.New("unknown master key")
+	ErrDecrypt    = errors.New("failed to decrypt")
+)
+
+// Envelope is what gets persisted for a secret. WrappedKey is the data key
+// sealed under the master key KeyID, Ciphertext the value sealed under the
+// data key. Nonces are prepended to both.
+type Envelope struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+// Keyring holds the master keys by ID. New envelopes use the active key, the
+// others are kept so existing envelopes still open until they are rewrapped.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// NewKeyring builds a keyring from 32-byte master keys, active naming the one
+// new envelopes are sealed with.
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, active)
+	}
+
+	k := &Keyring{active: active, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+
+	return k, nil
+}
+
+// KeyringFromEnv reads WS_MASTER_KEYS, a comma-separated list of
+// "<id>:<base64 key>" where the first entry is the active key. It returns
+// nil and no error when unset.
+func KeyringFromEnv() (*Keyring, error) {
+	raw := os.Getenv("WS_MASTER_KEYS")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var active string
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(raw, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid WS_MASTER_KEYS entry %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WS_MASTER_KEYS key %q: %w", id, err)
+		}
+		if active == "" {
+			active = id
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(active, keys)
+}
+
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Seal encrypts plaintext under a fresh data key. aad binds the envelope to
+// its context, such as the column it is stored in, and must be given to Open.
+func (k *Keyring) Seal(plaintext, aad []byte) (Envelope, error) {
+	if k == nil {
+		return Envelope{}, ErrNoKeyring
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Envelope{}, err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	ciphertext, err := seal(data, plaintext, aad)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	wrapped, err := seal(k.keys[k.active], dataKey, aad)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{KeyID: k.active, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+func (k *Keyring) Open(e Envelope, aad []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(e, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(data, e.Ciphertext, aad)
+}
+
+// Rewrap re-encrypts the data key of an envelope under the active master
+// key, leaving the ciphertext untouched. It reports false when the envelope
+// already uses the active key.
+func (k *Keyring) Rewrap(e Envelope, aad []byte) (Envelope, bool, error) {
+	if k == nil {
+		return Envelope{}, false, ErrNoKeyring
+	}
+	if e.KeyID == k.active {
+		return e, false, nil
+	}
+
+	dataKey, err := k.unwrap(e, aad)
+	if err != nil {
+		return Envelope{}, false, err
+	}
+
+	wrapped, err := seal(k.keys[k.active], dataKey, aad)
+	if err != nil {
+		return Envelope{}, false, err
+	}
+
+	return Envelope{KeyID: k.active, WrappedKey: wrapped, Ciphertext: e.Ciphertext}, true, nil
+}
+
+func (k *Keyring) unwrap(e Envelope, aad []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	master, ok := k.keys[e.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, e.KeyID)
+	}
+
+	return open(master, e.WrappedKey, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", dataKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
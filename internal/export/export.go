@@ -0,0 +1,132 @@
+package export
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/redact"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	FormatHTML = "html"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var roomTemplate = template.Must(template.New("room.html.tmpl").Funcs(template.FuncMap{
+	"videoLink":      VideoLink,
+	"videoTimestamp": VideoTimestamp,
+}).ParseFS(templates, "templates/room.html.tmpl"))
+
+// Transcript is everything an export needs about a room, loaded once and
+// rendered into any format.
+type Transcript struct {
+	Room        pg.Room
+	Messages    []pg.Message
+	GeneratedAt time.Time
+}
+
+func (t Transcript) TotalReactions() int64 {
+	var total int64
+	for _, message := range t.Messages {
+		total += message.ReactionCount
+	}
+
+	return total
+}
+
+func (t Transcript) AnsweredCount() int {
+	count := 0
+	for _, message := range t.Messages {
+		if message.Answered {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Redact masks personal data in the questions and answers, leaving t as is.
+func (t Transcript) Redact(r *redact.Redactor) Transcript {
+	messages := make([]pg.Message, len(t.Messages))
+	for i, message := range t.Messages {
+		message.Message = r.String(message.Message)
+		message.AnswerText = r.String(message.AnswerText)
+		messages[i] = message
+	}
+	t.Messages = messages
+
+	return t
+}
+
+// VideoTimestamp formats an offset into the stream as 1:02:03, or 2:03 under
+// an hour.
+func VideoTimestamp(offset pgtype.Int4) string {
+	seconds := int(offset.Int32)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// VideoLink points videoURL at the offset the answer was given, with the t
+// parameter YouTube and Twitch both understand. Without an offset the URL is
+// returned as is, and an invalid one yields "".
+func VideoLink(videoURL string, offset pgtype.Int4) string {
+	u, err := url.Parse(videoURL)
+	if videoURL == "" || err != nil {
+		return ""
+	}
+	if !offset.Valid {
+		return u.String()
+	}
+
+	seconds := int(offset.Int32)
+	t := fmt.Sprintf("%ds", seconds%60)
+	if seconds >= 60 {
+		t = fmt.Sprintf("%dm", seconds/60%60) + t
+	}
+	if seconds >= 3600 {
+		t = fmt.Sprintf("%dh", seconds/3600) + t
+	}
+	query := u.Query()
+	query.Set("t", t)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Load reads the room and its messages in chronological order.
+func Load(ctx context.Context, q pg.Querier, roomID uuid.UUID) (Transcript, error) {
+	room, err := q.GetRoom(ctx, roomID)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	messages, err := q.GetRoomMessages(ctx, roomID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	return Transcript{Room: room, Messages: messages, GeneratedAt: time.Now().UTC()}, nil
+}
+
+// RenderHTML writes a self-contained page: styles are inlined and nothing is
+// fetched, so the file can be archived as is.
+func RenderHTML(w io.Writer, t Transcript) error {
+	return roomTemplate.Execute(w, t)
+}
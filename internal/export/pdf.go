@@ -0,0 +1,94 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	FormatPDF = "pdf"
+)
+
+// RenderPDF writes the transcript as Q&A pairs. Core fonts only cover
+// cp1252, so text outside it is approximated by the translator.
+func RenderPDF(w io.Writer, t Transcript) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(t.Room.Theme, true)
+	pdf.SetCreator("week-tech-go-server", true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(113, 113, 122)
+		pdf.CellFormat(0, 10, fmt.Sprintf("%d/{nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 8, tr(t.Room.Theme), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(113, 113, 122)
+	pdf.MultiCell(0, 5, fmt.Sprintf(
+		"Room %s - %d questions, %d answered, %d reactions - exported %s",
+		t.Room.Code,
+		len(t.Messages),
+		t.AnsweredCount(),
+		t.TotalReactions(),
+		t.GeneratedAt.Format("2006-01-02 15:04 MST"),
+	), "", "L", false)
+	pdf.Ln(6)
+
+	if len(t.Messages) == 0 {
+		pdf.SetFont("Helvetica", "I", 11)
+		pdf.SetTextColor(24, 24, 27)
+		pdf.MultiCell(0, 6, "No questions were asked in this room.", "", "L", false)
+	}
+
+	for i, message := range t.Messages {
+		pdf.SetTextColor(24, 24, 27)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.MultiCell(0, 6, tr(fmt.Sprintf("Q%d. %s", i+1, message.Message)), "", "L", false)
+
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(113, 113, 122)
+		details := fmt.Sprintf("%d reactions - %s", message.ReactionCount, message.CreatedAt.Format("15:04"))
+		if message.AuthorName != "" {
+			details = tr(message.AuthorName) + " - " + details
+		}
+		pdf.MultiCell(0, 5, details, "", "L", false)
+
+		if message.Answered {
+			pdf.SetTextColor(24, 24, 27)
+			pdf.SetFont("Helvetica", "", 11)
+			answer := message.AnswerText
+			if answer == "" {
+				answer = "(answered live)"
+			}
+			pdf.MultiCell(0, 6, tr("A: "+answer), "", "L", false)
+			if link := VideoLink(message.AnswerVideoUrl, message.AnswerVideoOffset); link != "" {
+				pdf.SetFont("Helvetica", "", 9)
+				pdf.SetTextColor(37, 99, 235)
+				text := "Watch the answer"
+				if message.AnswerVideoOffset.Valid {
+					text += " at " + VideoTimestamp(message.AnswerVideoOffset)
+				}
+				pdf.WriteLinkString(5, text, link)
+				pdf.Ln(5)
+			} else if message.AnswerVideoOffset.Valid {
+				pdf.SetFont("Helvetica", "", 9)
+				pdf.SetTextColor(113, 113, 122)
+				pdf.MultiCell(0, 5, "Answered at "+VideoTimestamp(message.AnswerVideoOffset)+" into the stream", "", "L", false)
+			}
+		}
+		pdf.Ln(4)
+	}
+
+	return pdf.Output(w)
+}
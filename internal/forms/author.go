@@ -0,0 +1,58 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luiz504/week-tech-go-server/internal/profanity"
+)
+
+const (
+	PostingModeOptional  = "optional"
+	PostingModeNamed     = "named"
+	PostingModeAnonymous = "anonymous"
+)
+
+const (
+	MinAuthorNameLength = 2
+	MaxAuthorNameLength = 50
+)
+
+func IsPostingMode(mode string) bool {
+	switch mode {
+	case PostingModeOptional, PostingModeNamed, PostingModeAnonymous:
+		return true
+	}
+
+	return false
+}
+
+// ValidateAuthorName applies the room posting mode to the submitted display
+// name and returns the trimmed name to store.
+func ValidateAuthorName(mode string, name string) (string, []FieldError) {
+	name = strings.TrimSpace(name)
+
+	switch {
+	case mode == PostingModeAnonymous && name != "":
+		return "", []FieldError{{Field: "author_name", Message: "this room only accepts anonymous messages"}}
+	case name == "":
+		if mode == PostingModeNamed {
+			return "", []FieldError{{Field: "author_name", Message: "is required"}}
+		}
+		return "", nil
+	}
+
+	length := utf8.RuneCountInString(name)
+	if length < MinAuthorNameLength || length > MaxAuthorNameLength {
+		return "", []FieldError{{
+			Field:   "author_name",
+			Message: fmt.Sprintf("must be between %d and %d characters", MinAuthorNameLength, MaxAuthorNameLength),
+		}}
+	}
+	if profanity.Contains(name) {
+		return "", []FieldError{{Field: "author_name", Message: "is not allowed"}}
+	}
+
+	return name, nil
+}
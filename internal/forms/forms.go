@@ -0,0 +1,150 @@
+package forms
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FieldTypeText   = "text"
+	FieldTypeSelect = "select"
+)
+
+const (
+	MaxFields          = 10
+	DefaultMaxLength   = 255
+	maxNameLength      = 32
+	maxOptionsPerField = 50
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Field describes one extra input a room collects alongside each message.
+type Field struct {
+	Name      string   `json:"name"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	Options   []string `json:"options,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+}
+
+type Schema []Field
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ParseSchema decodes a stored schema. An empty document is an empty schema.
+func ParseSchema(data []byte) (Schema, error) {
+	if len(data) == 0 {
+		return Schema{}, nil
+	}
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		schema = Schema{}
+	}
+
+	return schema, nil
+}
+
+// Check validates the schema definition itself, as sent on room creation.
+func (s Schema) Check() []FieldError {
+	var errs []FieldError
+	if len(s) > MaxFields {
+		errs = append(errs, FieldError{Field: "fields", Message: fmt.Sprintf("at most %d fields are allowed", MaxFields)})
+	}
+
+	seen := make(map[string]bool, len(s))
+	for i, field := range s {
+		path := fmt.Sprintf("fields[%d]", i)
+		if len(field.Name) > maxNameLength || !fieldNamePattern.MatchString(field.Name) {
+			errs = append(errs, FieldError{Field: path + ".name", Message: "must be lowercase letters, digits or underscores"})
+		}
+		if seen[field.Name] {
+			errs = append(errs, FieldError{Field: path + ".name", Message: "must be unique"})
+		}
+		seen[field.Name] = true
+
+		switch field.Type {
+		case FieldTypeText:
+			if len(field.Options) > 0 {
+				errs = append(errs, FieldError{Field: path + ".options", Message: "only allowed on select fields"})
+			}
+		case FieldTypeSelect:
+			if len(field.Options) == 0 || len(field.Options) > maxOptionsPerField {
+				errs = append(errs, FieldError{Field: path + ".options", Message: fmt.Sprintf("must have between 1 and %d options", maxOptionsPerField)})
+			}
+		default:
+			errs = append(errs, FieldError{Field: path + ".type", Message: "must be text or select"})
+		}
+
+		if field.MaxLength < 0 || field.MaxLength > DefaultMaxLength {
+			errs = append(errs, FieldError{Field: path + ".max_length", Message: fmt.Sprintf("must be between 0 and %d", DefaultMaxLength)})
+		}
+	}
+
+	return errs
+}
+
+// Validate checks submitted values against the schema and returns the
+// trimmed values to store. Unknown fields are rejected.
+func (s Schema) Validate(values map[string]string) (map[string]string, []FieldError) {
+	var errs []FieldError
+	clean := make(map[string]string, len(s))
+
+	known := make(map[string]bool, len(s))
+	for _, field := range s {
+		known[field.Name] = true
+		path := "fields." + field.Name
+
+		value := strings.TrimSpace(values[field.Name])
+		if value == "" {
+			if field.Required {
+				errs = append(errs, FieldError{Field: path, Message: "is required"})
+			}
+			continue
+		}
+
+		maxLength := field.MaxLength
+		if maxLength == 0 {
+			maxLength = DefaultMaxLength
+		}
+		if utf8.RuneCountInString(value) > maxLength {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at most %d characters", maxLength)})
+			continue
+		}
+
+		if field.Type == FieldTypeSelect && !contains(field.Options, value) {
+			errs = append(errs, FieldError{Field: path, Message: "must be one of the field options"})
+			continue
+		}
+
+		clean[field.Name] = value
+	}
+
+	for name := range values {
+		if !known[name] {
+			errs = append(errs, FieldError{Field: "fields." + name, Message: "is not defined for this room"})
+		}
+	}
+
+	return clean, errs
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+
+	return false
+}
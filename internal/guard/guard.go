@@ -0,0 +1,146 @@
+// Package guard holds the middlewares that protect the service during
+// traffic spikes: bounded request bodies, a cap on in-flight requests and a
+// cap on concurrent websocket connections per client IP.
+package guard
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultMaxBodyBytes = 1 << 20
+
+// Limits configures the guards, a 0 cap meaning unlimited.
+type Limits struct {
+	MaxBodyBytes      int64
+	MaxInFlight       int64
+	MaxSocketsPerIP   int64
+	RetryAfterSeconds int64
+}
+
+// LimitsFromEnv reads WS_MAX_BODY_BYTES (1 MiB by default),
+// WS_MAX_INFLIGHT_REQUESTS, WS_MAX_SOCKETS_PER_IP and WS_GUARD_RETRY_AFTER
+// (1 second by default, sent along 503s).
+func LimitsFromEnv() Limits {
+	return Limits{
+		MaxBodyBytes:      limitFromEnv("WS_MAX_BODY_BYTES", defaultMaxBodyBytes),
+		MaxInFlight:       limitFromEnv("WS_MAX_INFLIGHT_REQUESTS", 0),
+		MaxSocketsPerIP:   limitFromEnv("WS_MAX_SOCKETS_PER_IP", 0),
+		RetryAfterSeconds: limitFromEnv("WS_GUARD_RETRY_AFTER", 1),
+	}
+}
+
+func limitFromEnv(key string, fallback int64) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit < 0 {
+		slog.Warn("invalid "+key+", using default", "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return limit
+}
+
+// isLongLived reports websocket upgrades and server-sent event streams.
+func isLongLived(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// MaxBytes bounds every request body. Handlers that need a tighter bound can
+// still wrap the body again.
+func MaxBytes(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// InFlight sheds requests beyond limit with a 503 instead of queueing them.
+// Websocket upgrades and event streams live as long as the connection, so
+// they are left to PerIPSockets and the connection quotas.
+func InFlight(limit, retryAfterSeconds int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		slots := make(chan struct{}, limit)
+		retryAfter := strconv.FormatInt(retryAfterSeconds, 10)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isLongLived(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", retryAfter)
+				http.Error(w, "server is busy", http.StatusServiceUnavailable)
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PerIPSockets caps the websockets and event streams a single client address
+// holds open. It wraps handlers that return when the connection closes.
+func PerIPSockets(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		var mu sync.Mutex
+		open := make(map[string]int64)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			//? RemoteAddr may already have lost its port to the client IP middlewares
+			ip := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+
+			mu.Lock()
+			if open[ip] >= limit {
+				mu.Unlock()
+				http.Error(w, "too many connections from this address", http.StatusTooManyRequests)
+				return
+			}
+			open[ip]++
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if open[ip]--; open[ip] <= 0 {
+					delete(open, ip)
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
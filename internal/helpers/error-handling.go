@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"encoding/json"
 	"log/slog"
 	"net/http"
 )
@@ -15,3 +16,18 @@ func LogErrorAndRespond(
 	slog.Warn(logMessage, "error", err)
 	http.Error(w, responseMessage, code)
 }
+
+// RespondValidationErrors writes a 422 with the structured errors under "errors".
+func RespondValidationErrors(w http.ResponseWriter, errs any) {
+	data, err := json.Marshal(map[string]any{"errors": errs})
+	if err != nil {
+		LogErrorAndRespond(w, "failed to marshal validation errors", err, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if _, err := w.Write(data); err != nil {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package ingest maps the webhook payloads of forms tools, like Typeform's or
+// one posted by a Google Forms Apps Script, to questions. A Mapping holds a
+// template per part of the question: text with {{path}} placeholders that are
+// filled in from the payload.
+//
+// A path reads keys separated by dots, numbers indexing arrays, like
+// "data.answers.0.text". A key followed by [path=value] picks the first
+// element of an array whose path equals value instead, for payloads that list
+// answers in no fixed order: "form_response.answers[field.ref=question].text".
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+)
+
+const (
+	MaxTemplateLength = 1000
+	MaxFieldTemplates = 20
+)
+
+var errNotText = errors.New("is an object, not text")
+
+// Mapping is how a room reads questions off a webhook's payloads. Message is
+// required; ExternalID, when set, keys the deliveries so a retried one doesn't
+// post its question twice.
+type Mapping struct {
+	Message    string            `json:"message"`
+	AuthorName string            `json:"author_name,omitempty"`
+	ExternalID string            `json:"external_id,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// Question is what a Mapping read from a payload.
+type Question struct {
+	Message    string
+	AuthorName string
+	ExternalID string
+	Fields     map[string]string
+}
+
+// Check reports the templates that don't parse.
+func (m Mapping) Check() []forms.FieldError {
+	var errs []forms.FieldError
+	check := func(field, text string, required bool) {
+		if strings.TrimSpace(text) == "" {
+			if required {
+				errs = append(errs, forms.FieldError{Field: field, Message: "must not be empty"})
+			}
+			return
+		}
+		if len(text) > MaxTemplateLength {
+			errs = append(errs, forms.FieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", MaxTemplateLength)})
+			return
+		}
+		if _, err := parseTemplate(text); err != nil {
+			errs = append(errs, forms.FieldError{Field: field, Message: err.Error()})
+		}
+	}
+
+	check("mapping.message", m.Message, true)
+	check("mapping.author_name", m.AuthorName, false)
+	check("mapping.external_id", m.ExternalID, false)
+	if len(m.Fields) > MaxFieldTemplates {
+		errs = append(errs, forms.FieldError{Field: "mapping.fields", Message: fmt.Sprintf("must have at most %d fields", MaxFieldTemplates)})
+	}
+	for name, text := range m.Fields {
+		check("mapping.fields."+name, text, true)
+	}
+
+	return errs
+}
+
+// Apply fills the templates in from payload, a decoded JSON value. Paths the
+// payload lacks read as empty.
+func (m Mapping) Apply(payload any) (Question, []forms.FieldError) {
+	var errs []forms.FieldError
+	apply := func(field, text string) string {
+		if text == "" {
+			return ""
+		}
+		t, err := parseTemplate(text)
+		if err == nil {
+			text, err = t.render(payload)
+		}
+		if err != nil {
+			errs = append(errs, forms.FieldError{Field: field, Message: err.Error()})
+		}
+		return strings.TrimSpace(text)
+	}
+
+	q := Question{
+		Message:    apply("message", m.Message),
+		AuthorName: apply("author_name", m.AuthorName),
+		ExternalID: apply("external_id", m.ExternalID),
+	}
+	if len(m.Fields) > 0 {
+		q.Fields = make(map[string]string, len(m.Fields))
+		for name, text := range m.Fields {
+			if value := apply("fields."+name, text); value != "" {
+				q.Fields[name] = value
+			}
+		}
+	}
+	if q.Message == "" && len(errs) == 0 {
+		errs = append(errs, forms.FieldError{Field: "message", Message: "the payload has no question"})
+	}
+
+	return q, errs
+}
+
+// template is literal text interleaved with paths, parts[i] coming before
+// paths[i].
+type template struct {
+	parts []string
+	paths []path
+}
+
+func parseTemplate(text string) (template, error) {
+	var t template
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			t.parts = append(t.parts, text)
+			return t, nil
+		}
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			return template{}, errors.New("has an unclosed {{")
+		}
+		p, err := parsePath(strings.TrimSpace(text[start+2 : start+end]))
+		if err != nil {
+			return template{}, err
+		}
+		t.parts = append(t.parts, text[:start])
+		t.paths = append(t.paths, p)
+		text = text[start+end+2:]
+	}
+}
+
+func (t template) render(payload any) (string, error) {
+	var b strings.Builder
+	for i, part := range t.parts {
+		b.WriteString(part)
+		if i == len(t.paths) {
+			break
+		}
+		value, err := text(t.paths[i].lookup(payload))
+		if err != nil {
+			return "", fmt.Errorf("{{%s}} %w", t.paths[i].raw, err)
+		}
+		b.WriteString(value)
+	}
+
+	return b.String(), nil
+}
+
+// step reads a key, or an index, of a value. With where set it then picks
+// the first element matching it.
+type step struct {
+	key   string
+	where *match
+}
+
+type match struct {
+	path  path
+	value string
+}
+
+type path struct {
+	raw   string
+	steps []step
+}
+
+func parsePath(raw string) (path, error) {
+	if raw == "" {
+		return path{}, errors.New("has an empty {{}}")
+	}
+
+	p := path{raw: raw}
+	for rest := raw; rest != ""; {
+		var segment string
+		if i := strings.IndexAny(rest, ".["); i < 0 {
+			segment, rest = rest, ""
+		} else {
+			segment, rest = rest[:i], rest[i:]
+		}
+		if segment == "" {
+			return path{}, fmt.Errorf("{{%s}} has an empty key", raw)
+		}
+		s := step{key: segment}
+
+		if condition, ok := strings.CutPrefix(rest, "["); ok {
+			end := strings.Index(condition, "]")
+			if end < 0 {
+				return path{}, fmt.Errorf("{{%s}} has an unclosed [", raw)
+			}
+			key, value, ok := strings.Cut(condition[:end], "=")
+			if !ok || strings.ContainsAny(key, "[]") {
+				return path{}, fmt.Errorf("{{%s}} must select with [path=value]", raw)
+			}
+			matchPath, err := parsePath(strings.TrimSpace(key))
+			if err != nil {
+				return path{}, err
+			}
+			s.where = &match{path: matchPath, value: strings.TrimSpace(value)}
+			rest = condition[end+1:]
+		}
+		p.steps = append(p.steps, s)
+
+		if rest == "" {
+			break
+		}
+		var ok bool
+		if rest, ok = strings.CutPrefix(rest, "."); !ok || rest == "" {
+			return path{}, fmt.Errorf("{{%s}} is not a path", raw)
+		}
+	}
+
+	return p, nil
+}
+
+// lookup follows the path through value, nil where it leads nowhere.
+func (p path) lookup(value any) any {
+	for _, s := range p.steps {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[s.key]
+		case []any:
+			i, err := strconv.Atoi(s.key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+
+		if s.where != nil {
+			items, _ := value.([]any)
+			value = nil
+			for _, item := range items {
+				if got, err := text(s.where.path.lookup(item)); err == nil && got == s.where.value {
+					value = item
+					break
+				}
+			}
+		}
+	}
+
+	return value
+}
+
+// text is how a value reads in a question. Lists, like the choices of a
+// multiple choice answer, read comma separated.
+func text(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := text(item)
+			if err != nil {
+				return "", err
+			}
+			if s != "" {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ", "), nil
+	}
+
+	return "", errNotText
+}
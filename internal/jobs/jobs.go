@@ -0,0 +1,245 @@
+// Package jobs runs background work persisted in the jobs table, so it
+// outlives the request that asked for it and survives restarts. Any number of
+// instances can run the same queue: claiming a job locks it for one of them.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+
+	// MaxAttempts is how many times a job runs before it is marked failed.
+	MaxAttempts = 3
+
+	defaultPollInterval = 5 * time.Second
+	defaultTimeout      = 5 * time.Minute
+)
+
+// Output is what a finished job leaves for download.
+type Output struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+}
+
+// Handler does the work of a job kind from the payload it was enqueued with.
+type Handler func(ctx context.Context, payload []byte) (Output, error)
+
+// Runner claims queued jobs and hands them to the handler of their kind.
+type Runner struct {
+	q            pg.Querier
+	pollInterval time.Duration
+	timeout      time.Duration
+	wake         chan struct{}
+	paused       atomic.Bool
+	// elector, when set, is who gets to schedule periodic jobs.
+	elector *Elector
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewRunner(q pg.Querier, pollInterval, timeout time.Duration) *Runner {
+	return &Runner{
+		q:            q,
+		pollInterval: pollInterval,
+		timeout:      timeout,
+		wake:         make(chan struct{}, 1),
+		handlers:     make(map[string]Handler),
+	}
+}
+
+// FromEnv reads WS_JOBS_POLL_INTERVAL and WS_JOBS_TIMEOUT, the latter also
+// being how long a running job can go before another instance reclaims it.
+func FromEnv(q pg.Querier) *Runner {
+	return NewRunner(q, durationFromEnv("WS_JOBS_POLL_INTERVAL", defaultPollInterval), durationFromEnv("WS_JOBS_TIMEOUT", defaultTimeout))
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		slog.Warn("invalid duration, using the default", "name", name, "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return d
+}
+
+// Register sets the handler of a job kind. Every instance should register the
+// same kinds, a job claimed by one without its handler counts as failed.
+func (r *Runner) Register(kind string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[kind] = handler
+}
+
+// Enqueue persists a job, payload being marshalled to JSON, and wakes the
+// local runner so it doesn't wait for the next poll.
+func (r *Runner) Enqueue(ctx context.Context, kind string, organizationID uuid.NullUUID, payload any) (pg.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return pg.Job{}, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	job, err := r.q.InsertJob(ctx, pg.InsertJobParams{Kind: kind, Payload: raw, OrganizationID: organizationID})
+	if err != nil {
+		return pg.Job{}, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Pause stops the runner from claiming and scheduling jobs until Resume, for
+// an instance that must not write, such as a standby. Jobs already running
+// finish.
+func (r *Runner) Pause() {
+	r.paused.Store(true)
+}
+
+// Resume undoes Pause and wakes the runner.
+func (r *Runner) Resume() {
+	r.paused.Store(false)
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ScheduleAsLeader has Schedule enqueue only while e leads, so periodic jobs
+// are scheduled by a single instance. Set it before calling Schedule.
+func (r *Runner) ScheduleAsLeader(e *Elector) {
+	r.elector = e
+}
+
+// Schedule enqueues a job of kind every interval until ctx is done. It skips
+// a round while one is still queued or running, so instances sharing the
+// queue don't pile up copies of the same periodic work, and every round
+// while another instance is the leader when ScheduleAsLeader was called.
+func (r *Runner) Schedule(ctx context.Context, kind string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if r.paused.Load() || (r.elector != nil && !r.elector.Leader()) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		inserted, err := r.q.InsertJobUnlessPending(ctx, pg.InsertJobUnlessPendingParams{Kind: kind, Payload: []byte("{}")})
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to schedule job", "kind", kind, "error", err)
+			}
+		} else if inserted > 0 {
+			select {
+			case r.wake <- struct{}{}:
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run works through the queue until ctx is done, polling when it runs dry.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		//* Drain the queue before waiting again
+		for ctx.Err() == nil && r.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// runNext claims and runs one job, reporting false when there was none.
+func (r *Runner) runNext(ctx context.Context) bool {
+	if r.paused.Load() {
+		return false
+	}
+
+	job, err := r.q.ClaimJob(ctx, time.Now().Add(-r.timeout))
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && ctx.Err() == nil {
+			slog.Error("failed to claim job", "error", err)
+		}
+		return false
+	}
+
+	r.mu.RLock()
+	handler, ok := r.handlers[job.Kind]
+	r.mu.RUnlock()
+
+	var output Output
+	if ok {
+		jobCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		output, err = handler(jobCtx, job.Payload)
+		cancel()
+	} else {
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	if err != nil {
+		slog.Error("job failed", "job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts, "error", err)
+		if err := r.q.FailJob(ctx, pg.FailJobParams{MaxAttempts: MaxAttempts, Error: err.Error(), ID: job.ID}); err != nil {
+			slog.Error("failed to record job failure", "job_id", job.ID, "error", err)
+		}
+		return true
+	}
+
+	err = r.q.CompleteJob(ctx, pg.CompleteJobParams{
+		ID:          job.ID,
+		Output:      output.Data,
+		ContentType: output.ContentType,
+		Filename:    output.Filename,
+	})
+	if err != nil {
+		slog.Error("failed to complete job", "job_id", job.ID, "error", err)
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/store"
+)
+
+const (
+	defaultLeaderCheckInterval = 5 * time.Second
+	leaderReleaseTimeout       = 5 * time.Second
+)
+
+// Locker takes named locks shared by every instance, as store.Store does with
+// Postgres advisory locks.
+type Locker interface {
+	TryLock(ctx context.Context, name string) (store.Lock, error)
+}
+
+// Elector makes one instance the leader of those campaigning under the same
+// name, for work that must run once however many replicas there are. The
+// leader is whoever holds the lock: it's given up on shutdown and lost with
+// the connection holding it, the others taking over on their next check.
+type Elector struct {
+	locker   Locker
+	name     string
+	interval time.Duration
+	leader   atomic.Bool
+}
+
+func NewElector(locker Locker, name string, interval time.Duration) *Elector {
+	return &Elector{locker: locker, name: name, interval: interval}
+}
+
+// ElectorFromEnv reads WS_LEADER_CHECK_INTERVAL, how often followers try to
+// take over and the leader checks it still holds the lock.
+func ElectorFromEnv(locker Locker, name string) *Elector {
+	return NewElector(locker, name, durationFromEnv("WS_LEADER_CHECK_INTERVAL", defaultLeaderCheckInterval))
+}
+
+// Leader reports whether this instance leads right now. It can stop leading
+// at any time, work started as the leader may overlap a new one's briefly.
+func (e *Elector) Leader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns until ctx is done, releasing the lock on the way out.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	var lock store.Lock
+	defer func() {
+		e.leader.Store(false)
+		if lock != nil {
+			releaseCtx, cancel := context.WithTimeout(context.Background(), leaderReleaseTimeout)
+			defer cancel()
+			if err := lock.Release(releaseCtx); err != nil {
+				slog.Warn("failed to release leadership", "name", e.name, "error", err)
+			}
+		}
+	}()
+
+	for {
+		switch {
+		case lock == nil:
+			var err error
+			lock, err = e.locker.TryLock(ctx, e.name)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("failed to campaign for leadership", "name", e.name, "error", err)
+				}
+			} else if lock != nil {
+				e.leader.Store(true)
+				slog.Info("elected leader", "name", e.name)
+			}
+		case ctx.Err() == nil && !lock.Held(ctx):
+			e.leader.Store(false)
+			slog.Warn("lost leadership", "name", e.name)
+			_ = lock.Release(ctx)
+			lock = nil
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package language guesses the language of messages, for filtering and room
+// stats in rooms with an international audience.
+package language
+
+import (
+	"unicode/utf8"
+
+	"github.com/abadojack/whatlanggo"
+)
+
+const (
+	// minLength is the shortest text worth guessing, shorter ones are mostly noise.
+	minLength = 12
+	//? whatlanggo's own IsReliable rejects most one-line questions, this keeps
+	//? them while still dropping gibberish
+	minConfidence = 0.2
+)
+
+// Detect returns the ISO 639-1 code of the text's language, or "" when the
+// text is too short or the guess too uncertain.
+func Detect(text string) string {
+	if utf8.RuneCountInString(text) < minLength {
+		return ""
+	}
+
+	info := whatlanggo.Detect(text)
+	if info.Confidence < minConfidence {
+		return ""
+	}
+
+	return info.Lang.Iso6391()
+}
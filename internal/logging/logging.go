@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/luiz504/week-tech-go-server/internal/redact"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// * High-volume lines subject to WS_LOG_SAMPLE_RATE
+const (
+	MsgSubscriberConnected    = "new subscriber connected"
+	MsgSubscriberDisconnected = "subscriber disconnected"
+)
+
+var sampledMessages = []string{MsgSubscriberConnected, MsgSubscriberDisconnected}
+
+// Level is shared by the installed handler so it can be changed at runtime.
+var Level = new(slog.LevelVar)
+
+// Setup installs the default slog logger configured from the environment:
+// WS_LOG_LEVEL, WS_LOG_FORMAT (text or json), WS_LOG_FILE with optional
+// rotation through WS_LOG_MAX_SIZE_MB / WS_LOG_MAX_BACKUPS, and
+// WS_LOG_SAMPLE_RATE to keep one of every N websocket lifecycle lines.
+func Setup() {
+	if raw := os.Getenv("WS_LOG_LEVEL"); raw != "" {
+		if err := Level.UnmarshalText([]byte(raw)); err != nil {
+			slog.Warn("ignoring invalid WS_LOG_LEVEL", "value", raw)
+		}
+	}
+
+	var out io.Writer = os.Stderr
+	if path := os.Getenv("WS_LOG_FILE"); path != "" {
+		maxSize, _ := strconv.Atoi(os.Getenv("WS_LOG_MAX_SIZE_MB"))
+		if maxSize > 0 {
+			maxBackups, _ := strconv.Atoi(os.Getenv("WS_LOG_MAX_BACKUPS"))
+			out = &lumberjack.Logger{Filename: path, MaxSize: maxSize, MaxBackups: maxBackups}
+		} else {
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				slog.Error("failed to open log file, logging to stderr", "path", path, "error", err)
+			} else {
+				out = file
+			}
+		}
+	}
+
+	redactor, err := redact.FromEnv()
+	if err != nil {
+		slog.Error("invalid redaction settings, masking emails and phone numbers only", "error", err)
+		redactor = redact.New(true)
+	}
+
+	opts := &slog.HandlerOptions{Level: Level, ReplaceAttr: redactAttr(redactor)}
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("WS_LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+
+	if rate, _ := strconv.Atoi(os.Getenv("WS_LOG_SAMPLE_RATE")); rate > 1 {
+		handler = NewSamplingHandler(handler, rate, sampledMessages...)
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
+// redactAttr masks personal data in string attributes, errors and the
+// message itself, which carries the lines written through the log package.
+func redactAttr(r *redact.Redactor) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Value.Kind() {
+		case slog.KindString:
+			a.Value = slog.StringValue(r.String(a.Value.String()))
+		case slog.KindAny:
+			if err, ok := a.Value.Any().(error); ok {
+				a.Value = slog.StringValue(r.String(err.Error()))
+			}
+		}
+		return a
+	}
+}
+
+// SamplingHandler passes through one of every rate records for the given
+// messages, leaving every other record untouched.
+type SamplingHandler struct {
+	next     slog.Handler
+	rate     uint64
+	counters map[string]*atomic.Uint64
+}
+
+func NewSamplingHandler(next slog.Handler, rate int, messages ...string) *SamplingHandler {
+	counters := make(map[string]*atomic.Uint64, len(messages))
+	for _, msg := range messages {
+		counters[msg] = new(atomic.Uint64)
+	}
+	return &SamplingHandler{next: next, rate: uint64(rate), counters: counters}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	counter, ok := h.counters[r.Message]
+	if !ok {
+		return h.next.Handle(ctx, r)
+	}
+
+	if (counter.Add(1)-1)%h.rate != 0 {
+		return nil
+	}
+	r.AddAttrs(slog.Uint64("sample_rate", h.rate))
+	return h.next.Handle(ctx, r)
+}
+
+//? Derived handlers share the counters so sampling holds across loggers
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), rate: h.rate, counters: h.counters}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), rate: h.rate, counters: h.counters}
+}
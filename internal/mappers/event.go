@@ -0,0 +1,55 @@
+package mappers
+
+import (
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+type Event struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func MapEvent(event pg.Event) Event {
+	var startsAt, endsAt *time.Time
+	if event.StartsAt.Valid {
+		startsAt = &event.StartsAt.Time
+	}
+	if event.EndsAt.Valid {
+		endsAt = &event.EndsAt.Time
+	}
+
+	return Event{
+		ID:        event.ID.String(),
+		Name:      event.Name,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+func MapEvents(events []pg.Event) []Event {
+	mapped := make([]Event, 0, len(events))
+	for _, event := range events {
+		mapped = append(mapped, MapEvent(event))
+	}
+	return mapped
+}
+
+type Track struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int32  `json:"position"`
+}
+
+func MapTrack(track pg.Track) Track {
+	return Track{
+		ID:       track.ID.String(),
+		Name:     track.Name,
+		Position: track.Position,
+	}
+}
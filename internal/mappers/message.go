@@ -1,13 +1,73 @@
 package mappers
 
-import "github.com/luiz504/week-tech-go-server/internal/store/pg"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/export"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
 
 type RoomMessage struct {
-	ID            string `json:"id"`
-	RoomID        string `json:"room_id"`
-	Message       string `json:"message"`
-	ReactionCount int64  `json:"reaction_count"`
-	Answered      bool   `json:"answered"`
+	ID             string            `json:"id"`
+	RoomID         string            `json:"room_id"`
+	Message        string            `json:"message"`
+	ReactionCount  int64             `json:"reaction_count"`
+	Answered       bool              `json:"answered"`
+	AnswerText     string            `json:"answer_text,omitempty"`
+	AnswerAudioURL string            `json:"answer_audio_url,omitempty"`
+	Fields         map[string]string `json:"fields"`
+	AuthorName     string            `json:"author_name,omitempty"`
+	Pinned         bool              `json:"pinned"`
+	Tags           []string          `json:"tags"`
+	Language       string            `json:"language,omitempty"`
+	EditedAt       *time.Time        `json:"edited_at,omitempty"`
+	Version        int32             `json:"version"`
+	// * Where the answer can be watched, the link jumping to the moment
+	AnswerVideoURL       string `json:"answer_video_url,omitempty"`
+	AnswerVideoTimestamp *int32 `json:"answer_video_timestamp,omitempty"`
+	AnswerVideoLink      string `json:"answer_video_link,omitempty"`
+	// * Held for review, only ever seen by the author and the host
+	Held bool `json:"held,omitempty"`
+}
+
+func MapMessage(message pg.Message) RoomMessage {
+	fields := map[string]string{}
+	_ = json.Unmarshal(message.Fields, &fields)
+	tags := message.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	var editedAt *time.Time
+	if message.EditedAt.Valid {
+		editedAt = &message.EditedAt.Time
+	}
+	var videoTimestamp *int32
+	if message.AnswerVideoOffset.Valid {
+		videoTimestamp = &message.AnswerVideoOffset.Int32
+	}
+
+	return RoomMessage{
+		ID:             message.ID.String(),
+		RoomID:         message.RoomID.String(),
+		Message:        message.Message,
+		ReactionCount:  message.ReactionCount,
+		Answered:       message.Answered,
+		AnswerText:     message.AnswerText,
+		AnswerAudioURL: message.AnswerAudioUrl,
+		Fields:         fields,
+		AuthorName:     message.AuthorName,
+		Pinned:         message.Pinned,
+		Tags:           tags,
+		Language:       message.Language,
+		EditedAt:       editedAt,
+		Version:        message.Version,
+		Held:           message.HiddenAt.Valid,
+
+		AnswerVideoURL:       message.AnswerVideoUrl,
+		AnswerVideoTimestamp: videoTimestamp,
+		AnswerVideoLink:      export.VideoLink(message.AnswerVideoUrl, message.AnswerVideoOffset),
+	}
 }
 
 func MapMessageToRoomMessage(messages []pg.Message) []RoomMessage {
@@ -16,13 +76,32 @@ func MapMessageToRoomMessage(messages []pg.Message) []RoomMessage {
 	}
 	var roomMessages []RoomMessage
 	for _, message := range messages {
-		roomMessages = append(roomMessages, RoomMessage{
+		roomMessages = append(roomMessages, MapMessage(message))
+	}
+	return roomMessages
+}
+
+// PublicMessage is the trimmed view of a message served without auth.
+type PublicMessage struct {
+	ID            string `json:"id"`
+	Message       string `json:"message"`
+	ReactionCount int64  `json:"reaction_count"`
+	Answered      bool   `json:"answered"`
+	AnswerText    string `json:"answer_text,omitempty"`
+	AuthorName    string `json:"author_name,omitempty"`
+}
+
+func MapMessageToPublicMessage(messages []pg.Message) []PublicMessage {
+	publicMessages := make([]PublicMessage, 0, len(messages))
+	for _, message := range messages {
+		publicMessages = append(publicMessages, PublicMessage{
 			ID:            message.ID.String(),
-			RoomID:        message.RoomID.String(),
 			Message:       message.Message,
 			ReactionCount: message.ReactionCount,
 			Answered:      message.Answered,
+			AnswerText:    message.AnswerText,
+			AuthorName:    message.AuthorName,
 		})
 	}
-	return roomMessages
+	return publicMessages
 }
@@ -0,0 +1,72 @@
+package mappers
+
+import (
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+type Room struct {
+	ID             string       `json:"id"`
+	Code           string       `json:"code"`
+	Theme          string       `json:"theme"`
+	Description    string       `json:"description"`
+	Fields         forms.Schema `json:"fields"`
+	PostingMode    string       `json:"posting_mode"`
+	MaxSubscribers int32        `json:"max_subscribers"`
+	ArchivedAt     *time.Time   `json:"archived_at"`
+	ClosedAt       *time.Time   `json:"closed_at"`
+	TranslateTo    []string     `json:"translate_to"`
+	TrackID        string       `json:"track_id,omitempty"`
+	StartsAt       *time.Time   `json:"starts_at,omitempty"`
+	HostName       string       `json:"host_name,omitempty"`
+}
+
+func MapRoom(room pg.Room) Room {
+	schema, err := forms.ParseSchema(room.FormSchema)
+	if err != nil {
+		schema = forms.Schema{}
+	}
+
+	var archivedAt *time.Time
+	if room.ArchivedAt.Valid {
+		archivedAt = &room.ArchivedAt.Time
+	}
+
+	translateTo := room.TranslateTo
+	if translateTo == nil {
+		translateTo = []string{}
+	}
+
+	var closedAt *time.Time
+	if room.ClosedAt.Valid {
+		closedAt = &room.ClosedAt.Time
+	}
+
+	var startsAt *time.Time
+	if room.StartsAt.Valid {
+		startsAt = &room.StartsAt.Time
+	}
+
+	var trackID string
+	if room.TrackID.Valid {
+		trackID = room.TrackID.UUID.String()
+	}
+
+	return Room{
+		ID:             room.ID.String(),
+		Code:           room.Code,
+		Theme:          room.Theme,
+		Description:    room.Description,
+		Fields:         schema,
+		PostingMode:    room.PostingMode,
+		MaxSubscribers: room.MaxSubscribers,
+		ArchivedAt:     archivedAt,
+		ClosedAt:       closedAt,
+		TranslateTo:    translateTo,
+		TrackID:        trackID,
+		StartsAt:       startsAt,
+		HostName:       room.HostName,
+	}
+}
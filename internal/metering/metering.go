@@ -0,0 +1,101 @@
+package metering
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/tenant"
+)
+
+const (
+	MetricRequests  = "requests"
+	MetricWSSeconds = "ws_seconds"
+)
+
+// Period is the granularity usage is bucketed at before it is persisted.
+const Period = time.Hour
+
+type Record struct {
+	OrganizationID uuid.UUID
+	APIKeyID       uuid.NullUUID
+	Metric         string
+	PeriodStart    time.Time
+	Quantity       int64
+}
+
+type recordKey struct {
+	organizationID uuid.UUID
+	apiKeyID       uuid.NullUUID
+	metric         string
+	periodStart    time.Time
+}
+
+// Meter accumulates usage in memory so the hot path never waits on the
+// database. Drain hands the totals to whoever persists them.
+type Meter struct {
+	mu      sync.Mutex
+	pending map[recordKey]int64
+}
+
+func New() *Meter {
+	return &Meter{pending: make(map[recordKey]int64)}
+}
+
+func (m *Meter) Add(organizationID uuid.UUID, apiKeyID uuid.NullUUID, metric string, at time.Time, quantity int64) {
+	if quantity <= 0 {
+		return
+	}
+	key := recordKey{
+		organizationID: organizationID,
+		apiKeyID:       apiKeyID,
+		metric:         metric,
+		periodStart:    at.UTC().Truncate(Period),
+	}
+
+	m.mu.Lock()
+	m.pending[key] += quantity
+	m.mu.Unlock()
+}
+
+// Drain returns and clears the pending usage.
+func (m *Meter) Drain() []Record {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[recordKey]int64)
+	m.mu.Unlock()
+
+	records := make([]Record, 0, len(pending))
+	for key, quantity := range pending {
+		records = append(records, Record{
+			OrganizationID: key.organizationID,
+			APIKeyID:       key.apiKeyID,
+			Metric:         key.metric,
+			PeriodStart:    key.periodStart,
+			Quantity:       quantity,
+		})
+	}
+
+	return records
+}
+
+// Restore puts back records that failed to persist so the next flush retries them.
+func (m *Meter) Restore(records []Record) {
+	for _, record := range records {
+		m.Add(record.OrganizationID, record.APIKeyID, record.Metric, record.PeriodStart, record.Quantity)
+	}
+}
+
+// Middleware counts every request made with an API key against its organization.
+func Middleware(m *Meter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := tenant.FromContext(r.Context()); ok {
+				m.Add(key.OrganizationID, uuid.NullUUID{UUID: key.ID, Valid: true}, MetricRequests, time.Now(), 1)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
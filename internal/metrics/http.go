@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var HTTPRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "request_seconds",
+	Help:      "Time taken to serve an HTTP request, by route pattern, method and status.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"route", "method", "status"})
+
+// routeUnmatched labels requests no route matched, so probing random paths
+// can't grow the label set.
+const routeUnmatched = "unmatched"
+
+// Middleware times requests under their chi route pattern rather than the
+// path, keeping one series per route. Websocket upgrades and event streams
+// are left out, their duration being the length of the session.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		if strings.HasPrefix(ww.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+
+		route := routeUnmatched
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		HTTPRequestSeconds.WithLabelValues(route, r.Method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wsrs"
+
+var (
+	FrameWriteSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "frame_write_seconds",
+		Help:      "Time spent writing a single websocket frame to a client.",
+		Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	FramesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "frames_dropped_total",
+		Help:      "Websocket frames that were not delivered, by reason.",
+	}, []string{"reason"})
+
+	FanoutSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "broadcast_fanout_size",
+		Help:      "Number of connections a single room broadcast was queued to.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 9),
+	})
+
+	FanoutSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "broadcast_fanout_seconds",
+		Help:      "Time taken to queue a single room broadcast to all its recipients.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	WriteQueueOverflows = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "write_queue_overflows_total",
+		Help:      "Connections disconnected because their write queue was full.",
+	})
+
+	ClientRTTSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "client_rtt_seconds",
+		Help:      "Round trip time measured from client heartbeats.",
+		Buckets:   []float64{.01, .025, .05, .1, .2, .3, .5, .75, 1, 2, 5},
+	})
+
+	ConnectionsReaped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "connections_reaped_total",
+		Help:      "Websocket connections removed after missing their pong deadline.",
+	})
+
+	EventLogPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event_log",
+		Name:      "pruned_total",
+		Help:      "Room events deleted from the event log, by the retention rule that expired them.",
+	}, []string{"rule"})
+
+	EventLogPrunedAt = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "event_log",
+		Name:      "last_prune_timestamp_seconds",
+		Help:      "Unix time of the last successful event log pruning.",
+	})
+
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Room webhook delivery attempts, by result: delivered, retried or failed for good.",
+	}, []string{"result"})
+
+	DeadLetters = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "dead_letters",
+		Name:      "pending",
+		Help:      "Async work that ran out of attempts and awaits a retry or discard, by source.",
+	}, []string{"source"})
+
+	DeadLetterOldestAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "dead_letters",
+		Name:      "oldest_age_seconds",
+		Help:      "Age of the oldest dead letter, by source, 0 when there is none.",
+	}, []string{"source"})
+
+	RoomSubscribers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "room_subscribers",
+		Help:      "Connections subscribed to a room on this instance, waiting ones included.",
+	}, []string{"room_id"})
+)
+
+// * Drop reasons
+const (
+	DropWriteError = "write_error"
+	DropClosing    = "closing"
+	DropInjected   = "injected"
+	DropOverflow   = "overflow"
+)
+
+// ObserveWrite records the latency of a frame write and counts it as dropped
+// when it failed. The request ID is attached as an exemplar rather than a
+// label to keep cardinality bounded.
+func ObserveWrite(start time.Time, err error, requestID string) {
+	elapsed := time.Since(start).Seconds()
+	if requestID == "" {
+		FrameWriteSeconds.Observe(elapsed)
+	} else {
+		FrameWriteSeconds.(prometheus.ExemplarObserver).ObserveWithExemplar(elapsed, prometheus.Labels{"request_id": requestID})
+	}
+
+	if err != nil {
+		FramesDropped.WithLabelValues(DropWriteError).Inc()
+	}
+}
+
+// Handler serves the default registry, negotiating OpenMetrics so exemplars are exposed.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// StartOTLP pushes the instruments defined here to an OTLP collector when
+// WS_METRICS_OTLP is true, bridging the Prometheus registry so both paths
+// export the same metrics. Endpoint, headers, interval and resource come
+// from the standard OTEL_* variables. The returned func flushes and stops
+// the exporter, and is a no-op when disabled.
+func StartOTLP(ctx context.Context) (func(context.Context) error, error) {
+	if enabled, _ := strconv.ParseBool(os.Getenv("WS_METRICS_OTLP")); !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithProducer(otelprom.NewMetricProducer()))
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	return provider.Shutdown, nil
+}
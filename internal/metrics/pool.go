@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the pool statistics at scrape time, so they are never
+// stale and cost nothing between scrapes.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquires             *prometheus.Desc
+	acquireSeconds       *prometheus.Desc
+	emptyAcquires        *prometheus.Desc
+	canceledAcquires     *prometheus.Desc
+	newConns             *prometheus.Desc
+	lifetimeDestroyConns *prometheus.Desc
+	idleDestroyConns     *prometheus.Desc
+}
+
+// RegisterPool exposes the statistics of the database pool.
+func RegisterPool(pool *pgxpool.Pool) error {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return prometheus.Register(&poolCollector{
+		pool: pool,
+
+		acquiredConns:        desc("acquired_connections", "Connections currently checked out of the pool."),
+		idleConns:            desc("idle_connections", "Connections idle in the pool."),
+		constructingConns:    desc("constructing_connections", "Connections being established."),
+		totalConns:           desc("connections", "Connections open in the pool."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		acquires:             desc("acquires_total", "Successful connection acquisitions."),
+		acquireSeconds:       desc("acquire_seconds_total", "Time spent acquiring connections."),
+		emptyAcquires:        desc("empty_acquires_total", "Acquisitions that had to wait for a connection."),
+		canceledAcquires:     desc("canceled_acquires_total", "Acquisitions canceled by their context."),
+		newConns:             desc("new_connections_total", "Connections opened."),
+		lifetimeDestroyConns: desc("lifetime_destroyed_connections_total", "Connections closed for exceeding their maximum lifetime."),
+		idleDestroyConns:     desc("idle_destroyed_connections_total", "Connections closed for exceeding their maximum idle time."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireSeconds, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.newConns, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(c.lifetimeDestroyConns, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleDestroyConns, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+}
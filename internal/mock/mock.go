@@ -0,0 +1,223 @@
+// Package mock serves the real API from an in-memory store, seeded with
+// sample rooms and fed by a deterministic generator that keeps messages,
+// reactions and answers trickling in. It lets frontends be built against
+// live behavior without Postgres. The generator goes through the API like
+// any client, so every event is the one the real server would send.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/api"
+	"github.com/luiz504/week-tech-go-server/internal/config"
+	"github.com/luiz504/week-tech-go-server/internal/session"
+	"github.com/luiz504/week-tech-go-server/internal/store/memory"
+)
+
+type room struct {
+	id         string
+	ownerToken string
+	messages   []string
+	// answered counts the messages answered, they're answered in order.
+	answered int
+}
+
+// Server is the api handler over a seeded memory store.
+type Server struct {
+	api.Handler
+	rand  *rand.Rand
+	rooms []*room
+}
+
+// New seeds the rooms. The same seed replays the same events in the same
+// order, the IDs are generated by the store though.
+func New(seed uint64) (*Server, error) {
+	s := &Server{
+		Handler: api.NewHandler(memory.New(), config.Config{CORSOrigins: config.DefaultCORSOrigins}),
+		rand:    rand.New(rand.NewPCG(seed, seed)),
+	}
+	if err := s.seed(); err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+
+	return s, nil
+}
+
+// * Seed data
+
+var seedRooms = []struct {
+	theme     string
+	questions []string
+}{
+	{"Go in production", []string{
+		"How do you size the pgx pool for websocket heavy services?",
+		"Do you run migrations on deploy or out of band?",
+		"What broke first when traffic grew?",
+	}},
+	{"Frontend office hours", []string{
+		"Should the room page resubscribe on every reconnect?",
+		"How do we render the leaderboard without layout jumps?",
+	}},
+}
+
+var trickle = []string{
+	"Can you share the slides afterwards?",
+	"Is there a recording?",
+	"How does this compare to server-sent events?",
+	"What about mobile clients on flaky networks?",
+	"Any tips for testing this locally?",
+	"How many rooms does a single instance handle?",
+	"Would you pick the same stack again?",
+}
+
+func (s *Server) seed() error {
+	for _, seed := range seedRooms {
+		var created struct {
+			ID         string `json:"id"`
+			OwnerToken string `json:"owner_token"`
+		}
+		if err := s.do(http.MethodPost, "/api/rooms", nil, map[string]any{"theme": seed.theme}, &created, http.StatusCreated); err != nil {
+			return err
+		}
+
+		rm := &room{id: created.ID, ownerToken: created.OwnerToken}
+		for _, question := range seed.questions {
+			if err := s.post(rm, question); err != nil {
+				return err
+			}
+		}
+		s.rooms = append(s.rooms, rm)
+	}
+
+	return nil
+}
+
+// * Events
+
+// Run generates activity every tick until stop is closed: new questions,
+// reactions and answers, in an order fixed by the seed.
+func (s *Server) Run(tick time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.step(); err != nil {
+				slog.Error("failed to generate mock event", "error", err)
+			}
+		}
+	}
+}
+
+func (s *Server) step() error {
+	rm := s.rooms[s.rand.IntN(len(s.rooms))]
+	switch roll := s.rand.IntN(10); {
+	case roll < 2 || len(rm.messages) == 0:
+		return s.post(rm, trickle[s.rand.IntN(len(trickle))])
+	case roll < 9:
+		//* Reactions come in bursts on one message, like a real audience
+		id := rm.messages[s.rand.IntN(len(rm.messages))]
+		for range 1 + s.rand.IntN(3) {
+			if err := s.react(rm, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if rm.answered == len(rm.messages) {
+			return nil
+		}
+		if err := s.answer(rm, rm.messages[rm.answered]); err != nil {
+			return err
+		}
+		rm.answered++
+		return nil
+	}
+}
+
+func (s *Server) post(rm *room, text string) error {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := s.do(http.MethodPost, "/api/rooms/"+rm.id+"/messages", nil, map[string]any{"message": text}, &created, http.StatusCreated); err != nil {
+		return err
+	}
+	rm.messages = append(rm.messages, created.ID)
+
+	return nil
+}
+
+// react adds a like from a new session, as each session reacts once to a
+// message.
+func (s *Server) react(rm *room, messageID string) error {
+	var sess struct {
+		Token string `json:"token"`
+	}
+	if err := s.do(http.MethodPost, "/api/sessions", nil, nil, &sess, http.StatusCreated); err != nil {
+		return err
+	}
+
+	header := http.Header{session.HeaderName: {sess.Token}}
+	body := map[string]any{"kind": api.ReactionKindLike}
+	return s.do(http.MethodPatch, "/api/rooms/"+rm.id+"/messages/"+messageID+"/react", header, body, nil, http.StatusOK)
+}
+
+func (s *Server) answer(rm *room, messageID string) error {
+	//* Answers must name the version they apply to
+	var current struct {
+		Message struct {
+			Version int32 `json:"version"`
+		} `json:"message"`
+	}
+	path := "/api/rooms/" + rm.id + "/messages/" + messageID
+	if err := s.do(http.MethodGet, path, nil, nil, &current, http.StatusOK); err != nil {
+		return err
+	}
+
+	header := http.Header{"Authorization": {"Bearer " + rm.ownerToken}}
+	body := map[string]any{"answer": "Answered live.", "version": current.Message.Version}
+	return s.do(http.MethodPatch, path+"/answer", header, body, nil, http.StatusNoContent)
+}
+
+// do serves a JSON request in-process and decodes the response into out.
+func (s *Server) do(method, path string, header http.Header, body, out any, want int) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luiz504/week-tech-go-server/internal/profanity"
+)
+
+const (
+	RuleThemeLength   = "theme_length"
+	RuleReservedTheme = "reserved_theme"
+	RuleBannedTerm    = "banned_term"
+	RuleProfanity     = "profanity"
+)
+
+const (
+	DefaultMinThemeLength = 3
+	DefaultMaxThemeLength = 255
+)
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// RoomInput is what a rule sees on room creation. Rules may rewrite it,
+// e.g. to strip content, before later rules run.
+type RoomInput struct {
+	Theme string
+}
+
+type Rule interface {
+	Apply(input *RoomInput) []Violation
+}
+
+// RuleFunc adapts a plain function into a Rule.
+type RuleFunc func(input *RoomInput) []Violation
+
+func (f RuleFunc) Apply(input *RoomInput) []Violation {
+	return f(input)
+}
+
+type Engine struct {
+	rules []Rule
+}
+
+func New(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Use appends rules, letting deployments plug in their own checks.
+func (e *Engine) Use(rules ...Rule) {
+	e.rules = append(e.rules, rules...)
+}
+
+// Apply runs every rule in order and collects all violations.
+func (e *Engine) Apply(input *RoomInput) []Violation {
+	var violations []Violation
+	for _, rule := range e.rules {
+		violations = append(violations, rule.Apply(input)...)
+	}
+
+	return violations
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// StripURLs removes links from the theme instead of rejecting it.
+type StripURLs struct{}
+
+func (StripURLs) Apply(input *RoomInput) []Violation {
+	input.Theme = strings.Join(strings.Fields(urlPattern.ReplaceAllString(input.Theme, "")), " ")
+
+	return nil
+}
+
+type ThemeLength struct {
+	Min int
+	Max int
+}
+
+func (r ThemeLength) Apply(input *RoomInput) []Violation {
+	length := utf8.RuneCountInString(strings.TrimSpace(input.Theme))
+	if length < r.Min || length > r.Max {
+		return []Violation{{
+			Rule:    RuleThemeLength,
+			Field:   "theme",
+			Message: fmt.Sprintf("must be between %d and %d characters", r.Min, r.Max),
+		}}
+	}
+
+	return nil
+}
+
+// ReservedThemes rejects themes matching a reserved name exactly, ignoring case.
+type ReservedThemes []string
+
+func (r ReservedThemes) Apply(input *RoomInput) []Violation {
+	theme := strings.TrimSpace(input.Theme)
+	for _, reserved := range r {
+		if strings.EqualFold(theme, reserved) {
+			return []Violation{{Rule: RuleReservedTheme, Field: "theme", Message: "is reserved"}}
+		}
+	}
+
+	return nil
+}
+
+// BannedTerms rejects themes containing any of the terms, ignoring case.
+type BannedTerms []string
+
+func (r BannedTerms) Apply(input *RoomInput) []Violation {
+	theme := strings.ToLower(input.Theme)
+	for _, term := range r {
+		if strings.Contains(theme, strings.ToLower(term)) {
+			return []Violation{{Rule: RuleBannedTerm, Field: "theme", Message: "contains a banned term"}}
+		}
+	}
+
+	return nil
+}
+
+type Profanity struct{}
+
+func (Profanity) Apply(input *RoomInput) []Violation {
+	if profanity.Contains(input.Theme) {
+		return []Violation{{Rule: RuleProfanity, Field: "theme", Message: "contains profanity"}}
+	}
+
+	return nil
+}
+
+// FromEnv builds the default room policy, reading the optional
+// WS_ROOM_RESERVED_THEMES, WS_ROOM_BANNED_TERMS (comma separated) and
+// WS_ROOM_THEME_MAX_LENGTH variables.
+func FromEnv() *Engine {
+	maxLength := DefaultMaxThemeLength
+	if raw := os.Getenv("WS_ROOM_THEME_MAX_LENGTH"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 && n < DefaultMaxThemeLength {
+			maxLength = n
+		}
+	}
+
+	return New(
+		StripURLs{},
+		ThemeLength{Min: DefaultMinThemeLength, Max: maxLength},
+		ReservedThemes(splitList(os.Getenv("WS_ROOM_RESERVED_THEMES"))),
+		BannedTerms(splitList(os.Getenv("WS_ROOM_BANNED_TERMS"))),
+		Profanity{},
+	)
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,112 @@
+// Package privacy anonymizes client IPs before they reach logs or storage.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+)
+
+const (
+	// ModeOff keeps addresses as they are.
+	ModeOff = "off"
+	// ModeTruncate zeroes the host part, keeping the /24 of IPv4 and the /48
+	// of IPv6 addresses, which is still enough for coarse geolocation.
+	ModeTruncate = "truncate"
+	// ModeHash replaces addresses with a keyed hash, stable while the salt
+	// is, so repeated clients can still be told apart.
+	ModeHash = "hash"
+)
+
+type Anonymizer struct {
+	mode string
+	salt []byte
+}
+
+func New(mode string, salt []byte) (*Anonymizer, error) {
+	switch mode {
+	case ModeOff, ModeTruncate, ModeHash:
+	default:
+		return nil, fmt.Errorf("unknown privacy mode %q", mode)
+	}
+
+	return &Anonymizer{mode: mode, salt: salt}, nil
+}
+
+// FromEnv reads WS_PRIVACY_MODE, off by default, and WS_PRIVACY_SALT. Without
+// a salt hashes are keyed per process and don't correlate across restarts.
+func FromEnv() (*Anonymizer, error) {
+	mode := os.Getenv("WS_PRIVACY_MODE")
+	if mode == "" {
+		mode = ModeOff
+	}
+
+	salt := []byte(os.Getenv("WS_PRIVACY_SALT"))
+	if len(salt) == 0 && mode == ModeHash {
+		salt = make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+
+	return New(mode, salt)
+}
+
+func (a *Anonymizer) Mode() string {
+	return a.mode
+}
+
+// IP anonymizes an address, with or without a port. The port is dropped in
+// every mode but off.
+func (a *Anonymizer) IP(addr string) string {
+	if a.mode == ModeOff {
+		return addr
+	}
+
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	switch a.mode {
+	case ModeTruncate:
+		ip, err := netip.ParseAddr(host)
+		if err != nil {
+			//? Not an address, nothing meaningful to keep
+			return ""
+		}
+		bits := 48
+		if ip.Unmap().Is4() {
+			ip, bits = ip.Unmap(), 24
+		}
+		prefix, _ := ip.Prefix(bits)
+		return prefix.Addr().String()
+	default:
+		mac := hmac.New(sha256.New, a.salt)
+		mac.Write([]byte(host))
+		return "anon-" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+}
+
+// Middleware anonymizes r.RemoteAddr, so every later handler, request logs
+// included, only sees the anonymized address. It must run after anything that
+// resolves the client address from proxy headers.
+func Middleware(a *Anonymizer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a.mode == ModeOff {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = a.IP(r.RemoteAddr)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package profanity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kept intentionally short: it catches the obvious cases in display names,
+// not a replacement for moderation.
+var defaultWords = []string{
+	"asshole",
+	"bastard",
+	"bitch",
+	"caralho",
+	"cunt",
+	"fuck",
+	"porra",
+	"shit",
+}
+
+var leet = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"@", "a",
+	"$", "s",
+)
+
+// normalize lowercases, maps common leetspeak and drops separators so that
+// "F.u_c-k" and "fvck" style evasions are partially covered.
+func normalize(text string) string {
+	text = leet.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// Contains reports whether the text contains any of the default words.
+func Contains(text string) bool {
+	normalized := normalize(text)
+	for _, word := range defaultWords {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+
+	return false
+}
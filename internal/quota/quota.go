@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// * Quota resources, also reported in the X-Quota-Resource header
+const (
+	ResourceRoomsPerMonth     = "rooms_per_month"
+	ResourceMessagesPerRoom   = "messages_per_room"
+	ResourceConnectionsPerOrg = "connections_per_org"
+)
+
+const (
+	HeaderResource  = "X-Quota-Resource"
+	HeaderLimit     = "X-Quota-Limit"
+	HeaderRemaining = "X-Quota-Remaining"
+	HeaderReset     = "X-Quota-Reset"
+)
+
+// Limits are the quotas of one organization, 0 meaning unlimited.
+type Limits struct {
+	RoomsPerMonth     int64
+	MessagesPerRoom   int64
+	ConnectionsPerOrg int64
+}
+
+// LimitsFromEnv reads the defaults applied to every organization:
+// WS_QUOTA_ROOMS_PER_MONTH, WS_QUOTA_MESSAGES_PER_ROOM and
+// WS_QUOTA_CONNECTIONS_PER_ORG.
+func LimitsFromEnv() Limits {
+	return Limits{
+		RoomsPerMonth:     limitFromEnv("WS_QUOTA_ROOMS_PER_MONTH"),
+		MessagesPerRoom:   limitFromEnv("WS_QUOTA_MESSAGES_PER_ROOM"),
+		ConnectionsPerOrg: limitFromEnv("WS_QUOTA_CONNECTIONS_PER_ORG"),
+	}
+}
+
+func limitFromEnv(key string) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit < 0 {
+		slog.Warn("invalid "+key+", quota disabled", "value", raw)
+		return 0
+	}
+
+	return limit
+}
+
+// Usage is how much of a quota is taken. Reset is when it frees up again,
+// zero for quotas that don't reset on a schedule.
+type Usage struct {
+	Resource string
+	Limit    int64
+	Used     int64
+	Reset    time.Time
+}
+
+func (u Usage) Exceeded() bool {
+	return u.Limit > 0 && u.Used >= u.Limit
+}
+
+// WriteHeaders reports the headroom left once the current request goes through.
+func WriteHeaders(w http.ResponseWriter, u Usage) {
+	if u.Limit <= 0 {
+		return
+	}
+
+	w.Header().Set(HeaderResource, u.Resource)
+	w.Header().Set(HeaderLimit, strconv.FormatInt(u.Limit, 10))
+	w.Header().Set(HeaderRemaining, strconv.FormatInt(max(u.Limit-u.Used-1, 0), 10))
+	if !u.Reset.IsZero() {
+		w.Header().Set(HeaderReset, strconv.FormatInt(u.Reset.Unix(), 10))
+	}
+}
+
+// Deny rejects a request over quota. Plan allowances answer 402 so clients
+// know waiting won't help, concurrency limits answer 429 with Retry-After.
+func Deny(w http.ResponseWriter, u Usage) {
+	WriteHeaders(w, u)
+	w.Header().Set(HeaderRemaining, "0")
+
+	code := http.StatusPaymentRequired
+	if u.Resource == ResourceConnectionsPerOrg {
+		code = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", "30")
+	}
+
+	http.Error(w, fmt.Sprintf("quota exceeded: %s is limited to %d", u.Resource, u.Limit), code)
+}
+
+// MonthStart is the start of the calendar month of t, in UTC.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
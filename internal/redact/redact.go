@@ -0,0 +1,188 @@
+// Package redact masks personal data in text leaving the service: logs,
+// exports and messages mirrored to other chats. Storage keeps the original,
+// so hosts still see what was asked.
+package redact
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Mask replaces every redacted span.
+const Mask = "[redacted]"
+
+var (
+	email = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	// phone is loose on purpose, candidates are then checked by isPhone.
+	phone = regexp.MustCompile(`\+?\(?\d[\d\s().-]{6,}\d`)
+	date  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+const (
+	minPhoneDigits = 9
+	maxPhoneDigits = 15
+)
+
+// Redactor masks emails and phone numbers, when enabled, and any configured
+// pattern. A nil Redactor returns text unchanged.
+type Redactor struct {
+	pii      bool
+	patterns []*regexp.Regexp
+}
+
+func New(pii bool, patterns ...*regexp.Regexp) *Redactor {
+	return &Redactor{pii: pii, patterns: patterns}
+}
+
+// FromEnv reads WS_REDACT_PII, true by default, and WS_REDACT_PATTERNS_FILE,
+// a file of extra regular expressions, one per line, # starting a comment.
+func FromEnv() (*Redactor, error) {
+	pii := true
+	if raw := os.Getenv("WS_REDACT_PII"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WS_REDACT_PII %q", raw)
+		}
+		pii = parsed
+	}
+
+	var patterns []*regexp.Regexp
+	if path := os.Getenv("WS_REDACT_PATTERNS_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			pattern, err := regexp.Compile(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			patterns = append(patterns, pattern)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return New(pii, patterns...), nil
+}
+
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	if r.pii {
+		s = email.ReplaceAllString(s, Mask)
+		s = replacePhones(s)
+	}
+	for _, pattern := range r.patterns {
+		s = pattern.ReplaceAllString(s, Mask)
+	}
+
+	return s
+}
+
+// JSON redacts the strings of a JSON document. Identifiers and timestamps are
+// kept as they are, their digit runs would otherwise pass for phone numbers.
+func (r *Redactor) JSON(raw []byte) ([]byte, error) {
+	if r == nil {
+		return raw, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(r.walk(value))
+}
+
+func (r *Redactor) walk(value any) any {
+	switch v := value.(type) {
+	case string:
+		if _, err := uuid.Parse(v); err == nil {
+			return v
+		}
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return v
+		}
+		return r.String(v)
+	case []any:
+		for i := range v {
+			v[i] = r.walk(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = r.walk(v[key])
+		}
+	}
+
+	return value
+}
+
+// replacePhones masks the phone candidates that stand on their own, have a
+// phone's number of digits and aren't dates.
+func replacePhones(s string) string {
+	matches := phone.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if !isPhone(s, m[0], m[1]) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(Mask)
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+func isPhone(s string, start, end int) bool {
+	//* A digit run glued to letters or dashes is part of an identifier
+	if start > 0 && isIdentifierRune(rune(s[start-1])) {
+		return false
+	}
+	if end < len(s) && isIdentifierRune(rune(s[end])) {
+		return false
+	}
+
+	candidate := s[start:end]
+	if date.MatchString(candidate) {
+		return false
+	}
+	digits := 0
+	for _, c := range candidate {
+		if unicode.IsDigit(c) {
+			digits++
+		}
+	}
+
+	return digits >= minPhoneDigits && digits <= maxPhoneDigits
+}
+
+func isIdentifierRune(c rune) bool {
+	return c == '-' || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
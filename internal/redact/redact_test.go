@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	r := New(true, regexp.MustCompile(`(?i)badge #\d+`))
+
+	cases := map[string]string{
+		"mail me at jane.doe+ama@example.co.uk please": "mail me at [redacted] please",
+		"call +1 (555) 123-4567 tonight":               "call [redacted] tonight",
+		"my number is 11987654321":                     "my number is [redacted]",
+		"show badge #42 at the door":                   "show [redacted] at the door",
+		"room 550e8400-e29b-41d4-a716-446655440000":    "room 550e8400-e29b-41d4-a716-446655440000",
+		"since 2024-08-07 10:00:00":                    "since 2024-08-07 10:00:00",
+		"we grew 120 percent in 2024":                  "we grew 120 percent in 2024",
+	}
+	for in, want := range cases {
+		if got := r.String(in); got != want {
+			t.Errorf("String(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r := New(true)
+
+	got, err := r.JSON([]byte(`{"id":"550e8400-e29b-41d4-a716-446655440000","message":"ping jane@example.com","created_at":"2024-08-07T10:00:00Z","count":5551234567}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"count":5551234567,"created_at":"2024-08-07T10:00:00Z","id":"550e8400-e29b-41d4-a716-446655440000","message":"ping [redacted]"}`
+	if string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestNilRedactor(t *testing.T) {
+	var r *Redactor
+	if got := r.String("jane@example.com"); got != "jane@example.com" {
+		t.Fatalf("got %q", got)
+	}
+}
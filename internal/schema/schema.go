@@ -0,0 +1,123 @@
+// Package schema derives JSON Schemas (draft 2020-12) from Go types by
+// following their encoding/json tags, so published schemas can't drift
+// from what the server actually marshals.
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Object is a JSON Schema document.
+type Object = map[string]any
+
+// For returns the schema of the value v marshals to.
+func For(v any) Object {
+	return forType(reflect.TypeOf(v))
+}
+
+func forType(t reflect.Type) Object {
+	if t == nil {
+		return Object{}
+	}
+
+	switch t {
+	case timeType:
+		return Object{"type": "string", "format": "date-time"}
+	case uuidType:
+		return Object{"type": "string", "format": "uuid"}
+	case rawMessageType:
+		return Object{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := forType(t.Elem())
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []string{typ, "null"}
+		}
+		return s
+	case reflect.Bool:
+		return Object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Object{"type": "number"}
+	case reflect.String:
+		return Object{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		//? encoding/json writes byte slices as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Object{"type": "string", "contentEncoding": "base64"}
+		}
+		//? nil slices and maps marshal to null
+		return Object{"type": []string{"array", "null"}, "items": forType(t.Elem())}
+	case reflect.Map:
+		return Object{"type": []string{"object", "null"}, "additionalProperties": forType(t.Elem())}
+	case reflect.Struct:
+		return forStruct(t)
+	default:
+		//* interfaces and anything else accept any value
+		return Object{}
+	}
+}
+
+func forStruct(t reflect.Type) Object {
+	properties := Object{}
+	required := []string{}
+	addFields(t, properties, &required)
+
+	s := Object{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+func addFields(t reflect.Type, properties Object, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		//* untagged embedded structs are flattened, like encoding/json does
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = forType(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
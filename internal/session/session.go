@@ -0,0 +1,122 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CookieName = "wsrs_session"
+	HeaderName = "X-Session-Token"
+)
+
+var ErrInvalidToken = errors.New("invalid session token")
+
+// Signer issues and verifies "<session id>.<signature>" tokens, so a session
+// can be trusted without a database round trip.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// SignerFromEnv reads WS_SESSION_SECRET. Without it a random secret is used,
+// which invalidates every session on restart.
+func SignerFromEnv() *Signer {
+	if secret := os.Getenv("WS_SESSION_SECRET"); secret != "" {
+		return NewSigner([]byte(secret))
+	}
+
+	slog.Warn("WS_SESSION_SECRET is not set, sessions will not survive restarts")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+
+	return NewSigner(secret)
+}
+
+func (s *Signer) signature(id uuid.UUID) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(id[:])
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) Sign(id uuid.UUID) string {
+	return id.String() + "." + s.signature(id)
+}
+
+func (s *Signer) Verify(token string) (uuid.UUID, error) {
+	rawID, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(id))) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return id, nil
+}
+
+// TokenFromRequest prefers the header over the cookie.
+func TokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get(HeaderName); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+type contextKey struct{}
+
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the caller's session, if the request carried a valid one.
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(contextKey{}).(uuid.UUID)
+
+	return id, ok
+}
+
+// Middleware attaches the session of requests carrying a valid token.
+// Requests without one pass through anonymously.
+func Middleware(signer *Signer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := TokenFromRequest(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, err := signer.Verify(token)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// sealedPrefix marks text stored as an envelope. Rows without it were written
+// before encryption was enabled and are returned as they are.
+const sealedPrefix = "enc:v1:"
+
+// Each column gets its own associated data, so an envelope copied into
+// another one fails to open.
+var (
+	messageAAD     = []byte("message")
+	answerAAD      = []byte("answer")
+	translationAAD = []byte("translation")
+	eventAAD       = []byte("event")
+	jobOutputAAD   = []byte("job_output")
+)
+
+// ErrSearchUnavailable is returned by message search while text is encrypted
+// at rest, as the search index only ever sees ciphertext.
+var ErrSearchUnavailable = errors.New("message search is unavailable while messages are encrypted")
+
+// Encrypted seals message text before it reaches the wrapped store and opens
+// it on the way out, so handlers only ever see plaintext. The copies of that
+// text are sealed too: answers, translations, the values of logged and queued
+// events, and job outputs like event reports. Reads of other tables go
+// straight through.
+type Encrypted struct {
+	encryptedQuerier
+	store Store
+}
+
+// NewEncrypted wraps s so message text is encrypted at rest with keyring.
+func NewEncrypted(s Store, keyring *crypto.Keyring) *Encrypted {
+	return &Encrypted{encryptedQuerier: encryptedQuerier{Querier: s, keyring: keyring}, store: s}
+}
+
+// EncryptedFromEnv wraps s with NewEncrypted when WS_ENCRYPT_MESSAGES is set,
+// sealing under the WS_MASTER_KEYS keyring, which it then requires.
+func EncryptedFromEnv(s Store) (Store, error) {
+	if enabled, _ := strconv.ParseBool(os.Getenv("WS_ENCRYPT_MESSAGES")); !enabled {
+		return s, nil
+	}
+
+	keyring, err := crypto.KeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if keyring == nil {
+		return nil, fmt.Errorf("WS_ENCRYPT_MESSAGES: %w", crypto.ErrNoKeyring)
+	}
+
+	return NewEncrypted(s, keyring), nil
+}
+
+func (e *Encrypted) Begin(ctx context.Context) (Tx, error) {
+	tx, err := e.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedTx{encryptedQuerier: encryptedQuerier{Querier: tx, keyring: e.keyring}, tx: tx}, nil
+}
+
+func (e *Encrypted) Utilization() float64 {
+	return e.store.Utilization()
+}
+
+func (e *Encrypted) TryLock(ctx context.Context, name string) (Lock, error) {
+	return e.store.TryLock(ctx, name)
+}
+
+func (e *Encrypted) Position(ctx context.Context) (string, error) {
+	return e.store.Position(ctx)
+}
+
+func (e *Encrypted) Reader(ctx context.Context, token string) (pg.Querier, error) {
+	q, err := e.store.Reader(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedQuerier{Querier: q, keyring: e.keyring}, nil
+}
+
+type encryptedTx struct {
+	encryptedQuerier
+	tx Tx
+}
+
+func (t encryptedTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t encryptedTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+// encryptedQuerier overrides the queries that write or read message text.
+type encryptedQuerier struct {
+	pg.Querier
+	keyring *crypto.Keyring
+}
+
+func (q encryptedQuerier) seal(plaintext []byte, aad []byte) (string, error) {
+	e, err := q.keyring.Seal(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + e.KeyID +
+		":" + base64.RawStdEncoding.EncodeToString(e.WrappedKey) +
+		":" + base64.RawStdEncoding.EncodeToString(e.Ciphertext), nil
+}
+
+// open reports false when stored isn't an envelope.
+func (q encryptedQuerier) open(stored string, aad []byte) ([]byte, bool, error) {
+	sealed, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return nil, false, nil
+	}
+
+	parts := strings.Split(sealed, ":")
+	if len(parts) != 3 {
+		return nil, false, fmt.Errorf("%w: malformed envelope", crypto.ErrDecrypt)
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %w", crypto.ErrDecrypt, err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %w", crypto.ErrDecrypt, err)
+	}
+
+	plaintext, err := q.keyring.Open(crypto.Envelope{KeyID: parts[0], WrappedKey: wrapped, Ciphertext: ciphertext}, aad)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return plaintext, true, nil
+}
+
+// sealText leaves empty text empty, like that of unanswered questions.
+func (q encryptedQuerier) sealText(plaintext string, aad []byte) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	return q.seal([]byte(plaintext), aad)
+}
+
+func (q encryptedQuerier) openText(stored string, aad []byte) (string, error) {
+	plaintext, ok, err := q.open(stored, aad)
+	if !ok {
+		return stored, err
+	}
+
+	return string(plaintext), nil
+}
+
+// sealJSON stores a JSONB value as the JSON string of its envelope.
+func (q encryptedQuerier) sealJSON(value []byte) ([]byte, error) {
+	sealed, err := q.seal(value, eventAAD)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(sealed)
+}
+
+func (q encryptedQuerier) openJSON(stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, []byte(`"`+sealedPrefix)) {
+		return stored, nil
+	}
+	var sealed string
+	if err := json.Unmarshal(stored, &sealed); err != nil {
+		return nil, fmt.Errorf("%w: %w", crypto.ErrDecrypt, err)
+	}
+
+	value, _, err := q.open(sealed, eventAAD)
+	return value, err
+}
+
+func (q encryptedQuerier) openMessage(message *pg.Message) error {
+	var err error
+	if message.Message, err = q.openText(message.Message, messageAAD); err != nil {
+		return err
+	}
+	message.AnswerText, err = q.openText(message.AnswerText, answerAAD)
+
+	return err
+}
+
+func (q encryptedQuerier) openMessages(messages []pg.Message, err error) ([]pg.Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		if err := q.openMessage(&messages[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+func (q encryptedQuerier) InsertMessage(ctx context.Context, arg pg.InsertMessageParams) (uuid.UUID, error) {
+	var err error
+	if arg.Message, err = q.sealText(arg.Message, messageAAD); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return q.Querier.InsertMessage(ctx, arg)
+}
+
+func (q encryptedQuerier) EditMessage(ctx context.Context, arg pg.EditMessageParams) (pg.EditMessageRow, error) {
+	var err error
+	if arg.Message, err = q.sealText(arg.Message, messageAAD); err != nil {
+		return pg.EditMessageRow{}, err
+	}
+
+	edited, err := q.Querier.EditMessage(ctx, arg)
+	if err != nil {
+		return pg.EditMessageRow{}, err
+	}
+	if edited.Message, err = q.openText(edited.Message, messageAAD); err != nil {
+		return pg.EditMessageRow{}, err
+	}
+
+	return edited, nil
+}
+
+func (q encryptedQuerier) GetRoomMessage(ctx context.Context, arg pg.GetRoomMessageParams) (pg.Message, error) {
+	message, err := q.Querier.GetRoomMessage(ctx, arg)
+	if err != nil {
+		return pg.Message{}, err
+	}
+	if err := q.openMessage(&message); err != nil {
+		return pg.Message{}, err
+	}
+
+	return message, nil
+}
+
+func (q encryptedQuerier) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]pg.Message, error) {
+	return q.openMessages(q.Querier.GetRoomMessages(ctx, roomID))
+}
+
+func (q encryptedQuerier) GetRoomMessagesPage(ctx context.Context, arg pg.GetRoomMessagesPageParams) ([]pg.Message, error) {
+	return q.openMessages(q.Querier.GetRoomMessagesPage(ctx, arg))
+}
+
+func (q encryptedQuerier) GetRoomTopMessagesPage(ctx context.Context, arg pg.GetRoomTopMessagesPageParams) ([]pg.Message, error) {
+	return q.openMessages(q.Querier.GetRoomTopMessagesPage(ctx, arg))
+}
+
+func (q encryptedQuerier) GetRoomSessionMessages(ctx context.Context, arg pg.GetRoomSessionMessagesParams) ([]pg.Message, error) {
+	return q.openMessages(q.Querier.GetRoomSessionMessages(ctx, arg))
+}
+
+func (q encryptedQuerier) GetRoomTopMessages(ctx context.Context, arg pg.GetRoomTopMessagesParams) ([]pg.Message, error) {
+	return q.openMessages(q.Querier.GetRoomTopMessages(ctx, arg))
+}
+
+func (q encryptedQuerier) GetRoomModerationQueue(ctx context.Context, arg pg.GetRoomModerationQueueParams) ([]pg.GetRoomModerationQueueRow, error) {
+	rows, err := q.Querier.GetRoomModerationQueue(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Message, err = q.openText(rows[i].Message, messageAAD); err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+func (q encryptedQuerier) GetEventTopMessages(ctx context.Context, arg pg.GetEventTopMessagesParams) ([]pg.GetEventTopMessagesRow, error) {
+	rows, err := q.Querier.GetEventTopMessages(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Message, err = q.openText(rows[i].Message, messageAAD); err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+func (q encryptedQuerier) SearchRoomMessages(ctx context.Context, arg pg.SearchRoomMessagesParams) ([]pg.SearchRoomMessagesRow, error) {
+	return nil, ErrSearchUnavailable
+}
+
+func (q encryptedQuerier) MarkMessageAsAnswered(ctx context.Context, arg pg.MarkMessageAsAnsweredParams) (int32, error) {
+	var err error
+	if arg.AnswerText, err = q.sealText(arg.AnswerText, answerAAD); err != nil {
+		return 0, err
+	}
+
+	return q.Querier.MarkMessageAsAnswered(ctx, arg)
+}
+
+// SetMessageAnswerAudio matches the answer against the stored envelope, as
+// sealing the same text again gives another one.
+func (q encryptedQuerier) SetMessageAnswerAudio(ctx context.Context, arg pg.SetMessageAnswerAudioParams) (int64, error) {
+	message, err := q.Querier.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: arg.RoomID, ID: arg.ID})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	answer, err := q.openText(message.AnswerText, answerAAD)
+	if err != nil {
+		return 0, err
+	}
+	if answer != arg.AnswerText {
+		return 0, nil
+	}
+	arg.AnswerText = message.AnswerText
+
+	return q.Querier.SetMessageAnswerAudio(ctx, arg)
+}
+
+func (q encryptedQuerier) UpsertMessageTranslation(ctx context.Context, arg pg.UpsertMessageTranslationParams) error {
+	var err error
+	if arg.Text, err = q.sealText(arg.Text, translationAAD); err != nil {
+		return err
+	}
+
+	return q.Querier.UpsertMessageTranslation(ctx, arg)
+}
+
+func (q encryptedQuerier) GetMessageTranslation(ctx context.Context, arg pg.GetMessageTranslationParams) (pg.MessageTranslation, error) {
+	translation, err := q.Querier.GetMessageTranslation(ctx, arg)
+	if err != nil {
+		return pg.MessageTranslation{}, err
+	}
+	if translation.Text, err = q.openText(translation.Text, translationAAD); err != nil {
+		return pg.MessageTranslation{}, err
+	}
+
+	return translation, nil
+}
+
+func (q encryptedQuerier) InsertRoomEvent(ctx context.Context, arg pg.InsertRoomEventParams) error {
+	var err error
+	if arg.Value, err = q.sealJSON(arg.Value); err != nil {
+		return err
+	}
+
+	return q.Querier.InsertRoomEvent(ctx, arg)
+}
+
+func (q encryptedQuerier) GetRoomEvents(ctx context.Context, arg pg.GetRoomEventsParams) ([]pg.RoomEvent, error) {
+	events, err := q.Querier.GetRoomEvents(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if events[i].Value, err = q.openJSON(events[i].Value); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func (q encryptedQuerier) InsertOutboxEvent(ctx context.Context, arg pg.InsertOutboxEventParams) error {
+	var err error
+	if arg.Value, err = q.sealJSON(arg.Value); err != nil {
+		return err
+	}
+
+	return q.Querier.InsertOutboxEvent(ctx, arg)
+}
+
+func (q encryptedQuerier) ClaimOutboxEvents(ctx context.Context, arg pg.ClaimOutboxEventsParams) ([]pg.EventOutbox, error) {
+	events, err := q.Querier.ClaimOutboxEvents(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if events[i].Value, err = q.openJSON(events[i].Value); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func (q encryptedQuerier) CompleteJob(ctx context.Context, arg pg.CompleteJobParams) error {
+	if arg.Output != nil {
+		sealed, err := q.seal(arg.Output, jobOutputAAD)
+		if err != nil {
+			return err
+		}
+		arg.Output = []byte(sealed)
+	}
+
+	return q.Querier.CompleteJob(ctx, arg)
+}
+
+func (q encryptedQuerier) openJob(job pg.Job, err error) (pg.Job, error) {
+	if err != nil {
+		return pg.Job{}, err
+	}
+	output, ok, err := q.open(string(job.Output), jobOutputAAD)
+	if err != nil {
+		return pg.Job{}, err
+	}
+	if ok {
+		job.Output = output
+	}
+
+	return job, nil
+}
+
+func (q encryptedQuerier) GetJob(ctx context.Context, id uuid.UUID) (pg.Job, error) {
+	return q.openJob(q.Querier.GetJob(ctx, id))
+}
+
+func (q encryptedQuerier) GetOrganizationJob(ctx context.Context, arg pg.GetOrganizationJobParams) (pg.Job, error) {
+	return q.openJob(q.Querier.GetOrganizationJob(ctx, arg))
+}
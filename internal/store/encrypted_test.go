@@ -0,0 +1,174 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luiz504/week-tech-go-server/internal/crypto"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/memory"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+func TestEncryptedMessages(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := crypto.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := memory.New()
+	s := store.NewEncrypted(raw, keyring)
+
+	room, err := s.InsertRoom(ctx, pg.InsertRoomParams{Theme: "encrypted", PostingMode: "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: room.ID, Message: "secret question"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//* Rows written before encryption was enabled stay readable
+	if _, err := raw.InsertMessage(ctx, pg.InsertMessageParams{RoomID: room.ID, Message: "plain question"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := raw.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: room.ID, ID: messageID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored.Message, "secret") {
+		t.Fatalf("stored in plaintext: %q", stored.Message)
+	}
+
+	messages, err := s.GetRoomMessages(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, m := range messages {
+		got[m.Message] = true
+	}
+	if len(messages) != 2 || !got["secret question"] || !got["plain question"] {
+		t.Fatalf("got messages %+v", messages)
+	}
+
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	edited, err := tx.EditMessage(ctx, pg.EditMessageParams{ID: messageID, Version: 1, Message: "edited question"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Message != "edited question" {
+		t.Fatalf("edit returned %q", edited.Message)
+	}
+}
+
+// TestEncryptedCopies covers the columns message text is copied into, which
+// must not keep it in plaintext either.
+func TestEncryptedCopies(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := crypto.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := memory.New()
+	s := store.NewEncrypted(raw, keyring)
+
+	room, err := s.InsertRoom(ctx, pg.InsertRoomParams{Theme: "encrypted", PostingMode: "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: room.ID, Message: "secret question"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := func(what string, stored []byte) {
+		t.Helper()
+		if bytes.Contains(stored, []byte("secret")) {
+			t.Fatalf("%s stored in plaintext: %s", what, stored)
+		}
+	}
+
+	if _, err := s.MarkMessageAsAnswered(ctx, pg.MarkMessageAsAnsweredParams{ID: messageID, AnswerText: "secret answer"}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := raw.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: room.ID, ID: messageID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext("answer", []byte(stored.AnswerText))
+	message, err := s.GetRoomMessage(ctx, pg.GetRoomMessageParams{RoomID: room.ID, ID: messageID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.AnswerText != "secret answer" {
+		t.Fatalf("answer read back as %q", message.AnswerText)
+	}
+	updated, err := s.SetMessageAnswerAudio(ctx, pg.SetMessageAnswerAudioParams{ID: messageID, RoomID: room.ID, AnswerText: "secret answer", AnswerAudioUrl: "https://audio"})
+	if err != nil || updated != 1 {
+		t.Fatalf("answer audio: updated %d, error %v", updated, err)
+	}
+
+	if err := s.UpsertMessageTranslation(ctx, pg.UpsertMessageTranslationParams{MessageID: messageID, Language: "pt", Text: "secret pergunta"}); err != nil {
+		t.Fatal(err)
+	}
+	storedTranslation, err := raw.GetMessageTranslation(ctx, pg.GetMessageTranslationParams{MessageID: messageID, Language: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext("translation", []byte(storedTranslation.Text))
+	translation, err := s.GetMessageTranslation(ctx, pg.GetMessageTranslationParams{MessageID: messageID, Language: "pt"})
+	if err != nil || translation.Text != "secret pergunta" {
+		t.Fatalf("translation read back as %q, error %v", translation.Text, err)
+	}
+
+	value := []byte(`{"message":"secret question"}`)
+	if err := s.InsertRoomEvent(ctx, pg.InsertRoomEventParams{RoomID: room.ID, EventID: 1, Channel: "questions", Kind: "message_created", Value: value}); err != nil {
+		t.Fatal(err)
+	}
+	storedEvents, err := raw.GetRoomEvents(ctx, pg.GetRoomEventsParams{RoomID: room.ID, MaxEvents: 10})
+	if err != nil || len(storedEvents) != 1 {
+		t.Fatalf("got %d logged events, error %v", len(storedEvents), err)
+	}
+	plaintext("logged event", storedEvents[0].Value)
+	events, err := s.GetRoomEvents(ctx, pg.GetRoomEventsParams{RoomID: room.ID, MaxEvents: 10})
+	if err != nil || !bytes.Equal(events[0].Value, value) {
+		t.Fatalf("logged event read back as %s, error %v", events[0].Value, err)
+	}
+
+	if err := s.InsertOutboxEvent(ctx, pg.InsertOutboxEventParams{RoomID: room.ID, Channel: "questions", Kind: "message_created", Value: value}); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	queued, err := tx.ClaimOutboxEvents(ctx, pg.ClaimOutboxEventsParams{StaleBefore: time.Now().Add(time.Hour), MaxEvents: 10})
+	if err != nil || len(queued) != 1 || !bytes.Equal(queued[0].Value, value) {
+		t.Fatalf("queued events read back as %+v, error %v", queued, err)
+	}
+
+	job, err := s.InsertJob(ctx, pg.InsertJobParams{Kind: "event_report", Payload: []byte("{}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CompleteJob(ctx, pg.CompleteJobParams{ID: job.ID, Output: []byte("secret report")}); err != nil {
+		t.Fatal(err)
+	}
+	storedJob, err := raw.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext("job output", storedJob.Output)
+	job, err = s.GetJob(ctx, job.ID)
+	if err != nil || string(job.Output) != "secret report" {
+		t.Fatalf("job output read back as %q, error %v", job.Output, err)
+	}
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Every Store reports these, so callers check them instead of the errors of
+// a driver. The original error stays wrapped, constraint names included.
+var (
+	// ErrNotFound is a query for one row that matched none.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a write breaking a unique constraint.
+	ErrConflict = errors.New("conflict")
+	// ErrForeignKey is a write referencing a row that doesn't exist, or a
+	// delete of a row still referenced.
+	ErrForeignKey = errors.New("foreign key violation")
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// Translate wraps a Postgres error in the domain error it stands for,
+// returning any other error as is.
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrConflict), errors.Is(err, ErrForeignKey):
+		return err
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation:
+		return fmt.Errorf("%w: %w", ErrForeignKey, err)
+	}
+
+	return err
+}
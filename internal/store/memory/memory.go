@@ -0,0 +1,311 @@
+// Package memory is a Store that keeps every table in memory, for tests that
+// exercise the API without a database. Queries follow the SQL they stand in
+// for: the same filters, ordering, defaults and the constraints handlers rely
+// on, reported as the errors Postgres would return.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+var _ store.Store = (*Store)(nil)
+
+// errNoRows goes through store.Translate like the errors of the Postgres store.
+var errNoRows = store.Translate(pgx.ErrNoRows)
+
+// tables holds a slice per table, rows in insertion order like a heap
+// without updates would return them.
+type tables struct {
+	adminCredentials    []pg.AdminCredential
+	apiKeys             []pg.ApiKey
+	captions            []pg.Caption
+	chatBridgeMessages  []pg.ChatBridgeMessage
+	deadLetters         []pg.DeadLetter
+	eventOutbox         []pg.EventOutbox
+	events              []pg.Event
+	ingestDeliveries    []pg.IngestDelivery
+	jobs                []pg.Job
+	messageEdits        []pg.MessageEdit
+	messageReactions    []pg.MessageReaction
+	messageReports      []pg.MessageReport
+	messageTranslations []pg.MessageTranslation
+	messages            []pg.Message
+	organizations       []pg.Organization
+	roomCaptionTokens   []pg.RoomCaptionToken
+	roomChatBridges     []pg.RoomChatBridge
+	roomEvents          []pg.RoomEvent
+	roomIngestHooks     []pg.RoomIngestHook
+	roomOverlayTokens   []pg.RoomOverlayToken
+	roomPeaks           []pg.RoomPeak
+	roomTransfers       []pg.RoomTransfer
+	roomWebhooks        []pg.RoomWebhook
+	roomWebinarTokens   []pg.RoomWebinarToken
+	rooms               []pg.Room
+	savedViews          []pg.SavedView
+	secrets             []pg.Secret
+	sessions            []pg.Session
+	tracks              []pg.Track
+	usageRecords        []pg.UsageRecord
+	webhookDeliveries   []pg.WebhookDelivery
+	webinarQuestions    []pg.WebinarQuestion
+
+	// eventOutboxSeq is the last event_outbox id handed out.
+	eventOutboxSeq int64
+}
+
+// clone copies the table slices. Rows are copied by value: their own slices
+// are shared, which holds as long as updates replace them rather than
+// writing into them.
+func (t *tables) clone() tables {
+	return tables{
+		adminCredentials:    slices.Clone(t.adminCredentials),
+		apiKeys:             slices.Clone(t.apiKeys),
+		captions:            slices.Clone(t.captions),
+		chatBridgeMessages:  slices.Clone(t.chatBridgeMessages),
+		deadLetters:         slices.Clone(t.deadLetters),
+		eventOutbox:         slices.Clone(t.eventOutbox),
+		events:              slices.Clone(t.events),
+		ingestDeliveries:    slices.Clone(t.ingestDeliveries),
+		jobs:                slices.Clone(t.jobs),
+		messageEdits:        slices.Clone(t.messageEdits),
+		messageReactions:    slices.Clone(t.messageReactions),
+		messageReports:      slices.Clone(t.messageReports),
+		messageTranslations: slices.Clone(t.messageTranslations),
+		messages:            slices.Clone(t.messages),
+		organizations:       slices.Clone(t.organizations),
+		roomCaptionTokens:   slices.Clone(t.roomCaptionTokens),
+		roomChatBridges:     slices.Clone(t.roomChatBridges),
+		roomEvents:          slices.Clone(t.roomEvents),
+		roomIngestHooks:     slices.Clone(t.roomIngestHooks),
+		roomOverlayTokens:   slices.Clone(t.roomOverlayTokens),
+		roomPeaks:           slices.Clone(t.roomPeaks),
+		roomTransfers:       slices.Clone(t.roomTransfers),
+		roomWebhooks:        slices.Clone(t.roomWebhooks),
+		roomWebinarTokens:   slices.Clone(t.roomWebinarTokens),
+		rooms:               slices.Clone(t.rooms),
+		savedViews:          slices.Clone(t.savedViews),
+		secrets:             slices.Clone(t.secrets),
+		sessions:            slices.Clone(t.sessions),
+		tracks:              slices.Clone(t.tracks),
+		usageRecords:        slices.Clone(t.usageRecords),
+		webhookDeliveries:   slices.Clone(t.webhookDeliveries),
+		webinarQuestions:    slices.Clone(t.webinarQuestions),
+
+		eventOutboxSeq: t.eventOutboxSeq,
+	}
+}
+
+// Store is safe for concurrent use. Transactions run one at a time; a
+// rollback restores the tables as they were at Begin, undoing the writes
+// made outside the transaction meanwhile too.
+type Store struct {
+	mu sync.Mutex
+	t  tables
+
+	// txMu is held from Begin to Commit or Rollback.
+	txMu sync.Mutex
+	// locks are the names held through TryLock.
+	locks map[string]bool
+	// Now is the clock behind now(), for tests that move time along.
+	Now func() time.Time
+}
+
+func New() *Store {
+	return &Store{Now: time.Now}
+}
+
+func (s *Store) Begin(ctx context.Context) (store.Tx, error) {
+	s.txMu.Lock()
+
+	s.mu.Lock()
+	snapshot := s.t.clone()
+	s.mu.Unlock()
+
+	return &tx{Store: s, snapshot: snapshot}, nil
+}
+
+// Utilization is always zero, there are no connections to run out of.
+func (s *Store) Utilization() float64 {
+	return 0
+}
+
+// Position is empty: there are no replicas, every read sees every write.
+func (s *Store) Position(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (s *Store) Reader(ctx context.Context, token string) (pg.Querier, error) {
+	return s, nil
+}
+
+func (s *Store) TryLock(ctx context.Context, name string) (store.Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locks[name] {
+		return nil, nil
+	}
+	if s.locks == nil {
+		s.locks = make(map[string]bool)
+	}
+	s.locks[name] = true
+
+	return &lock{store: s, name: name}, nil
+}
+
+type lock struct {
+	store    *Store
+	name     string
+	released bool
+}
+
+func (l *lock) Held(ctx context.Context) bool {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	return !l.released
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	//? Released once, not to drop the lock of whoever took it next
+	if !l.released {
+		l.released = true
+		delete(l.store.locks, l.name)
+	}
+	return nil
+}
+
+type tx struct {
+	*Store
+	snapshot tables
+	done     bool
+}
+
+func (t *tx) Commit(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	t.txMu.Unlock()
+
+	return nil
+}
+
+func (t *tx) Rollback(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+
+	t.mu.Lock()
+	t.t = t.snapshot
+	t.mu.Unlock()
+	t.txMu.Unlock()
+
+	return nil
+}
+
+func (s *Store) now() time.Time {
+	return s.Now()
+}
+
+func newID() uuid.UUID {
+	return uuid.New()
+}
+
+// newCode mirrors the default of rooms.code, ten hex characters.
+func newCode() string {
+	b := make([]byte, 5)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func foreignKeyError(constraint string) error {
+	return store.Translate(&pgconn.PgError{Code: foreignKeyViolation, Message: "violates foreign key constraint", ConstraintName: constraint})
+}
+
+func uniqueError(constraint string) error {
+	return store.Translate(&pgconn.PgError{Code: uniqueViolation, Message: "duplicate key value violates unique constraint", ConstraintName: constraint})
+}
+
+// sameNullable is IS NOT DISTINCT FROM.
+func sameNullable(a, b uuid.NullUUID) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.UUID == b.UUID)
+}
+
+// equalNullable is =, which never holds for NULL.
+func equalNullable(a uuid.NullUUID, b uuid.UUID) bool {
+	return a.Valid && a.UUID == b
+}
+
+// compareIDs orders uuids like Postgres does, byte by byte.
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// beforeCursor is (created_at, id) < (cursor_created_at, cursor_id).
+func beforeCursor(createdAt time.Time, id uuid.UUID, cursorCreatedAt time.Time, cursorID uuid.UUID) bool {
+	if !createdAt.Equal(cursorCreatedAt) {
+		return createdAt.Before(cursorCreatedAt)
+	}
+
+	return compareIDs(id, cursorID) < 0
+}
+
+func limit[T any](rows []T, n int32) []T {
+	if n >= 0 && int(n) < len(rows) {
+		return rows[:n]
+	}
+
+	return rows
+}
+
+func (s *Store) roomIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.t.rooms, func(r pg.Room) bool { return r.ID == id })
+}
+
+func (s *Store) messageIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.t.messages, func(m pg.Message) bool { return m.ID == id })
+}
+
+func (s *Store) hasOrganization(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.t.organizations, func(o pg.Organization) bool { return o.ID == id })
+}
+
+func (s *Store) hasSession(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.t.sessions, func(o pg.Session) bool { return o.ID == id })
+}
+
+func (s *Store) trackIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.t.tracks, func(t pg.Track) bool { return t.ID == id })
+}
+
+func (s *Store) eventIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.t.events, func(e pg.Event) bool { return e.ID == id })
+}
+
+// AcquireBootstrapLock has nothing to do: transactions already run one at a time.
+func (s *Store) AcquireBootstrapLock(ctx context.Context) error {
+	return nil
+}
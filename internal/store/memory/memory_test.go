@@ -0,0 +1,356 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/store"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+func newRoom(t *testing.T, s *Store) uuid.UUID {
+	t.Helper()
+
+	room, err := s.InsertRoom(context.Background(), pg.InsertRoomParams{Theme: "memory", PostingMode: "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return room.ID
+}
+
+func TestReactOncePerSession(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	roomID := newRoom(t, s)
+	messageID, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: roomID, Message: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.InsertSession(ctx, pg.InsertSessionParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arg := pg.ReactToMessageParams{Kind: "like", SessionID: session.ID, ID: messageID}
+	first, err := s.ReactToMessage(ctx, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.ReactToMessage(ctx, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != (pg.ReactToMessageRow{ReactionCount: 1, Reacted: 1}) || second != (pg.ReactToMessageRow{ReactionCount: 1}) {
+		t.Fatalf("reacted twice: %+v then %+v", first, second)
+	}
+
+	removed, err := s.RemoveReactionFromMessage(ctx, pg.RemoveReactionFromMessageParams{ID: messageID, SessionID: session.ID, Kind: "like"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != (pg.RemoveReactionFromMessageRow{ReactionCount: 0, Removed: 1}) {
+		t.Fatalf("removed %+v", removed)
+	}
+
+	if _, err := s.ReactToMessage(ctx, pg.ReactToMessageParams{Kind: "like", SessionID: session.ID, ID: uuid.New()}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("reacted to an unknown message: %v", err)
+	}
+}
+
+func TestMessageVersions(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	roomID := newRoom(t, s)
+	messageID, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: roomID, Message: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := s.EditMessage(ctx, pg.EditMessageParams{ID: messageID, Version: 1, Message: "hello there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Version != 2 {
+		t.Fatalf("edit left version %d", edited.Version)
+	}
+
+	//? A change made against the version before is refused and leaves no trace
+	if _, err := s.EditMessage(ctx, pg.EditMessageParams{ID: messageID, Version: 1, Message: "hi"}); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("edited a stale version: %v", err)
+	}
+	stale := pg.MarkMessageAsAnsweredParams{ID: messageID, Version: pgtype.Int4{Int32: 1, Valid: true}}
+	if _, err := s.MarkMessageAsAnswered(ctx, stale); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("answered a stale version: %v", err)
+	}
+	if len(s.t.messageEdits) != 1 {
+		t.Fatalf("kept %d previous texts", len(s.t.messageEdits))
+	}
+
+	//? Without a version, as bulk actions do, the current one is changed
+	version, err := s.MarkMessageAsAnswered(ctx, pg.MarkMessageAsAnsweredParams{ID: messageID})
+	if err != nil || version != 3 {
+		t.Fatalf("answered to version %d: %v", version, err)
+	}
+}
+
+func TestRoomStats(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	roomID := newRoom(t, s)
+
+	var ids []uuid.UUID
+	for _, text := range []string{"one", "two", "three"} {
+		id, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: roomID, Message: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := s.MarkMessageAsAnswered(ctx, pg.MarkMessageAsAnsweredParams{ID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SoftDeleteMessage(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	//? Reactions to deleted messages still count
+	if _, err := s.ReactToMessage(ctx, pg.ReactToMessageParams{ID: ids[1], Kind: "like", SessionID: uuid.New()}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.GetRoomStats(ctx, roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.MessageCount != 2 || stats.UnansweredCount != 1 || stats.ReactionTotal != 1 || !stats.LastActivity.Valid {
+		t.Fatalf("got %+v", stats)
+	}
+	if _, err := s.GetRoomStats(ctx, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("got stats of a missing room: %v", err)
+	}
+}
+
+func TestMessagesPage(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return at }
+	roomID := newRoom(t, s)
+
+	//* Two messages share a timestamp so the id breaks the tie
+	var ids []uuid.UUID
+	for i := range 3 {
+		if i == 2 {
+			at = at.Add(time.Second)
+		}
+		id, err := s.InsertMessage(ctx, pg.InsertMessageParams{RoomID: roomID, Message: "page"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	var seen []uuid.UUID
+	cursor := pg.GetRoomMessagesPageParams{RoomID: roomID, PageSize: 2}
+	for {
+		page, err := s.GetRoomMessagesPage(ctx, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, m := range page {
+			seen = append(seen, m.ID)
+		}
+		last := page[len(page)-1]
+		cursor.CursorCreatedAt = pgtype.Timestamptz{Time: last.CreatedAt, Valid: true}
+		cursor.CursorID = last.ID
+	}
+
+	if len(seen) != len(ids) || seen[0] != ids[2] {
+		t.Fatalf("paged %v, inserted %v", seen, ids)
+	}
+	for i := range seen {
+		for j := range i {
+			if seen[i] == seen[j] {
+				t.Fatalf("paged %v twice", seen[i])
+			}
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization, err := tx.InsertOrganization(ctx, "rolled back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(ctx); !errors.Is(err, pgx.ErrTxClosed) {
+		t.Fatalf("rolled back twice: %v", err)
+	}
+
+	if _, err := s.GetOrganization(ctx, organization.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("organization survived the rollback: %v", err)
+	}
+
+	//? Constraint errors are the domain errors handlers check, wrapping the Postgres ones
+	_, err = s.InsertAPIKey(ctx, pg.InsertAPIKeyParams{OrganizationID: organization.ID, Name: "orphan", KeyHash: "hash"})
+	var pgErr *pgconn.PgError
+	if !errors.Is(err, store.ErrForeignKey) || !errors.As(err, &pgErr) || pgErr.Code != foreignKeyViolation {
+		t.Fatalf("inserted a key for a missing organization: %v", err)
+	}
+}
+
+func TestPruneRoomEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 8, 7, 12, 0, 0, 0, time.UTC)
+	s := New()
+	s.Now = func() time.Time { return now }
+	roomID := newRoom(t, s)
+
+	for range 5 {
+		seq, err := s.IncrementRoomEventSeq(ctx, roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.InsertRoomEvent(ctx, pg.InsertRoomEventParams{RoomID: roomID, EventID: seq, Kind: "test", Value: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	pruned, err := s.DeleteRoomEventsBeyond(ctx, 3)
+	if err != nil || pruned != 2 {
+		t.Fatalf("pruned %d by count: %v", pruned, err)
+	}
+	pruned, err = s.DeleteRoomEventsBefore(ctx, now.Add(-90*time.Second))
+	if err != nil || pruned != 2 {
+		t.Fatalf("pruned %d by age: %v", pruned, err)
+	}
+
+	events, err := s.GetRoomEvents(ctx, pg.GetRoomEventsParams{RoomID: roomID, MaxEvents: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventID != 5 {
+		t.Fatalf("kept %+v", events)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	first, err := s.TryLock(ctx, "leader")
+	if err != nil || first == nil {
+		t.Fatalf("first TryLock = %v, %v; want the lock", first, err)
+	}
+	if second, _ := s.TryLock(ctx, "leader"); second != nil {
+		t.Fatal("second TryLock took a held lock")
+	}
+
+	_ = first.Release(ctx)
+	if first.Held(ctx) {
+		t.Fatal("released lock is still held")
+	}
+	third, _ := s.TryLock(ctx, "leader")
+	if third == nil {
+		t.Fatal("TryLock after release didn't take the lock")
+	}
+
+	//* Releasing twice must not drop whoever took it next
+	_ = first.Release(ctx)
+	if !third.Held(ctx) {
+		t.Fatal("stale release dropped the new holder's lock")
+	}
+	if again, _ := s.TryLock(ctx, "leader"); again != nil {
+		t.Fatal("stale release freed the lock")
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 8, 7, 12, 0, 0, 0, time.UTC)
+	s := New()
+	s.Now = func() time.Time { return now }
+	roomID := newRoom(t, s)
+
+	publish := func() {
+		t.Helper()
+		seq, err := s.IncrementRoomEventSeq(ctx, roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.InsertRoomEvent(ctx, pg.InsertRoomEventParams{RoomID: roomID, EventID: seq, Kind: "test", Value: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//* Events from before the webhook was set up aren't delivered
+	publish()
+	hook, err := s.UpsertRoomWebhook(ctx, pg.UpsertRoomWebhookParams{RoomID: roomID, Url: "https://example.com/hook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish()
+	now = now.Add(time.Minute)
+
+	for range 2 {
+		queued, err := s.EnqueueWebhookDeliveries(ctx, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queued > 1 {
+			t.Fatalf("queued %d deliveries, want the one event once", queued)
+		}
+	}
+
+	delivery, err := s.ClaimWebhookDelivery(ctx, now.Add(time.Minute))
+	if err != nil || delivery.EventID != 2 || delivery.Attempts != 1 {
+		t.Fatalf("claimed %+v: %v", delivery, err)
+	}
+	if _, err := s.ClaimWebhookDelivery(ctx, now.Add(time.Minute)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("claimed a leased delivery: %v", err)
+	}
+
+	fail := pg.FailWebhookDeliveryParams{MaxAttempts: 1, RetryAt: now, Error: "boom", WebhookID: hook.ID, EventID: 2}
+	if err := s.FailWebhookDelivery(ctx, fail); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ClaimWebhookDelivery(ctx, now); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("claimed a delivery out of attempts: %v", err)
+	}
+
+	letters, err := s.GetDeadLetters(ctx, pg.GetDeadLettersParams{MaxDeadLetters: 10})
+	if err != nil || len(letters) != 1 || letters[0].Source != "webhook" || letters[0].Error != "boom" {
+		t.Fatalf("dead letters %+v: %v", letters, err)
+	}
+
+	redriven, err := s.RedriveWebhookDeliveries(ctx, uuid.NullUUID{UUID: roomID, Valid: true})
+	if err != nil || redriven != 1 {
+		t.Fatalf("redrove %d: %v", redriven, err)
+	}
+	if letters, _ := s.GetDeadLetters(ctx, pg.GetDeadLettersParams{MaxDeadLetters: 10}); len(letters) != 0 {
+		t.Fatalf("redrive kept dead letters %+v", letters)
+	}
+	delivery, err = s.ClaimWebhookDelivery(ctx, now.Add(time.Minute))
+	if err != nil || delivery.Attempts != 1 {
+		t.Fatalf("claimed %+v after redrive: %v", delivery, err)
+	}
+}
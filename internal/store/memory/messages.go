@@ -0,0 +1,856 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// copyMessage detaches the slices of a message from the table.
+func copyMessage(m pg.Message) pg.Message {
+	m.Fields = slices.Clone(m.Fields)
+	m.Tags = slices.Clone(m.Tags)
+
+	return m
+}
+
+// roomMessages returns copies of the room's messages that pass keep.
+func (s *Store) roomMessages(roomID uuid.UUID, keep func(pg.Message) bool) []pg.Message {
+	var messages []pg.Message
+	for _, m := range s.t.messages {
+		if m.RoomID == roomID && keep(m) {
+			messages = append(messages, copyMessage(m))
+		}
+	}
+
+	return messages
+}
+
+func visible(m pg.Message) bool {
+	return !m.DeletedAt.Valid && !m.HiddenAt.Valid
+}
+
+// truncate is date_trunc in UTC.
+func truncate(unit string, t time.Time) time.Time {
+	t = t.UTC()
+	switch unit {
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week":
+		//? ISO weeks start on Monday
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return t
+}
+
+func (s *Store) InsertMessage(ctx context.Context, arg pg.InsertMessageParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return uuid.UUID{}, foreignKeyError("messages_room_id_fkey")
+	}
+
+	message := pg.Message{
+		ID:         newID(),
+		RoomID:     arg.RoomID,
+		Message:    arg.Message,
+		CreatedAt:  s.now(),
+		Fields:     slices.Clone(arg.Fields),
+		AuthorName: arg.AuthorName,
+		SessionID:  arg.SessionID,
+		Tags:       []string{},
+		Language:   arg.Language,
+		Toxicity:   arg.Toxicity,
+		HiddenAt:   arg.HiddenAt,
+		Version:    1,
+	}
+	s.t.messages = append(s.t.messages, message)
+
+	return message.ID, nil
+}
+
+func (s *Store) GetRoomMessage(ctx context.Context, arg pg.GetRoomMessageParams) (pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || s.t.messages[i].RoomID != arg.RoomID || s.t.messages[i].DeletedAt.Valid {
+		return pg.Message{}, errNoRows
+	}
+
+	return copyMessage(s.t.messages[i]), nil
+}
+
+func (s *Store) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.roomMessages(roomID, visible), nil
+}
+
+func (s *Store) GetRoomMessagesPage(ctx context.Context, arg pg.GetRoomMessagesPageParams) ([]pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.roomMessages(arg.RoomID, func(m pg.Message) bool {
+		return visible(m) && answeredIs(m, arg.Answered) &&
+			(!arg.CursorCreatedAt.Valid || beforeCursor(m.CreatedAt, m.ID, arg.CursorCreatedAt.Time, arg.CursorID))
+	})
+	slices.SortStableFunc(messages, func(a, b pg.Message) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIDs(b.ID, a.ID)
+	})
+
+	return limit(messages, arg.PageSize), nil
+}
+
+func (s *Store) GetRoomTopMessagesPage(ctx context.Context, arg pg.GetRoomTopMessagesPageParams) ([]pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//* (reaction_count, created_at, id) < cursor
+	afterCursor := func(m pg.Message) bool {
+		if !arg.CursorReactionCount.Valid {
+			return true
+		}
+		if m.ReactionCount != arg.CursorReactionCount.Int64 {
+			return m.ReactionCount < arg.CursorReactionCount.Int64
+		}
+		return beforeCursor(m.CreatedAt, m.ID, arg.CursorCreatedAt, arg.CursorID)
+	}
+	messages := s.roomMessages(arg.RoomID, func(m pg.Message) bool {
+		return visible(m) && answeredIs(m, arg.Answered) && afterCursor(m)
+	})
+	slices.SortStableFunc(messages, func(a, b pg.Message) int {
+		if a.ReactionCount != b.ReactionCount {
+			if a.ReactionCount > b.ReactionCount {
+				return -1
+			}
+			return 1
+		}
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIDs(b.ID, a.ID)
+	})
+
+	return limit(messages, arg.PageSize), nil
+}
+
+// answeredIs is the optional answered = @answered filter.
+func answeredIs(m pg.Message, answered pgtype.Bool) bool {
+	return !answered.Valid || m.Answered == answered.Bool
+}
+
+// SearchRoomMessages approximates websearch_to_tsquery with the 'simple'
+// configuration: every word must be in the message unless it's negated with
+// a leading "-". Messages rank by how often the words occur.
+func (s *Store) SearchRoomMessages(ctx context.Context, arg pg.SearchRoomMessagesParams) ([]pg.SearchRoomMessagesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var include, exclude []string
+	for _, term := range strings.Fields(strings.ToLower(arg.Query)) {
+		if negated, ok := strings.CutPrefix(term, "-"); ok {
+			exclude = append(exclude, searchWords(negated)...)
+			continue
+		}
+		include = append(include, searchWords(term)...)
+	}
+
+	var rows []pg.SearchRoomMessagesRow
+	for _, m := range s.roomMessages(arg.RoomID, visible) {
+		counts := map[string]int{}
+		for _, word := range searchWords(m.Message) {
+			counts[word]++
+		}
+		rank := 0
+		for _, word := range include {
+			if counts[word] == 0 {
+				rank = 0
+				break
+			}
+			rank += counts[word]
+		}
+		if rank == 0 || slices.ContainsFunc(exclude, func(word string) bool { return counts[word] > 0 }) {
+			continue
+		}
+		rows = append(rows, pg.SearchRoomMessagesRow{
+			ID:            m.ID,
+			Message:       m.Message,
+			AuthorName:    m.AuthorName,
+			ReactionCount: m.ReactionCount,
+			Answered:      m.Answered,
+			CreatedAt:     m.CreatedAt,
+			Rank:          float32(rank),
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.SearchRoomMessagesRow) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return limit(rows, arg.MaxMessages), nil
+}
+
+// searchWords splits text into the lowercase words of letters and digits
+// the 'simple' configuration indexes.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+func (s *Store) GetRoomSessionMessages(ctx context.Context, arg pg.GetRoomSessionMessagesParams) ([]pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.roomMessages(arg.RoomID, func(m pg.Message) bool {
+		return !m.DeletedAt.Valid && arg.SessionID.Valid && equalNullable(m.SessionID, arg.SessionID.UUID)
+	})
+	slices.SortStableFunc(messages, func(a, b pg.Message) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	return messages, nil
+}
+
+func (s *Store) GetRoomTopMessages(ctx context.Context, arg pg.GetRoomTopMessagesParams) ([]pg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.roomMessages(arg.RoomID, visible)
+	slices.SortStableFunc(messages, compareTop)
+
+	return limit(messages, arg.Limit), nil
+}
+
+// compareTop is ORDER BY reaction_count DESC, created_at ASC.
+func compareTop(a, b pg.Message) int {
+	if a.ReactionCount != b.ReactionCount {
+		if a.ReactionCount > b.ReactionCount {
+			return -1
+		}
+		return 1
+	}
+
+	return a.CreatedAt.Compare(b.CreatedAt)
+}
+
+func (s *Store) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, m := range s.t.messages {
+		if m.RoomID == roomID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ReactToMessage records one reaction per session and message; a second one
+// leaves the count as it was and reports nothing reacted.
+func (s *Store) ReactToMessage(ctx context.Context, arg pg.ReactToMessageParams) (pg.ReactToMessageRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 {
+		return pg.ReactToMessageRow{}, errNoRows
+	}
+
+	if slices.ContainsFunc(s.t.messageReactions, func(mr pg.MessageReaction) bool {
+		return mr.MessageID == arg.ID && equalNullable(mr.SessionID, arg.SessionID)
+	}) {
+		return pg.ReactToMessageRow{ReactionCount: s.t.messages[i].ReactionCount}, nil
+	}
+
+	s.t.messageReactions = append(s.t.messageReactions, pg.MessageReaction{
+		ID:        newID(),
+		MessageID: arg.ID,
+		RoomID:    s.t.messages[i].RoomID,
+		Kind:      arg.Kind,
+		CreatedAt: s.now(),
+		SessionID: uuid.NullUUID{UUID: arg.SessionID, Valid: true},
+	})
+	s.t.messages[i].ReactionCount++
+
+	return pg.ReactToMessageRow{ReactionCount: s.t.messages[i].ReactionCount, Reacted: 1}, nil
+}
+
+func (s *Store) RemoveReactionFromMessage(ctx context.Context, arg pg.RemoveReactionFromMessageParams) (pg.RemoveReactionFromMessageRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.messageReactions)
+	s.t.messageReactions = slices.DeleteFunc(s.t.messageReactions, func(mr pg.MessageReaction) bool {
+		return mr.MessageID == arg.ID && equalNullable(mr.SessionID, arg.SessionID) && mr.Kind == arg.Kind
+	})
+	removed := int64(before - len(s.t.messageReactions))
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 {
+		return pg.RemoveReactionFromMessageRow{}, errNoRows
+	}
+	s.t.messages[i].ReactionCount -= removed
+
+	return pg.RemoveReactionFromMessageRow{ReactionCount: s.t.messages[i].ReactionCount, Removed: removed}, nil
+}
+
+// AddMessageReactions adds reactions no session made, like upvotes counted
+// on another platform.
+func (s *Store) AddMessageReactions(ctx context.Context, arg pg.AddMessageReactionsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 {
+		return 0, errNoRows
+	}
+
+	now := s.now()
+	for range max(arg.Count, 0) {
+		s.t.messageReactions = append(s.t.messageReactions, pg.MessageReaction{
+			ID:        newID(),
+			MessageID: arg.ID,
+			RoomID:    s.t.messages[i].RoomID,
+			Kind:      arg.Kind,
+			CreatedAt: now,
+		})
+		s.t.messages[i].ReactionCount++
+	}
+
+	return s.t.messages[i].ReactionCount, nil
+}
+
+func (s *Store) MarkMessageAsAnswered(ctx context.Context, arg pg.MarkMessageAsAnsweredParams) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || !versionIs(s.t.messages[i], arg.Version) {
+		return 0, errNoRows
+	}
+	m := &s.t.messages[i]
+	m.Answered = true
+	m.AnswerText = arg.AnswerText
+	m.AnswerAudioUrl = ""
+	m.AnswerVideoUrl = arg.AnswerVideoUrl
+	m.AnswerVideoOffset = arg.AnswerVideoOffset
+	m.Version++
+
+	return m.Version, nil
+}
+
+func (s *Store) MarkMessageAsUnanswered(ctx context.Context, arg pg.MarkMessageAsUnansweredParams) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || !versionIs(s.t.messages[i], arg.Version) {
+		return 0, errNoRows
+	}
+	m := &s.t.messages[i]
+	m.Answered = false
+	m.AnswerText = ""
+	m.AnswerAudioUrl = ""
+	m.AnswerVideoUrl = ""
+	m.AnswerVideoOffset = pgtype.Int4{}
+	m.Version++
+
+	return m.Version, nil
+}
+
+// versionIs is the optional version check of message mutations, which an
+// unset version skips.
+func versionIs(m pg.Message, version pgtype.Int4) bool {
+	return !version.Valid || m.Version == version.Int32
+}
+
+func (s *Store) SetMessageAnswerAudio(ctx context.Context, arg pg.SetMessageAnswerAudioParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || s.t.messages[i].RoomID != arg.RoomID || !s.t.messages[i].Answered || s.t.messages[i].AnswerText != arg.AnswerText {
+		return 0, nil
+	}
+	s.t.messages[i].AnswerAudioUrl = arg.AnswerAudioUrl
+
+	return 1, nil
+}
+
+func (s *Store) EditMessage(ctx context.Context, arg pg.EditMessageParams) (pg.EditMessageRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || s.t.messages[i].Version != arg.Version {
+		return pg.EditMessageRow{}, errNoRows
+	}
+
+	s.t.messageEdits = append(s.t.messageEdits, pg.MessageEdit{
+		ID:              newID(),
+		MessageID:       arg.ID,
+		PreviousMessage: s.t.messages[i].Message,
+		CreatedAt:       s.now(),
+	})
+	s.t.messages[i].Message = arg.Message
+	s.t.messages[i].Language = arg.Language
+	s.t.messages[i].EditedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+	s.t.messages[i].Version++
+
+	return pg.EditMessageRow{Message: arg.Message, EditedAt: s.t.messages[i].EditedAt, Version: s.t.messages[i].Version}, nil
+}
+
+func (s *Store) SoftDeleteMessage(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.messageIndex(id); i >= 0 && !s.t.messages[i].DeletedAt.Valid {
+		s.t.messages[i].DeletedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+		s.t.messages[i].Version++
+	}
+
+	return nil
+}
+
+func (s *Store) PinMessage(ctx context.Context, arg pg.PinMessageParams) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.messageIndex(arg.ID)
+	if i < 0 || !versionIs(s.t.messages[i], arg.Version) {
+		return 0, errNoRows
+	}
+	m := &s.t.messages[i]
+	m.Pinned = true
+	m.Version++
+
+	return m.Version, nil
+}
+
+func (s *Store) AddMessageTag(ctx context.Context, arg pg.AddMessageTagParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.messageIndex(arg.ID); i >= 0 && !slices.Contains(s.t.messages[i].Tags, arg.Tag) {
+		s.t.messages[i].Tags = append(slices.Clip(s.t.messages[i].Tags), arg.Tag)
+		s.t.messages[i].Version++
+	}
+
+	return nil
+}
+
+func (s *Store) InsertMessageReport(ctx context.Context, arg pg.InsertMessageReportParams) (pg.MessageReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.messageIndex(arg.MessageID) < 0 {
+		return pg.MessageReport{}, foreignKeyError("message_reports_message_id_fkey")
+	}
+
+	report := pg.MessageReport{ID: newID(), MessageID: arg.MessageID, Reason: arg.Reason, CreatedAt: s.now()}
+	s.t.messageReports = append(s.t.messageReports, report)
+
+	return report, nil
+}
+
+// GetRoomModerationQueue counts only the reports made since a message was
+// last reviewed.
+func (s *Store) GetRoomModerationQueue(ctx context.Context, arg pg.GetRoomModerationQueueParams) ([]pg.GetRoomModerationQueueRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetRoomModerationQueueRow
+	for _, m := range s.t.messages {
+		if m.RoomID != arg.RoomID || m.DeletedAt.Valid {
+			continue
+		}
+		var reports int64
+		for _, mr := range s.t.messageReports {
+			if mr.MessageID == m.ID && (!m.ReviewedAt.Valid || mr.CreatedAt.After(m.ReviewedAt.Time)) {
+				reports++
+			}
+		}
+		if !m.HiddenAt.Valid && reports == 0 {
+			continue
+		}
+		rows = append(rows, pg.GetRoomModerationQueueRow{
+			ID:          m.ID,
+			Message:     m.Message,
+			AuthorName:  m.AuthorName,
+			CreatedAt:   m.CreatedAt,
+			Toxicity:    m.Toxicity,
+			HiddenAt:    m.HiddenAt,
+			ReportCount: reports,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetRoomModerationQueueRow) int {
+		if a.Toxicity.Valid != b.Toxicity.Valid {
+			if a.Toxicity.Valid {
+				return -1
+			}
+			return 1
+		}
+		if a.Toxicity.Float32 != b.Toxicity.Float32 {
+			if a.Toxicity.Float32 > b.Toxicity.Float32 {
+				return -1
+			}
+			return 1
+		}
+		if a.ReportCount != b.ReportCount {
+			if a.ReportCount > b.ReportCount {
+				return -1
+			}
+			return 1
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return limit(rows, arg.Limit), nil
+}
+
+func (s *Store) ApproveMessage(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.messageIndex(id); i >= 0 {
+		s.t.messages[i].HiddenAt = pgtype.Timestamptz{}
+		s.t.messages[i].ReviewedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+	}
+
+	return nil
+}
+
+func (s *Store) GetRoomReactionTotals(ctx context.Context, roomID uuid.UUID) ([]pg.GetRoomReactionTotalsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetRoomReactionTotalsRow
+	for _, mr := range s.t.messageReactions {
+		if mr.RoomID != roomID {
+			continue
+		}
+		if i := slices.IndexFunc(rows, func(r pg.GetRoomReactionTotalsRow) bool { return r.Kind == mr.Kind }); i >= 0 {
+			rows[i].Count++
+			continue
+		}
+		rows = append(rows, pg.GetRoomReactionTotalsRow{Kind: mr.Kind, Count: 1})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetRoomReactionTotalsRow) int { return int(b.Count - a.Count) })
+
+	return rows, nil
+}
+
+func (s *Store) GetRoomReactionBuckets(ctx context.Context, arg pg.GetRoomReactionBucketsParams) ([]pg.GetRoomReactionBucketsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	width := float64(arg.BucketSeconds)
+	var rows []pg.GetRoomReactionBucketsRow
+	for _, mr := range s.t.messageReactions {
+		if mr.RoomID != arg.RoomID {
+			continue
+		}
+		epoch := float64(mr.CreatedAt.UnixMicro()) / 1e6
+		start := time.Unix(int64(math.Floor(epoch/width)*width), 0)
+		i := slices.IndexFunc(rows, func(r pg.GetRoomReactionBucketsRow) bool {
+			return r.MessageID == mr.MessageID && r.Kind == mr.Kind && r.BucketStart.Equal(start)
+		})
+		if i >= 0 {
+			rows[i].Count++
+			continue
+		}
+		rows = append(rows, pg.GetRoomReactionBucketsRow{MessageID: mr.MessageID, Kind: mr.Kind, BucketStart: start, Count: 1})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetRoomReactionBucketsRow) int {
+		if c := compareIDs(a.MessageID, b.MessageID); c != 0 {
+			return c
+		}
+		return a.BucketStart.Compare(b.BucketStart)
+	})
+
+	return rows, nil
+}
+
+func (s *Store) GetRoomActivity(ctx context.Context, arg pg.GetRoomActivityParams) ([]pg.GetRoomActivityRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetRoomActivityRow
+	bucket := func(createdAt time.Time) *pg.GetRoomActivityRow {
+		start := truncate(arg.Unit, createdAt)
+		if i := slices.IndexFunc(rows, func(r pg.GetRoomActivityRow) bool { return r.BucketStart.Equal(start) }); i >= 0 {
+			return &rows[i]
+		}
+		rows = append(rows, pg.GetRoomActivityRow{BucketStart: start})
+		return &rows[len(rows)-1]
+	}
+	for _, m := range s.t.messages {
+		if m.RoomID == arg.RoomID {
+			bucket(m.CreatedAt).Messages++
+		}
+	}
+	for _, mr := range s.t.messageReactions {
+		if mr.RoomID == arg.RoomID {
+			bucket(mr.CreatedAt).Reactions++
+		}
+	}
+	slices.SortFunc(rows, func(a, b pg.GetRoomActivityRow) int { return a.BucketStart.Compare(b.BucketStart) })
+
+	return rows, nil
+}
+
+// roomStats computes what the triggers keep in room_stats. The last activity
+// is that of the latest message or reaction, so unlike in room_stats, one
+// moves back when the latest reaction is removed.
+func (s *Store) roomStats(roomID uuid.UUID) pg.RoomStat {
+	stats := pg.RoomStat{RoomID: roomID}
+	seen := func(at time.Time) {
+		if !stats.LastActivity.Valid || at.After(stats.LastActivity.Time) {
+			stats.LastActivity = pgtype.Timestamptz{Time: at, Valid: true}
+		}
+	}
+	for _, m := range s.t.messages {
+		if m.RoomID != roomID {
+			continue
+		}
+		seen(m.CreatedAt)
+		if !m.DeletedAt.Valid {
+			stats.MessageCount++
+			if !m.Answered {
+				stats.UnansweredCount++
+			}
+		}
+	}
+	for _, mr := range s.t.messageReactions {
+		if mr.RoomID == roomID {
+			stats.ReactionTotal++
+			seen(mr.CreatedAt)
+		}
+	}
+
+	return stats
+}
+
+func (s *Store) GetRoomStats(ctx context.Context, roomID uuid.UUID) (pg.RoomStat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(roomID) < 0 {
+		return pg.RoomStat{}, errNoRows
+	}
+
+	return s.roomStats(roomID), nil
+}
+
+func (s *Store) GetRoomLanguageCounts(ctx context.Context, roomID uuid.UUID) ([]pg.GetRoomLanguageCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetRoomLanguageCountsRow
+	for _, m := range s.t.messages {
+		if m.RoomID != roomID || m.DeletedAt.Valid {
+			continue
+		}
+		if i := slices.IndexFunc(rows, func(r pg.GetRoomLanguageCountsRow) bool { return r.Language == m.Language }); i >= 0 {
+			rows[i].Count++
+			continue
+		}
+		rows = append(rows, pg.GetRoomLanguageCountsRow{Language: m.Language, Count: 1})
+	}
+	slices.SortFunc(rows, func(a, b pg.GetRoomLanguageCountsRow) int {
+		if a.Count != b.Count {
+			return int(b.Count - a.Count)
+		}
+		return strings.Compare(a.Language, b.Language)
+	})
+
+	return rows, nil
+}
+
+func (s *Store) GetMessageTranslation(ctx context.Context, arg pg.GetMessageTranslationParams) (pg.MessageTranslation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.messageTranslations, func(t pg.MessageTranslation) bool {
+		return t.MessageID == arg.MessageID && t.Language == arg.Language
+	})
+	if i < 0 {
+		return pg.MessageTranslation{}, errNoRows
+	}
+
+	return s.t.messageTranslations[i], nil
+}
+
+func (s *Store) UpsertMessageTranslation(ctx context.Context, arg pg.UpsertMessageTranslationParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.messageIndex(arg.MessageID) < 0 {
+		return foreignKeyError("message_translations_message_id_fkey")
+	}
+
+	translation := pg.MessageTranslation{
+		MessageID:  arg.MessageID,
+		Language:   arg.Language,
+		SourceHash: arg.SourceHash,
+		Text:       arg.Text,
+		CreatedAt:  s.now(),
+	}
+	if i := slices.IndexFunc(s.t.messageTranslations, func(t pg.MessageTranslation) bool {
+		return t.MessageID == arg.MessageID && t.Language == arg.Language
+	}); i >= 0 {
+		s.t.messageTranslations[i] = translation
+		return nil
+	}
+	s.t.messageTranslations = append(s.t.messageTranslations, translation)
+
+	return nil
+}
+
+func (s *Store) InsertCaption(ctx context.Context, arg pg.InsertCaptionParams) (pg.Caption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return pg.Caption{}, foreignKeyError("captions_room_id_fkey")
+	}
+
+	caption := pg.Caption{
+		ID:        newID(),
+		RoomID:    arg.RoomID,
+		Text:      arg.Text,
+		Language:  arg.Language,
+		StartMs:   arg.StartMs,
+		EndMs:     arg.EndMs,
+		CreatedAt: s.now(),
+	}
+	s.t.captions = append(s.t.captions, caption)
+
+	return caption, nil
+}
+
+func (s *Store) GetRoomCaptions(ctx context.Context, arg pg.GetRoomCaptionsParams) ([]pg.Caption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var captions []pg.Caption
+	for _, c := range s.t.captions {
+		if c.RoomID == arg.RoomID && c.CreatedAt.After(arg.Since) {
+			captions = append(captions, c)
+		}
+	}
+	slices.SortStableFunc(captions, func(a, b pg.Caption) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIDs(a.ID, b.ID)
+	})
+
+	return limit(captions, arg.MaxCaptions), nil
+}
+
+func (s *Store) GetWebinarQuestion(ctx context.Context, arg pg.GetWebinarQuestionParams) (pg.WebinarQuestion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.webinarQuestions, func(q pg.WebinarQuestion) bool {
+		return q.RoomID == arg.RoomID && q.ExternalID == arg.ExternalID
+	})
+	if i < 0 {
+		return pg.WebinarQuestion{}, errNoRows
+	}
+
+	return s.t.webinarQuestions[i], nil
+}
+
+func (s *Store) InsertWebinarQuestion(ctx context.Context, arg pg.InsertWebinarQuestionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("webinar_questions_room_id_fkey")
+	}
+	if s.messageIndex(arg.MessageID) < 0 {
+		return foreignKeyError("webinar_questions_message_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.webinarQuestions, func(q pg.WebinarQuestion) bool {
+		return q.RoomID == arg.RoomID && q.ExternalID == arg.ExternalID
+	}) {
+		return uniqueError("webinar_questions_pkey")
+	}
+
+	s.t.webinarQuestions = append(s.t.webinarQuestions, pg.WebinarQuestion{
+		RoomID:     arg.RoomID,
+		ExternalID: arg.ExternalID,
+		MessageID:  arg.MessageID,
+		CreatedAt:  s.now(),
+	})
+
+	return nil
+}
+
+func (s *Store) SetWebinarQuestionUpvotes(ctx context.Context, arg pg.SetWebinarQuestionUpvotesParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.t.webinarQuestions, func(q pg.WebinarQuestion) bool {
+		return q.RoomID == arg.RoomID && q.ExternalID == arg.ExternalID
+	}); i >= 0 {
+		s.t.webinarQuestions[i].Upvotes = arg.Upvotes
+	}
+
+	return nil
+}
+
+func (s *Store) InsertIngestDelivery(ctx context.Context, arg pg.InsertIngestDeliveryParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return 0, foreignKeyError("ingest_deliveries_room_id_fkey")
+	}
+	if s.messageIndex(arg.MessageID) < 0 {
+		return 0, foreignKeyError("ingest_deliveries_message_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.ingestDeliveries, func(d pg.IngestDelivery) bool {
+		return d.RoomID == arg.RoomID && d.ExternalID == arg.ExternalID
+	}) {
+		return 0, nil
+	}
+
+	s.t.ingestDeliveries = append(s.t.ingestDeliveries, pg.IngestDelivery{
+		RoomID:     arg.RoomID,
+		ExternalID: arg.ExternalID,
+		MessageID:  arg.MessageID,
+		CreatedAt:  s.now(),
+	})
+
+	return 1, nil
+}
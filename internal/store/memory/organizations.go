@@ -0,0 +1,854 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+func (s *Store) InsertSession(ctx context.Context, arg pg.InsertSessionParams) (pg.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := pg.Session{ID: newID(), UserAgent: arg.UserAgent, ClientIp: arg.ClientIp, CreatedAt: s.now()}
+	s.t.sessions = append(s.t.sessions, session)
+
+	return session, nil
+}
+
+func (s *Store) GetSession(ctx context.Context, id uuid.UUID) (pg.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.sessions, func(session pg.Session) bool { return session.ID == id })
+	if i < 0 {
+		return pg.Session{}, errNoRows
+	}
+
+	return s.t.sessions[i], nil
+}
+
+func (s *Store) InsertOrganization(ctx context.Context, name string) (pg.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	organization := pg.Organization{ID: newID(), Name: name, CreatedAt: s.now(), Settings: []byte("{}")}
+	s.t.organizations = append(s.t.organizations, organization)
+
+	return organization, nil
+}
+
+func (s *Store) GetOrganization(ctx context.Context, id uuid.UUID) (pg.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.organizations, func(o pg.Organization) bool { return o.ID == id })
+	if i < 0 {
+		return pg.Organization{}, errNoRows
+	}
+
+	return s.t.organizations[i], nil
+}
+
+func (s *Store) GetOrganizationByStripeCustomer(ctx context.Context, stripeCustomerID pgtype.Text) (pg.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.organizations, func(o pg.Organization) bool {
+		return o.StripeCustomerID.Valid && stripeCustomerID.Valid && o.StripeCustomerID.String == stripeCustomerID.String
+	})
+	if i < 0 {
+		return pg.Organization{}, errNoRows
+	}
+
+	return s.t.organizations[i], nil
+}
+
+func (s *Store) UpdateOrganizationBilling(ctx context.Context, arg pg.UpdateOrganizationBillingParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.StripeCustomerID.Valid && slices.ContainsFunc(s.t.organizations, func(o pg.Organization) bool {
+		return o.ID != arg.ID && o.StripeCustomerID.Valid && o.StripeCustomerID.String == arg.StripeCustomerID.String
+	}) {
+		return uniqueError("organizations_stripe_customer_id_key")
+	}
+
+	if i := slices.IndexFunc(s.t.organizations, func(o pg.Organization) bool { return o.ID == arg.ID }); i >= 0 {
+		s.t.organizations[i].StripeCustomerID = arg.StripeCustomerID
+		s.t.organizations[i].Settings = slices.Clone(arg.Settings)
+	}
+
+	return nil
+}
+
+func (s *Store) InsertAPIKey(ctx context.Context, arg pg.InsertAPIKeyParams) (pg.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasOrganization(arg.OrganizationID) {
+		return pg.ApiKey{}, foreignKeyError("api_keys_organization_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.apiKeys, func(k pg.ApiKey) bool { return k.KeyHash == arg.KeyHash }) {
+		return pg.ApiKey{}, uniqueError("api_keys_key_hash_key")
+	}
+
+	key := pg.ApiKey{ID: newID(), OrganizationID: arg.OrganizationID, Name: arg.Name, KeyHash: arg.KeyHash, CreatedAt: s.now()}
+	s.t.apiKeys = append(s.t.apiKeys, key)
+
+	return key, nil
+}
+
+func (s *Store) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (pg.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.apiKeys, func(k pg.ApiKey) bool { return k.KeyHash == keyHash && !k.RevokedAt.Valid })
+	if i < 0 {
+		return pg.ApiKey{}, errNoRows
+	}
+
+	return s.t.apiKeys[i], nil
+}
+
+func (s *Store) GetActiveAPIKey(ctx context.Context, id uuid.UUID) (pg.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.apiKeys, func(k pg.ApiKey) bool { return k.ID == id && !k.RevokedAt.Valid })
+	if i < 0 {
+		return pg.ApiKey{}, errNoRows
+	}
+
+	return s.t.apiKeys[i], nil
+}
+
+func (s *Store) AddUsage(ctx context.Context, arg pg.AddUsageParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasOrganization(arg.OrganizationID) {
+		return foreignKeyError("usage_records_organization_id_fkey")
+	}
+
+	if i := slices.IndexFunc(s.t.usageRecords, func(u pg.UsageRecord) bool {
+		return u.OrganizationID == arg.OrganizationID && sameNullable(u.ApiKeyID, arg.ApiKeyID) &&
+			u.Metric == arg.Metric && u.PeriodStart.Equal(arg.PeriodStart)
+	}); i >= 0 {
+		s.t.usageRecords[i].Quantity += arg.Quantity
+		return nil
+	}
+	s.t.usageRecords = append(s.t.usageRecords, pg.UsageRecord{
+		OrganizationID: arg.OrganizationID,
+		ApiKeyID:       arg.ApiKeyID,
+		Metric:         arg.Metric,
+		PeriodStart:    arg.PeriodStart,
+		Quantity:       arg.Quantity,
+	})
+
+	return nil
+}
+
+func (s *Store) GetOrganizationUsage(ctx context.Context, arg pg.GetOrganizationUsageParams) ([]pg.GetOrganizationUsageRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetOrganizationUsageRow
+	for _, u := range s.t.usageRecords {
+		if u.OrganizationID != arg.OrganizationID || u.PeriodStart.Before(arg.FromTime) || !u.PeriodStart.Before(arg.ToTime) {
+			continue
+		}
+		day := truncate("day", u.PeriodStart)
+		if i := slices.IndexFunc(rows, func(r pg.GetOrganizationUsageRow) bool {
+			return r.Day.Equal(day) && sameNullable(r.ApiKeyID, u.ApiKeyID) && r.Metric == u.Metric
+		}); i >= 0 {
+			rows[i].Quantity += u.Quantity
+			continue
+		}
+		rows = append(rows, pg.GetOrganizationUsageRow{Day: day, ApiKeyID: u.ApiKeyID, Metric: u.Metric, Quantity: u.Quantity})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetOrganizationUsageRow) int {
+		if c := a.Day.Compare(b.Day); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Metric, b.Metric)
+	})
+
+	return rows, nil
+}
+
+func (s *Store) CountAdminCredentials(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.t.adminCredentials)), nil
+}
+
+func (s *Store) GetAdminCredentialByHash(ctx context.Context, tokenHash string) (pg.AdminCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.adminCredentials, func(c pg.AdminCredential) bool { return c.TokenHash == tokenHash })
+	if i < 0 {
+		return pg.AdminCredential{}, errNoRows
+	}
+
+	return s.t.adminCredentials[i], nil
+}
+
+func (s *Store) InsertAdminCredential(ctx context.Context, arg pg.InsertAdminCredentialParams) (pg.AdminCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.t.adminCredentials, func(c pg.AdminCredential) bool { return c.TokenHash == arg.TokenHash }) {
+		return pg.AdminCredential{}, uniqueError("admin_credentials_token_hash_key")
+	}
+
+	credential := pg.AdminCredential{ID: newID(), Name: arg.Name, TokenHash: arg.TokenHash, CreatedAt: s.now()}
+	s.t.adminCredentials = append(s.t.adminCredentials, credential)
+
+	return credential, nil
+}
+
+func (s *Store) UpsertSecret(ctx context.Context, arg pg.UpsertSecretParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.OrganizationID.Valid && !s.hasOrganization(arg.OrganizationID.UUID) {
+		return foreignKeyError("secrets_organization_id_fkey")
+	}
+
+	now := s.now()
+	if i := slices.IndexFunc(s.t.secrets, func(secret pg.Secret) bool {
+		return sameNullable(secret.OrganizationID, arg.OrganizationID) && secret.Name == arg.Name
+	}); i >= 0 {
+		secret := &s.t.secrets[i]
+		secret.KeyID = arg.KeyID
+		secret.WrappedKey = slices.Clone(arg.WrappedKey)
+		secret.Ciphertext = slices.Clone(arg.Ciphertext)
+		secret.UpdatedAt = now
+		return nil
+	}
+	s.t.secrets = append(s.t.secrets, pg.Secret{
+		ID:             newID(),
+		OrganizationID: arg.OrganizationID,
+		Name:           arg.Name,
+		KeyID:          arg.KeyID,
+		WrappedKey:     slices.Clone(arg.WrappedKey),
+		Ciphertext:     slices.Clone(arg.Ciphertext),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+
+	return nil
+}
+
+func (s *Store) GetSecret(ctx context.Context, arg pg.GetSecretParams) (pg.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.secrets, func(secret pg.Secret) bool {
+		return sameNullable(secret.OrganizationID, arg.OrganizationID) && secret.Name == arg.Name
+	})
+	if i < 0 {
+		return pg.Secret{}, errNoRows
+	}
+
+	return s.t.secrets[i], nil
+}
+
+func (s *Store) GetSecrets(ctx context.Context) ([]pg.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets := slices.Clone(s.t.secrets)
+	slices.SortStableFunc(secrets, func(a, b pg.Secret) int {
+		if a.OrganizationID.Valid != b.OrganizationID.Valid {
+			if a.OrganizationID.Valid {
+				return 1
+			}
+			return -1
+		}
+		if c := compareIDs(a.OrganizationID.UUID, b.OrganizationID.UUID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return secrets, nil
+}
+
+func (s *Store) RewrapSecret(ctx context.Context, arg pg.RewrapSecretParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.secrets, func(secret pg.Secret) bool { return secret.ID == arg.ID && secret.KeyID == arg.PreviousKeyID })
+	if i < 0 {
+		return 0, nil
+	}
+	s.t.secrets[i].KeyID = arg.KeyID
+	s.t.secrets[i].WrappedKey = slices.Clone(arg.WrappedKey)
+
+	return 1, nil
+}
+
+func (s *Store) DeleteSecret(ctx context.Context, arg pg.DeleteSecretParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.secrets)
+	s.t.secrets = slices.DeleteFunc(s.t.secrets, func(secret pg.Secret) bool {
+		return sameNullable(secret.OrganizationID, arg.OrganizationID) && secret.Name == arg.Name
+	})
+
+	return int64(before - len(s.t.secrets)), nil
+}
+
+func (s *Store) InsertEvent(ctx context.Context, arg pg.InsertEventParams) (pg.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasOrganization(arg.OrganizationID) {
+		return pg.Event{}, foreignKeyError("events_organization_id_fkey")
+	}
+
+	event := pg.Event{
+		ID:             newID(),
+		OrganizationID: arg.OrganizationID,
+		Name:           arg.Name,
+		StartsAt:       arg.StartsAt,
+		EndsAt:         arg.EndsAt,
+		CreatedAt:      s.now(),
+		RoomDefaults:   []byte("{}"),
+	}
+	s.t.events = append(s.t.events, event)
+
+	return event, nil
+}
+
+func (s *Store) GetOrganizationEvents(ctx context.Context, organizationID uuid.UUID) ([]pg.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []pg.Event
+	for _, e := range s.t.events {
+		if e.OrganizationID == organizationID {
+			events = append(events, e)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b pg.Event) int {
+		if c := compareStartsAt(a.StartsAt, b.StartsAt); c != 0 {
+			return c
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return events, nil
+}
+
+// compareStartsAt is ORDER BY starts_at ASC NULLS LAST.
+func compareStartsAt(a, b pgtype.Timestamptz) int {
+	if a.Valid != b.Valid {
+		if a.Valid {
+			return -1
+		}
+		return 1
+	}
+
+	return a.Time.Compare(b.Time)
+}
+
+func (s *Store) GetOrganizationEvent(ctx context.Context, arg pg.GetOrganizationEventParams) (pg.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.eventIndex(arg.ID)
+	if i < 0 || s.t.events[i].OrganizationID != arg.OrganizationID {
+		return pg.Event{}, errNoRows
+	}
+
+	return s.t.events[i], nil
+}
+
+// DeleteEvent cascades to the event's tracks, detaching their rooms.
+func (s *Store) DeleteEvent(ctx context.Context, arg pg.DeleteEventParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.eventIndex(arg.ID)
+	if i < 0 || s.t.events[i].OrganizationID != arg.OrganizationID {
+		return 0, nil
+	}
+	s.t.events = slices.Delete(s.t.events, i, i+1)
+	for _, t := range s.eventTracks(arg.ID) {
+		s.deleteTrack(t.ID)
+	}
+
+	return 1, nil
+}
+
+func (s *Store) SetEventRoomDefaults(ctx context.Context, arg pg.SetEventRoomDefaultsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.eventIndex(arg.ID); i >= 0 {
+		s.t.events[i].RoomDefaults = slices.Clone(arg.RoomDefaults)
+	}
+
+	return nil
+}
+
+func (s *Store) InsertTrack(ctx context.Context, arg pg.InsertTrackParams) (pg.Track, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.eventIndex(arg.EventID) < 0 {
+		return pg.Track{}, foreignKeyError("tracks_event_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.tracks, func(t pg.Track) bool { return t.EventID == arg.EventID && t.Name == arg.Name }) {
+		return pg.Track{}, uniqueError("tracks_event_id_name_key")
+	}
+
+	track := pg.Track{ID: newID(), EventID: arg.EventID, Name: arg.Name, Position: arg.Position, CreatedAt: s.now()}
+	s.t.tracks = append(s.t.tracks, track)
+
+	return track, nil
+}
+
+// eventTracks returns the event's tracks by position.
+func (s *Store) eventTracks(eventID uuid.UUID) []pg.Track {
+	var tracks []pg.Track
+	for _, t := range s.t.tracks {
+		if t.EventID == eventID {
+			tracks = append(tracks, t)
+		}
+	}
+	slices.SortStableFunc(tracks, func(a, b pg.Track) int {
+		if a.Position != b.Position {
+			return int(a.Position - b.Position)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return tracks
+}
+
+func (s *Store) GetEventTracks(ctx context.Context, eventID uuid.UUID) ([]pg.Track, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.eventTracks(eventID), nil
+}
+
+func (s *Store) GetEventTrack(ctx context.Context, arg pg.GetEventTrackParams) (pg.Track, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.trackIndex(arg.ID)
+	if i < 0 || s.t.tracks[i].EventID != arg.EventID {
+		return pg.Track{}, errNoRows
+	}
+
+	return s.t.tracks[i], nil
+}
+
+func (s *Store) DeleteTrack(ctx context.Context, arg pg.DeleteTrackParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.trackIndex(arg.ID)
+	if i < 0 || s.t.tracks[i].EventID != arg.EventID {
+		return 0, nil
+	}
+	s.deleteTrack(arg.ID)
+
+	return 1, nil
+}
+
+// deleteTrack removes a track and sets the track of its rooms to NULL.
+func (s *Store) deleteTrack(id uuid.UUID) {
+	s.t.tracks = slices.DeleteFunc(s.t.tracks, func(t pg.Track) bool { return t.ID == id })
+	for i, r := range s.t.rooms {
+		if equalNullable(r.TrackID, id) {
+			s.t.rooms[i].TrackID = uuid.NullUUID{}
+		}
+	}
+}
+
+func (s *Store) GetOrganizationTrackRoomDefaults(ctx context.Context, arg pg.GetOrganizationTrackRoomDefaultsParams) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.trackIndex(arg.ID)
+	if t < 0 {
+		return nil, errNoRows
+	}
+	e := s.eventIndex(s.t.tracks[t].EventID)
+	if e < 0 || s.t.events[e].OrganizationID != arg.OrganizationID {
+		return nil, errNoRows
+	}
+
+	return slices.Clone(s.t.events[e].RoomDefaults), nil
+}
+
+// eventRoom is a room joined with its track.
+type eventRoom struct {
+	room  pg.Room
+	track pg.Track
+}
+
+// eventRooms returns the rooms of the event's tracks, by track position and
+// then by start.
+func (s *Store) eventRooms(eventID uuid.UUID) []eventRoom {
+	var rooms []eventRoom
+	for _, t := range s.eventTracks(eventID) {
+		var trackRooms []pg.Room
+		for _, r := range s.t.rooms {
+			if equalNullable(r.TrackID, t.ID) {
+				trackRooms = append(trackRooms, r)
+			}
+		}
+		slices.SortStableFunc(trackRooms, func(a, b pg.Room) int {
+			if c := compareStartsAt(a.StartsAt, b.StartsAt); c != 0 {
+				return c
+			}
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		for _, r := range trackRooms {
+			rooms = append(rooms, eventRoom{room: r, track: t})
+		}
+	}
+
+	return rooms
+}
+
+func (s *Store) GetEventRoomStats(ctx context.Context, eventID uuid.UUID) ([]pg.GetEventRoomStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetEventRoomStatsRow
+	for _, er := range s.eventRooms(eventID) {
+		r := er.room
+		stats := s.roomStats(r.ID)
+		rows = append(rows, pg.GetEventRoomStatsRow{
+			ID:            r.ID,
+			TrackID:       r.TrackID,
+			Code:          r.Code,
+			Theme:         r.Theme,
+			ArchivedAt:    r.ArchivedAt,
+			ClosedAt:      r.ClosedAt,
+			StartsAt:      r.StartsAt,
+			HostName:      r.HostName,
+			MessageCount:  stats.MessageCount,
+			AnsweredCount: stats.MessageCount - stats.UnansweredCount,
+			ReactionCount: stats.ReactionTotal,
+		})
+	}
+
+	return rows, nil
+}
+
+func (s *Store) GetEventReportRooms(ctx context.Context, eventID uuid.UUID) ([]pg.GetEventReportRoomsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetEventReportRoomsRow
+	for _, er := range s.eventRooms(eventID) {
+		r := er.room
+		stats := s.roomStats(r.ID)
+
+		var peak int32
+		if i := slices.IndexFunc(s.t.roomPeaks, func(p pg.RoomPeak) bool { return p.RoomID == r.ID }); i >= 0 {
+			peak = s.t.roomPeaks[i].PeakSubscribers
+		}
+		//? COUNT(DISTINCT) skips NULL sessions
+		participants := map[uuid.UUID]bool{}
+		for _, m := range s.t.messages {
+			if m.RoomID == r.ID && !m.DeletedAt.Valid && m.SessionID.Valid {
+				participants[m.SessionID.UUID] = true
+			}
+		}
+
+		rows = append(rows, pg.GetEventReportRoomsRow{
+			ID:               r.ID,
+			Code:             r.Code,
+			Theme:            r.Theme,
+			StartsAt:         r.StartsAt,
+			HostName:         r.HostName,
+			TrackName:        er.track.Name,
+			PeakSubscribers:  peak,
+			ParticipantCount: int64(len(participants)),
+			MessageCount:     stats.MessageCount,
+			AnsweredCount:    stats.MessageCount - stats.UnansweredCount,
+			ReactionCount:    stats.ReactionTotal,
+		})
+	}
+
+	return rows, nil
+}
+
+func (s *Store) GetEventTopMessages(ctx context.Context, arg pg.GetEventTopMessagesParams) ([]pg.GetEventTopMessagesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rooms := s.eventRooms(arg.EventID)
+	slices.SortFunc(rooms, func(a, b eventRoom) int { return compareIDs(a.room.ID, b.room.ID) })
+
+	var rows []pg.GetEventTopMessagesRow
+	for _, er := range rooms {
+		messages := s.roomMessages(er.room.ID, visible)
+		slices.SortStableFunc(messages, compareTop)
+		for _, m := range limit(messages, arg.PerRoom) {
+			rows = append(rows, pg.GetEventTopMessagesRow{
+				ID:            m.ID,
+				RoomID:        m.RoomID,
+				Message:       m.Message,
+				ReactionCount: m.ReactionCount,
+				Answered:      m.Answered,
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+func (s *Store) InsertJob(ctx context.Context, arg pg.InsertJobParams) (pg.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.OrganizationID.Valid && !s.hasOrganization(arg.OrganizationID.UUID) {
+		return pg.Job{}, foreignKeyError("jobs_organization_id_fkey")
+	}
+
+	job := pg.Job{
+		ID:             newID(),
+		Kind:           arg.Kind,
+		Payload:        slices.Clone(arg.Payload),
+		OrganizationID: arg.OrganizationID,
+		Status:         jobQueued,
+		CreatedAt:      s.now(),
+	}
+	s.t.jobs = append(s.t.jobs, job)
+
+	return job, nil
+}
+
+func (s *Store) InsertJobUnlessPending(ctx context.Context, arg pg.InsertJobUnlessPendingParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.t.jobs, func(j pg.Job) bool {
+		return j.Kind == arg.Kind && (j.Status == jobQueued || j.Status == jobRunning)
+	}) {
+		return 0, nil
+	}
+
+	s.t.jobs = append(s.t.jobs, pg.Job{
+		ID:        newID(),
+		Kind:      arg.Kind,
+		Payload:   slices.Clone(arg.Payload),
+		Status:    jobQueued,
+		CreatedAt: s.now(),
+	})
+
+	return 1, nil
+}
+
+// ClaimJob takes the oldest job that is queued, or running since before
+// staleBefore.
+func (s *Store) ClaimJob(ctx context.Context, staleBefore time.Time) (pg.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claim := -1
+	for i, j := range s.t.jobs {
+		claimable := j.Status == jobQueued || (j.Status == jobRunning && j.StartedAt.Valid && j.StartedAt.Time.Before(staleBefore))
+		if claimable && (claim < 0 || j.CreatedAt.Before(s.t.jobs[claim].CreatedAt)) {
+			claim = i
+		}
+	}
+	if claim < 0 {
+		return pg.Job{}, errNoRows
+	}
+
+	j := &s.t.jobs[claim]
+	j.Status = jobRunning
+	j.Attempts++
+	j.StartedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+
+	return *j, nil
+}
+
+func (s *Store) CompleteJob(ctx context.Context, arg pg.CompleteJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.t.jobs, func(j pg.Job) bool { return j.ID == arg.ID }); i >= 0 {
+		j := &s.t.jobs[i]
+		j.Status = jobDone
+		j.Output = slices.Clone(arg.Output)
+		j.ContentType = arg.ContentType
+		j.Filename = arg.Filename
+		j.Error = ""
+		j.FinishedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+	}
+
+	return nil
+}
+
+func (s *Store) FailJob(ctx context.Context, arg pg.FailJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.t.jobs, func(j pg.Job) bool { return j.ID == arg.ID }); i >= 0 {
+		j := &s.t.jobs[i]
+		j.Error = arg.Error
+		if j.Attempts >= arg.MaxAttempts {
+			j.Status = jobFailed
+			j.FinishedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+			s.putDeadLetter(pg.DeadLetter{
+				Source:         "job",
+				Ref:            j.ID.String(),
+				Kind:           j.Kind,
+				OrganizationID: j.OrganizationID,
+				Error:          j.Error,
+				Attempts:       j.Attempts,
+			})
+		} else {
+			j.Status = jobQueued
+			j.FinishedAt = pgtype.Timestamptz{}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) RetryJob(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.jobs, func(j pg.Job) bool { return j.ID == id && j.Status == jobFailed })
+	if i < 0 {
+		return 0, nil
+	}
+	j := &s.t.jobs[i]
+	j.Status = jobQueued
+	j.Attempts = 0
+	j.Error = ""
+	j.StartedAt = pgtype.Timestamptz{}
+	j.FinishedAt = pgtype.Timestamptz{}
+
+	return 1, nil
+}
+
+// putDeadLetter upserts on (source, ref), as the failing queries do.
+func (s *Store) putDeadLetter(dl pg.DeadLetter) {
+	dl.CreatedAt = s.now()
+	if i := slices.IndexFunc(s.t.deadLetters, func(d pg.DeadLetter) bool { return d.Source == dl.Source && d.Ref == dl.Ref }); i >= 0 {
+		d := &s.t.deadLetters[i]
+		d.Error, d.Attempts, d.CreatedAt = dl.Error, dl.Attempts, dl.CreatedAt
+		return
+	}
+	dl.ID = newID()
+	s.t.deadLetters = append(s.t.deadLetters, dl)
+}
+
+func (s *Store) GetDeadLetters(ctx context.Context, arg pg.GetDeadLettersParams) ([]pg.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var letters []pg.DeadLetter
+	for _, d := range s.t.deadLetters {
+		if !arg.Source.Valid || d.Source == arg.Source.String {
+			letters = append(letters, d)
+		}
+	}
+	slices.SortStableFunc(letters, func(a, b pg.DeadLetter) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIDs(b.ID, a.ID)
+	})
+
+	return limit(letters, arg.MaxDeadLetters), nil
+}
+
+func (s *Store) GetDeadLetter(ctx context.Context, id uuid.UUID) (pg.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.deadLetters, func(d pg.DeadLetter) bool { return d.ID == id })
+	if i < 0 {
+		return pg.DeadLetter{}, errNoRows
+	}
+
+	return s.t.deadLetters[i], nil
+}
+
+func (s *Store) DeleteDeadLetter(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.t.deadLetters)
+	s.t.deadLetters = slices.DeleteFunc(s.t.deadLetters, func(d pg.DeadLetter) bool { return d.ID == id })
+
+	return int64(n - len(s.t.deadLetters)), nil
+}
+
+func (s *Store) GetDeadLetterStats(ctx context.Context) ([]pg.GetDeadLetterStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetDeadLetterStatsRow
+	for _, d := range s.t.deadLetters {
+		i := slices.IndexFunc(rows, func(r pg.GetDeadLetterStatsRow) bool { return r.Source == d.Source })
+		if i < 0 {
+			rows = append(rows, pg.GetDeadLetterStatsRow{Source: d.Source, Oldest: d.CreatedAt})
+			i = len(rows) - 1
+		}
+		rows[i].Count++
+		if d.CreatedAt.Before(rows[i].Oldest) {
+			rows[i].Oldest = d.CreatedAt
+		}
+	}
+
+	return rows, nil
+}
+
+func (s *Store) GetJob(ctx context.Context, id uuid.UUID) (pg.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.jobs, func(j pg.Job) bool { return j.ID == id })
+	if i < 0 {
+		return pg.Job{}, errNoRows
+	}
+
+	return s.t.jobs[i], nil
+}
+
+func (s *Store) GetOrganizationJob(ctx context.Context, arg pg.GetOrganizationJobParams) (pg.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.jobs, func(j pg.Job) bool {
+		return j.ID == arg.ID && arg.OrganizationID.Valid && equalNullable(j.OrganizationID, arg.OrganizationID.UUID)
+	})
+	if i < 0 {
+		return pg.Job{}, errNoRows
+	}
+
+	return s.t.jobs[i], nil
+}
@@ -0,0 +1,1107 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// trigramThreshold is pg_trgm's default similarity threshold, the one the %
+// operator applies.
+const trigramThreshold = 0.3
+
+// copyRoom detaches the slices of a room from the table.
+func copyRoom(r pg.Room) pg.Room {
+	r.FormSchema = slices.Clone(r.FormSchema)
+	r.TranslateTo = slices.Clone(r.TranslateTo)
+
+	return r
+}
+
+// matchesStatus is the filter of GetRooms and GetRoomsPage: archived rooms
+// only when includeArchived is set, then those of status unless it's empty.
+func matchesStatus(r pg.Room, includeArchived bool, status string) bool {
+	if r.ArchivedAt.Valid && !includeArchived {
+		return false
+	}
+	return status == "" || roomStatus(r) == status
+}
+
+// roomStatus is the status column of GetRooms and GetRoomsPage.
+func roomStatus(r pg.Room) string {
+	switch {
+	case r.ArchivedAt.Valid:
+		return "archived"
+	case r.ClosedAt.Valid:
+		return "closed"
+	default:
+		return "active"
+	}
+}
+
+func (s *Store) GetRoom(ctx context.Context, id uuid.UUID) (pg.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.roomIndex(id)
+	if i < 0 {
+		return pg.Room{}, errNoRows
+	}
+
+	return copyRoom(s.t.rooms[i]), nil
+}
+
+func (s *Store) GetRoomByCode(ctx context.Context, code string) (pg.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.rooms, func(r pg.Room) bool { return r.Code == code })
+	if i < 0 {
+		return pg.Room{}, errNoRows
+	}
+
+	return copyRoom(s.t.rooms[i]), nil
+}
+
+func (s *Store) GetRooms(ctx context.Context, arg pg.GetRoomsParams) ([]pg.GetRoomsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listRooms(pg.GetRoomsPageParams{IncludeArchived: arg.IncludeArchived, Status: arg.Status}), nil
+}
+
+func (s *Store) GetRoomsPage(ctx context.Context, arg pg.GetRoomsPageParams) ([]pg.GetRoomsPageRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listed := limit(s.listRooms(arg), arg.PageSize)
+	rows := make([]pg.GetRoomsPageRow, 0, len(listed))
+	for _, row := range listed {
+		rows = append(rows, pg.GetRoomsPageRow(row))
+	}
+
+	return rows, nil
+}
+
+// listRooms is GetRooms, from the cursor of page when it has one.
+func (s *Store) listRooms(page pg.GetRoomsPageParams) []pg.GetRoomsRow {
+	var rows []pg.GetRoomsRow
+	for _, r := range s.t.rooms {
+		if !matchesStatus(r, page.IncludeArchived, page.Status) {
+			continue
+		}
+		if page.CursorCreatedAt.Valid && !beforeCursor(r.CreatedAt, r.ID, page.CursorCreatedAt.Time, page.CursorID) {
+			continue
+		}
+		rows = append(rows, pg.GetRoomsRow{ID: r.ID, Theme: r.Theme, EventSeq: r.EventSeq, ArchivedAt: r.ArchivedAt, ClosedAt: r.ClosedAt, Status: roomStatus(r), CreatedAt: r.CreatedAt})
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetRoomsRow) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIDs(b.ID, a.ID)
+	})
+
+	return rows
+}
+
+func (s *Store) InsertRoom(ctx context.Context, arg pg.InsertRoomParams) (pg.InsertRoomRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.OrganizationID.Valid && !s.hasOrganization(arg.OrganizationID.UUID) {
+		return pg.InsertRoomRow{}, foreignKeyError("rooms_organization_id_fkey")
+	}
+	if arg.TrackID.Valid && s.trackIndex(arg.TrackID.UUID) < 0 {
+		return pg.InsertRoomRow{}, foreignKeyError("rooms_track_id_fkey")
+	}
+
+	room := pg.Room{
+		ID:             newID(),
+		Theme:          arg.Theme,
+		OwnerTokenHash: arg.OwnerTokenHash,
+		FormSchema:     slices.Clone(arg.FormSchema),
+		PostingMode:    arg.PostingMode,
+		MaxSubscribers: arg.MaxSubscribers,
+		Code:           newCode(),
+		OrganizationID: arg.OrganizationID,
+		CreatedAt:      s.now(),
+		TranslateTo:    slices.Clone(arg.TranslateTo),
+		Description:    arg.Description,
+		TrackID:        arg.TrackID,
+		StartsAt:       arg.StartsAt,
+		HostName:       arg.HostName,
+	}
+	s.t.rooms = append(s.t.rooms, room)
+
+	return pg.InsertRoomRow{ID: room.ID, Code: room.Code}, nil
+}
+
+func (s *Store) ArchiveRoom(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.roomIndex(id); i >= 0 && !s.t.rooms[i].ArchivedAt.Valid {
+		s.t.rooms[i].ArchivedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+	}
+
+	return nil
+}
+
+func (s *Store) UnarchiveRoom(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.roomIndex(id); i >= 0 {
+		s.t.rooms[i].ArchivedAt = pgtype.Timestamptz{}
+	}
+
+	return nil
+}
+
+func (s *Store) CloseRoom(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.roomIndex(id)
+	if i < 0 || s.t.rooms[i].ClosedAt.Valid {
+		return 0, nil
+	}
+	s.t.rooms[i].ClosedAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+
+	return 1, nil
+}
+
+func (s *Store) ReopenRoom(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.roomIndex(id)
+	if i < 0 || !s.t.rooms[i].ClosedAt.Valid {
+		return 0, nil
+	}
+	s.t.rooms[i].ClosedAt = pgtype.Timestamptz{}
+
+	return 1, nil
+}
+
+func (s *Store) GetRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.roomIndex(id)
+	if i < 0 {
+		return 0, errNoRows
+	}
+
+	return s.t.rooms[i].EventSeq, nil
+}
+
+func (s *Store) IncrementRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.roomIndex(id)
+	if i < 0 {
+		return 0, errNoRows
+	}
+	s.t.rooms[i].EventSeq++
+
+	return s.t.rooms[i].EventSeq, nil
+}
+
+func (s *Store) InsertRoomEvent(ctx context.Context, arg pg.InsertRoomEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("room_events_room_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.roomEvents, func(e pg.RoomEvent) bool {
+		return e.RoomID == arg.RoomID && e.EventID == arg.EventID
+	}) {
+		return uniqueError("room_events_pkey")
+	}
+	s.t.roomEvents = append(s.t.roomEvents, pg.RoomEvent{
+		RoomID:    arg.RoomID,
+		EventID:   arg.EventID,
+		Channel:   arg.Channel,
+		Kind:      arg.Kind,
+		Value:     arg.Value,
+		CreatedAt: s.now(),
+	})
+
+	return nil
+}
+
+func (s *Store) InsertOutboxEvent(ctx context.Context, arg pg.InsertOutboxEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("event_outbox_room_id_fkey")
+	}
+	s.t.eventOutboxSeq++
+	s.t.eventOutbox = append(s.t.eventOutbox, pg.EventOutbox{
+		ID:         s.t.eventOutboxSeq,
+		InstanceID: arg.InstanceID,
+		RoomID:     arg.RoomID,
+		Channel:    arg.Channel,
+		Kind:       arg.Kind,
+		Value:      arg.Value,
+		CreatedAt:  s.now(),
+	})
+
+	return nil
+}
+
+// ClaimOutboxEvents has nothing to lock: transactions already run one at a
+// time.
+func (s *Store) ClaimOutboxEvents(ctx context.Context, arg pg.ClaimOutboxEventsParams) ([]pg.EventOutbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []pg.EventOutbox
+	for _, e := range s.t.eventOutbox {
+		if e.InstanceID == arg.InstanceID || e.CreatedAt.Before(arg.StaleBefore) {
+			events = append(events, e)
+		}
+	}
+
+	return limit(events, arg.MaxEvents), nil
+}
+
+func (s *Store) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.t.eventOutbox = slices.DeleteFunc(s.t.eventOutbox, func(e pg.EventOutbox) bool { return slices.Contains(ids, e.ID) })
+
+	return nil
+}
+
+func (s *Store) GetRoomEvents(ctx context.Context, arg pg.GetRoomEventsParams) ([]pg.RoomEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []pg.RoomEvent
+	for _, e := range s.t.roomEvents {
+		if e.RoomID == arg.RoomID && e.EventID > arg.AfterEventID {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b pg.RoomEvent) int { return cmp.Compare(a.EventID, b.EventID) })
+
+	return limit(events, arg.MaxEvents), nil
+}
+
+func (s *Store) DeleteRoomEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.t.roomEvents)
+	s.t.roomEvents = slices.DeleteFunc(s.t.roomEvents, func(e pg.RoomEvent) bool { return e.CreatedAt.Before(before) })
+
+	return int64(n - len(s.t.roomEvents)), nil
+}
+
+// DeleteRoomEventsBeyond keeps the last keep events of every room, counted
+// from the room's sequence rather than the rows left.
+func (s *Store) DeleteRoomEventsBeyond(ctx context.Context, keep int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.t.roomEvents)
+	s.t.roomEvents = slices.DeleteFunc(s.t.roomEvents, func(e pg.RoomEvent) bool {
+		i := s.roomIndex(e.RoomID)
+		return i >= 0 && e.EventID <= s.t.rooms[i].EventSeq-keep
+	})
+
+	return int64(n - len(s.t.roomEvents)), nil
+}
+
+func (s *Store) CountOrganizationRoomsSince(ctx context.Context, arg pg.CountOrganizationRoomsSinceParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, r := range s.t.rooms {
+		if arg.OrganizationID.Valid && equalNullable(r.OrganizationID, arg.OrganizationID.UUID) && !r.CreatedAt.Before(arg.CreatedAt) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *Store) SetRoomTrack(ctx context.Context, arg pg.SetRoomTrackParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.TrackID.Valid && s.trackIndex(arg.TrackID.UUID) < 0 {
+		return 0, foreignKeyError("rooms_track_id_fkey")
+	}
+
+	var updated int64
+	for i, r := range s.t.rooms {
+		if r.ID == arg.ID && arg.OrganizationID.Valid && equalNullable(r.OrganizationID, arg.OrganizationID.UUID) {
+			s.t.rooms[i].TrackID = arg.TrackID
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+func (s *Store) ApplyEventRoomDefaults(ctx context.Context, arg pg.ApplyEventRoomDefaultsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated int64
+	for i, r := range s.t.rooms {
+		if !r.TrackID.Valid {
+			continue
+		}
+		if t := s.trackIndex(r.TrackID.UUID); t < 0 || s.t.tracks[t].EventID != arg.EventID {
+			continue
+		}
+
+		if arg.PostingMode.Valid {
+			s.t.rooms[i].PostingMode = arg.PostingMode.String
+		}
+		if arg.MaxSubscribers.Valid {
+			s.t.rooms[i].MaxSubscribers = arg.MaxSubscribers.Int32
+		}
+		//? A nil slice is sent as NULL, which COALESCE skips
+		if arg.TranslateTo != nil {
+			s.t.rooms[i].TranslateTo = slices.Clone(arg.TranslateTo)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+func (s *Store) GetOrganizationDashboardRooms(ctx context.Context, arg pg.GetOrganizationDashboardRoomsParams) ([]pg.GetOrganizationDashboardRoomsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []pg.GetOrganizationDashboardRoomsRow
+	for _, r := range s.t.rooms {
+		if !arg.OrganizationID.Valid || !equalNullable(r.OrganizationID, arg.OrganizationID.UUID) {
+			continue
+		}
+		stats := s.roomStats(r.ID)
+		row := pg.GetOrganizationDashboardRoomsRow{
+			ID:              r.ID,
+			Theme:           r.Theme,
+			Code:            r.Code,
+			ArchivedAt:      r.ArchivedAt,
+			ClosedAt:        r.ClosedAt,
+			CreatedAt:       r.CreatedAt,
+			UnansweredCount: stats.UnansweredCount,
+			LastActivity:    stats.LastActivity,
+		}
+		for _, m := range s.t.messages {
+			if m.RoomID == r.ID && !m.CreatedAt.Before(arg.Since) {
+				row.RecentMessages++
+			}
+		}
+		for _, mr := range s.t.messageReactions {
+			if mr.RoomID == r.ID && !mr.CreatedAt.Before(arg.Since) {
+				row.RecentReactions++
+			}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b pg.GetOrganizationDashboardRoomsRow) int {
+		if a.ArchivedAt.Valid != b.ArchivedAt.Valid {
+			if a.ArchivedAt.Valid {
+				return 1
+			}
+			return -1
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return rows, nil
+}
+
+// GetRelatedRooms scores rooms with pg_trgm's similarity, computed here the
+// way the extension does.
+func (s *Store) GetRelatedRooms(ctx context.Context, arg pg.GetRelatedRoomsParams) ([]pg.GetRelatedRoomsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source := trigrams(arg.Source)
+	type scored struct {
+		row  pg.GetRelatedRoomsRow
+		room pg.Room
+	}
+	var candidates []scored
+	for _, r := range s.t.rooms {
+		if r.ID == arg.ID || r.ArchivedAt.Valid || !sameNullable(r.OrganizationID, arg.OrganizationID) {
+			continue
+		}
+		score := similarity(trigrams(r.Theme+" "+r.Description), source)
+		if score < trigramThreshold {
+			continue
+		}
+		candidates = append(candidates, scored{
+			row:  pg.GetRelatedRoomsRow{ID: r.ID, Code: r.Code, Theme: r.Theme, Description: r.Description, Score: score},
+			room: r,
+		})
+	}
+	slices.SortStableFunc(candidates, func(a, b scored) int {
+		if a.row.Score != b.row.Score {
+			if a.row.Score > b.row.Score {
+				return -1
+			}
+			return 1
+		}
+		return b.room.CreatedAt.Compare(a.room.CreatedAt)
+	})
+
+	rows := make([]pg.GetRelatedRoomsRow, 0, len(candidates))
+	for _, c := range limit(candidates, arg.MaxRooms) {
+		rows = append(rows, c.row)
+	}
+
+	return rows, nil
+}
+
+// trigrams splits text the way pg_trgm does: lowercase words of letters and
+// digits, each padded with two spaces before and one after.
+func trigrams(text string) map[string]bool {
+	set := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+
+	return set
+}
+
+func similarity(a, b map[string]bool) float32 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+
+	return float32(shared) / float32(len(a)+len(b)-shared)
+}
+
+func (s *Store) InsertRoomTransfer(ctx context.Context, arg pg.InsertRoomTransferParams) (pg.RoomTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return pg.RoomTransfer{}, foreignKeyError("room_transfers_room_id_fkey")
+	}
+	if arg.ToSessionID.Valid && !s.hasSession(arg.ToSessionID.UUID) {
+		return pg.RoomTransfer{}, foreignKeyError("room_transfers_to_session_id_fkey")
+	}
+
+	transfer := pg.RoomTransfer{
+		ID:                 newID(),
+		RoomID:             arg.RoomID,
+		TokenHash:          arg.TokenHash,
+		FromOwnerTokenHash: arg.FromOwnerTokenHash,
+		ToSessionID:        arg.ToSessionID,
+		CreatedAt:          s.now(),
+		ExpiresAt:          arg.ExpiresAt,
+	}
+	s.t.roomTransfers = append(s.t.roomTransfers, transfer)
+
+	return transfer, nil
+}
+
+func (s *Store) GetPendingRoomTransfer(ctx context.Context, arg pg.GetPendingRoomTransferParams) (pg.RoomTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, t := range s.t.roomTransfers {
+		if t.RoomID == arg.RoomID && t.TokenHash == arg.TokenHash && !t.AcceptedAt.Valid && t.ExpiresAt.After(now) {
+			return t, nil
+		}
+	}
+
+	return pg.RoomTransfer{}, errNoRows
+}
+
+// AcceptRoomTransfer hands the room over only while its owner token is still
+// the one the transfer was started with.
+func (s *Store) AcceptRoomTransfer(ctx context.Context, arg pg.AcceptRoomTransferParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	t := slices.IndexFunc(s.t.roomTransfers, func(t pg.RoomTransfer) bool { return t.ID == arg.ID })
+	if t < 0 {
+		return 0, nil
+	}
+	transfer := s.t.roomTransfers[t]
+	r := s.roomIndex(transfer.RoomID)
+	if transfer.AcceptedAt.Valid || !transfer.ExpiresAt.After(now) || r < 0 || s.t.rooms[r].OwnerTokenHash != transfer.FromOwnerTokenHash {
+		return 0, nil
+	}
+
+	s.t.roomTransfers[t].AcceptedAt = pgtype.Timestamptz{Time: now, Valid: true}
+	s.t.rooms[r].OwnerTokenHash = arg.OwnerTokenHash
+
+	return 1, nil
+}
+
+func (s *Store) UpsertRoomOverlayToken(ctx context.Context, arg pg.UpsertRoomOverlayTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("room_overlay_tokens_room_id_fkey")
+	}
+
+	token := pg.RoomOverlayToken{RoomID: arg.RoomID, TokenHash: arg.TokenHash, CreatedAt: s.now()}
+	if i := slices.IndexFunc(s.t.roomOverlayTokens, func(t pg.RoomOverlayToken) bool { return t.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomOverlayTokens[i] = token
+		return nil
+	}
+	s.t.roomOverlayTokens = append(s.t.roomOverlayTokens, token)
+
+	return nil
+}
+
+func (s *Store) GetRoomOverlayTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomOverlayTokens, func(t pg.RoomOverlayToken) bool { return t.RoomID == roomID })
+	if i < 0 {
+		return "", errNoRows
+	}
+
+	return s.t.roomOverlayTokens[i].TokenHash, nil
+}
+
+func (s *Store) DeleteRoomOverlayToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.roomOverlayTokens)
+	s.t.roomOverlayTokens = slices.DeleteFunc(s.t.roomOverlayTokens, func(t pg.RoomOverlayToken) bool { return t.RoomID == roomID })
+
+	return int64(before - len(s.t.roomOverlayTokens)), nil
+}
+
+func (s *Store) UpsertRoomCaptionToken(ctx context.Context, arg pg.UpsertRoomCaptionTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("room_caption_tokens_room_id_fkey")
+	}
+
+	token := pg.RoomCaptionToken{RoomID: arg.RoomID, TokenHash: arg.TokenHash, CreatedAt: s.now()}
+	if i := slices.IndexFunc(s.t.roomCaptionTokens, func(t pg.RoomCaptionToken) bool { return t.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomCaptionTokens[i] = token
+		return nil
+	}
+	s.t.roomCaptionTokens = append(s.t.roomCaptionTokens, token)
+
+	return nil
+}
+
+func (s *Store) GetRoomCaptionTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomCaptionTokens, func(t pg.RoomCaptionToken) bool { return t.RoomID == roomID })
+	if i < 0 {
+		return "", errNoRows
+	}
+
+	return s.t.roomCaptionTokens[i].TokenHash, nil
+}
+
+func (s *Store) DeleteRoomCaptionToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.roomCaptionTokens)
+	s.t.roomCaptionTokens = slices.DeleteFunc(s.t.roomCaptionTokens, func(t pg.RoomCaptionToken) bool { return t.RoomID == roomID })
+
+	return int64(before - len(s.t.roomCaptionTokens)), nil
+}
+
+func (s *Store) UpsertRoomWebinarToken(ctx context.Context, arg pg.UpsertRoomWebinarTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("room_webinar_tokens_room_id_fkey")
+	}
+
+	token := pg.RoomWebinarToken{RoomID: arg.RoomID, TokenHash: arg.TokenHash, CreatedAt: s.now()}
+	if i := slices.IndexFunc(s.t.roomWebinarTokens, func(t pg.RoomWebinarToken) bool { return t.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomWebinarTokens[i] = token
+		return nil
+	}
+	s.t.roomWebinarTokens = append(s.t.roomWebinarTokens, token)
+
+	return nil
+}
+
+func (s *Store) GetRoomWebinarTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomWebinarTokens, func(t pg.RoomWebinarToken) bool { return t.RoomID == roomID })
+	if i < 0 {
+		return "", errNoRows
+	}
+
+	return s.t.roomWebinarTokens[i].TokenHash, nil
+}
+
+func (s *Store) DeleteRoomWebinarToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.roomWebinarTokens)
+	s.t.roomWebinarTokens = slices.DeleteFunc(s.t.roomWebinarTokens, func(t pg.RoomWebinarToken) bool { return t.RoomID == roomID })
+
+	return int64(before - len(s.t.roomWebinarTokens)), nil
+}
+
+func (s *Store) UpsertRoomIngestHook(ctx context.Context, arg pg.UpsertRoomIngestHookParams) (pg.RoomIngestHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return pg.RoomIngestHook{}, foreignKeyError("room_ingest_hooks_room_id_fkey")
+	}
+
+	hook := pg.RoomIngestHook{RoomID: arg.RoomID, Mapping: slices.Clone(arg.Mapping), UpdatedAt: s.now()}
+	if i := slices.IndexFunc(s.t.roomIngestHooks, func(h pg.RoomIngestHook) bool { return h.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomIngestHooks[i] = hook
+		return hook, nil
+	}
+	s.t.roomIngestHooks = append(s.t.roomIngestHooks, hook)
+
+	return hook, nil
+}
+
+func (s *Store) GetRoomIngestHook(ctx context.Context, roomID uuid.UUID) (pg.RoomIngestHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomIngestHooks, func(h pg.RoomIngestHook) bool { return h.RoomID == roomID })
+	if i < 0 {
+		return pg.RoomIngestHook{}, errNoRows
+	}
+
+	return s.t.roomIngestHooks[i], nil
+}
+
+func (s *Store) DeleteRoomIngestHook(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.roomIngestHooks)
+	s.t.roomIngestHooks = slices.DeleteFunc(s.t.roomIngestHooks, func(h pg.RoomIngestHook) bool { return h.RoomID == roomID })
+
+	return int64(before - len(s.t.roomIngestHooks)), nil
+}
+
+func (s *Store) UpsertRoomWebhook(ctx context.Context, arg pg.UpsertRoomWebhookParams) (pg.RoomWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room := s.roomIndex(arg.RoomID)
+	if room < 0 {
+		return pg.RoomWebhook{}, errNoRows
+	}
+
+	now := s.now()
+	if i := slices.IndexFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool { return w.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomWebhooks[i].Url = arg.Url
+		s.t.roomWebhooks[i].UpdatedAt = now
+		return s.t.roomWebhooks[i], nil
+	}
+
+	webhook := pg.RoomWebhook{
+		ID:          newID(),
+		RoomID:      arg.RoomID,
+		Url:         arg.Url,
+		LastEventID: s.t.rooms[room].EventSeq,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.t.roomWebhooks = append(s.t.roomWebhooks, webhook)
+
+	return webhook, nil
+}
+
+func (s *Store) GetRoomWebhook(ctx context.Context, roomID uuid.UUID) (pg.RoomWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool { return w.RoomID == roomID })
+	if i < 0 {
+		return pg.RoomWebhook{}, errNoRows
+	}
+
+	return s.t.roomWebhooks[i], nil
+}
+
+func (s *Store) DeleteRoomWebhook(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := map[uuid.UUID]bool{}
+	s.t.roomWebhooks = slices.DeleteFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool {
+		if w.RoomID == roomID {
+			deleted[w.ID] = true
+		}
+		return w.RoomID == roomID
+	})
+	//* ON DELETE CASCADE
+	s.t.webhookDeliveries = slices.DeleteFunc(s.t.webhookDeliveries, func(d pg.WebhookDelivery) bool { return deleted[d.WebhookID] })
+
+	return int64(len(deleted)), nil
+}
+
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, settledBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queued int64
+	now := s.now()
+	for i := range s.t.roomWebhooks {
+		w := &s.t.roomWebhooks[i]
+		last := w.LastEventID
+		for _, e := range s.t.roomEvents {
+			if e.RoomID != w.RoomID || e.EventID <= w.LastEventID || !e.CreatedAt.Before(settledBefore) {
+				continue
+			}
+			if slices.ContainsFunc(s.t.webhookDeliveries, func(d pg.WebhookDelivery) bool {
+				return d.WebhookID == w.ID && d.EventID == e.EventID
+			}) {
+				continue
+			}
+			s.t.webhookDeliveries = append(s.t.webhookDeliveries, pg.WebhookDelivery{
+				WebhookID:     w.ID,
+				EventID:       e.EventID,
+				Status:        "pending",
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+			queued++
+			last = max(last, e.EventID)
+		}
+		w.LastEventID = last
+	}
+
+	return queued, nil
+}
+
+func (s *Store) ClaimWebhookDelivery(ctx context.Context, leaseUntil time.Time) (pg.ClaimWebhookDeliveryRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	claim := -1
+	for i, d := range s.t.webhookDeliveries {
+		due := d.Status == "pending" && !d.NextAttemptAt.After(now)
+		if due && (claim < 0 || d.NextAttemptAt.Before(s.t.webhookDeliveries[claim].NextAttemptAt)) {
+			claim = i
+		}
+	}
+	if claim < 0 {
+		return pg.ClaimWebhookDeliveryRow{}, errNoRows
+	}
+
+	d := &s.t.webhookDeliveries[claim]
+	d.Attempts++
+	d.NextAttemptAt = leaseUntil
+	w := s.t.roomWebhooks[slices.IndexFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool { return w.ID == d.WebhookID })]
+
+	return pg.ClaimWebhookDeliveryRow{
+		WebhookID: d.WebhookID,
+		EventID:   d.EventID,
+		Attempts:  d.Attempts,
+		RoomID:    w.RoomID,
+		Url:       w.Url,
+	}, nil
+}
+
+func (s *Store) webhookDeliveryIndex(webhookID uuid.UUID, eventID int64) int {
+	return slices.IndexFunc(s.t.webhookDeliveries, func(d pg.WebhookDelivery) bool {
+		return d.WebhookID == webhookID && d.EventID == eventID
+	})
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, arg pg.CompleteWebhookDeliveryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookDeliveryIndex(arg.WebhookID, arg.EventID); i >= 0 {
+		d := &s.t.webhookDeliveries[i]
+		d.Status = "delivered"
+		d.LastError = ""
+		d.DeliveredAt = pgtype.Timestamptz{Time: s.now(), Valid: true}
+	}
+
+	return nil
+}
+
+func (s *Store) FailWebhookDelivery(ctx context.Context, arg pg.FailWebhookDeliveryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookDeliveryIndex(arg.WebhookID, arg.EventID); i >= 0 {
+		d := &s.t.webhookDeliveries[i]
+		d.Status = "pending"
+		if d.Attempts >= arg.MaxAttempts {
+			d.Status = "failed"
+		}
+		d.NextAttemptAt = arg.RetryAt
+		d.LastError = arg.Error
+
+		if d.Status == "failed" {
+			w := s.t.roomWebhooks[slices.IndexFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool { return w.ID == d.WebhookID })]
+			dl := pg.DeadLetter{
+				Source:   "webhook",
+				Ref:      webhookDeliveryRef(d.WebhookID, d.EventID),
+				Kind:     "room_event",
+				RoomID:   uuid.NullUUID{UUID: w.RoomID, Valid: true},
+				Error:    d.LastError,
+				Attempts: d.Attempts,
+			}
+			if r := s.roomIndex(w.RoomID); r >= 0 {
+				dl.OrganizationID = s.t.rooms[r].OrganizationID
+			}
+			s.putDeadLetter(dl)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) RedriveWebhookDeliveries(ctx context.Context, roomID uuid.NullUUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var redriven int64
+	for i := range s.t.webhookDeliveries {
+		d := &s.t.webhookDeliveries[i]
+		if d.Status != "failed" {
+			continue
+		}
+		w := slices.IndexFunc(s.t.roomWebhooks, func(w pg.RoomWebhook) bool { return w.ID == d.WebhookID })
+		if w < 0 || (roomID.Valid && s.t.roomWebhooks[w].RoomID != roomID.UUID) {
+			continue
+		}
+		d.Status = "pending"
+		d.Attempts = 0
+		d.NextAttemptAt = s.now()
+		ref := webhookDeliveryRef(d.WebhookID, d.EventID)
+		s.t.deadLetters = slices.DeleteFunc(s.t.deadLetters, func(dl pg.DeadLetter) bool { return dl.Source == "webhook" && dl.Ref == ref })
+		redriven++
+	}
+
+	return redriven, nil
+}
+
+func (s *Store) RedriveWebhookDelivery(ctx context.Context, arg pg.RedriveWebhookDeliveryParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.webhookDeliveryIndex(arg.WebhookID, arg.EventID)
+	if i < 0 || s.t.webhookDeliveries[i].Status != "failed" {
+		return 0, nil
+	}
+	d := &s.t.webhookDeliveries[i]
+	d.Status = "pending"
+	d.Attempts = 0
+	d.NextAttemptAt = s.now()
+
+	return 1, nil
+}
+
+// webhookDeliveryRef is how dead letters point at a delivery.
+func webhookDeliveryRef(webhookID uuid.UUID, eventID int64) string {
+	return webhookID.String() + ":" + strconv.FormatInt(eventID, 10)
+}
+
+func (s *Store) UpsertSavedView(ctx context.Context, arg pg.UpsertSavedViewParams) (pg.SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return pg.SavedView{}, foreignKeyError("saved_views_room_id_fkey")
+	}
+
+	now := s.now()
+	if i := slices.IndexFunc(s.t.savedViews, func(v pg.SavedView) bool { return v.RoomID == arg.RoomID && v.Name == arg.Name }); i >= 0 {
+		s.t.savedViews[i].Filter = slices.Clone(arg.Filter)
+		s.t.savedViews[i].UpdatedAt = now
+		return s.t.savedViews[i], nil
+	}
+
+	view := pg.SavedView{ID: newID(), RoomID: arg.RoomID, Name: arg.Name, Filter: slices.Clone(arg.Filter), CreatedAt: now, UpdatedAt: now}
+	s.t.savedViews = append(s.t.savedViews, view)
+
+	return view, nil
+}
+
+func (s *Store) GetSavedView(ctx context.Context, arg pg.GetSavedViewParams) (pg.SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.savedViews, func(v pg.SavedView) bool { return v.RoomID == arg.RoomID && v.Name == arg.Name })
+	if i < 0 {
+		return pg.SavedView{}, errNoRows
+	}
+
+	return s.t.savedViews[i], nil
+}
+
+func (s *Store) GetSavedViews(ctx context.Context, roomID uuid.UUID) ([]pg.SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var views []pg.SavedView
+	for _, v := range s.t.savedViews {
+		if v.RoomID == roomID {
+			views = append(views, v)
+		}
+	}
+	slices.SortStableFunc(views, func(a, b pg.SavedView) int { return strings.Compare(a.Name, b.Name) })
+
+	return views, nil
+}
+
+func (s *Store) DeleteSavedView(ctx context.Context, arg pg.DeleteSavedViewParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.savedViews)
+	s.t.savedViews = slices.DeleteFunc(s.t.savedViews, func(v pg.SavedView) bool { return v.RoomID == arg.RoomID && v.Name == arg.Name })
+
+	return int64(before - len(s.t.savedViews)), nil
+}
+
+func (s *Store) RecordRoomPeak(ctx context.Context, arg pg.RecordRoomPeakParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return foreignKeyError("room_peaks_room_id_fkey")
+	}
+
+	if i := slices.IndexFunc(s.t.roomPeaks, func(p pg.RoomPeak) bool { return p.RoomID == arg.RoomID }); i >= 0 {
+		if s.t.roomPeaks[i].PeakSubscribers < arg.PeakSubscribers {
+			s.t.roomPeaks[i].PeakSubscribers = arg.PeakSubscribers
+			s.t.roomPeaks[i].UpdatedAt = s.now()
+		}
+		return nil
+	}
+	s.t.roomPeaks = append(s.t.roomPeaks, pg.RoomPeak{RoomID: arg.RoomID, PeakSubscribers: arg.PeakSubscribers, UpdatedAt: s.now()})
+
+	return nil
+}
+
+func (s *Store) UpsertRoomChatBridge(ctx context.Context, arg pg.UpsertRoomChatBridgeParams) (pg.RoomChatBridge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return pg.RoomChatBridge{}, foreignKeyError("room_chat_bridges_room_id_fkey")
+	}
+
+	bridge := pg.RoomChatBridge{
+		RoomID:        arg.RoomID,
+		Platform:      arg.Platform,
+		Channel:       arg.Channel,
+		Prefix:        arg.Prefix,
+		RatePerMinute: arg.RatePerMinute,
+		UpdatedAt:     s.now(),
+	}
+	if i := slices.IndexFunc(s.t.roomChatBridges, func(b pg.RoomChatBridge) bool { return b.RoomID == arg.RoomID }); i >= 0 {
+		s.t.roomChatBridges[i] = bridge
+		return bridge, nil
+	}
+	s.t.roomChatBridges = append(s.t.roomChatBridges, bridge)
+
+	return bridge, nil
+}
+
+func (s *Store) GetRoomChatBridge(ctx context.Context, roomID uuid.UUID) (pg.RoomChatBridge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.t.roomChatBridges, func(b pg.RoomChatBridge) bool { return b.RoomID == roomID })
+	if i < 0 {
+		return pg.RoomChatBridge{}, errNoRows
+	}
+
+	return s.t.roomChatBridges[i], nil
+}
+
+func (s *Store) GetActiveChatBridges(ctx context.Context) ([]pg.RoomChatBridge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bridges []pg.RoomChatBridge
+	for _, b := range s.t.roomChatBridges {
+		if r := s.roomIndex(b.RoomID); r >= 0 && !s.t.rooms[r].ArchivedAt.Valid {
+			bridges = append(bridges, b)
+		}
+	}
+
+	return bridges, nil
+}
+
+func (s *Store) DeleteRoomChatBridge(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.t.roomChatBridges)
+	s.t.roomChatBridges = slices.DeleteFunc(s.t.roomChatBridges, func(b pg.RoomChatBridge) bool { return b.RoomID == roomID })
+
+	return int64(before - len(s.t.roomChatBridges)), nil
+}
+
+func (s *Store) MarkChatBridgeMessage(ctx context.Context, arg pg.MarkChatBridgeMessageParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roomIndex(arg.RoomID) < 0 {
+		return 0, foreignKeyError("chat_bridge_messages_room_id_fkey")
+	}
+	if slices.ContainsFunc(s.t.chatBridgeMessages, func(m pg.ChatBridgeMessage) bool {
+		return m.RoomID == arg.RoomID && m.ExternalID == arg.ExternalID
+	}) {
+		return 0, nil
+	}
+	s.t.chatBridgeMessages = append(s.t.chatBridgeMessages, pg.ChatBridgeMessage{RoomID: arg.RoomID, ExternalID: arg.ExternalID, CreatedAt: s.now()})
+
+	return 1, nil
+}
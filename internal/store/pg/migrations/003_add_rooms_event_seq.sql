@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "event_seq" BIGINT NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "event_seq";
@@ -0,0 +1,20 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "owner_token_hash" VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS message_reports (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "message_id"    uuid                            NOT NULL,
+    "reason"        VARCHAR(255)                    NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_reports;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "owner_token_hash";
@@ -0,0 +1,25 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE IF NOT EXISTS message_reactions (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "message_id"    uuid                            NOT NULL,
+    "room_id"       uuid                            NOT NULL,
+    "kind"          VARCHAR(32)                     NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id)
+);
+
+CREATE INDEX IF NOT EXISTS message_reactions_room_id_created_at_idx
+    ON message_reactions (room_id, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_reactions;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "created_at";
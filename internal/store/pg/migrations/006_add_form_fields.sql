@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "form_schema" JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE messages
+    ADD COLUMN "fields" JSONB NOT NULL DEFAULT '{}';
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "fields";
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "form_schema";
@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "posting_mode" VARCHAR(16) NOT NULL DEFAULT 'optional';
+
+ALTER TABLE messages
+    ADD COLUMN "author_name" VARCHAR(50) NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "author_name";
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "posting_mode";
@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "max_subscribers" INTEGER NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "max_subscribers";
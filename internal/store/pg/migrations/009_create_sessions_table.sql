@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS sessions (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "user_agent"    VARCHAR(255)                    NOT NULL    DEFAULT '',
+    "client_ip"     VARCHAR(64)                     NOT NULL    DEFAULT '',
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now()
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "session_id" uuid NULL REFERENCES sessions(id);
+
+CREATE INDEX IF NOT EXISTS messages_room_id_session_id_idx
+    ON messages (room_id, session_id);
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "session_id";
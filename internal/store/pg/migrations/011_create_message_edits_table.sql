@@ -0,0 +1,14 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS message_edits (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "message_id"        uuid                            NOT NULL,
+    "previous_message"  VARCHAR(255)                    NOT NULL,
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_edits;
@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "deleted_at" TIMESTAMPTZ NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "deleted_at";
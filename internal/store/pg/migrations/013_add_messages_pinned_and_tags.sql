@@ -0,0 +1,11 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "pinned" BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN "tags" VARCHAR(32)[] NOT NULL DEFAULT '{}';
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "tags",
+    DROP COLUMN IF EXISTS "pinned";
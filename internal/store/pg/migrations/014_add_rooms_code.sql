@@ -0,0 +1,10 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "code" VARCHAR(16) NOT NULL UNIQUE
+        DEFAULT substr(replace(gen_random_uuid()::text, '-', ''), 1, 10);
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "code";
@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "archived_at" TIMESTAMPTZ NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "archived_at";
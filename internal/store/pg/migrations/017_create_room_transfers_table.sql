@@ -0,0 +1,19 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_transfers (
+    "id"                    uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "room_id"               uuid                            NOT NULL,
+    "token_hash"            VARCHAR(64)                     NOT NULL,
+    "from_owner_token_hash" VARCHAR(64)                     NOT NULL,
+    "to_session_id"         uuid                            NULL,
+    "created_at"            TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "expires_at"            TIMESTAMPTZ                     NOT NULL,
+    "accepted_at"           TIMESTAMPTZ                     NULL,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id),
+    FOREIGN KEY (to_session_id) REFERENCES sessions(id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_transfers;
@@ -0,0 +1,44 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS organizations (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "name"          VARCHAR(255)                    NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "organization_id"   uuid                            NOT NULL,
+    "name"              VARCHAR(255)                    NOT NULL    DEFAULT '',
+    "key_hash"          VARCHAR(64)                     NOT NULL    UNIQUE,
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "revoked_at"        TIMESTAMPTZ                     NULL,
+
+    FOREIGN KEY (organization_id) REFERENCES organizations(id)
+);
+
+ALTER TABLE rooms
+    ADD COLUMN "organization_id" uuid NULL REFERENCES organizations(id);
+
+CREATE TABLE IF NOT EXISTS usage_records (
+    "organization_id"   uuid                            NOT NULL,
+    "api_key_id"        uuid                            NULL,
+    "metric"            VARCHAR(32)                     NOT NULL,
+    "period_start"      TIMESTAMPTZ                     NOT NULL,
+    "quantity"          BIGINT                          NOT NULL    DEFAULT 0,
+
+    FOREIGN KEY (organization_id) REFERENCES organizations(id),
+    FOREIGN KEY (api_key_id) REFERENCES api_keys(id),
+    UNIQUE NULLS NOT DISTINCT (organization_id, api_key_id, metric, period_start)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS usage_records;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "organization_id";
+
+DROP TABLE IF EXISTS api_keys;
+
+DROP TABLE IF EXISTS organizations;
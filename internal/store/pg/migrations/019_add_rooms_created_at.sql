@@ -0,0 +1,14 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS rooms_organization_id_created_at_idx
+    ON rooms ("organization_id", "created_at");
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_organization_id_created_at_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "created_at";
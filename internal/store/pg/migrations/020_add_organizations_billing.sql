@@ -0,0 +1,11 @@
+-- Write your migrate up statements here
+
+ALTER TABLE organizations
+    ADD COLUMN "settings" JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN "stripe_customer_id" VARCHAR(255) NULL UNIQUE;
+
+---- create above / drop below ----
+
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS "stripe_customer_id",
+    DROP COLUMN IF EXISTS "settings";
@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS admin_credentials (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "name"          VARCHAR(255)                    NOT NULL,
+    "token_hash"    VARCHAR(64)                     NOT NULL    UNIQUE,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now()
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS admin_credentials;
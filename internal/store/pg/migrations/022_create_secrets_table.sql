@@ -0,0 +1,19 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS secrets (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "organization_id"   uuid                            NULL,
+    "name"              VARCHAR(255)                    NOT NULL,
+    "key_id"            VARCHAR(64)                     NOT NULL,
+    "wrapped_key"       BYTEA                           NOT NULL,
+    "ciphertext"        BYTEA                           NOT NULL,
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "updated_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (organization_id) REFERENCES organizations(id),
+    UNIQUE NULLS NOT DISTINCT (organization_id, name)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS secrets;
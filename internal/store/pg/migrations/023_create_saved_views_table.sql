@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "room_id"       uuid                            NOT NULL,
+    "name"          VARCHAR(100)                    NOT NULL,
+    "filter"        JSONB                           NOT NULL    DEFAULT '{}'::jsonb,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "updated_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    UNIQUE (room_id, name)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS saved_views;
@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_overlay_tokens (
+    "room_id"       uuid            PRIMARY KEY     NOT NULL,
+    "token_hash"    VARCHAR(64)                     NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_overlay_tokens;
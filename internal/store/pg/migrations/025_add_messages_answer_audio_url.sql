@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "answer_audio_url" VARCHAR(2048) NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "answer_audio_url";
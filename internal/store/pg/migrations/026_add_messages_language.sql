@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "language" VARCHAR(8) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS messages_room_id_language_idx ON messages (room_id, language);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS messages_room_id_language_idx;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "language";
@@ -0,0 +1,22 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "translate_to" VARCHAR(16)[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS message_translations (
+    "message_id"    uuid                            NOT NULL,
+    "language"      VARCHAR(16)                     NOT NULL,
+    "source_hash"   VARCHAR(64)                     NOT NULL,
+    "text"          TEXT                            NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (message_id, language),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_translations;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "translate_to";
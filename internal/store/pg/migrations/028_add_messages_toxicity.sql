@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "toxicity"       REAL            NULL,
+    ADD COLUMN "hidden_at"      TIMESTAMPTZ     NULL,
+    ADD COLUMN "reviewed_at"    TIMESTAMPTZ     NULL;
+
+CREATE INDEX IF NOT EXISTS messages_room_id_hidden_at_idx ON messages (room_id) WHERE hidden_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS messages_room_id_hidden_at_idx;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "reviewed_at",
+    DROP COLUMN IF EXISTS "hidden_at",
+    DROP COLUMN IF EXISTS "toxicity";
@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE rooms
+    ADD COLUMN "description" VARCHAR(1000) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS rooms_theme_description_trgm_idx ON rooms USING gin (("theme" || ' ' || "description") gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_theme_description_trgm_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "description";
@@ -0,0 +1,41 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS events (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "organization_id"   uuid                            NOT NULL,
+    "name"              VARCHAR(255)                    NOT NULL,
+    "starts_at"         TIMESTAMPTZ                     NULL,
+    "ends_at"           TIMESTAMPTZ                     NULL,
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS events_organization_id_idx ON events (organization_id);
+
+CREATE TABLE IF NOT EXISTS tracks (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "event_id"      uuid                            NOT NULL,
+    "name"          VARCHAR(255)                    NOT NULL,
+    "position"      INTEGER                         NOT NULL    DEFAULT 0,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    UNIQUE (event_id, name)
+);
+
+ALTER TABLE rooms
+    ADD COLUMN "track_id" uuid NULL REFERENCES tracks(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS rooms_track_id_idx ON rooms (track_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_track_id_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "track_id";
+
+DROP TABLE IF EXISTS tracks;
+
+DROP TABLE IF EXISTS events;
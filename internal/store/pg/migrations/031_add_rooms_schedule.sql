@@ -0,0 +1,11 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "starts_at" TIMESTAMPTZ NULL,
+    ADD COLUMN "host_name" VARCHAR(255) NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "host_name",
+    DROP COLUMN IF EXISTS "starts_at";
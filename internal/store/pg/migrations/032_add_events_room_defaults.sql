@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE events
+    ADD COLUMN "room_defaults" JSONB NOT NULL DEFAULT '{}';
+
+---- create above / drop below ----
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS "room_defaults";
@@ -0,0 +1,35 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS jobs (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "kind"              VARCHAR(64)                     NOT NULL,
+    "payload"           JSONB                           NOT NULL    DEFAULT '{}',
+    "organization_id"   uuid                            NULL,
+    "status"            VARCHAR(16)                     NOT NULL    DEFAULT 'queued',
+    "attempts"          INTEGER                         NOT NULL    DEFAULT 0,
+    "error"             TEXT                            NOT NULL    DEFAULT '',
+    "output"            BYTEA                           NULL,
+    "content_type"      VARCHAR(128)                    NOT NULL    DEFAULT '',
+    "filename"          VARCHAR(255)                    NOT NULL    DEFAULT '',
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "started_at"        TIMESTAMPTZ                     NULL,
+    "finished_at"       TIMESTAMPTZ                     NULL,
+
+    FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS jobs_status_created_at_idx
+    ON jobs (status, created_at);
+
+CREATE TABLE IF NOT EXISTS room_peaks (
+    "room_id"           uuid            PRIMARY KEY     NOT NULL,
+    "peak_subscribers"  INTEGER                         NOT NULL    DEFAULT 0,
+    "updated_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_peaks;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,29 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_caption_tokens (
+    "room_id"       uuid            PRIMARY KEY     NOT NULL,
+    "token_hash"    VARCHAR(64)                     NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS captions (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "room_id"       uuid                            NOT NULL,
+    "text"          VARCHAR(2000)                   NOT NULL,
+    "language"      VARCHAR(35)                     NOT NULL    DEFAULT '',
+    "start_ms"      BIGINT                          NULL,
+    "end_ms"        BIGINT                          NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS captions_room_id_created_at_idx
+    ON captions (room_id, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS captions;
+DROP TABLE IF EXISTS room_caption_tokens;
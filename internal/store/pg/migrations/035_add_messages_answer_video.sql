@@ -0,0 +1,11 @@
+-- Write your migrate up statements here
+
+ALTER TABLE messages
+    ADD COLUMN "answer_video_url" VARCHAR(2048) NOT NULL DEFAULT '',
+    ADD COLUMN "answer_video_offset" INTEGER NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "answer_video_offset",
+    DROP COLUMN IF EXISTS "answer_video_url";
@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+ALTER TABLE message_reactions
+    ADD COLUMN "session_id" uuid NULL REFERENCES sessions(id) ON DELETE SET NULL;
+
+-- Reactions from before sessions were required keep a NULL session, which never conflicts
+CREATE UNIQUE INDEX IF NOT EXISTS message_reactions_message_id_session_id_idx
+    ON message_reactions (message_id, session_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS message_reactions_message_id_session_id_idx;
+
+ALTER TABLE message_reactions
+    DROP COLUMN IF EXISTS "session_id";
@@ -0,0 +1,26 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_chat_bridges (
+    "room_id"           uuid            PRIMARY KEY     NOT NULL,
+    "platform"          VARCHAR(16)                     NOT NULL,
+    "channel"           VARCHAR(255)                    NOT NULL,
+    "prefix"            VARCHAR(32)                     NOT NULL    DEFAULT '!ask',
+    "rate_per_minute"   INTEGER                         NOT NULL    DEFAULT 20,
+    "updated_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS chat_bridge_messages (
+    "room_id"       uuid            NOT NULL,
+    "external_id"   VARCHAR(255)    NOT NULL,
+    "created_at"    TIMESTAMPTZ     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (room_id, external_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS chat_bridge_messages;
+DROP TABLE IF EXISTS room_chat_bridges;
@@ -0,0 +1,26 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_webinar_tokens (
+    "room_id"       uuid            PRIMARY KEY     NOT NULL,
+    "token_hash"    VARCHAR(64)                     NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webinar_questions (
+    "room_id"       uuid            NOT NULL,
+    "external_id"   VARCHAR(255)    NOT NULL,
+    "message_id"    uuid            NOT NULL,
+    "upvotes"       INTEGER         NOT NULL    DEFAULT 0,
+    "created_at"    TIMESTAMPTZ     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (room_id, external_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS webinar_questions;
+DROP TABLE IF EXISTS room_webinar_tokens;
@@ -0,0 +1,25 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_ingest_hooks (
+    "room_id"       uuid            PRIMARY KEY     NOT NULL,
+    "mapping"       JSONB                           NOT NULL,
+    "updated_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ingest_deliveries (
+    "room_id"       uuid            NOT NULL,
+    "external_id"   VARCHAR(255)    NOT NULL,
+    "message_id"    uuid            NOT NULL,
+    "created_at"    TIMESTAMPTZ     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (room_id, external_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS ingest_deliveries;
+DROP TABLE IF EXISTS room_ingest_hooks;
@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_events (
+    "room_id"       uuid                            NOT NULL,
+    "event_id"      BIGINT                          NOT NULL,
+    "channel"       VARCHAR(32)                     NOT NULL,
+    "kind"          VARCHAR(64)                     NOT NULL,
+    "value"         JSONB                           NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (room_id, event_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_events;
@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+
+-- Sealed message text is longer than the 255 characters the API accepts, the
+-- length is enforced by the handlers instead.
+ALTER TABLE messages
+    ALTER COLUMN "message" TYPE TEXT;
+
+ALTER TABLE message_edits
+    ALTER COLUMN "previous_message" TYPE TEXT;
+
+---- create above / drop below ----
+
+ALTER TABLE message_edits
+    ALTER COLUMN "previous_message" TYPE VARCHAR(255);
+
+ALTER TABLE messages
+    ALTER COLUMN "message" TYPE VARCHAR(255);
@@ -0,0 +1,7 @@
+-- Write your migrate up statements here
+
+CREATE INDEX IF NOT EXISTS room_events_created_at_idx ON room_events (created_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS room_events_created_at_idx;
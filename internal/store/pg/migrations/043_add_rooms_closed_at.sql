@@ -0,0 +1,9 @@
+-- Write your migrate up statements here
+
+ALTER TABLE rooms
+    ADD COLUMN "closed_at" TIMESTAMPTZ;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "closed_at";
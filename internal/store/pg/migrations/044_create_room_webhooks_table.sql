@@ -0,0 +1,34 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS room_webhooks (
+    "id"            uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "room_id"       uuid            UNIQUE          NOT NULL,
+    "url"           VARCHAR(2048)                   NOT NULL,
+    -- last_event_id is the room event up to which deliveries were queued.
+    "last_event_id" BIGINT                          NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+    "updated_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    "webhook_id"        uuid            NOT NULL,
+    "event_id"          BIGINT          NOT NULL,
+    "status"            VARCHAR(16)     NOT NULL    DEFAULT 'pending',
+    "attempts"          INTEGER         NOT NULL    DEFAULT 0,
+    "next_attempt_at"   TIMESTAMPTZ     NOT NULL    DEFAULT now(),
+    "last_error"        TEXT            NOT NULL    DEFAULT '',
+    "delivered_at"      TIMESTAMPTZ     NULL,
+    "created_at"        TIMESTAMPTZ     NOT NULL    DEFAULT now(),
+
+    PRIMARY KEY (webhook_id, event_id),
+    FOREIGN KEY (webhook_id) REFERENCES room_webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS room_webhooks;
@@ -0,0 +1,39 @@
+-- Write your migrate up statements here
+
+-- The search document of each message lives beside it rather than on it, so
+-- the queries returning whole messages don't carry it. Messages are written
+-- by many paths, a trigger keeps it in step with the text. The 'simple'
+-- configuration doesn't stem, as rooms mix languages.
+CREATE TABLE IF NOT EXISTS message_search (
+    "message_id"    uuid            PRIMARY KEY     NOT NULL,
+    "room_id"       uuid                            NOT NULL,
+    "document"      tsvector                        NOT NULL,
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS message_search_document_idx ON message_search USING gin ("document");
+CREATE INDEX IF NOT EXISTS message_search_room_id_idx ON message_search (room_id);
+
+CREATE OR REPLACE FUNCTION index_message_search() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO message_search (message_id, room_id, document)
+    VALUES (NEW.id, NEW.room_id, to_tsvector('simple', NEW.message))
+    ON CONFLICT (message_id) DO UPDATE SET document = EXCLUDED.document;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER messages_search_trigger
+    AFTER INSERT OR UPDATE OF "message" ON messages
+    FOR EACH ROW EXECUTE FUNCTION index_message_search();
+
+INSERT INTO message_search (message_id, room_id, document)
+SELECT id, room_id, to_tsvector('simple', message) FROM messages
+ON CONFLICT (message_id) DO NOTHING;
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS messages_search_trigger ON messages;
+DROP FUNCTION IF EXISTS index_message_search();
+DROP TABLE IF EXISTS message_search;
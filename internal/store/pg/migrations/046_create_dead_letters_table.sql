@@ -0,0 +1,24 @@
+-- Write your migrate up statements here
+
+-- dead_letters lists the async work that ran out of attempts, written by the
+-- queries that mark it failed. ref points at the work: a job id, or
+-- "<webhook_id>:<event_id>" for webhook deliveries.
+CREATE TABLE IF NOT EXISTS dead_letters (
+    "id"                uuid            PRIMARY KEY     NOT NULL    DEFAULT gen_random_uuid(),
+    "source"            VARCHAR(16)                     NOT NULL,
+    "ref"               VARCHAR(128)                    NOT NULL,
+    "kind"              VARCHAR(64)                     NOT NULL,
+    "organization_id"   uuid                            NULL,
+    "room_id"           uuid                            NULL,
+    "error"             TEXT                            NOT NULL    DEFAULT '',
+    "attempts"          INTEGER                         NOT NULL,
+    "created_at"        TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    UNIQUE (source, ref)
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_created_at_idx ON dead_letters (created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS dead_letters;
@@ -0,0 +1,10 @@
+-- Write your migrate up statements here
+
+-- Keyset pages of a room's messages, newest first and most reacted first.
+CREATE INDEX IF NOT EXISTS messages_room_id_created_at_idx ON messages (room_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS messages_room_id_reaction_count_idx ON messages (room_id, reaction_count DESC, created_at DESC, id DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS messages_room_id_reaction_count_idx;
+DROP INDEX IF EXISTS messages_room_id_created_at_idx;
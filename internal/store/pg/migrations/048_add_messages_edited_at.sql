@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+
+-- When the author last edited the message, NULL if never. Messages edited
+-- before it existed take the time of their last edit.
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "edited_at" TIMESTAMPTZ NULL;
+
+UPDATE messages m
+SET edited_at = e.edited_at
+FROM (
+    SELECT message_id, max(created_at) AS edited_at FROM message_edits GROUP BY message_id
+) e
+WHERE e.message_id = m.id;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "edited_at";
@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+-- version counts the changes hosts and authors make to a message, so one
+-- made against a stale copy is refused instead of silently overwriting
+-- another.
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,21 @@
+-- Write your migrate up statements here
+
+-- event_outbox holds room events written in the same transaction as the
+-- change they announce, until an instance has broadcast them. instance_id is
+-- the instance that wrote the event; the others take it over once it's
+-- stale, when that instance died before broadcasting it.
+CREATE TABLE IF NOT EXISTS event_outbox (
+    "id"            BIGSERIAL       PRIMARY KEY     NOT NULL,
+    "instance_id"   uuid                            NOT NULL,
+    "room_id"       uuid                            NOT NULL,
+    "channel"       VARCHAR(32)                     NOT NULL,
+    "kind"          VARCHAR(64)                     NOT NULL,
+    "value"         JSONB                           NOT NULL,
+    "created_at"    TIMESTAMPTZ                     NOT NULL    DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS event_outbox;
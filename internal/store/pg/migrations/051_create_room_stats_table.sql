@@ -0,0 +1,122 @@
+-- Write your migrate up statements here
+
+-- room_stats holds the counts the listing and stats queries would otherwise
+-- aggregate from messages on every request. Like message_search, triggers
+-- keep it in step, in the transaction of each write whatever path made it.
+-- message_count and unanswered_count leave deleted messages out,
+-- reaction_total doesn't. last_activity is the last message posted or
+-- reaction added.
+CREATE TABLE IF NOT EXISTS room_stats (
+    "room_id"           uuid            PRIMARY KEY     NOT NULL,
+    "message_count"     BIGINT                          NOT NULL    DEFAULT 0,
+    "unanswered_count"  BIGINT                          NOT NULL    DEFAULT 0,
+    "reaction_total"    BIGINT                          NOT NULL    DEFAULT 0,
+    "last_activity"     TIMESTAMPTZ,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE FUNCTION create_room_stats() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO room_stats (room_id) VALUES (NEW.id) ON CONFLICT (room_id) DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER rooms_stats_trigger
+    AFTER INSERT ON rooms
+    FOR EACH ROW EXECUTE FUNCTION create_room_stats();
+
+-- The counting functions update the row without creating it, so the cascade
+-- deleting the messages of a room doesn't bring its stats back.
+CREATE OR REPLACE FUNCTION count_room_messages() RETURNS trigger AS $$
+DECLARE
+    room uuid;
+    messages_delta BIGINT := 0;
+    unanswered_delta BIGINT := 0;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        room := OLD.room_id;
+        IF OLD.deleted_at IS NULL THEN
+            messages_delta := messages_delta - 1;
+            IF NOT OLD.answered THEN
+                unanswered_delta := unanswered_delta - 1;
+            END IF;
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        room := NEW.room_id;
+        IF NEW.deleted_at IS NULL THEN
+            messages_delta := messages_delta + 1;
+            IF NOT NEW.answered THEN
+                unanswered_delta := unanswered_delta + 1;
+            END IF;
+        END IF;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        UPDATE room_stats
+        SET
+            message_count = message_count + messages_delta,
+            unanswered_count = unanswered_count + unanswered_delta,
+            last_activity = now()
+        WHERE room_id = room;
+    ELSIF messages_delta <> 0 OR unanswered_delta <> 0 THEN
+        UPDATE room_stats
+        SET
+            message_count = message_count + messages_delta,
+            unanswered_count = unanswered_count + unanswered_delta
+        WHERE room_id = room;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER messages_stats_trigger
+    AFTER INSERT OR DELETE OR UPDATE OF "answered", "deleted_at" ON messages
+    FOR EACH ROW EXECUTE FUNCTION count_room_messages();
+
+CREATE OR REPLACE FUNCTION count_room_reactions() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE room_stats
+        SET
+            reaction_total = reaction_total + 1,
+            last_activity = now()
+        WHERE room_id = NEW.room_id;
+    ELSE
+        UPDATE room_stats
+        SET
+            reaction_total = reaction_total - 1
+        WHERE room_id = OLD.room_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER message_reactions_stats_trigger
+    AFTER INSERT OR DELETE ON message_reactions
+    FOR EACH ROW EXECUTE FUNCTION count_room_reactions();
+
+INSERT INTO room_stats (room_id, message_count, unanswered_count, reaction_total, last_activity)
+SELECT
+    r.id,
+    (SELECT COUNT(*) FROM messages m WHERE m.room_id = r.id AND m.deleted_at IS NULL),
+    (SELECT COUNT(*) FROM messages m WHERE m.room_id = r.id AND m.deleted_at IS NULL AND NOT m.answered),
+    (SELECT COUNT(*) FROM message_reactions mr WHERE mr.room_id = r.id),
+    GREATEST(
+        (SELECT MAX(m.created_at) FROM messages m WHERE m.room_id = r.id),
+        (SELECT MAX(mr.created_at) FROM message_reactions mr WHERE mr.room_id = r.id)
+    )
+FROM rooms r
+ON CONFLICT (room_id) DO NOTHING;
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS message_reactions_stats_trigger ON message_reactions;
+DROP TRIGGER IF EXISTS messages_stats_trigger ON messages;
+DROP TRIGGER IF EXISTS rooms_stats_trigger ON rooms;
+DROP FUNCTION IF EXISTS count_room_reactions();
+DROP FUNCTION IF EXISTS count_room_messages();
+DROP FUNCTION IF EXISTS create_room_stats();
+DROP TABLE IF EXISTS room_stats;
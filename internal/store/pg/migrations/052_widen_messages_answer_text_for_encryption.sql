@@ -0,0 +1,11 @@
+-- Write your migrate up statements here
+
+-- A sealed answer is longer than the 1000 characters the API accepts, the
+-- length is enforced by the handlers instead.
+ALTER TABLE messages
+    ALTER COLUMN "answer_text" TYPE TEXT;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    ALTER COLUMN "answer_text" TYPE VARCHAR(1000);
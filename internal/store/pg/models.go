@@ -5,18 +5,323 @@
 package pg
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AdminCredential struct {
+	ID        uuid.UUID
+	Name      string
+	TokenHash string
+	CreatedAt time.Time
+}
+
+type ApiKey struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+	Name           string
+	KeyHash        string
+	CreatedAt      time.Time
+	RevokedAt      pgtype.Timestamptz
+}
+
+type Caption struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Text      string
+	Language  string
+	StartMs   pgtype.Int8
+	EndMs     pgtype.Int8
+	CreatedAt time.Time
+}
+
+type ChatBridgeMessage struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	CreatedAt  time.Time
+}
+
+type DeadLetter struct {
+	ID             uuid.UUID
+	Source         string
+	Ref            string
+	Kind           string
+	OrganizationID uuid.NullUUID
+	RoomID         uuid.NullUUID
+	Error          string
+	Attempts       int32
+	CreatedAt      time.Time
+}
+
+type Event struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+	Name           string
+	StartsAt       pgtype.Timestamptz
+	EndsAt         pgtype.Timestamptz
+	CreatedAt      time.Time
+	RoomDefaults   []byte
+}
+
+type EventOutbox struct {
+	ID         int64
+	InstanceID uuid.UUID
+	RoomID     uuid.UUID
+	Channel    string
+	Kind       string
+	Value      []byte
+	CreatedAt  time.Time
+}
+
+type IngestDelivery struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	MessageID  uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Job struct {
+	ID             uuid.UUID
+	Kind           string
+	Payload        []byte
+	OrganizationID uuid.NullUUID
+	Status         string
+	Attempts       int32
+	Error          string
+	Output         []byte
+	ContentType    string
+	Filename       string
+	CreatedAt      time.Time
+	StartedAt      pgtype.Timestamptz
+	FinishedAt     pgtype.Timestamptz
+}
+
 type Message struct {
-	ID            uuid.UUID
-	RoomID        uuid.UUID
-	Message       string
-	ReactionCount int64
-	Answered      bool
+	ID                uuid.UUID
+	RoomID            uuid.UUID
+	Message           string
+	ReactionCount     int64
+	Answered          bool
+	CreatedAt         time.Time
+	Fields            []byte
+	AuthorName        string
+	SessionID         uuid.NullUUID
+	DeletedAt         pgtype.Timestamptz
+	Pinned            bool
+	Tags              []string
+	AnswerText        string
+	AnswerAudioUrl    string
+	Language          string
+	Toxicity          pgtype.Float4
+	HiddenAt          pgtype.Timestamptz
+	ReviewedAt        pgtype.Timestamptz
+	AnswerVideoUrl    string
+	AnswerVideoOffset pgtype.Int4
+	EditedAt          pgtype.Timestamptz
+	Version           int32
+}
+
+type MessageEdit struct {
+	ID              uuid.UUID
+	MessageID       uuid.UUID
+	PreviousMessage string
+	CreatedAt       time.Time
+}
+
+type MessageReaction struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	RoomID    uuid.UUID
+	Kind      string
+	CreatedAt time.Time
+	SessionID uuid.NullUUID
+}
+
+type MessageReport struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	Reason    string
+	CreatedAt time.Time
+}
+
+type MessageSearch struct {
+	MessageID uuid.UUID
+	RoomID    uuid.UUID
+	Document  interface{}
+}
+
+type MessageTranslation struct {
+	MessageID  uuid.UUID
+	Language   string
+	SourceHash string
+	Text       string
+	CreatedAt  time.Time
+}
+
+type Organization struct {
+	ID               uuid.UUID
+	Name             string
+	CreatedAt        time.Time
+	Settings         []byte
+	StripeCustomerID pgtype.Text
 }
 
 type Room struct {
-	ID    uuid.UUID
-	Theme string
+	ID             uuid.UUID
+	Theme          string
+	EventSeq       int64
+	OwnerTokenHash string
+	FormSchema     []byte
+	PostingMode    string
+	MaxSubscribers int32
+	Code           string
+	ArchivedAt     pgtype.Timestamptz
+	OrganizationID uuid.NullUUID
+	CreatedAt      time.Time
+	TranslateTo    []string
+	Description    string
+	TrackID        uuid.NullUUID
+	StartsAt       pgtype.Timestamptz
+	HostName       string
+	ClosedAt       pgtype.Timestamptz
+}
+
+type RoomCaptionToken struct {
+	RoomID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+}
+
+type RoomChatBridge struct {
+	RoomID        uuid.UUID
+	Platform      string
+	Channel       string
+	Prefix        string
+	RatePerMinute int32
+	UpdatedAt     time.Time
+}
+
+type RoomEvent struct {
+	RoomID    uuid.UUID
+	EventID   int64
+	Channel   string
+	Kind      string
+	Value     []byte
+	CreatedAt time.Time
+}
+
+type RoomIngestHook struct {
+	RoomID    uuid.UUID
+	Mapping   []byte
+	UpdatedAt time.Time
+}
+
+type RoomOverlayToken struct {
+	RoomID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+}
+
+type RoomPeak struct {
+	RoomID          uuid.UUID
+	PeakSubscribers int32
+	UpdatedAt       time.Time
+}
+
+type RoomStat struct {
+	RoomID          uuid.UUID
+	MessageCount    int64
+	UnansweredCount int64
+	ReactionTotal   int64
+	LastActivity    pgtype.Timestamptz
+}
+
+type RoomTransfer struct {
+	ID                 uuid.UUID
+	RoomID             uuid.UUID
+	TokenHash          string
+	FromOwnerTokenHash string
+	ToSessionID        uuid.NullUUID
+	CreatedAt          time.Time
+	ExpiresAt          time.Time
+	AcceptedAt         pgtype.Timestamptz
+}
+
+type RoomWebhook struct {
+	ID          uuid.UUID
+	RoomID      uuid.UUID
+	Url         string
+	LastEventID int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RoomWebinarToken struct {
+	RoomID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+}
+
+type SavedView struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Name      string
+	Filter    []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Secret struct {
+	ID             uuid.UUID
+	OrganizationID uuid.NullUUID
+	Name           string
+	KeyID          string
+	WrappedKey     []byte
+	Ciphertext     []byte
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type Session struct {
+	ID        uuid.UUID
+	UserAgent string
+	ClientIp  string
+	CreatedAt time.Time
+}
+
+type Track struct {
+	ID        uuid.UUID
+	EventID   uuid.UUID
+	Name      string
+	Position  int32
+	CreatedAt time.Time
+}
+
+type UsageRecord struct {
+	OrganizationID uuid.UUID
+	ApiKeyID       uuid.NullUUID
+	Metric         string
+	PeriodStart    time.Time
+	Quantity       int64
+}
+
+type WebhookDelivery struct {
+	WebhookID     uuid.UUID
+	EventID       int64
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   pgtype.Timestamptz
+	CreatedAt     time.Time
+}
+
+type WebinarQuestion struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	MessageID  uuid.UUID
+	Upvotes    int32
+	CreatedAt  time.Time
 }
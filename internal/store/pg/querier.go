@@ -0,0 +1,157 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Querier interface {
+	AcceptRoomTransfer(ctx context.Context, arg AcceptRoomTransferParams) (int64, error)
+	AcquireBootstrapLock(ctx context.Context) error
+	AddMessageReactions(ctx context.Context, arg AddMessageReactionsParams) (int64, error)
+	AddMessageTag(ctx context.Context, arg AddMessageTagParams) error
+	AddUsage(ctx context.Context, arg AddUsageParams) error
+	ApplyEventRoomDefaults(ctx context.Context, arg ApplyEventRoomDefaultsParams) (int64, error)
+	ApproveMessage(ctx context.Context, id uuid.UUID) error
+	ArchiveRoom(ctx context.Context, id uuid.UUID) error
+	ClaimJob(ctx context.Context, staleBefore time.Time) (Job, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]EventOutbox, error)
+	ClaimWebhookDelivery(ctx context.Context, leaseUntil time.Time) (ClaimWebhookDeliveryRow, error)
+	CloseRoom(ctx context.Context, id uuid.UUID) (int64, error)
+	CompleteJob(ctx context.Context, arg CompleteJobParams) error
+	CompleteWebhookDelivery(ctx context.Context, arg CompleteWebhookDeliveryParams) error
+	CountAdminCredentials(ctx context.Context) (int64, error)
+	CountOrganizationRoomsSince(ctx context.Context, arg CountOrganizationRoomsSinceParams) (int64, error)
+	CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteDeadLetter(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteEvent(ctx context.Context, arg DeleteEventParams) (int64, error)
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
+	DeleteRoomCaptionToken(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteRoomChatBridge(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteRoomEventsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteRoomEventsBeyond(ctx context.Context, keep int64) (int64, error)
+	DeleteRoomIngestHook(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteRoomOverlayToken(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteRoomWebhook(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteRoomWebinarToken(ctx context.Context, roomID uuid.UUID) (int64, error)
+	DeleteSavedView(ctx context.Context, arg DeleteSavedViewParams) (int64, error)
+	DeleteSecret(ctx context.Context, arg DeleteSecretParams) (int64, error)
+	DeleteTrack(ctx context.Context, arg DeleteTrackParams) (int64, error)
+	// The row is locked before the previous text is kept, so it's only kept
+	// when the edit applies to the current version.
+	EditMessage(ctx context.Context, arg EditMessageParams) (EditMessageRow, error)
+	EnqueueWebhookDeliveries(ctx context.Context, settledBefore time.Time) (int64, error)
+	FailJob(ctx context.Context, arg FailJobParams) error
+	FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error
+	GetActiveAPIKey(ctx context.Context, id uuid.UUID) (ApiKey, error)
+	GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
+	GetActiveChatBridges(ctx context.Context) ([]RoomChatBridge, error)
+	GetAdminCredentialByHash(ctx context.Context, tokenHash string) (AdminCredential, error)
+	GetDeadLetter(ctx context.Context, id uuid.UUID) (DeadLetter, error)
+	GetDeadLetterStats(ctx context.Context) ([]GetDeadLetterStatsRow, error)
+	GetDeadLetters(ctx context.Context, arg GetDeadLettersParams) ([]DeadLetter, error)
+	GetEventReportRooms(ctx context.Context, eventID uuid.UUID) ([]GetEventReportRoomsRow, error)
+	GetEventRoomStats(ctx context.Context, eventID uuid.UUID) ([]GetEventRoomStatsRow, error)
+	GetEventTopMessages(ctx context.Context, arg GetEventTopMessagesParams) ([]GetEventTopMessagesRow, error)
+	GetEventTrack(ctx context.Context, arg GetEventTrackParams) (Track, error)
+	GetEventTracks(ctx context.Context, eventID uuid.UUID) ([]Track, error)
+	GetJob(ctx context.Context, id uuid.UUID) (Job, error)
+	GetMessageTranslation(ctx context.Context, arg GetMessageTranslationParams) (MessageTranslation, error)
+	GetOrganization(ctx context.Context, id uuid.UUID) (Organization, error)
+	GetOrganizationByStripeCustomer(ctx context.Context, stripeCustomerID pgtype.Text) (Organization, error)
+	GetOrganizationDashboardRooms(ctx context.Context, arg GetOrganizationDashboardRoomsParams) ([]GetOrganizationDashboardRoomsRow, error)
+	GetOrganizationEvent(ctx context.Context, arg GetOrganizationEventParams) (Event, error)
+	GetOrganizationEvents(ctx context.Context, organizationID uuid.UUID) ([]Event, error)
+	GetOrganizationJob(ctx context.Context, arg GetOrganizationJobParams) (Job, error)
+	GetOrganizationTrackRoomDefaults(ctx context.Context, arg GetOrganizationTrackRoomDefaultsParams) ([]byte, error)
+	GetOrganizationUsage(ctx context.Context, arg GetOrganizationUsageParams) ([]GetOrganizationUsageRow, error)
+	GetPendingRoomTransfer(ctx context.Context, arg GetPendingRoomTransferParams) (RoomTransfer, error)
+	GetRelatedRooms(ctx context.Context, arg GetRelatedRoomsParams) ([]GetRelatedRoomsRow, error)
+	GetRoom(ctx context.Context, id uuid.UUID) (Room, error)
+	GetRoomActivity(ctx context.Context, arg GetRoomActivityParams) ([]GetRoomActivityRow, error)
+	GetRoomByCode(ctx context.Context, code string) (Room, error)
+	GetRoomCaptionTokenHash(ctx context.Context, roomID uuid.UUID) (string, error)
+	GetRoomCaptions(ctx context.Context, arg GetRoomCaptionsParams) ([]Caption, error)
+	GetRoomChatBridge(ctx context.Context, roomID uuid.UUID) (RoomChatBridge, error)
+	GetRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error)
+	GetRoomEvents(ctx context.Context, arg GetRoomEventsParams) ([]RoomEvent, error)
+	GetRoomIngestHook(ctx context.Context, roomID uuid.UUID) (RoomIngestHook, error)
+	GetRoomLanguageCounts(ctx context.Context, roomID uuid.UUID) ([]GetRoomLanguageCountsRow, error)
+	GetRoomMessage(ctx context.Context, arg GetRoomMessageParams) (Message, error)
+	GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error)
+	GetRoomMessagesPage(ctx context.Context, arg GetRoomMessagesPageParams) ([]Message, error)
+	GetRoomModerationQueue(ctx context.Context, arg GetRoomModerationQueueParams) ([]GetRoomModerationQueueRow, error)
+	GetRoomOverlayTokenHash(ctx context.Context, roomID uuid.UUID) (string, error)
+	GetRoomReactionBuckets(ctx context.Context, arg GetRoomReactionBucketsParams) ([]GetRoomReactionBucketsRow, error)
+	GetRoomReactionTotals(ctx context.Context, roomID uuid.UUID) ([]GetRoomReactionTotalsRow, error)
+	GetRoomSessionMessages(ctx context.Context, arg GetRoomSessionMessagesParams) ([]Message, error)
+	GetRoomStats(ctx context.Context, roomID uuid.UUID) (RoomStat, error)
+	GetRoomTopMessages(ctx context.Context, arg GetRoomTopMessagesParams) ([]Message, error)
+	GetRoomTopMessagesPage(ctx context.Context, arg GetRoomTopMessagesPageParams) ([]Message, error)
+	GetRoomWebhook(ctx context.Context, roomID uuid.UUID) (RoomWebhook, error)
+	GetRoomWebinarTokenHash(ctx context.Context, roomID uuid.UUID) (string, error)
+	GetRooms(ctx context.Context, arg GetRoomsParams) ([]GetRoomsRow, error)
+	GetRoomsPage(ctx context.Context, arg GetRoomsPageParams) ([]GetRoomsPageRow, error)
+	GetSavedView(ctx context.Context, arg GetSavedViewParams) (SavedView, error)
+	GetSavedViews(ctx context.Context, roomID uuid.UUID) ([]SavedView, error)
+	GetSecret(ctx context.Context, arg GetSecretParams) (Secret, error)
+	GetSecrets(ctx context.Context) ([]Secret, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetWebinarQuestion(ctx context.Context, arg GetWebinarQuestionParams) (WebinarQuestion, error)
+	IncrementRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error)
+	InsertAPIKey(ctx context.Context, arg InsertAPIKeyParams) (ApiKey, error)
+	InsertAdminCredential(ctx context.Context, arg InsertAdminCredentialParams) (AdminCredential, error)
+	InsertCaption(ctx context.Context, arg InsertCaptionParams) (Caption, error)
+	InsertEvent(ctx context.Context, arg InsertEventParams) (Event, error)
+	InsertIngestDelivery(ctx context.Context, arg InsertIngestDeliveryParams) (int64, error)
+	InsertJob(ctx context.Context, arg InsertJobParams) (Job, error)
+	InsertJobUnlessPending(ctx context.Context, arg InsertJobUnlessPendingParams) (int64, error)
+	InsertMessage(ctx context.Context, arg InsertMessageParams) (uuid.UUID, error)
+	InsertMessageReport(ctx context.Context, arg InsertMessageReportParams) (MessageReport, error)
+	InsertOrganization(ctx context.Context, name string) (Organization, error)
+	InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) error
+	InsertRoom(ctx context.Context, arg InsertRoomParams) (InsertRoomRow, error)
+	InsertRoomEvent(ctx context.Context, arg InsertRoomEventParams) error
+	InsertRoomTransfer(ctx context.Context, arg InsertRoomTransferParams) (RoomTransfer, error)
+	InsertSession(ctx context.Context, arg InsertSessionParams) (Session, error)
+	InsertTrack(ctx context.Context, arg InsertTrackParams) (Track, error)
+	InsertWebinarQuestion(ctx context.Context, arg InsertWebinarQuestionParams) error
+	MarkChatBridgeMessage(ctx context.Context, arg MarkChatBridgeMessageParams) (int64, error)
+	MarkMessageAsAnswered(ctx context.Context, arg MarkMessageAsAnsweredParams) (int32, error)
+	MarkMessageAsUnanswered(ctx context.Context, arg MarkMessageAsUnansweredParams) (int32, error)
+	PinMessage(ctx context.Context, arg PinMessageParams) (int32, error)
+	ReactToMessage(ctx context.Context, arg ReactToMessageParams) (ReactToMessageRow, error)
+	RecordRoomPeak(ctx context.Context, arg RecordRoomPeakParams) error
+	RedriveWebhookDeliveries(ctx context.Context, roomID uuid.NullUUID) (int64, error)
+	RedriveWebhookDelivery(ctx context.Context, arg RedriveWebhookDeliveryParams) (int64, error)
+	RemoveReactionFromMessage(ctx context.Context, arg RemoveReactionFromMessageParams) (RemoveReactionFromMessageRow, error)
+	ReopenRoom(ctx context.Context, id uuid.UUID) (int64, error)
+	RetryJob(ctx context.Context, id uuid.UUID) (int64, error)
+	RewrapSecret(ctx context.Context, arg RewrapSecretParams) (int64, error)
+	SearchRoomMessages(ctx context.Context, arg SearchRoomMessagesParams) ([]SearchRoomMessagesRow, error)
+	SetEventRoomDefaults(ctx context.Context, arg SetEventRoomDefaultsParams) error
+	SetMessageAnswerAudio(ctx context.Context, arg SetMessageAnswerAudioParams) (int64, error)
+	SetRoomTrack(ctx context.Context, arg SetRoomTrackParams) (int64, error)
+	SetWebinarQuestionUpvotes(ctx context.Context, arg SetWebinarQuestionUpvotesParams) error
+	SoftDeleteMessage(ctx context.Context, id uuid.UUID) error
+	UnarchiveRoom(ctx context.Context, id uuid.UUID) error
+	UpdateOrganizationBilling(ctx context.Context, arg UpdateOrganizationBillingParams) error
+	UpsertMessageTranslation(ctx context.Context, arg UpsertMessageTranslationParams) error
+	UpsertRoomCaptionToken(ctx context.Context, arg UpsertRoomCaptionTokenParams) error
+	UpsertRoomChatBridge(ctx context.Context, arg UpsertRoomChatBridgeParams) (RoomChatBridge, error)
+	UpsertRoomIngestHook(ctx context.Context, arg UpsertRoomIngestHookParams) (RoomIngestHook, error)
+	UpsertRoomOverlayToken(ctx context.Context, arg UpsertRoomOverlayTokenParams) error
+	UpsertRoomWebhook(ctx context.Context, arg UpsertRoomWebhookParams) (RoomWebhook, error)
+	UpsertRoomWebinarToken(ctx context.Context, arg UpsertRoomWebinarTokenParams) error
+	UpsertSavedView(ctx context.Context, arg UpsertSavedViewParams) (SavedView, error)
+	UpsertSecret(ctx context.Context, arg UpsertSecretParams) error
+}
+
+var _ Querier = (*Queries)(nil)
@@ -7,68 +7,3650 @@ package pg
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getMessage = `-- name: GetMessage :one
+const acceptRoomTransfer = `-- name: AcceptRoomTransfer :execrows
+WITH accepted AS (
+    UPDATE room_transfers
+    SET
+        accepted_at = now()
+    FROM rooms
+    WHERE
+        room_transfers.id = $2
+        AND room_transfers.accepted_at IS NULL
+        AND room_transfers.expires_at > now()
+        AND rooms.id = room_transfers.room_id
+        AND rooms.owner_token_hash = room_transfers.from_owner_token_hash
+    RETURNING room_transfers.room_id, room_transfers.from_owner_token_hash
+)
+UPDATE rooms
+SET
+    owner_token_hash = $1
+FROM accepted
+WHERE
+    rooms.id = accepted.room_id AND rooms.owner_token_hash = accepted.from_owner_token_hash
+`
+
+type AcceptRoomTransferParams struct {
+	OwnerTokenHash string
+	ID             uuid.UUID
+}
+
+func (q *Queries) AcceptRoomTransfer(ctx context.Context, arg AcceptRoomTransferParams) (int64, error) {
+	result, err := q.db.Exec(ctx, acceptRoomTransfer, arg.OwnerTokenHash, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const acquireBootstrapLock = `-- name: AcquireBootstrapLock :exec
+SELECT pg_advisory_xact_lock(hashtext('wsrs_bootstrap'))
+`
+
+func (q *Queries) AcquireBootstrapLock(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, acquireBootstrapLock)
+	return err
+}
+
+const addMessageReactions = `-- name: AddMessageReactions :one
+WITH reactions AS (
+    INSERT INTO message_reactions
+        ("message_id", "room_id", "kind")
+    SELECT m."id", m."room_id", $2::varchar FROM messages m, generate_series(1, $3::int)
+    WHERE m.id = $1
+    RETURNING "id"
+)
+UPDATE messages
+SET
+    reaction_count = reaction_count + (SELECT COUNT(*) FROM reactions)
+WHERE
+    messages.id = $1
+RETURNING "reaction_count"
+`
+
+type AddMessageReactionsParams struct {
+	ID    uuid.UUID
+	Kind  string
+	Count int32
+}
+
+func (q *Queries) AddMessageReactions(ctx context.Context, arg AddMessageReactionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, addMessageReactions, arg.ID, arg.Kind, arg.Count)
+	var reaction_count int64
+	err := row.Scan(&reaction_count)
+	return reaction_count, err
+}
+
+const addMessageTag = `-- name: AddMessageTag :exec
+UPDATE messages
+SET
+    tags = array_append(tags, $1::varchar),
+    version = version + 1
+WHERE
+    id = $2 AND NOT ($1::varchar = ANY(tags))
+`
+
+type AddMessageTagParams struct {
+	Tag string
+	ID  uuid.UUID
+}
+
+func (q *Queries) AddMessageTag(ctx context.Context, arg AddMessageTagParams) error {
+	_, err := q.db.Exec(ctx, addMessageTag, arg.Tag, arg.ID)
+	return err
+}
+
+const addUsage = `-- name: AddUsage :exec
+INSERT INTO usage_records
+    ("organization_id", "api_key_id", "metric", "period_start", "quantity") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("organization_id", "api_key_id", "metric", "period_start") DO UPDATE
+SET
+    quantity = usage_records.quantity + EXCLUDED.quantity
+`
+
+type AddUsageParams struct {
+	OrganizationID uuid.UUID
+	ApiKeyID       uuid.NullUUID
+	Metric         string
+	PeriodStart    time.Time
+	Quantity       int64
+}
+
+func (q *Queries) AddUsage(ctx context.Context, arg AddUsageParams) error {
+	_, err := q.db.Exec(ctx, addUsage,
+		arg.OrganizationID,
+		arg.ApiKeyID,
+		arg.Metric,
+		arg.PeriodStart,
+		arg.Quantity,
+	)
+	return err
+}
+
+const applyEventRoomDefaults = `-- name: ApplyEventRoomDefaults :execrows
+UPDATE rooms r
+SET
+    posting_mode = COALESCE($1, r.posting_mode),
+    max_subscribers = COALESCE($2, r.max_subscribers),
+    translate_to = COALESCE($3, r.translate_to)
+FROM tracks t
+WHERE
+    r.track_id = t.id AND t.event_id = $4
+`
+
+type ApplyEventRoomDefaultsParams struct {
+	PostingMode    pgtype.Text
+	MaxSubscribers pgtype.Int4
+	TranslateTo    []string
+	EventID        uuid.UUID
+}
+
+func (q *Queries) ApplyEventRoomDefaults(ctx context.Context, arg ApplyEventRoomDefaultsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, applyEventRoomDefaults,
+		arg.PostingMode,
+		arg.MaxSubscribers,
+		arg.TranslateTo,
+		arg.EventID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const approveMessage = `-- name: ApproveMessage :exec
+UPDATE messages
+SET
+    hidden_at = NULL,
+    reviewed_at = now()
+WHERE
+    id = $1
+`
+
+func (q *Queries) ApproveMessage(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, approveMessage, id)
+	return err
+}
+
+const archiveRoom = `-- name: ArchiveRoom :exec
+UPDATE rooms
+SET
+    archived_at = now()
+WHERE
+    id = $1 AND archived_at IS NULL
+`
+
+func (q *Queries) ArchiveRoom(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, archiveRoom, id)
+	return err
+}
+
+const claimJob = `-- name: ClaimJob :one
+UPDATE jobs
+SET
+    status = 'running',
+    attempts = attempts + 1,
+    started_at = now()
+WHERE id = (
+    SELECT j.id FROM jobs j
+    WHERE
+        j.status = 'queued'
+        OR (j.status = 'running' AND j.started_at < $1::timestamptz)
+    ORDER BY j.created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+`
+
+func (q *Queries) ClaimJob(ctx context.Context, staleBefore time.Time) (Job, error) {
+	row := q.db.QueryRow(ctx, claimJob, staleBefore)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.OrganizationID,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.Output,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
+    "id", "instance_id", "room_id", "channel", "kind", "value", "created_at"
+FROM event_outbox
+WHERE
+    instance_id = $1 OR created_at < $2::timestamptz
+ORDER BY id ASC
+LIMIT $3::int
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimOutboxEventsParams struct {
+	InstanceID  uuid.UUID
+	StaleBefore time.Time
+	MaxEvents   int32
+}
+
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]EventOutbox, error) {
+	rows, err := q.db.Query(ctx, claimOutboxEvents, arg.InstanceID, arg.StaleBefore, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventOutbox
+	for rows.Next() {
+		var i EventOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.RoomID,
+			&i.Channel,
+			&i.Kind,
+			&i.Value,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimWebhookDelivery = `-- name: ClaimWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET
+    attempts = d.attempts + 1,
+    next_attempt_at = $1::timestamptz
+FROM room_webhooks w
+WHERE
+    w.id = d.webhook_id
+    AND (d.webhook_id, d.event_id) = (
+        SELECT dd.webhook_id, dd.event_id FROM webhook_deliveries dd
+        WHERE dd.status = 'pending' AND dd.next_attempt_at <= now()
+        ORDER BY dd.next_attempt_at ASC
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED
+    )
+RETURNING d."webhook_id", d."event_id", d."attempts", w."room_id", w."url"
+`
+
+type ClaimWebhookDeliveryRow struct {
+	WebhookID uuid.UUID
+	EventID   int64
+	Attempts  int32
+	RoomID    uuid.UUID
+	Url       string
+}
+
+func (q *Queries) ClaimWebhookDelivery(ctx context.Context, leaseUntil time.Time) (ClaimWebhookDeliveryRow, error) {
+	row := q.db.QueryRow(ctx, claimWebhookDelivery, leaseUntil)
+	var i ClaimWebhookDeliveryRow
+	err := row.Scan(
+		&i.WebhookID,
+		&i.EventID,
+		&i.Attempts,
+		&i.RoomID,
+		&i.Url,
+	)
+	return i, err
+}
+
+const closeRoom = `-- name: CloseRoom :execrows
+UPDATE rooms
+SET
+    closed_at = now()
+WHERE
+    id = $1 AND closed_at IS NULL
+`
+
+func (q *Queries) CloseRoom(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, closeRoom, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const completeJob = `-- name: CompleteJob :exec
+UPDATE jobs
+SET
+    status = 'done',
+    output = $2,
+    content_type = $3,
+    filename = $4,
+    error = '',
+    finished_at = now()
+WHERE
+    id = $1
+`
+
+type CompleteJobParams struct {
+	ID          uuid.UUID
+	Output      []byte
+	ContentType string
+	Filename    string
+}
+
+func (q *Queries) CompleteJob(ctx context.Context, arg CompleteJobParams) error {
+	_, err := q.db.Exec(ctx, completeJob,
+		arg.ID,
+		arg.Output,
+		arg.ContentType,
+		arg.Filename,
+	)
+	return err
+}
+
+const completeWebhookDelivery = `-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = 'delivered',
+    last_error = '',
+    delivered_at = now()
+WHERE webhook_id = $1 AND event_id = $2
+`
+
+type CompleteWebhookDeliveryParams struct {
+	WebhookID uuid.UUID
+	EventID   int64
+}
+
+func (q *Queries) CompleteWebhookDelivery(ctx context.Context, arg CompleteWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, completeWebhookDelivery, arg.WebhookID, arg.EventID)
+	return err
+}
+
+const countAdminCredentials = `-- name: CountAdminCredentials :one
+SELECT
+    COUNT(*)
+FROM admin_credentials
+`
+
+func (q *Queries) CountAdminCredentials(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countAdminCredentials)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOrganizationRoomsSince = `-- name: CountOrganizationRoomsSince :one
+SELECT
+    COUNT(*)
+FROM rooms
+WHERE
+    organization_id = $1 AND created_at >= $2
+`
+
+type CountOrganizationRoomsSinceParams struct {
+	OrganizationID uuid.NullUUID
+	CreatedAt      time.Time
+}
+
+func (q *Queries) CountOrganizationRoomsSince(ctx context.Context, arg CountOrganizationRoomsSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOrganizationRoomsSince, arg.OrganizationID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRoomMessages = `-- name: CountRoomMessages :one
+SELECT
+    COUNT(*)
 FROM messages
 WHERE
-    id = $1
+    room_id = $1
+`
+
+func (q *Queries) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countRoomMessages, roomID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteDeadLetter = `-- name: DeleteDeadLetter :execrows
+DELETE FROM dead_letters
+WHERE
+    id = $1
+`
+
+func (q *Queries) DeleteDeadLetter(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeadLetter, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteEvent = `-- name: DeleteEvent :execrows
+DELETE FROM events
+WHERE
+    id = $1 AND organization_id = $2
+`
+
+type DeleteEventParams struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+func (q *Queries) DeleteEvent(ctx context.Context, arg DeleteEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteEvent, arg.ID, arg.OrganizationID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOutboxEvents = `-- name: DeleteOutboxEvents :exec
+DELETE FROM event_outbox
+WHERE
+    id = ANY($1::bigint[])
+`
+
+func (q *Queries) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	_, err := q.db.Exec(ctx, deleteOutboxEvents, ids)
+	return err
+}
+
+const deleteRoomCaptionToken = `-- name: DeleteRoomCaptionToken :execrows
+DELETE FROM room_caption_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomCaptionToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomCaptionToken, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomChatBridge = `-- name: DeleteRoomChatBridge :execrows
+DELETE FROM room_chat_bridges
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomChatBridge(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomChatBridge, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomEventsBefore = `-- name: DeleteRoomEventsBefore :execrows
+DELETE FROM room_events
+WHERE
+    created_at < $1::timestamptz
+`
+
+func (q *Queries) DeleteRoomEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomEventsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomEventsBeyond = `-- name: DeleteRoomEventsBeyond :execrows
+DELETE FROM room_events e
+USING rooms r
+WHERE
+    r.id = e.room_id AND e.event_id <= r.event_seq - $1::bigint
+`
+
+func (q *Queries) DeleteRoomEventsBeyond(ctx context.Context, keep int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomEventsBeyond, keep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomIngestHook = `-- name: DeleteRoomIngestHook :execrows
+DELETE FROM room_ingest_hooks
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomIngestHook(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomIngestHook, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomOverlayToken = `-- name: DeleteRoomOverlayToken :execrows
+DELETE FROM room_overlay_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomOverlayToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomOverlayToken, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomWebhook = `-- name: DeleteRoomWebhook :execrows
+DELETE FROM room_webhooks
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomWebhook(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomWebhook, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRoomWebinarToken = `-- name: DeleteRoomWebinarToken :execrows
+DELETE FROM room_webinar_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) DeleteRoomWebinarToken(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomWebinarToken, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSavedView = `-- name: DeleteSavedView :execrows
+DELETE FROM saved_views
+WHERE
+    room_id = $1 AND name = $2
+`
+
+type DeleteSavedViewParams struct {
+	RoomID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteSavedView(ctx context.Context, arg DeleteSavedViewParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSavedView, arg.RoomID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSecret = `-- name: DeleteSecret :execrows
+DELETE FROM secrets
+WHERE
+    organization_id IS NOT DISTINCT FROM $1 AND name = $2
+`
+
+type DeleteSecretParams struct {
+	OrganizationID uuid.NullUUID
+	Name           string
+}
+
+func (q *Queries) DeleteSecret(ctx context.Context, arg DeleteSecretParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSecret, arg.OrganizationID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTrack = `-- name: DeleteTrack :execrows
+DELETE FROM tracks
+WHERE
+    id = $1 AND event_id = $2
+`
+
+type DeleteTrackParams struct {
+	ID      uuid.UUID
+	EventID uuid.UUID
+}
+
+func (q *Queries) DeleteTrack(ctx context.Context, arg DeleteTrackParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTrack, arg.ID, arg.EventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const editMessage = `-- name: EditMessage :one
+WITH previous AS (
+    SELECT m."id", m."message" FROM messages m WHERE m.id = $1 AND m.version = $2::int FOR UPDATE
+), edited AS (
+    UPDATE messages m
+    SET
+        message = $3,
+        language = $4,
+        edited_at = now(),
+        version = m.version + 1
+    FROM previous p
+    WHERE
+        m.id = p.id
+    RETURNING m."message", m."edited_at", m."version"
+), edit AS (
+    INSERT INTO message_edits
+        ("message_id", "previous_message")
+    SELECT p."id", p."message" FROM previous p, edited
+)
+SELECT "message", "edited_at", "version" FROM edited
+`
+
+type EditMessageParams struct {
+	ID       uuid.UUID
+	Version  int32
+	Message  string
+	Language string
+}
+
+type EditMessageRow struct {
+	Message  string
+	EditedAt pgtype.Timestamptz
+	Version  int32
+}
+
+// The row is locked before the previous text is kept, so it's only kept
+// when the edit applies to the current version.
+func (q *Queries) EditMessage(ctx context.Context, arg EditMessageParams) (EditMessageRow, error) {
+	row := q.db.QueryRow(ctx, editMessage,
+		arg.ID,
+		arg.Version,
+		arg.Message,
+		arg.Language,
+	)
+	var i EditMessageRow
+	err := row.Scan(&i.Message, &i.EditedAt, &i.Version)
+	return i, err
+}
+
+const enqueueWebhookDeliveries = `-- name: EnqueueWebhookDeliveries :one
+WITH queued AS (
+    INSERT INTO webhook_deliveries
+        ("webhook_id", "event_id")
+    SELECT
+        w.id, e.event_id
+    FROM room_webhooks w
+    JOIN room_events e ON e.room_id = w.room_id AND e.event_id > w.last_event_id
+    WHERE e.created_at < $1::timestamptz
+    ON CONFLICT DO NOTHING
+    RETURNING "webhook_id", "event_id"
+), advanced AS (
+    UPDATE room_webhooks w
+    SET
+        last_event_id = q.event_id
+    FROM (SELECT webhook_id, MAX(event_id) AS event_id FROM queued GROUP BY webhook_id) q
+    WHERE w.id = q.webhook_id
+)
+SELECT COUNT(*) FROM queued
+`
+
+func (q *Queries) EnqueueWebhookDeliveries(ctx context.Context, settledBefore time.Time) (int64, error) {
+	row := q.db.QueryRow(ctx, enqueueWebhookDeliveries, settledBefore)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const failJob = `-- name: FailJob :exec
+WITH failed AS (
+    UPDATE jobs
+    SET
+        status = CASE WHEN attempts >= $1::int THEN 'failed' ELSE 'queued' END,
+        error = $2,
+        finished_at = CASE WHEN attempts >= $1::int THEN now() END
+    WHERE
+        jobs.id = $3
+    RETURNING "id", "kind", "organization_id", "status", "error", "attempts"
+)
+INSERT INTO dead_letters
+    ("source", "ref", "kind", "organization_id", "error", "attempts")
+SELECT 'job', "id"::text, "kind", "organization_id", "error", "attempts"
+FROM failed
+WHERE "status" = 'failed'
+ON CONFLICT (source, ref) DO UPDATE
+SET error = EXCLUDED.error, attempts = EXCLUDED.attempts, created_at = now()
+`
+
+type FailJobParams struct {
+	MaxAttempts int32
+	Error       string
+	ID          uuid.UUID
+}
+
+func (q *Queries) FailJob(ctx context.Context, arg FailJobParams) error {
+	_, err := q.db.Exec(ctx, failJob, arg.MaxAttempts, arg.Error, arg.ID)
+	return err
+}
+
+const failWebhookDelivery = `-- name: FailWebhookDelivery :exec
+WITH failed AS (
+    UPDATE webhook_deliveries
+    SET
+        status = CASE WHEN attempts >= $1::int THEN 'failed' ELSE 'pending' END,
+        next_attempt_at = $2::timestamptz,
+        last_error = $3
+    WHERE webhook_id = $4 AND event_id = $5
+    RETURNING "webhook_id", "event_id", "status", "last_error", "attempts"
+)
+INSERT INTO dead_letters
+    ("source", "ref", "kind", "organization_id", "room_id", "error", "attempts")
+SELECT 'webhook', f."webhook_id"::text || ':' || f."event_id"::text, 'room_event', r."organization_id", r."id", f."last_error", f."attempts"
+FROM failed f
+JOIN room_webhooks w ON w.id = f.webhook_id
+JOIN rooms r ON r.id = w.room_id
+WHERE f."status" = 'failed'
+ON CONFLICT (source, ref) DO UPDATE
+SET error = EXCLUDED.error, attempts = EXCLUDED.attempts, created_at = now()
+`
+
+type FailWebhookDeliveryParams struct {
+	MaxAttempts int32
+	RetryAt     time.Time
+	Error       string
+	WebhookID   uuid.UUID
+	EventID     int64
+}
+
+func (q *Queries) FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, failWebhookDelivery,
+		arg.MaxAttempts,
+		arg.RetryAt,
+		arg.Error,
+		arg.WebhookID,
+		arg.EventID,
+	)
+	return err
+}
+
+const getActiveAPIKey = `-- name: GetActiveAPIKey :one
+SELECT
+    "id", "organization_id", "name", "key_hash", "created_at", "revoked_at"
+FROM api_keys
+WHERE
+    id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) GetActiveAPIKey(ctx context.Context, id uuid.UUID) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getActiveAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.KeyHash,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getActiveAPIKeyByHash = `-- name: GetActiveAPIKeyByHash :one
+SELECT
+    "id", "organization_id", "name", "key_hash", "created_at", "revoked_at"
+FROM api_keys
+WHERE
+    key_hash = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getActiveAPIKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.KeyHash,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getActiveChatBridges = `-- name: GetActiveChatBridges :many
+SELECT
+    b."room_id", b."platform", b."channel", b."prefix", b."rate_per_minute", b."updated_at"
+FROM room_chat_bridges b
+JOIN rooms r ON r.id = b.room_id
+WHERE
+    r.archived_at IS NULL
+`
+
+func (q *Queries) GetActiveChatBridges(ctx context.Context) ([]RoomChatBridge, error) {
+	rows, err := q.db.Query(ctx, getActiveChatBridges)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RoomChatBridge
+	for rows.Next() {
+		var i RoomChatBridge
+		if err := rows.Scan(
+			&i.RoomID,
+			&i.Platform,
+			&i.Channel,
+			&i.Prefix,
+			&i.RatePerMinute,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAdminCredentialByHash = `-- name: GetAdminCredentialByHash :one
+SELECT
+    "id", "name", "token_hash", "created_at"
+FROM admin_credentials
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetAdminCredentialByHash(ctx context.Context, tokenHash string) (AdminCredential, error) {
+	row := q.db.QueryRow(ctx, getAdminCredentialByHash, tokenHash)
+	var i AdminCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.TokenHash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDeadLetter = `-- name: GetDeadLetter :one
+SELECT
+    "id", "source", "ref", "kind", "organization_id", "room_id", "error", "attempts", "created_at"
+FROM dead_letters
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetDeadLetter(ctx context.Context, id uuid.UUID) (DeadLetter, error) {
+	row := q.db.QueryRow(ctx, getDeadLetter, id)
+	var i DeadLetter
+	err := row.Scan(
+		&i.ID,
+		&i.Source,
+		&i.Ref,
+		&i.Kind,
+		&i.OrganizationID,
+		&i.RoomID,
+		&i.Error,
+		&i.Attempts,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDeadLetterStats = `-- name: GetDeadLetterStats :many
+SELECT
+    "source",
+    COUNT(*) AS "count",
+    MIN(created_at)::timestamptz AS "oldest"
+FROM dead_letters
+GROUP BY "source"
+`
+
+type GetDeadLetterStatsRow struct {
+	Source string
+	Count  int64
+	Oldest time.Time
+}
+
+func (q *Queries) GetDeadLetterStats(ctx context.Context) ([]GetDeadLetterStatsRow, error) {
+	rows, err := q.db.Query(ctx, getDeadLetterStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeadLetterStatsRow
+	for rows.Next() {
+		var i GetDeadLetterStatsRow
+		if err := rows.Scan(&i.Source, &i.Count, &i.Oldest); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeadLetters = `-- name: GetDeadLetters :many
+SELECT
+    "id", "source", "ref", "kind", "organization_id", "room_id", "error", "attempts", "created_at"
+FROM dead_letters
+WHERE
+    $1::text IS NULL OR source = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2::int
+`
+
+type GetDeadLettersParams struct {
+	Source         pgtype.Text
+	MaxDeadLetters int32
+}
+
+func (q *Queries) GetDeadLetters(ctx context.Context, arg GetDeadLettersParams) ([]DeadLetter, error) {
+	rows, err := q.db.Query(ctx, getDeadLetters, arg.Source, arg.MaxDeadLetters)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeadLetter
+	for rows.Next() {
+		var i DeadLetter
+		if err := rows.Scan(
+			&i.ID,
+			&i.Source,
+			&i.Ref,
+			&i.Kind,
+			&i.OrganizationID,
+			&i.RoomID,
+			&i.Error,
+			&i.Attempts,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventReportRooms = `-- name: GetEventReportRooms :many
+SELECT
+    r."id", r."code", r."theme", r."starts_at", r."host_name", t."name" AS "track_name",
+    COALESCE((SELECT p.peak_subscribers FROM room_peaks p WHERE p.room_id = r.id), 0)::int AS "peak_subscribers",
+    (
+        SELECT COUNT(DISTINCT m.session_id) FROM messages m
+        WHERE m.room_id = r.id AND m.deleted_at IS NULL
+    ) AS "participant_count",
+    s."message_count", (s."message_count" - s."unanswered_count")::bigint AS "answered_count", s."reaction_total" AS "reaction_count"
+FROM rooms r
+JOIN tracks t ON t.id = r.track_id
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    t.event_id = $1
+ORDER BY t.position ASC, t.created_at ASC, r.starts_at ASC NULLS LAST, r.created_at ASC
+`
+
+type GetEventReportRoomsRow struct {
+	ID               uuid.UUID
+	Code             string
+	Theme            string
+	StartsAt         pgtype.Timestamptz
+	HostName         string
+	TrackName        string
+	PeakSubscribers  int32
+	ParticipantCount int64
+	MessageCount     int64
+	AnsweredCount    int64
+	ReactionCount    int64
+}
+
+func (q *Queries) GetEventReportRooms(ctx context.Context, eventID uuid.UUID) ([]GetEventReportRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getEventReportRooms, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventReportRoomsRow
+	for rows.Next() {
+		var i GetEventReportRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Theme,
+			&i.StartsAt,
+			&i.HostName,
+			&i.TrackName,
+			&i.PeakSubscribers,
+			&i.ParticipantCount,
+			&i.MessageCount,
+			&i.AnsweredCount,
+			&i.ReactionCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventRoomStats = `-- name: GetEventRoomStats :many
+SELECT
+    r."id", r."track_id", r."code", r."theme", r."archived_at", r."closed_at", r."starts_at", r."host_name",
+    s."message_count", (s."message_count" - s."unanswered_count")::bigint AS "answered_count", s."reaction_total" AS "reaction_count"
+FROM rooms r
+JOIN tracks t ON t.id = r.track_id
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    t.event_id = $1
+ORDER BY t.position ASC, t.created_at ASC, r.starts_at ASC NULLS LAST, r.created_at ASC
+`
+
+type GetEventRoomStatsRow struct {
+	ID            uuid.UUID
+	TrackID       uuid.NullUUID
+	Code          string
+	Theme         string
+	ArchivedAt    pgtype.Timestamptz
+	ClosedAt      pgtype.Timestamptz
+	StartsAt      pgtype.Timestamptz
+	HostName      string
+	MessageCount  int64
+	AnsweredCount int64
+	ReactionCount int64
+}
+
+func (q *Queries) GetEventRoomStats(ctx context.Context, eventID uuid.UUID) ([]GetEventRoomStatsRow, error) {
+	rows, err := q.db.Query(ctx, getEventRoomStats, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventRoomStatsRow
+	for rows.Next() {
+		var i GetEventRoomStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrackID,
+			&i.Code,
+			&i.Theme,
+			&i.ArchivedAt,
+			&i.ClosedAt,
+			&i.StartsAt,
+			&i.HostName,
+			&i.MessageCount,
+			&i.AnsweredCount,
+			&i.ReactionCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventTopMessages = `-- name: GetEventTopMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered"
+FROM (
+    SELECT
+        m.id, m.room_id, m.message, m.reaction_count, m.answered,
+        ROW_NUMBER() OVER (PARTITION BY m.room_id ORDER BY m.reaction_count DESC, m.created_at ASC) AS rank
+    FROM messages m
+    JOIN rooms r ON r.id = m.room_id
+    JOIN tracks t ON t.id = r.track_id
+    WHERE
+        t.event_id = $1 AND m.deleted_at IS NULL AND m.hidden_at IS NULL
+) ranked
+WHERE
+    rank <= $2::int
+ORDER BY room_id, rank
+`
+
+type GetEventTopMessagesParams struct {
+	EventID uuid.UUID
+	PerRoom int32
+}
+
+type GetEventTopMessagesRow struct {
+	ID            uuid.UUID
+	RoomID        uuid.UUID
+	Message       string
+	ReactionCount int64
+	Answered      bool
+}
+
+func (q *Queries) GetEventTopMessages(ctx context.Context, arg GetEventTopMessagesParams) ([]GetEventTopMessagesRow, error) {
+	rows, err := q.db.Query(ctx, getEventTopMessages, arg.EventID, arg.PerRoom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventTopMessagesRow
+	for rows.Next() {
+		var i GetEventTopMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventTrack = `-- name: GetEventTrack :one
+SELECT
+    "id", "event_id", "name", "position", "created_at"
+FROM tracks
+WHERE
+    id = $1 AND event_id = $2
+`
+
+type GetEventTrackParams struct {
+	ID      uuid.UUID
+	EventID uuid.UUID
+}
+
+func (q *Queries) GetEventTrack(ctx context.Context, arg GetEventTrackParams) (Track, error) {
+	row := q.db.QueryRow(ctx, getEventTrack, arg.ID, arg.EventID)
+	var i Track
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getEventTracks = `-- name: GetEventTracks :many
+SELECT
+    "id", "event_id", "name", "position", "created_at"
+FROM tracks
+WHERE
+    event_id = $1
+ORDER BY position ASC, created_at ASC
+`
+
+func (q *Queries) GetEventTracks(ctx context.Context, eventID uuid.UUID) ([]Track, error) {
+	rows, err := q.db.Query(ctx, getEventTracks, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Track
+	for rows.Next() {
+		var i Track
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.Name,
+			&i.Position,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getJob = `-- name: GetJob :one
+SELECT
+    "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+FROM jobs
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetJob(ctx context.Context, id uuid.UUID) (Job, error) {
+	row := q.db.QueryRow(ctx, getJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.OrganizationID,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.Output,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getMessageTranslation = `-- name: GetMessageTranslation :one
+SELECT
+    "message_id", "language", "source_hash", "text", "created_at"
+FROM message_translations
+WHERE
+    message_id = $1 AND language = $2
+`
+
+type GetMessageTranslationParams struct {
+	MessageID uuid.UUID
+	Language  string
+}
+
+func (q *Queries) GetMessageTranslation(ctx context.Context, arg GetMessageTranslationParams) (MessageTranslation, error) {
+	row := q.db.QueryRow(ctx, getMessageTranslation, arg.MessageID, arg.Language)
+	var i MessageTranslation
+	err := row.Scan(
+		&i.MessageID,
+		&i.Language,
+		&i.SourceHash,
+		&i.Text,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOrganization = `-- name: GetOrganization :one
+SELECT
+    "id", "name", "created_at", "settings", "stripe_customer_id"
+FROM organizations
+WHERE id = $1
+`
+
+func (q *Queries) GetOrganization(ctx context.Context, id uuid.UUID) (Organization, error) {
+	row := q.db.QueryRow(ctx, getOrganization, id)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Settings,
+		&i.StripeCustomerID,
+	)
+	return i, err
+}
+
+const getOrganizationByStripeCustomer = `-- name: GetOrganizationByStripeCustomer :one
+SELECT
+    "id", "name", "created_at", "settings", "stripe_customer_id"
+FROM organizations
+WHERE stripe_customer_id = $1
+`
+
+func (q *Queries) GetOrganizationByStripeCustomer(ctx context.Context, stripeCustomerID pgtype.Text) (Organization, error) {
+	row := q.db.QueryRow(ctx, getOrganizationByStripeCustomer, stripeCustomerID)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Settings,
+		&i.StripeCustomerID,
+	)
+	return i, err
+}
+
+const getOrganizationDashboardRooms = `-- name: GetOrganizationDashboardRooms :many
+SELECT
+    r."id", r."theme", r."code", r."archived_at", r."closed_at", r."created_at",
+    s."unanswered_count", s."last_activity",
+    (
+        SELECT COUNT(*) FROM messages m
+        WHERE m.room_id = r.id AND m.created_at >= $1
+    ) AS "recent_messages",
+    (
+        SELECT COUNT(*) FROM message_reactions mr
+        WHERE mr.room_id = r.id AND mr.created_at >= $1
+    ) AS "recent_reactions"
+FROM rooms r
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    r.organization_id = $2
+ORDER BY r.archived_at IS NOT NULL, r.created_at DESC
+`
+
+type GetOrganizationDashboardRoomsParams struct {
+	Since          time.Time
+	OrganizationID uuid.NullUUID
+}
+
+type GetOrganizationDashboardRoomsRow struct {
+	ID              uuid.UUID
+	Theme           string
+	Code            string
+	ArchivedAt      pgtype.Timestamptz
+	ClosedAt        pgtype.Timestamptz
+	CreatedAt       time.Time
+	UnansweredCount int64
+	LastActivity    pgtype.Timestamptz
+	RecentMessages  int64
+	RecentReactions int64
+}
+
+func (q *Queries) GetOrganizationDashboardRooms(ctx context.Context, arg GetOrganizationDashboardRoomsParams) ([]GetOrganizationDashboardRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getOrganizationDashboardRooms, arg.Since, arg.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrganizationDashboardRoomsRow
+	for rows.Next() {
+		var i GetOrganizationDashboardRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Theme,
+			&i.Code,
+			&i.ArchivedAt,
+			&i.ClosedAt,
+			&i.CreatedAt,
+			&i.UnansweredCount,
+			&i.LastActivity,
+			&i.RecentMessages,
+			&i.RecentReactions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrganizationEvent = `-- name: GetOrganizationEvent :one
+SELECT
+    "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults"
+FROM events
+WHERE
+    id = $1 AND organization_id = $2
+`
+
+type GetOrganizationEventParams struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+func (q *Queries) GetOrganizationEvent(ctx context.Context, arg GetOrganizationEventParams) (Event, error) {
+	row := q.db.QueryRow(ctx, getOrganizationEvent, arg.ID, arg.OrganizationID)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.RoomDefaults,
+	)
+	return i, err
+}
+
+const getOrganizationEvents = `-- name: GetOrganizationEvents :many
+SELECT
+    "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults"
+FROM events
+WHERE
+    organization_id = $1
+ORDER BY starts_at ASC NULLS LAST, created_at ASC
+`
+
+func (q *Queries) GetOrganizationEvents(ctx context.Context, organizationID uuid.UUID) ([]Event, error) {
+	rows, err := q.db.Query(ctx, getOrganizationEvents, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.Name,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.CreatedAt,
+			&i.RoomDefaults,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrganizationJob = `-- name: GetOrganizationJob :one
+SELECT
+    "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+FROM jobs
+WHERE
+    id = $1 AND organization_id = $2
+`
+
+type GetOrganizationJobParams struct {
+	ID             uuid.UUID
+	OrganizationID uuid.NullUUID
+}
+
+func (q *Queries) GetOrganizationJob(ctx context.Context, arg GetOrganizationJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, getOrganizationJob, arg.ID, arg.OrganizationID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.OrganizationID,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.Output,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getOrganizationTrackRoomDefaults = `-- name: GetOrganizationTrackRoomDefaults :one
+SELECT
+    e."room_defaults"
+FROM tracks t
+JOIN events e ON e.id = t.event_id
+WHERE
+    t.id = $1 AND e.organization_id = $2
+`
+
+type GetOrganizationTrackRoomDefaultsParams struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+func (q *Queries) GetOrganizationTrackRoomDefaults(ctx context.Context, arg GetOrganizationTrackRoomDefaultsParams) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getOrganizationTrackRoomDefaults, arg.ID, arg.OrganizationID)
+	var room_defaults []byte
+	err := row.Scan(&room_defaults)
+	return room_defaults, err
+}
+
+const getOrganizationUsage = `-- name: GetOrganizationUsage :many
+SELECT
+    date_trunc('day', period_start)::timestamptz AS "day",
+    "api_key_id",
+    "metric",
+    SUM(quantity)::bigint AS "quantity"
+FROM usage_records
+WHERE
+    organization_id = $1 AND period_start >= $2 AND period_start < $3
+GROUP BY "day", "api_key_id", "metric"
+ORDER BY "day", "metric"
+`
+
+type GetOrganizationUsageParams struct {
+	OrganizationID uuid.UUID
+	FromTime       time.Time
+	ToTime         time.Time
+}
+
+type GetOrganizationUsageRow struct {
+	Day      time.Time
+	ApiKeyID uuid.NullUUID
+	Metric   string
+	Quantity int64
+}
+
+func (q *Queries) GetOrganizationUsage(ctx context.Context, arg GetOrganizationUsageParams) ([]GetOrganizationUsageRow, error) {
+	rows, err := q.db.Query(ctx, getOrganizationUsage, arg.OrganizationID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrganizationUsageRow
+	for rows.Next() {
+		var i GetOrganizationUsageRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.ApiKeyID,
+			&i.Metric,
+			&i.Quantity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPendingRoomTransfer = `-- name: GetPendingRoomTransfer :one
+SELECT
+    "id", "room_id", "token_hash", "from_owner_token_hash", "to_session_id", "created_at", "expires_at", "accepted_at"
+FROM room_transfers
+WHERE
+    room_id = $1 AND token_hash = $2 AND accepted_at IS NULL AND expires_at > now()
+`
+
+type GetPendingRoomTransferParams struct {
+	RoomID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) GetPendingRoomTransfer(ctx context.Context, arg GetPendingRoomTransferParams) (RoomTransfer, error) {
+	row := q.db.QueryRow(ctx, getPendingRoomTransfer, arg.RoomID, arg.TokenHash)
+	var i RoomTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.TokenHash,
+		&i.FromOwnerTokenHash,
+		&i.ToSessionID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+	)
+	return i, err
+}
+
+const getRelatedRooms = `-- name: GetRelatedRooms :many
+SELECT
+    "id", "code", "theme", "description",
+    similarity("theme" || ' ' || "description", $1::text)::real AS "score"
+FROM rooms
+WHERE
+    id <> $2
+    AND archived_at IS NULL
+    AND organization_id IS NOT DISTINCT FROM $3
+    AND ("theme" || ' ' || "description") % $1::text
+ORDER BY "score" DESC, created_at DESC
+LIMIT $4::int
+`
+
+type GetRelatedRoomsParams struct {
+	Source         string
+	ID             uuid.UUID
+	OrganizationID uuid.NullUUID
+	MaxRooms       int32
+}
+
+type GetRelatedRoomsRow struct {
+	ID          uuid.UUID
+	Code        string
+	Theme       string
+	Description string
+	Score       float32
+}
+
+func (q *Queries) GetRelatedRooms(ctx context.Context, arg GetRelatedRoomsParams) ([]GetRelatedRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getRelatedRooms,
+		arg.Source,
+		arg.ID,
+		arg.OrganizationID,
+		arg.MaxRooms,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRelatedRoomsRow
+	for rows.Next() {
+		var i GetRelatedRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Theme,
+			&i.Description,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoom = `-- name: GetRoom :one
+SELECT
+    "id", "theme", "event_seq", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "code", "archived_at", "organization_id", "created_at", "translate_to", "description", "track_id", "starts_at", "host_name", "closed_at"
+FROM rooms
+WHERE id = $1
+`
+
+func (q *Queries) GetRoom(ctx context.Context, id uuid.UUID) (Room, error) {
+	row := q.db.QueryRow(ctx, getRoom, id)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.EventSeq,
+		&i.OwnerTokenHash,
+		&i.FormSchema,
+		&i.PostingMode,
+		&i.MaxSubscribers,
+		&i.Code,
+		&i.ArchivedAt,
+		&i.OrganizationID,
+		&i.CreatedAt,
+		&i.TranslateTo,
+		&i.Description,
+		&i.TrackID,
+		&i.StartsAt,
+		&i.HostName,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const getRoomActivity = `-- name: GetRoomActivity :many
+WITH activity AS (
+    SELECT date_trunc($1::text, m.created_at) AS "bucket_start", 1 AS "messages", 0 AS "reactions"
+    FROM messages m
+    WHERE m.room_id = $2
+    UNION ALL
+    SELECT date_trunc($1::text, mr.created_at), 0, 1
+    FROM message_reactions mr
+    WHERE mr.room_id = $2
+)
+SELECT
+    "bucket_start"::timestamptz AS "bucket_start",
+    SUM(messages)::bigint AS "messages",
+    SUM(reactions)::bigint AS "reactions"
+FROM activity
+GROUP BY "bucket_start"
+ORDER BY "bucket_start"
+`
+
+type GetRoomActivityParams struct {
+	Unit   string
+	RoomID uuid.UUID
+}
+
+type GetRoomActivityRow struct {
+	BucketStart time.Time
+	Messages    int64
+	Reactions   int64
+}
+
+func (q *Queries) GetRoomActivity(ctx context.Context, arg GetRoomActivityParams) ([]GetRoomActivityRow, error) {
+	rows, err := q.db.Query(ctx, getRoomActivity, arg.Unit, arg.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomActivityRow
+	for rows.Next() {
+		var i GetRoomActivityRow
+		if err := rows.Scan(&i.BucketStart, &i.Messages, &i.Reactions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomByCode = `-- name: GetRoomByCode :one
+SELECT
+    "id", "theme", "event_seq", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "code", "archived_at", "organization_id", "created_at", "translate_to", "description", "track_id", "starts_at", "host_name", "closed_at"
+FROM rooms
+WHERE code = $1
+`
+
+func (q *Queries) GetRoomByCode(ctx context.Context, code string) (Room, error) {
+	row := q.db.QueryRow(ctx, getRoomByCode, code)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.EventSeq,
+		&i.OwnerTokenHash,
+		&i.FormSchema,
+		&i.PostingMode,
+		&i.MaxSubscribers,
+		&i.Code,
+		&i.ArchivedAt,
+		&i.OrganizationID,
+		&i.CreatedAt,
+		&i.TranslateTo,
+		&i.Description,
+		&i.TrackID,
+		&i.StartsAt,
+		&i.HostName,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const getRoomCaptionTokenHash = `-- name: GetRoomCaptionTokenHash :one
+SELECT
+    "token_hash"
+FROM room_caption_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomCaptionTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getRoomCaptionTokenHash, roomID)
+	var token_hash string
+	err := row.Scan(&token_hash)
+	return token_hash, err
+}
+
+const getRoomCaptions = `-- name: GetRoomCaptions :many
+SELECT
+    "id", "room_id", "text", "language", "start_ms", "end_ms", "created_at"
+FROM captions
+WHERE
+    room_id = $1 AND created_at > $2::timestamptz
+ORDER BY created_at ASC, id ASC
+LIMIT $3::int
+`
+
+type GetRoomCaptionsParams struct {
+	RoomID      uuid.UUID
+	Since       time.Time
+	MaxCaptions int32
+}
+
+func (q *Queries) GetRoomCaptions(ctx context.Context, arg GetRoomCaptionsParams) ([]Caption, error) {
+	rows, err := q.db.Query(ctx, getRoomCaptions, arg.RoomID, arg.Since, arg.MaxCaptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Caption
+	for rows.Next() {
+		var i Caption
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Text,
+			&i.Language,
+			&i.StartMs,
+			&i.EndMs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomChatBridge = `-- name: GetRoomChatBridge :one
+SELECT
+    "room_id", "platform", "channel", "prefix", "rate_per_minute", "updated_at"
+FROM room_chat_bridges
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomChatBridge(ctx context.Context, roomID uuid.UUID) (RoomChatBridge, error) {
+	row := q.db.QueryRow(ctx, getRoomChatBridge, roomID)
+	var i RoomChatBridge
+	err := row.Scan(
+		&i.RoomID,
+		&i.Platform,
+		&i.Channel,
+		&i.Prefix,
+		&i.RatePerMinute,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRoomEventSeq = `-- name: GetRoomEventSeq :one
+SELECT
+    "event_seq"
+FROM rooms
+WHERE id = $1
+`
+
+func (q *Queries) GetRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, getRoomEventSeq, id)
+	var event_seq int64
+	err := row.Scan(&event_seq)
+	return event_seq, err
+}
+
+const getRoomEvents = `-- name: GetRoomEvents :many
+SELECT
+    "room_id", "event_id", "channel", "kind", "value", "created_at"
+FROM room_events
+WHERE
+    room_id = $1 AND event_id > $2
+ORDER BY event_id ASC
+LIMIT $3::int
+`
+
+type GetRoomEventsParams struct {
+	RoomID       uuid.UUID
+	AfterEventID int64
+	MaxEvents    int32
+}
+
+func (q *Queries) GetRoomEvents(ctx context.Context, arg GetRoomEventsParams) ([]RoomEvent, error) {
+	rows, err := q.db.Query(ctx, getRoomEvents, arg.RoomID, arg.AfterEventID, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RoomEvent
+	for rows.Next() {
+		var i RoomEvent
+		if err := rows.Scan(
+			&i.RoomID,
+			&i.EventID,
+			&i.Channel,
+			&i.Kind,
+			&i.Value,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomIngestHook = `-- name: GetRoomIngestHook :one
+SELECT
+    "room_id", "mapping", "updated_at"
+FROM room_ingest_hooks
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomIngestHook(ctx context.Context, roomID uuid.UUID) (RoomIngestHook, error) {
+	row := q.db.QueryRow(ctx, getRoomIngestHook, roomID)
+	var i RoomIngestHook
+	err := row.Scan(&i.RoomID, &i.Mapping, &i.UpdatedAt)
+	return i, err
+}
+
+const getRoomLanguageCounts = `-- name: GetRoomLanguageCounts :many
+SELECT
+    "language", COUNT(*) AS "count"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL
+GROUP BY "language"
+ORDER BY "count" DESC, "language"
+`
+
+type GetRoomLanguageCountsRow struct {
+	Language string
+	Count    int64
+}
+
+func (q *Queries) GetRoomLanguageCounts(ctx context.Context, roomID uuid.UUID) ([]GetRoomLanguageCountsRow, error) {
+	rows, err := q.db.Query(ctx, getRoomLanguageCounts, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomLanguageCountsRow
+	for rows.Next() {
+		var i GetRoomLanguageCountsRow
+		if err := rows.Scan(&i.Language, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessage = `-- name: GetRoomMessage :one
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND id = $2 AND deleted_at IS NULL
+`
+
+type GetRoomMessageParams struct {
+	RoomID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) GetRoomMessage(ctx context.Context, arg GetRoomMessageParams) (Message, error) {
+	row := q.db.QueryRow(ctx, getRoomMessage, arg.RoomID, arg.ID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.CreatedAt,
+		&i.Fields,
+		&i.AuthorName,
+		&i.SessionID,
+		&i.DeletedAt,
+		&i.Pinned,
+		&i.Tags,
+		&i.AnswerText,
+		&i.AnswerAudioUrl,
+		&i.Language,
+		&i.Toxicity,
+		&i.HiddenAt,
+		&i.ReviewedAt,
+		&i.AnswerVideoUrl,
+		&i.AnswerVideoOffset,
+		&i.EditedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getRoomMessages = `-- name: GetRoomMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+`
+
+func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomMessages, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.Fields,
+			&i.AuthorName,
+			&i.SessionID,
+			&i.DeletedAt,
+			&i.Pinned,
+			&i.Tags,
+			&i.AnswerText,
+			&i.AnswerAudioUrl,
+			&i.Language,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReviewedAt,
+			&i.AnswerVideoUrl,
+			&i.AnswerVideoOffset,
+			&i.EditedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessagesPage = `-- name: GetRoomMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+    AND ($2::timestamptz IS NULL OR (created_at, id) < ($2::timestamptz, $3::uuid))
+    AND ($4::bool IS NULL OR answered = $4)
+ORDER BY created_at DESC, id DESC
+LIMIT $5::int
+`
+
+type GetRoomMessagesPageParams struct {
+	RoomID          uuid.UUID
+	CursorCreatedAt pgtype.Timestamptz
+	CursorID        uuid.UUID
+	Answered        pgtype.Bool
+	PageSize        int32
+}
+
+func (q *Queries) GetRoomMessagesPage(ctx context.Context, arg GetRoomMessagesPageParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomMessagesPage,
+		arg.RoomID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Answered,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.Fields,
+			&i.AuthorName,
+			&i.SessionID,
+			&i.DeletedAt,
+			&i.Pinned,
+			&i.Tags,
+			&i.AnswerText,
+			&i.AnswerAudioUrl,
+			&i.Language,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReviewedAt,
+			&i.AnswerVideoUrl,
+			&i.AnswerVideoOffset,
+			&i.EditedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomModerationQueue = `-- name: GetRoomModerationQueue :many
+SELECT
+    m."id", m."message", m."author_name", m."created_at", m."toxicity", m."hidden_at",
+    COUNT(mr."id") AS "report_count"
+FROM messages m
+LEFT JOIN message_reports mr ON mr.message_id = m.id AND mr.created_at > COALESCE(m.reviewed_at, '-infinity')
+WHERE
+    m.room_id = $1 AND m.deleted_at IS NULL
+GROUP BY m."id"
+HAVING m.hidden_at IS NOT NULL OR COUNT(mr."id") > 0
+ORDER BY m."toxicity" DESC NULLS LAST, "report_count" DESC, m."created_at" ASC
+LIMIT $2
+`
+
+type GetRoomModerationQueueParams struct {
+	RoomID uuid.UUID
+	Limit  int32
+}
+
+type GetRoomModerationQueueRow struct {
+	ID          uuid.UUID
+	Message     string
+	AuthorName  string
+	CreatedAt   time.Time
+	Toxicity    pgtype.Float4
+	HiddenAt    pgtype.Timestamptz
+	ReportCount int64
+}
+
+func (q *Queries) GetRoomModerationQueue(ctx context.Context, arg GetRoomModerationQueueParams) ([]GetRoomModerationQueueRow, error) {
+	rows, err := q.db.Query(ctx, getRoomModerationQueue, arg.RoomID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomModerationQueueRow
+	for rows.Next() {
+		var i GetRoomModerationQueueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Message,
+			&i.AuthorName,
+			&i.CreatedAt,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReportCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomOverlayTokenHash = `-- name: GetRoomOverlayTokenHash :one
+SELECT
+    "token_hash"
+FROM room_overlay_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomOverlayTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getRoomOverlayTokenHash, roomID)
+	var token_hash string
+	err := row.Scan(&token_hash)
+	return token_hash, err
+}
+
+const getRoomReactionBuckets = `-- name: GetRoomReactionBuckets :many
+SELECT
+    "message_id",
+    "kind",
+    to_timestamp(
+        floor(extract(epoch FROM created_at) / $1::int) * $1::int
+    )::timestamptz AS "bucket_start",
+    COUNT(*) AS "count"
+FROM message_reactions
+WHERE
+    room_id = $2
+GROUP BY "message_id", "kind", "bucket_start"
+ORDER BY "message_id", "bucket_start"
+`
+
+type GetRoomReactionBucketsParams struct {
+	BucketSeconds int32
+	RoomID        uuid.UUID
+}
+
+type GetRoomReactionBucketsRow struct {
+	MessageID   uuid.UUID
+	Kind        string
+	BucketStart time.Time
+	Count       int64
+}
+
+func (q *Queries) GetRoomReactionBuckets(ctx context.Context, arg GetRoomReactionBucketsParams) ([]GetRoomReactionBucketsRow, error) {
+	rows, err := q.db.Query(ctx, getRoomReactionBuckets, arg.BucketSeconds, arg.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomReactionBucketsRow
+	for rows.Next() {
+		var i GetRoomReactionBucketsRow
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.Kind,
+			&i.BucketStart,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomReactionTotals = `-- name: GetRoomReactionTotals :many
+SELECT
+    "kind", COUNT(*) AS "count"
+FROM message_reactions
+WHERE
+    room_id = $1
+GROUP BY "kind"
+ORDER BY "count" DESC
+`
+
+type GetRoomReactionTotalsRow struct {
+	Kind  string
+	Count int64
+}
+
+func (q *Queries) GetRoomReactionTotals(ctx context.Context, roomID uuid.UUID) ([]GetRoomReactionTotalsRow, error) {
+	rows, err := q.db.Query(ctx, getRoomReactionTotals, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomReactionTotalsRow
+	for rows.Next() {
+		var i GetRoomReactionTotalsRow
+		if err := rows.Scan(&i.Kind, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomSessionMessages = `-- name: GetRoomSessionMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND session_id = $2 AND deleted_at IS NULL
+ORDER BY created_at DESC
+`
+
+type GetRoomSessionMessagesParams struct {
+	RoomID    uuid.UUID
+	SessionID uuid.NullUUID
+}
+
+func (q *Queries) GetRoomSessionMessages(ctx context.Context, arg GetRoomSessionMessagesParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomSessionMessages, arg.RoomID, arg.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.Fields,
+			&i.AuthorName,
+			&i.SessionID,
+			&i.DeletedAt,
+			&i.Pinned,
+			&i.Tags,
+			&i.AnswerText,
+			&i.AnswerAudioUrl,
+			&i.Language,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReviewedAt,
+			&i.AnswerVideoUrl,
+			&i.AnswerVideoOffset,
+			&i.EditedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomStats = `-- name: GetRoomStats :one
+SELECT
+    "room_id", "message_count", "unanswered_count", "reaction_total", "last_activity"
+FROM room_stats
+WHERE
+    room_id = $1
+`
+
+func (q *Queries) GetRoomStats(ctx context.Context, roomID uuid.UUID) (RoomStat, error) {
+	row := q.db.QueryRow(ctx, getRoomStats, roomID)
+	var i RoomStat
+	err := row.Scan(
+		&i.RoomID,
+		&i.MessageCount,
+		&i.UnansweredCount,
+		&i.ReactionTotal,
+		&i.LastActivity,
+	)
+	return i, err
+}
+
+const getRoomTopMessages = `-- name: GetRoomTopMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+ORDER BY reaction_count DESC, created_at ASC
+LIMIT $2
+`
+
+type GetRoomTopMessagesParams struct {
+	RoomID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRoomTopMessages(ctx context.Context, arg GetRoomTopMessagesParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomTopMessages, arg.RoomID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.Fields,
+			&i.AuthorName,
+			&i.SessionID,
+			&i.DeletedAt,
+			&i.Pinned,
+			&i.Tags,
+			&i.AnswerText,
+			&i.AnswerAudioUrl,
+			&i.Language,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReviewedAt,
+			&i.AnswerVideoUrl,
+			&i.AnswerVideoOffset,
+			&i.EditedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomTopMessagesPage = `-- name: GetRoomTopMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+    AND ($2::bigint IS NULL OR (reaction_count, created_at, id) < ($2::bigint, $3::timestamptz, $4::uuid))
+    AND ($5::bool IS NULL OR answered = $5)
+ORDER BY reaction_count DESC, created_at DESC, id DESC
+LIMIT $6::int
+`
+
+type GetRoomTopMessagesPageParams struct {
+	RoomID              uuid.UUID
+	CursorReactionCount pgtype.Int8
+	CursorCreatedAt     time.Time
+	CursorID            uuid.UUID
+	Answered            pgtype.Bool
+	PageSize            int32
+}
+
+func (q *Queries) GetRoomTopMessagesPage(ctx context.Context, arg GetRoomTopMessagesPageParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomTopMessagesPage,
+		arg.RoomID,
+		arg.CursorReactionCount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Answered,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.Fields,
+			&i.AuthorName,
+			&i.SessionID,
+			&i.DeletedAt,
+			&i.Pinned,
+			&i.Tags,
+			&i.AnswerText,
+			&i.AnswerAudioUrl,
+			&i.Language,
+			&i.Toxicity,
+			&i.HiddenAt,
+			&i.ReviewedAt,
+			&i.AnswerVideoUrl,
+			&i.AnswerVideoOffset,
+			&i.EditedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomWebhook = `-- name: GetRoomWebhook :one
+SELECT
+    "id", "room_id", "url", "last_event_id", "created_at", "updated_at"
+FROM room_webhooks
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomWebhook(ctx context.Context, roomID uuid.UUID) (RoomWebhook, error) {
+	row := q.db.QueryRow(ctx, getRoomWebhook, roomID)
+	var i RoomWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Url,
+		&i.LastEventID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRoomWebinarTokenHash = `-- name: GetRoomWebinarTokenHash :one
+SELECT
+    "token_hash"
+FROM room_webinar_tokens
+WHERE room_id = $1
+`
+
+func (q *Queries) GetRoomWebinarTokenHash(ctx context.Context, roomID uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getRoomWebinarTokenHash, roomID)
+	var token_hash string
+	err := row.Scan(&token_hash)
+	return token_hash, err
+}
+
+const getRooms = `-- name: GetRooms :many
+SELECT
+    "id", "theme", "event_seq", "archived_at", "closed_at",
+    (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END)::text AS "status",
+    "created_at"
+FROM rooms
+WHERE
+    ($1::bool OR archived_at IS NULL)
+    AND ($2::text = '' OR $2::text = (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END))
+ORDER BY created_at DESC, id DESC
+`
+
+type GetRoomsParams struct {
+	IncludeArchived bool
+	Status          string
+}
+
+type GetRoomsRow struct {
+	ID         uuid.UUID
+	Theme      string
+	EventSeq   int64
+	ArchivedAt pgtype.Timestamptz
+	ClosedAt   pgtype.Timestamptz
+	Status     string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetRooms(ctx context.Context, arg GetRoomsParams) ([]GetRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getRooms, arg.IncludeArchived, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomsRow
+	for rows.Next() {
+		var i GetRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Theme,
+			&i.EventSeq,
+			&i.ArchivedAt,
+			&i.ClosedAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomsPage = `-- name: GetRoomsPage :many
+SELECT
+    "id", "theme", "event_seq", "archived_at", "closed_at",
+    (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END)::text AS "status",
+    "created_at"
+FROM rooms
+WHERE
+    ($1::bool OR archived_at IS NULL)
+    AND ($2::text = '' OR $2::text = (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END))
+    AND ($3::timestamptz IS NULL OR (created_at, id) < ($3::timestamptz, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5::int
+`
+
+type GetRoomsPageParams struct {
+	IncludeArchived bool
+	Status          string
+	CursorCreatedAt pgtype.Timestamptz
+	CursorID        uuid.UUID
+	PageSize        int32
+}
+
+type GetRoomsPageRow struct {
+	ID         uuid.UUID
+	Theme      string
+	EventSeq   int64
+	ArchivedAt pgtype.Timestamptz
+	ClosedAt   pgtype.Timestamptz
+	Status     string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetRoomsPage(ctx context.Context, arg GetRoomsPageParams) ([]GetRoomsPageRow, error) {
+	rows, err := q.db.Query(ctx, getRoomsPage,
+		arg.IncludeArchived,
+		arg.Status,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomsPageRow
+	for rows.Next() {
+		var i GetRoomsPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Theme,
+			&i.EventSeq,
+			&i.ArchivedAt,
+			&i.ClosedAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSavedView = `-- name: GetSavedView :one
+SELECT
+    "id", "room_id", "name", "filter", "created_at", "updated_at"
+FROM saved_views
+WHERE
+    room_id = $1 AND name = $2
+`
+
+type GetSavedViewParams struct {
+	RoomID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetSavedView(ctx context.Context, arg GetSavedViewParams) (SavedView, error) {
+	row := q.db.QueryRow(ctx, getSavedView, arg.RoomID, arg.Name)
+	var i SavedView
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Name,
+		&i.Filter,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSavedViews = `-- name: GetSavedViews :many
+SELECT
+    "id", "room_id", "name", "filter", "created_at", "updated_at"
+FROM saved_views
+WHERE
+    room_id = $1
+ORDER BY name
+`
+
+func (q *Queries) GetSavedViews(ctx context.Context, roomID uuid.UUID) ([]SavedView, error) {
+	rows, err := q.db.Query(ctx, getSavedViews, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SavedView
+	for rows.Next() {
+		var i SavedView
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Name,
+			&i.Filter,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSecret = `-- name: GetSecret :one
+SELECT
+    "id", "organization_id", "name", "key_id", "wrapped_key", "ciphertext", "created_at", "updated_at"
+FROM secrets
+WHERE
+    organization_id IS NOT DISTINCT FROM $1 AND name = $2
+`
+
+type GetSecretParams struct {
+	OrganizationID uuid.NullUUID
+	Name           string
+}
+
+func (q *Queries) GetSecret(ctx context.Context, arg GetSecretParams) (Secret, error) {
+	row := q.db.QueryRow(ctx, getSecret, arg.OrganizationID, arg.Name)
+	var i Secret
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.KeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSecrets = `-- name: GetSecrets :many
+SELECT
+    "id", "organization_id", "name", "key_id", "wrapped_key", "ciphertext", "created_at", "updated_at"
+FROM secrets
+ORDER BY organization_id NULLS FIRST, name
+`
+
+func (q *Queries) GetSecrets(ctx context.Context) ([]Secret, error) {
+	rows, err := q.db.Query(ctx, getSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Secret
+	for rows.Next() {
+		var i Secret
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.Name,
+			&i.KeyID,
+			&i.WrappedKey,
+			&i.Ciphertext,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSession = `-- name: GetSession :one
+SELECT
+    "id", "user_agent", "client_ip", "created_at"
+FROM sessions
+WHERE id = $1
+`
+
+func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	row := q.db.QueryRow(ctx, getSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebinarQuestion = `-- name: GetWebinarQuestion :one
+SELECT
+    "room_id", "external_id", "message_id", "upvotes", "created_at"
+FROM webinar_questions
+WHERE
+    room_id = $1 AND external_id = $2
+FOR UPDATE
+`
+
+type GetWebinarQuestionParams struct {
+	RoomID     uuid.UUID
+	ExternalID string
+}
+
+func (q *Queries) GetWebinarQuestion(ctx context.Context, arg GetWebinarQuestionParams) (WebinarQuestion, error) {
+	row := q.db.QueryRow(ctx, getWebinarQuestion, arg.RoomID, arg.ExternalID)
+	var i WebinarQuestion
+	err := row.Scan(
+		&i.RoomID,
+		&i.ExternalID,
+		&i.MessageID,
+		&i.Upvotes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const incrementRoomEventSeq = `-- name: IncrementRoomEventSeq :one
+UPDATE rooms
+SET
+    event_seq = event_seq + 1
+WHERE
+    id = $1
+RETURNING "event_seq"
+`
+
+func (q *Queries) IncrementRoomEventSeq(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, incrementRoomEventSeq, id)
+	var event_seq int64
+	err := row.Scan(&event_seq)
+	return event_seq, err
+}
+
+const insertAPIKey = `-- name: InsertAPIKey :one
+INSERT INTO api_keys
+    ("organization_id", "name", "key_hash") VALUES
+    ($1, $2, $3)
+RETURNING "id", "organization_id", "name", "key_hash", "created_at", "revoked_at"
+`
+
+type InsertAPIKeyParams struct {
+	OrganizationID uuid.UUID
+	Name           string
+	KeyHash        string
+}
+
+func (q *Queries) InsertAPIKey(ctx context.Context, arg InsertAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, insertAPIKey, arg.OrganizationID, arg.Name, arg.KeyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.KeyHash,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const insertAdminCredential = `-- name: InsertAdminCredential :one
+INSERT INTO admin_credentials
+    ("name", "token_hash") VALUES
+    ($1, $2)
+RETURNING "id", "name", "token_hash", "created_at"
+`
+
+type InsertAdminCredentialParams struct {
+	Name      string
+	TokenHash string
+}
+
+func (q *Queries) InsertAdminCredential(ctx context.Context, arg InsertAdminCredentialParams) (AdminCredential, error) {
+	row := q.db.QueryRow(ctx, insertAdminCredential, arg.Name, arg.TokenHash)
+	var i AdminCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.TokenHash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertCaption = `-- name: InsertCaption :one
+INSERT INTO captions
+    ("room_id", "text", "language", "start_ms", "end_ms") VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING "id", "room_id", "text", "language", "start_ms", "end_ms", "created_at"
+`
+
+type InsertCaptionParams struct {
+	RoomID   uuid.UUID
+	Text     string
+	Language string
+	StartMs  pgtype.Int8
+	EndMs    pgtype.Int8
+}
+
+func (q *Queries) InsertCaption(ctx context.Context, arg InsertCaptionParams) (Caption, error) {
+	row := q.db.QueryRow(ctx, insertCaption,
+		arg.RoomID,
+		arg.Text,
+		arg.Language,
+		arg.StartMs,
+		arg.EndMs,
+	)
+	var i Caption
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Text,
+		&i.Language,
+		&i.StartMs,
+		&i.EndMs,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertEvent = `-- name: InsertEvent :one
+INSERT INTO events
+    ("organization_id", "name", "starts_at", "ends_at") VALUES
+    ($1, $2, $3, $4)
+RETURNING "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults"
+`
+
+type InsertEventParams struct {
+	OrganizationID uuid.UUID
+	Name           string
+	StartsAt       pgtype.Timestamptz
+	EndsAt         pgtype.Timestamptz
+}
+
+func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (Event, error) {
+	row := q.db.QueryRow(ctx, insertEvent,
+		arg.OrganizationID,
+		arg.Name,
+		arg.StartsAt,
+		arg.EndsAt,
+	)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.RoomDefaults,
+	)
+	return i, err
+}
+
+const insertIngestDelivery = `-- name: InsertIngestDelivery :execrows
+INSERT INTO ingest_deliveries
+    ("room_id", "external_id", "message_id") VALUES
+    ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type InsertIngestDeliveryParams struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	MessageID  uuid.UUID
+}
+
+func (q *Queries) InsertIngestDelivery(ctx context.Context, arg InsertIngestDeliveryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertIngestDelivery, arg.RoomID, arg.ExternalID, arg.MessageID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertJob = `-- name: InsertJob :one
+INSERT INTO jobs
+    ("kind", "payload", "organization_id") VALUES
+    ($1, $2, $3)
+RETURNING "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+`
+
+type InsertJobParams struct {
+	Kind           string
+	Payload        []byte
+	OrganizationID uuid.NullUUID
+}
+
+func (q *Queries) InsertJob(ctx context.Context, arg InsertJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, insertJob, arg.Kind, arg.Payload, arg.OrganizationID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.OrganizationID,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.Output,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const insertJobUnlessPending = `-- name: InsertJobUnlessPending :execrows
+INSERT INTO jobs
+    ("kind", "payload")
+SELECT $1::varchar, $2::jsonb
+WHERE NOT EXISTS (
+    SELECT 1 FROM jobs WHERE kind = $1::varchar AND status IN ('queued', 'running')
+)
+`
+
+type InsertJobUnlessPendingParams struct {
+	Kind    string
+	Payload []byte
+}
+
+func (q *Queries) InsertJobUnlessPending(ctx context.Context, arg InsertJobUnlessPendingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertJobUnlessPending, arg.Kind, arg.Payload)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertMessage = `-- name: InsertMessage :one
+INSERT INTO messages
+    ("room_id", "message", "fields", "author_name", "session_id", "language", "toxicity", "hidden_at") VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING "id"
+`
+
+type InsertMessageParams struct {
+	RoomID     uuid.UUID
+	Message    string
+	Fields     []byte
+	AuthorName string
+	SessionID  uuid.NullUUID
+	Language   string
+	Toxicity   pgtype.Float4
+	HiddenAt   pgtype.Timestamptz
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, insertMessage,
+		arg.RoomID,
+		arg.Message,
+		arg.Fields,
+		arg.AuthorName,
+		arg.SessionID,
+		arg.Language,
+		arg.Toxicity,
+		arg.HiddenAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertMessageReport = `-- name: InsertMessageReport :one
+INSERT INTO message_reports
+    ("message_id", "reason") VALUES
+    ($1, $2)
+RETURNING "id", "message_id", "reason", "created_at"
+`
+
+type InsertMessageReportParams struct {
+	MessageID uuid.UUID
+	Reason    string
+}
+
+func (q *Queries) InsertMessageReport(ctx context.Context, arg InsertMessageReportParams) (MessageReport, error) {
+	row := q.db.QueryRow(ctx, insertMessageReport, arg.MessageID, arg.Reason)
+	var i MessageReport
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.Reason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertOrganization = `-- name: InsertOrganization :one
+INSERT INTO organizations
+    ("name") VALUES
+    ($1)
+RETURNING "id", "name", "created_at", "settings", "stripe_customer_id"
+`
+
+func (q *Queries) InsertOrganization(ctx context.Context, name string) (Organization, error) {
+	row := q.db.QueryRow(ctx, insertOrganization, name)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Settings,
+		&i.StripeCustomerID,
+	)
+	return i, err
+}
+
+const insertOutboxEvent = `-- name: InsertOutboxEvent :exec
+INSERT INTO event_outbox
+    ("instance_id", "room_id", "channel", "kind", "value") VALUES
+    ($1, $2, $3, $4, $5)
+`
+
+type InsertOutboxEventParams struct {
+	InstanceID uuid.UUID
+	RoomID     uuid.UUID
+	Channel    string
+	Kind       string
+	Value      []byte
+}
+
+func (q *Queries) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, insertOutboxEvent,
+		arg.InstanceID,
+		arg.RoomID,
+		arg.Channel,
+		arg.Kind,
+		arg.Value,
+	)
+	return err
+}
+
+const insertRoom = `-- name: InsertRoom :one
+INSERT INTO rooms
+    ("theme", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "organization_id", "translate_to", "description", "track_id", "starts_at", "host_name") VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING "id", "code"
+`
+
+type InsertRoomParams struct {
+	Theme          string
+	OwnerTokenHash string
+	FormSchema     []byte
+	PostingMode    string
+	MaxSubscribers int32
+	OrganizationID uuid.NullUUID
+	TranslateTo    []string
+	Description    string
+	TrackID        uuid.NullUUID
+	StartsAt       pgtype.Timestamptz
+	HostName       string
+}
+
+type InsertRoomRow struct {
+	ID   uuid.UUID
+	Code string
+}
+
+func (q *Queries) InsertRoom(ctx context.Context, arg InsertRoomParams) (InsertRoomRow, error) {
+	row := q.db.QueryRow(ctx, insertRoom,
+		arg.Theme,
+		arg.OwnerTokenHash,
+		arg.FormSchema,
+		arg.PostingMode,
+		arg.MaxSubscribers,
+		arg.OrganizationID,
+		arg.TranslateTo,
+		arg.Description,
+		arg.TrackID,
+		arg.StartsAt,
+		arg.HostName,
+	)
+	var i InsertRoomRow
+	err := row.Scan(&i.ID, &i.Code)
+	return i, err
+}
+
+const insertRoomEvent = `-- name: InsertRoomEvent :exec
+INSERT INTO room_events
+    ("room_id", "event_id", "channel", "kind", "value") VALUES
+    ($1, $2, $3, $4, $5)
+`
+
+type InsertRoomEventParams struct {
+	RoomID  uuid.UUID
+	EventID int64
+	Channel string
+	Kind    string
+	Value   []byte
+}
+
+func (q *Queries) InsertRoomEvent(ctx context.Context, arg InsertRoomEventParams) error {
+	_, err := q.db.Exec(ctx, insertRoomEvent,
+		arg.RoomID,
+		arg.EventID,
+		arg.Channel,
+		arg.Kind,
+		arg.Value,
+	)
+	return err
+}
+
+const insertRoomTransfer = `-- name: InsertRoomTransfer :one
+INSERT INTO room_transfers
+    ("room_id", "token_hash", "from_owner_token_hash", "to_session_id", "expires_at") VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING "id", "room_id", "token_hash", "from_owner_token_hash", "to_session_id", "created_at", "expires_at", "accepted_at"
+`
+
+type InsertRoomTransferParams struct {
+	RoomID             uuid.UUID
+	TokenHash          string
+	FromOwnerTokenHash string
+	ToSessionID        uuid.NullUUID
+	ExpiresAt          time.Time
+}
+
+func (q *Queries) InsertRoomTransfer(ctx context.Context, arg InsertRoomTransferParams) (RoomTransfer, error) {
+	row := q.db.QueryRow(ctx, insertRoomTransfer,
+		arg.RoomID,
+		arg.TokenHash,
+		arg.FromOwnerTokenHash,
+		arg.ToSessionID,
+		arg.ExpiresAt,
+	)
+	var i RoomTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.TokenHash,
+		&i.FromOwnerTokenHash,
+		&i.ToSessionID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+	)
+	return i, err
+}
+
+const insertSession = `-- name: InsertSession :one
+INSERT INTO sessions
+    ("user_agent", "client_ip") VALUES
+    ($1, $2)
+RETURNING "id", "user_agent", "client_ip", "created_at"
+`
+
+type InsertSessionParams struct {
+	UserAgent string
+	ClientIp  string
+}
+
+func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, insertSession, arg.UserAgent, arg.ClientIp)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertTrack = `-- name: InsertTrack :one
+INSERT INTO tracks
+    ("event_id", "name", "position") VALUES
+    ($1, $2, $3)
+RETURNING "id", "event_id", "name", "position", "created_at"
+`
+
+type InsertTrackParams struct {
+	EventID  uuid.UUID
+	Name     string
+	Position int32
+}
+
+func (q *Queries) InsertTrack(ctx context.Context, arg InsertTrackParams) (Track, error) {
+	row := q.db.QueryRow(ctx, insertTrack, arg.EventID, arg.Name, arg.Position)
+	var i Track
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertWebinarQuestion = `-- name: InsertWebinarQuestion :exec
+INSERT INTO webinar_questions
+    ("room_id", "external_id", "message_id") VALUES
+    ($1, $2, $3)
+`
+
+type InsertWebinarQuestionParams struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	MessageID  uuid.UUID
+}
+
+func (q *Queries) InsertWebinarQuestion(ctx context.Context, arg InsertWebinarQuestionParams) error {
+	_, err := q.db.Exec(ctx, insertWebinarQuestion, arg.RoomID, arg.ExternalID, arg.MessageID)
+	return err
+}
+
+const markChatBridgeMessage = `-- name: MarkChatBridgeMessage :execrows
+INSERT INTO chat_bridge_messages
+    ("room_id", "external_id") VALUES
+    ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type MarkChatBridgeMessageParams struct {
+	RoomID     uuid.UUID
+	ExternalID string
+}
+
+func (q *Queries) MarkChatBridgeMessage(ctx context.Context, arg MarkChatBridgeMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markChatBridgeMessage, arg.RoomID, arg.ExternalID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const markMessageAsAnswered = `-- name: MarkMessageAsAnswered :one
+UPDATE messages
+SET
+    answered = true,
+    answer_text = $1,
+    answer_audio_url = '',
+    answer_video_url = $2,
+    answer_video_offset = $3,
+    version = version + 1
+WHERE
+    id = $4 AND ($5::int IS NULL OR version = $5::int)
+RETURNING "version"
+`
+
+type MarkMessageAsAnsweredParams struct {
+	AnswerText        string
+	AnswerVideoUrl    string
+	AnswerVideoOffset pgtype.Int4
+	ID                uuid.UUID
+	Version           pgtype.Int4
+}
+
+func (q *Queries) MarkMessageAsAnswered(ctx context.Context, arg MarkMessageAsAnsweredParams) (int32, error) {
+	row := q.db.QueryRow(ctx, markMessageAsAnswered,
+		arg.AnswerText,
+		arg.AnswerVideoUrl,
+		arg.AnswerVideoOffset,
+		arg.ID,
+		arg.Version,
+	)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const markMessageAsUnanswered = `-- name: MarkMessageAsUnanswered :one
+UPDATE messages
+SET
+    answered = false,
+    answer_text = '',
+    answer_audio_url = '',
+    answer_video_url = '',
+    answer_video_offset = NULL,
+    version = version + 1
+WHERE
+    id = $1 AND ($2::int IS NULL OR version = $2::int)
+RETURNING "version"
+`
+
+type MarkMessageAsUnansweredParams struct {
+	ID      uuid.UUID
+	Version pgtype.Int4
+}
+
+func (q *Queries) MarkMessageAsUnanswered(ctx context.Context, arg MarkMessageAsUnansweredParams) (int32, error) {
+	row := q.db.QueryRow(ctx, markMessageAsUnanswered, arg.ID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const pinMessage = `-- name: PinMessage :one
+UPDATE messages
+SET
+    pinned = true,
+    version = version + 1
+WHERE
+    id = $1 AND ($2::int IS NULL OR version = $2::int)
+RETURNING "version"
+`
+
+type PinMessageParams struct {
+	ID      uuid.UUID
+	Version pgtype.Int4
+}
+
+func (q *Queries) PinMessage(ctx context.Context, arg PinMessageParams) (int32, error) {
+	row := q.db.QueryRow(ctx, pinMessage, arg.ID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const reactToMessage = `-- name: ReactToMessage :one
+WITH reaction AS (
+    INSERT INTO message_reactions
+        ("message_id", "room_id", "kind", "session_id")
+    SELECT m."id", m."room_id", $2::varchar, $3::uuid FROM messages m WHERE m.id = $1
+    ON CONFLICT ("message_id", "session_id") DO NOTHING
+    RETURNING "id"
+)
+UPDATE messages
+SET
+    reaction_count = reaction_count + (SELECT COUNT(*) FROM reaction)
+WHERE
+    messages.id = $1
+RETURNING "reaction_count", (SELECT COUNT(*) FROM reaction) AS "reacted"
 `
 
-func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
-	row := q.db.QueryRow(ctx, getMessage, id)
-	var i Message
-	err := row.Scan(
-		&i.ID,
-		&i.RoomID,
-		&i.Message,
-		&i.ReactionCount,
-		&i.Answered,
-	)
+type ReactToMessageParams struct {
+	ID        uuid.UUID
+	Kind      string
+	SessionID uuid.UUID
+}
+
+type ReactToMessageRow struct {
+	ReactionCount int64
+	Reacted       int64
+}
+
+func (q *Queries) ReactToMessage(ctx context.Context, arg ReactToMessageParams) (ReactToMessageRow, error) {
+	row := q.db.QueryRow(ctx, reactToMessage, arg.ID, arg.Kind, arg.SessionID)
+	var i ReactToMessageRow
+	err := row.Scan(&i.ReactionCount, &i.Reacted)
 	return i, err
 }
 
-const getRoom = `-- name: GetRoom :one
-SELECT
-    "id", "theme"
-FROM rooms
-WHERE id = $1
+const recordRoomPeak = `-- name: RecordRoomPeak :exec
+INSERT INTO room_peaks
+    ("room_id", "peak_subscribers") VALUES
+    ($1, $2)
+ON CONFLICT (room_id) DO UPDATE
+SET
+    peak_subscribers = GREATEST(room_peaks.peak_subscribers, EXCLUDED.peak_subscribers),
+    updated_at = now()
+WHERE
+    room_peaks.peak_subscribers < EXCLUDED.peak_subscribers
 `
 
-func (q *Queries) GetRoom(ctx context.Context, id uuid.UUID) (Room, error) {
-	row := q.db.QueryRow(ctx, getRoom, id)
-	var i Room
-	err := row.Scan(&i.ID, &i.Theme)
+type RecordRoomPeakParams struct {
+	RoomID          uuid.UUID
+	PeakSubscribers int32
+}
+
+func (q *Queries) RecordRoomPeak(ctx context.Context, arg RecordRoomPeakParams) error {
+	_, err := q.db.Exec(ctx, recordRoomPeak, arg.RoomID, arg.PeakSubscribers)
+	return err
+}
+
+const redriveWebhookDeliveries = `-- name: RedriveWebhookDeliveries :one
+WITH redriven AS (
+    UPDATE webhook_deliveries d
+    SET
+        status = 'pending',
+        attempts = 0,
+        next_attempt_at = now()
+    FROM room_webhooks w
+    WHERE
+        w.id = d.webhook_id
+        AND d.status = 'failed'
+        AND ($1::uuid IS NULL OR w.room_id = $1)
+    RETURNING d."webhook_id", d."event_id"
+), dropped AS (
+    DELETE FROM dead_letters dl
+    USING redriven r
+    WHERE dl.source = 'webhook' AND dl.ref = r."webhook_id"::text || ':' || r."event_id"::text
+)
+SELECT COUNT(*) FROM redriven
+`
+
+func (q *Queries) RedriveWebhookDeliveries(ctx context.Context, roomID uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRow(ctx, redriveWebhookDeliveries, roomID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const redriveWebhookDelivery = `-- name: RedriveWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET
+    status = 'pending',
+    attempts = 0,
+    next_attempt_at = now()
+WHERE webhook_id = $1 AND event_id = $2 AND status = 'failed'
+`
+
+type RedriveWebhookDeliveryParams struct {
+	WebhookID uuid.UUID
+	EventID   int64
+}
+
+func (q *Queries) RedriveWebhookDelivery(ctx context.Context, arg RedriveWebhookDeliveryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, redriveWebhookDelivery, arg.WebhookID, arg.EventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeReactionFromMessage = `-- name: RemoveReactionFromMessage :one
+WITH reaction AS (
+    DELETE FROM message_reactions
+    WHERE
+        message_id = $1 AND session_id = $2::uuid AND kind = $3::varchar
+    RETURNING "id"
+)
+UPDATE messages
+SET
+    reaction_count = reaction_count - (SELECT COUNT(*) FROM reaction)
+WHERE
+    messages.id = $1
+RETURNING "reaction_count", (SELECT COUNT(*) FROM reaction) AS "removed"
+`
+
+type RemoveReactionFromMessageParams struct {
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	Kind      string
+}
+
+type RemoveReactionFromMessageRow struct {
+	ReactionCount int64
+	Removed       int64
+}
+
+func (q *Queries) RemoveReactionFromMessage(ctx context.Context, arg RemoveReactionFromMessageParams) (RemoveReactionFromMessageRow, error) {
+	row := q.db.QueryRow(ctx, removeReactionFromMessage, arg.ID, arg.SessionID, arg.Kind)
+	var i RemoveReactionFromMessageRow
+	err := row.Scan(&i.ReactionCount, &i.Removed)
 	return i, err
 }
 
-const getRoomMessages = `-- name: GetRoomMessages :many
+const reopenRoom = `-- name: ReopenRoom :execrows
+UPDATE rooms
+SET
+    closed_at = NULL
+WHERE
+    id = $1 AND closed_at IS NOT NULL
+`
+
+func (q *Queries) ReopenRoom(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, reopenRoom, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retryJob = `-- name: RetryJob :execrows
+UPDATE jobs
+SET
+    status = 'queued',
+    attempts = 0,
+    error = '',
+    started_at = NULL,
+    finished_at = NULL
+WHERE
+    id = $1 AND status = 'failed'
+`
+
+func (q *Queries) RetryJob(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, retryJob, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rewrapSecret = `-- name: RewrapSecret :execrows
+UPDATE secrets
+SET
+    key_id = $1,
+    wrapped_key = $2
+WHERE
+    id = $3 AND key_id = $4
+`
+
+type RewrapSecretParams struct {
+	KeyID         string
+	WrappedKey    []byte
+	ID            uuid.UUID
+	PreviousKeyID string
+}
+
+func (q *Queries) RewrapSecret(ctx context.Context, arg RewrapSecretParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rewrapSecret,
+		arg.KeyID,
+		arg.WrappedKey,
+		arg.ID,
+		arg.PreviousKeyID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const searchRoomMessages = `-- name: SearchRoomMessages :many
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
-FROM messages
+    m."id", m."message", m."author_name", m."reaction_count", m."answered", m."created_at",
+    ts_rank_cd(ms."document", query)::real AS "rank"
+FROM message_search ms
+JOIN messages m ON m.id = ms.message_id,
+    websearch_to_tsquery('simple', $1::text) query
 WHERE
-    room_id = $1
+    ms.room_id = $2 AND ms."document" @@ query
+    AND m.deleted_at IS NULL AND m.hidden_at IS NULL
+ORDER BY "rank" DESC, m."created_at" DESC
+LIMIT $3::int
 `
 
-func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error) {
-	rows, err := q.db.Query(ctx, getRoomMessages, roomID)
+type SearchRoomMessagesParams struct {
+	Query       string
+	RoomID      uuid.UUID
+	MaxMessages int32
+}
+
+type SearchRoomMessagesRow struct {
+	ID            uuid.UUID
+	Message       string
+	AuthorName    string
+	ReactionCount int64
+	Answered      bool
+	CreatedAt     time.Time
+	Rank          float32
+}
+
+func (q *Queries) SearchRoomMessages(ctx context.Context, arg SearchRoomMessagesParams) ([]SearchRoomMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchRoomMessages, arg.Query, arg.RoomID, arg.MaxMessages)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Message
+	var items []SearchRoomMessagesRow
 	for rows.Next() {
-		var i Message
+		var i SearchRoomMessagesRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.RoomID,
 			&i.Message,
+			&i.AuthorName,
 			&i.ReactionCount,
 			&i.Answered,
+			&i.CreatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -80,106 +3662,384 @@ func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Mess
 	return items, nil
 }
 
-const getRooms = `-- name: GetRooms :many
-SELECT
-    "id", "theme"
-FROM rooms
+const setEventRoomDefaults = `-- name: SetEventRoomDefaults :exec
+UPDATE events
+SET
+    room_defaults = $1
+WHERE
+    id = $2
+`
+
+type SetEventRoomDefaultsParams struct {
+	RoomDefaults []byte
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetEventRoomDefaults(ctx context.Context, arg SetEventRoomDefaultsParams) error {
+	_, err := q.db.Exec(ctx, setEventRoomDefaults, arg.RoomDefaults, arg.ID)
+	return err
+}
+
+const setMessageAnswerAudio = `-- name: SetMessageAnswerAudio :execrows
+UPDATE messages
+SET
+    answer_audio_url = $1
+WHERE
+    id = $2 AND room_id = $3 AND answered AND answer_text = $4
 `
 
-func (q *Queries) GetRooms(ctx context.Context) ([]Room, error) {
-	rows, err := q.db.Query(ctx, getRooms)
+type SetMessageAnswerAudioParams struct {
+	AnswerAudioUrl string
+	ID             uuid.UUID
+	RoomID         uuid.UUID
+	AnswerText     string
+}
+
+func (q *Queries) SetMessageAnswerAudio(ctx context.Context, arg SetMessageAnswerAudioParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setMessageAnswerAudio,
+		arg.AnswerAudioUrl,
+		arg.ID,
+		arg.RoomID,
+		arg.AnswerText,
+	)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	var items []Room
-	for rows.Next() {
-		var i Room
-		if err := rows.Scan(&i.ID, &i.Theme); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const insertMessage = `-- name: InsertMessage :one
-INSERT INTO messages
-    ("room_id", "message") VALUES
-    ($1, $2)
-RETURNING "id"
+const setRoomTrack = `-- name: SetRoomTrack :execrows
+UPDATE rooms
+SET
+    track_id = $1
+WHERE
+    id = $2 AND organization_id = $3
 `
 
-type InsertMessageParams struct {
-	RoomID  uuid.UUID
-	Message string
+type SetRoomTrackParams struct {
+	TrackID        uuid.NullUUID
+	ID             uuid.UUID
+	OrganizationID uuid.NullUUID
 }
 
-func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, insertMessage, arg.RoomID, arg.Message)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) SetRoomTrack(ctx context.Context, arg SetRoomTrackParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setRoomTrack, arg.TrackID, arg.ID, arg.OrganizationID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const insertRoom = `-- name: InsertRoom :one
-INSERT INTO rooms
-    ("theme") VALUES
-    ($1) 
-RETURNING "id"
+const setWebinarQuestionUpvotes = `-- name: SetWebinarQuestionUpvotes :exec
+UPDATE webinar_questions
+SET
+    upvotes = $3
+WHERE
+    room_id = $1 AND external_id = $2
 `
 
-func (q *Queries) InsertRoom(ctx context.Context, theme string) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, insertRoom, theme)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+type SetWebinarQuestionUpvotesParams struct {
+	RoomID     uuid.UUID
+	ExternalID string
+	Upvotes    int32
 }
 
-const markMessageAsAnswered = `-- name: MarkMessageAsAnswered :exec
+func (q *Queries) SetWebinarQuestionUpvotes(ctx context.Context, arg SetWebinarQuestionUpvotesParams) error {
+	_, err := q.db.Exec(ctx, setWebinarQuestionUpvotes, arg.RoomID, arg.ExternalID, arg.Upvotes)
+	return err
+}
+
+const softDeleteMessage = `-- name: SoftDeleteMessage :exec
 UPDATE messages
 SET
-    answered = true
+    deleted_at = now(),
+    version = version + 1
 WHERE
-    id = $1
+    id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) MarkMessageAsAnswered(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, markMessageAsAnswered, id)
+func (q *Queries) SoftDeleteMessage(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, softDeleteMessage, id)
 	return err
 }
 
-const reactToMessage = `-- name: ReactToMessage :one
-UPDATE messages
+const unarchiveRoom = `-- name: UnarchiveRoom :exec
+UPDATE rooms
 SET
-    reaction_count = reaction_count + 1
+    archived_at = NULL
 WHERE
     id = $1
-RETURNING "reaction_count"
 `
 
-func (q *Queries) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, reactToMessage, id)
-	var reaction_count int64
-	err := row.Scan(&reaction_count)
-	return reaction_count, err
+func (q *Queries) UnarchiveRoom(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, unarchiveRoom, id)
+	return err
 }
 
-const removeReactionFromMessage = `-- name: RemoveReactionFromMessage :one
-UPDATE messages
+const updateOrganizationBilling = `-- name: UpdateOrganizationBilling :exec
+UPDATE organizations
 SET
-    reaction_count = reaction_count - 1
+    stripe_customer_id = $2,
+    settings = $3
 WHERE
     id = $1
-RETURNING "reaction_count"
 `
 
-func (q *Queries) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, removeReactionFromMessage, id)
-	var reaction_count int64
-	err := row.Scan(&reaction_count)
-	return reaction_count, err
+type UpdateOrganizationBillingParams struct {
+	ID               uuid.UUID
+	StripeCustomerID pgtype.Text
+	Settings         []byte
+}
+
+func (q *Queries) UpdateOrganizationBilling(ctx context.Context, arg UpdateOrganizationBillingParams) error {
+	_, err := q.db.Exec(ctx, updateOrganizationBilling, arg.ID, arg.StripeCustomerID, arg.Settings)
+	return err
+}
+
+const upsertMessageTranslation = `-- name: UpsertMessageTranslation :exec
+INSERT INTO message_translations
+    ("message_id", "language", "source_hash", "text") VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT ("message_id", "language") DO UPDATE
+SET
+    source_hash = EXCLUDED.source_hash,
+    text = EXCLUDED.text,
+    created_at = now()
+`
+
+type UpsertMessageTranslationParams struct {
+	MessageID  uuid.UUID
+	Language   string
+	SourceHash string
+	Text       string
+}
+
+func (q *Queries) UpsertMessageTranslation(ctx context.Context, arg UpsertMessageTranslationParams) error {
+	_, err := q.db.Exec(ctx, upsertMessageTranslation,
+		arg.MessageID,
+		arg.Language,
+		arg.SourceHash,
+		arg.Text,
+	)
+	return err
+}
+
+const upsertRoomCaptionToken = `-- name: UpsertRoomCaptionToken :exec
+INSERT INTO room_caption_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now()
+`
+
+type UpsertRoomCaptionTokenParams struct {
+	RoomID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) UpsertRoomCaptionToken(ctx context.Context, arg UpsertRoomCaptionTokenParams) error {
+	_, err := q.db.Exec(ctx, upsertRoomCaptionToken, arg.RoomID, arg.TokenHash)
+	return err
+}
+
+const upsertRoomChatBridge = `-- name: UpsertRoomChatBridge :one
+INSERT INTO room_chat_bridges
+    ("room_id", "platform", "channel", "prefix", "rate_per_minute") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    platform = EXCLUDED.platform,
+    channel = EXCLUDED.channel,
+    prefix = EXCLUDED.prefix,
+    rate_per_minute = EXCLUDED.rate_per_minute,
+    updated_at = now()
+RETURNING "room_id", "platform", "channel", "prefix", "rate_per_minute", "updated_at"
+`
+
+type UpsertRoomChatBridgeParams struct {
+	RoomID        uuid.UUID
+	Platform      string
+	Channel       string
+	Prefix        string
+	RatePerMinute int32
+}
+
+func (q *Queries) UpsertRoomChatBridge(ctx context.Context, arg UpsertRoomChatBridgeParams) (RoomChatBridge, error) {
+	row := q.db.QueryRow(ctx, upsertRoomChatBridge,
+		arg.RoomID,
+		arg.Platform,
+		arg.Channel,
+		arg.Prefix,
+		arg.RatePerMinute,
+	)
+	var i RoomChatBridge
+	err := row.Scan(
+		&i.RoomID,
+		&i.Platform,
+		&i.Channel,
+		&i.Prefix,
+		&i.RatePerMinute,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertRoomIngestHook = `-- name: UpsertRoomIngestHook :one
+INSERT INTO room_ingest_hooks
+    ("room_id", "mapping") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    mapping = EXCLUDED.mapping,
+    updated_at = now()
+RETURNING "room_id", "mapping", "updated_at"
+`
+
+type UpsertRoomIngestHookParams struct {
+	RoomID  uuid.UUID
+	Mapping []byte
+}
+
+func (q *Queries) UpsertRoomIngestHook(ctx context.Context, arg UpsertRoomIngestHookParams) (RoomIngestHook, error) {
+	row := q.db.QueryRow(ctx, upsertRoomIngestHook, arg.RoomID, arg.Mapping)
+	var i RoomIngestHook
+	err := row.Scan(&i.RoomID, &i.Mapping, &i.UpdatedAt)
+	return i, err
+}
+
+const upsertRoomOverlayToken = `-- name: UpsertRoomOverlayToken :exec
+INSERT INTO room_overlay_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now()
+`
+
+type UpsertRoomOverlayTokenParams struct {
+	RoomID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) UpsertRoomOverlayToken(ctx context.Context, arg UpsertRoomOverlayTokenParams) error {
+	_, err := q.db.Exec(ctx, upsertRoomOverlayToken, arg.RoomID, arg.TokenHash)
+	return err
+}
+
+const upsertRoomWebhook = `-- name: UpsertRoomWebhook :one
+INSERT INTO room_webhooks
+    ("room_id", "url", "last_event_id")
+SELECT
+    r.id, $1, r.event_seq
+FROM rooms r
+WHERE r.id = $2
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    url = EXCLUDED.url,
+    updated_at = now()
+RETURNING "id", "room_id", "url", "last_event_id", "created_at", "updated_at"
+`
+
+type UpsertRoomWebhookParams struct {
+	Url    string
+	RoomID uuid.UUID
+}
+
+func (q *Queries) UpsertRoomWebhook(ctx context.Context, arg UpsertRoomWebhookParams) (RoomWebhook, error) {
+	row := q.db.QueryRow(ctx, upsertRoomWebhook, arg.Url, arg.RoomID)
+	var i RoomWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Url,
+		&i.LastEventID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertRoomWebinarToken = `-- name: UpsertRoomWebinarToken :exec
+INSERT INTO room_webinar_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now()
+`
+
+type UpsertRoomWebinarTokenParams struct {
+	RoomID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) UpsertRoomWebinarToken(ctx context.Context, arg UpsertRoomWebinarTokenParams) error {
+	_, err := q.db.Exec(ctx, upsertRoomWebinarToken, arg.RoomID, arg.TokenHash)
+	return err
+}
+
+const upsertSavedView = `-- name: UpsertSavedView :one
+INSERT INTO saved_views
+    ("room_id", "name", "filter") VALUES
+    ($1, $2, $3)
+ON CONFLICT ("room_id", "name") DO UPDATE
+SET
+    filter = EXCLUDED.filter,
+    updated_at = now()
+RETURNING "id", "room_id", "name", "filter", "created_at", "updated_at"
+`
+
+type UpsertSavedViewParams struct {
+	RoomID uuid.UUID
+	Name   string
+	Filter []byte
+}
+
+func (q *Queries) UpsertSavedView(ctx context.Context, arg UpsertSavedViewParams) (SavedView, error) {
+	row := q.db.QueryRow(ctx, upsertSavedView, arg.RoomID, arg.Name, arg.Filter)
+	var i SavedView
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Name,
+		&i.Filter,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertSecret = `-- name: UpsertSecret :exec
+INSERT INTO secrets
+    ("organization_id", "name", "key_id", "wrapped_key", "ciphertext") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("organization_id", "name") DO UPDATE
+SET
+    key_id = EXCLUDED.key_id,
+    wrapped_key = EXCLUDED.wrapped_key,
+    ciphertext = EXCLUDED.ciphertext,
+    updated_at = now()
+`
+
+type UpsertSecretParams struct {
+	OrganizationID uuid.NullUUID
+	Name           string
+	KeyID          string
+	WrappedKey     []byte
+	Ciphertext     []byte
+}
+
+func (q *Queries) UpsertSecret(ctx context.Context, arg UpsertSecretParams) error {
+	_, err := q.db.Exec(ctx, upsertSecret,
+		arg.OrganizationID,
+		arg.Name,
+		arg.KeyID,
+		arg.WrappedKey,
+		arg.Ciphertext,
+	)
+	return err
 }
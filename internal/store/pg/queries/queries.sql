@@ -1,59 +1,1196 @@
 -- name: GetRoom :one
 SELECT
-    "id", "theme"
+    "id", "theme", "event_seq", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "code", "archived_at", "organization_id", "created_at", "translate_to", "description", "track_id", "starts_at", "host_name", "closed_at"
 FROM rooms
 WHERE id = $1;
 
+-- name: GetRoomByCode :one
+SELECT
+    "id", "theme", "event_seq", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "code", "archived_at", "organization_id", "created_at", "translate_to", "description", "track_id", "starts_at", "host_name", "closed_at"
+FROM rooms
+WHERE code = $1;
+
 -- name: GetRooms :many
 SELECT
-    "id", "theme"
-FROM rooms;
+    "id", "theme", "event_seq", "archived_at", "closed_at",
+    (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END)::text AS "status",
+    "created_at"
+FROM rooms
+WHERE
+    (@include_archived::bool OR archived_at IS NULL)
+    AND (@status::text = '' OR @status::text = (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END))
+ORDER BY created_at DESC, id DESC;
+
+-- name: ArchiveRoom :exec
+UPDATE rooms
+SET
+    archived_at = now()
+WHERE
+    id = $1 AND archived_at IS NULL;
+
+-- name: UnarchiveRoom :exec
+UPDATE rooms
+SET
+    archived_at = NULL
+WHERE
+    id = $1;
+
+-- name: CloseRoom :execrows
+UPDATE rooms
+SET
+    closed_at = now()
+WHERE
+    id = $1 AND closed_at IS NULL;
+
+-- name: ReopenRoom :execrows
+UPDATE rooms
+SET
+    closed_at = NULL
+WHERE
+    id = $1 AND closed_at IS NOT NULL;
+
+-- name: GetRoomEventSeq :one
+SELECT
+    "event_seq"
+FROM rooms
+WHERE id = $1;
+
+-- name: IncrementRoomEventSeq :one
+UPDATE rooms
+SET
+    event_seq = event_seq + 1
+WHERE
+    id = $1
+RETURNING "event_seq";
 
 -- name: InsertRoom :one
 INSERT INTO rooms
-    ("theme") VALUES
-    ($1) 
-RETURNING "id";
+    ("theme", "owner_token_hash", "form_schema", "posting_mode", "max_subscribers", "organization_id", "translate_to", "description", "track_id", "starts_at", "host_name") VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING "id", "code";
 
--- name: GetMessage :one
+-- name: GetRoomMessage :one
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
 FROM messages
 WHERE
-    id = $1;
+    room_id = $1 AND id = $2 AND deleted_at IS NULL;
 
 -- name: GetRoomMessages :many
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
 FROM messages
 WHERE
-    room_id = $1;
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL;
 
 -- name: InsertMessage :one
 INSERT INTO messages
-    ("room_id", "message") VALUES
-    ($1, $2)
+    ("room_id", "message", "fields", "author_name", "session_id", "language", "toxicity", "hidden_at") VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING "id";
 
 -- name: ReactToMessage :one
+WITH reaction AS (
+    INSERT INTO message_reactions
+        ("message_id", "room_id", "kind", "session_id")
+    SELECT m."id", m."room_id", @kind::varchar, @session_id::uuid FROM messages m WHERE m.id = @id
+    ON CONFLICT ("message_id", "session_id") DO NOTHING
+    RETURNING "id"
+)
 UPDATE messages
 SET
-    reaction_count = reaction_count + 1
+    reaction_count = reaction_count + (SELECT COUNT(*) FROM reaction)
 WHERE
-    id = $1
-RETURNING "reaction_count";
+    messages.id = @id
+RETURNING "reaction_count", (SELECT COUNT(*) FROM reaction) AS "reacted";
 
 -- name: RemoveReactionFromMessage :one
+WITH reaction AS (
+    DELETE FROM message_reactions
+    WHERE
+        message_id = @id AND session_id = @session_id::uuid AND kind = @kind::varchar
+    RETURNING "id"
+)
 UPDATE messages
 SET
-    reaction_count = reaction_count - 1
+    reaction_count = reaction_count - (SELECT COUNT(*) FROM reaction)
 WHERE
-    id = $1
-RETURNING "reaction_count";
+    messages.id = @id
+RETURNING "reaction_count", (SELECT COUNT(*) FROM reaction) AS "removed";
+
+-- name: MarkMessageAsAnswered :one
+UPDATE messages
+SET
+    answered = true,
+    answer_text = @answer_text,
+    answer_audio_url = '',
+    answer_video_url = @answer_video_url,
+    answer_video_offset = @answer_video_offset,
+    version = version + 1
+WHERE
+    id = @id AND (sqlc.narg(version)::int IS NULL OR version = sqlc.narg(version)::int)
+RETURNING "version";
+
+-- name: MarkMessageAsUnanswered :one
+UPDATE messages
+SET
+    answered = false,
+    answer_text = '',
+    answer_audio_url = '',
+    answer_video_url = '',
+    answer_video_offset = NULL,
+    version = version + 1
+WHERE
+    id = @id AND (sqlc.narg(version)::int IS NULL OR version = sqlc.narg(version)::int)
+RETURNING "version";
+
+-- name: InsertMessageReport :one
+INSERT INTO message_reports
+    ("message_id", "reason") VALUES
+    ($1, $2)
+RETURNING "id", "message_id", "reason", "created_at";
+
+-- name: GetRoomReactionTotals :many
+SELECT
+    "kind", COUNT(*) AS "count"
+FROM message_reactions
+WHERE
+    room_id = $1
+GROUP BY "kind"
+ORDER BY "count" DESC;
+
+-- name: GetRoomReactionBuckets :many
+SELECT
+    "message_id",
+    "kind",
+    to_timestamp(
+        floor(extract(epoch FROM created_at) / @bucket_seconds::int) * @bucket_seconds::int
+    )::timestamptz AS "bucket_start",
+    COUNT(*) AS "count"
+FROM message_reactions
+WHERE
+    room_id = @room_id
+GROUP BY "message_id", "kind", "bucket_start"
+ORDER BY "message_id", "bucket_start";
+
+-- name: InsertSession :one
+INSERT INTO sessions
+    ("user_agent", "client_ip") VALUES
+    ($1, $2)
+RETURNING "id", "user_agent", "client_ip", "created_at";
+
+-- name: GetSession :one
+SELECT
+    "id", "user_agent", "client_ip", "created_at"
+FROM sessions
+WHERE id = $1;
+
+-- name: GetRoomSessionMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND session_id = $2 AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: EditMessage :one
+-- The row is locked before the previous text is kept, so it's only kept
+-- when the edit applies to the current version.
+WITH previous AS (
+    SELECT m."id", m."message" FROM messages m WHERE m.id = @id AND m.version = @version::int FOR UPDATE
+), edited AS (
+    UPDATE messages m
+    SET
+        message = @message,
+        language = @language,
+        edited_at = now(),
+        version = m.version + 1
+    FROM previous p
+    WHERE
+        m.id = p.id
+    RETURNING m."message", m."edited_at", m."version"
+), edit AS (
+    INSERT INTO message_edits
+        ("message_id", "previous_message")
+    SELECT p."id", p."message" FROM previous p, edited
+)
+SELECT "message", "edited_at", "version" FROM edited;
+
+-- name: SoftDeleteMessage :exec
+UPDATE messages
+SET
+    deleted_at = now(),
+    version = version + 1
+WHERE
+    id = $1 AND deleted_at IS NULL;
+
+-- name: PinMessage :one
+UPDATE messages
+SET
+    pinned = true,
+    version = version + 1
+WHERE
+    id = @id AND (sqlc.narg(version)::int IS NULL OR version = sqlc.narg(version)::int)
+RETURNING "version";
+
+-- name: AddMessageTag :exec
+UPDATE messages
+SET
+    tags = array_append(tags, @tag::varchar),
+    version = version + 1
+WHERE
+    id = @id AND NOT (@tag::varchar = ANY(tags));
+
+-- name: GetRoomTopMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+ORDER BY reaction_count DESC, created_at ASC
+LIMIT $2;
+
+-- name: InsertRoomTransfer :one
+INSERT INTO room_transfers
+    ("room_id", "token_hash", "from_owner_token_hash", "to_session_id", "expires_at") VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING "id", "room_id", "token_hash", "from_owner_token_hash", "to_session_id", "created_at", "expires_at", "accepted_at";
+
+-- name: GetPendingRoomTransfer :one
+SELECT
+    "id", "room_id", "token_hash", "from_owner_token_hash", "to_session_id", "created_at", "expires_at", "accepted_at"
+FROM room_transfers
+WHERE
+    room_id = $1 AND token_hash = $2 AND accepted_at IS NULL AND expires_at > now();
+
+-- name: AcceptRoomTransfer :execrows
+WITH accepted AS (
+    UPDATE room_transfers
+    SET
+        accepted_at = now()
+    FROM rooms
+    WHERE
+        room_transfers.id = @id
+        AND room_transfers.accepted_at IS NULL
+        AND room_transfers.expires_at > now()
+        AND rooms.id = room_transfers.room_id
+        AND rooms.owner_token_hash = room_transfers.from_owner_token_hash
+    RETURNING room_transfers.room_id, room_transfers.from_owner_token_hash
+)
+UPDATE rooms
+SET
+    owner_token_hash = @owner_token_hash
+FROM accepted
+WHERE
+    rooms.id = accepted.room_id AND rooms.owner_token_hash = accepted.from_owner_token_hash;
+
+-- name: InsertOrganization :one
+INSERT INTO organizations
+    ("name") VALUES
+    ($1)
+RETURNING "id", "name", "created_at", "settings", "stripe_customer_id";
+
+-- name: InsertAPIKey :one
+INSERT INTO api_keys
+    ("organization_id", "name", "key_hash") VALUES
+    ($1, $2, $3)
+RETURNING "id", "organization_id", "name", "key_hash", "created_at", "revoked_at";
+
+-- name: GetActiveAPIKeyByHash :one
+SELECT
+    "id", "organization_id", "name", "key_hash", "created_at", "revoked_at"
+FROM api_keys
+WHERE
+    key_hash = $1 AND revoked_at IS NULL;
+
+-- name: GetActiveAPIKey :one
+SELECT
+    "id", "organization_id", "name", "key_hash", "created_at", "revoked_at"
+FROM api_keys
+WHERE
+    id = $1 AND revoked_at IS NULL;
+
+-- name: AddUsage :exec
+INSERT INTO usage_records
+    ("organization_id", "api_key_id", "metric", "period_start", "quantity") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("organization_id", "api_key_id", "metric", "period_start") DO UPDATE
+SET
+    quantity = usage_records.quantity + EXCLUDED.quantity;
+
+-- name: GetOrganizationUsage :many
+SELECT
+    date_trunc('day', period_start)::timestamptz AS "day",
+    "api_key_id",
+    "metric",
+    SUM(quantity)::bigint AS "quantity"
+FROM usage_records
+WHERE
+    organization_id = @organization_id AND period_start >= @from_time AND period_start < @to_time
+GROUP BY "day", "api_key_id", "metric"
+ORDER BY "day", "metric";
+
+-- name: CountOrganizationRoomsSince :one
+SELECT
+    COUNT(*)
+FROM rooms
+WHERE
+    organization_id = $1 AND created_at >= $2;
+
+-- name: CountRoomMessages :one
+SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1;
+
+-- name: GetOrganization :one
+SELECT
+    "id", "name", "created_at", "settings", "stripe_customer_id"
+FROM organizations
+WHERE id = $1;
+
+-- name: GetOrganizationByStripeCustomer :one
+SELECT
+    "id", "name", "created_at", "settings", "stripe_customer_id"
+FROM organizations
+WHERE stripe_customer_id = $1;
+
+-- name: UpdateOrganizationBilling :exec
+UPDATE organizations
+SET
+    stripe_customer_id = $2,
+    settings = $3
+WHERE
+    id = $1;
+
+-- name: CountAdminCredentials :one
+SELECT
+    COUNT(*)
+FROM admin_credentials;
+
+-- name: GetAdminCredentialByHash :one
+SELECT
+    "id", "name", "token_hash", "created_at"
+FROM admin_credentials
+WHERE token_hash = $1;
+
+-- name: InsertAdminCredential :one
+INSERT INTO admin_credentials
+    ("name", "token_hash") VALUES
+    ($1, $2)
+RETURNING "id", "name", "token_hash", "created_at";
+
+-- name: AcquireBootstrapLock :exec
+SELECT pg_advisory_xact_lock(hashtext('wsrs_bootstrap'));
+
+-- name: UpsertSecret :exec
+INSERT INTO secrets
+    ("organization_id", "name", "key_id", "wrapped_key", "ciphertext") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("organization_id", "name") DO UPDATE
+SET
+    key_id = EXCLUDED.key_id,
+    wrapped_key = EXCLUDED.wrapped_key,
+    ciphertext = EXCLUDED.ciphertext,
+    updated_at = now();
+
+-- name: GetSecret :one
+SELECT
+    "id", "organization_id", "name", "key_id", "wrapped_key", "ciphertext", "created_at", "updated_at"
+FROM secrets
+WHERE
+    organization_id IS NOT DISTINCT FROM $1 AND name = $2;
+
+-- name: GetSecrets :many
+SELECT
+    "id", "organization_id", "name", "key_id", "wrapped_key", "ciphertext", "created_at", "updated_at"
+FROM secrets
+ORDER BY organization_id NULLS FIRST, name;
+
+-- name: RewrapSecret :execrows
+UPDATE secrets
+SET
+    key_id = @key_id,
+    wrapped_key = @wrapped_key
+WHERE
+    id = @id AND key_id = @previous_key_id;
+
+-- name: DeleteSecret :execrows
+DELETE FROM secrets
+WHERE
+    organization_id IS NOT DISTINCT FROM $1 AND name = $2;
+
+-- name: GetRoomActivity :many
+WITH activity AS (
+    SELECT date_trunc(@unit::text, m.created_at) AS "bucket_start", 1 AS "messages", 0 AS "reactions"
+    FROM messages m
+    WHERE m.room_id = @room_id
+    UNION ALL
+    SELECT date_trunc(@unit::text, mr.created_at), 0, 1
+    FROM message_reactions mr
+    WHERE mr.room_id = @room_id
+)
+SELECT
+    "bucket_start"::timestamptz AS "bucket_start",
+    SUM(messages)::bigint AS "messages",
+    SUM(reactions)::bigint AS "reactions"
+FROM activity
+GROUP BY "bucket_start"
+ORDER BY "bucket_start";
+
+-- name: GetOrganizationDashboardRooms :many
+SELECT
+    r."id", r."theme", r."code", r."archived_at", r."closed_at", r."created_at",
+    s."unanswered_count", s."last_activity",
+    (
+        SELECT COUNT(*) FROM messages m
+        WHERE m.room_id = r.id AND m.created_at >= @since
+    ) AS "recent_messages",
+    (
+        SELECT COUNT(*) FROM message_reactions mr
+        WHERE mr.room_id = r.id AND mr.created_at >= @since
+    ) AS "recent_reactions"
+FROM rooms r
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    r.organization_id = @organization_id
+ORDER BY r.archived_at IS NOT NULL, r.created_at DESC;
+
+-- name: UpsertSavedView :one
+INSERT INTO saved_views
+    ("room_id", "name", "filter") VALUES
+    ($1, $2, $3)
+ON CONFLICT ("room_id", "name") DO UPDATE
+SET
+    filter = EXCLUDED.filter,
+    updated_at = now()
+RETURNING "id", "room_id", "name", "filter", "created_at", "updated_at";
+
+-- name: GetSavedView :one
+SELECT
+    "id", "room_id", "name", "filter", "created_at", "updated_at"
+FROM saved_views
+WHERE
+    room_id = $1 AND name = $2;
+
+-- name: GetSavedViews :many
+SELECT
+    "id", "room_id", "name", "filter", "created_at", "updated_at"
+FROM saved_views
+WHERE
+    room_id = $1
+ORDER BY name;
+
+-- name: DeleteSavedView :execrows
+DELETE FROM saved_views
+WHERE
+    room_id = $1 AND name = $2;
+
+-- name: UpsertRoomOverlayToken :exec
+INSERT INTO room_overlay_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now();
+
+-- name: GetRoomOverlayTokenHash :one
+SELECT
+    "token_hash"
+FROM room_overlay_tokens
+WHERE room_id = $1;
+
+-- name: DeleteRoomOverlayToken :execrows
+DELETE FROM room_overlay_tokens
+WHERE room_id = $1;
+
+-- name: SetMessageAnswerAudio :execrows
+UPDATE messages
+SET
+    answer_audio_url = @answer_audio_url
+WHERE
+    id = @id AND room_id = @room_id AND answered AND answer_text = @answer_text;
+
+-- name: GetRoomStats :one
+SELECT
+    "room_id", "message_count", "unanswered_count", "reaction_total", "last_activity"
+FROM room_stats
+WHERE
+    room_id = $1;
+
+-- name: GetRoomLanguageCounts :many
+SELECT
+    "language", COUNT(*) AS "count"
+FROM messages
+WHERE
+    room_id = $1 AND deleted_at IS NULL
+GROUP BY "language"
+ORDER BY "count" DESC, "language";
+
+-- name: GetMessageTranslation :one
+SELECT
+    "message_id", "language", "source_hash", "text", "created_at"
+FROM message_translations
+WHERE
+    message_id = $1 AND language = $2;
+
+-- name: UpsertMessageTranslation :exec
+INSERT INTO message_translations
+    ("message_id", "language", "source_hash", "text") VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT ("message_id", "language") DO UPDATE
+SET
+    source_hash = EXCLUDED.source_hash,
+    text = EXCLUDED.text,
+    created_at = now();
 
--- name: MarkMessageAsAnswered :exec
+-- name: GetRoomModerationQueue :many
+SELECT
+    m."id", m."message", m."author_name", m."created_at", m."toxicity", m."hidden_at",
+    COUNT(mr."id") AS "report_count"
+FROM messages m
+LEFT JOIN message_reports mr ON mr.message_id = m.id AND mr.created_at > COALESCE(m.reviewed_at, '-infinity')
+WHERE
+    m.room_id = $1 AND m.deleted_at IS NULL
+GROUP BY m."id"
+HAVING m.hidden_at IS NOT NULL OR COUNT(mr."id") > 0
+ORDER BY m."toxicity" DESC NULLS LAST, "report_count" DESC, m."created_at" ASC
+LIMIT $2;
+
+-- name: ApproveMessage :exec
 UPDATE messages
 SET
-    answered = true
+    hidden_at = NULL,
+    reviewed_at = now()
+WHERE
+    id = $1;
+
+-- name: GetRelatedRooms :many
+SELECT
+    "id", "code", "theme", "description",
+    similarity("theme" || ' ' || "description", @source::text)::real AS "score"
+FROM rooms
+WHERE
+    id <> @id
+    AND archived_at IS NULL
+    AND organization_id IS NOT DISTINCT FROM @organization_id
+    AND ("theme" || ' ' || "description") % @source::text
+ORDER BY "score" DESC, created_at DESC
+LIMIT @max_rooms::int;
+
+-- name: InsertEvent :one
+INSERT INTO events
+    ("organization_id", "name", "starts_at", "ends_at") VALUES
+    ($1, $2, $3, $4)
+RETURNING "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults";
+
+-- name: GetOrganizationEvents :many
+SELECT
+    "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults"
+FROM events
+WHERE
+    organization_id = $1
+ORDER BY starts_at ASC NULLS LAST, created_at ASC;
+
+-- name: GetOrganizationEvent :one
+SELECT
+    "id", "organization_id", "name", "starts_at", "ends_at", "created_at", "room_defaults"
+FROM events
+WHERE
+    id = $1 AND organization_id = $2;
+
+-- name: DeleteEvent :execrows
+DELETE FROM events
+WHERE
+    id = $1 AND organization_id = $2;
+
+-- name: InsertTrack :one
+INSERT INTO tracks
+    ("event_id", "name", "position") VALUES
+    ($1, $2, $3)
+RETURNING "id", "event_id", "name", "position", "created_at";
+
+-- name: GetEventTracks :many
+SELECT
+    "id", "event_id", "name", "position", "created_at"
+FROM tracks
+WHERE
+    event_id = $1
+ORDER BY position ASC, created_at ASC;
+
+-- name: GetEventTrack :one
+SELECT
+    "id", "event_id", "name", "position", "created_at"
+FROM tracks
+WHERE
+    id = $1 AND event_id = $2;
+
+-- name: DeleteTrack :execrows
+DELETE FROM tracks
+WHERE
+    id = $1 AND event_id = $2;
+
+-- name: SetRoomTrack :execrows
+UPDATE rooms
+SET
+    track_id = $1
+WHERE
+    id = $2 AND organization_id = $3;
+
+-- name: GetEventRoomStats :many
+SELECT
+    r."id", r."track_id", r."code", r."theme", r."archived_at", r."closed_at", r."starts_at", r."host_name",
+    s."message_count", (s."message_count" - s."unanswered_count")::bigint AS "answered_count", s."reaction_total" AS "reaction_count"
+FROM rooms r
+JOIN tracks t ON t.id = r.track_id
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    t.event_id = $1
+ORDER BY t.position ASC, t.created_at ASC, r.starts_at ASC NULLS LAST, r.created_at ASC;
+
+-- name: SetEventRoomDefaults :exec
+UPDATE events
+SET
+    room_defaults = $1
+WHERE
+    id = $2;
+
+-- name: GetOrganizationTrackRoomDefaults :one
+SELECT
+    e."room_defaults"
+FROM tracks t
+JOIN events e ON e.id = t.event_id
 WHERE
-    id = $1;
\ No newline at end of file
+    t.id = $1 AND e.organization_id = $2;
+
+-- name: ApplyEventRoomDefaults :execrows
+UPDATE rooms r
+SET
+    posting_mode = COALESCE(sqlc.narg(posting_mode), r.posting_mode),
+    max_subscribers = COALESCE(sqlc.narg(max_subscribers), r.max_subscribers),
+    translate_to = COALESCE(sqlc.narg(translate_to), r.translate_to)
+FROM tracks t
+WHERE
+    r.track_id = t.id AND t.event_id = @event_id;
+
+-- name: GetRoomsPage :many
+SELECT
+    "id", "theme", "event_seq", "archived_at", "closed_at",
+    (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END)::text AS "status",
+    "created_at"
+FROM rooms
+WHERE
+    (@include_archived::bool OR archived_at IS NULL)
+    AND (@status::text = '' OR @status::text = (CASE WHEN archived_at IS NOT NULL THEN 'archived' WHEN closed_at IS NOT NULL THEN 'closed' ELSE 'active' END))
+    AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, @cursor_id::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size::int;
+
+-- name: GetRoomTopMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = @room_id AND deleted_at IS NULL AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_reaction_count)::bigint IS NULL OR (reaction_count, created_at, id) < (sqlc.narg(cursor_reaction_count)::bigint, @cursor_created_at::timestamptz, @cursor_id::uuid))
+    AND (sqlc.narg(answered)::bool IS NULL OR answered = sqlc.narg(answered))
+ORDER BY reaction_count DESC, created_at DESC, id DESC
+LIMIT @page_size::int;
+
+-- name: GetRoomMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "created_at", "fields", "author_name", "session_id", "deleted_at", "pinned", "tags", "answer_text", "answer_audio_url", "language", "toxicity", "hidden_at", "reviewed_at", "answer_video_url", "answer_video_offset", "edited_at", "version"
+FROM messages
+WHERE
+    room_id = @room_id AND deleted_at IS NULL AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, @cursor_id::uuid))
+    AND (sqlc.narg(answered)::bool IS NULL OR answered = sqlc.narg(answered))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size::int;
+
+-- name: SearchRoomMessages :many
+SELECT
+    m."id", m."message", m."author_name", m."reaction_count", m."answered", m."created_at",
+    ts_rank_cd(ms."document", query)::real AS "rank"
+FROM message_search ms
+JOIN messages m ON m.id = ms.message_id,
+    websearch_to_tsquery('simple', @query::text) query
+WHERE
+    ms.room_id = @room_id AND ms."document" @@ query
+    AND m.deleted_at IS NULL AND m.hidden_at IS NULL
+ORDER BY "rank" DESC, m."created_at" DESC
+LIMIT @max_messages::int;
+
+-- name: InsertJob :one
+INSERT INTO jobs
+    ("kind", "payload", "organization_id") VALUES
+    ($1, $2, $3)
+RETURNING "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at";
+
+-- name: InsertJobUnlessPending :execrows
+INSERT INTO jobs
+    ("kind", "payload")
+SELECT @kind::varchar, @payload::jsonb
+WHERE NOT EXISTS (
+    SELECT 1 FROM jobs WHERE kind = @kind::varchar AND status IN ('queued', 'running')
+);
+
+-- name: ClaimJob :one
+UPDATE jobs
+SET
+    status = 'running',
+    attempts = attempts + 1,
+    started_at = now()
+WHERE id = (
+    SELECT j.id FROM jobs j
+    WHERE
+        j.status = 'queued'
+        OR (j.status = 'running' AND j.started_at < @stale_before::timestamptz)
+    ORDER BY j.created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at";
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET
+    status = 'done',
+    output = $2,
+    content_type = $3,
+    filename = $4,
+    error = '',
+    finished_at = now()
+WHERE
+    id = $1;
+
+-- name: FailJob :exec
+WITH failed AS (
+    UPDATE jobs
+    SET
+        status = CASE WHEN attempts >= @max_attempts::int THEN 'failed' ELSE 'queued' END,
+        error = @error,
+        finished_at = CASE WHEN attempts >= @max_attempts::int THEN now() END
+    WHERE
+        jobs.id = @id
+    RETURNING "id", "kind", "organization_id", "status", "error", "attempts"
+)
+INSERT INTO dead_letters
+    ("source", "ref", "kind", "organization_id", "error", "attempts")
+SELECT 'job', "id"::text, "kind", "organization_id", "error", "attempts"
+FROM failed
+WHERE "status" = 'failed'
+ON CONFLICT (source, ref) DO UPDATE
+SET error = EXCLUDED.error, attempts = EXCLUDED.attempts, created_at = now();
+
+-- name: GetJob :one
+SELECT
+    "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+FROM jobs
+WHERE
+    id = $1;
+
+-- name: GetOrganizationJob :one
+SELECT
+    "id", "kind", "payload", "organization_id", "status", "attempts", "error", "output", "content_type", "filename", "created_at", "started_at", "finished_at"
+FROM jobs
+WHERE
+    id = $1 AND organization_id = $2;
+
+-- name: RecordRoomPeak :exec
+INSERT INTO room_peaks
+    ("room_id", "peak_subscribers") VALUES
+    ($1, $2)
+ON CONFLICT (room_id) DO UPDATE
+SET
+    peak_subscribers = GREATEST(room_peaks.peak_subscribers, EXCLUDED.peak_subscribers),
+    updated_at = now()
+WHERE
+    room_peaks.peak_subscribers < EXCLUDED.peak_subscribers;
+
+-- name: GetEventReportRooms :many
+SELECT
+    r."id", r."code", r."theme", r."starts_at", r."host_name", t."name" AS "track_name",
+    COALESCE((SELECT p.peak_subscribers FROM room_peaks p WHERE p.room_id = r.id), 0)::int AS "peak_subscribers",
+    (
+        SELECT COUNT(DISTINCT m.session_id) FROM messages m
+        WHERE m.room_id = r.id AND m.deleted_at IS NULL
+    ) AS "participant_count",
+    s."message_count", (s."message_count" - s."unanswered_count")::bigint AS "answered_count", s."reaction_total" AS "reaction_count"
+FROM rooms r
+JOIN tracks t ON t.id = r.track_id
+JOIN room_stats s ON s.room_id = r.id
+WHERE
+    t.event_id = $1
+ORDER BY t.position ASC, t.created_at ASC, r.starts_at ASC NULLS LAST, r.created_at ASC;
+
+-- name: GetEventTopMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered"
+FROM (
+    SELECT
+        m.id, m.room_id, m.message, m.reaction_count, m.answered,
+        ROW_NUMBER() OVER (PARTITION BY m.room_id ORDER BY m.reaction_count DESC, m.created_at ASC) AS rank
+    FROM messages m
+    JOIN rooms r ON r.id = m.room_id
+    JOIN tracks t ON t.id = r.track_id
+    WHERE
+        t.event_id = @event_id AND m.deleted_at IS NULL AND m.hidden_at IS NULL
+) ranked
+WHERE
+    rank <= @per_room::int
+ORDER BY room_id, rank;
+
+-- name: UpsertRoomCaptionToken :exec
+INSERT INTO room_caption_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now();
+
+-- name: GetRoomCaptionTokenHash :one
+SELECT
+    "token_hash"
+FROM room_caption_tokens
+WHERE room_id = $1;
+
+-- name: DeleteRoomCaptionToken :execrows
+DELETE FROM room_caption_tokens
+WHERE room_id = $1;
+
+-- name: InsertCaption :one
+INSERT INTO captions
+    ("room_id", "text", "language", "start_ms", "end_ms") VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING "id", "room_id", "text", "language", "start_ms", "end_ms", "created_at";
+
+-- name: GetRoomCaptions :many
+SELECT
+    "id", "room_id", "text", "language", "start_ms", "end_ms", "created_at"
+FROM captions
+WHERE
+    room_id = @room_id AND created_at > @since::timestamptz
+ORDER BY created_at ASC, id ASC
+LIMIT @max_captions::int;
+
+-- name: UpsertRoomChatBridge :one
+INSERT INTO room_chat_bridges
+    ("room_id", "platform", "channel", "prefix", "rate_per_minute") VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    platform = EXCLUDED.platform,
+    channel = EXCLUDED.channel,
+    prefix = EXCLUDED.prefix,
+    rate_per_minute = EXCLUDED.rate_per_minute,
+    updated_at = now()
+RETURNING "room_id", "platform", "channel", "prefix", "rate_per_minute", "updated_at";
+
+-- name: GetRoomChatBridge :one
+SELECT
+    "room_id", "platform", "channel", "prefix", "rate_per_minute", "updated_at"
+FROM room_chat_bridges
+WHERE room_id = $1;
+
+-- name: GetActiveChatBridges :many
+SELECT
+    b."room_id", b."platform", b."channel", b."prefix", b."rate_per_minute", b."updated_at"
+FROM room_chat_bridges b
+JOIN rooms r ON r.id = b.room_id
+WHERE
+    r.archived_at IS NULL;
+
+-- name: DeleteRoomChatBridge :execrows
+DELETE FROM room_chat_bridges
+WHERE room_id = $1;
+
+-- name: MarkChatBridgeMessage :execrows
+INSERT INTO chat_bridge_messages
+    ("room_id", "external_id") VALUES
+    ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UpsertRoomWebinarToken :exec
+INSERT INTO room_webinar_tokens
+    ("room_id", "token_hash") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = now();
+
+-- name: GetRoomWebinarTokenHash :one
+SELECT
+    "token_hash"
+FROM room_webinar_tokens
+WHERE room_id = $1;
+
+-- name: DeleteRoomWebinarToken :execrows
+DELETE FROM room_webinar_tokens
+WHERE room_id = $1;
+
+-- name: GetWebinarQuestion :one
+SELECT
+    "room_id", "external_id", "message_id", "upvotes", "created_at"
+FROM webinar_questions
+WHERE
+    room_id = $1 AND external_id = $2
+FOR UPDATE;
+
+-- name: InsertWebinarQuestion :exec
+INSERT INTO webinar_questions
+    ("room_id", "external_id", "message_id") VALUES
+    ($1, $2, $3);
+
+-- name: SetWebinarQuestionUpvotes :exec
+UPDATE webinar_questions
+SET
+    upvotes = $3
+WHERE
+    room_id = $1 AND external_id = $2;
+
+-- name: AddMessageReactions :one
+WITH reactions AS (
+    INSERT INTO message_reactions
+        ("message_id", "room_id", "kind")
+    SELECT m."id", m."room_id", @kind::varchar FROM messages m, generate_series(1, @count::int)
+    WHERE m.id = @id
+    RETURNING "id"
+)
+UPDATE messages
+SET
+    reaction_count = reaction_count + (SELECT COUNT(*) FROM reactions)
+WHERE
+    messages.id = @id
+RETURNING "reaction_count";
+
+-- name: UpsertRoomIngestHook :one
+INSERT INTO room_ingest_hooks
+    ("room_id", "mapping") VALUES
+    ($1, $2)
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    mapping = EXCLUDED.mapping,
+    updated_at = now()
+RETURNING "room_id", "mapping", "updated_at";
+
+-- name: GetRoomIngestHook :one
+SELECT
+    "room_id", "mapping", "updated_at"
+FROM room_ingest_hooks
+WHERE room_id = $1;
+
+-- name: DeleteRoomIngestHook :execrows
+DELETE FROM room_ingest_hooks
+WHERE room_id = $1;
+
+-- name: InsertIngestDelivery :execrows
+INSERT INTO ingest_deliveries
+    ("room_id", "external_id", "message_id") VALUES
+    ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: InsertRoomEvent :exec
+INSERT INTO room_events
+    ("room_id", "event_id", "channel", "kind", "value") VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: InsertOutboxEvent :exec
+INSERT INTO event_outbox
+    ("instance_id", "room_id", "channel", "kind", "value") VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: ClaimOutboxEvents :many
+SELECT
+    "id", "instance_id", "room_id", "channel", "kind", "value", "created_at"
+FROM event_outbox
+WHERE
+    instance_id = @instance_id OR created_at < @stale_before::timestamptz
+ORDER BY id ASC
+LIMIT @max_events::int
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteOutboxEvents :exec
+DELETE FROM event_outbox
+WHERE
+    id = ANY(@ids::bigint[]);
+
+-- name: GetRoomEvents :many
+SELECT
+    "room_id", "event_id", "channel", "kind", "value", "created_at"
+FROM room_events
+WHERE
+    room_id = @room_id AND event_id > @after_event_id
+ORDER BY event_id ASC
+LIMIT @max_events::int;
+
+-- name: DeleteRoomEventsBefore :execrows
+DELETE FROM room_events
+WHERE
+    created_at < @before::timestamptz;
+
+-- name: DeleteRoomEventsBeyond :execrows
+DELETE FROM room_events e
+USING rooms r
+WHERE
+    r.id = e.room_id AND e.event_id <= r.event_seq - @keep::bigint;
+
+-- name: UpsertRoomWebhook :one
+INSERT INTO room_webhooks
+    ("room_id", "url", "last_event_id")
+SELECT
+    r.id, @url, r.event_seq
+FROM rooms r
+WHERE r.id = @room_id
+ON CONFLICT ("room_id") DO UPDATE
+SET
+    url = EXCLUDED.url,
+    updated_at = now()
+RETURNING "id", "room_id", "url", "last_event_id", "created_at", "updated_at";
+
+-- name: GetRoomWebhook :one
+SELECT
+    "id", "room_id", "url", "last_event_id", "created_at", "updated_at"
+FROM room_webhooks
+WHERE room_id = $1;
+
+-- name: DeleteRoomWebhook :execrows
+DELETE FROM room_webhooks
+WHERE room_id = $1;
+
+-- name: EnqueueWebhookDeliveries :one
+WITH queued AS (
+    INSERT INTO webhook_deliveries
+        ("webhook_id", "event_id")
+    SELECT
+        w.id, e.event_id
+    FROM room_webhooks w
+    JOIN room_events e ON e.room_id = w.room_id AND e.event_id > w.last_event_id
+    WHERE e.created_at < @settled_before::timestamptz
+    ON CONFLICT DO NOTHING
+    RETURNING "webhook_id", "event_id"
+), advanced AS (
+    UPDATE room_webhooks w
+    SET
+        last_event_id = q.event_id
+    FROM (SELECT webhook_id, MAX(event_id) AS event_id FROM queued GROUP BY webhook_id) q
+    WHERE w.id = q.webhook_id
+)
+SELECT COUNT(*) FROM queued;
+
+-- name: ClaimWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET
+    attempts = d.attempts + 1,
+    next_attempt_at = @lease_until::timestamptz
+FROM room_webhooks w
+WHERE
+    w.id = d.webhook_id
+    AND (d.webhook_id, d.event_id) = (
+        SELECT dd.webhook_id, dd.event_id FROM webhook_deliveries dd
+        WHERE dd.status = 'pending' AND dd.next_attempt_at <= now()
+        ORDER BY dd.next_attempt_at ASC
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED
+    )
+RETURNING d."webhook_id", d."event_id", d."attempts", w."room_id", w."url";
+
+-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = 'delivered',
+    last_error = '',
+    delivered_at = now()
+WHERE webhook_id = $1 AND event_id = $2;
+
+-- name: FailWebhookDelivery :exec
+WITH failed AS (
+    UPDATE webhook_deliveries
+    SET
+        status = CASE WHEN attempts >= @max_attempts::int THEN 'failed' ELSE 'pending' END,
+        next_attempt_at = @retry_at::timestamptz,
+        last_error = @error
+    WHERE webhook_id = @webhook_id AND event_id = @event_id
+    RETURNING "webhook_id", "event_id", "status", "last_error", "attempts"
+)
+INSERT INTO dead_letters
+    ("source", "ref", "kind", "organization_id", "room_id", "error", "attempts")
+SELECT 'webhook', f."webhook_id"::text || ':' || f."event_id"::text, 'room_event', r."organization_id", r."id", f."last_error", f."attempts"
+FROM failed f
+JOIN room_webhooks w ON w.id = f.webhook_id
+JOIN rooms r ON r.id = w.room_id
+WHERE f."status" = 'failed'
+ON CONFLICT (source, ref) DO UPDATE
+SET error = EXCLUDED.error, attempts = EXCLUDED.attempts, created_at = now();
+
+-- name: RedriveWebhookDeliveries :one
+WITH redriven AS (
+    UPDATE webhook_deliveries d
+    SET
+        status = 'pending',
+        attempts = 0,
+        next_attempt_at = now()
+    FROM room_webhooks w
+    WHERE
+        w.id = d.webhook_id
+        AND d.status = 'failed'
+        AND (sqlc.narg('room_id')::uuid IS NULL OR w.room_id = sqlc.narg('room_id'))
+    RETURNING d."webhook_id", d."event_id"
+), dropped AS (
+    DELETE FROM dead_letters dl
+    USING redriven r
+    WHERE dl.source = 'webhook' AND dl.ref = r."webhook_id"::text || ':' || r."event_id"::text
+)
+SELECT COUNT(*) FROM redriven;
+
+-- name: RedriveWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET
+    status = 'pending',
+    attempts = 0,
+    next_attempt_at = now()
+WHERE webhook_id = @webhook_id AND event_id = @event_id AND status = 'failed';
+
+-- name: RetryJob :execrows
+UPDATE jobs
+SET
+    status = 'queued',
+    attempts = 0,
+    error = '',
+    started_at = NULL,
+    finished_at = NULL
+WHERE
+    id = $1 AND status = 'failed';
+
+-- name: GetDeadLetters :many
+SELECT
+    "id", "source", "ref", "kind", "organization_id", "room_id", "error", "attempts", "created_at"
+FROM dead_letters
+WHERE
+    sqlc.narg('source')::text IS NULL OR source = sqlc.narg('source')
+ORDER BY created_at DESC, id DESC
+LIMIT @max_dead_letters::int;
+
+-- name: GetDeadLetter :one
+SELECT
+    "id", "source", "ref", "kind", "organization_id", "room_id", "error", "attempts", "created_at"
+FROM dead_letters
+WHERE
+    id = $1;
+
+-- name: DeleteDeadLetter :execrows
+DELETE FROM dead_letters
+WHERE
+    id = $1;
+
+-- name: GetDeadLetterStats :many
+SELECT
+    "source",
+    COUNT(*) AS "count",
+    MIN(created_at)::timestamptz AS "oldest"
+FROM dead_letters
+GROUP BY "source";
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// replicaPollInterval is how often a read waiting on the replica checks
+// whether it caught up.
+const replicaPollInterval = 10 * time.Millisecond
+
+// Consistency tokens are WAL positions of the primary, as pg_lsn prints them.
+var lsnPattern = regexp.MustCompile(`^[0-9A-F]{1,8}/[0-9A-F]{1,8}$`)
+
+// Postgres is the Store backed by a connection pool.
+type Postgres struct {
+	*pg.Queries
+	pool    *pgxpool.Pool
+	replica *replica
+}
+
+type replica struct {
+	queries *pg.Queries
+	pool    *pgxpool.Pool
+	maxWait time.Duration
+}
+
+func NewPostgres(pool *pgxpool.Pool) *Postgres {
+	return &Postgres{Queries: pg.New(translatingDB{pool}), pool: pool}
+}
+
+// WithReplica has Reader send reads to the streaming replica pool connects
+// to, waiting up to maxWait for it to replay the writes they must see before
+// falling back to the primary.
+func (p *Postgres) WithReplica(pool *pgxpool.Pool, maxWait time.Duration) *Postgres {
+	p.replica = &replica{queries: pg.New(translatingDB{pool}), pool: pool, maxWait: maxWait}
+	return p
+}
+
+func (p *Postgres) Begin(ctx context.Context) (Tx, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresTx{Queries: pg.New(translatingDB{tx}), tx: tx}, nil
+}
+
+// Utilization reports the share of the pool's connections acquired.
+func (p *Postgres) Utilization() float64 {
+	stat := p.pool.Stat()
+	if stat.MaxConns() == 0 {
+		return 0
+	}
+
+	return float64(stat.AcquiredConns()) / float64(stat.MaxConns())
+}
+
+// TryLock takes a session advisory lock on a connection of its own, kept out
+// of the pool while the lock is held.
+func (p *Postgres) TryLock(ctx context.Context, name string) (Lock, error) {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+
+	return &postgresLock{conn: conn, name: name}, nil
+}
+
+// Position is the primary's current WAL position, empty without a replica.
+func (p *Postgres) Position(ctx context.Context) (string, error) {
+	if p.replica == nil {
+		return "", nil
+	}
+
+	var lsn string
+	err := p.pool.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn)
+
+	return lsn, err
+}
+
+func (p *Postgres) Reader(ctx context.Context, token string) (pg.Querier, error) {
+	if token != "" && !lsnPattern.MatchString(token) {
+		return nil, ErrInvalidToken
+	}
+	if p.replica == nil {
+		return p.Queries, nil
+	}
+	if token == "" {
+		return p.replica.queries, nil
+	}
+
+	deadline := time.Now().Add(p.replica.maxWait)
+	for {
+		var replayed bool
+		err := p.replica.pool.QueryRow(ctx, "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", token).Scan(&replayed)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			//? A replica that can't be asked may well be down, the primary answers instead
+			return p.Queries, nil
+		}
+		if replayed {
+			return p.replica.queries, nil
+		}
+		if time.Now().Add(replicaPollInterval).After(deadline) {
+			return p.Queries, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(replicaPollInterval):
+		}
+	}
+}
+
+type postgresLock struct {
+	conn *pgxpool.Conn
+	name string
+}
+
+func (l *postgresLock) Held(ctx context.Context) bool {
+	return l.conn.Ping(ctx) == nil
+}
+
+func (l *postgresLock) Release(ctx context.Context) error {
+	var unlocked bool
+	err := l.conn.QueryRow(ctx, "SELECT pg_advisory_unlock(hashtext($1))", l.name).Scan(&unlocked)
+	if err != nil {
+		//? Closing the connection drops the lock with it
+		_ = l.conn.Conn().Close(ctx)
+	}
+	l.conn.Release()
+
+	return err
+}
+
+type postgresTx struct {
+	*pg.Queries
+	tx pgx.Tx
+}
+
+func (t postgresTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t postgresTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+// translatingDB runs the generated queries, passing the errors they return
+// through Translate.
+type translatingDB struct {
+	db pg.DBTX
+}
+
+func (d translatingDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := d.db.Exec(ctx, sql, args...)
+
+	return tag, Translate(err)
+}
+
+func (d translatingDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := d.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, Translate(err)
+	}
+
+	return translatingRows{rows}, nil
+}
+
+func (d translatingDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return translatingRow{d.db.QueryRow(ctx, sql, args...)}
+}
+
+// translatingRow translates on Scan, as a single row query only fails there.
+type translatingRow struct {
+	pgx.Row
+}
+
+func (r translatingRow) Scan(dest ...any) error {
+	return Translate(r.Row.Scan(dest...))
+}
+
+type translatingRows struct {
+	pgx.Rows
+}
+
+func (r translatingRows) Err() error {
+	return Translate(r.Rows.Err())
+}
@@ -0,0 +1,49 @@
+// Package store is the persistence the API runs on, so handlers depend on an
+// interface rather than on Postgres: NewPostgres in production, the memory
+// package in tests.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/luiz504/week-tech-go-server/internal/store/pg"
+)
+
+// Store runs every query of the API, on its own or in a transaction.
+type Store interface {
+	pg.Querier
+	// Begin starts a transaction, rolled back unless committed.
+	Begin(ctx context.Context) (Tx, error)
+	// Utilization reports the share of the store's connections in use, for
+	// admission control.
+	Utilization() float64
+	// TryLock takes the lock called name for this instance, or returns nil
+	// when another one holds it.
+	TryLock(ctx context.Context, name string) (Lock, error)
+	// Position returns a consistency token for the writes committed so far,
+	// empty when every read sees them anyway.
+	Position(ctx context.Context) (string, error)
+	// Reader returns where to run reads that must see the writes of token,
+	// any when it's empty: a replica that caught up with them, or else the
+	// primary.
+	Reader(ctx context.Context, token string) (pg.Querier, error)
+}
+
+// ErrInvalidToken is a consistency token Position didn't return.
+var ErrInvalidToken = errors.New("invalid consistency token")
+
+// Lock is held until released or until the connection holding it is lost,
+// which Held reports.
+type Lock interface {
+	Held(ctx context.Context) bool
+	Release(ctx context.Context) error
+}
+
+// Tx runs queries in a transaction. Rollback after Commit has no effect, so it
+// can be deferred.
+type Tx interface {
+	pg.Querier
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
@@ -0,0 +1,153 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signed requests authenticate with an HMAC instead of sending the API key,
+// so an intercepted request can't be reused or turned into a credential.
+const (
+	HeaderKeyID     = "X-API-Key-ID"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+const maxNonceLength = 128
+
+// SigningResolver looks up an active key by ID along with its signing
+// secret, returning ErrUnknownKey when either is missing.
+type SigningResolver func(ctx context.Context, keyID uuid.UUID) (Key, []byte, error)
+
+// StringToSign is what a client signs: the unix timestamp, the nonce, the
+// method, the request URI and the hex SHA-256 of the body, one per line.
+func StringToSign(timestamp, nonce, method, requestURI string, body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return strings.Join([]string{timestamp, nonce, method, requestURI, hex.EncodeToString(sum[:])}, "\n")
+}
+
+// Sign returns the hex HMAC-SHA256 of the string to sign.
+func Sign(secret []byte, stringToSign string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NonceCache remembers the nonces seen within the tolerance window. Older
+// ones don't need remembering as their timestamp is already rejected.
+type NonceCache struct {
+	mu       sync.Mutex
+	seen     map[string]time.Time
+	ttl      time.Duration
+	prunedAt time.Time
+}
+
+func NewNonceCache(ttl time.Duration) *NonceCache {
+	return &NonceCache{seen: make(map[string]time.Time), ttl: ttl}
+}
+
+// Claim records a nonce, reporting false if it was already used.
+func (c *NonceCache) Claim(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.prunedAt) > c.ttl/2 {
+		for n, at := range c.seen {
+			if now.Sub(at) > c.ttl {
+				delete(c.seen, n)
+			}
+		}
+		c.prunedAt = now
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now
+
+	return true
+}
+
+// SignedMiddleware authenticates requests carrying X-Signature. Their
+// timestamp must be within tolerance of the server clock, either way, and
+// their nonce unused. Requests without a signature pass through untouched.
+func SignedMiddleware(resolve SigningResolver, tolerance time.Duration) func(http.Handler) http.Handler {
+	//? A nonce must outlive every timestamp that could still be accepted with it
+	nonces := NewNonceCache(2 * tolerance)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature := r.Header.Get(HeaderSignature)
+			if signature == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keyID, err := uuid.Parse(r.Header.Get(HeaderKeyID))
+			if err != nil {
+				http.Error(w, "invalid api key id", http.StatusUnauthorized)
+				return
+			}
+			rawTimestamp, nonce := r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce)
+			if nonce == "" || len(nonce) > maxNonceLength {
+				http.Error(w, "invalid signature nonce", http.StatusUnauthorized)
+				return
+			}
+			timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid signature timestamp", http.StatusUnauthorized)
+				return
+			}
+			now := time.Now()
+			if skew := now.Sub(time.Unix(timestamp, 0)); skew > tolerance || skew < -tolerance {
+				http.Error(w, "signature timestamp outside tolerance", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key, secret, err := resolve(r.Context(), keyID)
+			if err != nil {
+				if errors.Is(err, ErrUnknownKey) {
+					http.Error(w, "invalid signature", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			expected := Sign(secret, StringToSign(rawTimestamp, nonce, r.Method, r.URL.RequestURI(), body))
+			if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+
+			//* Claimed only once verified, so forged requests can't burn nonces
+			if !nonces.Claim(keyID.String()+":"+nonce, now) {
+				http.Error(w, "replayed request", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const HeaderName = "X-API-Key"
+
+// Key is the API key a request authenticated with and the organization it belongs to.
+type Key struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+var ErrUnknownKey = errors.New("unknown api key")
+
+// Resolver looks up an active key by its raw value, returning ErrUnknownKey
+// when there is none.
+type Resolver func(ctx context.Context, rawKey string) (Key, error)
+
+type contextKey struct{}
+
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the API key of the request, if it carried a valid one.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+
+	return key, ok
+}
+
+// Middleware attaches the organization of requests carrying an API key.
+// Requests without one pass through, but a key that doesn't resolve is
+// rejected instead of silently downgrading to anonymous.
+func Middleware(resolve Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := strings.TrimSpace(r.Header.Get(HeaderName))
+			if raw == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := resolve(r.Context(), raw)
+			if err != nil {
+				if errors.Is(err, ErrUnknownKey) {
+					http.Error(w, "invalid api key", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+		})
+	}
+}
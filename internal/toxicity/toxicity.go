@@ -0,0 +1,150 @@
+// Package toxicity scores messages through an external classifier, so rooms
+// can hold abusive questions for review before the audience sees them.
+package toxicity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultThreshold = 0.8
+	defaultTimeout   = 2 * time.Second
+)
+
+// Provider scores text from 0, harmless, to 1, certainly toxic.
+type Provider interface {
+	Score(ctx context.Context, text string) (float64, error)
+}
+
+// HTTPProvider posts {"text"} to an endpoint answering with {"score"}, a
+// shape thin adapters can give most moderation services.
+type HTTPProvider struct {
+	endpoint string
+	apiKey   func(ctx context.Context) (string, error)
+	client   *http.Client
+}
+
+// NewHTTPProvider sends the key returned by apiKey, when not empty, as a bearer token.
+func NewHTTPProvider(endpoint string, apiKey func(ctx context.Context) (string, error)) *HTTPProvider {
+	return &HTTPProvider{endpoint: endpoint, apiKey: apiKey, client: &http.Client{}}
+}
+
+func (p *HTTPProvider) Score(ctx context.Context, text string) (float64, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	key, err := p.apiKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("load api key: %w", err)
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var res struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, err
+	}
+	if res.Score == nil || *res.Score < 0 || *res.Score > 1 {
+		return 0, fmt.Errorf("provider returned no score between 0 and 1")
+	}
+
+	return *res.Score, nil
+}
+
+// Scorer scores messages as they're posted, holding back those at or above
+// its threshold.
+type Scorer struct {
+	provider  Provider
+	threshold float64
+	timeout   time.Duration
+}
+
+func NewScorer(provider Provider, threshold float64, timeout time.Duration) *Scorer {
+	return &Scorer{provider: provider, threshold: threshold, timeout: timeout}
+}
+
+// FromEnv reads WS_TOXICITY_URL, returning nil when unset. WS_TOXICITY_API_KEY
+// is sent when set, the key from fallbackKey otherwise. Messages scoring at
+// least WS_TOXICITY_THRESHOLD are held, and WS_TOXICITY_TIMEOUT bounds how
+// long posting waits on the provider.
+func FromEnv(fallbackKey func(ctx context.Context) (string, error)) *Scorer {
+	endpoint := os.Getenv("WS_TOXICITY_URL")
+	if endpoint == "" {
+		return nil
+	}
+
+	threshold := defaultThreshold
+	if raw := os.Getenv("WS_TOXICITY_THRESHOLD"); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v > 0 && v <= 1 {
+			threshold = v
+		} else {
+			slog.Warn("invalid WS_TOXICITY_THRESHOLD, using default", "value", raw, "default", defaultThreshold)
+		}
+	}
+	timeout := defaultTimeout
+	if raw := os.Getenv("WS_TOXICITY_TIMEOUT"); raw != "" {
+		if v, err := time.ParseDuration(raw); err == nil && v > 0 {
+			timeout = v
+		} else {
+			slog.Warn("invalid WS_TOXICITY_TIMEOUT, using default", "value", raw, "default", defaultTimeout)
+		}
+	}
+
+	apiKey := fallbackKey
+	if key := os.Getenv("WS_TOXICITY_API_KEY"); key != "" {
+		apiKey = func(context.Context) (string, error) { return key, nil }
+	}
+
+	return NewScorer(NewHTTPProvider(endpoint, apiKey), threshold, timeout)
+}
+
+// Check scores text, reporting ok false when scoring is off or the provider
+// fails. Failures are logged and the message goes through unscored, an
+// outage of the classifier shouldn't stop a room from taking questions.
+func (s *Scorer) Check(ctx context.Context, text string) (score float64, hold bool, ok bool) {
+	if s == nil {
+		return 0, false, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	score, err := s.provider.Score(ctx, text)
+	if err != nil {
+		slog.Warn("failed to score message toxicity", "error", err)
+		return 0, false, false
+	}
+
+	return score, score >= s.threshold, true
+}
@@ -0,0 +1,169 @@
+// Package translate translates messages through an external provider, on
+// demand and on a background queue for rooms that broadcast translations.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+const (
+	queueSize      = 256
+	requestTimeout = 15 * time.Second
+	// MaxTargets bounds the languages a room broadcasts, each one costing a
+	// provider call per message.
+	MaxTargets = 5
+)
+
+var ErrInvalidLanguage = errors.New("invalid language tag")
+
+// ParseLanguage validates a BCP 47 tag like pt-BR and returns its canonical form.
+func ParseLanguage(raw string) (string, error) {
+	if raw == "" || len(raw) > 16 {
+		return "", ErrInvalidLanguage
+	}
+	tag, err := language.Parse(raw)
+	if err != nil {
+		return "", ErrInvalidLanguage
+	}
+
+	return tag.String(), nil
+}
+
+// SameLanguage reports whether a message detected as the ISO 639-1 code
+// detected already reads as target, like pt for pt-BR.
+func SameLanguage(detected, target string) bool {
+	if detected == "" {
+		return false
+	}
+	base, _ := language.Make(target).Base()
+
+	return base.String() == detected
+}
+
+// Provider translates text into the target language.
+type Provider interface {
+	Translate(ctx context.Context, text, target string) (string, error)
+}
+
+// HTTPProvider posts {"text", "target"} to an endpoint answering with
+// {"text"}, a shape thin adapters can give most translation services.
+type HTTPProvider struct {
+	endpoint string
+	apiKey   func(ctx context.Context) (string, error)
+	client   *http.Client
+}
+
+// NewHTTPProvider sends the key returned by apiKey, when not empty, as a bearer token.
+func NewHTTPProvider(endpoint string, apiKey func(ctx context.Context) (string, error)) *HTTPProvider {
+	return &HTTPProvider{endpoint: endpoint, apiKey: apiKey, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// FromEnv reads WS_TRANSLATE_URL, returning nil when unset. WS_TRANSLATE_API_KEY
+// is sent when set, the key from fallbackKey otherwise.
+func FromEnv(fallbackKey func(ctx context.Context) (string, error)) Provider {
+	endpoint := os.Getenv("WS_TRANSLATE_URL")
+	if endpoint == "" {
+		return nil
+	}
+
+	apiKey := fallbackKey
+	if key := os.Getenv("WS_TRANSLATE_API_KEY"); key != "" {
+		apiKey = func(context.Context) (string, error) { return key, nil }
+	}
+
+	return NewHTTPProvider(endpoint, apiKey)
+}
+
+func (p *HTTPProvider) Translate(ctx context.Context, text, target string) (string, error) {
+	body, err := json.Marshal(map[string]string{"text": text, "target": target})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	key, err := p.apiKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load api key: %w", err)
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var res struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+
+	return res.Text, nil
+}
+
+// Job asks for a message to be translated into each of Targets.
+type Job struct {
+	RoomID    uuid.UUID
+	MessageID uuid.UUID
+	Text      string
+	Targets   []string
+}
+
+// Queue runs jobs on one goroutine, so a burst of messages in a multilingual
+// room queues up instead of fanning out to the provider.
+type Queue struct {
+	jobs chan Job
+}
+
+func NewQueue() *Queue {
+	return &Queue{jobs: make(chan Job, queueSize)}
+}
+
+// Enqueue queues the job without blocking, reporting false when full.
+func (q *Queue) Enqueue(job Job) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		slog.Warn("translation queue is full, skipping message", "room_id", job.RoomID, "message_id", job.MessageID)
+		return false
+	}
+}
+
+// Run hands queued jobs to handle until ctx is done.
+func (q *Queue) Run(ctx context.Context, handle func(ctx context.Context, job Job)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			handle(ctx, job)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package tts turns answers into speech through an external provider, on a
+// queue so a slow provider never holds up the request that answered.
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultQueueSize = 100
+	defaultTimeout   = 30 * time.Second
+)
+
+// Provider synthesizes text and returns where the audio can be fetched.
+type Provider interface {
+	Synthesize(ctx context.Context, text string) (audioURL string, err error)
+}
+
+// HTTPProvider posts {"text", "voice"} to an endpoint answering with
+// {"audio_url"}, the shape most hosted TTS services can be adapted to.
+type HTTPProvider struct {
+	endpoint string
+	voice    string
+	apiKey   func(ctx context.Context) (string, error)
+	client   *http.Client
+}
+
+// NewHTTPProvider sends the key returned by apiKey, when not empty, as a bearer token.
+func NewHTTPProvider(endpoint, voice string, apiKey func(ctx context.Context) (string, error)) *HTTPProvider {
+	return &HTTPProvider{endpoint: endpoint, voice: voice, apiKey: apiKey, client: &http.Client{}}
+}
+
+func (p *HTTPProvider) Synthesize(ctx context.Context, text string) (string, error) {
+	body, err := json.Marshal(map[string]string{"text": text, "voice": p.voice})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	key, err := p.apiKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load api key: %w", err)
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var res struct {
+		AudioURL string `json:"audio_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	if res.AudioURL == "" {
+		return "", errors.New("provider returned no audio_url")
+	}
+
+	return res.AudioURL, nil
+}
+
+// Job is an answer waiting for its audio.
+type Job struct {
+	RoomID    uuid.UUID
+	MessageID uuid.UUID
+	Text      string
+}
+
+// Queue feeds jobs to the provider one at a time. A nil Queue means TTS is
+// off and drops everything.
+type Queue struct {
+	provider Provider
+	jobs     chan Job
+	timeout  time.Duration
+}
+
+func NewQueue(provider Provider, size int, timeout time.Duration) *Queue {
+	return &Queue{provider: provider, jobs: make(chan Job, size), timeout: timeout}
+}
+
+// FromEnv reads WS_TTS_URL, returning nil when unset, WS_TTS_VOICE,
+// WS_TTS_QUEUE_SIZE and WS_TTS_TIMEOUT. WS_TTS_API_KEY is sent when set,
+// the key from fallbackKey otherwise.
+func FromEnv(fallbackKey func(ctx context.Context) (string, error)) *Queue {
+	endpoint := os.Getenv("WS_TTS_URL")
+	if endpoint == "" {
+		return nil
+	}
+
+	size := defaultQueueSize
+	if raw := os.Getenv("WS_TTS_QUEUE_SIZE"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			size = v
+		} else {
+			slog.Warn("invalid WS_TTS_QUEUE_SIZE, using default", "value", raw, "default", defaultQueueSize)
+		}
+	}
+	timeout := defaultTimeout
+	if raw := os.Getenv("WS_TTS_TIMEOUT"); raw != "" {
+		if v, err := time.ParseDuration(raw); err == nil && v > 0 {
+			timeout = v
+		} else {
+			slog.Warn("invalid WS_TTS_TIMEOUT, using default", "value", raw, "default", defaultTimeout)
+		}
+	}
+
+	apiKey := fallbackKey
+	if key := os.Getenv("WS_TTS_API_KEY"); key != "" {
+		apiKey = func(context.Context) (string, error) { return key, nil }
+	}
+
+	return NewQueue(NewHTTPProvider(endpoint, os.Getenv("WS_TTS_VOICE"), apiKey), size, timeout)
+}
+
+// Enqueue queues the job without blocking, reporting false when TTS is off
+// or the queue is full.
+func (q *Queue) Enqueue(job Job) bool {
+	if q == nil {
+		return false
+	}
+
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		slog.Warn("tts queue is full, skipping answer", "room_id", job.RoomID, "message_id", job.MessageID)
+		return false
+	}
+}
+
+// Run synthesizes queued jobs until ctx is done, handing each audio URL to
+// done. Failed jobs are logged and dropped.
+func (q *Queue) Run(ctx context.Context, done func(ctx context.Context, job Job, audioURL string)) {
+	if q == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			jobCtx, cancel := context.WithTimeout(ctx, q.timeout)
+			audioURL, err := q.provider.Synthesize(jobCtx, job.Text)
+			cancel()
+			if err != nil {
+				slog.Error("failed to synthesize answer", "room_id", job.RoomID, "message_id", job.MessageID, "error", err)
+				continue
+			}
+			done(ctx, job, audioURL)
+		}
+	}
+}
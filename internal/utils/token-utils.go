@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// MatchTokenHash reports whether token hashes to the stored hash, in constant time.
+func MatchTokenHash(token string, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// ParseBearerToken reads the token from an "Authorization: Bearer <token>" header.
+func ParseBearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,63 @@
+// Package validation checks decoded request bodies. A Validator collects
+// every field error instead of stopping at the first, so a client gets them
+// all in one 422 and can fix them at once.
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luiz504/week-tech-go-server/internal/forms"
+	"github.com/luiz504/week-tech-go-server/internal/helpers"
+)
+
+// Validator is used once per request, its zero value ready to go.
+type Validator struct {
+	errs []forms.FieldError
+}
+
+// Add records errors found elsewhere, such as by a form schema.
+func (v *Validator) Add(errs ...forms.FieldError) {
+	v.errs = append(v.errs, errs...)
+}
+
+// Check records message against field unless ok.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, forms.FieldError{Field: field, Message: message})
+	}
+}
+
+// Text trims the string value points at, then checks it's between min and
+// max characters. A zero min allows it empty.
+func (v *Validator) Text(field string, value *string, min, max int) {
+	*value = strings.TrimSpace(*value)
+	length := utf8.RuneCountInString(*value)
+
+	switch {
+	case min > 0 && length == 0:
+		v.Check(false, field, "is required")
+	case min == 0 && length > max:
+		v.Check(false, field, fmt.Sprintf("must be at most %d characters", max))
+	case length < min || length > max:
+		v.Check(false, field, fmt.Sprintf("must be between %d and %d characters", min, max))
+	}
+}
+
+// Errors returns what was recorded, nil when the body is valid.
+func (v *Validator) Errors() []forms.FieldError {
+	return v.errs
+}
+
+// Respond writes the recorded errors as a 422, reporting whether there were
+// any: the handler returns when it did.
+func (v *Validator) Respond(w http.ResponseWriter) bool {
+	if len(v.errs) == 0 {
+		return false
+	}
+	helpers.RespondValidationErrors(w, v.errs)
+
+	return true
+}